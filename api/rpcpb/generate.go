@@ -0,0 +1,5 @@
+// Package rpcpb holds the gRPC service of rom-organizer and its generated Go client and
+// server stubs. Clients in other languages generate theirs from rom_organizer.proto.
+package rpcpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rom_organizer.proto
@@ -0,0 +1,345 @@
+// gRPC control API of rom-organizer, served by "rom-organizer rpc". Jobs submitted here
+// go to the same queue as "jobs add" and are run by a "jobs run --watch" worker.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: rom_organizer.proto
+
+package rpcpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	RomOrganizer_SubmitJob_FullMethodName = "/romorganizer.v1.RomOrganizer/SubmitJob"
+	RomOrganizer_GetJob_FullMethodName    = "/romorganizer.v1.RomOrganizer/GetJob"
+	RomOrganizer_ListJobs_FullMethodName  = "/romorganizer.v1.RomOrganizer/ListJobs"
+	RomOrganizer_CancelJob_FullMethodName = "/romorganizer.v1.RomOrganizer/CancelJob"
+	RomOrganizer_WatchJob_FullMethodName  = "/romorganizer.v1.RomOrganizer/WatchJob"
+	RomOrganizer_ListGames_FullMethodName = "/romorganizer.v1.RomOrganizer/ListGames"
+)
+
+// RomOrganizerClient is the client API for RomOrganizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RomOrganizerClient interface {
+	// SubmitJob queues a compress, decompress or organize job
+	SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// GetJob returns a queued job
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// ListJobs returns the queued jobs, oldest first
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// CancelJob stops a pending job from running
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// WatchJob streams a job's progress events and state changes, starting with those
+	// already recorded, and ends once the job is completed, failed or cancelled
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (RomOrganizer_WatchJobClient, error)
+	// ListGames returns the organized games of the libraries the server was started with
+	ListGames(ctx context.Context, in *ListGamesRequest, opts ...grpc.CallOption) (*ListGamesResponse, error)
+}
+
+type romOrganizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRomOrganizerClient(cc grpc.ClientConnInterface) RomOrganizerClient {
+	return &romOrganizerClient{cc}
+}
+
+func (c *romOrganizerClient) SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, RomOrganizer_SubmitJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *romOrganizerClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, RomOrganizer_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *romOrganizerClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, RomOrganizer_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *romOrganizerClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, RomOrganizer_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *romOrganizerClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (RomOrganizer_WatchJobClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RomOrganizer_ServiceDesc.Streams[0], RomOrganizer_WatchJob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &romOrganizerWatchJobClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RomOrganizer_WatchJobClient interface {
+	Recv() (*JobUpdate, error)
+	grpc.ClientStream
+}
+
+type romOrganizerWatchJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *romOrganizerWatchJobClient) Recv() (*JobUpdate, error) {
+	m := new(JobUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *romOrganizerClient) ListGames(ctx context.Context, in *ListGamesRequest, opts ...grpc.CallOption) (*ListGamesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGamesResponse)
+	err := c.cc.Invoke(ctx, RomOrganizer_ListGames_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RomOrganizerServer is the server API for RomOrganizer service.
+// All implementations must embed UnimplementedRomOrganizerServer
+// for forward compatibility
+type RomOrganizerServer interface {
+	// SubmitJob queues a compress, decompress or organize job
+	SubmitJob(context.Context, *SubmitJobRequest) (*Job, error)
+	// GetJob returns a queued job
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// ListJobs returns the queued jobs, oldest first
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// CancelJob stops a pending job from running
+	CancelJob(context.Context, *CancelJobRequest) (*Job, error)
+	// WatchJob streams a job's progress events and state changes, starting with those
+	// already recorded, and ends once the job is completed, failed or cancelled
+	WatchJob(*WatchJobRequest, RomOrganizer_WatchJobServer) error
+	// ListGames returns the organized games of the libraries the server was started with
+	ListGames(context.Context, *ListGamesRequest) (*ListGamesResponse, error)
+	mustEmbedUnimplementedRomOrganizerServer()
+}
+
+// UnimplementedRomOrganizerServer must be embedded to have forward compatible implementations.
+type UnimplementedRomOrganizerServer struct {
+}
+
+func (UnimplementedRomOrganizerServer) SubmitJob(context.Context, *SubmitJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedRomOrganizerServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedRomOrganizerServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedRomOrganizerServer) CancelJob(context.Context, *CancelJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedRomOrganizerServer) WatchJob(*WatchJobRequest, RomOrganizer_WatchJobServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedRomOrganizerServer) ListGames(context.Context, *ListGamesRequest) (*ListGamesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGames not implemented")
+}
+func (UnimplementedRomOrganizerServer) mustEmbedUnimplementedRomOrganizerServer() {}
+
+// UnsafeRomOrganizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RomOrganizerServer will
+// result in compilation errors.
+type UnsafeRomOrganizerServer interface {
+	mustEmbedUnimplementedRomOrganizerServer()
+}
+
+func RegisterRomOrganizerServer(s grpc.ServiceRegistrar, srv RomOrganizerServer) {
+	s.RegisterService(&RomOrganizer_ServiceDesc, srv)
+}
+
+func _RomOrganizer_SubmitJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RomOrganizerServer).SubmitJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RomOrganizer_SubmitJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RomOrganizerServer).SubmitJob(ctx, req.(*SubmitJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RomOrganizer_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RomOrganizerServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RomOrganizer_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RomOrganizerServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RomOrganizer_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RomOrganizerServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RomOrganizer_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RomOrganizerServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RomOrganizer_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RomOrganizerServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RomOrganizer_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RomOrganizerServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RomOrganizer_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RomOrganizerServer).WatchJob(m, &romOrganizerWatchJobServer{ServerStream: stream})
+}
+
+type RomOrganizer_WatchJobServer interface {
+	Send(*JobUpdate) error
+	grpc.ServerStream
+}
+
+type romOrganizerWatchJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *romOrganizerWatchJobServer) Send(m *JobUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RomOrganizer_ListGames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RomOrganizerServer).ListGames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RomOrganizer_ListGames_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RomOrganizerServer).ListGames(ctx, req.(*ListGamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RomOrganizer_ServiceDesc is the grpc.ServiceDesc for RomOrganizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RomOrganizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "romorganizer.v1.RomOrganizer",
+	HandlerType: (*RomOrganizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitJob",
+			Handler:    _RomOrganizer_SubmitJob_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _RomOrganizer_GetJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _RomOrganizer_ListJobs_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _RomOrganizer_CancelJob_Handler,
+		},
+		{
+			MethodName: "ListGames",
+			Handler:    _RomOrganizer_ListGames_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchJob",
+			Handler:       _RomOrganizer_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rom_organizer.proto",
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var archiveJSON bool
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Inspect game.7z archives",
+}
+
+var archiveLsCmd = &cobra.Command{
+	Use:   "ls <organized-dir|game.7z>",
+	Short: "List the files in a game.7z archive without extracting it",
+	Long: `List the paths, sizes and CRCs of the files packed in a game.7z archive.
+
+Pass either the archive itself or an organized game directory containing one.
+
+Examples:
+  rom-organizer archive ls "/mnt/nas/ps3/Demon's Souls [BLUS30443]"
+  rom-organizer archive ls --json /mnt/nas/ps3/game.7z`,
+	Args: cobra.ExactArgs(1),
+	RunE: archiveLsHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+	archiveCmd.AddCommand(archiveLsCmd)
+
+	archiveLsCmd.Flags().BoolVarP(&archiveJSON, "json", "j", false, "Output in JSON format")
+}
+
+// archivePathFor returns the game.7z for an archive path or an organized game directory
+func archivePathFor(path string) (string, error) {
+	if !isDir(path) {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("archive not found: %w", err)
+		}
+		return path, nil
+	}
+
+	archivePath := filepath.Join(path, "game.7z")
+	if _, err := os.Stat(archivePath); err != nil {
+		return "", fmt.Errorf("no game.7z in %s", path)
+	}
+	return archivePath, nil
+}
+
+func archiveLsHandler(cmd *cobra.Command, args []string) error {
+	archivePath, err := archivePathFor(args[0])
+	if err != nil {
+		return err
+	}
+
+	entries, err := common.List7zArchive(archivePath)
+	if err != nil {
+		return err
+	}
+
+	if archiveJSON {
+		if entries == nil {
+			entries = []common.ArchiveEntry{}
+		}
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	var files int
+	var total int64
+	fmt.Printf("%-8s  %14s  %-19s  %s\n", "CRC", "Size", "Modified", "Path")
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		// 7z prints sub-second precision, which only widens the table
+		modified := entry.Modified
+		if len(modified) > 19 {
+			modified = modified[:19]
+		}
+		fmt.Printf("%-8s  %14d  %-19s  %s\n", entry.CRC, entry.Size, modified, entry.Path)
+		files++
+		total += entry.Size
+	}
+	fmt.Printf("\n%d files, %s\n", files, common.FormatSize(total))
+	return nil
+}
+
+// is7zFile reports whether path is a .7z file rather than a folder
+func is7zFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".7z") && !isDir(path)
+}
+
+// handle7zMetadata identifies a game.7z found outside its organized folder by the record
+// rom-organizer embeds in new archives, and prints the PARAM.SFO packed inside it
+func handle7zMetadata(path string) error {
+	meta, err := common.ReadArchiveMetadata(path)
+	if err != nil {
+		return err
+	}
+	var paramSFO *parsers.ParamSFO
+	data, sfoErr := common.Read7zFile(path, "PS3_GAME/PARAM.SFO")
+	if sfoErr == nil {
+		paramSFO, sfoErr = parsers.ParseParamSFO(data)
+	}
+	if meta == nil && sfoErr != nil {
+		return fmt.Errorf("%w: %s has neither a rom-organizer record nor a PS3_GAME/PARAM.SFO", common.ErrNotDetected, path)
+	}
+	if sfoErr != nil {
+		ui.Warnf("reading PARAM.SFO from %s: %v\n", path, sfoErr)
+	}
+
+	if jsonOutput {
+		out := map[string]interface{}{"path": path}
+		if meta != nil {
+			out["archive"] = meta
+			out["title"] = meta.Title
+			out["gameId"] = meta.GameID
+		}
+		if paramSFO != nil {
+			out["title"] = paramSFO.GetTitle()
+			out["gameId"] = paramSFO.GetTitleID()
+			out["appVersion"] = paramSFO.GetString("APP_VER")
+			out["category"] = paramSFO.GetString("CATEGORY")
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if meta != nil {
+		fmt.Printf("File Type:    game.7z (made by rom-organizer %s)\n", meta.Organizer)
+		fmt.Printf("Folder Name:  %s\n", common.GameFolderName(meta.Title, meta.GameID))
+		fmt.Printf("Source Hash:  %s\n", meta.SourceHash)
+	} else {
+		fmt.Println("File Type:    7z archive (no rom-organizer record)")
+	}
+	fmt.Println()
+
+	if paramSFO != nil {
+		outputText(paramSFO, verbose)
+		return nil
+	}
+	fmt.Println("Summary:")
+	fmt.Println("========")
+	fmt.Printf("Game Title:  %s\n", meta.Title)
+	fmt.Printf("Game ID:     %s\n", meta.GameID)
+	if meta.AppVersion != "" {
+		fmt.Printf("App Version: %s\n", meta.AppVersion)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	auditFirmwareVersion string
+	auditFilter          catalog.Filter
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check organized libraries against console requirements",
+}
+
+var auditFirmwareCmd = &cobra.Command{
+	Use:   "firmware <library> [library...]",
+	Short: "Report the firmware each game requires",
+	Long: `Report the system software version each organized game requires, read from
+PS3_SYSTEM_VER in its PARAM.SFO (from game.7z without extracting it), and the
+highest version across the libraries.
+
+With --firmware, games needing a newer version than your console runs are
+listed separately and the command fails, so scripts can check a library.
+For decompressed games the key revision of EBOOT.BIN's SELF header is shown as
+well; newer firmware brings newer key revisions.
+
+Examples:
+  rom-organizer audit firmware /mnt/nas/ps3
+  rom-organizer audit firmware --firmware 4.55 /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: auditFirmwareHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditFirmwareCmd)
+	auditFirmwareCmd.Flags().StringVar(&auditFirmwareVersion, "firmware", "", "Firmware of your console, e.g. 4.81; games needing newer firmware are flagged")
+	addCatalogFilterFlags(auditFirmwareCmd, &auditFilter)
+}
+
+func auditFirmwareHandler(cmd *cobra.Command, args []string) error {
+	var console library.Firmware
+	if auditFirmwareVersion != "" {
+		var err error
+		if console, err = library.ParseFirmware(auditFirmwareVersion); err != nil {
+			return err
+		}
+	}
+
+	games, _, err := findFilteredGames(args, auditFilter)
+	if err != nil {
+		return err
+	}
+	reports := library.AuditFirmware(games)
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Required > reports[j].Required })
+
+	var highest *library.FirmwareReport
+	var tooNew []library.FirmwareReport
+	for i, report := range reports {
+		name := fmt.Sprintf("%s [%s]", report.Info.GameInfo.Title, report.Info.GameInfo.GameID)
+		if report.Err != nil {
+			ui.Warnf("%s: %v\n", name, report.Err)
+			continue
+		}
+
+		required := "unknown"
+		if report.Required > 0 {
+			required = report.Required.String()
+		}
+		line := fmt.Sprintf("%-8s %s", required, name)
+		if report.KeyRevision >= 0 {
+			line += fmt.Sprintf("  (key revision 0x%02X)", report.KeyRevision)
+		}
+		fmt.Println(line)
+
+		if highest == nil && report.Required > 0 {
+			highest = &reports[i]
+		}
+		if console > 0 && report.Required > console {
+			tooNew = append(tooNew, report)
+		}
+	}
+
+	if highest == nil {
+		ui.Infof("\nNo game lists a required firmware\n")
+		return nil
+	}
+	ui.Infof("\nHighest required firmware: %s (%s [%s])\n", highest.Required, highest.Info.GameInfo.Title, highest.Info.GameInfo.GameID)
+
+	if console == 0 {
+		return nil
+	}
+	if len(tooNew) == 0 {
+		ui.Successf("All games run on firmware %s\n", console)
+		return nil
+	}
+	fmt.Println()
+	ui.Warnf("%d game(s) need firmware newer than %s:\n", len(tooNew), console)
+	for _, report := range tooNew {
+		fmt.Printf("  %s [%s] needs %s\n", report.Info.GameInfo.Title, report.Info.GameInfo.GameID, report.Required)
+	}
+	return fmt.Errorf("%d game(s) need firmware newer than %s", len(tooNew), console)
+}
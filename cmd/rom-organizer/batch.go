@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/jobs"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	batchDryRun    bool
+	batchKeepGoing bool
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Run migrations declared in a plan file",
+}
+
+var batchRunCmd = &cobra.Command{
+	Use:   "run <plan.yaml>",
+	Short: "Run the compress, decompress and organize steps of a plan file",
+	Long: `Run the steps of a plan file (YAML, or JSON) in order. Each step names its sources,
+the format to organize them into and where to, so a migration of many folders can be
+reviewed and kept under version control instead of typed as shell commands:
+
+  defaults:
+    output: /mnt/nas/ps3
+    organize_by: first-letter
+    flags: [--checksum=sha256]
+  steps:
+    - name: old USB drive
+      sources: [/media/usb/PS3ISO/*.iso]
+      format: compressed
+    - name: raw dumps
+      sources: [dumps/*]
+      console: ps3
+      format: decompressed
+      move: true
+      mirrors: [/mnt/backup/ps3]
+
+format is compressed, decompressed or keep (the default); output, mirrors, organize_by,
+move and flags (any other flag of the command) can be set per step or in defaults.
+Sources are paths or glob patterns; relative paths are taken from the plan's folder.
+With console, a folder source detected as another console, or as no game at all,
+stops the plan before anything runs.
+
+The commands are printed and confirmed before the first step runs. Every step is
+its own run in the history. The plan stops at the first failing step unless
+--keep-going is given.
+
+Examples:
+  rom-organizer batch run migration.yaml --dry-run
+  rom-organizer batch run migration.yaml --yes --keep-going`,
+	Args: cobra.ExactArgs(1),
+	RunE: batchRunHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.AddCommand(batchRunCmd)
+	batchRunCmd.Flags().BoolVarP(&batchDryRun, "dry-run", "n", false, "Check the plan and print its commands without running them")
+	batchRunCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before running the plan")
+	batchRunCmd.Flags().BoolVar(&batchKeepGoing, "keep-going", false, "Run the remaining steps after a step fails")
+	addSearchFlags(batchRunCmd)
+}
+
+func batchRunHandler(cmd *cobra.Command, args []string) error {
+	plan, err := jobs.LoadPlan(args[0])
+	if err != nil {
+		return err
+	}
+	planned, err := plan.Jobs()
+	if err != nil {
+		return err
+	}
+	limits, err := searchLimits()
+	if err != nil {
+		return err
+	}
+	for _, job := range planned {
+		if err := checkPlannedFlags(job); err != nil {
+			return fmt.Errorf("%s: %w", job.Name, err)
+		}
+		if err := checkPlannedConsole(job, limits); err != nil {
+			return fmt.Errorf("%s: %w", job.Name, err)
+		}
+	}
+
+	sources := 0
+	for _, job := range planned {
+		fmt.Printf("%d. %s (%d sources)\n   rom-organizer %s %s\n", job.Step, job.Name, len(job.Sources), job.Command, shellArgs(job.Args))
+		sources += len(job.Sources)
+	}
+	if batchDryRun {
+		return nil
+	}
+	if !assumeYes {
+		ok, err := confirm(fmt.Sprintf("Run %d steps with %d sources?", len(planned), sources))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	var failed []string
+	for _, job := range planned {
+		ui.Infof("=== %d/%d: %s ===\n", job.Step, len(planned), job.Name)
+		// The plan was confirmed as a whole, so its steps don't ask again
+		if err := runSubcommand(append([]string{job.Command}, job.Args...), ui.Output(), os.Stderr, true); err != nil {
+			ui.Errorf("%s failed: %v\n", job.Name, err)
+			failed = append(failed, job.Name)
+			if !batchKeepGoing {
+				return fmt.Errorf("plan stopped at %s; %d steps not run (--keep-going runs them after a failure)", job.Name, len(planned)-job.Step)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d steps failed: %s", len(failed), len(planned), strings.Join(failed, ", "))
+	}
+	ui.Successf("Ran %d steps\n", len(planned))
+	return nil
+}
+
+// checkPlannedFlags reports flags of a planned step that its command doesn't have, so a
+// typo stops the plan before anything runs rather than in the middle of it
+func checkPlannedFlags(job *jobs.PlannedJob) error {
+	command, _, err := rootCmd.Find([]string{job.Command})
+	if err != nil {
+		return err
+	}
+	for _, arg := range job.Args {
+		if arg == "--" {
+			break
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		var known bool
+		if long, ok := strings.CutPrefix(name, "--"); ok {
+			known = command.Flags().Lookup(long) != nil || command.InheritedFlags().Lookup(long) != nil
+		} else if short := strings.TrimPrefix(name, "-"); len(short) == 1 {
+			known = command.Flags().ShorthandLookup(short) != nil || command.InheritedFlags().ShorthandLookup(short) != nil
+		} else {
+			// Combined shorthands such as -fm are left to the command
+			known = true
+		}
+		if !known {
+			return fmt.Errorf("%s has no flag %s", job.Command, name)
+		}
+	}
+	return nil
+}
+
+// checkPlannedConsole detects the folder sources of a step that names a console, and
+// reports those that aren't a game of it. Archives and disc images are only read when
+// their step runs.
+func checkPlannedConsole(job *jobs.PlannedJob, limits detect.SearchLimits) error {
+	if job.Console == detect.Unknown {
+		return nil
+	}
+	for _, source := range job.Sources {
+		info, err := os.Stat(source)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		detection, err := detect.DetectConsoleWithLimits(source, limits)
+		if err != nil {
+			return err
+		}
+		if detection.ConsoleType != job.Console {
+			return fmt.Errorf("%s is detected as %s, not %s", source, detection.ConsoleType, job.Console)
+		}
+	}
+	return nil
+}
+
+// shellArgs joins arguments the way they would be typed in a shell, so a printed plan can
+// be copied and run step by step
+func shellArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t'\"\\$`!*?[]()&;|<>#~") {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	collectionDescription string
+	catalogMergePolicy    string
+	catalogReplace        bool
+	catalogDryRun         bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag games in the catalog",
+	Long: `Add and remove tags (favorites, kids, co-op, backlog, ...) on games.
+
+Games are given by game ID or by the path of an organized game directory.
+Tags are stored in the catalog (see the catalog config setting), so they follow
+a game across libraries and mirrors.
+
+Examples:
+  rom-organizer tag add BLUS30001 favorites co-op
+  rom-organizer tag add "/mnt/nas/ps3/Game Name [BLUS30001]" kids
+  rom-organizer tag remove BLUS30001 backlog
+  rom-organizer tag list`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <game> <tag>...",
+	Short: "Add tags to a game",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  tagAddHandler,
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove <game> <tag>...",
+	Short: "Remove tags from a game",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  tagRemoveHandler,
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list [game]",
+	Short: "List all tags, or the tags of one game",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  tagListHandler,
+}
+
+var collectionCmd = &cobra.Command{
+	Use:   "collection",
+	Short: "Manage collections of games in the catalog",
+	Long: `Create named collections of games, for example to pick the games exported to
+a frontend or copied to a USB drive.
+
+Examples:
+  rom-organizer collection create party --description "Local multiplayer"
+  rom-organizer collection add party BLUS30001 BCES00002
+  rom-organizer collection show party
+  rom-organizer list --collection party /mnt/nas/ps3`,
+}
+
+var collectionCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an empty collection",
+	Args:  cobra.ExactArgs(1),
+	RunE:  collectionCreateHandler,
+}
+
+var collectionDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a collection",
+	Args:  cobra.ExactArgs(1),
+	RunE:  collectionDeleteHandler,
+}
+
+var collectionAddCmd = &cobra.Command{
+	Use:   "add <name> <game>...",
+	Short: "Add games to a collection",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  collectionAddHandler,
+}
+
+var collectionRemoveCmd = &cobra.Command{
+	Use:   "remove <name> <game>...",
+	Short: "Remove games from a collection",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  collectionRemoveHandler,
+}
+
+var collectionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List collections",
+	Args:  cobra.NoArgs,
+	RunE:  collectionListHandler,
+}
+
+var collectionShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "List the games in a collection",
+	Args:  cobra.ExactArgs(1),
+	RunE:  collectionShowHandler,
+}
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Export, import and merge catalog files",
+	Long: `Copy the catalog (tags, collections, compatibility and disc keys) between machines.
+
+Examples:
+  rom-organizer catalog export desktop.json
+  rom-organizer catalog import desktop.json
+  rom-organizer catalog import --prefer union nas.json`,
+}
+
+var catalogExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the catalog as JSON to a file or stdout",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  catalogExportHandler,
+}
+
+var catalogImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Merge an exported catalog into this one",
+	Long: `Merge an exported catalog into the local catalog.
+
+Games, collections and disc keys only in the imported file are added. Records present
+in both are compared by a hash of their content; when they differ, --prefer decides:
+  newer     the side modified most recently wins (default)
+  local     the local record is kept
+  incoming  the imported record wins
+  union     tags and collection members from both sides are combined
+A differing disc key is only replaced with --prefer incoming. Deletions are not tracked,
+so a game untagged on one machine only stays untagged if it was modified later there.
+
+Use --replace to overwrite the local catalog with the file instead of merging.`,
+	Args: cobra.ExactArgs(1),
+	RunE: catalogImportHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+	catalogCmd.AddCommand(catalogExportCmd, catalogImportCmd)
+
+	catalogImportCmd.Flags().StringVar(&catalogMergePolicy, "prefer", string(catalog.PreferNewer), "Conflict resolution: newer, local, incoming or union")
+	catalogImportCmd.Flags().BoolVar(&catalogReplace, "replace", false, "Replace the local catalog instead of merging")
+	catalogImportCmd.Flags().BoolVarP(&catalogDryRun, "dry-run", "n", false, "Show what would change without saving")
+
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd, tagRemoveCmd, tagListCmd)
+
+	rootCmd.AddCommand(collectionCmd)
+	collectionCmd.AddCommand(collectionCreateCmd, collectionDeleteCmd, collectionAddCmd,
+		collectionRemoveCmd, collectionListCmd, collectionShowCmd)
+
+	collectionCreateCmd.Flags().StringVar(&collectionDescription, "description", "", "Description of the collection")
+}
+
+// openCatalog opens the catalog file from the config
+func openCatalog() (*catalog.Catalog, error) {
+	return catalog.Open(appConfig.CatalogPath())
+}
+
+// resolveGame returns the game ID and title for a game given as an organized game
+// directory or a game ID. The title is empty when only an ID was given.
+func resolveGame(arg string) (gameID, title string, err error) {
+	if _, statErr := os.Stat(arg); statErr == nil {
+		info, err := common.DetectOrganizedDirectory(arg, appConfig.Layout, false)
+		if err != nil {
+			return "", "", err
+		}
+		if !info.IsOrganized || info.GameInfo == nil {
+			return "", "", fmt.Errorf("%s is not an organized game directory", arg)
+		}
+		return info.GameInfo.GameID, info.GameInfo.Title, nil
+	}
+
+	if strings.ContainsAny(arg, `/\ `) {
+		return "", "", fmt.Errorf("%s: no such game directory", arg)
+	}
+	return strings.ToUpper(arg), "", nil
+}
+
+// gameLabel formats a game for display, with its title when the catalog knows it
+func gameLabel(c *catalog.Catalog, gameID string) string {
+	if title := c.Title(gameID); title != "" {
+		return fmt.Sprintf("%s [%s]", title, gameID)
+	}
+	return gameID
+}
+
+func tagAddHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	gameID, title, err := resolveGame(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.Tag(gameID, title, args[1:]...); err != nil {
+		return err
+	}
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Infof("%s: %s\n", gameLabel(c, gameID), formatTags(c.Tags(gameID)))
+	return nil
+}
+
+func tagRemoveHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	gameID, _, err := resolveGame(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.Untag(gameID, args[1:]...); err != nil {
+		return err
+	}
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Infof("%s: %s\n", gameLabel(c, gameID), formatTags(c.Tags(gameID)))
+	return nil
+}
+
+// formatTags joins tags for display
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "(no tags)"
+	}
+	return strings.Join(tags, ", ")
+}
+
+func tagListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		gameID, _, err := resolveGame(args[0])
+		if err != nil {
+			return err
+		}
+		for _, tag := range c.Tags(gameID) {
+			fmt.Println(tag)
+		}
+		return nil
+	}
+
+	counts := c.TagCounts()
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		fmt.Printf("%-20s %d games\n", tag, counts[tag])
+	}
+	return nil
+}
+
+func collectionCreateHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	if err := c.CreateCollection(args[0], collectionDescription); err != nil {
+		return err
+	}
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("Created collection %s\n", args[0])
+	return nil
+}
+
+func collectionDeleteHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	if err := c.DeleteCollection(args[0]); err != nil {
+		return err
+	}
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Infof("Deleted collection %s\n", args[0])
+	return nil
+}
+
+func collectionAddHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	for _, arg := range args[1:] {
+		gameID, title, err := resolveGame(arg)
+		if err != nil {
+			return err
+		}
+		if err := c.AddToCollection(args[0], gameID, title); err != nil {
+			return err
+		}
+		ui.Infof("Added %s to %s\n", gameLabel(c, gameID), args[0])
+	}
+	return c.Save()
+}
+
+func collectionRemoveHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	for _, arg := range args[1:] {
+		gameID, _, err := resolveGame(arg)
+		if err != nil {
+			return err
+		}
+		label := gameLabel(c, gameID)
+		if err := c.RemoveFromCollection(args[0], gameID); err != nil {
+			return err
+		}
+		ui.Infof("Removed %s from %s\n", label, args[0])
+	}
+	return c.Save()
+}
+
+func collectionListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(c.Collections))
+	for name := range c.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		coll := c.Collections[name]
+		fmt.Printf("%-20s %3d games  %s\n", name, len(coll.Games), coll.Description)
+	}
+	return nil
+}
+
+func collectionShowHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	coll, err := c.Collection(args[0])
+	if err != nil {
+		return err
+	}
+	for _, gameID := range coll.Games {
+		fmt.Println(gameLabel(c, gameID))
+	}
+	return nil
+}
+
+func catalogExportHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return c.Export(os.Stdout)
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("creating export file: %w", err)
+	}
+	if err := c.Export(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing export file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing export file: %w", err)
+	}
+	ui.Successf("Exported %d games, %d collections and %d disc keys to %s\n", len(c.Games), len(c.Collections), len(c.DiscKeys), args[0])
+	return nil
+}
+
+func catalogImportHandler(cmd *cobra.Command, args []string) error {
+	policy, err := catalog.ParseMergePolicy(catalogMergePolicy)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading import file: %w", err)
+	}
+	incoming, err := catalog.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", args[0], err)
+	}
+
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	if catalogReplace {
+		c.Games, c.Collections, c.DiscKeys, c.Contents, c.DATs = incoming.Games, incoming.Collections, incoming.DiscKeys, incoming.Contents, incoming.DATs
+		ui.Infof("Replacing the catalog with %d games, %d collections and %d disc keys\n", len(c.Games), len(c.Collections), len(c.DiscKeys))
+	} else {
+		result := c.Merge(incoming, policy)
+		for _, conflict := range result.Conflicts {
+			ui.Infof("  %-10s %-20s %s\n", conflict.Kind, conflict.Key, conflict.Resolution)
+		}
+		ui.Infof("Added %d, unchanged %d, conflicts %d\n", result.Added, result.Unchanged, len(result.Conflicts))
+	}
+
+	if catalogDryRun {
+		ui.Infof("Dry run: catalog not saved\n")
+		return nil
+	}
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("Catalog saved to %s\n", appConfig.CatalogPath())
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/remote"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	checkQuarantine bool
+	checkSpotChecks int
+	checkSpotSize   string
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check libraries for problems",
+}
+
+var checkLibraryCmd = &cobra.Command{
+	Use:   "library <library> [library...]",
+	Short: "Find files that don't belong to any organized game",
+	Long: `List what sits in a library root (or in an alphabetical bucket such as A/ or
+0-9/) without belonging to an organized game:
+
+  stray archive     input archives and disc images (.7z, .zip, .rar, .iso, .pkg)
+  temp leftover     temporary folders from an interrupted organize
+  incomplete game   game folders missing game.7z or game/, _updates/ or _dlc/,
+                    such as half-copied folders
+  unknown folder    folders that are neither a game nor a bucket of games
+  stray file        any other file
+  junk file         OS metadata such as Thumbs.db and .DS_Store
+
+With --quarantine, everything listed is moved into _quarantine/ in the library root
+after confirmation (or with --yes), keeping its relative path, so it can be reviewed
+and deleted or organized later. Nothing is deleted.
+
+Examples:
+  rom-organizer check library /mnt/nas/ps3
+  rom-organizer check library --quarantine --yes /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: checkLibraryHandler,
+}
+
+var checkRemoteCmd = &cobra.Command{
+	Use:   "remote <library> <URL>",
+	Short: "Verify a copy of a library on an HTTP server or object store without downloading it",
+	Long: `Check that a copy of a library uploaded to an HTTP server or an object store (S3, B2,
+R2, GCS and the like, through an HTTP endpoint or presigned URL) matches the local
+library, without downloading the archives. The URL is the folder holding the copy of
+the library root, so <URL>/<game folder>/game.7z is the remote archive.
+
+For each game.7z and each package in _updates/ and _dlc/:
+
+  1. The remote size must match the local file
+  2. A checksum reported by the server (a single-part ETag or Content-MD5, which
+     hold the MD5, x-goog-hash or x-amz-checksum-sha256) must match the local
+     checksum files (game.7z.<algorithm>) or the digest stored by scan
+  3. Checksum files uploaded next to the archive must match the local ones
+  4. When the server reports no checksum to compare, --spot-checks ranges of
+     --spot-size bytes (the start, the end and random offsets) are read and
+     compared with the local file
+
+Files whose contents could only be checked by size are reported as unverified.
+Decompressed game/ folders are skipped. User info in the URL is sent as basic
+authentication.
+
+Examples:
+  rom-organizer check remote /mnt/nas/ps3 https://backup.example.org/ps3
+  rom-organizer check remote --spot-checks 16 /mnt/nas/ps3 https://bucket.s3.amazonaws.com/ps3`,
+	Args: cobra.ExactArgs(2),
+	RunE: checkRemoteHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.AddCommand(checkLibraryCmd, checkRemoteCmd)
+	checkLibraryCmd.Flags().BoolVar(&checkQuarantine, "quarantine", false, "Move what was found into _quarantine/ in the library root")
+	checkLibraryCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before quarantining")
+
+	checkRemoteCmd.Flags().IntVar(&checkSpotChecks, "spot-checks", 4, "Ranges to read from files the server reports no checksum for (0 to only compare sizes)")
+	checkRemoteCmd.Flags().StringVar(&checkSpotSize, "spot-size", "64KB", "Size of each spot check range")
+}
+
+func checkLibraryHandler(cmd *cobra.Command, args []string) error {
+	if checkQuarantine {
+		if err := checkWritable(args...); err != nil {
+			return err
+		}
+	}
+
+	found := make(map[string][]library.Orphan)
+	total, size := 0, int64(0)
+	for _, root := range args {
+		orphans, err := library.FindOrphans(root, appConfig.Layout, appConfig.Cleanup.JunkFiles)
+		if err != nil {
+			return err
+		}
+		found[root] = orphans
+		for _, orphan := range orphans {
+			line := fmt.Sprintf("%-16s %10s  %s", orphan.Kind, common.FormatSize(orphan.Size), orphan.Path)
+			if orphan.Detail != "" {
+				line += " (" + orphan.Detail + ")"
+			}
+			fmt.Println(line)
+			total++
+			size += orphan.Size
+		}
+	}
+
+	if total == 0 {
+		ui.Successf("Nothing found outside organized games\n")
+		return nil
+	}
+	ui.Infof("\n%d item(s), %s, don't belong to an organized game\n", total, common.FormatSize(size))
+	if !checkQuarantine {
+		return nil
+	}
+
+	if !assumeYes {
+		ok, err := confirm(fmt.Sprintf("Move %d item(s) into %s/?", total, library.QuarantineDir))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	if err := takeSnapshot("quarantine", args...); err != nil {
+		return err
+	}
+	for _, root := range args {
+		for _, orphan := range found[root] {
+			target, err := library.Quarantine(root, orphan)
+			if err != nil {
+				return err
+			}
+			ui.Verbosef("Moved %s to %s\n", orphan.Path, target)
+		}
+	}
+	ui.Successf("Moved %d item(s) into %s/\n", total, library.QuarantineDir)
+	return nil
+}
+
+func checkRemoteHandler(cmd *cobra.Command, args []string) error {
+	root, base := args[0], args[1]
+	spotSize, err := common.ParseSize(checkSpotSize)
+	if err != nil {
+		return fmt.Errorf("invalid --spot-size: %w", err)
+	}
+	client, err := remote.NewClient(base, 60*time.Second)
+	if err != nil {
+		return err
+	}
+	games, err := library.FindGames(root, appConfig.Layout)
+	if err != nil {
+		return err
+	}
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	opts := remote.VerifyOptions{SpotChecks: checkSpotChecks, SpotSize: spotSize}
+	layout := appConfig.Layout.WithDefaults()
+	counts := make(map[string]int)
+	var read int64
+	for _, game := range games {
+		var files []string
+		if game.Info.HasCompressed {
+			files = append(files, filepath.Join(game.Path, "game.7z"))
+		} else {
+			ui.Verbosef("Skipping decompressed %s\n", game.Path)
+		}
+		files = append(files, gamePackages(filepath.Join(game.Path, layout.UpdatesDir))...)
+		files = append(files, gamePackages(filepath.Join(game.Path, layout.DLCDir))...)
+
+		absPath, _ := filepath.Abs(game.Path)
+		for _, file := range files {
+			manifest, err := localManifest(file)
+			if err != nil {
+				return err
+			}
+			if filepath.Base(file) == "game.7z" {
+				alg, digest, ok := library.StoredArchiveHash(game, c.Scans[absPath])
+				if _, have := manifest.Digests[alg]; ok && !have {
+					manifest.Digests[alg] = digest
+				}
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+
+			result := client.Verify(filepath.ToSlash(rel), file, manifest, opts)
+			counts[result.Status]++
+			read += result.Read
+			switch result.Status {
+			case remote.StatusOK:
+				ui.Verbosef("%-10s %s (%s)\n", result.Status, result.Path, strings.Join(result.Checks, ", "))
+			case remote.StatusUnverified:
+				line := fmt.Sprintf("%-10s %s", result.Status, result.Path)
+				if result.Detail != "" {
+					line += " (" + result.Detail + ")"
+				}
+				fmt.Println(line)
+			default:
+				fmt.Printf("%-10s %s (%s)\n", result.Status, result.Path, result.Detail)
+			}
+		}
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	bad := counts[remote.StatusMissing] + counts[remote.StatusMismatch] + counts[remote.StatusError]
+	summary := fmt.Sprintf("%d file(s): %d verified, %d unverified, %d missing, %d mismatched, %d errors (%s downloaded)",
+		total, counts[remote.StatusOK], counts[remote.StatusUnverified], counts[remote.StatusMissing],
+		counts[remote.StatusMismatch], counts[remote.StatusError], common.FormatSize(read))
+	if bad > 0 {
+		return fmt.Errorf("%s", summary)
+	}
+	ui.Successf("%s\n", summary)
+	return nil
+}
+
+// localManifest returns the size of a local file and the digests in its checksum files
+func localManifest(path string) (remote.Manifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return remote.Manifest{}, err
+	}
+	manifest := remote.Manifest{Size: info.Size(), Digests: make(map[common.HashAlgorithm]string)}
+	for _, alg := range common.HashAlgorithms {
+		if digest, err := common.ReadChecksumSidecar(path, alg); err == nil {
+			manifest.Digests[alg] = digest
+		}
+	}
+	return manifest, nil
+}
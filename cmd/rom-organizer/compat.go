@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/compat"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// compatCacheMaxAge is how long a downloaded compatibility database is reused
+const compatCacheMaxAge = 7 * 24 * time.Hour
+
+var (
+	compatFilter catalog.Filter
+	compatDBPath string
+	compatURL    string
+	compatUpdate bool
+	compatSort   string
+)
+
+var compatCmd = &cobra.Command{
+	Use:   "compat <library> [library...]",
+	Short: "Look up RPCS3 compatibility status for a library",
+	Long: `Cross-reference the game IDs in a library against the RPCS3 compatibility
+database and record each game's status (Playable, Ingame, Intro, Loadable, Nothing)
+in the catalog. Recorded statuses are shown by list and stats.
+
+The database is downloaded once and cached for a week; use --update to refresh it
+or --db to use a file saved from the RPCS3 compatibility API.
+
+Examples:
+  rom-organizer compat /mnt/nas/ps3
+  rom-organizer compat --update --tag backlog /mnt/nas/ps3
+  rom-organizer compat --db rpcs3-compat.json /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: compatHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(compatCmd)
+	addCatalogFilterFlags(compatCmd, &compatFilter)
+	compatCmd.Flags().StringVar(&compatDBPath, "db", "", "Use a saved RPCS3 compatibility API response instead of downloading")
+	compatCmd.Flags().StringVar(&compatURL, "url", compat.DefaultURL, "RPCS3 compatibility database URL")
+	compatCmd.Flags().BoolVar(&compatUpdate, "update", false, "Download the database even if the cached copy is recent")
+	addSortFlag(compatCmd, &compatSort)
+}
+
+// loadCompatDatabase returns the database from --db, the cache, or a fresh download.
+// A failed download falls back to an outdated cache with a warning.
+func loadCompatDatabase() (compat.Database, error) {
+	if compatDBPath != "" {
+		return compat.LoadFile(compatDBPath)
+	}
+
+	cachePath := compat.DefaultCachePath()
+	info, statErr := os.Stat(cachePath)
+	if statErr == nil && !compatUpdate && time.Since(info.ModTime()) < compatCacheMaxAge {
+		ui.Verbosef("Using cached compatibility database: %s\n", cachePath)
+		return compat.LoadFile(cachePath)
+	}
+
+	ui.Infof("Downloading RPCS3 compatibility database...\n")
+	db, err := compat.Download(compatURL, cachePath)
+	if err != nil {
+		if statErr != nil {
+			return nil, err
+		}
+		ui.Warnf("%v; using cached copy from %s\n", err, info.ModTime().Format("2006-01-02"))
+		return compat.LoadFile(cachePath)
+	}
+	return db, nil
+}
+
+func compatHandler(cmd *cobra.Command, args []string) error {
+	games, c, err := findFilteredGames(args, compatFilter)
+	if err != nil {
+		return err
+	}
+	if err := sortGames(games, compatSort); err != nil {
+		return err
+	}
+
+	db, err := loadCompatDatabase()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	now := time.Now()
+	for _, game := range games {
+		info := game.Info.GameInfo
+		entry, ok := db[info.GameID]
+		if !ok {
+			counts["Unknown"]++
+			fmt.Printf("%-60s %s\n", fmt.Sprintf("%s [%s]", info.Title, info.GameID), "Unknown")
+			continue
+		}
+
+		c.SetCompat(info.GameID, info.Title, catalog.CompatStatus{
+			Status:    entry.Status,
+			Updated:   entry.Date,
+			CheckedAt: now,
+		})
+		counts[entry.Status]++
+		fmt.Printf("%-60s %s\n", fmt.Sprintf("%s [%s]", info.Title, info.GameID), entry.Status)
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("Compatibility: %s\n", formatCompatCounts(counts))
+	return nil
+}
+
+// formatCompatCounts lists status counts from best to worst, e.g. "3 Playable, 1 Ingame"
+func formatCompatCounts(counts map[string]int) string {
+	var parts []string
+	for _, status := range append(compat.Statuses, "Unknown") {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	if len(parts) == 0 {
+		return "no games"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	contentTitleID string
+	contentJSON    bool
+)
+
+var contentCmd = &cobra.Command{
+	Use:   "content",
+	Short: "Cross-reference PSN content IDs, title IDs and DLC names",
+	Long: `Keep a table in the catalog mapping PSN content IDs (such as
+EP0001-BLES00001_00-MAPPACK000000001) to the game they belong to and their name.
+metadata shows the recorded names next to the packages in a game's _dlc folder.
+
+scan reads the content IDs of the packages in organized games (or given .pkg files)
+and the name in their PARAM.SFO. Many DLC packages carry no PARAM.SFO; import fills
+in their names from a content list, a tab- or comma-separated file or URL with
+"Content ID", "Title ID" and "Name" columns, as published by PSN archive sites.
+
+Examples:
+  rom-organizer content scan /mnt/nas/ps3
+  rom-organizer content import ps3_dlcs.tsv
+  rom-organizer content import https://example.org/PS3_DLCS.tsv
+  rom-organizer content list --id BLES00001`,
+}
+
+var contentScanCmd = &cobra.Command{
+	Use:   "scan <library|game|file.pkg>...",
+	Short: "Record the content IDs of the update and DLC packages in a library",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  contentScanHandler,
+}
+
+var contentImportCmd = &cobra.Command{
+	Use:   "import <list.tsv|list.csv|URL>...",
+	Short: "Record content IDs and names from a content list",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  contentImportHandler,
+}
+
+var contentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded content IDs",
+	Args:  cobra.NoArgs,
+	RunE:  contentListHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(contentCmd)
+	contentCmd.AddCommand(contentScanCmd, contentImportCmd, contentListCmd)
+
+	contentListCmd.Flags().StringVar(&contentTitleID, "id", "", "Only list content of this title ID")
+	contentListCmd.Flags().BoolVarP(&contentJSON, "json", "j", false, "Output in JSON format")
+}
+
+// gamePackages returns the .pkg files in a folder of an organized game, sorted
+func gamePackages(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var packages []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isPKGFile(entry.Name()) {
+			packages = append(packages, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// readPKGContent reads the content ID of a package and the record the catalog keeps for it
+func readPKGContent(path string) (string, catalog.ContentRecord, error) {
+	pkg, err := consoles.OpenPS3PKG(path)
+	if err != nil {
+		return "", catalog.ContentRecord{}, err
+	}
+	defer pkg.Close()
+
+	record := catalog.ContentRecord{
+		TitleID: pkg.Header.TitleID(),
+		Type:    pkg.Header.ContentTypeName(),
+		Source:  catalog.ContentSourcePKG,
+	}
+	if record.Type == "unknown" {
+		record.Type = ""
+	}
+	sfo, err := pkg.ParamSFO()
+	if err != nil {
+		ui.Verbosef("reading PARAM.SFO from %s: %v\n", path, err)
+	}
+	if sfo != nil {
+		record.Name = sfo.GetTitle()
+	}
+	if pkg.Header.ContentID == "" {
+		return "", record, fmt.Errorf("%s: package has no content ID", path)
+	}
+	return pkg.Header.ContentID, record, nil
+}
+
+func contentScanHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	var packages []string
+	var roots []string
+	for _, arg := range args {
+		if isPKGFile(arg) {
+			packages = append(packages, arg)
+		} else {
+			roots = append(roots, arg)
+		}
+	}
+	if len(roots) > 0 {
+		games, _, err := findFilteredGames(roots, catalog.Filter{})
+		if err != nil {
+			return err
+		}
+		layout := appConfig.Layout.WithDefaults()
+		for _, game := range games {
+			packages = append(packages, gamePackages(filepath.Join(game.Path, layout.UpdatesDir))...)
+			packages = append(packages, gamePackages(filepath.Join(game.Path, layout.DLCDir))...)
+		}
+	}
+
+	changed, failed := 0, 0
+	for _, path := range packages {
+		contentID, record, err := readPKGContent(path)
+		if err != nil {
+			ui.Warnf("%v\n", err)
+			failed++
+			continue
+		}
+		if c.SetContent(contentID, record) {
+			ui.Verbosef("%s  %s\n", contentID, record.Name)
+			changed++
+		}
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("Scanned %d packages: %d content IDs added or updated\n", len(packages), changed)
+	if failed > 0 {
+		return fmt.Errorf("%d packages could not be read", failed)
+	}
+	return nil
+}
+
+func contentImportHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	total, changed := 0, 0
+	for _, source := range args {
+		listings, err := catalog.ReadContentList(source)
+		if err != nil {
+			return err
+		}
+		for _, listing := range listings {
+			record := catalog.ContentRecord{TitleID: listing.TitleID, Name: listing.Name, Source: catalog.ContentSourceList}
+			if c.SetContent(listing.ContentID, record) {
+				changed++
+			}
+		}
+		ui.Verbosef("%s: %d content IDs\n", source, len(listings))
+		total += len(listings)
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("Imported %d content IDs: %d added or updated\n", total, changed)
+	return nil
+}
+
+func contentListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	if contentTitleID != "" {
+		ids = c.ContentIDs(strings.ToUpper(contentTitleID))
+	} else {
+		for id := range c.Contents {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+
+	if contentJSON {
+		out := make(map[string]*catalog.ContentRecord, len(ids))
+		for _, id := range ids {
+			out[id] = c.Content(id)
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(ids) == 0 {
+		ui.Infof("No content IDs recorded\n")
+		return nil
+	}
+	for _, id := range ids {
+		record := c.Content(id)
+		name := record.Name
+		if name == "" {
+			name = "[unknown]"
+		}
+		fmt.Printf("%-40s %-10s %s\n", id, record.TitleID, name)
+	}
+	return nil
+}
+
+// dlcPackage is a package in a game's _dlc folder, for metadata output
+type dlcPackage struct {
+	Path      string `json:"path"`
+	ContentID string `json:"contentId"`
+	Name      string `json:"name,omitempty"`
+}
+
+// findDLCPackages lists the packages in a game's _dlc folder, named from the catalog's
+// content table or else the package's PARAM.SFO
+func findDLCPackages(gameDir string) []dlcPackage {
+	paths := gamePackages(filepath.Join(gameDir, appConfig.Layout.WithDefaults().DLCDir))
+	if len(paths) == 0 {
+		return nil
+	}
+	c, err := openCatalog()
+	if err != nil {
+		ui.Warnf("%v\n", err)
+	}
+
+	var packages []dlcPackage
+	for _, path := range paths {
+		contentID, record, err := readPKGContent(path)
+		if err != nil {
+			ui.Warnf("%v\n", err)
+			continue
+		}
+		if c != nil {
+			if name := c.ContentName(contentID); name != "" {
+				record.Name = name
+			}
+		}
+		packages = append(packages, dlcPackage{Path: path, ContentID: contentID, Name: record.Name})
+	}
+	return packages
+}
+
+// printDLCPackages lists the packages in a game's _dlc folder with their names
+func printDLCPackages(packages []dlcPackage) {
+	if len(packages) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("DLC:")
+	fmt.Println("====")
+	for _, pkg := range packages {
+		name := pkg.Name
+		if name == "" {
+			name = "[unknown, see content import]"
+		}
+		fmt.Printf("%-40s %s\n", pkg.ContentID, name)
+		if verbose {
+			fmt.Printf("  %s\n", pkg.Path)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/config"
+	"github.com/NeilGraham/rom-organizer/internal/daemon"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	pidFile string
+
+	daemonName  string
+	daemonUser  bool
+	daemonPrint bool
+	daemonJSON  bool
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Install the long-running commands as a service and check on them",
+	Long: `Run "schedule daemon", "serve", "rpc" or "jobs run --watch" unattended: as a systemd
+service on Linux (e.g. a NAS), or as a Task Scheduler task on Windows.
+
+Installed daemons write a PID file, re-read the config file on SIGHUP
+(systemctl reload) and stop after their current task, job or game on SIGTERM.
+
+Examples:
+  rom-organizer daemon install schedule daemon
+  rom-organizer daemon install --name nas-share serve --listen :8080 /mnt/nas/ps3
+  rom-organizer daemon install --user jobs run --watch 1m
+  rom-organizer daemon install rpc --listen :50051 /mnt/nas/ps3
+  rom-organizer daemon install --print serve /mnt/nas/ps3
+  rom-organizer daemon status`,
+}
+
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install [flags] <schedule daemon | serve ... | rpc ... | jobs run --watch ...>",
+	Short: "Install a long-running command as a systemd service or Windows task",
+	Long: `Install a long-running command to start at boot (or at logon with --user).
+
+On Linux this writes a systemd unit named rom-organizer-{name} to /etc/systemd/system
+(or ~/.config/systemd/user with --user), then enables and starts it. On Windows it
+registers a Task Scheduler task of the same name, run as SYSTEM at boot (or as the
+current user at logon with --user) without a time limit. --print only shows the unit
+or task definition.
+
+The command keeps the config file given with --config, and gets a --pid-file in the
+folder "daemon status" reads.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: daemonInstallHandler,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status [name...]",
+	Short: "Show whether installed daemons are running",
+	Long: `Show the daemons that have a PID file, or the named ones, and whether their process
+is still running. Fails if a named daemon isn't running.`,
+	RunE: daemonStatusHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonInstallCmd, daemonStatusCmd)
+
+	// Flags after the command name belong to the installed command
+	daemonInstallCmd.Flags().SetInterspersed(false)
+	daemonInstallCmd.Flags().StringVar(&daemonName, "name", "", "Daemon name (default the command name, e.g. serve)")
+	daemonInstallCmd.Flags().BoolVar(&daemonUser, "user", false, "Run for the current user at logon instead of system-wide at boot")
+	daemonInstallCmd.Flags().BoolVar(&daemonPrint, "print", false, "Print the systemd unit or task definition instead of installing it")
+	daemonStatusCmd.Flags().BoolVarP(&daemonJSON, "json", "j", false, "Output in JSON format")
+
+	for _, cmd := range []*cobra.Command{scheduleDaemonCmd, serveCmd, rpcCmd, jobsRunCmd} {
+		cmd.Flags().StringVar(&pidFile, "pid-file", "", "Write the process ID to this file while running")
+	}
+}
+
+// daemonCommands are the long-running commands and how many words name them
+var daemonCommands = map[string][]string{
+	"schedule": {"schedule", "daemon"},
+	"serve":    {"serve"},
+	"rpc":      {"rpc"},
+	"jobs":     {"jobs", "run"},
+}
+
+var daemonNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func daemonInstallHandler(cmd *cobra.Command, args []string) error {
+	words, ok := daemonCommands[args[0]]
+	if !ok || len(args) < len(words) || strings.Join(args[:len(words)], " ") != strings.Join(words, " ") {
+		return fmt.Errorf("only \"schedule daemon\", \"serve\", \"rpc\" and \"jobs run\" can run as a daemon")
+	}
+	if args[0] == "jobs" && !containsFlag(args, "--watch") {
+		ui.Warnf("\"jobs run\" without --watch exits once the queue is empty\n")
+	}
+
+	name := daemonName
+	if name == "" {
+		name = args[0]
+	}
+	if !daemonNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid daemon name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+
+	service, err := newService(name, words, args)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case runtime.GOOS == "windows":
+		if daemonPrint {
+			fmt.Print(service.TaskXML())
+			return nil
+		}
+		return installTask(service)
+	case daemonPrint:
+		fmt.Print(service.SystemdUnit())
+		return nil
+	case runtime.GOOS == "linux":
+		return installSystemdUnit(service)
+	default:
+		return fmt.Errorf("installing a daemon is supported with systemd (Linux) and Task Scheduler (Windows); use --print for a systemd unit to adapt")
+	}
+}
+
+// newService builds the service definition: the daemon command with the config file
+// pinned and a --pid-file added after the command name
+func newService(name string, words, args []string) (daemon.Service, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return daemon.Service{}, fmt.Errorf("locating rom-organizer executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	// The service may not run with the installing user's environment, so name the
+	// config file whenever there is one
+	var command []string
+	cfgPath := configPath
+	if cfgPath == "" {
+		if _, err := os.Stat(config.DefaultPath()); err == nil {
+			cfgPath = config.DefaultPath()
+		}
+	}
+	if cfgPath != "" {
+		abs, err := filepath.Abs(cfgPath)
+		if err != nil {
+			return daemon.Service{}, err
+		}
+		command = append(command, "--config", abs)
+	}
+
+	command = append(command, words...)
+	command = append(command, "--pid-file", daemon.PIDPath(name, daemonUser))
+	command = append(command, args[len(words):]...)
+
+	home, _ := os.UserHomeDir()
+	return daemon.Service{Name: name, Executable: exe, Args: command, User: daemonUser, Home: home}, nil
+}
+
+// installSystemdUnit writes the unit file, then enables and starts it
+func installSystemdUnit(service daemon.Service) error {
+	path, err := service.SystemdPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating unit folder: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(service.SystemdUnit()), 0644); err != nil {
+		return fmt.Errorf("writing unit file: %w", err)
+	}
+	ui.Successf("Wrote %s\n", path)
+
+	systemctl := func(args ...string) error {
+		if service.User {
+			args = append([]string{"--user"}, args...)
+		}
+		ui.Verbosef("Running systemctl %v\n", args)
+		out, err := exec.Command("systemctl", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("systemctl %v: %w: %s", args, err, out)
+		}
+		return nil
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := systemctl("enable", "--now", service.UnitName()); err != nil {
+		return err
+	}
+
+	user := ""
+	if service.User {
+		user = "--user "
+	}
+	ui.Successf("Started %s\n", service.UnitName())
+	ui.Infof("Reload its config with: systemctl %sreload %s\n", user, service.UnitName())
+	ui.Infof("Follow its output with: journalctl %s-u %s -f\n", user, service.UnitName())
+	return nil
+}
+
+// installTask registers the service with Task Scheduler and starts it
+func installTask(service daemon.Service) error {
+	file, err := os.CreateTemp("", "rom-organizer-task-*.xml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(service.TaskXMLFile())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing task definition: %w", err)
+	}
+
+	for _, args := range [][]string{
+		{"/Create", "/F", "/TN", service.UnitName(), "/XML", file.Name()},
+		{"/Run", "/TN", service.UnitName()},
+	} {
+		out, err := exec.Command("schtasks", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("schtasks %s: %w: %s", args[0], err, out)
+		}
+	}
+	ui.Successf("Registered and started task %s\n", service.UnitName())
+	ui.Infof("Remove it with: schtasks /Delete /TN %s\n", service.UnitName())
+	return nil
+}
+
+func daemonStatusHandler(cmd *cobra.Command, args []string) error {
+	var statuses []*daemon.Status
+	var notRunning []string
+	if len(args) == 0 {
+		var err error
+		if statuses, err = daemon.ListStatus(); err != nil {
+			return err
+		}
+	}
+	for _, name := range args {
+		path := daemon.FindPID(name)
+		status, err := daemon.ReadStatus(path)
+		if os.IsNotExist(err) {
+			status = &daemon.Status{Name: name, PIDFile: path}
+		} else if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+		if !status.Running {
+			notRunning = append(notRunning, name)
+		}
+	}
+
+	if daemonJSON {
+		if statuses == nil {
+			statuses = []*daemon.Status{}
+		}
+		out, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+		fmt.Println(string(out))
+		return notRunningError(notRunning)
+	}
+
+	if len(statuses) == 0 {
+		ui.Infof("No daemons have a PID file in %s\n", strings.Join(daemon.PIDDirs(), " or "))
+	}
+	for _, status := range statuses {
+		switch {
+		case status.Running:
+			fmt.Printf("%-16s running  pid %-7d since %s\n", status.Name, status.PID, status.Since.Format("2006-01-02 15:04"))
+		case status.PID == 0:
+			fmt.Printf("%-16s stopped  (no PID file)\n", status.Name)
+		default:
+			fmt.Printf("%-16s stopped  (stale PID file for pid %d)\n", status.Name, status.PID)
+		}
+	}
+
+	return notRunningError(notRunning)
+}
+
+// notRunningError fails "daemon status" for named daemons that aren't running
+func notRunningError(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return fmt.Errorf("not running: %s", strings.Join(names, ", "))
+}
+
+// daemonRun gives a long-running command its service semantics: the --pid-file, a
+// config reload on SIGHUP, and a stop after the current unit of work on SIGINT or
+// SIGTERM (a second one exits at once)
+type daemonRun struct {
+	pid      *daemon.PIDFile
+	signals  chan os.Signal
+	reload   chan struct{}
+	stopping chan struct{}
+	cmd      *cobra.Command
+	args     []string
+}
+
+// startDaemon writes the PID file, if any, and starts handling signals until Close
+func startDaemon(cmd *cobra.Command, args []string, work string) (*daemonRun, error) {
+	d := &daemonRun{
+		signals:  make(chan os.Signal, 3),
+		reload:   make(chan struct{}, 1),
+		stopping: make(chan struct{}),
+		cmd:      cmd,
+		args:     args,
+	}
+	if pidFile != "" {
+		pid, err := daemon.WritePID(pidFile)
+		if err != nil {
+			return nil, err
+		}
+		d.pid = pid
+	}
+
+	signal.Notify(d.signals, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		stopped := false
+		for sig := range d.signals {
+			switch {
+			case sig == syscall.SIGHUP:
+				select {
+				case d.reload <- struct{}{}:
+				default:
+				}
+			case !stopped:
+				stopped = true
+				close(d.stopping)
+				ui.Warnf("Stopping after the current %s (interrupt again to exit now)\n", work)
+			default:
+				d.pid.Remove()
+				os.Exit(130)
+			}
+		}
+	}()
+	return d, nil
+}
+
+// Close stops handling signals and removes the PID file
+func (d *daemonRun) Close() {
+	signal.Stop(d.signals)
+	d.pid.Remove()
+}
+
+// Stopping reports whether a signal asked the daemon to stop
+func (d *daemonRun) Stopping() bool {
+	select {
+	case <-d.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait sleeps until the given time (forever if zero), a reload or a stop, and reports
+// which it was
+func (d *daemonRun) Wait(until time.Time) (reload, stop bool) {
+	var elapsed <-chan time.Time
+	if !until.IsZero() {
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+		elapsed = timer.C
+	}
+	select {
+	case <-elapsed:
+		return false, false
+	case <-d.reload:
+		return true, false
+	case <-d.stopping:
+		return false, true
+	}
+}
+
+// ReloadConfig re-reads the config file the way it was loaded at startup. If it can't
+// be loaded the previous settings are kept.
+func (d *daemonRun) ReloadConfig() bool {
+	previous, sevenZip, watchdog := appConfig, common.SevenZipPath, common.Watchdog
+	previousReadOnly, previousNetwork := readOnly, readOnlyNetwork
+	if err := loadConfig(d.cmd, d.args); err != nil {
+		appConfig, common.SevenZipPath, common.Watchdog = previous, sevenZip, watchdog
+		readOnly, readOnlyNetwork = previousReadOnly, previousNetwork
+		ui.Errorf("Reloading config: %v (keeping the previous settings)\n", err)
+		return false
+	}
+	ui.Infof("Reloaded config\n")
+	return true
+}
+
+// containsFlag reports whether args set a flag, as "--flag value" or "--flag=value"
+func containsFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
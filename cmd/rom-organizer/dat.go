@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/romset"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	datName        string
+	datClones      bool
+	setSplit       bool
+	setMerged      bool
+	setNonMerged   bool
+	setPolicyFlags = []string{"split", "merged", "non-merged"}
+)
+
+var datCmd = &cobra.Command{
+	Use:   "dat",
+	Short: "Import arcade and cartridge DAT files and their parent/clone sets",
+	Long: `Record the set lists of arcade and cartridge DAT files in the catalog: the ROMs of
+each set, and which sets are clones of which parent. DAT files are Logiqx XML, as
+published for MAME, FinalBurn Neo and No-Intro, or the output of mame -listxml.
+
+organize --split, --merged or --non-merged rebuilds sets from these lists.
+
+Examples:
+  rom-organizer dat import "MAME 0.262.dat"
+  rom-organizer dat list --clones
+  rom-organizer organize --non-merged --output /roms/mame /downloads/mame`,
+}
+
+var datImportCmd = &cobra.Command{
+	Use:   "import <file.dat>...",
+	Short: "Record the sets of DAT files, replacing earlier versions of the same DAT",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  datImportHandler,
+}
+
+var datListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List imported DAT files, or the parent sets of one with their clones",
+	Args:  cobra.NoArgs,
+	RunE:  datListHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(datCmd)
+	datCmd.AddCommand(datImportCmd, datListCmd)
+
+	datListCmd.Flags().StringVar(&datName, "dat", "", "DAT whose sets to list (default the only one imported)")
+	datListCmd.Flags().BoolVar(&datClones, "clones", false, "List the parent sets of a DAT and their clones")
+}
+
+func datImportHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, path := range args {
+		name, dat, err := catalog.ReadDAT(path)
+		if err != nil {
+			return err
+		}
+		parents := 0
+		for _, set := range dat.Sets {
+			if set.Parent == "" {
+				parents++
+			}
+		}
+		if c.SetDAT(name, dat) {
+			changed++
+		}
+		ui.Infof("%s %s: %d sets, %d parents and %d clones\n", name, dat.Version, len(dat.Sets), parents, len(dat.Sets)-parents)
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("Imported %d DAT files: %d added or updated\n", len(args), changed)
+	return nil
+}
+
+func datListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	if !datClones {
+		if len(c.DATs) == 0 {
+			ui.Infof("No DAT files imported\n")
+			return nil
+		}
+		names := make([]string, 0, len(c.DATs))
+		for name := range c.DATs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			dat := c.DATs[name]
+			fmt.Printf("%-30s %-12s %6d sets\n", name, dat.Version, len(dat.Sets))
+		}
+		return nil
+	}
+
+	dat, err := c.DAT(datName)
+	if err != nil {
+		return err
+	}
+	var parents []string
+	for name, set := range dat.Sets {
+		if set.Parent == "" {
+			parents = append(parents, name)
+		}
+	}
+	sort.Strings(parents)
+	for _, name := range parents {
+		fmt.Printf("%-16s %s\n", name, dat.Sets[name].Description)
+		for _, clone := range dat.Clones(name) {
+			fmt.Printf("  %-14s %s\n", clone, dat.Sets[clone].Description)
+		}
+	}
+	return nil
+}
+
+// setPolicy returns the set policy chosen with --split, --merged or --non-merged, or ""
+// when sources are organized as games
+func setPolicy() romset.Policy {
+	switch {
+	case setSplit:
+		return romset.Split
+	case setMerged:
+		return romset.Merged
+	case setNonMerged:
+		return romset.NonMerged
+	}
+	return ""
+}
+
+// organizeROMSets rebuilds the sets of the chosen DAT found in sources into the output
+// directory, one zip archive per set stored as policy says
+func organizeROMSets(sources []string, policy romset.Policy) error {
+	if len(outputDirs) > 1 {
+		return fmt.Errorf("--%s takes a single --output", policy)
+	}
+	if moveSource {
+		return fmt.Errorf("--%s cannot be combined with --move; sources are only read", policy)
+	}
+	output := outputDirs[0]
+	if err := checkWritable(output); err != nil {
+		return err
+	}
+
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	dat, err := c.DAT(datName)
+	if err != nil {
+		return err
+	}
+
+	index, err := romset.IndexSources(sources)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	written, existing, incomplete, failed := 0, 0, 0, 0
+	for _, result := range index.Rebuild(romset.Archives(dat, policy), output, force) {
+		switch {
+		case result.Err != nil:
+			ui.Errorf("%s: %v\n", result.Name, result.Err)
+			failed++
+		case len(result.Missing) > 0:
+			// Sets with none of their ROMs in the sources aren't being collected
+			if result.Found == 0 {
+				continue
+			}
+			ui.Warnf("%s: %d of %d ROMs missing\n", result.Name, len(result.Missing), result.Found+len(result.Missing))
+			for _, entry := range result.Missing {
+				ui.Verbosef("  %s (%s)\n", entry.Path, entry.ROM.CRC)
+			}
+			incomplete++
+		case result.Exists:
+			existing++
+		default:
+			ui.Infof("%s.zip\n", result.Name)
+			written++
+		}
+	}
+
+	ui.Successf("Wrote %d %s sets to %s (%d already there, %d incomplete and not written)\n", written, policy, output, existing, incomplete)
+	if failed > 0 {
+		return fmt.Errorf("%d sets could not be written", failed)
+	}
+	return nil
+}
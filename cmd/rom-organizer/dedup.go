@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/dedup"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+var dedupPoolDir string
+
+var dedupCmd = &cobra.Command{
+	Use:   "dedup",
+	Short: "Store files shared between decompressed games once",
+	Long: `Keep one copy of files that several decompressed games share (middleware,
+videos repeated across regional releases) in a content-addressed pool.
+
+Pooled game files are hard links to the pool objects, so games stay playable in
+place, and each game gets a game.manifest.json listing the objects it uses. The
+pool defaults to _pool in the library root and must be on the same file system.
+
+Pooled files are shared: run 'dedup remove' on a game before changing its files.`,
+}
+
+var dedupAddCmd = &cobra.Command{
+	Use:   "add <library|game-dir>...",
+	Short: "Link the decompressed games of libraries into the pool",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  dedupAddHandler,
+}
+
+var dedupRemoveCmd = &cobra.Command{
+	Use:   "remove <game-dir>...",
+	Short: "Give pooled games independent copies of their files again",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  dedupRemoveHandler,
+}
+
+var dedupGCCmd = &cobra.Command{
+	Use:   "gc <library>...",
+	Short: "Delete pool objects no game in the libraries uses",
+	Long: `Delete pool objects that no game manifest in the given libraries references.
+
+Pass every library that shares the pool, or objects still used elsewhere are deleted
+(the games keep their files, but they are no longer deduplicated).`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: dedupGCHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(dedupCmd)
+	dedupCmd.AddCommand(dedupAddCmd, dedupRemoveCmd, dedupGCCmd)
+
+	dedupCmd.PersistentFlags().StringVar(&dedupPoolDir, "pool", "", "Pool directory (default: _pool in the library root)")
+}
+
+// dedupPoolFor returns the pool used for a library, or for the library containing a game directory
+func dedupPoolFor(root string) (*dedup.Pool, error) {
+	if dedupPoolDir != "" {
+		return dedup.OpenPool(dedupPoolDir)
+	}
+	if info, err := common.DetectOrganizedDirectory(root, appConfig.Layout, false); err == nil && info.IsOrganized {
+		root = filepath.Dir(root)
+	}
+	return dedup.OpenPool(filepath.Join(root, dedup.DefaultPoolDir))
+}
+
+// dedupTargets returns the libraries and the --pool directory a pool command modifies
+func dedupTargets(roots []string) []string {
+	if dedupPoolDir != "" {
+		return append(roots, dedupPoolDir)
+	}
+	return roots
+}
+
+func dedupAddHandler(cmd *cobra.Command, args []string) error {
+	if err := checkWritable(dedupTargets(args)...); err != nil {
+		return err
+	}
+	if err := takeSnapshot("dedup", dedupTargets(args)...); err != nil {
+		return err
+	}
+	var total dedup.Stats
+	for _, root := range args {
+		games, err := library.FindGames(root, appConfig.Layout)
+		if err != nil {
+			return err
+		}
+		pool, err := dedupPoolFor(root)
+		if err != nil {
+			return err
+		}
+
+		for _, game := range games {
+			if !game.Info.HasDecompressed {
+				continue
+			}
+			stats, err := pool.AddGame(game.Path)
+			if err != nil {
+				return err
+			}
+			info := game.Info.GameInfo
+			fmt.Printf("%s [%s]: %d of %d files shared, saved %s\n", info.Title, info.GameID, stats.Shared, stats.Files, common.FormatSize(stats.Saved))
+			total.Files += stats.Files
+			total.Shared += stats.Shared
+			total.Saved += stats.Saved
+		}
+	}
+
+	fmt.Printf("\n%d of %d files shared, saved %s\n", total.Shared, total.Files, common.FormatSize(total.Saved))
+	return nil
+}
+
+func dedupRemoveHandler(cmd *cobra.Command, args []string) error {
+	if err := checkWritable(args...); err != nil {
+		return err
+	}
+	for _, dir := range args {
+		if !dedup.IsPooled(dir) {
+			return fmt.Errorf("%s is not pooled (no %s)", dir, dedup.ManifestName)
+		}
+		if err := dedup.RemoveGame(dir); err != nil {
+			return err
+		}
+		fmt.Printf("Unpooled %s\n", dir)
+	}
+	return nil
+}
+
+func dedupGCHandler(cmd *cobra.Command, args []string) error {
+	if err := checkWritable(dedupTargets(args)...); err != nil {
+		return err
+	}
+	if err := takeSnapshot("dedup gc", dedupTargets(args)...); err != nil {
+		return err
+	}
+	pool, err := dedupPoolFor(args[0])
+	if err != nil {
+		return err
+	}
+
+	var dirs []string
+	for _, root := range args {
+		games, err := library.FindGames(root, appConfig.Layout)
+		if err != nil {
+			return err
+		}
+		for _, game := range games {
+			dirs = append(dirs, game.Path)
+		}
+	}
+
+	removed, freed, err := pool.GC(dirs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d unused objects, freed %s\n", removed, common.FormatSize(freed))
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/delta"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+)
+
+var (
+	deltaForce  bool
+	deltaOutput string
+	deltaUpTo   string
+)
+
+var deltaCmd = &cobra.Command{
+	Use:   "delta",
+	Short: "Store changes to a compressed game without recompressing it",
+	Long: `Record a revised dump of a compressed game as a small delta archive next to its
+game.7z instead of recompressing everything.
+
+Deltas are kept in the game's _deltas folder and applied in order on top of game.7z.`,
+}
+
+var deltaCreateCmd = &cobra.Command{
+	Use:   "create <organized-dir> <source>",
+	Short: "Store the differences between a new source and the current game",
+	Long: `Compare a new source folder (e.g. a redump revision) with the game stored in an
+organized directory's game.7z and its deltas, and store the changed, added and removed
+files as a new delta.
+
+Examples:
+  rom-organizer delta create "/mnt/nas/ps3/Demon's Souls [BLUS30443]" /downloads/BLUS30443-rev2`,
+	Args: cobra.ExactArgs(2),
+	RunE: deltaCreateHandler,
+}
+
+var deltaListCmd = &cobra.Command{
+	Use:   "list <organized-dir>",
+	Short: "List the deltas of a compressed game",
+	Args:  cobra.ExactArgs(1),
+	RunE:  deltaListHandler,
+}
+
+var deltaMaterializeCmd = &cobra.Command{
+	Use:   "materialize <organized-dir> --output <dir>",
+	Short: "Extract the full game from game.7z and its deltas",
+	Long: `Extract game.7z into a folder and apply the game's deltas in order.
+
+Examples:
+  rom-organizer delta materialize --output /tmp/game "/mnt/nas/ps3/Demon's Souls [BLUS30443]"
+  rom-organizer delta materialize --upto 20240301-101500 --output /tmp/game "/mnt/nas/ps3/Demon's Souls [BLUS30443]"`,
+	Args: cobra.ExactArgs(1),
+	RunE: deltaMaterializeHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(deltaCmd)
+	deltaCmd.AddCommand(deltaCreateCmd, deltaListCmd, deltaMaterializeCmd)
+
+	deltaCreateCmd.Flags().BoolVarP(&deltaForce, "force", "f", false, "Create the delta even if the source has a different game ID")
+	deltaMaterializeCmd.Flags().StringVarP(&deltaOutput, "output", "o", "", "Folder to extract the game into (must not exist)")
+	deltaMaterializeCmd.Flags().StringVar(&deltaUpTo, "upto", "", "Stop after this delta instead of applying all of them")
+	deltaMaterializeCmd.MarkFlagRequired("output")
+}
+
+// compressedGameInfo returns the game information of an organized directory with a game.7z
+func compressedGameInfo(gameDir string) (*common.GameInfo, error) {
+	info, err := common.DetectOrganizedDirectory(gameDir, appConfig.Layout, false)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsOrganized || !info.HasCompressed {
+		return nil, fmt.Errorf("%s is not an organized game directory with a game.7z", gameDir)
+	}
+	return info.GameInfo, nil
+}
+
+func deltaCreateHandler(cmd *cobra.Command, args []string) error {
+	gameDir, source := args[0], args[1]
+	if err := checkWritable(gameDir); err != nil {
+		return err
+	}
+	gameInfo, err := compressedGameInfo(gameDir)
+	if err != nil {
+		return err
+	}
+
+	// Find the game root inside the source, as it was packed into game.7z
+	detection, err := detect.DetectConsole(source)
+	if err != nil {
+		return fmt.Errorf("detecting console type: %w", err)
+	}
+	if detection.ConsoleType == detect.Unknown {
+		return fmt.Errorf("%w for: %s", common.ErrNotDetected, source)
+	}
+	handler, err := consoles.NewRegistry().GetHandler(detection.ConsoleType)
+	if err != nil {
+		return fmt.Errorf("getting console handler: %w", err)
+	}
+	sourceInfo, err := handler.ExtractGameInfo(detection.GamePath, verbose)
+	if err != nil {
+		return fmt.Errorf("extracting game info: %w", err)
+	}
+	if sourceInfo.GameID != gameInfo.GameID && !deltaForce {
+		return fmt.Errorf("source is %s but the organized game is %s (use --force to create the delta anyway)", sourceInfo.GameID, gameInfo.GameID)
+	}
+
+	d, err := delta.Create(gameDir, sourceInfo.Source, appConfig.Compression.ArchiveOptions(detection.ConsoleType))
+	if errors.Is(err, delta.ErrNoChanges) {
+		fmt.Printf("%s is unchanged, no delta created\n", gameLabelFor(gameInfo))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	size := "no archive"
+	if info, err := os.Stat(d.ArchivePath(gameDir)); err == nil {
+		size = common.FormatSize(info.Size())
+	}
+	fmt.Printf("Created delta %s for %s: %d changed, %d added, %d removed (%s)\n",
+		d.Name, gameLabelFor(gameInfo), len(d.Changed), len(d.Added), len(d.Removed), size)
+	return nil
+}
+
+func deltaListHandler(cmd *cobra.Command, args []string) error {
+	if _, err := compressedGameInfo(args[0]); err != nil {
+		return err
+	}
+	deltas, err := delta.List(args[0])
+	if err != nil {
+		return err
+	}
+	if len(deltas) == 0 {
+		fmt.Println("No deltas")
+		return nil
+	}
+
+	for _, d := range deltas {
+		fmt.Printf("%s  %d changed, %d added, %d removed\n", d.Name, len(d.Changed), len(d.Added), len(d.Removed))
+		if verbose {
+			for _, path := range d.Changed {
+				fmt.Printf("  M %s\n", path)
+			}
+			for _, path := range d.Added {
+				fmt.Printf("  A %s\n", path)
+			}
+			for _, path := range d.Removed {
+				fmt.Printf("  D %s\n", path)
+			}
+		}
+	}
+	return nil
+}
+
+func deltaMaterializeHandler(cmd *cobra.Command, args []string) error {
+	gameInfo, err := compressedGameInfo(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(deltaOutput); err == nil {
+		return fmt.Errorf("%w: %s", common.ErrTargetExists, deltaOutput)
+	}
+
+	if err := delta.Materialize(args[0], deltaOutput, deltaUpTo); err != nil {
+		os.RemoveAll(deltaOutput)
+		return err
+	}
+	abs, _ := filepath.Abs(deltaOutput)
+	fmt.Printf("Materialized %s into %s\n", gameLabelFor(gameInfo), abs)
+	return nil
+}
+
+// gameLabelFor formats a game as "Title [ID]"
+func gameLabelFor(info *common.GameInfo) string {
+	return fmt.Sprintf("%s [%s]", info.Title, info.GameID)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/devtools"
+)
+
+var (
+	genOutputDir string
+	genCount     int
+	genSeed      int64
+	genClean     bool
+	genConsoles  []string
+	genFileSize  string
+	genNested    bool
+	genZip       bool
+)
+
+var devtoolsCmd = &cobra.Command{
+	Use:    "devtools",
+	Short:  "Development and testing utilities",
+	Hidden: true,
+}
+
+var generateGamesCmd = &cobra.Command{
+	Use:   "generate-games",
+	Short: "Generate fake games for testing",
+	Long: `Generate completely fictional games for testing rom-organizer.
+
+Games are generated for every registered console handler unless --console is
+given. Use --file-size to add random filler data, --nested to wrap each game in
+extra parent folders, and --zip to wrap each game in a .zip archive.
+
+Examples:
+  rom-organizer devtools generate-games --count 5 --output test-games
+  rom-organizer devtools generate-games --seed 12345 --file-size 10MB --zip
+  rom-organizer devtools generate-games --console ps3 --nested --clean`,
+	Args: cobra.NoArgs,
+	RunE: generateGamesHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(devtoolsCmd)
+	devtoolsCmd.AddCommand(generateGamesCmd)
+
+	generateGamesCmd.Flags().StringVarP(&genOutputDir, "output", "o", "test-games", "Output directory for test games")
+	generateGamesCmd.Flags().IntVarP(&genCount, "count", "n", 5, "Number of test games to generate per console")
+	generateGamesCmd.Flags().Int64Var(&genSeed, "seed", 0, "Random seed (0 for current time)")
+	generateGamesCmd.Flags().BoolVar(&genClean, "clean", false, "Clean output directory before generating")
+	generateGamesCmd.Flags().StringSliceVar(&genConsoles, "console", nil, "Only generate games for these consoles (e.g. ps3)")
+	generateGamesCmd.Flags().StringVar(&genFileSize, "file-size", "", "Size of random filler data per game (e.g. 512KB, 10MB)")
+	generateGamesCmd.Flags().BoolVar(&genNested, "nested", false, "Wrap each game in extra parent folders")
+	generateGamesCmd.Flags().BoolVar(&genZip, "zip", false, "Wrap each game in a .zip archive")
+}
+
+func generateGamesHandler(cmd *cobra.Command, args []string) error {
+	opts := devtools.GenerateOptions{
+		OutputDir: genOutputDir,
+		Count:     genCount,
+		Seed:      genSeed,
+		Clean:     genClean,
+		Nested:    genNested,
+		Zip:       genZip,
+	}
+
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	if genFileSize != "" {
+		size, err := common.ParseSize(genFileSize)
+		if err != nil {
+			return err
+		}
+		opts.FileSize = size
+	}
+
+	for _, name := range genConsoles {
+		console, err := detect.ParseConsoleType(name)
+		if err != nil {
+			return err
+		}
+		opts.Consoles = append(opts.Consoles, console)
+	}
+
+	fmt.Printf("Using random seed: %d\n", opts.Seed)
+	fmt.Printf("Generating test games in %s\n", opts.OutputDir)
+	fmt.Println("==================================================")
+
+	created, err := devtools.GenerateGames(opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("==================================================")
+	fmt.Printf("Generated %d test games in: %s\n", len(created), opts.OutputDir)
+	return nil
+}
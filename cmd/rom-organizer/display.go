@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	displayResolution  string
+	displayAudio       string
+	displaySupported   bool
+	displayUnsupported bool
+	displayFilter      catalog.Filter
+	displaySort        string
+)
+
+var displayCmd = &cobra.Command{
+	Use:   "display <library> [library...]",
+	Short: "Show which games support a display and audio setup",
+	Long: `Show the video modes and audio formats of organized games, read from the
+RESOLUTION and SOUND_FORMAT entries of their PARAM.SFO, and whether each game
+supports your setup.
+
+--resolution is the mode your display takes (480p, 576p, 720p or 1080p) and
+--audio your speakers (stereo, 5.1 or 7.1). PARAM.SFO is read from game.7z
+without extracting it.
+
+Examples:
+  rom-organizer display --resolution 720p /mnt/nas/ps3
+  rom-organizer display --resolution 720p --audio stereo --unsupported /mnt/nas/ps3
+  rom-organizer display --audio 5.1 --supported --tag favorites /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: displayHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(displayCmd)
+	displayCmd.Flags().StringVar(&displayResolution, "resolution", "", "Display resolution to check: 480p, 576p, 720p or 1080p")
+	displayCmd.Flags().StringVar(&displayAudio, "audio", "", "Audio setup to check: stereo, 5.1 or 7.1")
+	displayCmd.Flags().BoolVar(&displaySupported, "supported", false, "Only list games that support the setup")
+	displayCmd.Flags().BoolVar(&displayUnsupported, "unsupported", false, "Only list games that don't support the setup")
+	addCatalogFilterFlags(displayCmd, &displayFilter)
+	addSortFlag(displayCmd, &displaySort)
+}
+
+func displayHandler(cmd *cobra.Command, args []string) error {
+	setup, err := library.ParseDisplaySetup(displayResolution, displayAudio)
+	if err != nil {
+		return err
+	}
+	if displaySupported && displayUnsupported {
+		return fmt.Errorf("--supported and --unsupported cannot be combined")
+	}
+
+	games, _, err := findFilteredGames(args, displayFilter)
+	if err != nil {
+		return err
+	}
+	if err := sortGames(games, displaySort); err != nil {
+		return err
+	}
+
+	supported, unreadable := 0, 0
+	reports := library.CheckDisplay(games, setup)
+	for _, report := range reports {
+		name := fmt.Sprintf("%s [%s]", report.Info.GameInfo.Title, report.Info.GameInfo.GameID)
+		if report.Err != nil {
+			ui.Warnf("%s: %v\n", name, report.Err)
+			unreadable++
+			continue
+		}
+		if report.Supported {
+			supported++
+		}
+		if (displaySupported && !report.Supported) || (displayUnsupported && report.Supported) {
+			continue
+		}
+
+		status := "   "
+		if setup != (library.DisplaySetup{}) {
+			status = "no "
+			if report.Supported {
+				status = "yes"
+			}
+		}
+		fmt.Printf("%s %-50s %-28s %s\n", status, name, joinOrNone(report.Resolutions), joinOrNone(report.SoundFormats))
+	}
+
+	if setup != (library.DisplaySetup{}) {
+		ui.Infof("\n%d of %d game(s) support %s\n", supported, len(reports)-unreadable, describeSetup(setup))
+	}
+	return nil
+}
+
+// describeSetup names a display setup for the summary, e.g. "720p with stereo audio"
+func describeSetup(setup library.DisplaySetup) string {
+	switch {
+	case setup.Audio == "":
+		return setup.Resolution
+	case setup.Resolution == "":
+		return setup.Audio + " audio"
+	}
+	return setup.Resolution + " with " + setup.Audio + " audio"
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "(none listed)"
+	}
+	return strings.Join(items, ", ")
+}
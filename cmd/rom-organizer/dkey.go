@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	dkeyTitleID string
+	dkeyOutput  string
+)
+
+// dkeyTitleIDPattern finds a title ID such as BLUS30001 or BLUS-30001 in a file name
+var dkeyTitleIDPattern = regexp.MustCompile(`\b([A-Z]{4})-?(\d{5})\b`)
+
+var dkeyCmd = &cobra.Command{
+	Use:   "dkey",
+	Short: "Manage PS3 disc keys for encrypted disc images",
+	Long: `Store PS3 disc keys (.dkey files, as published by Redump) in the catalog keyed by
+title ID, check which encrypted disc images can be decrypted, and decrypt them.
+
+Examples:
+  rom-organizer dkey import keys/*.dkey
+  rom-organizer dkey import --id BLUS30001 "Game (USA).dkey"
+  rom-organizer dkey check /isos/*.iso
+  rom-organizer dkey decrypt "/isos/Game (USA).iso"`,
+}
+
+var dkeyImportCmd = &cobra.Command{
+	Use:   "import <file.dkey|file.ird|dir>...",
+	Short: "Store disc keys in the catalog",
+	Long: `Store disc keys in the catalog. The title ID of each .dkey comes from --id, a title ID
+in the file name, or the disc image with the same name next to the .dkey file.
+IRD files carry both the title ID and the data the disc key is derived from.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: dkeyImportHandler,
+}
+
+var dkeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored disc keys",
+	Args:  cobra.NoArgs,
+	RunE:  dkeyListHandler,
+}
+
+var dkeyCheckCmd = &cobra.Command{
+	Use:   "check <image.iso>...",
+	Short: "Report which encrypted disc images can be decrypted",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  dkeyCheckHandler,
+}
+
+var dkeyDecryptCmd = &cobra.Command{
+	Use:   "decrypt <image.iso>",
+	Short: "Write a decrypted copy of a disc image using its stored key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  dkeyDecryptHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(dkeyCmd)
+	dkeyCmd.AddCommand(dkeyImportCmd, dkeyListCmd, dkeyCheckCmd, dkeyDecryptCmd)
+
+	dkeyImportCmd.Flags().StringVar(&dkeyTitleID, "id", "", "Title ID of the key (when importing a single file)")
+	dkeyDecryptCmd.Flags().StringVarP(&dkeyOutput, "output", "o", "", "Decrypted image path (default <image>.dec.iso)")
+}
+
+// dkeyFiles expands directories into the .dkey files they contain
+func dkeyFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".dkey" || ext == ".ird") {
+				files = append(files, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// dkeyTitleIDFor works out the title ID a .dkey file belongs to
+func dkeyTitleIDFor(path string) (string, error) {
+	if dkeyTitleID != "" {
+		return strings.ToUpper(strings.ReplaceAll(dkeyTitleID, "-", "")), nil
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if m := dkeyTitleIDPattern.FindStringSubmatch(base); m != nil {
+		return m[1] + m[2], nil
+	}
+
+	for _, ext := range []string{".iso", ".ISO"} {
+		iso := strings.TrimSuffix(path, filepath.Ext(path)) + ext
+		if _, err := os.Stat(iso); err == nil {
+			disc, err := consoles.OpenPS3ISO(iso)
+			if err != nil {
+				return "", err
+			}
+			return disc.TitleID, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: cannot tell the title ID (use --id or put the disc image next to it)", path)
+}
+
+// readDiscKeyFile reads the title ID and disc key from a .dkey file, or derives
+// them from an IRD file
+func readDiscKeyFile(path string) (string, []byte, error) {
+	if strings.EqualFold(filepath.Ext(path), ".ird") {
+		ird, err := loadIRD(path)
+		if err != nil {
+			return "", nil, err
+		}
+		return ird.ProductCode, consoles.DiscKeyFromIRD(ird), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading disc key: %w", err)
+	}
+	key, err := consoles.ParseDiscKey(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	titleID, err := dkeyTitleIDFor(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return titleID, key, nil
+}
+
+// lookupDiscKey finds the key for a disc image being organized: a .dkey or .ird
+// file next to the image first, then the keys imported into the catalog
+func lookupDiscKey(imagePath, titleID string) ([]byte, error) {
+	base := strings.TrimSuffix(imagePath, filepath.Ext(imagePath))
+	for _, sidecar := range []string{base + ".dkey", base + ".ird"} {
+		if _, err := os.Stat(sidecar); err != nil {
+			continue
+		}
+		_, key, err := readDiscKeyFile(sidecar)
+		if err != nil {
+			return nil, err
+		}
+		ui.Verbosef("Using disc key from %s\n", sidecar)
+		return key, nil
+	}
+
+	c, err := openCatalog()
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := c.DiscKey(titleID); ok {
+		return consoles.ParseDiscKey([]byte(key))
+	}
+	return nil, nil
+}
+
+func dkeyImportHandler(cmd *cobra.Command, args []string) error {
+	files, err := dkeyFiles(args)
+	if err != nil {
+		return err
+	}
+	if dkeyTitleID != "" && len(files) != 1 {
+		return fmt.Errorf("--id can only be used when importing a single key")
+	}
+
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	imported := 0
+	for _, file := range files {
+		titleID, key, err := readDiscKeyFile(file)
+		if err != nil {
+			ui.Warnf("skipping %v\n", err)
+			continue
+		}
+
+		c.SetDiscKey(titleID, hex.EncodeToString(key))
+		ui.Verbosef("Imported key for %s from %s\n", titleID, file)
+		imported++
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("Imported %d disc keys\n", imported)
+	return nil
+}
+
+func dkeyListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(c.DiscKeys))
+	for id := range c.DiscKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Printf("%-50s %s\n", gameLabel(c, id), c.DiscKeys[id])
+	}
+	return nil
+}
+
+func dkeyCheckHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	missing, failed := 0, 0
+	for _, path := range args {
+		titleID, status, err := dkeyStatus(c, path)
+		if err != nil {
+			ui.Errorf("%v\n", err)
+			failed++
+			continue
+		}
+		if status == "NO KEY" {
+			missing++
+		}
+		fmt.Printf("%-10s %-14s %s\n", titleID, status, path)
+	}
+
+	if missing > 0 {
+		ui.Warnf("%d encrypted images have no disc key (see dkey import)\n", missing)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d ISOs failed", failed, len(args))
+	}
+	return nil
+}
+
+// dkeyStatus reads a disc image's title ID and reports whether it's encrypted and, if so,
+// whether the stored key decrypts it
+func dkeyStatus(c *catalog.Catalog, path string) (titleID, status string, err error) {
+	disc, err := consoles.OpenPS3ISO(path)
+	if err != nil {
+		return "", "", err
+	}
+	if !disc.IsEncrypted() {
+		return disc.TitleID, "not encrypted", nil
+	}
+	hexKey, ok := c.DiscKey(disc.TitleID)
+	if !ok {
+		return disc.TitleID, "NO KEY", nil
+	}
+
+	key, err := consoles.ParseDiscKey([]byte(hexKey))
+	if err != nil {
+		return "", "", fmt.Errorf("stored key for %s: %w", disc.TitleID, err)
+	}
+	files, err := disc.Files()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	ok, err = disc.CheckKey(files, key)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	if !ok {
+		return "", "", fmt.Errorf("disc key for %s does not decrypt %s", disc.TitleID, path)
+	}
+	return disc.TitleID, "decryptable", nil
+}
+
+func dkeyDecryptHandler(cmd *cobra.Command, args []string) error {
+	disc, err := consoles.OpenPS3ISO(args[0])
+	if err != nil {
+		return err
+	}
+	if !disc.IsEncrypted() {
+		return fmt.Errorf("%s is not encrypted", args[0])
+	}
+
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	hexKey, ok := c.DiscKey(disc.TitleID)
+	if !ok {
+		return fmt.Errorf("no disc key for %s (see dkey import)", disc.TitleID)
+	}
+	key, err := consoles.ParseDiscKey([]byte(hexKey))
+	if err != nil {
+		return fmt.Errorf("stored key for %s: %w", disc.TitleID, err)
+	}
+
+	output := dkeyOutput
+	if output == "" {
+		output = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + ".dec.iso"
+	}
+
+	ui.Infof("Decrypting %s [%s]...\n", filepath.Base(args[0]), disc.TitleID)
+	if err := disc.Decrypt(output, key); err != nil {
+		return err
+	}
+	ui.Successf("Wrote %s\n", output)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/config"
+	"github.com/NeilGraham/rom-organizer/internal/doctor"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var doctorMinFree string
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [directory...]",
+	Short: "Check the environment for problems",
+	Long: `Check that rom-organizer can do its work and print how to fix what's missing:
+
+- external tools (7z and which formats it reads, par2, chdman, zstd) and their versions
+- the config file (--config, or the default location)
+- write access and free space in the temporary directory and in each given
+  directory, such as your output libraries
+
+Exits with an error when a check fails; warnings don't change the exit code.
+
+Examples:
+  rom-organizer doctor
+  rom-organizer doctor /mnt/nas/ps3 --min-free 100GB`,
+	// The config is checked like everything else instead of stopping the command
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return setupOutput() },
+	RunE:              doctorHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVar(&doctorMinFree, "min-free", "50GB", "Warn when a directory has less free space than this")
+}
+
+func doctorHandler(cmd *cobra.Command, args []string) error {
+	minFree, err := common.ParseSize(doctorMinFree)
+	if err != nil {
+		return fmt.Errorf("--min-free: %w", err)
+	}
+
+	// The config wasn't loaded before the command, so apply its 7z path here
+	common.SevenZipPath = sevenZipPath
+	if cfg, err := config.Load(configPath); err == nil && sevenZipPath == "" {
+		common.SevenZipPath = cfg.Compression.SevenZip
+	}
+
+	checks := doctor.CheckTools()
+	checks = append(checks, doctor.CheckConfig(configPath))
+	checks = append(checks, doctor.CheckDir("temp dir", os.TempDir(), minFree)...)
+	for _, dir := range args {
+		checks = append(checks, doctor.CheckDir(dir, dir, minFree)...)
+	}
+
+	failed, warned := 0, 0
+	for _, check := range checks {
+		switch check.Status {
+		case doctor.Fail:
+			failed++
+			fmt.Printf("[FAIL] %s: %s\n", check.Name, check.Detail)
+		case doctor.Warn:
+			warned++
+			fmt.Printf("[WARN] %s: %s\n", check.Name, check.Detail)
+		default:
+			fmt.Printf("[ OK ] %s: %s\n", check.Name, check.Detail)
+		}
+		if check.Fix != "" {
+			fmt.Printf("       fix: %s\n", check.Fix)
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed, %d warning(s)", failed, warned)
+	}
+	if warned > 0 {
+		ui.Warnf("%d warning(s)\n", warned)
+		return nil
+	}
+	ui.Successf("Everything looks good\n")
+	return nil
+}
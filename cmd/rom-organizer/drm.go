@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// isEDATFile reports whether path names an EDAT or SDAT file
+func isEDATFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".edat" || ext == ".sdat"
+}
+
+// handleEDATMetadata prints the header of a single EDAT/SDAT file
+func handleEDATMetadata(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	header, err := parsers.ParseEDAT(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if jsonOutput {
+		out, err := json.MarshalIndent(drmFileJSON(consoles.PS3DRMFile{Path: path, Header: header}), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	kind := "EDAT"
+	if header.IsSDAT() {
+		kind = "SDAT"
+	}
+	fmt.Printf("File Type:   PlayStation 3 %s (NPD version %d)\n", kind, header.Version)
+	fmt.Printf("Content ID:  %s\n", header.ContentID)
+	if titleID := header.TitleID(); titleID != "" {
+		fmt.Printf("Game ID:     %s\n", titleID)
+	}
+	fmt.Printf("License:     %s\n", header.LicenseName())
+	fmt.Printf("Data Size:   %d bytes\n", header.FileSize)
+	return nil
+}
+
+// printDRMFiles lists EDAT/SDAT files found in a game with their license status
+func printDRMFiles(files []consoles.PS3DRMFile, problems []error) {
+	if len(files) == 0 && len(problems) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Encrypted Content:")
+	fmt.Println("==================")
+	missing := 0
+	for _, file := range files {
+		status := "no license needed"
+		switch {
+		case file.License != "":
+			status = "license: " + filepath.Base(file.License)
+		case file.LicenseMissing():
+			status = "LICENSE MISSING"
+			missing++
+		}
+		fmt.Printf("%-40s %-8s %s\n", file.Header.ContentID, file.Header.LicenseName(), status)
+		if verbose {
+			fmt.Printf("  %s\n", file.Path)
+		}
+	}
+	for _, problem := range problems {
+		ui.Warnf("unreadable EDAT/SDAT: %v\n", problem)
+	}
+	if missing > 0 {
+		ui.Warnf("%d EDAT files have no matching .rap/.rif license (see --licenses)\n", missing)
+	}
+}
+
+// drmFileJSON returns the JSON form of an EDAT/SDAT file
+func drmFileJSON(file consoles.PS3DRMFile) map[string]interface{} {
+	return map[string]interface{}{
+		"path":         file.Path,
+		"contentId":    file.Header.ContentID,
+		"gameId":       file.Header.TitleID(),
+		"sdat":         file.Header.IsSDAT(),
+		"license":      file.Header.LicenseName(),
+		"licenseFile":  file.License,
+		"needsLicense": file.Header.NeedsLicense(),
+	}
+}
+
+// drmFilesJSON encodes EDAT/SDAT files as an indented JSON array nested one level deep
+func drmFilesJSON(files []consoles.PS3DRMFile) string {
+	list := make([]map[string]interface{}, 0, len(files))
+	for _, file := range files {
+		list = append(list, drmFileJSON(file))
+	}
+	out, _ := json.MarshalIndent(list, "  ", "  ")
+	return string(out)
+}
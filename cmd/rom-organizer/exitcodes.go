@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+)
+
+// Exit codes returned by rom-organizer so scripts can branch on what went wrong
+const (
+	ExitOK             = 0   // Everything succeeded
+	ExitError          = 1   // Any failure without a more specific code (bad flags, I/O errors, ...)
+	ExitNotDetected    = 2   // No supported game was detected in a source
+	ExitTargetExists   = 3   // An output directory already exists and --force was not given, or holds a different game
+	ExitToolNotFound   = 4   // A required tool (7z, par2) is not installed
+	ExitPartialFailure = 5   // Some games in a batch failed, or failed for different reasons
+	ExitBatchAborted   = 6   // The batch stopped early because of --fail-fast or --max-errors
+	ExitReadOnly       = 7   // The command would have modified a library in read-only mode
+	ExitInterrupted    = 130 // Stopped by SIGINT or SIGTERM (128 + SIGINT, as shells report it)
+)
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var batch *organizer.BatchError
+	if !errors.As(err, &batch) {
+		return categoryCode(err)
+	}
+	if batch.Interrupted {
+		return ExitInterrupted
+	}
+
+	if batch.Skipped > 0 {
+		return ExitBatchAborted
+	}
+	if batch.Succeeded > 0 || batch.MirrorFailures > 0 || len(batch.Errs) == 0 {
+		return ExitPartialFailure
+	}
+
+	// Every game failed: report the shared category if there is one
+	code := categoryCode(batch.Errs[0])
+	for _, gameErr := range batch.Errs[1:] {
+		if categoryCode(gameErr) != code {
+			return ExitPartialFailure
+		}
+	}
+	return code
+}
+
+// categoryCode returns the exit code for a single failure
+func categoryCode(err error) int {
+	switch {
+	case errors.Is(err, common.ErrToolNotFound):
+		return ExitToolNotFound
+	case errors.Is(err, common.ErrNotDetected):
+		return ExitNotDetected
+	case errors.Is(err, common.ErrTargetExists):
+		return ExitTargetExists
+	case errors.Is(err, common.ErrReadOnly):
+		return ExitReadOnly
+	case errors.Is(err, common.ErrInterrupted):
+		return ExitInterrupted
+	default:
+		return ExitError
+	}
+}
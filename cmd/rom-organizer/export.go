@@ -0,0 +1,617 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/export"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	exportTo          string
+	exportLicenseDirs []string
+	exportForce       bool
+	exportDryRun      bool
+	exportFilter      catalog.Filter
+	exportSize        string
+	exportPrefix      string
+	exportInstalled   string
+	exportFormat      string
+	exportEmulator    string
+	exportEmuArgs     string
+	exportEncrypt     []string
+	exportIdentities  []string
+	exportTitle       string
+	exportSort        string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Copy organized games into layouts used by consoles and other tools",
+}
+
+var exportPKGLayoutCmd = &cobra.Command{
+	Use:   "pkg-layout <library|game-dir>... --to <usb-root>",
+	Short: "Copy update and DLC packages and their licenses for the PS3 HEN package manager",
+	Long: `Copy the .pkg files in the _updates and _dlc folders of organized games to
+packages/ on a USB stick (or any folder), and their .rap/.rif licenses to exdata/,
+the layout the package manager of PS3 HEN installs from.
+
+Licenses are taken from the game's _updates and _dlc folders, and from --licenses
+folders when named after the content ID of an exported package. Files already on
+the stick with the same size are skipped, so an export can be repeated.
+
+Update packages are only copied when they are newer than the game's own APP_VER,
+and with --installed, than the version installed on a console (ftp://host) or in
+RPCS3 (its dev_hdd0 folder or the folder containing it).
+
+Examples:
+  rom-organizer export pkg-layout "/mnt/nas/ps3/Game [BLUS30001]" --to /media/usb
+  rom-organizer export pkg-layout /mnt/nas/ps3 --collection party --to /media/usb
+  rom-organizer export pkg-layout /mnt/nas/ps3 --tag dlc --licenses ~/raps --to /media/usb -n
+  rom-organizer export pkg-layout /mnt/nas/ps3 --installed ftp://192.168.1.20 --to /media/usb`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportPKGLayoutHandler,
+}
+
+var exportSplitCmd = &cobra.Command{
+	Use:   "split <library>... --size <size> --to <dir>",
+	Short: "Group organized games into numbered folders that each fit on one backup disc or tape",
+	Long: `Copy organized games into numbered folders (backup-01, backup-02, ...) that each fit
+on one disc or tape, for burning or writing to archive media. Games are never split
+across folders; a game larger than the media is reported and left out.
+
+--size takes a size (binary units: 25GB is 25 GiB) or one of the media names dvd,
+dvd-dl, bd25, bd50, bd100, lto5 and lto6. Every file is counted in whole 2 KiB sectors
+and 32 MiB of each folder is kept free for the file system, so a folder always fits.
+Each folder gets a manifest.json listing its games, sizes and its number in the set.
+
+Examples:
+  rom-organizer export split /mnt/nas/ps3 --size bd25 --to /mnt/staging -n
+  rom-organizer export split /mnt/nas/ps3 --size bd50 --to /mnt/staging --prefix ps3-2024
+  rom-organizer export split /mnt/nas/ps3 --collection finished --size 100GB --to /mnt/tape`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportSplitHandler,
+}
+
+var exportShortcutsCmd = &cobra.Command{
+	Use:   "shortcuts <library>... --to <dir|shortcuts.vdf>",
+	Short: "Create desktop, Windows or Steam shortcuts that launch games in an emulator",
+	Long: `Create a shortcut for each organized game that starts it in RPCS3, or another
+emulator, with the path of its EBOOT.BIN:
+
+  desktop  a .desktop entry per game in --to (e.g. ~/.local/share/applications),
+           with the game's ICON0.PNG and an "Open Manual" action when it has manuals
+  lnk      a Windows .lnk shortcut per game in --to
+  steam    non-Steam game entries in the shortcuts.vdf given by --to, replacing those
+           added by an earlier export and keeping the others (close Steam first; the
+           previous file is kept as shortcuts.vdf.bak)
+
+--emulator-args is split on spaces, then {eboot}, {game}, {title} and {id} are
+replaced by the EBOOT.BIN path, the organized game folder, the title and the game ID.
+Games only kept as game.7z can't be launched and are skipped. Paths are those seen
+from this machine, so create shortcuts on the machine that will use them.
+
+Examples:
+  rom-organizer export shortcuts /mnt/nas/ps3 --to ~/.local/share/applications
+  rom-organizer export shortcuts D:\PS3 --format lnk --emulator C:\RPCS3\rpcs3.exe --to %USERPROFILE%\Desktop
+  rom-organizer export shortcuts /mnt/nas/ps3 --format steam --to ~/.steam/steam/userdata/12345/config/shortcuts.vdf`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportShortcutsHandler,
+}
+
+var exportBackupCmd = &cobra.Command{
+	Use:   "backup <library|game-dir>... --encrypt age:<recipient> --to <dir>",
+	Short: "Write encrypted backups of organized games for offsite storage",
+	Long: `Write each organized game folder (game.7z or game/, updates, DLC and the other
+files in it) as one encrypted file in --to, named after the folder:
+"Title [ID].tar.age" or "Title [ID].tar.gpg". The folder is streamed through the
+encryption tool, so nothing unencrypted is written to --to.
+
+--encrypt takes age:<recipient> (an age public key, or a recipients file) or
+gpg:<recipient> (a key ID or e-mail in the gpg keyring) and can be repeated to
+encrypt to several recipients of the same tool. age or gpg must be installed.
+
+Restore backups with "export restore".
+
+Examples:
+  rom-organizer export backup /mnt/nas/ps3 --encrypt age:age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p --to /mnt/offsite
+  rom-organizer export backup /mnt/nas/ps3 --collection finished --encrypt age:~/backup-recipients.txt --to /mnt/offsite
+  rom-organizer export backup "/mnt/nas/ps3/Game [BLUS30001]" --encrypt gpg:me@example.org --to /mnt/offsite`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportBackupHandler,
+}
+
+var exportRestoreCmd = &cobra.Command{
+	Use:   "restore <backup-file|dir>... --to <library>",
+	Short: "Decrypt backups written by export backup into a library",
+	Long: `Decrypt backups written by "export backup" and restore the game folders they hold
+into the library given by --to. A directory argument restores every .tar.age and
+.tar.gpg file in it.
+
+age backups need the identity file holding the private key (--identity, repeatable);
+gpg finds the key in its keyring and may ask for its passphrase. Each game is
+unpacked next to the library first and only moved into place once it decrypted
+completely. An existing game folder is only replaced with --force.
+
+Examples:
+  rom-organizer export restore /mnt/offsite --identity ~/.config/age/key.txt --to /mnt/nas/ps3
+  rom-organizer export restore "/mnt/offsite/Game [BLUS30001].tar.gpg" --to /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportRestoreHandler,
+}
+
+var exportHTMLCmd = &cobra.Command{
+	Use:   "html <library>... --to <dir>",
+	Short: "Write a static HTML gallery of organized games for sharing a library overview",
+	Long: `Write a static site to --to with a cover grid of the organized games, a search box
+and filters by console, region and catalog tag, and a page per game with its ID,
+region, version, format, size, tags, RPCS3 status and artwork.
+
+Covers come from each game's _artwork folder (an image named cover or front, else the
+first image in it), falling back to the game's ICON0.PNG. The other _artwork images are
+shown on the game's page. Images are copied into the site and no scripts or styles are
+loaded from elsewhere, so the folder can be opened from disk, zipped or put on any web
+server as is. Exporting again replaces the previous gallery.
+
+Examples:
+  rom-organizer export html /mnt/nas/ps3 --to ~/ps3-gallery
+  rom-organizer export html /mnt/nas/ps3 --collection finished --title "Finished games" --to /var/www/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportHTMLHandler,
+}
+
+var exportNFOCmd = &cobra.Command{
+	Use:   "nfo <library|game-dir>...",
+	Short: "Write Kodi NFO files and folder.jpg thumbnails into organized game folders",
+	Long: `Write game.nfo into each organized game folder, next to game.7z or game/, and
+folder.jpg from its cover, so Kodi's game add-ons and media managers scrape the
+library without looking the games up online.
+
+The NFO file holds the title and version from PARAM.SFO, the platform, the game ID,
+the region read from it, the catalog tags as genres, folder.jpg as the thumbnail and
+the other _artwork images as fanart. The cover is the image in _artwork named cover
+or front (else the first image there), falling back to the game's ICON0.PNG, and is
+converted to JPEG when it's another format.
+
+NFO files written by an earlier export are updated; other NFO files, and folder.jpg
+files already there, are kept unless --force is given. Run it again after adding
+tags or artwork.
+
+Examples:
+  rom-organizer export nfo /mnt/nas/ps3
+  rom-organizer export nfo "/mnt/nas/ps3/Game [BLUS30001]" --force
+  rom-organizer export nfo /mnt/nas/ps3 --tag favorites -n`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportNFOHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportPKGLayoutCmd)
+	exportCmd.AddCommand(exportSplitCmd)
+	exportCmd.AddCommand(exportShortcutsCmd)
+	exportCmd.AddCommand(exportBackupCmd)
+	exportCmd.AddCommand(exportRestoreCmd)
+	exportCmd.AddCommand(exportHTMLCmd)
+	exportCmd.AddCommand(exportNFOCmd)
+
+	exportPKGLayoutCmd.Flags().StringVar(&exportTo, "to", "", "Root of the USB stick or folder to export to")
+	exportPKGLayoutCmd.Flags().StringArrayVar(&exportLicenseDirs, "licenses", nil, "Also look for .rap/.rif licenses in this directory (repeatable)")
+	exportPKGLayoutCmd.Flags().BoolVarP(&exportForce, "force", "f", false, "Replace files of the same name that differ in size")
+	exportPKGLayoutCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show what would be copied without copying")
+	exportPKGLayoutCmd.Flags().StringVar(&exportInstalled, "installed", "", "Only copy updates newer than the version installed on a console (ftp://host) or in RPCS3 (dev_hdd0 folder)")
+	exportPKGLayoutCmd.MarkFlagRequired("to")
+	addCatalogFilterFlags(exportPKGLayoutCmd, &exportFilter)
+
+	exportSplitCmd.Flags().StringVar(&exportSize, "size", "", "Capacity of one disc or tape: a size such as 25GB, or dvd, dvd-dl, bd25, bd50, bd100, lto5, lto6")
+	exportSplitCmd.Flags().StringVar(&exportTo, "to", "", "Directory the numbered folders are created in")
+	exportSplitCmd.Flags().StringVar(&exportPrefix, "prefix", "backup", "Name of the numbered folders (prefix-01, prefix-02, ...)")
+	exportSplitCmd.Flags().BoolVarP(&exportForce, "force", "f", false, "Replace numbered folders left by an earlier split")
+	exportSplitCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show how the games would be grouped without copying")
+	exportSplitCmd.MarkFlagRequired("size")
+	exportSplitCmd.MarkFlagRequired("to")
+	addCatalogFilterFlags(exportSplitCmd, &exportFilter)
+
+	exportShortcutsCmd.Flags().StringVar(&exportTo, "to", "", "Folder for desktop and lnk shortcuts, or the Steam shortcuts.vdf to update")
+	exportShortcutsCmd.Flags().StringVar(&exportFormat, "format", export.ShortcutDesktop, "Shortcut format: "+strings.Join(export.ShortcutFormats, ", "))
+	exportShortcutsCmd.Flags().StringVar(&exportEmulator, "emulator", export.DefaultEmulator, "Emulator executable, as a path or a name in PATH")
+	exportShortcutsCmd.Flags().StringVar(&exportEmuArgs, "emulator-args", export.DefaultEmulatorArgs, "Emulator arguments; {eboot}, {game}, {title} and {id} are replaced")
+	exportShortcutsCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show which shortcuts would be written without writing them")
+	exportShortcutsCmd.MarkFlagRequired("to")
+	addCatalogFilterFlags(exportShortcutsCmd, &exportFilter)
+
+	exportBackupCmd.Flags().StringVar(&exportTo, "to", "", "Directory the encrypted backups are written to")
+	exportBackupCmd.Flags().StringArrayVar(&exportEncrypt, "encrypt", nil, "Recipient to encrypt to: age:<recipient|file> or gpg:<key> (repeatable)")
+	exportBackupCmd.Flags().BoolVarP(&exportForce, "force", "f", false, "Replace backups written by an earlier export")
+	exportBackupCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show which games would be backed up without writing anything")
+	exportBackupCmd.MarkFlagRequired("to")
+	exportBackupCmd.MarkFlagRequired("encrypt")
+	addCatalogFilterFlags(exportBackupCmd, &exportFilter)
+
+	exportRestoreCmd.Flags().StringVar(&exportTo, "to", "", "Library the game folders are restored into")
+	exportRestoreCmd.Flags().StringArrayVarP(&exportIdentities, "identity", "i", nil, "age identity file to decrypt with (repeatable)")
+	exportRestoreCmd.Flags().BoolVarP(&exportForce, "force", "f", false, "Replace game folders that already exist in the library")
+	exportRestoreCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show which backups would be restored without decrypting them")
+	exportRestoreCmd.MarkFlagRequired("to")
+
+	exportHTMLCmd.Flags().StringVar(&exportTo, "to", "", "Directory the gallery is written to")
+	exportHTMLCmd.Flags().StringVar(&exportTitle, "title", "Game Library", "Title of the gallery")
+	exportHTMLCmd.Flags().BoolVarP(&exportForce, "force", "f", false, "Write into a folder that has other files in it")
+	exportHTMLCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show which games would be in the gallery without writing it")
+	exportHTMLCmd.MarkFlagRequired("to")
+	addSortFlag(exportHTMLCmd, &exportSort)
+	addCatalogFilterFlags(exportHTMLCmd, &exportFilter)
+
+	exportNFOCmd.Flags().BoolVarP(&exportForce, "force", "f", false, "Replace NFO files and folder.jpg images not written by an earlier export")
+	exportNFOCmd.Flags().BoolVarP(&exportDryRun, "dry-run", "n", false, "Show which files would be written without writing them")
+	addCatalogFilterFlags(exportNFOCmd, &exportFilter)
+}
+
+func exportPKGLayoutHandler(cmd *cobra.Command, args []string) error {
+	games, _, err := findFilteredGames(args, exportFilter)
+	if err != nil {
+		return err
+	}
+
+	opts := export.PKGLayoutOptions{
+		Layout:      appConfig.Layout,
+		LicenseDirs: exportLicenseDirs,
+		Force:       exportForce,
+		DryRun:      exportDryRun,
+	}
+	if exportInstalled != "" {
+		installed, err := export.OpenInstalled(exportInstalled)
+		if err != nil {
+			return err
+		}
+		defer installed.Close()
+		opts.Installed = installed
+	}
+	action := "Copied"
+	if exportDryRun {
+		action = "Would copy"
+	}
+
+	packages, licenses := 0, 0
+	for _, game := range games {
+		info := game.Info.GameInfo
+		result, err := export.PKGLayout(game, exportTo, opts)
+		if err != nil {
+			return fmt.Errorf("%s [%s]: %w", info.Title, info.GameID, err)
+		}
+		if len(result.Packages)+len(result.Licenses)+len(result.Skipped)+len(result.Outdated) == 0 {
+			ui.Verbosef("%s [%s]: no update or DLC packages\n", info.Title, info.GameID)
+			continue
+		}
+		ui.Infof("%s [%s]: %s %d packages, %d licenses", info.Title, info.GameID, action, len(result.Packages), len(result.Licenses))
+		if len(result.Skipped) > 0 {
+			ui.Infof(" (%d already present)", len(result.Skipped))
+		}
+		if len(result.Outdated) > 0 {
+			ui.Infof(" (%d updates not newer than %s)", len(result.Outdated), result.Version)
+		}
+		ui.Infof("\n")
+		for _, name := range append(result.Packages, result.Licenses...) {
+			ui.Verbosef("  %s\n", name)
+		}
+		for _, name := range result.Outdated {
+			ui.Verbosef("  skipped %s\n", name)
+		}
+		packages += len(result.Packages)
+		licenses += len(result.Licenses)
+	}
+
+	ui.Successf("%s %d packages and %d licenses from %d games to %s\n", action, packages, licenses, len(games), exportTo)
+	return nil
+}
+
+func exportSplitHandler(cmd *cobra.Command, args []string) error {
+	mediaSize, err := export.ParseMediaSize(exportSize)
+	if err != nil {
+		return fmt.Errorf("--size: %w", err)
+	}
+	games, _, err := findFilteredGames(args, exportFilter)
+	if err != nil {
+		return err
+	}
+
+	plan, err := export.PlanSplit(games, mediaSize, exportPrefix, appConfig.Names.Export)
+	if err != nil {
+		return err
+	}
+	for _, game := range plan.Oversized {
+		ui.Warnf("%s [%s] is %s, more than the %s that fits in one bucket; skipped\n", game.Title, game.GameID, common.FormatSize(game.Size), common.FormatSize(plan.Capacity))
+	}
+
+	for _, bucket := range plan.Buckets {
+		ui.Infof("%s: %d games, %s of %s\n", bucket.Name, len(bucket.Games), common.FormatSize(bucket.Size), common.FormatSize(plan.Capacity))
+		for _, game := range bucket.Games {
+			ui.Verbosef("  %s [%s]  %s\n", game.Title, game.GameID, common.FormatSize(game.Size))
+		}
+	}
+	if exportDryRun {
+		ui.Successf("Would split %d games into %d folders in %s\n", len(games)-len(plan.Oversized), len(plan.Buckets), exportTo)
+		return nil
+	}
+
+	err = export.WriteSplit(plan, exportTo, exportForce, func(bucket *export.Bucket, game export.SplitGame) {
+		ui.Verbosef("Copying %s to %s\n", game.Dir, bucket.Name)
+	})
+	if err != nil {
+		return err
+	}
+	ui.Successf("Split %d games into %d folders in %s\n", len(games)-len(plan.Oversized), len(plan.Buckets), exportTo)
+	if len(plan.Oversized) > 0 {
+		return fmt.Errorf("%d games are larger than the bucket size and were not exported", len(plan.Oversized))
+	}
+	return nil
+}
+
+func exportShortcutsHandler(cmd *cobra.Command, args []string) error {
+	format, err := export.ParseShortcutFormat(exportFormat)
+	if err != nil {
+		return fmt.Errorf("--format: %w", err)
+	}
+	opts := export.ShortcutOptions{
+		Format:   format,
+		Emulator: exportEmulator,
+		Args:     exportEmuArgs,
+		Layout:   appConfig.Layout,
+		Names:    appConfig.Names.Export,
+		DryRun:   exportDryRun,
+	}
+	emulator, err := export.ResolveEmulator(opts)
+	if err != nil {
+		return fmt.Errorf("--emulator: %w", err)
+	}
+	games, _, err := findFilteredGames(args, exportFilter)
+	if err != nil {
+		return err
+	}
+
+	var shortcuts []*export.Shortcut
+	compressed, failed := 0, 0
+	for _, game := range games {
+		info := game.Info.GameInfo
+		shortcut, err := export.NewShortcut(game, opts)
+		switch {
+		case errors.Is(err, export.ErrNotLaunchable):
+			ui.Verbosef("%s [%s]: %v\n", info.Title, info.GameID, err)
+			compressed++
+			continue
+		case err != nil:
+			ui.Warnf("%s [%s]: %v\n", info.Title, info.GameID, err)
+			failed++
+			continue
+		}
+		shortcuts = append(shortcuts, shortcut)
+	}
+
+	written, err := export.WriteShortcuts(shortcuts, emulator, exportTo, opts)
+	if err != nil {
+		return err
+	}
+	action := "Wrote"
+	if exportDryRun {
+		action = "Would write"
+	}
+	if opts.Format != export.ShortcutSteam {
+		for _, path := range written {
+			ui.Verbosef("  %s\n", path)
+		}
+	}
+	ui.Successf("%s %d %s shortcuts to %s\n", action, len(shortcuts), opts.Format, exportTo)
+	if compressed > 0 {
+		ui.Infof("Skipped %d games only kept as game.7z (decompress them to launch them from a shortcut)\n", compressed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d games have no EBOOT.BIN to launch", failed)
+	}
+	return nil
+}
+
+func exportBackupHandler(cmd *cobra.Command, args []string) error {
+	enc, err := export.ParseEncryption(exportEncrypt)
+	if err != nil {
+		return fmt.Errorf("--encrypt: %w", err)
+	}
+	games, _, err := findFilteredGames(args, exportFilter)
+	if err != nil {
+		return err
+	}
+
+	if exportDryRun {
+		for _, game := range games {
+			ui.Infof("%s\n", filepath.Join(exportTo, export.BackupFileName(game, enc)))
+		}
+		ui.Successf("Would back up %d games to %s\n", len(games), exportTo)
+		return nil
+	}
+
+	failed := 0
+	for _, game := range games {
+		info := game.Info.GameInfo
+		ui.Verbosef("Backing up %s [%s]\n", info.Title, info.GameID)
+		output, err := export.WriteBackup(game, exportTo, enc, exportForce)
+		if err != nil {
+			ui.Warnf("%s [%s]: %v\n", info.Title, info.GameID, err)
+			failed++
+			continue
+		}
+		ui.Infof("%s\n", output)
+	}
+	ui.Successf("Backed up %d games to %s, encrypted with %s\n", len(games)-failed, exportTo, enc.Tool)
+	if failed > 0 {
+		return fmt.Errorf("%d games could not be backed up", failed)
+	}
+	return nil
+}
+
+func exportRestoreHandler(cmd *cobra.Command, args []string) error {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && export.IsBackupFile(entry.Name()) {
+				files = append(files, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no .tar.age or .tar.gpg backups found")
+	}
+
+	if exportDryRun {
+		for _, file := range files {
+			ui.Infof("%s\n", file)
+		}
+		ui.Successf("Would restore %d backups to %s\n", len(files), exportTo)
+		return nil
+	}
+	if err := checkWritable(exportTo); err != nil {
+		return err
+	}
+	if exportForce {
+		if err := takeSnapshot("restore", exportTo); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, file := range files {
+		ui.Verbosef("Restoring %s\n", file)
+		target, err := export.RestoreBackup(file, exportTo, exportIdentities, exportForce)
+		if err != nil {
+			ui.Warnf("%v\n", err)
+			failed++
+			continue
+		}
+		ui.Infof("%s\n", target)
+	}
+	ui.Successf("Restored %d games to %s\n", len(files)-failed, exportTo)
+	if failed > 0 {
+		return fmt.Errorf("%d backups could not be restored", failed)
+	}
+	return nil
+}
+
+func exportHTMLHandler(cmd *cobra.Command, args []string) error {
+	games, c, err := findFilteredGames(args, exportFilter)
+	if err != nil {
+		return err
+	}
+	if err := sortGames(games, exportSort); err != nil {
+		return err
+	}
+
+	gallery := make([]*export.GalleryGame, 0, len(games))
+	covers := 0
+	for _, game := range games {
+		// The last scan's size saves measuring every game again
+		absPath, _ := filepath.Abs(game.Path)
+		var size int64
+		if record, ok := c.Scans[absPath]; ok {
+			size = record.Size
+		} else if size, err = common.DirSize(game.Path); err != nil {
+			ui.Warnf("Could not measure %s: %v\n", game.Path, err)
+		}
+		g := export.NewGalleryGame(game, c.Games[game.Info.GameInfo.GameID], size)
+		gallery = append(gallery, g)
+		if g.HasCover() {
+			covers++
+		}
+	}
+
+	opts := export.GalleryOptions{Title: exportTitle, Force: exportForce, DryRun: exportDryRun}
+	if err := export.WriteGallery(exportTo, gallery, opts); err != nil {
+		return err
+	}
+	for _, g := range gallery {
+		ui.Verbosef("  %s  %s [%s]\n", g.Page, g.Title, g.GameID)
+	}
+	action := "Wrote"
+	if exportDryRun {
+		action = "Would write"
+	}
+	ui.Successf("%s a gallery of %d games (%d with covers) to %s\n", action, len(gallery), covers, filepath.Join(exportTo, "index.html"))
+	return nil
+}
+
+func exportNFOHandler(cmd *cobra.Command, args []string) error {
+	games, c, err := findFilteredGames(args, exportFilter)
+	if err != nil {
+		return err
+	}
+	if !exportDryRun {
+		paths := make([]string, len(games))
+		for i, game := range games {
+			paths[i] = game.Path
+		}
+		if err := checkWritable(paths...); err != nil {
+			return err
+		}
+	}
+
+	opts := export.NFOOptions{Force: exportForce, DryRun: exportDryRun}
+	written, images, unchanged, kept, failed := 0, 0, 0, 0, 0
+	for _, game := range games {
+		info := game.Info.GameInfo
+		result, err := export.WriteNFO(game, c.Games[info.GameID], opts)
+		if result != nil {
+			switch {
+			case result.NFO != "":
+				ui.Verbosef("  %s\n", result.NFO)
+				written++
+			case result.Unchanged:
+				unchanged++
+			default:
+				ui.Verbosef("%s [%s]: keeping its own %s (--force replaces it)\n", info.Title, info.GameID, export.NFOFile)
+				kept++
+			}
+			if result.Image != "" && err == nil {
+				ui.Verbosef("  %s\n", result.Image)
+				images++
+			}
+		}
+		if err != nil {
+			ui.Warnf("%s [%s]: %v\n", info.Title, info.GameID, err)
+			failed++
+		}
+	}
+
+	action := "Wrote"
+	if exportDryRun {
+		action = "Would write"
+	}
+	ui.Successf("%s %d NFO files and %d folder images for %d games\n", action, written, images, len(games))
+	if unchanged > 0 {
+		ui.Infof("%d NFO files were already up to date\n", unchanged)
+	}
+	if kept > 0 {
+		ui.Infof("Kept %d NFO files not written by rom-organizer (--force replaces them)\n", kept)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d games could not be exported", failed)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var extractTo string
+
+var extractFileCmd = &cobra.Command{
+	Use:   "extract-file <organized-dir|game.7z> <path>",
+	Short: "Extract a single file from a game",
+	Long: `Extract one file of a game, given by its path inside the game folder (e.g.
+PS3_GAME/ICON0.PNG), from game.7z without unpacking the rest, or from the game/
+folder of a decompressed game.
+
+--to names the output file or an existing directory to put it in (default the
+current directory). With --to -, the file is written to stdout so it can be piped
+into other tools; messages then go to stderr.
+
+Examples:
+  rom-organizer extract-file "/mnt/nas/ps3/Demon's Souls [BLUS30443]" PS3_GAME/ICON0.PNG
+  rom-organizer extract-file --to - /mnt/nas/ps3/game.7z PS3_GAME/PARAM.SFO | xxd | head`,
+	Args: cobra.ExactArgs(2),
+	RunE: extractFileHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(extractFileCmd)
+	extractFileCmd.Flags().StringVar(&extractTo, "to", ".", "Output file or directory, or - for stdout")
+}
+
+func extractFileHandler(cmd *cobra.Command, args []string) error {
+	source, name := args[0], args[1]
+
+	var out io.Writer = os.Stdout
+	target := "stdout"
+	if extractTo == "-" {
+		ui.SetOutput(os.Stderr)
+	} else {
+		target = extractTo
+		if isDir(extractTo) {
+			target = filepath.Join(extractTo, filepath.Base(filepath.FromSlash(name)))
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	written, err := extractGameFile(source, name, out)
+	if err != nil {
+		if extractTo != "-" {
+			os.Remove(target)
+		}
+		return err
+	}
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("writing output file: %w", err)
+		}
+	}
+	ui.Verbosef("Extracted %s (%s) to %s\n", name, common.FormatSize(written), target)
+	return nil
+}
+
+// extractGameFile writes one file of a game to w, reading it from game.7z or from the
+// game/ folder of a decompressed organized directory
+func extractGameFile(source, name string, w io.Writer) (int64, error) {
+	gameDir := filepath.Join(source, "game")
+	if isDir(source) && isDir(gameDir) {
+		path := filepath.Join(gameDir, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(gameDir, path); err != nil || strings.HasPrefix(rel, "..") {
+			return 0, fmt.Errorf("%s is outside the game folder", name)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("%s not found in %s", name, gameDir)
+		}
+		defer f.Close()
+		return io.Copy(w, f)
+	}
+
+	archivePath, err := archivePathFor(source)
+	if err != nil {
+		return 0, err
+	}
+	return common.Stream7zFile(archivePath, name, w)
+}
+
+// streamGames writes organized games to stdout as one tar stream, each under its
+// folder name with its archive unpacked into game/ as decompress would lay it out
+func streamGames(sources []string) error {
+	if isTerminal(os.Stdout) {
+		return fmt.Errorf("refusing to write a tar stream to a terminal; pipe or redirect stdout")
+	}
+	ui.SetOutput(os.Stderr)
+
+	tw := tar.NewWriter(os.Stdout)
+	for _, source := range sources {
+		if err := streamGame(source, tw); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return tw.Close()
+}
+
+// streamGame adds one organized game directory, or a game.7z inside one, to tw
+func streamGame(source string, tw *tar.Writer) error {
+	dir := source
+	if !isDir(source) {
+		dir = filepath.Dir(source)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	info, err := common.DetectOrganizedDirectory(dir, appConfig.Layout, false)
+	if err != nil {
+		return err
+	}
+	if !info.IsOrganized {
+		return fmt.Errorf("--stream only takes organized games (a folder with game.7z or game/)")
+	}
+
+	prefix := filepath.Base(dir)
+	ui.Infof("Streaming %s\n", prefix)
+	// The archive and its checksum and recovery files become the game/ folder
+	err = common.WriteDirTar(dir, prefix, tw, func(rel string) bool {
+		return strings.HasPrefix(rel, "game.7z")
+	})
+	if err != nil {
+		return err
+	}
+	if info.HasCompressed && !info.HasDecompressed {
+		return common.Write7zTar(filepath.Join(dir, "game.7z"), prefix+"/game", tw)
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a character device other than the null device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
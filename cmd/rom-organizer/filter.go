@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/ignore"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	recursive    bool
+	onlyConsoles []string
+	onlyFormat   string
+	minSize      string
+	maxSize      string
+	filterIDs    []string
+	filterTitles []string
+)
+
+// addBatchFilterFlags adds --recursive and the game filters to a batch command
+func addBatchFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Treat sources as libraries and process every organized game in them")
+	cmd.Flags().StringArrayVar(&onlyConsoles, "only-console", nil, "With --recursive, only process games for this console, e.g. ps3 (repeatable)")
+	cmd.Flags().StringVar(&onlyFormat, "only-format", "", "With --recursive, only process games stored as compressed or decompressed")
+	cmd.Flags().StringVar(&minSize, "min-size", "", "With --recursive, only process games at least this large on disk (e.g. 4GB)")
+	cmd.Flags().StringVar(&maxSize, "max-size", "", "With --recursive, only process games at most this large on disk")
+	cmd.Flags().StringArrayVar(&filterIDs, "id", nil, "With --recursive, only process this game ID or pattern, e.g. BLUS3* (repeatable)")
+	cmd.Flags().StringArrayVar(&filterTitles, "title", nil, "With --recursive, only process games whose title contains this text (repeatable)")
+}
+
+// newBatchFilter builds a library filter from the filter flags
+func newBatchFilter() (library.Filter, error) {
+	filter := library.Filter{
+		Format: onlyFormat,
+		IDs:    filterIDs,
+		Titles: filterTitles,
+	}
+	for _, name := range onlyConsoles {
+		console, err := detect.ParseConsoleType(name)
+		if err != nil {
+			return library.Filter{}, err
+		}
+		filter.Consoles = append(filter.Consoles, console)
+	}
+
+	var err error
+	if minSize != "" {
+		if filter.MinSize, err = common.ParseSize(minSize); err != nil {
+			return library.Filter{}, fmt.Errorf("--min-size: %w", err)
+		}
+	}
+	if maxSize != "" {
+		if filter.MaxSize, err = common.ParseSize(maxSize); err != nil {
+			return library.Filter{}, fmt.Errorf("--max-size: %w", err)
+		}
+	}
+	if err := filter.Validate(nil); err != nil {
+		return library.Filter{}, err
+	}
+	return filter, nil
+}
+
+// batchSources returns the sources to process. With --recursive every source is a
+// library whose organized games are filtered; otherwise the sources are used as given,
+// less those the .romignore of the folder they are in ignores. It returns no sources when
+// nothing matched.
+func batchSources(args []string) ([]string, error) {
+	filter, err := newBatchFilter()
+	if err != nil {
+		return nil, err
+	}
+	if !recursive {
+		if !filter.IsEmpty() {
+			return nil, fmt.Errorf("--only-console, --only-format, --min-size, --max-size, --id and --title need --recursive")
+		}
+		return unignoredSources(args)
+	}
+
+	var sources []string
+	for _, root := range args {
+		games, err := library.FindGames(root, appConfig.Layout)
+		if err != nil {
+			return nil, err
+		}
+		matched, err := library.FilterGames(games, filter, nil)
+		if err != nil {
+			return nil, err
+		}
+		ui.Verbosef("%s: %d of %d organized games selected\n", root, len(matched), len(games))
+		for _, game := range matched {
+			sources = append(sources, game.Path)
+		}
+	}
+	if len(sources) == 0 {
+		ui.Infof("No organized games matched the filters\n")
+	}
+	return sources, nil
+}
+
+// unignoredSources leaves out the sources ignored by a .romignore in their parent
+// folder, such as a download still in progress in an intake folder
+func unignoredSources(args []string) ([]string, error) {
+	rules := make(map[string]*ignore.Rules)
+	var sources []string
+	for _, source := range args {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Dir(abs)
+		r, loaded := rules[dir]
+		if !loaded {
+			if r, err = ignore.Load(dir); err != nil {
+				return nil, err
+			}
+			rules[dir] = r
+		}
+		info, err := os.Stat(abs)
+		if err == nil && r.Ignored(abs, info.IsDir()) {
+			ui.Infof("Skipping %s (ignored by %s)\n", source, filepath.Join(filepath.Dir(source), ignore.FileName))
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 && len(args) > 0 {
+		ui.Infof("Every source is ignored\n")
+	}
+	return sources, nil
+}
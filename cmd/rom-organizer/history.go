@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	historyJSON  bool
+	historyLimit int
+
+	// reportPath and retryFailed are the batch commands' --report and --retry-failed
+	reportPath  string
+	retryFailed string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Review past compress, decompress and organize runs",
+	Long: `Every compress, decompress and organize batch is recorded in the catalog: the
+command and its flags, when it ran, and what happened to each source (organized,
+failed, skipped by the collision policy or not attempted after an aborted batch)
+and how long it took. The last 200 runs are kept.
+
+Examples:
+  rom-organizer history
+  rom-organizer history show 12
+  rom-organizer history show last --json
+  rom-organizer history rerun 12`,
+	Args: cobra.NoArgs,
+	RunE: historyListHandler,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <run-id|last>",
+	Short: "Show the games of a past run and their outcome",
+	Args:  cobra.ExactArgs(1),
+	RunE:  historyShowHandler,
+}
+
+var historyRerunCmd = &cobra.Command{
+	Use:   "rerun <run-id|last>",
+	Short: "Run a past batch again for only the games that failed",
+	Long: `Run a past compress, decompress or organize batch again with the same flags,
+for only the sources that failed or were not attempted because the batch was
+aborted. Sources found with --recursive are passed by path, so the filter flags
+are left out. The new run is recorded in the history as well.`,
+	Args: cobra.ExactArgs(1),
+	RunE: historyRerunHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd, historyRerunCmd)
+
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of recent runs to list (0 for all)")
+	historyShowCmd.Flags().BoolVarP(&historyJSON, "json", "j", false, "Output the run record in JSON format")
+}
+
+// runBatch organizes sources and records the run in the catalog's history. Failing to
+// record the run is only a warning; the batch result is what's returned.
+func runBatch(cmd *cobra.Command, sources []string, opts organizer.OrganizeOptions) error {
+	dir, _ := os.Getwd()
+	run := &catalog.RunRecord{
+		Command: cmd.Name(),
+		Dir:     dir,
+		Flags:   commandFlags(cmd),
+		Started: time.Now().UTC(),
+		Games:   make([]catalog.RunGame, len(sources)),
+	}
+	for i, source := range sources {
+		run.Games[i] = catalog.RunGame{Source: source, Status: catalog.RunGameAborted}
+	}
+
+	var gameStarted time.Time
+	progress := opts.Progress
+	opts.Progress = func(event organizer.ProgressEvent) {
+		switch event.Event {
+		case organizer.EventGameStarted:
+			gameStarted = time.Now()
+		case organizer.EventGameDone:
+			game := &run.Games[event.Index-1]
+			game.Status, game.Error = event.Status, event.Error
+			game.Target, game.GameID, game.Title = event.Target, event.GameID, event.Title
+			game.Unverified, game.InPlace, game.Mirrors = event.Unverified, event.InPlace, event.Mirrors
+			for _, moved := range event.Moved {
+				game.Moved = append(game.Moved, catalog.MovedPath{From: moved.From, To: moved.To})
+			}
+			game.Duration = time.Since(gameStarted).Round(time.Millisecond)
+		}
+		if progress != nil {
+			progress(event)
+		}
+	}
+
+	// An interrupt finishes (or, a second time, rolls back) the current game, so the
+	// run is still recorded and can be resumed
+	stopInterrupts := common.HandleInterrupts()
+	err := organizer.OrganizeGames(sources, opts)
+	stopInterrupts()
+	run.Finished = time.Now().UTC()
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if recordErr := recordRun(run); recordErr != nil {
+		ui.Warnf("Could not record the run in the history: %v\n", recordErr)
+	} else if failed := len(run.FailedSources()); failed > 0 && common.StopRequested() {
+		ui.Infof("Run %d recorded; resume its %d remaining games with: rom-organizer %s --retry-failed %d\n", run.ID, failed, run.Command, run.ID)
+	} else if failed > 0 {
+		ui.Infof("Run %d recorded; retry its %d failed games with: rom-organizer %s --retry-failed %d\n", run.ID, failed, run.Command, run.ID)
+	} else {
+		ui.Verbosef("Run %d recorded in the history\n", run.ID)
+	}
+	if reportPath != "" {
+		if reportErr := writeReport(reportPath, run); reportErr != nil {
+			ui.Warnf("Could not write the report: %v\n", reportErr)
+		}
+	}
+	return err
+}
+
+// recordRun adds a run to the catalog's history, and flags the games it organized
+// whose metadata is unverified (or clears the flag of those now read from PARAM.SFO)
+func recordRun(run *catalog.RunRecord) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	c.AddRun(run)
+	for _, game := range run.Games {
+		if game.Status == catalog.RunGameSuccess && game.GameID != "" {
+			c.SetUnverified(game.GameID, game.Title, game.Unverified)
+		}
+	}
+	return c.Save()
+}
+
+// commandFlags returns the command's own flags that were set, as --name=value
+// arguments that reproduce them. Repeatable flags give one argument per value.
+func commandFlags(cmd *cobra.Command) []string {
+	var args []string
+	local := cmd.LocalNonPersistentFlags()
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if local.Lookup(flag.Name) == nil {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range slice.GetSlice() {
+				args = append(args, "--"+flag.Name+"="+value)
+			}
+			return
+		}
+		args = append(args, "--"+flag.Name+"="+flag.Value.String())
+	})
+	return args
+}
+
+// lookupRun returns a run of the history by ID, or the latest one for "last"
+func lookupRun(c *catalog.Catalog, arg string) (*catalog.RunRecord, error) {
+	if arg == "last" {
+		if run := c.LastRun(); run != nil {
+			return run, nil
+		}
+		return nil, fmt.Errorf("no runs recorded in the history")
+	}
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run ID %q", arg)
+	}
+	return c.Run(id)
+}
+
+func historyListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	runs := c.Runs
+	if historyLimit > 0 && len(runs) > historyLimit {
+		runs = runs[len(runs)-historyLimit:]
+	}
+	if len(runs) == 0 {
+		ui.Infof("No runs recorded yet\n")
+		return nil
+	}
+
+	fmt.Printf("%4s  %-16s  %-10s  %5s  %6s  %7s  %8s\n", "ID", "Started", "Command", "Games", "Failed", "Skipped", "Duration")
+	for _, run := range runs {
+		failed := run.Count(catalog.RunGameFailed)
+		skipped := run.Count(catalog.RunGameSkipped) + run.Count(catalog.RunGameAborted)
+		fmt.Printf("%4d  %-16s  %-10s  %5d  %6d  %7d  %8s\n", run.ID, run.Started.Local().Format("2006-01-02 15:04"), run.Command,
+			len(run.Games), failed, skipped, run.Duration().Round(time.Second))
+	}
+	return nil
+}
+
+func historyShowHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	run, err := lookupRun(c, args[0])
+	if err != nil {
+		return err
+	}
+	if historyJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(run)
+	}
+
+	fmt.Printf("Run %d: %s %s\n", run.ID, run.Command, strings.Join(run.Flags, " "))
+	fmt.Printf("Started:  %s in %s\n", run.Started.Local().Format("2006-01-02 15:04:05"), run.Dir)
+	fmt.Printf("Duration: %s\n", run.Duration().Round(time.Second))
+	fmt.Printf("Games:    %d succeeded, %d failed, %d skipped, %d not attempted\n", run.Count(catalog.RunGameSuccess),
+		run.Count(catalog.RunGameFailed), run.Count(catalog.RunGameSkipped), run.Count(catalog.RunGameAborted))
+	for _, game := range run.Games {
+		name := game.Source
+		if game.Title != "" {
+			name = fmt.Sprintf("%s [%s]", game.Title, game.GameID)
+		}
+		fmt.Printf("  %-8s %8s  %s\n", game.Status, game.Duration.Round(time.Second), name)
+		if game.Target != "" {
+			ui.Verbosef("           %s -> %s\n", game.Source, game.Target)
+		}
+		if game.Error != "" {
+			fmt.Printf("           %s\n", game.Error)
+		}
+	}
+	return nil
+}
+
+func historyRerunHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	run, err := lookupRun(c, args[0])
+	if err != nil {
+		return err
+	}
+	return rerunFailed(run, nil)
+}
+
+// retryFailedHandler runs a batch command given --retry-failed: the earlier run's
+// command again, with its flags, for only its failed sources
+func retryFailedHandler(cmd *cobra.Command) error {
+	run, err := loadRun(retryFailed)
+	if err != nil {
+		return fmt.Errorf("--retry-failed: %w", err)
+	}
+	if run.Command != cmd.Name() {
+		return fmt.Errorf("--retry-failed: run %d ran %s, not %s", run.ID, run.Command, cmd.Name())
+	}
+	var extra []string
+	for _, flag := range commandFlags(cmd) {
+		name, value, _ := strings.Cut(strings.TrimPrefix(flag, "--"), "=")
+		switch name {
+		case "retry-failed":
+		case "report":
+			abs, err := filepath.Abs(value)
+			if err != nil {
+				return err
+			}
+			extra = append(extra, "--report="+abs)
+		default:
+			return fmt.Errorf("--retry-failed reuses the flags of run %d; --%s can't be changed", run.ID, name)
+		}
+	}
+	return rerunFailed(run, extra)
+}
+
+// loadRun returns the run named by a --retry-failed value: a file written by --report or
+// history show --json, a run ID or "last"
+func loadRun(arg string) (*catalog.RunRecord, error) {
+	if data, err := os.ReadFile(arg); err == nil {
+		var run catalog.RunRecord
+		if err := json.Unmarshal(data, &run); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", arg, err)
+		}
+		if run.Command == "" {
+			return nil, fmt.Errorf("%s is not a run report", arg)
+		}
+		return &run, nil
+	}
+	c, err := openCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return lookupRun(c, arg)
+}
+
+// writeReport writes a run record as JSON, for --report
+func writeReport(path string, run *catalog.RunRecord) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// rerunFailed runs a recorded batch again as a child process, with its flags and extra,
+// for only the sources that failed or were not attempted
+func rerunFailed(run *catalog.RunRecord, extra []string) error {
+	sources := run.FailedSources()
+	if len(sources) == 0 {
+		ui.Successf("Run %d has no failed games\n", run.ID)
+		return nil
+	}
+
+	rerun := append([]string{run.Command}, rerunFlags(run.Flags)...)
+	rerun = append(rerun, extra...)
+	rerun = append(rerun, "--")
+	rerun = append(rerun, sources...)
+	ui.Infof("Running %s again for %d failed games of run %d\n", run.Command, len(sources), run.ID)
+
+	// Relative sources and outputs are relative to where the run was started
+	if configPath != "" {
+		abs, err := filepath.Abs(configPath)
+		if err != nil {
+			return err
+		}
+		configPath = abs
+	}
+	if err := os.Chdir(run.Dir); err != nil {
+		return fmt.Errorf("run %d was started in %s: %w", run.ID, run.Dir, err)
+	}
+	return runSubcommand(rerun, ui.Output(), os.Stderr, false)
+}
+
+// rerunFlags drops --recursive and the filters from a run's flags, since a rerun
+// passes the games it found by path, and the report file, which the rerun would overwrite
+func rerunFlags(flags []string) []string {
+	dropped := map[string]bool{"recursive": true, "only-console": true, "only-format": true, "min-size": true, "max-size": true, "id": true, "title": true, "report": true}
+	var kept []string
+	for _, flag := range flags {
+		name, _, _ := strings.Cut(strings.TrimPrefix(flag, "--"), "=")
+		if !dropped[name] {
+			kept = append(kept, flag)
+		}
+	}
+	return kept
+}
+
+// batchArgs requires sources for a batch command, unless they come from --retry-failed
+func batchArgs(cmd *cobra.Command, args []string) error {
+	if retryFailed == "" {
+		return cobra.MinimumNArgs(1)(cmd, args)
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("--retry-failed takes its sources from the earlier run; don't give any")
+	}
+	return nil
+}
+
+// addRunFlags adds --report and --retry-failed to a batch command
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportPath, "report", "", "Write the run's summary (each game's outcome, error and duration) to this JSON file")
+	cmd.Flags().StringVar(&retryFailed, "retry-failed", "", "Only process the sources that failed in an earlier run, with its flags: a run ID, \"last\" or a --report file")
+}
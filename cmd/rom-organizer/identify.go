@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/compat"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	identifyJSON   bool
+	identifyAccept int
+)
+
+var identifyCmd = &cobra.Command{
+	Use:   "identify <path>",
+	Short: "Guess what an unnamed archive, disc image or folder holds, and organize it as that",
+	Long: `Try every supported console on an archive, disc image or folder with an
+unhelpful name and print ranked guesses of what it is, with the evidence for each:
+
+  - games found inside it, read through the console handlers (each game folder of
+    a folder, each PS3_GAME/PARAM.SFO listed in an archive, a disc image's header)
+  - game folders whose PS3_GAME is in the wrong case
+  - a title ID in its name (BLUS30001, BLUS-30001, blus_30001), titled from the
+    cached RPCS3 compatibility database when there is one
+  - what the contents look like when no console recognizes them
+
+Nothing is extracted or changed. In a terminal, press the number of a guess to
+organize what it identifies into --output right away, or any other key to quit.
+--accept picks a guess without asking. Guesses that can't be organized as they are
+(a wrong-case folder, a name-only match) are listed without a number.
+
+Examples:
+  rom-organizer identify ~/Downloads/a8f3e2.7z
+  rom-organizer identify -o /mnt/nas/ps3 ~/Downloads/dump
+  rom-organizer identify --accept 1 -o /mnt/nas/ps3 ~/Downloads/dump
+  rom-organizer identify --json ~/Downloads/unknown.iso`,
+	Args: cobra.ExactArgs(1),
+	RunE: identifyHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(identifyCmd)
+	identifyCmd.Flags().StringArrayVarP(&outputDirs, "output", "o", []string{"."}, "Output directory to organize the chosen game into (repeat to mirror to several destinations)")
+	identifyCmd.Flags().BoolVarP(&identifyJSON, "json", "j", false, "Output the guesses in JSON format and don't ask")
+	identifyCmd.Flags().IntVar(&identifyAccept, "accept", 0, "Organize the guess with this number without asking")
+	addSearchFlags(identifyCmd)
+}
+
+func identifyHandler(cmd *cobra.Command, args []string) error {
+	source := args[0]
+	limits, err := searchLimits()
+	if err != nil {
+		return err
+	}
+	guesses, err := organizer.Identify(source, organizer.IdentifyOptions{Limits: limits, TitleFor: cachedCompatTitle()})
+	if err != nil {
+		return err
+	}
+
+	if identifyJSON {
+		data, err := json.MarshalIndent(guesses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(guesses) == 0 {
+		return fmt.Errorf("%w: nothing in %s points to a supported game", common.ErrNotDetected, source)
+	}
+
+	// Only guesses that can be organized get a number to choose them by
+	var choices []organizer.Guess
+	for _, guess := range guesses {
+		label := "  "
+		if guess.Path != "" {
+			choices = append(choices, guess)
+			label = fmt.Sprintf("%d)", len(choices))
+		}
+		name := guess.Title
+		if name == "" {
+			name = "(unknown title)"
+		}
+		if guess.GameID != "" {
+			name += " [" + guess.GameID + "]"
+		}
+		fmt.Printf("%s %3.0f%%  %s  %s\n", label, guess.Confidence*100, guess.Console, name)
+		for _, evidence := range guess.Evidence {
+			fmt.Printf("          %s\n", evidence)
+		}
+		if guess.Path != "" && guess.Path != source {
+			fmt.Printf("          organizes %s\n", guess.Path)
+		}
+	}
+
+	choice := identifyAccept
+	switch {
+	case choice < 0 || choice > len(choices):
+		return fmt.Errorf("--accept must be between 1 and %d", len(choices))
+	case choice == 0 && len(choices) == 0:
+		return nil
+	case choice == 0:
+		if !ui.IsTerminal(os.Stdin) {
+			return nil
+		}
+		// One keystroke picks a guess, so only the first nine can be
+		keys := min(len(choices), 9)
+		key, err := ui.ReadKey(fmt.Sprintf("Press 1-%d to organize into %s, any other key to quit: ", keys, outputDirs[0]))
+		if errors.Is(err, ui.ErrNoTerminal) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if key < '1' || int(key-'0') > keys {
+			return nil
+		}
+		choice = int(key - '0')
+	}
+
+	chosen := choices[choice-1]
+	ui.Infof("Organizing %s as %s [%s]\n", chosen.Path, chosen.Title, chosen.GameID)
+	opts, err := newOrganizeOptions(organizer.KeepOriginal)
+	if err != nil {
+		return err
+	}
+	if err := checkWritable(batchTargets([]string{chosen.Path}, opts)...); err != nil {
+		return err
+	}
+	return runBatch(cmd, []string{chosen.Path}, opts)
+}
+
+// cachedCompatTitle returns a title lookup in the cached RPCS3 compatibility database,
+// or nil when none was downloaded. Identifying never downloads it.
+func cachedCompatTitle() func(string) string {
+	db, err := compat.LoadFile(compat.DefaultCachePath())
+	if err != nil {
+		return nil
+	}
+	return func(gameID string) string {
+		return db[gameID].Title
+	}
+}
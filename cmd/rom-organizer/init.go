@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/config"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/doctor"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var initForce bool
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the config file with a guided setup",
+	Long: `Walk through the settings most libraries need and write them to the config file
+(--config, or the default location):
+
+- which external tools are installed, and where 7z is when it isn't in PATH
+- the library folders; the first becomes the default --output
+- whether new games are kept compressed (game.7z) or decompressed (game/)
+- folder naming: first-letter buckets and the title cleanup rules
+- per console: the 7z level and tuning, and what to do with optional disc folders
+
+Answers default to the current config, so running init again changes only what you
+answer differently. The file is shown before it is written; a config that already
+exists is kept as config.yaml.bak. Answers can also be piped in, one per line.
+
+Examples:
+  rom-organizer init
+  rom-organizer init --config ~/ps3.yaml`,
+	Args: cobra.NoArgs,
+	// The config is what init replaces, so it isn't loaded before the command
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return setupOutput() },
+	RunE:              initHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Replace an existing config file without asking")
+}
+
+// prompter asks the wizard's questions on stderr and reads the answers from stdin, one
+// per line
+type prompter struct {
+	in *bufio.Reader
+}
+
+// ask returns the answer to a question, or def when the answer is empty
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			fmt.Fprintln(os.Stderr)
+			return "", errors.New("input ended before every question was answered")
+		}
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// choose asks until the answer is one of choices
+func (p *prompter) choose(question string, choices []string, def string) (string, error) {
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, ", ")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, choice := range choices {
+			if strings.EqualFold(answer, choice) {
+				return choice, nil
+			}
+		}
+		ui.Warnf("Answer one of %s\n", strings.Join(choices, ", "))
+	}
+}
+
+// yesNo asks a yes/no question
+func (p *prompter) yesNo(question string, def bool) (bool, error) {
+	defAnswer := "n"
+	if def {
+		defAnswer = "y"
+	}
+	answer, err := p.choose(question, []string{"y", "n"}, defAnswer)
+	return answer == "y", err
+}
+
+// initAnswers are the settings the wizard writes
+type initAnswers struct {
+	sevenZip   string
+	roots      []string
+	format     string
+	organizeBy string
+	titles     common.TitleRules
+	consoles   []consoleAnswers
+	components map[string]string
+}
+
+// consoleAnswers are the compression settings of one console
+type consoleAnswers struct {
+	name   string
+	level  int
+	tuning string
+}
+
+func initHandler(cmd *cobra.Command, args []string) error {
+	path := configPath
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	if path == "" {
+		return fmt.Errorf("no user config directory; choose a file with --config")
+	}
+	if abs, err := filepath.Abs(expandHome(path)); err == nil {
+		path = abs
+	}
+
+	// Answers default to the current settings; a file that doesn't load starts over
+	current := config.Default()
+	_, statErr := os.Stat(path)
+	exists := statErr == nil
+	if exists {
+		cfg, err := config.Load(path)
+		if err != nil {
+			ui.Warnf("%v; starting from the defaults\n", err)
+		} else {
+			current = cfg
+		}
+	}
+
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	answers, err := askInit(p, current)
+	if err != nil {
+		return err
+	}
+
+	data := answers.yaml()
+	tmp, err := os.CreateTemp("", "rom-organizer-config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if _, err := config.Load(tmp.Name()); err != nil {
+		return fmt.Errorf("the answers don't make a valid config: %w", err)
+	}
+
+	fmt.Printf("\n%s\n", data)
+	question := "Write this config to " + path + "?"
+	if exists && !initForce {
+		question = path + " already exists. Replace it (the old file is kept as .bak)?"
+	}
+	ok, err := p.yesNo(question, !exists || initForce)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		ui.Infof("Config not written\n")
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if exists {
+		if err := common.CopyFile(path, path+".bak"); err != nil {
+			return fmt.Errorf("keeping the old config: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		return err
+	}
+	ui.Successf("Wrote %s\n", path)
+	if exists {
+		ui.Infof("The old config is in %s.bak; copy over any other settings you want to keep\n", path)
+	}
+	if len(answers.roots) > 0 {
+		ui.Infof("Next: rom-organizer organize <folder> organizes games into %s\n", answers.roots[0])
+	}
+	return nil
+}
+
+// askInit asks the wizard's questions in order
+func askInit(p *prompter, current *config.Config) (*initAnswers, error) {
+	answers := &initAnswers{components: map[string]string{}}
+
+	fmt.Fprintln(os.Stderr, "== Tools ==")
+	common.SevenZipPath = current.Compression.SevenZip
+	missing7z := false
+	for _, check := range doctor.CheckTools() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", check.Name, check.Detail)
+		if check.Status == doctor.Fail {
+			missing7z = true
+		}
+	}
+	answers.sevenZip = current.Compression.SevenZip
+	if missing7z {
+		for {
+			answer, err := p.ask("7z executable (empty to install it in PATH later)", "")
+			if err != nil {
+				return nil, err
+			}
+			if answer == "" {
+				ui.Warnf("compress and organize need 7z; run rom-organizer doctor once it's installed\n")
+				answers.sevenZip = ""
+				break
+			}
+			if _, err := exec.LookPath(expandHome(answer)); err != nil {
+				ui.Warnf("%s: %v\n", answer, err)
+				continue
+			}
+			answers.sevenZip = expandHome(answer)
+			break
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "\n== Library ==")
+	keep := false
+	if roots := current.Library.Roots; len(roots) > 0 {
+		fmt.Fprintf(os.Stderr, "  Library folders: %s\n", strings.Join(roots, ", "))
+		var err error
+		if keep, err = p.yesNo("Keep these folders?", true); err != nil {
+			return nil, err
+		}
+		if keep {
+			answers.roots = roots
+		}
+	}
+	for !keep {
+		answer, err := p.ask(fmt.Sprintf("Library folder %d (empty when done)", len(answers.roots)+1), "")
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			break
+		}
+		root, err := filepath.Abs(expandHome(answer))
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(root); err != nil {
+			ui.Warnf("%s doesn't exist yet; it is created by the first organize\n", root)
+		} else if !info.IsDir() {
+			ui.Warnf("%s is not a folder\n", root)
+			continue
+		}
+		answers.roots = append(answers.roots, root)
+	}
+
+	format := current.Library.Format
+	if format == "" {
+		format = "decompressed"
+	}
+	fmt.Fprintln(os.Stderr, "  compressed: one game.7z per game, smaller and easy to copy")
+	fmt.Fprintln(os.Stderr, "  decompressed: a game/ folder, ready for webMAN MOD, multiMAN and RPCS3")
+	var err error
+	if answers.format, err = p.choose("Format of new games", []string{"compressed", "decompressed"}, format); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(os.Stderr, "\n== Naming ==")
+	fmt.Fprintln(os.Stderr, `  Games are organized as "Title [GAME ID]", e.g. "Demon's Souls [BLUS30443]"`)
+	organizeBy := current.Library.OrganizeBy
+	if organizeBy == "" {
+		organizeBy = "none"
+	}
+	if answers.organizeBy, err = p.choose("Group games into A/, B/, ..., 0-9/ folders", []string{"none", "first-letter"}, organizeBy); err != nil {
+		return nil, err
+	}
+	rules := current.Titles
+	titles := rules.StripSymbols || rules.CollapseWhitespace || rules.FixAllCaps || rules.StripDiscNumbers
+	clean, err := p.yesNo(`Clean up titles (remove ™ and ®, "GOD OF WAR" -> "God of War", drop "(Disc 1)")?`, titles)
+	if err != nil {
+		return nil, err
+	}
+	// Rules turned off one by one in the current config stay off
+	switch {
+	case clean && titles:
+		answers.titles = rules
+	case clean:
+		answers.titles = common.DefaultTitleRules()
+	}
+
+	for _, console := range []detect.ConsoleType{detect.PS3} {
+		fmt.Fprintf(os.Stderr, "\n== %s ==\n", console)
+		policy := current.Compression.ArchiveOptions(console)
+		level := strconv.Itoa(policy.Level)
+		var answer string
+		for {
+			if answer, err = p.ask("7z level, 0 (store only) to 9 (smallest)", level); err != nil {
+				return nil, err
+			}
+			if n, err := strconv.Atoi(answer); err == nil && n >= 0 && n <= 9 {
+				break
+			}
+			ui.Warnf("Answer a number from 0 to 9\n")
+		}
+		n, _ := strconv.Atoi(answer)
+
+		tuning := "max"
+		if t := current.Compression.Consoles[console.ShortName()].Tuning; t != nil {
+			tuning = *t
+		} else if t := current.Compression.Default.Tuning; t != nil {
+			tuning = *t
+		}
+		fmt.Fprintln(os.Stderr, "  max: smallest archives; fast-access: quicker to read single files from, e.g. when served")
+		tuning, err = p.choose("7z tuning", common.ArchiveTuningNames(), tuning)
+		if err != nil {
+			return nil, err
+		}
+		answers.consoles = append(answers.consoles, consoleAnswers{name: console.ShortName(), level: n, tuning: tuning})
+
+		if console == detect.PS3 {
+			choices := make([]string, len(common.ComponentPolicies))
+			for i, policy := range common.ComponentPolicies {
+				choices[i] = string(policy)
+			}
+			for _, component := range common.DiscComponents {
+				def := current.Components[component]
+				if def == "" {
+					def = string(common.ComponentInclude)
+				}
+				if answer, err = p.choose(component+" folders on discs", choices, def); err != nil {
+					return nil, err
+				}
+				answers.components[component] = answer
+			}
+		}
+	}
+	return answers, nil
+}
+
+// yaml returns the config file for the answers, commented like the README's examples
+func (a *initAnswers) yaml() string {
+	var b strings.Builder
+	b.WriteString("# Written by rom-organizer init; every setting is described in the README\n\n")
+
+	b.WriteString("library:\n")
+	if len(a.roots) > 0 {
+		b.WriteString("  roots:                    # the first is the default --output\n")
+		for _, root := range a.roots {
+			fmt.Fprintf(&b, "    - %s\n", strconv.Quote(root))
+		}
+	}
+	fmt.Fprintf(&b, "  format: %-17s # new games: compressed (game.7z) or decompressed (game/)\n", a.format)
+	fmt.Fprintf(&b, "  organize_by: %-12s # none or first-letter\n", a.organizeBy)
+
+	b.WriteString("\ncompression:\n")
+	if a.sevenZip != "" {
+		fmt.Fprintf(&b, "  seven_zip: %s\n", strconv.Quote(a.sevenZip))
+	}
+	b.WriteString("  consoles:\n")
+	for _, console := range a.consoles {
+		fmt.Fprintf(&b, "    %s:\n", console.name)
+		fmt.Fprintf(&b, "      level: %-14d # 0 (store only) to 9\n", console.level)
+		fmt.Fprintf(&b, "      tuning: %-13s # max, balanced or fast-access\n", console.tuning)
+	}
+
+	b.WriteString("\ntitles:\n")
+	fmt.Fprintf(&b, "  strip_symbols: %t\n", a.titles.StripSymbols)
+	fmt.Fprintf(&b, "  collapse_whitespace: %t\n", a.titles.CollapseWhitespace)
+	fmt.Fprintf(&b, "  fix_all_caps: %t\n", a.titles.FixAllCaps)
+	fmt.Fprintf(&b, "  strip_disc_numbers: %t\n", a.titles.StripDiscNumbers)
+
+	if len(a.components) > 0 {
+		b.WriteString("\ncomponents:                 # include, exclude or separate\n")
+		for _, component := range common.DiscComponents {
+			if policy, ok := a.components[component]; ok {
+				fmt.Fprintf(&b, "  %s: %s\n", component, policy)
+			}
+		}
+	}
+	return b.String()
+}
+
+// expandHome replaces a leading ~ with the home directory, as a shell would for a typed path
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/' && rest[0] != filepath.Separator) {
+		return path
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return home + rest
+	}
+	return path
+}
@@ -10,14 +10,39 @@ import (
 	"testing"
 )
 
-const (
-	testGamesDir        = "../../tests/test-games"
-	testOrganizedDir    = "../../tests/test-organized"
-	testCompressedDir   = "../../tests/test-compressed"
-	testDecompressedDir = "../../tests/test-decompressed"
-	testGameCount       = 5
+const testGameCount = 5
+
+// Directories of the integration test, set up by TestIntegration
+var (
+	testGamesDir        string
+	testOrganizedDir    string
+	testCompressedDir   string
+	testDecompressedDir string
 )
 
+// testArtifactsDir returns the directory a test writes the named artifacts to: a
+// temporary directory, or tests/<name> when KEEP_TEST_ARTIFACTS is set so they can be
+// inspected afterwards
+func testArtifactsDir(t testing.TB, name string) string {
+	if os.Getenv("KEEP_TEST_ARTIFACTS") != "true" {
+		return filepath.Join(t.TempDir(), name)
+	}
+	dir := filepath.Join("..", "..", "tests", name)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Logf("Warning: could not remove %s: %v", dir, err)
+	}
+	t.Logf("🔒 Keeping test artifacts in %s", dir)
+	return dir
+}
+
+// isolateUserConfig points the config directory of the commands the test runs to a
+// temporary directory, so their runs aren't recorded in the user's catalog
+func isolateUserConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("APPDATA", dir)
+}
+
 // getBinaryPath returns the correct path to the ROM organizer binary
 func getBinaryPath() string {
 	if runtime.GOOS == "windows" {
@@ -40,9 +65,11 @@ func TestIntegration(t *testing.T) {
 		t.Fatalf("Failed to build ROM organizer binary: %v\nOutput: %s", err, output)
 	}
 
-	// Clean up any existing test directories
-	t.Log("Cleaning up existing test directories...")
-	cleanupTestDirs(t)
+	testGamesDir = testArtifactsDir(t, "test-games")
+	testOrganizedDir = testArtifactsDir(t, "test-organized")
+	testCompressedDir = testArtifactsDir(t, "test-compressed")
+	testDecompressedDir = testArtifactsDir(t, "test-decompressed")
+	isolateUserConfig(t)
 
 	// Generate test games
 	t.Log("Generating test games...")
@@ -72,34 +99,15 @@ func TestIntegration(t *testing.T) {
 	t.Log("Testing multiple path operations...")
 	testMultiplePaths(t)
 
-	// Clean up after tests (unless --keep flag was used in shell script)
-	keepArtifacts := os.Getenv("KEEP_TEST_ARTIFACTS") == "true"
-	if keepArtifacts {
-		t.Log("🔒 Keeping test artifacts (KEEP_TEST_ARTIFACTS environment variable set)")
-	} else {
-		t.Log("Cleaning up test directories...")
-		cleanupTestDirs(t)
-	}
-
 	t.Log("✅ All integration tests passed!")
 }
 
-// cleanupTestDirs removes all test directories
-func cleanupTestDirs(t *testing.T) {
-	dirs := []string{testGamesDir, testOrganizedDir, testCompressedDir, testDecompressedDir}
-	for _, dir := range dirs {
-		if err := os.RemoveAll(dir); err != nil && !os.IsNotExist(err) {
-			t.Logf("Warning: could not remove %s: %v", dir, err)
-		}
-	}
-}
-
-// generateTestGames creates fake test games using the development script
+// generateTestGames creates fake test games using the hidden devtools command
 func generateTestGames(t *testing.T) {
-	cmd := exec.Command("go", "run", "../../tests/generate-test-games.go",
-		"-count", fmt.Sprintf("%d", testGameCount),
-		"-output", testGamesDir,
-		"-clean")
+	cmd := exec.Command(getBinaryPath(), "devtools", "generate-games",
+		"--count", fmt.Sprintf("%d", testGameCount),
+		"--output", testGamesDir,
+		"--clean")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -467,28 +475,16 @@ func TestBuildBinary(t *testing.T) {
 	t.Log("✅ ROM organizer binary built successfully")
 }
 
-// TestGenerateTestGamesScript tests the test game generation script independently
-func TestGenerateTestGamesScript(t *testing.T) {
-	tempDir := "../../tests/temp-test-games"
+// TestDevtoolsGenerateGames tests the test game generation command independently
+func TestDevtoolsGenerateGames(t *testing.T) {
+	tempDir := testArtifactsDir(t, "temp-test-games")
 
-	// Clean up temp directory (unless --keep flag was used)
-	keepArtifacts := os.Getenv("KEEP_TEST_ARTIFACTS") == "true"
-	defer func() {
-		if !keepArtifacts {
-			if err := os.RemoveAll(tempDir); err != nil {
-				t.Logf("Warning: could not clean up temp directory: %v", err)
-			}
-		} else {
-			t.Logf("🔒 Keeping temp test games directory: %s", tempDir)
-		}
-	}()
-
-	t.Log("Testing test game generation script...")
+	t.Log("Testing test game generation command...")
 
-	cmd := exec.Command("go", "run", "../../tests/generate-test-games.go",
-		"-count", "3",
-		"-output", tempDir,
-		"-seed", "12345") // Use fixed seed for reproducible tests
+	cmd := exec.Command("go", "run", ".", "devtools", "generate-games",
+		"--count", "3",
+		"--output", tempDir,
+		"--seed", "12345") // Use fixed seed for reproducible tests
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -512,7 +508,27 @@ func TestGenerateTestGamesScript(t *testing.T) {
 		t.Fatalf("Expected 3 test games, found %d", gameCount)
 	}
 
-	t.Log("✅ Test game generation script works correctly")
+	// Verify zip-wrapped and nested variants
+	variantsDir := testArtifactsDir(t, "temp-test-games-variants")
+
+	cmd = exec.Command("go", "run", ".", "devtools", "generate-games",
+		"--count", "2",
+		"--output", variantsDir,
+		"--seed", "12345",
+		"--nested", "--zip",
+		"--file-size", "4KB")
+
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Test game variant generation failed: %v\nOutput: %s", err, output)
+	}
+
+	zips, err := filepath.Glob(filepath.Join(variantsDir, "*.zip"))
+	if err != nil || len(zips) != 2 {
+		t.Fatalf("Expected 2 zip-wrapped test games, found %d", len(zips))
+	}
+
+	t.Log("✅ Test game generation command works correctly")
 }
 
 // BenchmarkFullWorkflow benchmarks the complete workflow
@@ -535,11 +551,11 @@ func BenchmarkFullWorkflow(t *testing.B) {
 	t.ResetTimer()
 
 	for i := 0; i < t.N; i++ {
-		benchDir := fmt.Sprintf("../../tests/bench-games-%d", i)
+		benchDir := filepath.Join(t.TempDir(), fmt.Sprintf("bench-games-%d", i))
 
 		// Generate test games
-		if err := exec.Command("go", "run", "../../tests/generate-test-games.go",
-			"-count", "2", "-output", benchDir, "-clean").Run(); err != nil {
+		if err := exec.Command(benchBinaryPath, "devtools", "generate-games",
+			"--count", "2", "--output", benchDir, "--clean").Run(); err != nil {
 			t.Fatalf("Benchmark game generation failed: %v", err)
 		}
 
@@ -557,8 +573,5 @@ func BenchmarkFullWorkflow(t *testing.B) {
 				exec.Command(benchBinaryPath, "metadata", gamePath).Run()
 			}
 		}
-
-		// Clean up
-		os.RemoveAll(benchDir)
 	}
 }
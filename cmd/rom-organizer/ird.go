@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var irdCmd = &cobra.Command{
+	Use:   "ird",
+	Short: "Check PS3 games against IRD files",
+	Long: `Read IRD (ISO Rebuild Data) files, which describe original pressed PS3 discs, and
+check that a JB folder or disc image matches the original disc.
+
+Examples:
+  rom-organizer ird info BLUS30001.ird
+  rom-organizer ird verify BLUS30001.ird "/games/Game [BLUS30001]"
+  rom-organizer ird verify BLUS30001.ird "Game (USA).iso"`,
+}
+
+var irdInfoCmd = &cobra.Command{
+	Use:   "info <file.ird>",
+	Short: "Show the contents of an IRD file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  irdInfoHandler,
+}
+
+var irdVerifyCmd = &cobra.Command{
+	Use:   "verify <file.ird> <folder|image.iso>",
+	Short: "Check a JB folder or disc image against an IRD file",
+	Long: `Check a JB folder, a decompressed organized game, or a disc image against an IRD file.
+
+Every file hash in the IRD is compared; disc images also have their region hashes
+compared. Encrypted and decrypted images are both accepted (the disc key is derived
+from the IRD). Mismatching files are listed and the command fails.`,
+	Args: cobra.ExactArgs(2),
+	RunE: irdVerifyHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(irdCmd)
+	irdCmd.AddCommand(irdInfoCmd, irdVerifyCmd)
+}
+
+// loadIRD reads and parses an IRD file
+func loadIRD(path string) (*parsers.IRD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading IRD file: %w", err)
+	}
+	ird, err := parsers.ParseIRD(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ird, nil
+}
+
+func irdInfoHandler(cmd *cobra.Command, args []string) error {
+	ird, err := loadIRD(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Game Title:     %s\n", ird.Title)
+	fmt.Printf("Game ID:        %s\n", ird.ProductCode)
+	fmt.Printf("IRD Version:    %d\n", ird.Version)
+	fmt.Printf("Firmware:       %s\n", ird.SystemVersion)
+	fmt.Printf("Game Version:   %s\n", ird.GameVersion)
+	fmt.Printf("App Version:    %s\n", ird.AppVersion)
+	fmt.Printf("Regions:        %d\n", len(ird.RegionHashes))
+	fmt.Printf("Files:          %d\n", len(ird.Files))
+	fmt.Printf("Disc Key:       %s\n", hex.EncodeToString(consoles.DiscKeyFromIRD(ird)))
+
+	if verbose {
+		files, err := parsers.ReadISO9660(bytes.NewReader(ird.Header))
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		for _, file := range files {
+			fmt.Printf("%12d  %s\n", file.Size, file.Path)
+		}
+	}
+	return nil
+}
+
+func irdVerifyHandler(cmd *cobra.Command, args []string) error {
+	ird, err := loadIRD(args[0])
+	if err != nil {
+		return err
+	}
+
+	target := args[1]
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	var report *consoles.IRDReport
+	if info.IsDir() {
+		// Decompressed organized games keep the disc contents in game/
+		if gameDir := filepath.Join(target, "game"); isDir(gameDir) {
+			target = gameDir
+		}
+		report, err = consoles.VerifyPS3Folder(ird, target)
+	} else {
+		var disc *consoles.PS3ISO
+		if disc, err = consoles.OpenPS3ISO(target); err != nil {
+			return err
+		}
+		if disc.TitleID != ird.ProductCode {
+			ui.Warnf("disc image is %s but the IRD is for %s\n", disc.TitleID, ird.ProductCode)
+		}
+		report, err = consoles.VerifyPS3ISO(ird, disc, consoles.DiscKeyFromIRD(ird))
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, m := range report.Mismatches {
+		fmt.Printf("MISMATCH  %-50s %s\n", m.Path, m.Problem)
+	}
+	fmt.Printf("Files:    %d/%d match\n", report.FilesOK, report.Files)
+	if report.Regions > 0 {
+		fmt.Printf("Regions:  %d/%d match\n", report.RegionsOK, report.Regions)
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("%d items do not match %s [%s]", len(report.Mismatches), ird.Title, ird.ProductCode)
+	}
+	ui.Successf("Matches the original disc: %s [%s]\n", ird.Title, ird.ProductCode)
+	return nil
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
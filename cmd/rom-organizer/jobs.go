@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/jobs"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	jobsQueuePath string
+	jobsState     string
+	jobsWatch     time.Duration
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Queue organize jobs and run them later",
+	Long: `Manage a persistent queue of compress, decompress and organize jobs.
+
+Jobs are stored in a queue file and keep their state (pending, running, completed,
+failed, cancelled) across restarts: jobs that were running when the worker stopped
+are run again by the next "jobs run".
+
+Examples:
+  rom-organizer jobs add organize --output /mnt/nas/ps3 /downloads/game1 /downloads/game2
+  rom-organizer jobs list --state failed
+  rom-organizer jobs run --watch 1m
+  rom-organizer jobs retry 3`,
+}
+
+var jobsAddCmd = &cobra.Command{
+	Use:   "add <compress|decompress|organize> [flags] <source>...",
+	Short: "Queue a job",
+	Long: `Queue a compress, decompress or organize job. Everything after the command name
+is passed to it unchanged when the job runs.`,
+	Args:               cobra.MinimumNArgs(2),
+	DisableFlagParsing: true,
+	RunE:               jobsAddHandler,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued jobs",
+	Args:  cobra.NoArgs,
+	RunE:  jobsListHandler,
+}
+
+var jobsRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run pending jobs",
+	Long: `Run pending jobs one at a time until none are left. With --watch, keep
+running and check the queue for new jobs at the given interval.`,
+	Args: cobra.NoArgs,
+	RunE: jobsRunHandler,
+}
+
+var jobsRetryCmd = &cobra.Command{
+	Use:   "retry <id>...",
+	Short: "Return failed or cancelled jobs to the queue",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateJobs(args, (*jobs.Queue).Retry, "queued again")
+	},
+}
+
+var jobsCancelCmd = &cobra.Command{
+	Use:   "cancel <id>...",
+	Short: "Cancel pending jobs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateJobs(args, (*jobs.Queue).Cancel, "cancelled")
+	},
+}
+
+var jobsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove completed and cancelled jobs",
+	Args:  cobra.NoArgs,
+	RunE:  jobsPruneHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsAddCmd, jobsListCmd, jobsRunCmd, jobsRetryCmd, jobsCancelCmd, jobsPruneCmd)
+
+	jobsCmd.PersistentFlags().StringVar(&jobsQueuePath, "queue", jobs.DefaultPath(), "Job queue file")
+	jobsListCmd.Flags().StringVar(&jobsState, "state", "", "Only list jobs in this state (pending, running, completed, failed, cancelled)")
+	jobsRunCmd.Flags().DurationVar(&jobsWatch, "watch", 0, "Keep running and check for new jobs at this interval")
+}
+
+func jobsAddHandler(cmd *cobra.Command, args []string) error {
+	// Flag parsing is disabled so the job's own flags pass through untouched;
+	// --queue must therefore come before the command name if given
+	if len(args) >= 2 && args[0] == "--queue" {
+		jobsQueuePath, args = args[1], args[2:]
+	} else if strings.HasPrefix(args[0], "--queue=") {
+		jobsQueuePath, args = strings.TrimPrefix(args[0], "--queue="), args[1:]
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: jobs add <compress|decompress|organize> [flags] <source>...")
+	}
+
+	var job *jobs.Job
+	err := jobs.Update(jobsQueuePath, func(queue *jobs.Queue) (err error) {
+		job, err = queue.Add(args[0], args[1:])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	ui.Successf("Queued job %d: %s %s\n", job.ID, job.Command, strings.Join(job.Args, " "))
+	return nil
+}
+
+func jobsListHandler(cmd *cobra.Command, args []string) error {
+	queue, err := jobs.Open(jobsQueuePath)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range queue.Jobs {
+		if jobsState != "" && string(job.State) != jobsState {
+			continue
+		}
+		fmt.Printf("%4d  %-10s %s %s\n", job.ID, job.State, job.Command, strings.Join(job.Args, " "))
+		if job.Error != "" {
+			fmt.Printf("      error: %s\n", job.Error)
+		}
+	}
+	return nil
+}
+
+func jobsRunHandler(cmd *cobra.Command, args []string) error {
+	d, err := startDaemon(cmd, args, "job")
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	for !d.Stopping() {
+		ran, err := runNextJob()
+		if err != nil {
+			return err
+		}
+		if ran {
+			continue
+		}
+		if jobsWatch <= 0 {
+			return nil
+		}
+		// Jobs read the config themselves; reloading it here applies to the worker's own settings
+		if reload, _ := d.Wait(time.Now().Add(jobsWatch)); reload {
+			d.ReloadConfig()
+		}
+	}
+	ui.Infof("Job worker stopped\n")
+	return nil
+}
+
+// runNextJob runs the oldest pending job and reports whether there was one. The queue
+// is only locked while the job is picked and its outcome recorded, so jobs can be added,
+// listed and cancelled while it runs.
+func runNextJob() (bool, error) {
+	var job *jobs.Job
+	var release func()
+	var progressPath string
+	err := jobs.Update(jobsQueuePath, func(queue *jobs.Queue) (err error) {
+		if job = queue.Next(); job == nil {
+			return nil
+		}
+		progressPath = queue.ProgressPath(job.ID)
+		release, err = queue.Start(job)
+		return err
+	})
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	ui.Infof("=== Job %d: %s %s ===\n", job.ID, job.Command, strings.Join(job.Args, " "))
+	runErr := runJob(job, progressPath)
+
+	err = jobs.Update(jobsQueuePath, func(queue *jobs.Queue) error {
+		// Released before the save, but under the queue's lock, so no one sees the job
+		// running without an owner
+		defer release()
+		job, err := queue.Get(job.ID)
+		if err != nil {
+			return err
+		}
+		queue.Finish(job, runErr)
+		return nil
+	})
+	if err != nil {
+		release()
+		return false, err
+	}
+
+	if runErr != nil {
+		ui.Errorf("Job %d failed: %v\n", job.ID, runErr)
+	} else {
+		ui.Successf("Job %d completed\n", job.ID)
+	}
+	return true, nil
+}
+
+// runJob runs a job as a child rom-organizer process so it gets exactly the flags it was
+// queued with. The child also appends its progress events to progressPath.
+func runJob(job *jobs.Job, progressPath string) error {
+	return runSubcommand(append([]string{job.Command}, job.Args...), ui.Output(), os.Stderr, false, progressFileEnv+"="+progressPath)
+}
+
+// runSubcommand runs rom-organizer itself with the given arguments, passing on --config,
+// and env added to this process's environment. A confirmed child answers its prompts with
+// yes, as with --yes; otherwise it gets this process's stdin to ask on.
+func runSubcommand(args []string, stdout, stderr io.Writer, confirmed bool, env ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating rom-organizer executable: %w", err)
+	}
+
+	if configPath != "" {
+		args = append([]string{args[0], "--config", configPath}, args[1:]...)
+	}
+	if sevenZipPath != "" {
+		args = append([]string{args[0], "--7z-path", sevenZipPath}, args[1:]...)
+	}
+	if readOnlyFlagSet {
+		args = append([]string{args[0], fmt.Sprintf("--read-only=%t", readOnly)}, args[1:]...)
+	}
+	if noSnapshot {
+		args = append([]string{args[0], "--no-snapshot"}, args[1:]...)
+	}
+	if zipModes {
+		args = append([]string{args[0], "--zip-modes"}, args[1:]...)
+	}
+	if zipSymlinks {
+		args = append([]string{args[0], "--zip-symlinks"}, args[1:]...)
+	}
+
+	child := exec.Command(exe, args...)
+	child.Stdout = stdout
+	child.Stderr = stderr
+	child.Env = append(os.Environ(), env...)
+	if confirmed {
+		child.Env = append(child.Env, assumeYesEnv+"=1")
+	} else {
+		child.Stdin = os.Stdin
+	}
+	return child.Run()
+}
+
+// updateJobs applies a state change to each job ID and saves the queue. Nothing is
+// saved if any ID can't be changed.
+func updateJobs(ids []string, update func(*jobs.Queue, int) (*jobs.Job, error), verb string) error {
+	var changed []int
+	err := jobs.Update(jobsQueuePath, func(queue *jobs.Queue) error {
+		for _, arg := range ids {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid job ID %q", arg)
+			}
+			if _, err := update(queue, id); err != nil {
+				return err
+			}
+			changed = append(changed, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range changed {
+		ui.Infof("Job %d %s\n", id, verb)
+	}
+	return nil
+}
+
+func jobsPruneHandler(cmd *cobra.Command, args []string) error {
+	var removed int
+	err := jobs.Update(jobsQueuePath, func(queue *jobs.Queue) error {
+		removed = queue.Prune()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ui.Infof("Removed %d finished jobs\n", removed)
+	return nil
+}
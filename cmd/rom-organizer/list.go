@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	listFilter catalog.Filter
+	listSort   string
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list <library> [library...]",
+	Short: "List organized games, filtered by catalog tags and collections",
+	Long: `List the organized games in one or more libraries with their catalog tags.
+
+Repeat --tag to require several tags; --exclude-tag hides games with a tag.
+Games are listed in natural title order ("Game 2" before "Game 10"); use --sort
+to order them by game ID, console or size instead.
+
+Examples:
+  rom-organizer list /mnt/nas/ps3
+  rom-organizer list --tag favorites --tag co-op /mnt/nas/ps3
+  rom-organizer list --collection party --exclude-tag kids /mnt/nas/ps3
+  rom-organizer list --sort size /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: listHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	addCatalogFilterFlags(listCmd, &listFilter)
+	addSortFlag(listCmd, &listSort)
+}
+
+// addSortFlag adds the --sort flag to a command that lists games
+func addSortFlag(cmd *cobra.Command, sortBy *string) {
+	cmd.Flags().StringVar(sortBy, "sort", string(library.SortTitle), "Order games by title, id, console or size (largest first)")
+}
+
+// sortGames orders games by a --sort value, measuring directories only when sorting by size
+func sortGames(games []library.Game, sortBy string) error {
+	by, err := library.ParseSortBy(sortBy)
+	if err != nil {
+		return err
+	}
+	library.SortGames(games, by, func(game library.Game) int64 {
+		size, err := common.DirSize(game.Path)
+		if err != nil {
+			ui.Warnf("Could not measure %s: %v\n", game.Path, err)
+		}
+		return size
+	})
+	return nil
+}
+
+// addCatalogFilterFlags adds the --tag, --exclude-tag, --collection and --no-demos flags to a command
+func addCatalogFilterFlags(cmd *cobra.Command, filter *catalog.Filter) {
+	cmd.Flags().StringArrayVar(&filter.Tags, "tag", nil, "Only include games with this catalog tag (repeatable)")
+	cmd.Flags().StringArrayVar(&filter.ExcludeTags, "exclude-tag", nil, "Skip games with this catalog tag (repeatable)")
+	cmd.Flags().StringVar(&filter.Collection, "collection", "", "Only include games in this catalog collection")
+	cmd.Flags().BoolVar(&filter.ExcludePrerelease, "no-demos", false, "Skip games scan tagged demo or beta")
+}
+
+// findFilteredGames returns the organized games in the libraries that pass the filter
+func findFilteredGames(roots []string, filter catalog.Filter) ([]library.Game, *catalog.Catalog, error) {
+	c, err := openCatalog()
+	if err != nil {
+		return nil, nil, err
+	}
+	libraryFilter := library.Filter{Filter: filter}
+	if err := libraryFilter.Validate(c); err != nil {
+		return nil, nil, err
+	}
+
+	var games []library.Game
+	for _, root := range roots {
+		found, err := library.FindGames(root, appConfig.Layout)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, game := range found {
+			if game.Info.GameInfo != nil {
+				games = append(games, game)
+			}
+		}
+	}
+	games, err = library.FilterGames(games, libraryFilter, c)
+	return games, c, err
+}
+
+func listHandler(cmd *cobra.Command, args []string) error {
+	games, c, err := findFilteredGames(args, listFilter)
+	if err != nil {
+		return err
+	}
+	if err := sortGames(games, listSort); err != nil {
+		return err
+	}
+
+	for _, game := range games {
+		info := game.Info.GameInfo
+		line := fmt.Sprintf("%s [%s]", info.Title, info.GameID)
+		if status := c.Compat(info.GameID); status != nil {
+			line += "  (" + status.Status + ")"
+		}
+		if c.Unverified(info.GameID) {
+			line += "  (metadata unverified)"
+		}
+		if tags := c.Tags(info.GameID); len(tags) > 0 {
+			line += "  #" + strings.Join(tags, " #")
+		}
+		if verbose {
+			line += "  " + game.Path
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -1,29 +1,111 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/config"
 	"github.com/NeilGraham/rom-organizer/internal/consoles"
 	"github.com/NeilGraham/rom-organizer/internal/detect"
 	"github.com/NeilGraham/rom-organizer/internal/organizer"
 	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
 )
 
 var (
-	verbose    bool
-	jsonOutput bool
-	outputDir  string
-	force      bool
-	moveSource bool
+	verbose     bool
+	verbosity   int
+	quiet       bool
+	noColor     bool
+	jsonOutput  bool
+	outputDirs  []string
+	force       bool
+	moveSource  bool
+	organizeBy  string
+	configPath  string
+	preHook     string
+	postHook    string
+	retries     int
+	retryDelay  time.Duration
+	failFast    bool
+	maxErrors   int
+	checksum    bool
+	checksumAlg string
+	verifyHash  string
+	par2        int
+	allowBadID  bool
+	progressFmt string
+	licenseDirs []string
+	dedupPool   bool
+	timings     bool
+	quickVerify bool
+	noTrash     bool
+
+	// reproducible makes new archives byte-identical for identical games
+	reproducible bool
+
+	// sevenZipPath and sevenZipArgs select the 7z executable and add switches to it
+	sevenZipPath string
+	sevenZipArgs string
+
+	// stream writes decompressed games to stdout as a tar stream instead of a directory
+	stream bool
+
+	// tuning, solidBlock and dictionary set the 7z solid block and dictionary sizes
+	tuning     string
+	solidBlock string
+	dictionary string
+
+	// onCollision is the policy for a folder name already used by a different game
+	onCollision string
+
+	// componentPolicies are --component NAME=POLICY values for optional disc folders
+	componentPolicies []string
+
+	// maxDepth and maxEntries bound how much of each source detection reads
+	maxDepth   int
+	maxEntries int
+
+	// stagingDir holds games bound for an sftp:// --output until they are uploaded
+	stagingDir string
+
+	// noChecksumFiles skips checking sources against their .sfv and .md5 files
+	noChecksumFiles bool
+
+	// torrentSafe and settle protect intake folders still in use by a download client
+	torrentSafe bool
+	settle      time.Duration
+
+	// chown gives created games to this user:group instead of the config's ownership
+	chown string
+
+	// heartbeat, stallTimeout and killStalled override the watchdog settings of the config
+	heartbeat    time.Duration
+	stallTimeout time.Duration
+	killStalled  bool
+
+	// zipModes and zipSymlinks keep the permissions and symbolic links of input zips
+	zipModes    bool
+	zipSymlinks bool
+
+	// titleOverride and idOverride (--set-title, --set-id) name a single source whose PARAM.SFO is missing or wrong
+	titleOverride string
+	idOverride    string
+
+	// appConfig holds the settings loaded from the config file before any command runs
+	appConfig = config.Default()
 )
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		ui.Errorf("Error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -32,8 +114,118 @@ var rootCmd = &cobra.Command{
 	Short: "Tools for working with ROM game files",
 	Long: `ROM Organizer - A collection of tools for working with ROM game files.
 
-This toolkit provides utilities for organizing and optimizing ROM game files from various consoles.`,
-	Version: "1.0.0",
+This toolkit provides utilities for organizing and optimizing ROM game files from various consoles.
+
+Settings are read from a YAML config file (see --config). Example:
+
+  layout:
+    updates_dir: _updates
+    dlc_dir: _dlc
+    extra_dirs: [_saves, _manuals, _artwork]`,
+	Version:           common.Version,
+	PersistentPreRunE: prepareRun,
+	// main prints errors itself; usage is only shown for --help
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// prepareRun applies the output flags and loads the config file before any command runs
+func prepareRun(cmd *cobra.Command, args []string) error {
+	if err := setupOutput(); err != nil {
+		return err
+	}
+	if os.Getenv(assumeYesEnv) == "1" {
+		assumeYes = true
+	}
+	return loadConfig(cmd, args)
+}
+
+// setupOutput sets the output level and color from -v/-vv, -q and --no-color
+func setupOutput() error {
+	if quiet && verbosity > 0 {
+		return fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+
+	level := ui.LevelNormal
+	switch {
+	case quiet:
+		level = ui.LevelQuiet
+	case verbosity == 1:
+		level = ui.LevelVerbose
+	case verbosity >= 2:
+		level = ui.LevelDebug
+	}
+	ui.SetLevel(level)
+	verbose = ui.IsVerbose()
+
+	if noColor {
+		ui.SetColor(false)
+	}
+	return nil
+}
+
+// loadConfig loads the config file given by --config, or the default location
+func loadConfig(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	appConfig = cfg
+
+	common.SevenZipPath = cfg.Compression.SevenZip
+	if sevenZipPath != "" {
+		common.SevenZipPath = sevenZipPath
+	}
+
+	common.Watchdog = cfg.Watchdog
+	if cmd.Flags().Changed("heartbeat") {
+		common.Watchdog.Heartbeat = heartbeat
+	}
+	if cmd.Flags().Changed("stall-timeout") {
+		common.Watchdog.StallTimeout = stallTimeout
+	}
+	if cmd.Flags().Changed("kill-stalled") {
+		common.Watchdog.KillStalled = killStalled
+	}
+	if err := common.Watchdog.Validate(); err != nil {
+		return fmt.Errorf("--heartbeat, --stall-timeout, --kill-stalled: %w", err)
+	}
+	common.ZipExtraction = common.ZipOptions{Modes: zipModes, Symlinks: zipSymlinks}
+
+	// Detection rules for other systems; a configured file must exist
+	if err := detect.LoadIndicators(cfg.IndicatorsPath(), cfg.Indicators != ""); err != nil {
+		return err
+	}
+
+	// --torrent-safe and --settle override the intake settings; torrent-safe runs wait
+	// organizer.DefaultSettle unless a settle time is given
+	if !cmd.Flags().Changed("torrent-safe") {
+		torrentSafe = cfg.Intake.TorrentSafe
+	}
+	if !cmd.Flags().Changed("settle") {
+		settle = cfg.Intake.Settle
+		if settle == 0 && torrentSafe {
+			settle = organizer.DefaultSettle
+		}
+	}
+
+	// The library settings replace the defaults of --output and --organize-by
+	if cmd.Flags().Lookup("organize-by") != nil {
+		if len(cfg.Library.Roots) > 0 && !cmd.Flags().Changed("output") {
+			outputDirs = []string{cfg.Library.Roots[0]}
+		}
+		if cfg.Library.OrganizeBy != "" && !cmd.Flags().Changed("organize-by") {
+			organizeBy = cfg.Library.OrganizeBy
+		}
+	}
+
+	// --read-only on the command line replaces both config settings
+	readOnlyFlagSet = cmd.Flags().Changed("read-only")
+	if !readOnlyFlagSet {
+		readOnly = cfg.ReadOnly.Enabled
+		readOnlyNetwork = cfg.ReadOnly.NetworkMounts
+	}
+	return nil
 }
 
 var metadataCmd = &cobra.Command{
@@ -80,8 +272,11 @@ Examples:
   rom-organizer c /path/to/game1 /path/to/game2 /path/to/game3
   rom-organizer compress --output /target/dir /path/to/game.zip
   rom-organizer c --move /path/to/game_folder1 /path/to/game_folder2
-  rom-organizer compress --force /path/to/game_folder`,
-	Args: cobra.MinimumNArgs(1),
+  rom-organizer compress --force /path/to/game_folder
+  rom-organizer compress --output /mnt/nas/ps3 --output /mnt/backup/ps3 /path/to/game_folder
+  rom-organizer compress -r --only-format decompressed --min-size 10GB -o /mnt/nas/ps3 /mnt/nas/ps3
+  rom-organizer compress --retry-failed last`,
+	Args: batchArgs,
 	RunE: compressHandler,
 }
 
@@ -110,8 +305,9 @@ Examples:
   rom-organizer d /path/to/game1 /path/to/game2 /path/to/game3
   rom-organizer decompress --output /target/dir /path/to/game.zip
   rom-organizer d --move /path/to/game_folder1 /path/to/game_folder2
-  rom-organizer decompress --force /path/to/game_folder`,
-	Args: cobra.MinimumNArgs(1),
+  rom-organizer decompress --force /path/to/game_folder
+  rom-organizer decompress --stream "/mnt/nas/ps3/Game [BLUS30001]" | tar -x -C /mnt/usb`,
+	Args: batchArgs,
 	RunE: decompressHandler,
 }
 
@@ -142,8 +338,14 @@ Examples:
   rom-organizer o /path/to/game1 /path/to/game2 /path/to/game3
   rom-organizer organize --output /target/dir /path/to/game_folder
   rom-organizer o --move /path/to/game_folder1 /path/to/game_folder2
-  rom-organizer organize --force /path/to/existing_organized_game1 /path/to/game2`,
-	Args: cobra.MinimumNArgs(1),
+  rom-organizer organize --force /path/to/existing_organized_game1 /path/to/game2
+  rom-organizer organize --organize-by first-letter --output /library /path/to/games/*
+  rom-organizer organize --split --output /roms/mame /downloads/mame
+
+With --split, --merged or --non-merged, sources are arcade or cartridge ROMs instead: the
+sets of a DAT imported with "dat import" are rebuilt from the zip archives and files found
+in them, one zip per set in the output directory.`,
+	Args: batchArgs,
 	RunE: organizeHandler,
 }
 
@@ -154,60 +356,442 @@ func init() {
 	rootCmd.AddCommand(decompressCmd)
 	rootCmd.AddCommand(organizeCmd)
 
+	// Global flags
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", fmt.Sprintf("Config file (default %s)", config.DefaultPath()))
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Show detailed information (-vv also shows external commands)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only show warnings and errors")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also disabled by NO_COLOR or when not a terminal)")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "Refuse any operation that would modify or delete content in a library")
+	rootCmd.PersistentFlags().BoolVar(&noSnapshot, "no-snapshot", false, "Don't run the configured snapshot command before destructive operations")
+	rootCmd.PersistentFlags().StringVar(&sevenZipPath, "7z-path", "", "7z executable to use, e.g. /usr/local/bin/7zz (overrides config; default the first of 7z, 7za, 7zr in PATH)")
+	rootCmd.PersistentFlags().DurationVar(&heartbeat, "heartbeat", time.Minute, "Report that a 7z run or copy is still working at this interval (0 disables; overrides config)")
+	rootCmd.PersistentFlags().DurationVar(&stallTimeout, "stall-timeout", 10*time.Minute, "Warn when a 7z run or copy makes no progress for this long (0 disables; overrides config)")
+	rootCmd.PersistentFlags().BoolVar(&killStalled, "kill-stalled", false, "Stop a stalled 7z run or copy and fail it, so --retries can retry it (overrides config)")
+	rootCmd.PersistentFlags().BoolVar(&zipModes, "zip-modes", false, "Keep the file and folder permissions stored in input zips (default 0644 files, 0755 folders)")
+	rootCmd.PersistentFlags().BoolVar(&zipSymlinks, "zip-symlinks", false, "Recreate the symbolic links stored in input zips instead of skipping them")
+
 	// Add flags to metadata command
-	metadataCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed information")
 	metadataCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output in JSON format")
+	metadataCmd.Flags().StringArrayVar(&licenseDirs, "licenses", nil, "Also look for .rap/.rif licenses in this directory (repeatable)")
+	addSearchFlags(metadataCmd)
 
 	// Add flags to compress command
-	compressCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory for compressed game")
+	compressCmd.Flags().StringArrayVarP(&outputDirs, "output", "o", []string{"."}, "Output directory for compressed game (repeat to mirror to several destinations)")
 	compressCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing output directory")
-	compressCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed information")
+	compressCmd.Flags().StringVar(&onCollision, "on-collision", "", "When a different game already has the target folder name: fail (default), suffix, skip or overwrite")
+	compressCmd.Flags().StringVar(&stagingDir, "staging", "", "Local folder games are organized in before uploading to an sftp:// --output (default a temporary folder)")
+	compressCmd.Flags().BoolVar(&noChecksumFiles, "no-checksum-files", false, "Don't check sources against the .sfv and .md5 files in them before organizing")
+	compressCmd.Flags().BoolVar(&torrentSafe, "torrent-safe", false, "Treat sources as read-only intake still being seeded: never write inside them, refuse --move, and wait for them to stop changing")
+	compressCmd.Flags().DurationVar(&settle, "settle", 0, "Skip sources whose files change within this long before they are organized (default 30s with --torrent-safe)")
+	compressCmd.Flags().StringVar(&chown, "chown", "", "Give created games to this user:group, user or :group (overrides the config's ownership)")
+	compressCmd.Flags().StringArrayVar(&componentPolicies, "component", nil, "What to do with an optional disc folder: PS3_EXTRA=separate, PS3_UPDATE=exclude, ... (include, exclude or separate; repeatable)")
 	compressCmd.Flags().BoolVarP(&moveSource, "move", "m", false, "Move files instead of copying (deletes source directory - only works with unorganized directories)")
+	compressCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before a --move that compresses onto another file system")
+	compressCmd.Flags().BoolVar(&quickVerify, "quick-verify", false, "With --move, only compare file names, sizes and PARAM.SFO before deleting the source")
+	compressCmd.Flags().BoolVar(&noTrash, "no-trash", false, "With --move, delete sources right away instead of keeping them in the trash for undo")
+	compressCmd.Flags().StringVar(&organizeBy, "organize-by", "none", "Group output into subfolders: none or first-letter (A/, B/, ..., 0-9/)")
+	compressCmd.Flags().StringVar(&preHook, "pre-hook", "", "Shell command run before each game (overrides config)")
+	compressCmd.Flags().StringVar(&postHook, "post-hook", "", "Shell command run after each game (overrides config)")
+	compressCmd.Flags().IntVar(&retries, "retries", 0, "Retry copy and 7z operations this many times on failure")
+	compressCmd.Flags().DurationVar(&retryDelay, "retry-delay", 5*time.Second, "Delay before the first retry (doubles after each attempt)")
+	compressCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop the batch at the first failed game")
+	compressCmd.Flags().IntVar(&maxErrors, "max-errors", 0, "Abort the batch after this many failed games (0 for no limit)")
+	compressCmd.Flags().BoolVar(&allowBadID, "allow-invalid-id", false, "Organize games whose PARAM.SFO has a malformed or placeholder game ID (with a warning)")
+	compressCmd.Flags().StringVar(&titleOverride, "set-title", "", "Title to organize a single game under, for a missing or wrong PARAM.SFO (marks it unverified)")
+	compressCmd.Flags().StringVar(&idOverride, "set-id", "", "Game ID to organize a single game under, for a missing or wrong PARAM.SFO (marks it unverified)")
+	compressCmd.Flags().BoolVar(&timings, "timings", false, "Report the time spent detecting, copying and compressing each game, and the throughput")
+	compressCmd.Flags().StringVar(&progressFmt, "progress-format", "text", "Progress output: text, or ndjson to stream one JSON event per line on stdout")
+	addBatchFilterFlags(compressCmd)
+	addSearchFlags(compressCmd)
+	addRunFlags(compressCmd)
+	compressCmd.Flags().BoolVar(&checksum, "sha256", false, "Write a game.7z.sha256 checksum file next to each new archive (same as --checksum=sha256)")
+	compressCmd.Flags().StringVar(&checksumAlg, "checksum", "", "Write a game.7z.<algorithm> checksum file next to each new archive: sha256, sha1, md5, crc32 or xxh64 (alone, the config's hashes.manifest)")
+	compressCmd.Flags().Lookup("checksum").NoOptDefVal = defaultChecksum
+	compressCmd.Flags().StringVar(&verifyHash, "verify-hash", "", "Hash comparing a moved game/ folder with its source: xxh64, crc32, md5, sha1 or sha256 (default the config's hashes.verify)")
+	compressCmd.Flags().StringVar(&sevenZipArgs, "7z-args", "", "Extra 7z switches for new archives, e.g. \"-mqs=on -mmt=4\" (added to compression.*.extra_args)")
+	compressCmd.Flags().StringVar(&tuning, "tuning", "", "Solid block and dictionary preset: max (default), balanced or fast-access (faster single-file extraction)")
+	compressCmd.Flags().StringVar(&solidBlock, "solid-block", "", "7z solid block size: on, off or a size such as 64m (overrides --tuning)")
+	compressCmd.Flags().StringVar(&dictionary, "dictionary", "", "LZMA dictionary size such as 16m (overrides --tuning; default 32m at level 9)")
+	compressCmd.Flags().BoolVar(&reproducible, "reproducible", false, "Create byte-identical game.7z files for identical games (sorted entries, no timestamps, single-threaded 7z)")
+	compressCmd.Flags().IntVar(&par2, "par2", 0, "Create PAR2 recovery data for each new archive with this redundancy percent (requires par2)")
 
 	// Add flags to decompress command
-	decompressCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory for decompressed game")
+	decompressCmd.Flags().StringArrayVarP(&outputDirs, "output", "o", []string{"."}, "Output directory for decompressed game (repeat to mirror to several destinations)")
 	decompressCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing output directory")
-	decompressCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed information")
+	decompressCmd.Flags().StringVar(&onCollision, "on-collision", "", "When a different game already has the target folder name: fail (default), suffix, skip or overwrite")
+	decompressCmd.Flags().StringVar(&stagingDir, "staging", "", "Local folder games are organized in before uploading to an sftp:// --output (default a temporary folder)")
+	decompressCmd.Flags().BoolVar(&noChecksumFiles, "no-checksum-files", false, "Don't check sources against the .sfv and .md5 files in them before organizing")
+	decompressCmd.Flags().BoolVar(&torrentSafe, "torrent-safe", false, "Treat sources as read-only intake still being seeded: never write inside them, refuse --move, and wait for them to stop changing")
+	decompressCmd.Flags().DurationVar(&settle, "settle", 0, "Skip sources whose files change within this long before they are organized (default 30s with --torrent-safe)")
+	decompressCmd.Flags().StringVar(&chown, "chown", "", "Give created games to this user:group, user or :group (overrides the config's ownership)")
+	decompressCmd.Flags().StringArrayVar(&componentPolicies, "component", nil, "What to do with an optional disc folder: PS3_EXTRA=separate, PS3_UPDATE=exclude, ... (include, exclude or separate; repeatable)")
+	decompressCmd.Flags().BoolVar(&stream, "stream", false, "Write organized games to stdout as a tar stream instead of an output directory")
 	decompressCmd.Flags().BoolVarP(&moveSource, "move", "m", false, "Move files instead of copying (deletes source directory - only works with unorganized directories)")
+	decompressCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before a --move that has to copy across file systems")
+	decompressCmd.Flags().BoolVar(&quickVerify, "quick-verify", false, "With --move, only compare file names, sizes and PARAM.SFO before deleting the source")
+	decompressCmd.Flags().BoolVar(&noTrash, "no-trash", false, "With --move, delete sources right away instead of keeping them in the trash for undo")
+	decompressCmd.Flags().StringVar(&verifyHash, "verify-hash", "", "Hash comparing a moved game/ folder with its source: xxh64, crc32, md5, sha1 or sha256 (default the config's hashes.verify)")
+	decompressCmd.Flags().StringVar(&organizeBy, "organize-by", "none", "Group output into subfolders: none or first-letter (A/, B/, ..., 0-9/)")
+	decompressCmd.Flags().StringVar(&preHook, "pre-hook", "", "Shell command run before each game (overrides config)")
+	decompressCmd.Flags().StringVar(&postHook, "post-hook", "", "Shell command run after each game (overrides config)")
+	decompressCmd.Flags().IntVar(&retries, "retries", 0, "Retry copy and 7z operations this many times on failure")
+	decompressCmd.Flags().DurationVar(&retryDelay, "retry-delay", 5*time.Second, "Delay before the first retry (doubles after each attempt)")
+	decompressCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop the batch at the first failed game")
+	decompressCmd.Flags().IntVar(&maxErrors, "max-errors", 0, "Abort the batch after this many failed games (0 for no limit)")
+	decompressCmd.Flags().BoolVar(&allowBadID, "allow-invalid-id", false, "Organize games whose PARAM.SFO has a malformed or placeholder game ID (with a warning)")
+	decompressCmd.Flags().StringVar(&titleOverride, "set-title", "", "Title to organize a single game under, for a missing or wrong PARAM.SFO (marks it unverified)")
+	decompressCmd.Flags().StringVar(&idOverride, "set-id", "", "Game ID to organize a single game under, for a missing or wrong PARAM.SFO (marks it unverified)")
+	decompressCmd.Flags().BoolVar(&dedupPool, "dedup", false, "Store files shared between games once in the output directory's _pool (hard links)")
+	decompressCmd.Flags().BoolVar(&timings, "timings", false, "Report the time spent detecting, copying and compressing each game, and the throughput")
+	decompressCmd.Flags().StringVar(&progressFmt, "progress-format", "text", "Progress output: text, or ndjson to stream one JSON event per line on stdout")
+	addBatchFilterFlags(decompressCmd)
+	addSearchFlags(decompressCmd)
+	addRunFlags(decompressCmd)
 
 	// Add flags to organize command
-	organizeCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory for organized game")
+	organizeCmd.Flags().StringArrayVarP(&outputDirs, "output", "o", []string{"."}, "Output directory for organized game (repeat to mirror to several destinations)")
 	organizeCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing output directory")
-	organizeCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed information")
+	organizeCmd.Flags().StringVar(&onCollision, "on-collision", "", "When a different game already has the target folder name: fail (default), suffix, skip or overwrite")
+	organizeCmd.Flags().StringVar(&stagingDir, "staging", "", "Local folder games are organized in before uploading to an sftp:// --output (default a temporary folder)")
+	organizeCmd.Flags().BoolVar(&noChecksumFiles, "no-checksum-files", false, "Don't check sources against the .sfv and .md5 files in them before organizing")
+	organizeCmd.Flags().BoolVar(&torrentSafe, "torrent-safe", false, "Treat sources as read-only intake still being seeded: never write inside them, refuse --move, and wait for them to stop changing")
+	organizeCmd.Flags().DurationVar(&settle, "settle", 0, "Skip sources whose files change within this long before they are organized (default 30s with --torrent-safe)")
+	organizeCmd.Flags().StringVar(&chown, "chown", "", "Give created games to this user:group, user or :group (overrides the config's ownership)")
+	organizeCmd.Flags().StringArrayVar(&componentPolicies, "component", nil, "What to do with an optional disc folder: PS3_EXTRA=separate, PS3_UPDATE=exclude, ... (include, exclude or separate; repeatable)")
 	organizeCmd.Flags().BoolVarP(&moveSource, "move", "m", false, "Move files instead of copying (deletes source directory - only works with unorganized directories)")
+	organizeCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before a --move that has to copy across file systems")
+	organizeCmd.Flags().BoolVar(&quickVerify, "quick-verify", false, "With --move, only compare file names, sizes and PARAM.SFO before deleting the source")
+	organizeCmd.Flags().BoolVar(&noTrash, "no-trash", false, "With --move, delete sources right away instead of keeping them in the trash for undo")
+	organizeCmd.Flags().StringVar(&organizeBy, "organize-by", "none", "Group output into subfolders: none or first-letter (A/, B/, ..., 0-9/)")
+	organizeCmd.Flags().StringVar(&preHook, "pre-hook", "", "Shell command run before each game (overrides config)")
+	organizeCmd.Flags().StringVar(&postHook, "post-hook", "", "Shell command run after each game (overrides config)")
+	organizeCmd.Flags().IntVar(&retries, "retries", 0, "Retry copy and 7z operations this many times on failure")
+	organizeCmd.Flags().DurationVar(&retryDelay, "retry-delay", 5*time.Second, "Delay before the first retry (doubles after each attempt)")
+	organizeCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop the batch at the first failed game")
+	organizeCmd.Flags().IntVar(&maxErrors, "max-errors", 0, "Abort the batch after this many failed games (0 for no limit)")
+	organizeCmd.Flags().BoolVar(&allowBadID, "allow-invalid-id", false, "Organize games whose PARAM.SFO has a malformed or placeholder game ID (with a warning)")
+	organizeCmd.Flags().StringVar(&titleOverride, "set-title", "", "Title to organize a single game under, for a missing or wrong PARAM.SFO (marks it unverified)")
+	organizeCmd.Flags().StringVar(&idOverride, "set-id", "", "Game ID to organize a single game under, for a missing or wrong PARAM.SFO (marks it unverified)")
+	organizeCmd.Flags().BoolVar(&dedupPool, "dedup", false, "Store files shared between games once in the output directory's _pool (hard links)")
+	organizeCmd.Flags().BoolVar(&timings, "timings", false, "Report the time spent detecting, copying and compressing each game, and the throughput")
+	organizeCmd.Flags().StringVar(&progressFmt, "progress-format", "text", "Progress output: text, or ndjson to stream one JSON event per line on stdout")
+	addBatchFilterFlags(organizeCmd)
+	addSearchFlags(organizeCmd)
+	addRunFlags(organizeCmd)
+	organizeCmd.Flags().BoolVar(&checksum, "sha256", false, "Write a game.7z.sha256 checksum file next to each new archive (same as --checksum=sha256)")
+	organizeCmd.Flags().StringVar(&checksumAlg, "checksum", "", "Write a game.7z.<algorithm> checksum file next to each new archive: sha256, sha1, md5, crc32 or xxh64 (alone, the config's hashes.manifest)")
+	organizeCmd.Flags().Lookup("checksum").NoOptDefVal = defaultChecksum
+	organizeCmd.Flags().StringVar(&verifyHash, "verify-hash", "", "Hash comparing a moved game/ folder with its source: xxh64, crc32, md5, sha1 or sha256 (default the config's hashes.verify)")
+	organizeCmd.Flags().StringVar(&sevenZipArgs, "7z-args", "", "Extra 7z switches for new archives, e.g. \"-mqs=on -mmt=4\" (added to compression.*.extra_args)")
+	organizeCmd.Flags().StringVar(&tuning, "tuning", "", "Solid block and dictionary preset: max (default), balanced or fast-access (faster single-file extraction)")
+	organizeCmd.Flags().StringVar(&solidBlock, "solid-block", "", "7z solid block size: on, off or a size such as 64m (overrides --tuning)")
+	organizeCmd.Flags().StringVar(&dictionary, "dictionary", "", "LZMA dictionary size such as 16m (overrides --tuning; default 32m at level 9)")
+	organizeCmd.Flags().BoolVar(&reproducible, "reproducible", false, "Create byte-identical game.7z files for identical games (sorted entries, no timestamps, single-threaded 7z)")
+	organizeCmd.Flags().IntVar(&par2, "par2", 0, "Create PAR2 recovery data for each new archive with this redundancy percent (requires par2)")
+	organizeCmd.Flags().BoolVar(&setSplit, "split", false, "Rebuild arcade or cartridge ROM sets from an imported DAT as split sets: each set holds only its own ROMs")
+	organizeCmd.Flags().BoolVar(&setMerged, "merged", false, "Rebuild ROM sets as merged sets: a parent's zip also holds its clones' ROMs")
+	organizeCmd.Flags().BoolVar(&setNonMerged, "non-merged", false, "Rebuild ROM sets as non-merged sets: each set holds every ROM it needs except its BIOS's")
+	organizeCmd.Flags().StringVar(&datName, "dat", "", "Imported DAT whose sets --split, --merged or --non-merged rebuilds (default the only one)")
+	organizeCmd.MarkFlagsMutuallyExclusive(setPolicyFlags...)
 }
 
 func compressHandler(cmd *cobra.Command, args []string) error {
-	opts := organizer.OrganizeOptions{
-		OutputDir:  outputDir,
-		Force:      force,
-		Verbose:    verbose,
-		MoveSource: moveSource,
-		Format:     organizer.Compressed,
+	if retryFailed != "" {
+		return retryFailedHandler(cmd)
+	}
+	opts, err := newOrganizeOptions(organizer.Compressed)
+	if err != nil {
+		return err
+	}
+	args, err = batchSources(args)
+	if err != nil || len(args) == 0 {
+		return err
+	}
+	if err := checkOverrides(args); err != nil {
+		return err
 	}
-	return organizer.OrganizeGames(args, opts)
+	if err := checkWritable(batchTargets(args, opts)...); err != nil {
+		return err
+	}
+	if err := confirmCrossDeviceMove(args, opts); err != nil {
+		return err
+	}
+	if err := batchSnapshot(args, opts); err != nil {
+		return err
+	}
+	return runBatch(cmd, args, opts)
 }
 
 func decompressHandler(cmd *cobra.Command, args []string) error {
-	opts := organizer.OrganizeOptions{
-		OutputDir:  outputDir,
-		Force:      force,
-		Verbose:    verbose,
-		MoveSource: moveSource,
-		Format:     organizer.Decompressed,
+	if stream {
+		if cmd.Flags().Changed("output") || moveSource {
+			return fmt.Errorf("--stream cannot be combined with --output or --move")
+		}
+		return streamGames(args)
+	}
+	if retryFailed != "" {
+		return retryFailedHandler(cmd)
+	}
+	opts, err := newOrganizeOptions(organizer.Decompressed)
+	if err != nil {
+		return err
+	}
+	args, err = batchSources(args)
+	if err != nil || len(args) == 0 {
+		return err
+	}
+	if err := checkOverrides(args); err != nil {
+		return err
 	}
-	return organizer.OrganizeGames(args, opts)
+	if err := checkWritable(batchTargets(args, opts)...); err != nil {
+		return err
+	}
+	if err := confirmCrossDeviceMove(args, opts); err != nil {
+		return err
+	}
+	if err := batchSnapshot(args, opts); err != nil {
+		return err
+	}
+	return runBatch(cmd, args, opts)
 }
 
 func organizeHandler(cmd *cobra.Command, args []string) error {
-	opts := organizer.OrganizeOptions{
-		OutputDir:  outputDir,
+	if retryFailed != "" {
+		return retryFailedHandler(cmd)
+	}
+	if policy := setPolicy(); policy != "" {
+		return organizeROMSets(args, policy)
+	}
+	opts, err := newOrganizeOptions(organizer.KeepOriginal)
+	if err != nil {
+		return err
+	}
+	args, err = batchSources(args)
+	if err != nil || len(args) == 0 {
+		return err
+	}
+	if err := checkOverrides(args); err != nil {
+		return err
+	}
+	if err := checkWritable(batchTargets(args, opts)...); err != nil {
+		return err
+	}
+	if err := confirmCrossDeviceMove(args, opts); err != nil {
+		return err
+	}
+	if err := batchSnapshot(args, opts); err != nil {
+		return err
+	}
+	return runBatch(cmd, args, opts)
+}
+
+// newGameFormat returns the format the config's library.format gives new sources
+func newGameFormat(format string) organizer.GameFormat {
+	if format == "compressed" {
+		return organizer.Compressed
+	}
+	return organizer.Decompressed
+}
+
+// newOrganizeOptions builds organizer options from the shared command flags and config.
+// The first --output is the primary destination; any others receive mirrored copies.
+func newOrganizeOptions(format organizer.GameFormat) (organizer.OrganizeOptions, error) {
+	groupBy, err := organizer.ParseOrganizeBy(organizeBy)
+	if err != nil {
+		return organizer.OrganizeOptions{}, err
+	}
+
+	if len(outputDirs) == 0 {
+		outputDirs = []string{"."}
+	}
+
+	if maxErrors < 0 {
+		return organizer.OrganizeOptions{}, fmt.Errorf("--max-errors must not be negative")
+	}
+	if torrentSafe && moveSource {
+		return organizer.OrganizeOptions{}, fmt.Errorf("--move cannot be combined with --torrent-safe, which never changes sources")
+	}
+	if settle < 0 {
+		return organizer.OrganizeOptions{}, fmt.Errorf("--settle must not be negative")
+	}
+	if par2 < 0 || par2 > 100 {
+		return organizer.OrganizeOptions{}, fmt.Errorf("--par2 must be a percentage between 0 and 100")
+	}
+	search, err := searchLimits()
+	if err != nil {
+		return organizer.OrganizeOptions{}, err
+	}
+
+	errorLimit := maxErrors
+	if failFast {
+		errorLimit = 1
+	}
+
+	progress, err := newProgressFunc(progressFmt)
+	if err != nil {
+		return organizer.OrganizeOptions{}, err
+	}
+
+	checksumHash, verify, err := hashFlags()
+	if err != nil {
+		return organizer.OrganizeOptions{}, err
+	}
+
+	collisionPolicy := appConfig.OnCollision
+	if onCollision != "" {
+		collisionPolicy = onCollision
+	}
+	collision, err := common.ParseCollisionPolicy(collisionPolicy)
+	if err != nil {
+		return organizer.OrganizeOptions{}, fmt.Errorf("--on-collision: %w", err)
+	}
+
+	configComponents, err := appConfig.ComponentPolicies()
+	if err != nil {
+		return organizer.OrganizeOptions{}, err
+	}
+	components, err := common.ParseComponentPolicies(configComponents, componentPolicies)
+	if err != nil {
+		return organizer.OrganizeOptions{}, fmt.Errorf("--component: %w", err)
+	}
+
+	compression := make(map[string]common.ArchiveOptions)
+	for _, console := range consoles.NewRegistry().GetSupportedConsoles() {
+		archive := appConfig.Compression.ArchiveOptions(console)
+		archive.Reproducible = archive.Reproducible || reproducible
+		archive.ExtraArgs = append(archive.ExtraArgs[:len(archive.ExtraArgs):len(archive.ExtraArgs)], strings.Fields(sevenZipArgs)...)
+		if tuning != "" {
+			if err := archive.ApplyTuning(tuning); err != nil {
+				return organizer.OrganizeOptions{}, fmt.Errorf("--tuning: %w", err)
+			}
+		}
+		if solidBlock != "" {
+			archive.SolidBlock = solidBlock
+		}
+		if dictionary != "" {
+			archive.Dictionary = dictionary
+		}
+		if err := archive.Validate(); err != nil {
+			return organizer.OrganizeOptions{}, err
+		}
+		compression[console.ShortName()] = archive
+	}
+
+	// --chown replaces the config's owners, including those per library
+	owners, err := appConfig.Ownership.Rules()
+	if chown != "" {
+		owners = common.OwnerRules{}
+		owners.Default, err = common.ParseOwner(chown)
+		if err != nil {
+			err = fmt.Errorf("--chown: %w", err)
+		}
+	}
+	if err != nil {
+		return organizer.OrganizeOptions{}, err
+	}
+
+	// Hook flags override the hooks from the config file
+	pre, post := appConfig.Hooks.Pre, appConfig.Hooks.Post
+	if preHook != "" {
+		pre = preHook
+	}
+	if postHook != "" {
+		post = postHook
+	}
+
+	return organizer.OrganizeOptions{
+		OutputDir:  outputDirs[0],
+		MirrorDirs: outputDirs[1:],
 		Force:      force,
-		Verbose:    verbose,
 		MoveSource: moveSource,
-		Format:     organizer.KeepOriginal,
+		Format:     format,
+		OrganizeBy: groupBy,
+		Layout:     appConfig.Layout,
+		PreHook:    pre,
+		PostHook:   post,
+		Retry:      common.RetryPolicy{Retries: retries, Delay: retryDelay},
+		MaxErrors:  errorLimit,
+		PAR2:       par2,
+
+		Compression:    compression,
+		AllowInvalidID: allowBadID,
+		Progress:       progress,
+		DiscKey:        lookupDiscKey,
+		Dedup:          dedupPool,
+		Timings:        timings,
+		QuickVerify:    quickVerify,
+		NoTrash:        noTrash,
+		JunkFiles:      appConfig.Cleanup.JunkFiles,
+		Checksum:       checksumHash,
+		VerifyHash:     verify,
+		TitleRules:     appConfig.Titles,
+		NameLimits:     appConfig.Names.NameLimits,
+		Homebrew:       appConfig.Homebrew,
+		Search:         search,
+		NewGameFormat:  newGameFormat(appConfig.Library.Format),
+
+		CompressionOverride: lookupCompressionOverride,
+		OnCollision:         collision,
+		Components:          components,
+		Owners:              owners,
+		Staging:             stagingDir,
+		TorrentSafe:         torrentSafe,
+		NoChecksumFiles:     noChecksumFiles,
+		Settle:              settle,
+		TitleOverride:       strings.TrimSpace(titleOverride),
+		GameIDOverride:      strings.TrimSpace(idOverride),
+	}, nil
+}
+
+// checkOverrides refuses --set-title and --set-id for more than one source, which they
+// would all be organized under
+func checkOverrides(sources []string) error {
+	if (titleOverride != "" || idOverride != "") && len(sources) > 1 {
+		return fmt.Errorf("--set-title and --set-id name a single game, but %d sources were given", len(sources))
+	}
+	return nil
+}
+
+// addSearchFlags adds the flags bounding how much of each source detection reads
+func addSearchFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&maxDepth, "max-depth", detect.MaxSearchDepth, "How many folder levels below each source to search for a game")
+	cmd.Flags().IntVar(&maxEntries, "max-entries", detect.MaxSearchEntries, "How many files and folders to look at in each source before giving up")
+}
+
+// searchLimits returns the detection limits from --max-depth and --max-entries
+func searchLimits() (detect.SearchLimits, error) {
+	if maxDepth < 1 {
+		return detect.SearchLimits{}, fmt.Errorf("--max-depth must be at least 1")
+	}
+	if maxEntries < 1 {
+		return detect.SearchLimits{}, fmt.Errorf("--max-entries must be at least 1")
 	}
-	return organizer.OrganizeGames(args, opts)
+	return detect.SearchLimits{MaxDepth: maxDepth, MaxEntries: maxEntries}, nil
+}
+
+// defaultChecksum is the value of a bare --checksum, which uses the config's manifest hash
+const defaultChecksum = "default"
+
+// hashFlags resolves --checksum, --sha256 and --verify-hash against the config defaults
+func hashFlags() (checksumHash, verify common.HashAlgorithm, err error) {
+	switch {
+	case checksumAlg == defaultChecksum:
+		checksumHash = appConfig.Hashes.ManifestHash()
+	case checksumAlg != "":
+		if checksumHash, err = common.ParseHashAlgorithm(checksumAlg); err != nil {
+			return "", "", fmt.Errorf("--checksum: %w", err)
+		}
+	case checksum:
+		checksumHash = common.HashSHA256
+	}
+
+	verify = appConfig.Hashes.VerifyHash()
+	if verifyHash != "" {
+		if verify, err = common.ParseHashAlgorithm(verifyHash); err != nil {
+			return "", "", fmt.Errorf("--verify-hash: %w", err)
+		}
+	}
+	return checksumHash, verify, nil
 }
 
 func metadataHandler(cmd *cobra.Command, args []string) error {
@@ -222,7 +806,7 @@ func metadataHandler(cmd *cobra.Command, args []string) error {
 			}
 			if err := processMetadataForPath(path); err != nil {
 				if !jsonOutput {
-					fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", path, err)
+					ui.Errorf("Error processing %s: %v\n", path, err)
 				}
 				continue
 			}
@@ -235,8 +819,23 @@ func metadataHandler(cmd *cobra.Command, args []string) error {
 }
 
 func processMetadataForPath(path string) error {
+	// EDAT/SDAT files carry their own header rather than a PARAM.SFO
+	if isEDATFile(path) {
+		return handleEDATMetadata(path)
+	}
+	if isPKGFile(path) {
+		return handlePKGMetadata(path)
+	}
+	if is7zFile(path) {
+		return handle7zMetadata(path)
+	}
+
 	// First, auto-detect the console type
-	detection, err := detect.DetectConsole(path)
+	limits, err := searchLimits()
+	if err != nil {
+		return err
+	}
+	detection, err := detect.DetectConsoleWithLimits(path, limits)
 	if err != nil {
 		return fmt.Errorf("error detecting console type: %w", err)
 	}
@@ -249,6 +848,13 @@ func processMetadataForPath(path string) error {
 		fmt.Printf("Game Path:       %s\n", detection.GamePath)
 		fmt.Printf("Indicator:       %s\n", detection.IndicatorFound)
 		fmt.Printf("Search Depth:    %d\n", detection.SearchDepth)
+		fmt.Printf("Entries Seen:    %d\n", detection.Entries)
+		if len(detection.Candidates) > 0 {
+			fmt.Printf("Candidates:\n")
+			for _, candidate := range detection.Candidates {
+				fmt.Printf("  - %s\n", candidate)
+			}
+		}
 		if len(detection.AmbiguousFiles) > 0 {
 			fmt.Printf("Ambiguous Files: %d found\n", len(detection.AmbiguousFiles))
 			for _, file := range detection.AmbiguousFiles {
@@ -271,9 +877,12 @@ func processMetadataForPath(path string) error {
 			for _, file := range detection.AmbiguousFiles {
 				fmt.Printf("  - %s\n", file)
 			}
-			return fmt.Errorf("ambiguous file types detected - specific console type analysis not yet implemented")
+			return fmt.Errorf("%w: ambiguous file types detected - specific console type analysis not yet implemented", common.ErrNotDetected)
+		}
+		if detection.Truncated {
+			return fmt.Errorf("%w for: %s (search stopped at --max-depth %d / --max-entries %d)", common.ErrNotDetected, path, limits.MaxDepth, limits.MaxEntries)
 		}
-		return fmt.Errorf("unable to determine console type for: %s", path)
+		return fmt.Errorf("%w for: %s", common.ErrNotDetected, path)
 	default:
 		if !registry.IsSupported(detection.ConsoleType) {
 			return fmt.Errorf("metadata extraction for %s is not yet implemented", detection.ConsoleType.String())
@@ -298,7 +907,7 @@ func handlePS3Metadata(originalPath string, detection *detect.DetectionResult) e
 	}
 
 	// Read and parse the PARAM.SFO file
-	data, err := os.ReadFile(paramSFOPath)
+	data, err := parsers.ReadParamSFOFile(paramSFOPath)
 	if err != nil {
 		return fmt.Errorf("reading PARAM.SFO file: %w", err)
 	}
@@ -308,11 +917,25 @@ func handlePS3Metadata(originalPath string, detection *detect.DetectionResult) e
 		return fmt.Errorf("parsing PlayStation 3 PARAM.SFO: %w", err)
 	}
 
+	// List EDAT/SDAT content (DLC, licensed data) and DLC packages when given a game folder
+	var drmFiles []consoles.PS3DRMFile
+	var drmProblems []error
+	var dlc []dlcPackage
+	if info, err := os.Stat(originalPath); err == nil && info.IsDir() {
+		drmFiles, drmProblems, err = consoles.FindPS3DRMFiles(originalPath, licenseDirs)
+		if err != nil {
+			return err
+		}
+		dlc = findDLCPackages(originalPath)
+	}
+
 	// Output based on format preference
 	if jsonOutput {
-		outputJSON(paramSFO)
+		outputJSON(paramSFO, drmFiles, dlc)
 	} else {
 		outputText(paramSFO, verbose)
+		printDRMFiles(drmFiles, drmProblems)
+		printDLCPackages(dlc)
 	}
 
 	return nil
@@ -340,6 +963,9 @@ func outputText(paramSFO *parsers.ParamSFO, verbose bool) {
 				valueStr = v
 			case uint32:
 				valueStr = fmt.Sprintf("%d", v)
+				if entry.Key == "ATTRIBUTE" {
+					valueStr += " (" + parsers.FormatAttribute(v) + ")"
+				}
 			case []byte:
 				valueStr = fmt.Sprintf("[unsupported format 0x%04X]", entry.DataFmt)
 			default:
@@ -376,9 +1002,40 @@ func outputText(paramSFO *parsers.ParamSFO, verbose bool) {
 	if category := paramSFO.GetString("CATEGORY"); category != "" {
 		fmt.Printf("Category:    %s\n", category)
 	}
+
+	features := paramSFO.GetFeatures()
+	if len(features.Languages) > 0 {
+		fmt.Printf("Languages:   %s\n", strings.Join(features.Languages, ", "))
+	}
+	if len(features.Features) > 0 {
+		fmt.Printf("Features:    %s\n", strings.Join(features.Features, ", "))
+	}
+	if len(features.RemotePlay) > 0 {
+		fmt.Printf("Remote Play: %s\n", strings.Join(features.RemotePlay, ", "))
+	} else {
+		fmt.Println("Remote Play: not supported")
+	}
+	if len(features.Resolution) > 0 {
+		fmt.Printf("Video:       %s\n", strings.Join(features.Resolution, ", "))
+	}
+	if len(features.Sound) > 0 {
+		fmt.Printf("Audio:       %s\n", strings.Join(features.Sound, ", "))
+	}
+	if features.Unknown != 0 {
+		fmt.Printf("Unknown ATTRIBUTE bits: 0x%08X\n", features.Unknown)
+	}
 }
 
-func outputJSON(paramSFO *parsers.ParamSFO) {
+// jsonStrings encodes a list of strings as a JSON array, empty rather than null
+func jsonStrings(items []string) string {
+	if items == nil {
+		items = []string{}
+	}
+	out, _ := json.Marshal(items)
+	return string(out)
+}
+
+func outputJSON(paramSFO *parsers.ParamSFO, drmFiles []consoles.PS3DRMFile, dlc []dlcPackage) {
 	fmt.Printf("{\n")
 	fmt.Printf("  \"header\": {\n")
 	fmt.Printf("    \"version\": \"%d.%d\",\n",
@@ -413,7 +1070,21 @@ func outputJSON(paramSFO *parsers.ParamSFO) {
 	fmt.Printf("    \"title\": \"%s\",\n", paramSFO.GetTitle())
 	fmt.Printf("    \"gameId\": \"%s\",\n", paramSFO.GetTitleID())
 	fmt.Printf("    \"appVersion\": \"%s\",\n", paramSFO.GetString("APP_VER"))
-	fmt.Printf("    \"category\": \"%s\"\n", paramSFO.GetString("CATEGORY"))
-	fmt.Printf("  }\n")
-	fmt.Printf("}\n")
+	fmt.Printf("    \"category\": \"%s\",\n", paramSFO.GetString("CATEGORY"))
+	features := paramSFO.GetFeatures()
+	fmt.Printf("    \"languages\": %s,\n", jsonStrings(features.Languages))
+	fmt.Printf("    \"features\": %s,\n", jsonStrings(features.Features))
+	fmt.Printf("    \"remotePlay\": %s,\n", jsonStrings(features.RemotePlay))
+	fmt.Printf("    \"resolutions\": %s,\n", jsonStrings(features.Resolution))
+	fmt.Printf("    \"soundFormats\": %s,\n", jsonStrings(features.Sound))
+	fmt.Printf("    \"unknownAttributes\": %d\n", features.Unknown)
+	fmt.Printf("  }")
+	if len(drmFiles) > 0 {
+		fmt.Printf(",\n  \"drm\": %s", drmFilesJSON(drmFiles))
+	}
+	if len(dlc) > 0 {
+		data, _ := json.MarshalIndent(dlc, "  ", "  ")
+		fmt.Printf(",\n  \"dlc\": %s", data)
+	}
+	fmt.Printf("\n}\n")
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// assumeYes skips confirmation prompts
+var assumeYes bool
+
+// assumeYesEnv, set to 1, confirms for a child rom-organizer what the run starting it
+// already confirmed, since a child has no terminal to ask on
+const assumeYesEnv = "ROM_ORGANIZER_ASSUME_YES"
+
+// estimatedCopyRate is the throughput assumed when estimating copy times (bytes per second),
+// roughly a USB 3 drive or gigabit network share
+const estimatedCopyRate = 100 * 1024 * 1024
+
+// confirmCrossDeviceMove warns when --move would copy folders to another file system
+// instead of renaming them, or compress them onto it, before deleting the sources, and
+// asks for confirmation unless --yes is set
+func confirmCrossDeviceMove(sources []string, opts organizer.OrganizeOptions) error {
+	if !opts.MoveSource {
+		return nil
+	}
+
+	var crossing []string
+	var size int64
+	for _, source := range sources {
+		if organizer.IsDiscImage(source) || organizer.IsInputArchive(source) {
+			continue
+		}
+		same, err := common.SameDevice(source, opts.OutputDir)
+		if err != nil || same {
+			continue
+		}
+		crossing = append(crossing, source)
+		if n, err := common.DirSize(source); err == nil {
+			size += n
+		}
+	}
+	if len(crossing) == 0 {
+		return nil
+	}
+
+	estimate := time.Duration(float64(size) / estimatedCopyRate * float64(time.Second)).Round(time.Second)
+	from := crossing[0]
+	if len(crossing) > 1 {
+		from = fmt.Sprintf("%d sources", len(crossing))
+	}
+	then, done := "moves the source to the trash", "moved to the trash"
+	if opts.NoTrash {
+		then, done = "deletes the source", "deleted"
+	}
+	if opts.Format == organizer.Compressed {
+		ui.Warnf("--move from %s to %s crosses file systems, so it compresses onto the other file system and then %s\n",
+			from, opts.OutputDir, then)
+		ui.Warnf("This reads %s into archives on %s (about %s at 100 MB/s); each source is %s once its archive is verified\n",
+			common.FormatSize(size), opts.OutputDir, estimate, done)
+	} else {
+		ui.Warnf("--move from %s to %s crosses file systems, so it copies and then %s instead of renaming\n",
+			from, opts.OutputDir, then)
+		ui.Warnf("This writes %s to %s (about %s at 100 MB/s); each source is %s once its copy is verified\n",
+			common.FormatSize(size), opts.OutputDir, estimate, done)
+	}
+
+	if assumeYes {
+		return nil
+	}
+	ok, err := confirm("Continue?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("cancelled")
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal it fails, pointing to --yes.
+func confirm(question string) (bool, error) {
+	if !ui.IsTerminal(os.Stdin) {
+		return false, fmt.Errorf("confirmation needed but stdin is not a terminal (use --yes)")
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("reading answer: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
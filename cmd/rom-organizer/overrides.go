@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	overrideLevel     int
+	overrideStore     bool
+	overrideStoreExts []string
+	override7zArgs    string
+	overrideNote      string
+)
+
+var overrideCmd = &cobra.Command{
+	Use:   "override",
+	Short: "Pin compression settings for specific games",
+	Long: `Pin the archive settings of specific games in the catalog, for example store-only
+for games that are mostly pre-compressed movies. Overrides replace the console's
+compression policy (and --reproducible and --7z-args still apply on top) whenever
+the game is compressed again by compress or organize.
+
+Games are given by game ID or by the path of an organized game directory.
+
+Examples:
+  rom-organizer override set BLUS30001 --store --note "mostly FMV"
+  rom-organizer override set BLUS30001 --level 5 --store-ext .pkg,.bik
+  rom-organizer override list
+  rom-organizer override clear BLUS30001`,
+}
+
+var overrideSetCmd = &cobra.Command{
+	Use:   "set <game>",
+	Short: "Pin a game's compression settings",
+	Args:  cobra.ExactArgs(1),
+	RunE:  overrideSetHandler,
+}
+
+var overrideClearCmd = &cobra.Command{
+	Use:   "clear <game>...",
+	Short: "Remove games' pinned compression settings",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  overrideClearHandler,
+}
+
+var overrideListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List games with pinned compression settings",
+	Args:  cobra.NoArgs,
+	RunE:  overrideListHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(overrideCmd)
+	overrideCmd.AddCommand(overrideSetCmd, overrideClearCmd, overrideListCmd)
+
+	overrideSetCmd.Flags().IntVar(&overrideLevel, "level", 0, "7z compression level, 0 (store only) to 9")
+	overrideSetCmd.Flags().BoolVar(&overrideStore, "store", false, "Store every file uncompressed (same as --level 0)")
+	overrideSetCmd.Flags().StringSliceVar(&overrideStoreExts, "store-ext", nil, "Extensions stored uncompressed, replacing the console's list (e.g. .pkg,.bik)")
+	overrideSetCmd.Flags().StringVar(&override7zArgs, "7z-args", "", "Extra 7z switches, replacing the console's")
+	overrideSetCmd.Flags().StringVar(&overrideNote, "note", "", "Why the game needs different settings")
+}
+
+func overrideSetHandler(cmd *cobra.Command, args []string) error {
+	var override catalog.CompressionOverride
+	switch {
+	case overrideStore && cmd.Flags().Changed("level"):
+		return fmt.Errorf("--store and --level cannot be combined")
+	case overrideStore:
+		level := 0
+		override.Level = &level
+	case cmd.Flags().Changed("level"):
+		override.Level = &overrideLevel
+	}
+	if cmd.Flags().Changed("store-ext") {
+		override.StoreExtensions = normalizeExtensions(overrideStoreExts)
+	}
+	if cmd.Flags().Changed("7z-args") {
+		override.ExtraArgs = strings.Fields(override7zArgs)
+	}
+	if override.Level == nil && override.StoreExtensions == nil && override.ExtraArgs == nil {
+		return fmt.Errorf("nothing to pin: give --level, --store, --store-ext or --7z-args")
+	}
+	override.Note = overrideNote
+
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	gameID, title, err := resolveGame(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.SetCompression(gameID, title, override); err != nil {
+		return err
+	}
+	if err := c.Save(); err != nil {
+		return err
+	}
+	ui.Successf("%s: %s\n", gameLabel(c, gameID), formatOverride(override))
+	return nil
+}
+
+func overrideClearHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		gameID, _, err := resolveGame(arg)
+		if err != nil {
+			return err
+		}
+		label := gameLabel(c, gameID)
+		c.ClearCompression(gameID)
+		ui.Infof("Cleared compression override of %s\n", label)
+	}
+	return c.Save()
+}
+
+func overrideListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+
+	var gameIDs []string
+	for gameID, e := range c.Games {
+		if e.Compression != nil {
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	sort.Strings(gameIDs)
+	for _, gameID := range gameIDs {
+		fmt.Printf("%-50s %s\n", gameLabel(c, gameID), formatOverride(*c.Compression(gameID)))
+	}
+	return nil
+}
+
+// lookupCompressionOverride applies a game's pinned settings from the catalog to archive
+func lookupCompressionOverride(gameID string, archive common.ArchiveOptions) (common.ArchiveOptions, error) {
+	c, err := openCatalog()
+	if err != nil {
+		return archive, err
+	}
+	override := c.Compression(gameID)
+	if override == nil {
+		return archive, nil
+	}
+	ui.Verbosef("Using compression override for %s from the catalog\n", gameID)
+	archive = override.Apply(archive)
+	if override.ExtraArgs != nil {
+		// --7z-args applies to every game, so it survives the override
+		archive.ExtraArgs = append(archive.ExtraArgs[:len(archive.ExtraArgs):len(archive.ExtraArgs)], strings.Fields(sevenZipArgs)...)
+	}
+	return archive, nil
+}
+
+// formatOverride describes pinned settings for display
+func formatOverride(o catalog.CompressionOverride) string {
+	var parts []string
+	if o.Level != nil {
+		parts = append(parts, fmt.Sprintf("level %d", *o.Level))
+	}
+	if o.StoreExtensions != nil {
+		parts = append(parts, "store "+strings.Join(o.StoreExtensions, ", "))
+	}
+	if o.ExtraArgs != nil {
+		parts = append(parts, "7z args "+strings.Join(o.ExtraArgs, " "))
+	}
+	if o.Note != "" {
+		parts = append(parts, fmt.Sprintf("(%s)", o.Note))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// normalizeExtensions lowercases extensions and adds the leading dot when missing
+func normalizeExtensions(exts []string) []string {
+	out := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		out = append(out, ext)
+	}
+	return out
+}
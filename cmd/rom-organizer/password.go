@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// archivePasswordEnv names the environment variable holding the password for encrypted input archives
+const archivePasswordEnv = "ROM_ORGANIZER_ARCHIVE_PASSWORD"
+
+// keyringService is the service name archive passwords are stored under in the OS keyring
+const keyringService = "rom-organizer"
+
+func init() {
+	common.ArchivePassword = archivePassword
+}
+
+// archivePassword supplies passwords for an encrypted archive: the environment variable,
+// then the OS keyring, then prompts on the terminal for each further attempt
+func archivePassword(archivePath string, attempt int) (string, error) {
+	var stored []string
+	if password := os.Getenv(archivePasswordEnv); password != "" {
+		stored = append(stored, password)
+	}
+	if password := keyringPassword(filepath.Base(archivePath)); password != "" {
+		stored = append(stored, password)
+	}
+	if attempt <= len(stored) {
+		return stored[attempt-1], nil
+	}
+
+	password, err := ui.ReadPassword(fmt.Sprintf("Password for %s: ", filepath.Base(archivePath)))
+	if err == ui.ErrNoTerminal {
+		return "", fmt.Errorf("no valid password found (set %s or store it in the keyring)", archivePasswordEnv)
+	}
+	return password, err
+}
+
+// keyringPassword looks up an archive password in the OS keyring, by archive file name and
+// then under the name "default". It returns "" when there is none or no keyring tool.
+func keyringPassword(name string) string {
+	for _, account := range []string{name, "default"} {
+		var cmd *exec.Cmd
+		switch runtime.GOOS {
+		case "linux", "freebsd", "openbsd", "netbsd":
+			cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "archive", account)
+		case "darwin":
+			cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+		default:
+			return ""
+		}
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err == nil {
+			if password := strings.TrimRight(out.String(), "\r\n"); password != "" {
+				ui.Debugf("Using keyring password %q for %s\n", account, name)
+				return password
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// isPKGFile reports whether path names a PS3 installer package
+func isPKGFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".pkg")
+}
+
+// handlePKGMetadata prints the header of a .pkg file and the summary of the PARAM.SFO
+// it carries (PSN games and updates), like a disc game
+func handlePKGMetadata(path string) error {
+	pkg, err := consoles.OpenPS3PKG(path)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	defer pkg.Close()
+	header := pkg.Header
+
+	paramSFO, sfoErr := pkg.ParamSFO()
+	if sfoErr != nil {
+		ui.Warnf("reading PARAM.SFO from %s: %v\n", path, sfoErr)
+	}
+
+	if jsonOutput {
+		out := map[string]interface{}{
+			"path":        path,
+			"contentId":   header.ContentID,
+			"gameId":      header.TitleID(),
+			"contentType": header.ContentTypeName(),
+			"retail":      header.Revision == parsers.PKGRevisionRetail,
+			"size":        header.TotalSize,
+			"items":       header.ItemCount,
+		}
+		if paramSFO != nil {
+			out["title"] = paramSFO.GetTitle()
+			out["gameId"] = paramSFO.GetTitleID()
+			out["appVersion"] = paramSFO.GetString("APP_VER")
+			out["category"] = paramSFO.GetString("CATEGORY")
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	revision := "retail"
+	if header.Revision != parsers.PKGRevisionRetail {
+		revision = "debug"
+	}
+	fmt.Printf("File Type:    PlayStation 3 PKG (%s)\n", revision)
+	fmt.Printf("Content ID:   %s\n", header.ContentID)
+	fmt.Printf("Content Type: %s\n", header.ContentTypeName())
+	fmt.Printf("Package Size: %d bytes, %d items\n", header.TotalSize, header.ItemCount)
+	fmt.Println()
+
+	if paramSFO != nil {
+		outputText(paramSFO, verbose)
+		return nil
+	}
+	fmt.Println("Summary:")
+	fmt.Println("========")
+	fmt.Println("Game Title:  [no PARAM.SFO in package]")
+	if titleID := header.TitleID(); titleID != "" {
+		fmt.Printf("Game ID:     %s\n", titleID)
+	} else {
+		fmt.Println("Game ID:     [not found]")
+	}
+	return nil
+}
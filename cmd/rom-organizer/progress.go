@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// progressFileEnv names a file a child rom-organizer appends its progress events to, as
+// --progress-format ndjson lines, whatever its --progress-format. The job worker sets it
+// so the rpc service can stream a job's progress.
+const progressFileEnv = "ROM_ORGANIZER_PROGRESS_FILE"
+
+// ndjsonEvent is one line of --progress-format ndjson output
+type ndjsonEvent struct {
+	Time string `json:"time"`
+	organizer.ProgressEvent
+}
+
+// newProgressFunc returns the progress reporter for a --progress-format value.
+// In ndjson mode stdout carries only JSON events, so human-readable messages move to stderr.
+func newProgressFunc(format string) (organizer.ProgressFunc, error) {
+	var progress organizer.ProgressFunc
+	switch format {
+	case "", "text":
+	case "ndjson":
+		ui.SetOutput(os.Stderr)
+		progress = ndjsonProgress(json.NewEncoder(os.Stdout))
+	default:
+		return nil, fmt.Errorf("invalid --progress-format value %q (expected text or ndjson)", format)
+	}
+
+	path := os.Getenv(progressFileEnv)
+	if path == "" {
+		return progress, nil
+	}
+	// Left open until the process exits, as stdout is
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("recording progress: %w", err)
+	}
+	toFile := ndjsonProgress(json.NewEncoder(f))
+	if progress == nil {
+		return toFile, nil
+	}
+	return func(event organizer.ProgressEvent) {
+		progress(event)
+		toFile(event)
+	}, nil
+}
+
+// ndjsonProgress returns a progress reporter writing each event as a line of JSON
+func ndjsonProgress(encoder *json.Encoder) organizer.ProgressFunc {
+	return func(event organizer.ProgressEvent) {
+		encoder.Encode(ndjsonEvent{
+			Time:          time.Now().UTC().Format(time.RFC3339),
+			ProgressEvent: event,
+		})
+	}
+}
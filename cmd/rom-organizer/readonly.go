@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	readOnly        bool
+	readOnlyNetwork bool
+	readOnlyFlagSet bool // --read-only was given and overrides the config
+
+	// isNetworkMount is swapped out by tests, which can't mount a network share
+	isNetworkMount = common.IsNetworkMount
+)
+
+// checkWritable refuses to continue when read-only mode covers any of the paths a
+// command is about to modify or delete
+func checkWritable(paths ...string) error {
+	if readOnly {
+		return fmt.Errorf("%w: not modifying %s (remove --read-only or read_only.enabled to allow changes)", common.ErrReadOnly, paths[0])
+	}
+	if !readOnlyNetwork {
+		return nil
+	}
+	for _, path := range paths {
+		if sftp.IsURL(path) {
+			return fmt.Errorf("%w: %s is a remote location (read_only.network_mounts in config; use --read-only=false to allow changes)", common.ErrReadOnly, path)
+		}
+		network, err := isNetworkMount(path)
+		if err != nil {
+			ui.Debugf("Could not check the file system of %s: %v\n", path, err)
+			continue
+		}
+		if network {
+			return fmt.Errorf("%w: %s is on a network mount (read_only.network_mounts in config; use --read-only=false to allow changes)", common.ErrReadOnly, path)
+		}
+	}
+	return nil
+}
+
+// batchTargets returns the paths a packaging command writes to: its output
+// directories, the sources it deletes with --move, and the organized sources it
+// converts in place
+func batchTargets(sources []string, opts organizer.OrganizeOptions) []string {
+	targets := append([]string{opts.OutputDir}, opts.MirrorDirs...)
+	for _, source := range sources {
+		if opts.MoveSource || organizer.ConvertsInPlace(source, opts) {
+			targets = append(targets, source)
+		}
+	}
+	return targets
+}
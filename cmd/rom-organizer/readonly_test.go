@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+)
+
+func TestReadOnlyNetworkSources(t *testing.T) {
+	dir := t.TempDir()
+	nas, local := filepath.Join(dir, "nas"), filepath.Join(dir, "local")
+	organized := organizedGame(t, filepath.Join(nas, "Game A [BLUS30001]"))
+	raw := filepath.Join(nas, "Game B")
+	writeTestFile(t, filepath.Join(raw, "PS3_GAME", "PARAM.SFO"))
+
+	readOnlyNetwork = true
+	isNetworkMount = func(path string) (bool, error) {
+		return strings.HasPrefix(path, nas), nil
+	}
+	t.Cleanup(func() {
+		readOnlyNetwork = false
+		isNetworkMount = common.IsNetworkMount
+	})
+
+	opts := organizer.OrganizeOptions{OutputDir: local, Layout: appConfig.Layout}
+	tests := []struct {
+		format  organizer.GameFormat
+		move    bool
+		sources []string
+		refused bool
+	}{
+		// Only read from the mount
+		{organizer.Compressed, false, []string{raw}, false},
+		{organizer.KeepOriginal, false, []string{organized}, false},
+		{organizer.Decompressed, false, []string{organized}, false}, // Already game/
+
+		// game/ would be replaced by game.7z on the mount
+		{organizer.Compressed, false, []string{raw, organized}, true},
+
+		// The source would be deleted
+		{organizer.Compressed, true, []string{raw}, true},
+	}
+	for _, tt := range tests {
+		opts.Format, opts.MoveSource = tt.format, tt.move
+		err := checkWritable(batchTargets(tt.sources, opts)...)
+		if refused := errors.Is(err, common.ErrReadOnly); refused != tt.refused {
+			t.Errorf("format %d, move %v, %d sources: refused %v (%v), want %v", tt.format, tt.move, len(tt.sources), refused, err, tt.refused)
+		}
+	}
+}
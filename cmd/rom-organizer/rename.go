@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var renameDryRun bool
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <library> [library...]",
+	Short: "Rename organized games with the title cleanup rules",
+	Long: `Rename organized game folders whose titles the title rules would change, or
+whose names are longer than the name length limits (names in the config file).
+
+New games are cleaned up when they are organized; this brings folders organized
+before the rules were enabled or changed in line. The rules are set under
+titles in the config file: strip_symbols (™, ®, ©), collapse_whitespace,
+fix_all_caps and strip_disc_numbers ("(Disc 1)"). Each game keeps its place in
+the library; only the folder name changes.
+
+Use --dry-run to preview the renames first.
+
+Examples:
+  rom-organizer rename --dry-run /mnt/nas/ps3
+  rom-organizer rename /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: renameHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+	renameCmd.Flags().BoolVarP(&renameDryRun, "dry-run", "n", false, "Show the renames without renaming anything")
+}
+
+func renameHandler(cmd *cobra.Command, args []string) error {
+	// Scan records are keyed by game folder, so they follow the renames; otherwise the
+	// next scan would report each renamed game as removed and new
+	var c *catalog.Catalog
+	if !renameDryRun {
+		if err := checkWritable(args...); err != nil {
+			return err
+		}
+		var err error
+		if c, err = openCatalog(); err != nil {
+			return err
+		}
+	}
+
+	renamed, err := renameGames(args, c)
+	if c != nil && renamed > 0 {
+		if saveErr := c.Save(); err == nil {
+			err = saveErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case renamed == 0:
+		ui.Infof("All game folders already follow the title rules\n")
+	case renameDryRun:
+		ui.Infof("\n%d game(s) would be renamed (dry run)\n", renamed)
+	default:
+		ui.Successf("\nRenamed %d game(s)\n", renamed)
+	}
+	return nil
+}
+
+// renameGames renames the games under roots that the title rules would name differently
+// and moves their scan records in c (nil for a dry run). It returns how many games were
+// renamed, also when it stops at an error.
+func renameGames(roots []string, c *catalog.Catalog) (int, error) {
+	renamed := 0
+	for _, root := range roots {
+		games, err := library.FindGames(root, appConfig.Layout)
+		if err != nil {
+			return renamed, err
+		}
+		for _, game := range games {
+			info := game.Info.GameInfo
+			if info == nil || info.Title == "" || info.GameID == "" {
+				continue
+			}
+			parent := filepath.Dir(game.Path)
+			if abs, err := filepath.Abs(parent); err == nil {
+				parent = abs
+			}
+			name, err := appConfig.Names.GameFolderName(parent, appConfig.Titles.Apply(info.Title), info.GameID)
+			if err != nil {
+				ui.Warnf("Not renaming %s: %v\n", game.Path, err)
+				continue
+			}
+			if name == filepath.Base(game.Path) {
+				continue
+			}
+			dest := filepath.Join(filepath.Dir(game.Path), name)
+
+			if renameDryRun {
+				fmt.Printf("%s -> %s\n", filepath.Base(game.Path), name)
+				renamed++
+				continue
+			}
+			// A name differing only in case is the same folder on a case-insensitive file system
+			if existing, err := os.Stat(dest); err == nil {
+				if source, err := os.Stat(game.Path); err != nil || !os.SameFile(source, existing) {
+					ui.Warnf("Not renaming %s: %s already exists\n", game.Path, name)
+					continue
+				}
+			}
+			if err := os.Rename(game.Path, dest); err != nil {
+				return renamed, fmt.Errorf("renaming %s: %w", game.Path, err)
+			}
+			ui.Infof("%s -> %s\n", filepath.Base(game.Path), name)
+			renamed++
+
+			from, errFrom := filepath.Abs(game.Path)
+			to, errTo := filepath.Abs(dest)
+			if record, ok := c.Scans[from]; ok && errFrom == nil && errTo == nil {
+				delete(c.Scans, from)
+				c.Scans[to] = record
+			}
+		}
+	}
+	return renamed, nil
+}
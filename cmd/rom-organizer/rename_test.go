@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+)
+
+func TestRenameMovesScanRecords(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	renamed := organizedGame(t, filepath.Join(lib, "Game™ A [BLUS30001]"))
+	blocked := organizedGame(t, filepath.Join(lib, "Game™ B [BLUS30002]"))
+	organizedGame(t, filepath.Join(lib, "Game B [BLUS30002]"))
+
+	catalogPath := appConfig.Catalog
+	appConfig.Catalog = filepath.Join(dir, "catalog.json")
+	t.Cleanup(func() { appConfig.Catalog = catalogPath })
+
+	c, err := openCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Scans[renamed] = &catalog.ScanRecord{GameID: "BLUS30001", Title: "Game™ A"}
+	c.Scans[blocked] = &catalog.ScanRecord{GameID: "BLUS30002", Title: "Game™ B"}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := renameHandler(renameCmd, []string{lib}); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(lib, "Game A [BLUS30001]")
+	if exists(renamed) || !exists(dest) {
+		t.Fatalf("%s not renamed to %s", renamed, dest)
+	}
+	if !exists(blocked) {
+		t.Errorf("%s renamed onto another game's folder", blocked)
+	}
+
+	c, err = openCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record := c.Scans[dest]; record == nil || record.GameID != "BLUS30001" {
+		t.Errorf("scan record at the new path %+v", record)
+	}
+	if _, ok := c.Scans[renamed]; ok {
+		t.Error("scan record left at the old path")
+	}
+	if record := c.Scans[blocked]; record == nil || record.GameID != "BLUS30002" {
+		t.Errorf("scan record of the game not renamed %+v", record)
+	}
+}
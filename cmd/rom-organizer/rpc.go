@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/NeilGraham/rom-organizer/api/rpcpb"
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/jobs"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/rpc"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	rpcListen    string
+	rpcQueuePath string
+)
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc <library> [library...]",
+	Short: "Serve the gRPC control API for job submission, progress and library queries",
+	Long: `Serve a gRPC service for desktop apps and other programs driving rom-organizer,
+for example on a NAS: submit compress, decompress and organize jobs, stream their
+progress, and list the organized games of the given libraries with their catalog
+tags.
+
+Submitted jobs go to the job queue, the same as "jobs add", and are run by a
+"jobs run --watch" worker, which records each job's progress for the service to
+stream. The service is defined in api/rpcpb/rom_organizer.proto, with generated Go
+client stubs in the api/rpcpb package.
+
+The service has no authentication or encryption; listen on a trusted network only.
+
+Examples:
+  rom-organizer rpc /mnt/nas/ps3
+  rom-organizer rpc --listen 127.0.0.1:50051 --queue /mnt/nas/jobs.json /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: rpcHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(rpcCmd)
+	rpcCmd.Flags().StringVar(&rpcListen, "listen", ":50051", "Address to listen on")
+	rpcCmd.Flags().StringVar(&rpcQueuePath, "queue", jobs.DefaultPath(), "Job queue file")
+}
+
+func rpcHandler(cmd *cobra.Command, args []string) error {
+	listener, err := net.Listen("tcp", rpcListen)
+	if err != nil {
+		return err
+	}
+
+	d, err := startDaemon(cmd, args, "request")
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	defer d.Close()
+
+	// Games are looked up on each request with the config of the time, so a reload
+	// only needs to re-read the config
+	server := grpc.NewServer()
+	rpcpb.RegisterRomOrganizerServer(server, rpc.NewServer(rpcQueuePath, func(filter catalog.Filter) ([]library.Game, *catalog.Catalog, error) {
+		return findFilteredGames(args, filter)
+	}))
+	go func() {
+		for {
+			reload, stop := d.Wait(time.Time{})
+			switch {
+			case stop:
+				// Requests may finish, but streams watching a job would run until
+				// it's done, so they're cut off after a while
+				stopped := make(chan struct{})
+				go func() {
+					server.GracefulStop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+				case <-time.After(5 * time.Second):
+					server.Stop()
+				}
+				return
+			case reload:
+				d.ReloadConfig()
+			}
+		}
+	}()
+
+	ui.Successf("Serving the gRPC API at %s\n", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		return err
+	}
+	ui.Infof("Server stopped\n")
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/saves"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	savesFrom     string
+	savesTo       string
+	savesUser     string
+	savesCompress bool
+	savesSnapshot string
+	savesForce    bool
+)
+
+var savesCmd = &cobra.Command{
+	Use:   "saves",
+	Short: "Back up and restore PS3 save data in organized games",
+	Long: `Copy PS3 save folders (savedata/<TITLEID>...) between a console or RPCS3 and the
+_saves folder of an organized game. Each import is a timestamped snapshot.
+
+Locations are ftp://host[:port] for a console running an FTP server (such as
+webMAN MOD), or RPCS3's dev_hdd0 folder (or the RPCS3 folder containing it).
+
+Examples:
+  rom-organizer saves import "/mnt/nas/ps3/Game [BLUS30001]" --from ftp://192.168.1.50
+  rom-organizer saves import "/mnt/nas/ps3/Game [BLUS30001]" --from ~/.config/rpcs3 --compress
+  rom-organizer saves list "/mnt/nas/ps3/Game [BLUS30001]"
+  rom-organizer saves export "/mnt/nas/ps3/Game [BLUS30001]" --to ~/.config/rpcs3/dev_hdd0`,
+}
+
+var savesImportCmd = &cobra.Command{
+	Use:   "import <organized-game>",
+	Short: "Copy a game's saves from a console or RPCS3 into its _saves folder",
+	Args:  cobra.ExactArgs(1),
+	RunE:  savesImportHandler,
+}
+
+var savesExportCmd = &cobra.Command{
+	Use:   "export <organized-game>",
+	Short: "Copy a save snapshot back to a console or RPCS3",
+	Args:  cobra.ExactArgs(1),
+	RunE:  savesExportHandler,
+}
+
+var savesListCmd = &cobra.Command{
+	Use:   "list <organized-game>",
+	Short: "List a game's save snapshots",
+	Args:  cobra.ExactArgs(1),
+	RunE:  savesListHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(savesCmd)
+	savesCmd.AddCommand(savesImportCmd, savesExportCmd, savesListCmd)
+
+	savesCmd.PersistentFlags().StringVar(&savesUser, "user", saves.DefaultUser, "PS3 user profile")
+	savesImportCmd.Flags().StringVar(&savesFrom, "from", "", "Console (ftp://host) or RPCS3 dev_hdd0 folder to copy saves from")
+	savesImportCmd.Flags().BoolVar(&savesCompress, "compress", false, "Store the snapshot as a .7z archive")
+	savesImportCmd.MarkFlagRequired("from")
+	savesExportCmd.Flags().StringVar(&savesTo, "to", "", "Console (ftp://host) or RPCS3 dev_hdd0 folder to copy saves to")
+	savesExportCmd.Flags().StringVar(&savesSnapshot, "snapshot", "", "Snapshot to restore (default the latest)")
+	savesExportCmd.Flags().BoolVarP(&savesForce, "force", "f", false, "Replace save folders that already exist")
+	savesExportCmd.MarkFlagRequired("to")
+}
+
+// organizedGameInfo returns the game information of an organized game directory
+func organizedGameInfo(gameDir string) (*common.GameInfo, error) {
+	info, err := common.DetectOrganizedDirectory(gameDir, appConfig.Layout, false)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsOrganized || info.GameInfo == nil {
+		return nil, fmt.Errorf("%s is not an organized game directory", gameDir)
+	}
+	return info.GameInfo, nil
+}
+
+func savesImportHandler(cmd *cobra.Command, args []string) error {
+	if err := checkWritable(args[0]); err != nil {
+		return err
+	}
+	game, err := organizedGameInfo(args[0])
+	if err != nil {
+		return err
+	}
+
+	store, err := saves.Open(savesFrom, savesUser)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshot, names, err := saves.Import(store, args[0], game.GameID, savesCompress, appConfig.Compression.ArchiveOptions(detect.PS3))
+	if err != nil {
+		return err
+	}
+	ui.Successf("Saved %s snapshot %s: %s\n", game.GameID, snapshot, strings.Join(names, ", "))
+	return nil
+}
+
+func savesExportHandler(cmd *cobra.Command, args []string) error {
+	if _, err := organizedGameInfo(args[0]); err != nil {
+		return err
+	}
+
+	store, err := saves.Open(savesTo, savesUser)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshot, names, err := saves.Export(store, args[0], savesSnapshot, savesForce)
+	if err != nil {
+		return err
+	}
+	ui.Successf("Restored snapshot %s to %s: %s\n", snapshot, store, strings.Join(names, ", "))
+	return nil
+}
+
+func savesListHandler(cmd *cobra.Command, args []string) error {
+	if _, err := organizedGameInfo(args[0]); err != nil {
+		return err
+	}
+	snapshots, err := saves.Snapshots(args[0])
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		fmt.Println(snapshot)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	scanFull   bool
+	scanNoHash bool
+	scanAll    bool
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <library> [library...]",
+	Short: "Record new, changed and removed games since the last scan",
+	Long: `Scan libraries and record each game's size, modification time, content hash
+and PARAM.SFO version in the catalog, then report what was added, changed or
+removed since the previous scan.
+
+Scans are incremental: a game whose total size and latest file modification time
+match the last scan keeps its record, so only new and changed games are hashed
+and parsed. Unchanged games cost a directory walk, which keeps repeat scans of
+large libraries fast. --full hashes and parses every game again, for example to
+catch bit rot that left sizes and times alone.
+
+The content hash is the config's hashes.verify algorithm (xxh64 by default) of
+game.7z, or of the files in game/ for decompressed games.
+
+Demo and beta builds, recognized from their title ID (BCED, BCET, ...) or a
+marker such as "Demo", "Trial" or "Beta" in their title, are tagged demo or beta
+in the catalog. Exports, list, stats and the other commands taking --tag leave
+them out with --no-demos.
+
+Examples:
+  rom-organizer scan /mnt/nas/ps3
+  rom-organizer scan --full /mnt/nas/ps3 /mnt/usb/ps3
+  rom-organizer scan --no-hash /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: scanHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+	scanCmd.Flags().BoolVar(&scanFull, "full", false, "Hash and parse every game, not only new and changed ones")
+	scanCmd.Flags().BoolVar(&scanNoHash, "no-hash", false, "Detect changes by size and modification time only, without hashing")
+	scanCmd.Flags().BoolVar(&scanAll, "all", false, "Also list unchanged games")
+}
+
+func scanHandler(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+
+	roots := make([]string, len(args))
+	for i, arg := range args {
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		roots[i] = abs
+	}
+
+	games, c, err := findFilteredGames(roots, catalog.Filter{})
+	if err != nil {
+		return err
+	}
+
+	opts := library.ScanOptions{Full: scanFull}
+	if !scanNoHash {
+		opts.Hash = appConfig.Hashes.VerifyHash()
+	}
+	results := library.Scan(games, c.Scans, opts)
+	removed := library.Removed(roots, games, c.Scans)
+
+	counts := make(map[library.ScanChange]int)
+	failed, tagged := 0, 0
+	for _, result := range append(results, removed...) {
+		if result.Err != nil {
+			// Not recorded, so the next scan tries the game again
+			ui.Warnf("%s: %v\n", result.Path, result.Err)
+			failed++
+			continue
+		}
+		counts[result.Change]++
+
+		label := fmt.Sprintf("%s [%s]", result.Record.Title, result.Record.GameID)
+		if result.Record.Build != "" {
+			label += " (" + result.Record.Build + ")"
+		}
+		switch result.Change {
+		case library.ScanNew:
+			fmt.Printf("+ %s\n", label)
+		case library.ScanChanged:
+			detail := ""
+			if result.ContentChange {
+				detail = " (content changed)"
+			}
+			fmt.Printf("~ %s%s\n", label, detail)
+		case library.ScanRemoved:
+			fmt.Printf("- %s\n", label)
+		default:
+			if scanAll {
+				fmt.Printf("  %s\n", label)
+			}
+		}
+
+		if result.Change == library.ScanRemoved {
+			delete(c.Scans, result.Path)
+		} else {
+			c.Scans[result.Path] = result.Record
+		}
+
+		// Only freshly read games are tagged, so removing the tag by hand sticks
+		record := result.Record
+		if record.Build != "" && (result.Change == library.ScanNew || result.Change == library.ScanChanged || scanFull) && !c.HasTag(record.GameID, record.Build) {
+			if err := c.Tag(record.GameID, record.Title, record.Build); err != nil {
+				return err
+			}
+			tagged++
+		}
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+
+	ui.Infof("\n%d new, %d changed, %d removed, %d unchanged in %s\n",
+		counts[library.ScanNew], counts[library.ScanChanged], counts[library.ScanRemoved], counts[library.ScanUnchanged],
+		time.Since(start).Round(time.Millisecond))
+	if tagged > 0 {
+		ui.Infof("Tagged %d demo or beta build(s); skip them with --no-demos\n", tagged)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d game(s) could not be scanned completely", failed)
+	}
+	return nil
+}
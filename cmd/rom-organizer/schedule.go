@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/config"
+	"github.com/NeilGraham/rom-organizer/internal/schedule"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run recurring library tasks from the config file",
+	Long: `Run rom-organizer commands on cron schedules defined in the config file.
+
+Example config:
+  schedule:
+    log: /var/log/rom-organizer/schedule.log
+    notify: 'notify-send "rom-organizer" "$TASK: $STATUS $ERROR"'
+    tasks:
+      - name: nightly-stats
+        cron: "0 3 * * *"
+        command: [stats, /mnt/nas/ps3]
+      - name: weekly-queue
+        cron: "@weekly"
+        command: [jobs, run]
+        notify: always
+
+Examples:
+  rom-organizer schedule list
+  rom-organizer schedule run nightly-stats
+  rom-organizer schedule daemon`,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled tasks and their next run time",
+	Args:  cobra.NoArgs,
+	RunE:  scheduleListHandler,
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run <task>",
+	Short: "Run a scheduled task now",
+	Args:  cobra.ExactArgs(1),
+	RunE:  scheduleRunHandler,
+}
+
+var scheduleDaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run scheduled tasks in the foreground until interrupted",
+	Args:  cobra.NoArgs,
+	RunE:  scheduleDaemonHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleListCmd, scheduleRunCmd, scheduleDaemonCmd)
+}
+
+// scheduledTask pairs a configured task with its parsed cron expression and next run time
+type scheduledTask struct {
+	config.ScheduledTask
+	cron *schedule.Cron
+	next time.Time
+}
+
+// loadScheduledTasks parses the configured tasks and computes their next run after now
+func loadScheduledTasks(now time.Time) ([]*scheduledTask, error) {
+	var tasks []*scheduledTask
+	for _, task := range appConfig.Schedule.Tasks {
+		cron, err := schedule.ParseCron(task.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule task %q: %w", task.Name, err)
+		}
+		tasks = append(tasks, &scheduledTask{ScheduledTask: task, cron: cron, next: cron.Next(now)})
+	}
+	return tasks, nil
+}
+
+func scheduleListHandler(cmd *cobra.Command, args []string) error {
+	tasks, err := loadScheduledTasks(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		ui.Infof("No scheduled tasks configured\n")
+		return nil
+	}
+
+	for _, task := range tasks {
+		next := "never"
+		if !task.next.IsZero() {
+			next = task.next.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-20s %-16s next: %s  rom-organizer %s\n", task.Name, task.Cron, next, strings.Join(task.Command, " "))
+	}
+	return nil
+}
+
+func scheduleRunHandler(cmd *cobra.Command, args []string) error {
+	task, ok := appConfig.Schedule.Task(args[0])
+	if !ok {
+		return fmt.Errorf("no scheduled task named %q", args[0])
+	}
+	return runScheduledTask(task)
+}
+
+func scheduleDaemonHandler(cmd *cobra.Command, args []string) error {
+	tasks, err := loadScheduledTasks(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("no scheduled tasks configured")
+	}
+
+	d, err := startDaemon(cmd, args, "task")
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	ui.Infof("Running %d scheduled tasks\n", len(tasks))
+	for !d.Stopping() {
+		var due *scheduledTask
+		for _, task := range tasks {
+			if task.next.IsZero() {
+				continue
+			}
+			if due == nil || task.next.Before(due.next) {
+				due = task
+			}
+		}
+		if due == nil {
+			return fmt.Errorf("no scheduled task will run again")
+		}
+
+		ui.Verbosef("Next task: %s at %s\n", due.Name, due.next.Format("2006-01-02 15:04"))
+		reload, stop := d.Wait(due.next)
+		if stop {
+			break
+		}
+		if reload {
+			// Tasks changed in the config replace the current ones
+			if d.ReloadConfig() {
+				if reloaded, err := loadScheduledTasks(time.Now()); err != nil {
+					ui.Errorf("%v (keeping the previous tasks)\n", err)
+				} else {
+					tasks = reloaded
+					ui.Infof("Running %d scheduled tasks\n", len(tasks))
+				}
+			}
+			continue
+		}
+
+		// A failed task is logged and notified; the daemon keeps running
+		runScheduledTask(due.ScheduledTask)
+		due.next = due.cron.Next(time.Now())
+	}
+	ui.Infof("Schedule daemon stopped\n")
+	return nil
+}
+
+// runScheduledTask runs one task, appending its output and result to the schedule log
+// and running the notify command according to the task's notify setting
+func runScheduledTask(task config.ScheduledTask) error {
+	stdout, stderr := ui.Output(), io.Writer(os.Stderr)
+	logFile := appConfig.Schedule.Log
+	var log io.Writer = io.Discard
+
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("opening schedule log: %w", err)
+		}
+		defer f.Close()
+		log = f
+		stdout, stderr = io.MultiWriter(stdout, f), io.MultiWriter(stderr, f)
+	}
+
+	start := time.Now()
+	ui.Infof("=== Task %s: rom-organizer %s ===\n", task.Name, strings.Join(task.Command, " "))
+	fmt.Fprintf(log, "[%s] %s started: rom-organizer %s\n", start.Format(time.RFC3339), task.Name, strings.Join(task.Command, " "))
+
+	// Tasks run unattended; scheduling one is what confirms it
+	runErr := runSubcommand(task.Command, stdout, stderr, true)
+
+	status := "success"
+	if runErr != nil {
+		status = "failed"
+		ui.Errorf("Task %s failed: %v\n", task.Name, runErr)
+		fmt.Fprintf(log, "[%s] %s failed after %s: %v\n", time.Now().Format(time.RFC3339), task.Name, time.Since(start).Round(time.Second), runErr)
+	} else {
+		ui.Successf("Task %s completed\n", task.Name)
+		fmt.Fprintf(log, "[%s] %s completed in %s\n", time.Now().Format(time.RFC3339), task.Name, time.Since(start).Round(time.Second))
+	}
+
+	notify := task.NotifyOn()
+	if appConfig.Schedule.Notify != "" && (notify == config.NotifyAlways || (notify == config.NotifyFailure && runErr != nil)) {
+		if err := notifyTask(task, status, runErr, logFile); err != nil {
+			ui.Warnf("%v\n", err)
+		}
+	}
+
+	return runErr
+}
+
+// notifyTask runs the configured notify command with the task result in its environment
+func notifyTask(task config.ScheduledTask, status string, taskErr error, logFile string) error {
+	cmd := common.ShellCommand(appConfig.Schedule.Notify)
+	cmd.Env = append(os.Environ(),
+		"TASK="+task.Name,
+		"STATUS="+status,
+		"LOG_FILE="+logFile,
+	)
+	if taskErr != nil {
+		cmd.Env = append(cmd.Env, "ERROR="+taskErr.Error())
+	}
+	cmd.Stdout = ui.Output()
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("schedule notify command failed: %w", err)
+	}
+	return nil
+}
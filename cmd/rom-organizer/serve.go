@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/server"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	serveFilter catalog.Filter
+	serveListen string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve <library> [library...]",
+	Short: "Share decompressed games over HTTP for webMAN MOD",
+	Long: `Serve the decompressed games of one or more libraries over read-only HTTP in the
+layout webMAN MOD expects from a network share:
+
+  GAMES/
+  └── {Game Name} [{Game ID}]/
+      ├── PS3_DISC.SFB
+      └── PS3_GAME/
+
+Compressed games (game.7z) cannot be streamed and are skipped; decompress the games
+you want to play from the NAS. The game list is read at startup and again when the
+config is reloaded with SIGHUP.
+
+Examples:
+  rom-organizer serve /mnt/nas/ps3
+  rom-organizer serve --listen :8080 --tag favorites /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: serveHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	addCatalogFilterFlags(serveCmd, &serveFilter)
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+}
+
+func serveHandler(cmd *cobra.Command, args []string) error {
+	handler, count, err := serveGames(args)
+	if err != nil {
+		return err
+	}
+
+	d, err := startDaemon(cmd, args, "request")
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// A reload re-reads the config and the game list, and swaps in the new share
+	var current atomic.Value
+	current.Store(handler)
+	httpServer := &http.Server{
+		Addr: serveListen,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current.Load().(http.Handler).ServeHTTP(w, r)
+		}),
+	}
+	go func() {
+		for {
+			reload, stop := d.Wait(time.Time{})
+			switch {
+			case stop:
+				httpServer.Shutdown(context.Background())
+				return
+			case reload && d.ReloadConfig():
+				handler, count, err := serveGames(args)
+				if err != nil {
+					ui.Errorf("Reloading games: %v (still serving the previous list)\n", err)
+					continue
+				}
+				current.Store(handler)
+				ui.Successf("Serving %d games\n", count)
+			}
+		}
+	}()
+
+	ui.Successf("Serving %d games at http://%s/%s/\n", count, serveListen, server.GamesDir)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	ui.Infof("Server stopped\n")
+	return nil
+}
+
+// serveGames finds the decompressed games of the libraries and returns the read-only
+// share serving them, and how many there are
+func serveGames(libraries []string) (http.Handler, int, error) {
+	games, _, err := findFilteredGames(libraries, serveFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	share, skipped := server.NewGamesFS(games)
+	for _, path := range skipped {
+		ui.Verbosef("Skipping compressed game: %s\n", path)
+	}
+	if share.Len() == 0 {
+		return nil, 0, fmt.Errorf("no decompressed games to serve")
+	}
+	if len(skipped) > 0 {
+		ui.Infof("Skipped %d compressed games (decompress them to stream)\n", len(skipped))
+	}
+
+	handler := server.ReadOnly(http.FileServer(share))
+	if ui.IsVerbose() {
+		files := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ui.Verbosef("%s %s %s\n", r.RemoteAddr, r.Method, r.URL.Path)
+			files.ServeHTTP(w, r)
+		})
+	}
+	return handler, share.Len(), nil
+}
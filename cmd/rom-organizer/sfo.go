@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	sfoSpecPath     string
+	sfoOutputPath   string
+	sfoSetInt       bool
+	sfoValidateJSON bool
+)
+
+var sfoCmd = &cobra.Command{
+	Use:   "sfo",
+	Short: "Work with PlayStation 3 PARAM.SFO files",
+	Long: `Tools for creating and inspecting PlayStation 3 PARAM.SFO files.
+
+Use the metadata command to read an existing PARAM.SFO, and sfo validate to find
+out why one can't be read.`,
+}
+
+var sfoCreateCmd = &cobra.Command{
+	Use:   "create --from <spec>",
+	Short: "Generate a PARAM.SFO from a JSON or YAML description",
+	Long: `Generate a valid PARAM.SFO file from a JSON or YAML spec.
+
+The spec lists entries with their key, format (utf8, utf8-special, int32 or a
+numeric format code), value, and optional reserved length. Raw formats take a
+hex string as value. Alignment of the key table and data slots can be set with
+key_alignment and data_alignment.
+
+Example spec:
+  version: "1.1"
+  sort_keys: true
+  entries:
+    - key: CATEGORY
+      value: HG
+    - key: TITLE
+      value: My Homebrew
+      max: 128
+    - key: TITLE_ID
+      value: NPUB90001
+    - key: ATTRIBUTE
+      format: int32
+      value: 0
+
+Examples:
+  rom-organizer sfo create --from spec.yaml
+  rom-organizer sfo create --from spec.json --output PS3_GAME/PARAM.SFO`,
+	Args: cobra.NoArgs,
+	RunE: sfoCreateHandler,
+}
+
+var sfoSetCmd = &cobra.Command{
+	Use:   "set <PARAM.SFO> <key> <value>",
+	Short: "Set a value in an existing PARAM.SFO",
+	Long: `Set a value in an existing PARAM.SFO file in place.
+
+Existing entries keep their format: integer entries take a number and string
+entries take text. New entries are strings unless --int is given.
+
+Examples:
+  rom-organizer sfo set PS3_GAME/PARAM.SFO TITLE_ID BLUS30001
+  rom-organizer sfo set PS3_GAME/PARAM.SFO TITLE "My Game"
+  rom-organizer sfo set --int PS3_GAME/PARAM.SFO PARENTAL_LEVEL 3`,
+	Args: cobra.ExactArgs(3),
+	RunE: sfoSetHandler,
+}
+
+var sfoValidateCmd = &cobra.Command{
+	Use:   "validate <PARAM.SFO>...",
+	Short: "Check PARAM.SFO files for corrupt or unusual structure",
+	Long: `Check PARAM.SFO files and list every problem found, with the table (header,
+index, key table or data table), index entry and file offset it is at.
+
+Errors make a file unreadable: offsets or lengths beyond the end of the file, keys
+without a terminator, too many entries for the file's size, or files over 1 MB.
+Warnings are files the PS3 may still read but that retail files never are: keys out
+of order or repeated, values longer than their slot, strings that aren't UTF-8 or
+unknown data formats. The command fails when any file has errors.
+
+Examples:
+  rom-organizer sfo validate PS3_GAME/PARAM.SFO
+  rom-organizer sfo validate --json */PS3_GAME/PARAM.SFO`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: sfoValidateHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(sfoCmd)
+	sfoCmd.AddCommand(sfoCreateCmd)
+	sfoCmd.AddCommand(sfoSetCmd)
+	sfoCmd.AddCommand(sfoValidateCmd)
+
+	sfoValidateCmd.Flags().BoolVarP(&sfoValidateJSON, "json", "j", false, "Output in JSON format")
+
+	sfoSetCmd.Flags().BoolVar(&sfoSetInt, "int", false, "Store a new entry as a 32-bit integer")
+
+	sfoCreateCmd.Flags().StringVar(&sfoSpecPath, "from", "", "Path to the JSON or YAML spec file")
+	sfoCreateCmd.Flags().StringVarP(&sfoOutputPath, "output", "o", "PARAM.SFO", "Path of the PARAM.SFO file to write")
+	sfoCreateCmd.MarkFlagRequired("from")
+}
+
+func sfoCreateHandler(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(sfoSpecPath)
+	if err != nil {
+		return fmt.Errorf("reading spec file: %w", err)
+	}
+
+	spec, err := parsers.ParseSFOSpec(data)
+	if err != nil {
+		return err
+	}
+
+	paramSFO, opts, err := spec.Build()
+	if err != nil {
+		return fmt.Errorf("building PARAM.SFO: %w", err)
+	}
+
+	out, err := paramSFO.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("writing PARAM.SFO: %w", err)
+	}
+
+	// Sanity check: the generated file must be readable by our own parser
+	if _, err := parsers.ParseParamSFO(out); err != nil {
+		return fmt.Errorf("generated PARAM.SFO failed validation: %w", err)
+	}
+
+	if err := common.ReplaceFile(sfoOutputPath, out, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", sfoOutputPath, err)
+	}
+
+	fmt.Printf("Created %s (%d entries, %d bytes)\n", sfoOutputPath, len(paramSFO.Entries), len(out))
+	return nil
+}
+
+func sfoSetHandler(cmd *cobra.Command, args []string) error {
+	path, key, value := args[0], args[1], args[2]
+
+	data, err := parsers.ReadParamSFOFile(path)
+	if err != nil {
+		return fmt.Errorf("reading PARAM.SFO file: %w", err)
+	}
+
+	paramSFO, err := parsers.ParseParamSFO(data)
+	if err != nil {
+		return fmt.Errorf("parsing PARAM.SFO: %w", err)
+	}
+
+	isInt := sfoSetInt
+	if entry, ok := paramSFO.GetEntry(key); ok {
+		isInt = entry.DataFmt == parsers.FMT_INT32
+	}
+
+	if isInt {
+		number, err := strconv.ParseUint(value, 0, 32)
+		if err != nil {
+			return fmt.Errorf("%s is an integer entry: invalid value %q", key, value)
+		}
+		paramSFO.SetInt(key, uint32(number))
+	} else {
+		paramSFO.SetString(key, value)
+	}
+
+	// Only the changed value's bytes are rewritten when it fits its slot
+	out, err := paramSFO.Write()
+	if err != nil {
+		return fmt.Errorf("writing PARAM.SFO: %w", err)
+	}
+
+	if _, err := parsers.ParseParamSFO(out); err != nil {
+		return fmt.Errorf("updated PARAM.SFO failed validation: %w", err)
+	}
+
+	if err := common.ReplaceFile(path, out, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	fmt.Printf("Set %s = %s in %s\n", key, value, path)
+	return nil
+}
+
+// sfoValidation is the result of sfo validate for one file
+type sfoValidation struct {
+	Path     string               `json:"path"`
+	Valid    bool                 `json:"valid"`
+	Error    string               `json:"error,omitempty"` // The file couldn't be read
+	Problems []parsers.SFOProblem `json:"problems"`
+}
+
+func sfoValidateHandler(cmd *cobra.Command, args []string) error {
+	results := make([]sfoValidation, 0, len(args))
+	invalid := 0
+	for _, path := range args {
+		result := sfoValidation{Path: path, Problems: []parsers.SFOProblem{}}
+		data, err := parsers.ReadParamSFOFile(path)
+		var formatErr *parsers.SFOFormatError
+		switch {
+		case errors.As(err, &formatErr):
+			result.Problems = append(result.Problems, formatErr.Problem)
+		case err != nil:
+			result.Error = err.Error()
+		default:
+			result.Problems = append(result.Problems, parsers.ValidateParamSFO(data)...)
+		}
+		result.Valid = result.Error == ""
+		for _, problem := range result.Problems {
+			result.Valid = result.Valid && !problem.Fatal
+		}
+		if !result.Valid {
+			invalid++
+		}
+		results = append(results, result)
+	}
+
+	if sfoValidateJSON {
+		out, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, result := range results {
+			switch {
+			case result.Error != "":
+				ui.Errorf("%s: %s\n", result.Path, result.Error)
+			case len(result.Problems) == 0:
+				ui.Successf("%s: valid\n", result.Path)
+			default:
+				fmt.Printf("%s:\n", result.Path)
+				for _, problem := range result.Problems {
+					level := "WARNING"
+					if problem.Fatal {
+						level = "ERROR"
+					}
+					fmt.Printf("  %-7s  %s\n", level, problem)
+				}
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d PARAM.SFO files can't be read", invalid, len(results))
+	}
+	return nil
+}
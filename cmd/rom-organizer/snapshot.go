@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// noSnapshot skips the configured snapshot command for one run
+var noSnapshot bool
+
+// takeSnapshot runs snapshot.command from the config before an operation that deletes or
+// replaces content in paths, so the library can be rolled back. Without a command it does
+// nothing; a failing command stops the operation.
+func takeSnapshot(operation string, paths ...string) error {
+	command := appConfig.Snapshot.Command
+	if command == "" {
+		return nil
+	}
+	if noSnapshot {
+		ui.Warnf("Not taking a snapshot before %s (--no-snapshot)\n", operation)
+		return nil
+	}
+
+	name := "rom-organizer-" + time.Now().Format("20060102-150405")
+	ui.Infof("Taking snapshot %s before %s\n", name, operation)
+	ui.Verbosef("Running snapshot command: %s\n", command)
+	cmd := common.ShellCommand(command)
+	cmd.Env = append(os.Environ(),
+		"SNAPSHOT_NAME="+name,
+		"OPERATION="+operation,
+		"PATHS="+strings.Join(paths, "\n"),
+	)
+	cmd.Stdout = ui.Output()
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("snapshot command %q failed: %w (nothing was changed; use --no-snapshot to go ahead without one)", command, err)
+	}
+	return nil
+}
+
+// batchSnapshot takes a snapshot before a packaging batch that deletes sources (--move),
+// converts organized games in place, or replaces existing games (--force, --on-collision
+// overwrite)
+func batchSnapshot(sources []string, opts organizer.OrganizeOptions) error {
+	var operations []string
+	if opts.MoveSource {
+		operations = append(operations, "move")
+	}
+	for _, source := range sources {
+		if organizer.ConvertsInPlace(source, opts) {
+			operations = append(operations, "convert")
+			break
+		}
+	}
+	if opts.Force {
+		operations = append(operations, "force")
+	}
+	if opts.OnCollision == common.CollisionOverwrite {
+		operations = append(operations, "overwrite")
+	}
+	if len(operations) == 0 {
+		return nil
+	}
+	return takeSnapshot(strings.Join(operations, ","), batchTargets(sources, opts)...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+)
+
+func TestSnapshotBeforeConversion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the snapshot command below is written for sh")
+	}
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	organized := organizedGame(t, filepath.Join(lib, "Game A [BLUS30001]"))
+	record := filepath.Join(dir, "snapshot.txt")
+
+	command := appConfig.Snapshot.Command
+	appConfig.Snapshot.Command = `printf '%s\n%s\n' "$OPERATION" "$PATHS" > "` + record + `"`
+	t.Cleanup(func() { appConfig.Snapshot.Command = command })
+
+	// Already game/: nothing is deleted
+	opts := organizer.OrganizeOptions{OutputDir: lib, Layout: appConfig.Layout, Format: organizer.Decompressed}
+	if err := batchSnapshot([]string{organized}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if exists(record) {
+		t.Fatal("snapshot taken for a game already in the requested format")
+	}
+
+	// game/ is replaced by game.7z
+	opts.Format = organizer.Compressed
+	if err := batchSnapshot([]string{organized}, opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("no snapshot taken before converting game/ to game.7z: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if lines[0] != "convert" {
+		t.Errorf("OPERATION = %q, want convert", lines[0])
+	}
+	if paths := lines[1:]; len(paths) != 2 || paths[1] != organized {
+		t.Errorf("PATHS = %q, want the output and %s", paths, organized)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <library> [library...]",
+	Short: "Show size and compression statistics for organized libraries",
+	Long: `Show size and compression statistics for directories of organized games.
+
+For every compressed game the original size is read from the game.7z listing, so
+the report shows how much space 7z compression saves across the library.
+With --verbose every game is listed, ordered by --sort. --tag, --exclude-tag,
+--collection and --no-demos count only the matching games.
+
+Examples:
+  rom-organizer stats /mnt/nas/ps3
+  rom-organizer stats --verbose /mnt/nas/ps3 /mnt/backup/ps3
+  rom-organizer stats --verbose --sort size /mnt/nas/ps3
+  rom-organizer stats --no-demos /mnt/nas/ps3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: statsHandler,
+}
+
+var (
+	statsSort   string
+	statsFilter catalog.Filter
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	addSortFlag(statsCmd, &statsSort)
+	addCatalogFilterFlags(statsCmd, &statsFilter)
+}
+
+func statsHandler(cmd *cobra.Command, args []string) error {
+	sortBy, err := library.ParseSortBy(statsSort)
+	if err != nil {
+		return err
+	}
+
+	games, c, err := findFilteredGames(args, statsFilter)
+	if err != nil {
+		return err
+	}
+
+	stats, err := library.CollectStats(games)
+	if err != nil {
+		return err
+	}
+	library.SortStats(stats.Games, sortBy)
+
+	if verbose {
+		for _, game := range stats.Games {
+			name := fmt.Sprintf("%s [%s]", game.Info.GameInfo.Title, game.Info.GameInfo.GameID)
+			var status string
+			if compat := c.Compat(game.Info.GameInfo.GameID); compat != nil {
+				status = "  " + compat.Status
+			}
+			if game.Compression != nil {
+				fmt.Printf("%-50s compressed    %s%s\n", name, game.Compression, status)
+			} else {
+				fmt.Printf("%-50s decompressed  %s%s\n", name, common.FormatSize(game.DiskSize), status)
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Games:        %d (%d compressed, %d decompressed)\n", len(stats.Games), stats.Compressed, stats.Decompressed)
+	fmt.Printf("Disk usage:   %s\n", common.FormatSize(stats.DiskSize))
+	if stats.Compressed > 0 {
+		fmt.Printf("Compression:  %s\n", stats.Compression)
+	}
+
+	// Compatibility is only shown once compat has recorded statuses
+	counts := make(map[string]int)
+	known := 0
+	for _, game := range stats.Games {
+		if compat := c.Compat(game.Info.GameInfo.GameID); compat != nil {
+			counts[compat.Status]++
+			known++
+		}
+	}
+	if known > 0 {
+		counts["Unknown"] = len(stats.Games) - known
+		fmt.Printf("RPCS3:        %s\n", formatCompatCounts(counts))
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var trashDryRun bool
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List or purge the sources --move kept for undo",
+	Long: `Sources that --move is done with (a folder copied to another file system, an
+archive or disc image once extracted, a source compressed into game.7z) are not
+deleted right away: they are moved to a .rom-organizer-trash folder next to them,
+which takes no time or extra space, so undo can put them back. They take up their
+space until the trash is purged. Pass --no-trash to a batch to delete them instead.
+
+Examples:
+  rom-organizer trash
+  rom-organizer trash purge
+  rom-organizer trash purge --dry-run 12`,
+	Args: cobra.NoArgs,
+	RunE: trashListHandler,
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:   "purge [run-id...]",
+	Short: "Delete the sources runs kept in the trash (all runs by default)",
+	Long: `Delete the sources that runs kept in the trash, for the given runs or all of
+them. Undo can't put those sources back anymore, so it keeps their games.`,
+	RunE: trashPurgeHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashPurgeCmd)
+
+	trashPurgeCmd.Flags().BoolVarP(&trashDryRun, "dry-run", "n", false, "Show what would be deleted without deleting it")
+	trashPurgeCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before deleting")
+}
+
+// trashedPaths returns the paths a run moved into the trash that are still there
+func trashedPaths(run *catalog.RunRecord) []string {
+	var paths []string
+	for _, game := range run.Games {
+		for _, moved := range game.Moved {
+			if _, err := os.Lstat(moved.To); err == nil && inTrash(moved.To) {
+				paths = append(paths, moved.To)
+			}
+		}
+	}
+	return paths
+}
+
+// trashSize returns the combined size of paths
+func trashSize(paths []string) int64 {
+	var total int64
+	for _, path := range paths {
+		size, _ := common.DirSize(path)
+		total += size
+	}
+	return total
+}
+
+func trashListHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	listed := 0
+	for _, run := range c.Runs {
+		paths := trashedPaths(run)
+		if len(paths) == 0 {
+			continue
+		}
+		if listed == 0 {
+			fmt.Printf("%4s  %-16s  %-10s  %7s  %10s\n", "Run", "Started", "Command", "Sources", "Size")
+		}
+		listed++
+		fmt.Printf("%4d  %-16s  %-10s  %7d  %10s\n", run.ID, run.Started.Local().Format("2006-01-02 15:04"), run.Command,
+			len(paths), common.FormatSize(trashSize(paths)))
+		for _, path := range paths {
+			ui.Verbosef("      %s\n", path)
+		}
+	}
+	if listed == 0 {
+		ui.Infof("The trash is empty\n")
+	}
+	return nil
+}
+
+func trashPurgeHandler(cmd *cobra.Command, args []string) error {
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	runs := c.Runs
+	if len(args) > 0 {
+		runs = nil
+		for _, arg := range args {
+			run, err := lookupRun(c, arg)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+	}
+
+	var paths []string
+	for _, run := range runs {
+		paths = append(paths, trashedPaths(run)...)
+	}
+	if len(paths) == 0 {
+		ui.Infof("Nothing in the trash to purge\n")
+		return nil
+	}
+	for _, path := range paths {
+		fmt.Printf("delete  %s\n", path)
+	}
+	if trashDryRun {
+		return nil
+	}
+	if err := checkWritable(paths...); err != nil {
+		return err
+	}
+	if !assumeYes {
+		ok, err := confirm(fmt.Sprintf("Delete %d source(s), %s, from the trash? Undo can't restore them afterwards", len(paths), common.FormatSize(trashSize(paths))))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("deleting %s: %w", path, err)
+		}
+		removeEmptyTrash(filepath.Dir(path))
+	}
+	ui.Successf("Purged %d source(s) from the trash\n", len(paths))
+	return nil
+}
+
+// inTrash reports whether path is inside a trash folder of --move
+func inTrash(path string) bool {
+	for _, name := range strings.Split(filepath.ToSlash(path), "/") {
+		if name == organizer.TrashDir {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEmptyTrash removes dir and the folders above it up to the trash folder, as long
+// as they are empty
+func removeEmptyTrash(dir string) {
+	for inTrash(dir) {
+		if os.Remove(dir) != nil || filepath.Base(dir) == organizer.TrashDir {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var undoDryRun bool
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [run-id|last]",
+	Short: "Remove what a past compress, decompress or organize run created",
+	Long: `Reverse a batch recorded in the history (the last one by default) by removing
+the game folders it created, in the primary output and in every mirror it copied
+them to. The run is then marked as undone, so it can't be undone twice. Games the
+run handled in place, already organized or converted where they were, are left
+alone: the run created no folder for them.
+
+Sources moved with --move go back where they were: folders renamed into the game
+are renamed back, and sources --move was done with are taken out of the trash
+next to them. A game whose source can't be put back, because the trash was purged
+or it was deleted with --no-trash, is kept. Outputs on sftp:// locations are listed
+for removal by hand. Runs with --force or --on-collision=overwrite replaced earlier
+games, which undo can't bring back.
+
+Examples:
+  rom-organizer undo
+  rom-organizer undo --dry-run 12
+  rom-organizer undo --yes last`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: undoHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().BoolVarP(&undoDryRun, "dry-run", "n", false, "Show what would be removed without removing it")
+	undoCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before removing")
+}
+
+// undoGame is a game of a run that undo takes back
+type undoGame struct {
+	remove []string            // Folders the run created: the game and its mirror copies
+	moved  []catalog.MovedPath // Source paths to move back, in the order they were moved
+}
+
+func undoHandler(cmd *cobra.Command, args []string) error {
+	arg := "last"
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	c, err := openCatalog()
+	if err != nil {
+		return err
+	}
+	run, err := lookupRun(c, arg)
+	if err != nil {
+		return err
+	}
+	if run.Undone != nil {
+		return fmt.Errorf("run %d was already undone on %s", run.ID, run.Undone.Local().Format("2006-01-02 15:04"))
+	}
+
+	outputs := runOutputs(run)
+	games, kept := undoGames(run)
+	if overwrote(run) {
+		ui.Warnf("Run %d could replace existing games (--force or --on-collision=overwrite); those can't be restored\n", run.ID)
+	}
+
+	var paths, restores []string
+	for _, game := range games {
+		for _, moved := range game.moved {
+			if !isWithinAny(moved.From, game.remove) {
+				fmt.Printf("restore %s\n", moved.From)
+				restores = append(restores, moved.From)
+			}
+		}
+		for _, path := range game.remove {
+			fmt.Printf("remove  %s\n", path)
+		}
+		paths = append(paths, game.remove...)
+	}
+	if len(paths) == 0 {
+		ui.Infof("Nothing of run %d left to remove\n", run.ID)
+		return nil
+	}
+	if undoDryRun {
+		return nil
+	}
+
+	if err := checkWritable(append(paths, restores...)...); err != nil {
+		return err
+	}
+	if !assumeYes {
+		ok, err := confirm(fmt.Sprintf("Remove %d game folder(s) created by run %d (%s)?", len(paths), run.ID, run.Command))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	if err := takeSnapshot("undo", outputs...); err != nil {
+		return err
+	}
+
+	restored := 0
+	for _, game := range games {
+		if len(game.moved) > 0 {
+			if err := moveBack(game.moved); err != nil {
+				return err
+			}
+			restored++
+		}
+		for _, path := range game.remove {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("removing %s: %w", path, err)
+			}
+			ui.Verbosef("Removed %s\n", path)
+			// An alphabetical bucket (--organize-by first-letter) left empty goes too
+			if parent := filepath.Dir(path); !isOutput(parent, outputs) {
+				os.Remove(parent)
+			}
+		}
+	}
+
+	undone := time.Now().UTC()
+	run.Undone = &undone
+	if err := c.Save(); err != nil {
+		ui.Warnf("Could not mark run %d as undone: %v\n", run.ID, err)
+	}
+	ui.Successf("Removed %d game folder(s) created by run %d\n", len(paths), run.ID)
+	if restored > 0 {
+		ui.Infof("Moved %d game(s) back to their sources\n", restored)
+	}
+	if kept > 0 {
+		ui.Infof("Kept %d game(s) whose sources were moved and can't be put back\n", kept)
+	}
+	return nil
+}
+
+// undoGames returns what undo takes back of each game a run organized, and the number of
+// games kept because their source can't be put back
+func undoGames(run *catalog.RunRecord) (games []undoGame, kept int) {
+	for _, game := range run.Games {
+		if game.Status != catalog.RunGameSuccess || game.Target == "" {
+			continue
+		}
+		name := fmt.Sprintf("%s [%s]", game.Title, game.GameID)
+		target, source := runPath(run, game.Target), runPath(run, game.Source)
+		if game.InPlace || filepath.Clean(target) == filepath.Clean(source) {
+			ui.Verbosef("Skipping %s: it was handled in place, the run created no folder for it\n", name)
+			continue
+		}
+		if sftp.IsURL(target) && len(game.Moved) > 0 {
+			ui.Warnf("Keeping %s: its source was moved to a remote output\n", name)
+			kept++
+			continue
+		}
+		if len(game.Moved) > 0 && !isOrganizedGame(target, game.GameID) {
+			ui.Warnf("Keeping %s: %s isn't an organized copy of it anymore, so its source can't be put back\n", name, target)
+			kept++
+			continue
+		}
+		if !canMoveBack(game.Moved) {
+			ui.Warnf("Keeping %s: its source was moved and the trash doesn't hold it anymore\n", name)
+			kept++
+			continue
+		}
+		if _, err := os.Stat(source); len(game.Moved) == 0 && !sftp.IsURL(source) && err != nil {
+			ui.Warnf("Keeping %s: its source %s is gone and can't be put back\n", name, game.Source)
+			kept++
+			continue
+		}
+
+		undo := undoGame{moved: game.Moved}
+		for _, path := range append([]string{target}, game.Mirrors...) {
+			if sftp.IsURL(path) {
+				ui.Warnf("Not removing %s: remote outputs have to be removed by hand\n", path)
+				continue
+			}
+			if !isOrganizedGame(path, game.GameID) {
+				ui.Verbosef("Skipping %s: not an organized copy of %s anymore\n", path, name)
+				continue
+			}
+			undo.remove = append(undo.remove, path)
+		}
+		games = append(games, undo)
+	}
+	return games, kept
+}
+
+// canMoveBack reports whether every path a source was moved to is still there and its
+// original place free, so the source can be put back as it was
+func canMoveBack(moved []catalog.MovedPath) bool {
+	for _, m := range moved {
+		if _, err := os.Lstat(m.To); err != nil {
+			return false
+		}
+		if _, err := os.Lstat(m.From); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// moveBack renames the paths a source was moved to back where they were, last first, and
+// removes the trash folders this leaves empty
+func moveBack(moved []catalog.MovedPath) error {
+	for i := len(moved) - 1; i >= 0; i-- {
+		m := moved[i]
+		if err := os.MkdirAll(filepath.Dir(m.From), 0755); err != nil {
+			return fmt.Errorf("restoring %s: %w", m.From, err)
+		}
+		if err := os.Rename(m.To, m.From); err != nil {
+			return fmt.Errorf("restoring %s: %w", m.From, err)
+		}
+		ui.Verbosef("Restored %s\n", m.From)
+		if inTrash(m.To) {
+			removeEmptyTrash(filepath.Dir(m.To))
+		}
+	}
+	return nil
+}
+
+// isWithinAny reports whether path is one of dirs or inside one
+func isWithinAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPath resolves a path recorded in a run against the folder the run was started in
+func runPath(run *catalog.RunRecord, path string) string {
+	if sftp.IsURL(path) || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(run.Dir, path)
+}
+
+// runOutputs returns the --output folders of a run, primary first
+func runOutputs(run *catalog.RunRecord) []string {
+	var outputs []string
+	for _, flag := range run.Flags {
+		if value, ok := strings.CutPrefix(flag, "--output="); ok {
+			outputs = append(outputs, runPath(run, value))
+		}
+	}
+	if len(outputs) == 0 {
+		outputs = []string{run.Dir}
+	}
+	return outputs
+}
+
+// isOrganizedGame reports whether path still holds an organized copy of the game
+func isOrganizedGame(path, gameID string) bool {
+	info, err := common.DetectOrganizedDirectory(path, appConfig.Layout, false)
+	if err != nil || !info.IsOrganized {
+		return false
+	}
+	return info.GameInfo == nil || gameID == "" || info.GameInfo.GameID == gameID
+}
+
+// isOutput reports whether dir is one of a run's output folders
+func isOutput(dir string, outputs []string) bool {
+	for _, output := range outputs {
+		if filepath.Clean(dir) == filepath.Clean(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// overwrote reports whether a run's flags let it replace existing games
+func overwrote(run *catalog.RunRecord) bool {
+	for _, flag := range run.Flags {
+		if flag == "--force=true" || flag == "--on-collision=overwrite" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+)
+
+// organizedGame creates an organized game folder with a file in game/
+func organizedGame(t *testing.T, path string) string {
+	t.Helper()
+	if err := common.CreateTargetStructure(path, appConfig.Layout, false); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(path, "game", "EBOOT.BIN"))
+	return path
+}
+
+func writeTestFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestUndoGames(t *testing.T) {
+	dir := t.TempDir()
+	lib, src, mirror := filepath.Join(dir, "lib"), filepath.Join(dir, "src"), filepath.Join(dir, "mirror")
+	trash := filepath.Join(src, organizer.TrashDir, "20261016-120000")
+
+	// Organized in place: the run's target is its source
+	inPlace := organizedGame(t, filepath.Join(lib, "Game A [BLUS30001]"))
+
+	// Copied, with a mirror copy; another copy in a mirror the run didn't make is kept
+	copiedSource := filepath.Join(src, "Game B")
+	writeTestFile(t, filepath.Join(copiedSource, "PS3_GAME", "PARAM.SFO"))
+	copied := organizedGame(t, filepath.Join(lib, "Game B [BLUS30002]"))
+	copiedMirror := organizedGame(t, filepath.Join(mirror, "Game B [BLUS30002]"))
+	unrecorded := organizedGame(t, filepath.Join(dir, "other-mirror", "Game B [BLUS30002]"))
+
+	// Moved: the game folder was renamed into the game, then its emptied wrapper trashed
+	moved := organizedGame(t, filepath.Join(lib, "Game C [BLUS30003]"))
+	wrapper := filepath.Join(src, "Pack C")
+	trashedWrapper := filepath.Join(trash, "1", "Pack C")
+	if err := os.MkdirAll(trashedWrapper, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(trashedWrapper, "readme.nfo"))
+
+	// Moved, but the trash was purged since
+	purged := organizedGame(t, filepath.Join(lib, "Game D [BLUS30004]"))
+
+	run := &catalog.RunRecord{
+		ID:    1,
+		Dir:   dir,
+		Flags: []string{"--output=" + lib, "--output=" + mirror},
+		Games: []catalog.RunGame{
+			{Source: inPlace, Target: inPlace, GameID: "BLUS30001", Status: catalog.RunGameSuccess, InPlace: true},
+			{Source: "src/Game B", Target: copied, GameID: "BLUS30002", Status: catalog.RunGameSuccess, Mirrors: []string{copiedMirror}},
+			{Source: wrapper, Target: moved, GameID: "BLUS30003", Status: catalog.RunGameSuccess, Moved: []catalog.MovedPath{
+				{From: filepath.Join(wrapper, "Game C"), To: filepath.Join(moved, "game")},
+				{From: wrapper, To: trashedWrapper},
+			}},
+			{Source: filepath.Join(src, "Game D"), Target: purged, GameID: "BLUS30004", Status: catalog.RunGameSuccess, Moved: []catalog.MovedPath{
+				{From: filepath.Join(src, "Game D"), To: filepath.Join(trash, "2", "Game D")},
+			}},
+			{Source: filepath.Join(src, "Game E"), Status: catalog.RunGameFailed},
+		},
+	}
+
+	games, kept := undoGames(run)
+	if kept != 1 {
+		t.Errorf("kept %d games, want the one whose trash was purged", kept)
+	}
+	if len(games) != 2 {
+		t.Fatalf("undo takes back %d games, want the copied and the moved one: %+v", len(games), games)
+	}
+	if got := games[0].remove; len(got) != 2 || got[0] != copied || got[1] != copiedMirror {
+		t.Errorf("copied game removes %v, want %s and its recorded mirror", got, copied)
+	}
+	if got := games[1].remove; len(got) != 1 || got[0] != moved || len(games[1].moved) != 2 {
+		t.Errorf("moved game removes %v and moves back %+v", got, games[1].moved)
+	}
+
+	if err := moveBack(games[1].moved); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(filepath.Join(wrapper, "Game C", "EBOOT.BIN")) || !exists(filepath.Join(wrapper, "readme.nfo")) {
+		t.Error("moved source not restored as it was")
+	}
+	if exists(filepath.Join(moved, "game")) {
+		t.Error("game/ folder still in the organized game after moving it back")
+	}
+	if exists(filepath.Join(src, organizer.TrashDir)) {
+		t.Error("emptied trash folder left behind")
+	}
+	for _, path := range []string{inPlace, unrecorded, purged, copiedSource} {
+		if !exists(path) {
+			t.Errorf("%s is gone", path)
+		}
+	}
+}
+
+func TestCanMoveBack(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "from"), filepath.Join(dir, "to")
+	writeTestFile(t, to)
+	if !canMoveBack([]catalog.MovedPath{{From: from, To: to}}) {
+		t.Error("refused a move back to a free place")
+	}
+	if canMoveBack([]catalog.MovedPath{{From: from, To: filepath.Join(dir, "purged")}}) {
+		t.Error("accepted a move back from a path that is gone")
+	}
+	writeTestFile(t, from)
+	if canMoveBack([]catalog.MovedPath{{From: from, To: to}}) {
+		t.Error("accepted a move back over an existing path")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/export"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	verifyLibraries []string
+	verifyHashName  string
+	verifyFilter    catalog.Filter
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check copies of the library without changing them",
+}
+
+var verifyExportCmd = &cobra.Command{
+	Use:   "export <drive-root>",
+	Short: "Compare an exported drive with the library before unplugging it",
+	Long: `Compare an external drive, filled by hand or by export, with the library and report
+each game on it as ok, extra (not in the library) or corrupted (files missing or of
+another size, or with --hash, other contents). Nothing on the drive is written.
+
+The drive's root can hold:
+
+  GAMES/       game folders for webMAN MOD and multiMAN, compared with the game/ folder
+               or game.7z of the library game with the same title ID
+  PS3ISO/      disc images, checked to be of a game in the library
+  backup-01/   folders of export split, compared with the whole organized folders
+               listed in their manifest.json (the root may also be one of them)
+  packages/    packages of export pkg-layout, compared with those in _updates/ and _dlc/
+
+Files split into parts for FAT32 (EBOOT.BIN.66600, ...; game.iso.0, ...) are joined.
+Games listed in a split manifest but not copied are missing; with --tag, --collection,
+--exclude-tag or --no-demos, so are the games they select that aren't on the drive.
+
+--hash compares contents: files of game/ folders are hashed on both sides, and those
+of game.7z are compared with the CRC32 stored in the archive. Without --library the
+config's library.roots are the library.
+
+Examples:
+  rom-organizer verify export /media/usb --library /mnt/nas/ps3
+  rom-organizer verify export /media/usb --collection party --hash`,
+	Args: cobra.ExactArgs(1),
+	RunE: verifyExportHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.AddCommand(verifyExportCmd)
+	verifyExportCmd.Flags().StringArrayVar(&verifyLibraries, "library", nil, "Library to compare the drive with (repeatable; default the config's library.roots)")
+	verifyExportCmd.Flags().StringVar(&verifyHashName, "hash", "", "Also compare file contents with this hash: xxh64, crc32, md5, sha1 or sha256 (alone, the config's hashes.verify)")
+	verifyExportCmd.Flags().Lookup("hash").NoOptDefVal = defaultChecksum
+	addCatalogFilterFlags(verifyExportCmd, &verifyFilter)
+}
+
+func verifyExportHandler(cmd *cobra.Command, args []string) error {
+	roots := verifyLibraries
+	if len(roots) == 0 {
+		roots = appConfig.Library.Roots
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no library to compare with: give --library or set library.roots in the config")
+	}
+
+	var hash common.HashAlgorithm
+	switch verifyHashName {
+	case "":
+	case defaultChecksum:
+		hash = appConfig.Hashes.VerifyHash()
+	default:
+		var err error
+		if hash, err = common.ParseHashAlgorithm(verifyHashName); err != nil {
+			return fmt.Errorf("--hash: %w", err)
+		}
+	}
+
+	var games []library.Game
+	for _, root := range roots {
+		found, err := library.FindGames(root, appConfig.Layout)
+		if err != nil {
+			return err
+		}
+		games = append(games, found...)
+	}
+	opts := export.VerifyDriveOptions{
+		Layout:   appConfig.Layout,
+		Hash:     hash,
+		Progress: func(path string) { ui.Verbosef("Checking %s\n", path) },
+	}
+	if !verifyFilter.IsEmpty() {
+		expected, _, err := findFilteredGames(roots, verifyFilter)
+		if err != nil {
+			return err
+		}
+		opts.Expected = expected
+	}
+
+	checks, err := export.VerifyDrive(args[0], games, opts)
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		return fmt.Errorf("no GAMES/, PS3ISO/, split folders or packages/ found in %s", args[0])
+	}
+
+	counts := map[export.DriveStatus]int{}
+	for _, check := range checks {
+		counts[check.Status]++
+		name := check.Path
+		if check.GameID != "" {
+			name = fmt.Sprintf("%s [%s]", check.Title, check.GameID)
+		}
+		line := fmt.Sprintf("%-11s %-8s %s: %s", "["+strings.ToUpper(string(check.Status))+"]", check.Kind, name, check.Detail)
+		if check.Status == export.DriveOK && !ui.IsVerbose() {
+			continue
+		}
+		fmt.Println(line)
+		if check.Path != "" && check.GameID != "" {
+			ui.Verbosef("            %s\n", check.Path)
+		}
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d ok, %d missing, %d corrupted, %d extra", counts[export.DriveOK], counts[export.DriveMissing], counts[export.DriveCorrupted], counts[export.DriveExtra])
+	if counts[export.DriveMissing]+counts[export.DriveCorrupted] > 0 {
+		return fmt.Errorf("drive doesn't match the library: %s", summary)
+	}
+	if counts[export.DriveExtra] > 0 {
+		ui.Warnf("%s\n", summary)
+		return nil
+	}
+	ui.Successf("%s; safe to unplug\n", summary)
+	return nil
+}
@@ -0,0 +1,513 @@
+// Package catalog stores user-maintained information about games (tags and
+// collections) in a JSON file, keyed by game ID so it follows a game across libraries.
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// Entry is the catalog record for one game
+type Entry struct {
+	Title    string        `json:"title,omitempty"` // Last known title, for display
+	Tags     []string      `json:"tags,omitempty"`
+	Compat   *CompatStatus `json:"compat,omitempty"` // Emulator compatibility, if looked up
+	Modified time.Time     `json:"modified"`         // Last change, used to resolve merge conflicts
+
+	// Compression pins the game's archive settings, replacing the console's
+	Compression *CompressionOverride `json:"compression,omitempty"`
+
+	// Unverified marks a game organized under a title and ID that weren't read from its
+	// PARAM.SFO, but from its folder name or --set-title and --set-id
+	Unverified bool `json:"unverified,omitempty"`
+}
+
+// CompressionOverride holds the archive settings pinned for one game. Unset fields
+// keep the value from the console's compression policy.
+type CompressionOverride struct {
+	Level           *int     `json:"level,omitempty"`            // 0 stores everything uncompressed
+	StoreExtensions []string `json:"store_extensions,omitempty"` // Replaces the console's list
+	ExtraArgs       []string `json:"extra_args,omitempty"`       // Replaces the console's arguments
+	Note            string   `json:"note,omitempty"`             // Why the override exists
+}
+
+// Apply returns archive with the fields set in the override replaced
+func (o CompressionOverride) Apply(archive common.ArchiveOptions) common.ArchiveOptions {
+	if o.Level != nil {
+		archive.Level = *o.Level
+	}
+	if o.StoreExtensions != nil {
+		archive.StoreExtensions = o.StoreExtensions
+	}
+	if o.ExtraArgs != nil {
+		archive.ExtraArgs = o.ExtraArgs
+	}
+	return archive
+}
+
+// CompatStatus is a game's emulator compatibility as last looked up
+type CompatStatus struct {
+	Status    string    `json:"status"`            // e.g. Playable, Ingame, Intro, Loadable, Nothing
+	Updated   string    `json:"updated,omitempty"` // Date the status last changed upstream
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Collection is a named, user-defined list of games
+type Collection struct {
+	Description string    `json:"description,omitempty"`
+	Games       []string  `json:"games"`    // Game IDs
+	Modified    time.Time `json:"modified"` // Last change, used to resolve merge conflicts
+}
+
+// Catalog is the set of game entries and collections stored in a catalog file
+type Catalog struct {
+	path        string
+	loaded      []byte                 // The file as it was when opened or last saved
+	base        *Catalog               // loaded, parsed: what Save compares changes against
+	Games       map[string]*Entry      `json:"games"`               // Keyed by game ID
+	Collections map[string]*Collection `json:"collections"`         // Keyed by collection name
+	DiscKeys    map[string]string      `json:"disc_keys,omitempty"` // PS3 disc keys (hex) keyed by title ID
+
+	// Scans remembers what the last scan found, keyed by the absolute path of each
+	// organized game directory. Paths only make sense on one machine, so imports skip it.
+	Scans map[string]*ScanRecord `json:"scans,omitempty"`
+
+	// Contents maps PSN content IDs to the game they belong to and their name
+	Contents map[string]*ContentRecord `json:"contents,omitempty"`
+
+	// DATs holds the set lists of imported arcade and cartridge DAT files, keyed by the
+	// DAT's name, with the parent/clone relationships between their sets
+	DATs map[string]*DAT `json:"dats,omitempty"`
+
+	// Runs is the history of batch runs, oldest first. Like Scans it holds local
+	// paths, so imports skip it.
+	Runs []*RunRecord `json:"runs,omitempty"`
+}
+
+// ScanRecord is what a scan found in one organized game directory
+type ScanRecord struct {
+	GameID   string    `json:"game_id"`
+	Title    string    `json:"title,omitempty"`
+	Size     int64     `json:"size"`               // Total size of the directory
+	ModTime  time.Time `json:"mod_time"`           // Latest modification time of any file in it
+	Hash     string    `json:"hash,omitempty"`     // "<algorithm>:<hex>" of game.7z, or of the game/ file hashes
+	Version  string    `json:"version,omitempty"`  // APP_VER from PARAM.SFO
+	Firmware string    `json:"firmware,omitempty"` // PS3_SYSTEM_VER from PARAM.SFO
+	Build    string    `json:"build,omitempty"`    // "demo" or "beta" for pre-release builds
+	Scanned  time.Time `json:"scanned"`
+}
+
+// DefaultPath returns the default catalog file location next to the config file
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "catalog.json"
+	}
+	return filepath.Join(dir, "rom-organizer", "catalog.json")
+}
+
+// Open loads the catalog file at path, returning an empty catalog if it does not exist.
+// Several processes can use the same catalog: reads and saves take a lock, and a save
+// keeps what others saved since this catalog was opened.
+func Open(path string) (*Catalog, error) {
+	release, err := acquire(path, false)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	release()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading catalog: %w", err)
+	}
+	if err != nil {
+		data = []byte("{}")
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing catalog %s: %w", path, err)
+	}
+	c.path = path
+	c.remember(data)
+	return c, nil
+}
+
+// remember records the file content c now matches
+func (c *Catalog) remember(data []byte) {
+	c.loaded = data
+	c.base, _ = Parse(data)
+}
+
+// Parse decodes catalog JSON, as written by Save or Export
+func Parse(data []byte) (*Catalog, error) {
+	c := &Catalog{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+
+	if c.Games == nil {
+		c.Games = make(map[string]*Entry)
+	}
+	if c.Collections == nil {
+		c.Collections = make(map[string]*Collection)
+	}
+	if c.DiscKeys == nil {
+		c.DiscKeys = make(map[string]string)
+	}
+	if c.Scans == nil {
+		c.Scans = make(map[string]*ScanRecord)
+	}
+	if c.Contents == nil {
+		c.Contents = make(map[string]*ContentRecord)
+	}
+	if c.DATs == nil {
+		c.DATs = make(map[string]*DAT)
+	}
+	return c, nil
+}
+
+// Export writes the catalog as JSON
+func (c *Catalog) Export(w io.Writer) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding catalog: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Save writes the catalog atomically (write to a temporary file, then rename). When
+// another process saved the catalog since it was opened, the changes made here are
+// applied on top of that version rather than replacing it.
+func (c *Catalog) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("creating catalog directory: %w", err)
+	}
+	release, err := acquire(c.path, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	current, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		current, err = []byte("{}"), nil // As Open reads a missing catalog
+	}
+	if err != nil {
+		return fmt.Errorf("reading catalog: %w", err)
+	}
+	if !bytes.Equal(current, c.loaded) {
+		theirs, err := Parse(current)
+		if err != nil {
+			return fmt.Errorf("parsing catalog %s: %w", c.path, err)
+		}
+		c.rebase(theirs)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding catalog: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing catalog: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing catalog: %w", err)
+	}
+	c.remember(data)
+	return nil
+}
+
+// NormalizeTag lowercases a tag and checks that it is a single word-like token
+// (letters, digits, '-', '_' and '.'), e.g. "favorites" or "co-op"
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	for _, r := range tag {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return "", fmt.Errorf("invalid tag %q: use letters, digits, '-', '_' or '.'", tag)
+		}
+	}
+	return tag, nil
+}
+
+// entry returns the entry for a game, creating it if needed
+func (c *Catalog) entry(gameID, title string) *Entry {
+	e, ok := c.Games[gameID]
+	if !ok {
+		e = &Entry{}
+		c.Games[gameID] = e
+	}
+	if title != "" {
+		e.Title = title
+	}
+	return e
+}
+
+// Tag adds tags to a game. The title is remembered for display when given.
+func (c *Catalog) Tag(gameID, title string, tags ...string) error {
+	e := c.entry(gameID, title)
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		if !contains(e.Tags, tag) {
+			e.Tags = append(e.Tags, tag)
+			e.Modified = time.Now()
+		}
+	}
+	sort.Strings(e.Tags)
+	return nil
+}
+
+// Untag removes tags from a game
+func (c *Catalog) Untag(gameID string, tags ...string) error {
+	e, ok := c.Games[gameID]
+	if !ok {
+		return nil
+	}
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		if contains(e.Tags, tag) {
+			e.Tags = remove(e.Tags, tag)
+			e.Modified = time.Now()
+		}
+	}
+	c.dropIfEmpty(gameID)
+	return nil
+}
+
+// Tags returns a game's tags
+func (c *Catalog) Tags(gameID string) []string {
+	if e, ok := c.Games[gameID]; ok {
+		return e.Tags
+	}
+	return nil
+}
+
+// HasTag reports whether a game has the given tag
+func (c *Catalog) HasTag(gameID, tag string) bool {
+	return contains(c.Tags(gameID), tag)
+}
+
+// TagCounts returns the number of games carrying each tag
+func (c *Catalog) TagCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, e := range c.Games {
+		for _, tag := range e.Tags {
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// CreateCollection adds an empty collection
+func (c *Catalog) CreateCollection(name, description string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("collection name must not be empty")
+	}
+	if _, ok := c.Collections[name]; ok {
+		return fmt.Errorf("collection %q already exists", name)
+	}
+	c.Collections[name] = &Collection{Description: description, Games: []string{}, Modified: time.Now()}
+	return nil
+}
+
+// DeleteCollection removes a collection (the games themselves are untouched)
+func (c *Catalog) DeleteCollection(name string) error {
+	if _, ok := c.Collections[name]; !ok {
+		return fmt.Errorf("no collection named %q", name)
+	}
+	coll := c.Collections[name]
+	delete(c.Collections, name)
+	for _, gameID := range coll.Games {
+		c.dropIfEmpty(gameID)
+	}
+	return nil
+}
+
+// Collection returns the named collection
+func (c *Catalog) Collection(name string) (*Collection, error) {
+	coll, ok := c.Collections[name]
+	if !ok {
+		return nil, fmt.Errorf("no collection named %q", name)
+	}
+	return coll, nil
+}
+
+// AddToCollection adds a game to a collection. The title is remembered for display when given.
+func (c *Catalog) AddToCollection(name, gameID, title string) error {
+	coll, err := c.Collection(name)
+	if err != nil {
+		return err
+	}
+	if title != "" {
+		c.entry(gameID, title)
+	}
+	if !contains(coll.Games, gameID) {
+		coll.Games = append(coll.Games, gameID)
+		sort.Strings(coll.Games)
+		coll.Modified = time.Now()
+	}
+	return nil
+}
+
+// RemoveFromCollection removes a game from a collection
+func (c *Catalog) RemoveFromCollection(name, gameID string) error {
+	coll, err := c.Collection(name)
+	if err != nil {
+		return err
+	}
+	if contains(coll.Games, gameID) {
+		coll.Games = remove(coll.Games, gameID)
+		coll.Modified = time.Now()
+	}
+	c.dropIfEmpty(gameID)
+	return nil
+}
+
+// SetCompat records a game's emulator compatibility status
+func (c *Catalog) SetCompat(gameID, title string, status CompatStatus) {
+	e := c.entry(gameID, title)
+	e.Compat = &status
+	e.Modified = time.Now()
+}
+
+// Compat returns a game's recorded compatibility status, or nil if it was never looked up
+func (c *Catalog) Compat(gameID string) *CompatStatus {
+	if e, ok := c.Games[gameID]; ok {
+		return e.Compat
+	}
+	return nil
+}
+
+// SetCompression pins a game's archive settings
+func (c *Catalog) SetCompression(gameID, title string, override CompressionOverride) error {
+	if err := override.Apply(common.DefaultArchiveOptions()).Validate(); err != nil {
+		return err
+	}
+	e := c.entry(gameID, title)
+	e.Compression = &override
+	e.Modified = time.Now()
+	return nil
+}
+
+// ClearCompression removes a game's pinned archive settings
+func (c *Catalog) ClearCompression(gameID string) {
+	e, ok := c.Games[gameID]
+	if !ok || e.Compression == nil {
+		return
+	}
+	e.Compression = nil
+	e.Modified = time.Now()
+	c.dropIfEmpty(gameID)
+}
+
+// Compression returns a game's pinned archive settings, or nil if it has none
+func (c *Catalog) Compression(gameID string) *CompressionOverride {
+	if e, ok := c.Games[gameID]; ok {
+		return e.Compression
+	}
+	return nil
+}
+
+// SetUnverified records whether a game's title and ID were read from its metadata the
+// last time it was organized
+func (c *Catalog) SetUnverified(gameID, title string, unverified bool) {
+	if e, ok := c.Games[gameID]; !unverified && (!ok || !e.Unverified) {
+		return
+	}
+	e := c.entry(gameID, title)
+	e.Unverified = unverified
+	e.Modified = time.Now()
+	c.dropIfEmpty(gameID)
+}
+
+// Unverified reports whether a game was organized with unverified metadata
+func (c *Catalog) Unverified(gameID string) bool {
+	if e, ok := c.Games[gameID]; ok {
+		return e.Unverified
+	}
+	return false
+}
+
+// SetDiscKey stores the disc key (32 hex digits) for a title ID
+func (c *Catalog) SetDiscKey(titleID, key string) {
+	c.DiscKeys[titleID] = strings.ToLower(key)
+}
+
+// DiscKey returns the stored disc key for a title ID
+func (c *Catalog) DiscKey(titleID string) (string, bool) {
+	key, ok := c.DiscKeys[titleID]
+	return key, ok
+}
+
+// Title returns the last known title of a game, or "" if the catalog has none
+func (c *Catalog) Title(gameID string) string {
+	if e, ok := c.Games[gameID]; ok {
+		return e.Title
+	}
+	return ""
+}
+
+// dropIfEmpty removes a game entry that no longer carries any information
+// and is not part of a collection
+func (c *Catalog) dropIfEmpty(gameID string) {
+	e, ok := c.Games[gameID]
+	if !ok || len(e.Tags) > 0 || e.Compat != nil || e.Compression != nil || e.Unverified {
+		return
+	}
+	for _, coll := range c.Collections {
+		if contains(coll.Games, gameID) {
+			return
+		}
+	}
+	delete(c.Games, gameID)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(list []string, value string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Content sources recorded in ContentRecord.Source
+const (
+	ContentSourcePKG  = "pkg"  // Read from a package header and its PARAM.SFO
+	ContentSourceList = "list" // Imported from a content list
+)
+
+// ContentRecord is what the catalog knows about one PSN content ID, such as
+// EP0001-BLES00001_00-MAPPACK000000001
+type ContentRecord struct {
+	TitleID  string    `json:"title_id"`
+	Name     string    `json:"name,omitempty"` // e.g. the DLC's name
+	Type     string    `json:"type,omitempty"` // e.g. "game data (update or add-on)"
+	Source   string    `json:"source"`         // ContentSourcePKG or ContentSourceList
+	Modified time.Time `json:"modified"`
+}
+
+// ContentTitleID returns the title ID embedded in a content ID, or "" if malformed
+func ContentTitleID(contentID string) string {
+	if len(contentID) < 16 || contentID[6] != '-' {
+		return ""
+	}
+	return contentID[7:16]
+}
+
+// SetContent records a content ID. Empty fields of record keep their stored value, and
+// a name read from a package doesn't replace one imported from a content list, which
+// are usually cleaner. It reports whether anything changed.
+func (c *Catalog) SetContent(contentID string, record ContentRecord) bool {
+	if record.TitleID == "" {
+		record.TitleID = ContentTitleID(contentID)
+	}
+	existing, ok := c.Contents[contentID]
+	if !ok {
+		record.Modified = time.Now().UTC()
+		c.Contents[contentID] = &record
+		return true
+	}
+
+	updated := *existing
+	if record.TitleID != "" {
+		updated.TitleID = record.TitleID
+	}
+	if record.Type != "" {
+		updated.Type = record.Type
+	}
+	if record.Name != "" && (record.Source == ContentSourceList || existing.Source != ContentSourceList || existing.Name == "") {
+		updated.Name = record.Name
+		updated.Source = record.Source
+	}
+	if updated == *existing {
+		return false
+	}
+	updated.Modified = time.Now().UTC()
+	c.Contents[contentID] = &updated
+	return true
+}
+
+// Content returns what the catalog knows about a content ID, or nil
+func (c *Catalog) Content(contentID string) *ContentRecord {
+	return c.Contents[contentID]
+}
+
+// ContentName returns the recorded name of a content ID, or ""
+func (c *Catalog) ContentName(contentID string) string {
+	if record, ok := c.Contents[contentID]; ok {
+		return record.Name
+	}
+	return ""
+}
+
+// ContentIDs returns the content IDs recorded for a title ID, sorted
+func (c *Catalog) ContentIDs(titleID string) []string {
+	var ids []string
+	for id, record := range c.Contents {
+		if record.TitleID == titleID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ContentListing is one row of a content list
+type ContentListing struct {
+	ContentID string
+	TitleID   string
+	Name      string
+}
+
+// ReadContentList reads a content list from a file or an http(s) URL. Lists are tab- or
+// comma-separated with a header row naming at least a "Content ID" column, and usually
+// "Title ID" and "Name" columns, as in the PS3 DLC lists published by PSN archive sites.
+func ReadContentList(source string) ([]ContentListing, error) {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 2 * time.Minute}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("downloading content list: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("downloading content list: %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("reading content list: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	listings, err := ParseContentList(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing content list %s: %w", source, err)
+	}
+	return listings, nil
+}
+
+// ParseContentList parses a tab- or comma-separated content list with a header row
+func ParseContentList(r io.Reader) ([]ContentListing, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	header, _, _ := strings.Cut(string(data), "\n")
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if strings.Contains(header, "\t") {
+		reader.Comma = '\t'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	contentCol, ok := columns["content id"]
+	if !ok {
+		return nil, fmt.Errorf("no \"Content ID\" column in the header")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var listings []ContentListing
+	for _, row := range rows[1:] {
+		if contentCol >= len(row) {
+			continue
+		}
+		// Rows without a well-formed content ID (lists mark unknown ones "MISSING") are skipped
+		contentID := strings.ToUpper(strings.TrimSpace(row[contentCol]))
+		if ContentTitleID(contentID) == "" {
+			continue
+		}
+		titleID := strings.ToUpper(field(row, "title id"))
+		if titleID == "" {
+			titleID = ContentTitleID(contentID)
+		}
+		listings = append(listings, ContentListing{ContentID: contentID, TitleID: titleID, Name: field(row, "name")})
+	}
+	return listings, nil
+}
@@ -0,0 +1,176 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DAT is the set list of one arcade or cartridge DAT file, such as MAME's, recording
+// which sets are clones of which parent and the ROMs each set is made of
+type DAT struct {
+	Version  string             `json:"version,omitempty"`
+	Sets     map[string]*ROMSet `json:"sets"` // Keyed by set name
+	Modified time.Time          `json:"modified"`
+}
+
+// ROMSet is one game or machine of a DAT file
+type ROMSet struct {
+	Description string `json:"description,omitempty"`
+	Parent      string `json:"parent,omitempty"` // The set this one is a clone of (cloneof)
+	ROMOf       string `json:"rom_of,omitempty"` // The set its shared ROMs are stored in: the parent or a BIOS (romof)
+	BIOS        bool   `json:"bios,omitempty"`
+	ROMs        []ROM  `json:"roms,omitempty"`
+}
+
+// ROM is one file of a set
+type ROM struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	CRC   string `json:"crc,omitempty"`   // CRC32 in lowercase hex; empty for a ROM never dumped
+	SHA1  string `json:"sha1,omitempty"`  // Lowercase hex
+	Merge string `json:"merge,omitempty"` // Name of the same ROM in the ROMOf set, when it's shared
+}
+
+// Clones returns the names of the sets that are clones of parent, sorted
+func (d *DAT) Clones(parent string) []string {
+	var clones []string
+	for name, set := range d.Sets {
+		if set.Parent == parent {
+			clones = append(clones, name)
+		}
+	}
+	sort.Strings(clones)
+	return clones
+}
+
+// datFile is the Logiqx XML format used by No-Intro, Redump and ClrMamePro DAT files,
+// and the output of mame -listxml, which names sets machines and has no header
+type datFile struct {
+	Build  string `xml:"build,attr"`
+	Header struct {
+		Name    string `xml:"name"`
+		Version string `xml:"version"`
+	} `xml:"header"`
+	Games    []datSet `xml:"game"`
+	Machines []datSet `xml:"machine"`
+}
+
+type datSet struct {
+	Name        string `xml:"name,attr"`
+	CloneOf     string `xml:"cloneof,attr"`
+	ROMOf       string `xml:"romof,attr"`
+	IsBIOS      string `xml:"isbios,attr"`
+	Description string `xml:"description"`
+	ROMs        []struct {
+		Name   string `xml:"name,attr"`
+		Size   string `xml:"size,attr"`
+		CRC    string `xml:"crc,attr"`
+		SHA1   string `xml:"sha1,attr"`
+		Merge  string `xml:"merge,attr"`
+		Status string `xml:"status,attr"`
+	} `xml:"rom"`
+}
+
+// ReadDAT reads a DAT file, returning its name (from its header, or "MAME" for mame
+// -listxml output) and set list
+func ReadDAT(path string) (string, *DAT, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading DAT file: %w", err)
+	}
+	defer f.Close()
+
+	name, dat, err := ParseDAT(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("parsing DAT file %s: %w", path, err)
+	}
+	return name, dat, nil
+}
+
+// ParseDAT parses a Logiqx XML DAT file or mame -listxml output
+func ParseDAT(r io.Reader) (string, *DAT, error) {
+	var file datFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return "", nil, err
+	}
+
+	name, version := file.Header.Name, file.Header.Version
+	if name == "" && file.Build != "" {
+		name, version = "MAME", file.Build
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("no DAT name in the header")
+	}
+
+	dat := &DAT{Version: version, Sets: make(map[string]*ROMSet)}
+	for _, s := range append(file.Games, file.Machines...) {
+		if s.Name == "" {
+			return "", nil, fmt.Errorf("set without a name")
+		}
+		set := &ROMSet{
+			Description: strings.TrimSpace(s.Description),
+			Parent:      s.CloneOf,
+			ROMOf:       s.ROMOf,
+			BIOS:        s.IsBIOS == "yes",
+		}
+		for _, entry := range s.ROMs {
+			size, err := strconv.ParseInt(entry.Size, 10, 64)
+			if err != nil && entry.Size != "" {
+				return "", nil, fmt.Errorf("set %s: ROM %s has an invalid size %q", s.Name, entry.Name, entry.Size)
+			}
+			rom := ROM{Name: entry.Name, Size: size, SHA1: strings.ToLower(entry.SHA1), Merge: entry.Merge}
+			if entry.Status != "nodump" {
+				rom.CRC = strings.ToLower(entry.CRC)
+			}
+			set.ROMs = append(set.ROMs, rom)
+		}
+		dat.Sets[s.Name] = set
+	}
+
+	// A clone of a set the DAT doesn't list is a set of its own
+	for _, set := range dat.Sets {
+		if _, ok := dat.Sets[set.Parent]; !ok {
+			set.Parent = ""
+		}
+	}
+	return name, dat, nil
+}
+
+// SetDAT records the set list of a DAT file under its name, replacing an earlier version.
+// It reports whether anything changed.
+func (c *Catalog) SetDAT(name string, dat *DAT) bool {
+	if existing, ok := c.DATs[name]; ok && hashOf(datContent(*existing)) == hashOf(datContent(*dat)) {
+		return false
+	}
+	recorded := *dat
+	recorded.Modified = time.Now().UTC()
+	c.DATs[name] = &recorded
+	return true
+}
+
+// DAT returns the set list recorded under name. An empty name picks the only DAT
+// recorded, if there is exactly one.
+func (c *Catalog) DAT(name string) (*DAT, error) {
+	if name == "" {
+		switch len(c.DATs) {
+		case 0:
+			return nil, fmt.Errorf("no DAT files imported (see dat import)")
+		case 1:
+			for _, dat := range c.DATs {
+				return dat, nil
+			}
+		}
+		return nil, fmt.Errorf("several DAT files imported; choose one with --dat (%s)", strings.Join(sortedKeys(c.DATs), ", "))
+	}
+	dat, ok := c.DATs[name]
+	if !ok {
+		return nil, fmt.Errorf("no DAT named %q imported (see dat list)", name)
+	}
+	return dat, nil
+}
@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDAT = `<?xml version="1.0"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>FinalBurn Neo - Arcade Games</name>
+		<version>1.0.0.03</version>
+	</header>
+	<game name="neogeo" isbios="yes">
+		<description>Neo Geo</description>
+		<rom name="sp-s2.sp1" size="131072" crc="9036D879" sha1="4F5ED7105B7128794654CE82B51723E16E389543"/>
+	</game>
+	<game name="mslug" romof="neogeo">
+		<description>Metal Slug - Super Vehicle-001</description>
+		<rom name="201-p1.p1" size="2097152" crc="08d8daa5"/>
+		<rom name="sp-s2.sp1" merge="sp-s2.sp1" size="131072" crc="9036d879"/>
+		<rom name="201-x1.x1" size="1024" status="nodump"/>
+	</game>
+	<game name="mslugj" cloneof="mslug" romof="mslug">
+		<description>Metal Slug (Japan)</description>
+		<rom name="201-p1.p1" merge="201-p1.p1" size="2097152" crc="08d8daa5"/>
+	</game>
+	<game name="mslugb" cloneof="mslugx" romof="mslugx">
+		<description>Metal Slug (bootleg of a set not in this DAT)</description>
+	</game>
+</datafile>`
+
+func TestParseDAT(t *testing.T) {
+	name, dat, err := ParseDAT(strings.NewReader(testDAT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "FinalBurn Neo - Arcade Games" || dat.Version != "1.0.0.03" || len(dat.Sets) != 4 {
+		t.Fatalf("DAT %q %q with %d sets", name, dat.Version, len(dat.Sets))
+	}
+
+	if bios := dat.Sets["neogeo"]; !bios.BIOS || bios.Description != "Neo Geo" || bios.ROMs[0].CRC != "9036d879" ||
+		bios.ROMs[0].SHA1 != "4f5ed7105b7128794654ce82b51723e16e389543" {
+		t.Errorf("BIOS set %+v", bios)
+	}
+	want := &ROMSet{
+		Description: "Metal Slug - Super Vehicle-001",
+		ROMOf:       "neogeo",
+		ROMs: []ROM{
+			{Name: "201-p1.p1", Size: 2097152, CRC: "08d8daa5"},
+			{Name: "sp-s2.sp1", Size: 131072, CRC: "9036d879", Merge: "sp-s2.sp1"},
+			{Name: "201-x1.x1", Size: 1024}, // Never dumped, so no CRC
+		},
+	}
+	if got := dat.Sets["mslug"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("parent set %+v, want %+v", got, want)
+	}
+	if clone := dat.Sets["mslugj"]; clone.Parent != "mslug" || clone.ROMOf != "mslug" {
+		t.Errorf("clone set %+v", clone)
+	}
+	if orphan := dat.Sets["mslugb"]; orphan.Parent != "" {
+		t.Errorf("clone of a set missing from the DAT has parent %q", orphan.Parent)
+	}
+	if clones := dat.Clones("mslug"); !reflect.DeepEqual(clones, []string{"mslugj"}) {
+		t.Errorf("clones %v", clones)
+	}
+
+	// mame -listxml names sets machines and has a build instead of a header
+	name, dat, err = ParseDAT(strings.NewReader(`<mame build="0.262 (mame0262)"><machine name="pacman" cloneof="puckman"/><machine name="puckman"/></mame>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "MAME" || dat.Version != "0.262 (mame0262)" || dat.Sets["pacman"].Parent != "puckman" {
+		t.Errorf("mame -listxml read as %q %q %+v", name, dat.Version, dat.Sets)
+	}
+
+	for _, bad := range []string{
+		"not xml",
+		`<datafile><game name="x"/></datafile>`,
+		`<datafile><header><name>x</name></header><game/></datafile>`,
+		`<datafile><header><name>x</name></header><game name="x"><rom name="a" size="big"/></game></datafile>`,
+	} {
+		if _, _, err := ParseDAT(strings.NewReader(bad)); err == nil {
+			t.Errorf("parsed %q", bad)
+		}
+	}
+}
+
+func TestSetDAT(t *testing.T) {
+	c, _ := Parse([]byte("{}"))
+	if _, err := c.DAT(""); err == nil {
+		t.Error("picked a DAT from an empty catalog")
+	}
+
+	_, dat, err := ParseDAT(strings.NewReader(testDAT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.SetDAT("FBNeo", dat) {
+		t.Error("adding a DAT reported no change")
+	}
+	if c.SetDAT("FBNeo", dat) {
+		t.Error("importing the same DAT again reported a change")
+	}
+	if got, err := c.DAT(""); err != nil || len(got.Sets) != 4 {
+		t.Errorf("only DAT = %v, %v", got, err)
+	}
+
+	c.SetDAT("MAME", &DAT{Sets: map[string]*ROMSet{}})
+	if _, err := c.DAT(""); err == nil || !strings.Contains(err.Error(), "FBNeo, MAME") {
+		t.Errorf("picking one of several DATs: %v", err)
+	}
+	if got, err := c.DAT("MAME"); err != nil || len(got.Sets) != 0 {
+		t.Errorf("DAT by name = %v, %v", got, err)
+	}
+	if _, err := c.DAT("No-Intro"); err == nil {
+		t.Error("found a DAT that wasn't imported")
+	}
+}
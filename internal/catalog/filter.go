@@ -0,0 +1,74 @@
+package catalog
+
+import "fmt"
+
+// Filter selects games by catalog tags and collection membership.
+// The zero Filter matches every game.
+type Filter struct {
+	Tags        []string // Game must have all of these tags
+	ExcludeTags []string // Game must have none of these tags
+	Collection  string   // Game must be in this collection, if set
+
+	// ExcludePrerelease skips games carrying one of the PrereleaseTags
+	ExcludePrerelease bool
+}
+
+// PrereleaseTags are the tags scan gives demo and beta builds
+var PrereleaseTags = []string{"demo", "beta"}
+
+// IsEmpty reports whether the filter matches every game
+func (f Filter) IsEmpty() bool {
+	return len(f.Tags) == 0 && len(f.ExcludeTags) == 0 && f.Collection == "" && !f.ExcludePrerelease
+}
+
+// Validate normalizes the filter's tags and checks that its collection exists
+func (f *Filter) Validate(c *Catalog) error {
+	for i, tag := range f.Tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		f.Tags[i] = normalized
+	}
+	for i, tag := range f.ExcludeTags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		f.ExcludeTags[i] = normalized
+	}
+	if f.Collection != "" {
+		if _, err := c.Collection(f.Collection); err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the game with the given ID passes the filter
+func (f Filter) Match(c *Catalog, gameID string) bool {
+	for _, tag := range f.Tags {
+		if !c.HasTag(gameID, tag) {
+			return false
+		}
+	}
+	for _, tag := range f.ExcludeTags {
+		if c.HasTag(gameID, tag) {
+			return false
+		}
+	}
+	if f.ExcludePrerelease {
+		for _, tag := range PrereleaseTags {
+			if c.HasTag(gameID, tag) {
+				return false
+			}
+		}
+	}
+	if f.Collection != "" {
+		coll, ok := c.Collections[f.Collection]
+		if !ok || !contains(coll.Games, gameID) {
+			return false
+		}
+	}
+	return true
+}
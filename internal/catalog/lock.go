@@ -0,0 +1,102 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// LockTimeout is how long opening or saving the catalog waits for another process
+// (a daemon, the server or another command) to finish with it
+var LockTimeout = 30 * time.Second
+
+// ErrLocked means another process kept the catalog locked for longer than LockTimeout
+var ErrLocked = errors.New("catalog is locked by another rom-organizer process")
+
+// lockPath is the file locked while the catalog at path is read or written. The catalog
+// itself is replaced on every save, so it can't carry the lock.
+func lockPath(path string) string {
+	return path + ".lock"
+}
+
+// acquire takes the catalog's advisory lock, shared for reading or exclusive for writing,
+// and returns the function releasing it
+func acquire(path string, exclusive bool) (func(), error) {
+	release, err := common.LockFile(lockPath(path), exclusive, LockTimeout)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !exclusive:
+		// Nothing to lock against before the catalog's folder exists
+		return func() {}, nil
+	case errors.Is(err, common.ErrLockTimeout):
+		return nil, fmt.Errorf("%w (waited %s for %s)", ErrLocked, LockTimeout, lockPath(path))
+	case err != nil:
+		return nil, fmt.Errorf("locking catalog: %w", err)
+	}
+	return release, nil
+}
+
+// rebase carries the changes made to c since it was loaded over to theirs, the catalog
+// another process saved in the meantime, and makes the result c's content. Changes are
+// whole records: a game, collection, disc key, scan, content record or DAT changed on both
+// sides ends up as c has it. Runs recorded here are renumbered after theirs.
+func (c *Catalog) rebase(theirs *Catalog) {
+	base := c.base
+	if base == nil {
+		base, _ = Parse([]byte("{}"))
+	}
+	c.Games = rebaseMap(base.Games, c.Games, theirs.Games)
+	c.Collections = rebaseMap(base.Collections, c.Collections, theirs.Collections)
+	c.DiscKeys = rebaseMap(base.DiscKeys, c.DiscKeys, theirs.DiscKeys)
+	c.Scans = rebaseMap(base.Scans, c.Scans, theirs.Scans)
+	c.Contents = rebaseMap(base.Contents, c.Contents, theirs.Contents)
+	c.DATs = rebaseMap(base.DATs, c.DATs, theirs.DATs)
+	c.Runs = rebaseRuns(base.Runs, c.Runs, theirs.Runs)
+}
+
+// rebaseMap applies the records added, changed and removed between base and ours to theirs
+func rebaseMap[V any](base, ours, theirs map[string]V) map[string]V {
+	for key, value := range ours {
+		if old, ok := base[key]; !ok || hashOf(old) != hashOf(value) {
+			theirs[key] = value
+		}
+	}
+	for key := range base {
+		if _, ok := ours[key]; !ok {
+			delete(theirs, key)
+		}
+	}
+	return theirs
+}
+
+// rebaseRuns adds the runs recorded since base to theirs under new IDs, and applies
+// changes to runs already recorded (such as undo marking one)
+func rebaseRuns(base, ours, theirs []*RunRecord) []*RunRecord {
+	known := make(map[int]*RunRecord, len(base))
+	for _, run := range base {
+		known[run.ID] = run
+	}
+	index := make(map[int]int, len(theirs))
+	for i, run := range theirs {
+		index[run.ID] = i
+	}
+
+	var added []*RunRecord
+	for _, run := range ours {
+		old, recorded := known[run.ID]
+		if !recorded {
+			added = append(added, run)
+			continue
+		}
+		if i, ok := index[run.ID]; ok && hashOf(old) != hashOf(run) {
+			theirs[i] = run
+		}
+	}
+	merged := &Catalog{Runs: theirs}
+	for _, run := range added {
+		merged.AddRun(run)
+	}
+	return merged.Runs
+}
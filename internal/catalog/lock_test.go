@@ -0,0 +1,238 @@
+//go:build linux || darwin || freebsd || windows
+
+package catalog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shortLockTimeout makes a blocked lock fail fast for the test
+func shortLockTimeout(t *testing.T) {
+	old := LockTimeout
+	LockTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { LockTimeout = old })
+}
+
+func openCatalog(t *testing.T, path string) *Catalog {
+	t.Helper()
+	c, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	const writers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := c.Tag(fmt.Sprintf("BLUS3000%d", i), "", "favorite"); err != nil {
+				errs <- err
+				return
+			}
+			c.AddRun(&RunRecord{Command: "organize", Dir: fmt.Sprint(i)})
+			errs <- c.Save()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every writer's changes are kept, and each run got its own ID
+	c := openCatalog(t, path)
+	if len(c.Games) != writers {
+		t.Errorf("catalog has %d games, want one from each of %d writers", len(c.Games), writers)
+	}
+	if len(c.Runs) != writers {
+		t.Fatalf("catalog has %d runs, want %d", len(c.Runs), writers)
+	}
+	for i, run := range c.Runs {
+		if run.ID != i+1 {
+			t.Errorf("run %d has ID %d, want runs numbered in order", i, run.ID)
+		}
+	}
+}
+
+func TestSaveWaitsForLock(t *testing.T) {
+	shortLockTimeout(t)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	c := openCatalog(t, path)
+	c.SetDiscKey("BLUS30001", "00")
+
+	release, err := acquire(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Save while another writer holds the lock = %v, want ErrLocked", err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Open while another writer holds the lock = %v, want ErrLocked", err)
+	}
+
+	// The save goes through once the other writer is done
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release()
+	}()
+	LockTimeout = 5 * time.Second
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := openCatalog(t, path).DiscKey("BLUS30001"); !ok {
+		t.Error("disc key not saved")
+	}
+}
+
+// TestLockHelperProcess holds the catalog lock for TestStaleLock until it is killed
+func TestLockHelperProcess(t *testing.T) {
+	path := os.Getenv("ROM_ORGANIZER_LOCK_HELPER")
+	if path == "" {
+		return
+	}
+	if _, err := acquire(path, true); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+func TestStaleLock(t *testing.T) {
+	shortLockTimeout(t)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+
+	// A lock file left behind by a process that is gone doesn't hold the catalog
+	if err := os.WriteFile(lockPath(path), []byte("12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := openCatalog(t, path)
+	c.SetDiscKey("BLUS30001", "00")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save with a stale lock file: %v", err)
+	}
+
+	// Nor does the lock of a process that was killed while holding it
+	helper := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	helper.Env = append(os.Environ(), "ROM_ORGANIZER_LOCK_HELPER="+path)
+	stdout, err := helper.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := helper.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer helper.Wait()
+	defer helper.Process.Kill()
+	if line, _ := bufio.NewReader(stdout).ReadString('\n'); line != "locked\n" {
+		t.Fatalf("helper process: %q", line)
+	}
+
+	c.SetDiscKey("BLUS30002", "00")
+	if err := c.Save(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Save while a live process holds the lock = %v, want ErrLocked", err)
+	}
+	if err := helper.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	helper.Wait()
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save after the lock holder was killed: %v", err)
+	}
+	if _, ok := openCatalog(t, path).DiscKey("BLUS30002"); !ok {
+		t.Error("disc key not saved")
+	}
+}
+
+func TestSaveRebasesOnChangedCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	setup := openCatalog(t, path)
+	setup.Tag("BLUS30001", "Shared Game", "rpg")
+	setup.Tag("BLUS30002", "Removed Here", "rpg")
+	setup.CreateCollection("old", "")
+	setup.AddRun(&RunRecord{Command: "organize"})
+	if err := setup.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	ours := openCatalog(t, path)
+	theirs := openCatalog(t, path)
+
+	// Another process changes the catalog and saves first
+	theirs.Tag("BLUS30001", "Shared Game", "theirs")
+	theirs.Tag("BLES00003", "Their Game", "new")
+	theirs.AddRun(&RunRecord{Command: "compress"})
+	if err := theirs.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// This one changes records of its own, one record both changed, and a run
+	ours.Tag("BLUS30001", "Shared Game", "ours")
+	ours.Untag("BLUS30002", "rpg")
+	ours.Tag("BLES00004", "Our Game", "new")
+	ours.DeleteCollection("old")
+	undone := time.Now().UTC()
+	ours.Runs[0].Undone = &undone
+	ours.AddRun(&RunRecord{Command: "decompress"})
+	if err := ours.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := openCatalog(t, path)
+	if _, ok := c.Games["BLES00003"]; !ok {
+		t.Error("lost the game the other process added")
+	}
+	if _, ok := c.Games["BLES00004"]; !ok {
+		t.Error("lost the game added here")
+	}
+	if _, ok := c.Games["BLUS30002"]; ok {
+		t.Error("kept the game removed here")
+	}
+	if tags := c.Tags("BLUS30001"); len(tags) != 2 || !c.HasTag("BLUS30001", "ours") {
+		t.Errorf("game changed on both sides has tags %v, want it as this process has it", tags)
+	}
+	if _, err := c.Collection("old"); err == nil {
+		t.Error("kept the collection deleted here")
+	}
+
+	if len(c.Runs) != 3 {
+		t.Fatalf("catalog has %d runs, want 3", len(c.Runs))
+	}
+	for i, want := range []string{"organize", "compress", "decompress"} {
+		if run := c.Runs[i]; run.ID != i+1 || run.Command != want {
+			t.Errorf("run %d is %d %s, want %d %s", i, run.ID, run.Command, i+1, want)
+		}
+	}
+	if c.Runs[0].Undone == nil {
+		t.Error("lost the undo recorded on a run both processes had")
+	}
+
+	// The saving catalog holds the rebased content too, so its next save changes nothing
+	if len(ours.Runs) != 3 || ours.Runs[2].ID != 3 {
+		t.Errorf("saving catalog has runs %+v after the rebase", ours.Runs)
+	}
+}
@@ -0,0 +1,257 @@
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergePolicy decides which side wins when a game or collection differs between catalogs
+type MergePolicy string
+
+const (
+	PreferNewer    MergePolicy = "newer"    // The most recently modified side wins (default)
+	PreferLocal    MergePolicy = "local"    // The catalog being merged into wins
+	PreferIncoming MergePolicy = "incoming" // The imported catalog wins
+	PreferUnion    MergePolicy = "union"    // Tags and collection members are combined
+)
+
+// MergePolicies lists the valid merge policies
+var MergePolicies = []MergePolicy{PreferNewer, PreferLocal, PreferIncoming, PreferUnion}
+
+// ParseMergePolicy validates a merge policy name
+func ParseMergePolicy(value string) (MergePolicy, error) {
+	for _, policy := range MergePolicies {
+		if string(policy) == value {
+			return policy, nil
+		}
+	}
+	names := make([]string, len(MergePolicies))
+	for i, policy := range MergePolicies {
+		names[i] = string(policy)
+	}
+	return "", fmt.Errorf("invalid merge policy %q (expected %s)", value, strings.Join(names, ", "))
+}
+
+// Conflict is a game, collection or disc key that differs between the merged catalogs
+type Conflict struct {
+	Kind       string // "game", "collection", "disc key", "content" or "DAT"
+	Key        string // Game ID, collection name or title ID
+	Resolution string // e.g. "kept local", "took incoming", "combined"
+}
+
+// MergeResult summarizes a merge
+type MergeResult struct {
+	Added     int // Games, collections, disc keys, content records and DATs only in the incoming catalog
+	Unchanged int // Present in both with identical content
+	Conflicts []Conflict
+}
+
+// Merge combines another catalog into c. Records only in other are added; records in
+// both are compared by content hash and, when they differ, resolved by policy.
+// Deletions are not tracked, so a record removed on one side is restored by a merge.
+func (c *Catalog) Merge(other *Catalog, policy MergePolicy) *MergeResult {
+	result := &MergeResult{}
+
+	for _, gameID := range sortedKeys(other.Games) {
+		incoming := other.Games[gameID]
+		local, ok := c.Games[gameID]
+		switch {
+		case !ok:
+			c.Games[gameID] = incoming
+			result.Added++
+		case hashOf(entryContent(*local)) == hashOf(entryContent(*incoming)):
+			result.Unchanged++
+		default:
+			merged, resolution := mergeEntry(local, incoming, policy)
+			c.Games[gameID] = merged
+			result.Conflicts = append(result.Conflicts, Conflict{Kind: "game", Key: gameID, Resolution: resolution})
+		}
+	}
+
+	for _, name := range sortedKeys(other.Collections) {
+		incoming := other.Collections[name]
+		local, ok := c.Collections[name]
+		switch {
+		case !ok:
+			c.Collections[name] = incoming
+			result.Added++
+		case hashOf(collectionContent(*local)) == hashOf(collectionContent(*incoming)):
+			result.Unchanged++
+		default:
+			merged, resolution := mergeCollection(local, incoming, policy)
+			c.Collections[name] = merged
+			result.Conflicts = append(result.Conflicts, Conflict{Kind: "collection", Key: name, Resolution: resolution})
+		}
+	}
+
+	for _, titleID := range sortedKeys(other.DiscKeys) {
+		incoming := other.DiscKeys[titleID]
+		local, ok := c.DiscKeys[titleID]
+		switch {
+		case !ok:
+			c.DiscKeys[titleID] = incoming
+			result.Added++
+		case local == incoming:
+			result.Unchanged++
+		default:
+			// Disc keys carry no timestamp, so only an explicit preference replaces one
+			resolution := "kept local"
+			if policy == PreferIncoming {
+				c.DiscKeys[titleID] = incoming
+				resolution = "took incoming"
+			}
+			result.Conflicts = append(result.Conflicts, Conflict{Kind: "disc key", Key: titleID, Resolution: resolution})
+		}
+	}
+
+	for _, contentID := range sortedKeys(other.Contents) {
+		incoming := other.Contents[contentID]
+		local, ok := c.Contents[contentID]
+		switch {
+		case !ok:
+			c.Contents[contentID] = incoming
+			result.Added++
+		case hashOf(contentRecordContent(*local)) == hashOf(contentRecordContent(*incoming)):
+			result.Unchanged++
+		default:
+			merged, resolution := pick(local, incoming, local.Modified, incoming.Modified, policy)
+			c.Contents[contentID] = merged
+			result.Conflicts = append(result.Conflicts, Conflict{Kind: "content", Key: contentID, Resolution: resolution})
+		}
+	}
+
+	for _, name := range sortedKeys(other.DATs) {
+		incoming := other.DATs[name]
+		local, ok := c.DATs[name]
+		switch {
+		case !ok:
+			c.DATs[name] = incoming
+			result.Added++
+		case hashOf(datContent(*local)) == hashOf(datContent(*incoming)):
+			result.Unchanged++
+		default:
+			merged, resolution := pick(local, incoming, local.Modified, incoming.Modified, policy)
+			c.DATs[name] = merged
+			result.Conflicts = append(result.Conflicts, Conflict{Kind: "DAT", Key: name, Resolution: resolution})
+		}
+	}
+
+	return result
+}
+
+// mergeEntry resolves two differing versions of a game entry
+func mergeEntry(local, incoming *Entry, policy MergePolicy) (*Entry, string) {
+	if policy == PreferUnion {
+		merged := *newer(local, incoming, local.Modified, incoming.Modified)
+		merged.Tags = nil
+		for _, tag := range append(append([]string{}, local.Tags...), incoming.Tags...) {
+			if !contains(merged.Tags, tag) {
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+		sort.Strings(merged.Tags)
+		if local.Compat != nil && (incoming.Compat == nil || local.Compat.CheckedAt.After(incoming.Compat.CheckedAt)) {
+			merged.Compat = local.Compat
+		} else {
+			merged.Compat = incoming.Compat
+		}
+		if merged.Compression == nil {
+			// Keep a compression override set on either side
+			merged.Compression = local.Compression
+			if merged.Compression == nil {
+				merged.Compression = incoming.Compression
+			}
+		}
+		merged.Modified = latest(local.Modified, incoming.Modified)
+		return &merged, "combined"
+	}
+	return pick(local, incoming, local.Modified, incoming.Modified, policy)
+}
+
+// mergeCollection resolves two differing versions of a collection
+func mergeCollection(local, incoming *Collection, policy MergePolicy) (*Collection, string) {
+	if policy == PreferUnion {
+		merged := *newer(local, incoming, local.Modified, incoming.Modified)
+		merged.Games = nil
+		for _, gameID := range append(append([]string{}, local.Games...), incoming.Games...) {
+			if !contains(merged.Games, gameID) {
+				merged.Games = append(merged.Games, gameID)
+			}
+		}
+		sort.Strings(merged.Games)
+		merged.Modified = latest(local.Modified, incoming.Modified)
+		return &merged, "combined"
+	}
+	return pick(local, incoming, local.Modified, incoming.Modified, policy)
+}
+
+// pick chooses one side by policy; ties between modification times keep the local side
+func pick[T any](local, incoming *T, localModified, incomingModified time.Time, policy MergePolicy) (*T, string) {
+	switch policy {
+	case PreferLocal:
+		return local, "kept local"
+	case PreferIncoming:
+		return incoming, "took incoming"
+	}
+	switch {
+	case incomingModified.After(localModified):
+		return incoming, "took incoming (newer)"
+	case localModified.After(incomingModified):
+		return local, "kept local (newer)"
+	}
+	return local, "kept local"
+}
+
+func newer[T any](local, incoming *T, localModified, incomingModified time.Time) *T {
+	if incomingModified.After(localModified) {
+		return incoming
+	}
+	return local
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// entryContent, collectionContent, contentRecordContent and datContent drop the modification time,
+// so records that were changed to the same content on both machines hash the same
+func entryContent(e Entry) Entry {
+	e.Modified = time.Time{}
+	return e
+}
+
+func collectionContent(coll Collection) Collection {
+	coll.Modified = time.Time{}
+	return coll
+}
+
+func contentRecordContent(record ContentRecord) ContentRecord {
+	record.Modified = time.Time{}
+	return record
+}
+
+func datContent(dat DAT) DAT {
+	dat.Modified = time.Time{}
+	return dat
+}
+
+func hashOf(v any) [sha256.Size]byte {
+	data, _ := json.Marshal(v)
+	return sha256.Sum256(data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,160 @@
+package catalog
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	earlier = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later   = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c, err := Parse([]byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// mergeCatalogs returns a local and an incoming catalog that share some records,
+// differ on others, and each have records of their own
+func mergeCatalogs(t *testing.T) (local, incoming *Catalog) {
+	local, incoming = newCatalog(t), newCatalog(t)
+
+	// Changed last on the incoming side
+	local.Games["BLUS30001"] = &Entry{Title: "Game One", Tags: []string{"rpg"}, Modified: earlier}
+	incoming.Games["BLUS30001"] = &Entry{Title: "Game One", Tags: []string{"favorite"}, Modified: later}
+	// The same content, tagged at different times
+	local.Games["BLUS30002"] = &Entry{Title: "Game Two", Tags: []string{"action"}, Modified: later}
+	incoming.Games["BLUS30002"] = &Entry{Title: "Game Two", Tags: []string{"action"}, Modified: earlier}
+	// Changed last on the local side
+	local.Games["BLUS30003"] = &Entry{Title: "Game Three", Tags: []string{"local"}, Modified: later}
+	incoming.Games["BLUS30003"] = &Entry{Title: "Game Three", Tags: []string{"incoming"}, Modified: earlier}
+	incoming.Games["BLES00004"] = &Entry{Title: "Game Four", Tags: []string{"new"}, Modified: earlier}
+
+	local.Collections["best"] = &Collection{Games: []string{"BLUS30001"}, Modified: earlier}
+	incoming.Collections["best"] = &Collection{Games: []string{"BLUS30003"}, Modified: later}
+	incoming.Collections["recent"] = &Collection{Games: []string{"BLES00004"}, Modified: earlier}
+
+	local.DiscKeys["BLUS30001"] = "00"
+	incoming.DiscKeys["BLUS30001"] = "11"
+	incoming.DiscKeys["BLUS30003"] = "33"
+	return local, incoming
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		policy      MergePolicy
+		tags1       []string // Tags of BLUS30001 after the merge
+		tags3       []string // Tags of BLUS30003
+		best        []string // Games in the best collection
+		discKey     string   // Disc key of BLUS30001
+		resolutions []string // Of the game, game, collection and disc key conflicts
+	}{
+		{
+			policy: PreferNewer, tags1: []string{"favorite"}, tags3: []string{"local"}, best: []string{"BLUS30003"}, discKey: "00",
+			resolutions: []string{"took incoming (newer)", "kept local (newer)", "took incoming (newer)", "kept local"},
+		},
+		{
+			policy: PreferLocal, tags1: []string{"rpg"}, tags3: []string{"local"}, best: []string{"BLUS30001"}, discKey: "00",
+			resolutions: []string{"kept local", "kept local", "kept local", "kept local"},
+		},
+		{
+			policy: PreferIncoming, tags1: []string{"favorite"}, tags3: []string{"incoming"}, best: []string{"BLUS30003"}, discKey: "11",
+			resolutions: []string{"took incoming", "took incoming", "took incoming", "took incoming"},
+		},
+		{
+			policy: PreferUnion, tags1: []string{"favorite", "rpg"}, tags3: []string{"incoming", "local"}, best: []string{"BLUS30001", "BLUS30003"}, discKey: "00",
+			resolutions: []string{"combined", "combined", "combined", "kept local"},
+		},
+	}
+	for _, tt := range tests {
+		local, incoming := mergeCatalogs(t)
+		result := local.Merge(incoming, tt.policy)
+
+		if result.Added != 3 || result.Unchanged != 1 {
+			t.Errorf("%s: %d added and %d unchanged, want 3 and 1", tt.policy, result.Added, result.Unchanged)
+		}
+		var resolutions []string
+		for _, conflict := range result.Conflicts {
+			resolutions = append(resolutions, conflict.Resolution)
+		}
+		if !reflect.DeepEqual(resolutions, tt.resolutions) {
+			t.Errorf("%s: conflicts resolved as %v, want %v", tt.policy, resolutions, tt.resolutions)
+		}
+
+		if tags := local.Tags("BLUS30001"); !reflect.DeepEqual(tags, tt.tags1) {
+			t.Errorf("%s: BLUS30001 tagged %v, want %v", tt.policy, tags, tt.tags1)
+		}
+		if tags := local.Tags("BLUS30003"); !reflect.DeepEqual(tags, tt.tags3) {
+			t.Errorf("%s: BLUS30003 tagged %v, want %v", tt.policy, tags, tt.tags3)
+		}
+		if games := local.Collections["best"].Games; !reflect.DeepEqual(games, tt.best) {
+			t.Errorf("%s: best collection holds %v, want %v", tt.policy, games, tt.best)
+		}
+		if key, _ := local.DiscKey("BLUS30001"); key != tt.discKey {
+			t.Errorf("%s: disc key %s, want %s", tt.policy, key, tt.discKey)
+		}
+
+		// Records only in the incoming catalog are added whatever the policy
+		if _, ok := local.Games["BLES00004"]; !ok {
+			t.Errorf("%s: game only in the incoming catalog not added", tt.policy)
+		}
+		if _, ok := local.Collections["recent"]; !ok {
+			t.Errorf("%s: collection only in the incoming catalog not added", tt.policy)
+		}
+		if key, _ := local.DiscKey("BLUS30003"); key != "33" {
+			t.Errorf("%s: disc key only in the incoming catalog not added", tt.policy)
+		}
+	}
+}
+
+func TestMergeUnionKeepsLatestChange(t *testing.T) {
+	local, incoming := mergeCatalogs(t)
+	local.Merge(incoming, PreferUnion)
+	if modified := local.Games["BLUS30001"].Modified; !modified.Equal(later) {
+		t.Errorf("combined game modified %v, want the later side's %v", modified, later)
+	}
+	if modified := local.Collections["best"].Modified; !modified.Equal(later) {
+		t.Errorf("combined collection modified %v, want the later side's %v", modified, later)
+	}
+}
+
+func TestMergeExported(t *testing.T) {
+	local, incoming := mergeCatalogs(t)
+	var buf bytes.Buffer
+	if err := incoming.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An exported catalog merges as the catalog itself does; merging it again only finds
+	// the records the first merge kept local
+	first := local.Merge(imported, PreferNewer)
+	if len(first.Conflicts) != 4 {
+		t.Errorf("merging the exported catalog: %d conflicts, want 4", len(first.Conflicts))
+	}
+	again := local.Merge(imported, PreferIncoming)
+	if again.Added != 0 || len(again.Conflicts) != 2 {
+		t.Errorf("merging again: %d added, conflicts %+v, want only the local game and disc key kept by the first merge", again.Added, again.Conflicts)
+	}
+}
+
+func TestParseMergePolicy(t *testing.T) {
+	for _, policy := range MergePolicies {
+		if got, err := ParseMergePolicy(string(policy)); err != nil || got != policy {
+			t.Errorf("ParseMergePolicy(%q) = %q, %v", policy, got, err)
+		}
+	}
+	if _, err := ParseMergePolicy("theirs"); err == nil {
+		t.Error("parsed an unknown merge policy")
+	}
+}
@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxRuns is how many batch runs the catalog keeps; older runs are dropped
+const MaxRuns = 200
+
+// Game statuses recorded in RunGame.Status; the first three match the organizer's progress events
+const (
+	RunGameSuccess = "success"
+	RunGameFailed  = "failed"
+	RunGameSkipped = "skipped" // Left out by the collision policy
+	RunGameAborted = "aborted" // Not attempted because the batch was aborted
+)
+
+// RunRecord is the summary of one compress, decompress or organize batch
+type RunRecord struct {
+	ID       int        `json:"id"`
+	Command  string     `json:"command"`         // compress, decompress or organize
+	Dir      string     `json:"dir"`             // Working directory, which relative paths are relative to
+	Flags    []string   `json:"flags,omitempty"` // Command flags as given, e.g. "--output=/mnt/nas"
+	Started  time.Time  `json:"started"`
+	Finished time.Time  `json:"finished"`
+	Games    []RunGame  `json:"games"`
+	Error    string     `json:"error,omitempty"`  // Error that ended the batch, if any
+	Undone   *time.Time `json:"undone,omitempty"` // When undo removed the run's outputs
+}
+
+// RunGame is what happened to one source in a batch run
+type RunGame struct {
+	Source   string        `json:"source"`
+	Target   string        `json:"target,omitempty"`
+	GameID   string        `json:"game_id,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Status   string        `json:"status"` // success, failed, skipped or aborted
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+
+	// Unverified is set when the game's title and ID weren't read from its metadata
+	Unverified bool `json:"unverified,omitempty"`
+
+	// InPlace is set when an already organized game was handled where it is; the run
+	// created no folder for it
+	InPlace bool `json:"in_place,omitempty"`
+
+	// Mirrors are the mirror copies the run made of the game
+	Mirrors []string `json:"mirrors,omitempty"`
+
+	// Moved are the source paths --move renamed into the game or the trash, in order;
+	// undo moves them back in reverse
+	Moved []MovedPath `json:"moved,omitempty"`
+}
+
+// MovedPath is a source path a run renamed from From to To
+type MovedPath struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Duration returns how long the run took
+func (r *RunRecord) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+// Count returns the number of games in the run with the given status
+func (r *RunRecord) Count(status string) int {
+	n := 0
+	for _, game := range r.Games {
+		if game.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// FailedSources returns the sources that failed or were never attempted because the
+// batch was aborted, in batch order
+func (r *RunRecord) FailedSources() []string {
+	var sources []string
+	for _, game := range r.Games {
+		if game.Status == RunGameFailed || game.Status == RunGameAborted {
+			sources = append(sources, game.Source)
+		}
+	}
+	return sources
+}
+
+// AddRun records a batch run under the next run ID, dropping the oldest runs beyond MaxRuns
+func (c *Catalog) AddRun(run *RunRecord) {
+	run.ID = 1
+	if len(c.Runs) > 0 {
+		run.ID = c.Runs[len(c.Runs)-1].ID + 1
+	}
+	c.Runs = append(c.Runs, run)
+	if len(c.Runs) > MaxRuns {
+		c.Runs = c.Runs[len(c.Runs)-MaxRuns:]
+	}
+}
+
+// Run returns the recorded run with the given ID
+func (c *Catalog) Run(id int) (*RunRecord, error) {
+	for _, run := range c.Runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("no run %d in the history", id)
+}
+
+// LastRun returns the most recent run, or nil if none was recorded
+func (c *Catalog) LastRun() *RunRecord {
+	if len(c.Runs) == 0 {
+		return nil
+	}
+	return c.Runs[len(c.Runs)-1]
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ArchiveOptions controls how game.7z archives are created
+type ArchiveOptions struct {
+	Level           int      // 7z compression level, 0 (store only) to 9 (maximum)
+	StoreExtensions []string // Files with these extensions (e.g. ".pkg") are stored without compression
+	ExtraArgs       []string // Additional 7z switches for every pass, stored files too (e.g. "-mqs=on")
+
+	// Reproducible makes archives of the same files byte-identical: entries are added in
+	// sorted order, no timestamps are stored and 7z runs single-threaded
+	Reproducible bool
+
+	// SolidBlock is the 7z solid block size: "on" (the whole archive is one block),
+	// "off", or a size such as "64m". Smaller blocks make extracting single files
+	// faster at some cost in ratio. Empty means "on".
+	SolidBlock string
+
+	// Dictionary is the LZMA dictionary size such as "32m" (empty uses 32m at level 9
+	// and 7z's default for the level otherwise)
+	Dictionary string
+}
+
+// ArchiveTuning is a named solid block and dictionary combination
+type ArchiveTuning struct {
+	SolidBlock string
+	Dictionary string
+	Summary    string
+}
+
+// ArchiveTunings are the presets accepted by --tuning and compression.*.tuning
+var ArchiveTunings = map[string]ArchiveTuning{
+	"max":         {SolidBlock: "on", Summary: "one solid block, best ratio, slowest single-file extraction (default)"},
+	"balanced":    {SolidBlock: "64m", Dictionary: "16m", Summary: "64 MB solid blocks, 16 MB dictionary"},
+	"fast-access": {SolidBlock: "off", Dictionary: "16m", Summary: "no solid blocks, any file extracts without decoding others"},
+}
+
+// ArchiveTuningNames returns the preset names, sorted
+func ArchiveTuningNames() []string {
+	names := make([]string, 0, len(ArchiveTunings))
+	for name := range ArchiveTunings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyTuning sets the solid block and dictionary size of a preset
+func (o *ArchiveOptions) ApplyTuning(name string) error {
+	tuning, ok := ArchiveTunings[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown tuning %q (expected %s)", name, strings.Join(ArchiveTuningNames(), ", "))
+	}
+	o.SolidBlock = tuning.SolidBlock
+	o.Dictionary = tuning.Dictionary
+	return nil
+}
+
+// sevenZipSize matches 7z size values: a number with an optional b, k, m or g
+// suffix (a bare number n means 2^n bytes for the dictionary)
+var sevenZipSize = regexp.MustCompile(`(?i)^\d+[bkmg]?$`)
+
+// DefaultArchiveOptions returns the maximum compression settings used when nothing is configured
+func DefaultArchiveOptions() ArchiveOptions {
+	return ArchiveOptions{Level: 9}
+}
+
+// Validate checks the compression level and extension list
+func (o ArchiveOptions) Validate() error {
+	if o.Level < 0 || o.Level > 9 {
+		return fmt.Errorf("compression level must be between 0 and 9, got %d", o.Level)
+	}
+	for _, ext := range o.StoreExtensions {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 || strings.ContainsAny(ext, `/\*?`) {
+			return fmt.Errorf("invalid store extension %q (expected e.g. \".pkg\")", ext)
+		}
+	}
+	if o.SolidBlock != "" && o.SolidBlock != "on" && o.SolidBlock != "off" && !sevenZipSize.MatchString(o.SolidBlock) {
+		return fmt.Errorf("invalid solid block size %q (expected on, off or a size such as 64m)", o.SolidBlock)
+	}
+	if o.Dictionary != "" && !sevenZipSize.MatchString(o.Dictionary) {
+		return fmt.Errorf("invalid dictionary size %q (expected a size such as 16m)", o.Dictionary)
+	}
+	for _, arg := range o.ExtraArgs {
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("invalid 7z argument %q (only switches such as \"-mqs=on\" are allowed)", arg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Version is the rom-organizer release, reported by --version and recorded in archives
+const Version = "1.0.0"
+
+// ArchiveMetadataName is the entry at the root of game.7z holding its ArchiveMetadata.
+// The 7z command line can't write the format's archive comment, so the record is a
+// small stored file instead; listings and extraction leave it out.
+const ArchiveMetadataName = ".rom-organizer.json"
+
+// excludeMetadataArg leaves the metadata record out when 7z extracts an archive
+const excludeMetadataArg = "-x!" + ArchiveMetadataName
+
+// ArchiveMetadata identifies the game in an archive without its organized folder
+type ArchiveMetadata struct {
+	Title      string `json:"title"`
+	GameID     string `json:"game_id"`
+	Console    string `json:"console,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+	Organizer  string `json:"organizer"`   // rom-organizer version that created the archive
+	SourceHash string `json:"source_hash"` // ListingHash of the archived files
+}
+
+// ListingHash returns "sha256:<hex>" of the sorted paths, sizes and CRC32s of the files
+// in an archive listing, as 7z recorded them while reading the source. Archives of the
+// same game folder have the same hash whatever their compression settings.
+func ListingHash(entries []ArchiveEntry) string {
+	var lines []string
+	for _, entry := range entries {
+		if !entry.IsDir {
+			lines = append(lines, fmt.Sprintf("%s\x00%d\x00%s\n", entry.Path, entry.Size, entry.CRC))
+		}
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil))
+}
+
+// WriteArchiveMetadata adds meta to an existing 7z archive, replacing any earlier record
+func WriteArchiveMetadata(archivePath string, meta ArchiveMetadata) error {
+	cmd, err := find7zCommand()
+	if err != nil {
+		return err
+	}
+	absArchivePath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return fmt.Errorf("getting absolute path for archive: %w", err)
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding archive metadata: %w", err)
+	}
+	dir, err := os.MkdirTemp("", ".rom-organizer-meta-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, ArchiveMetadataName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing archive metadata: %w", err)
+	}
+
+	// Stored without times, so reproducible archives stay byte-identical
+	args := append([]string{"a", "-t7z", "-mx=0"}, reproducibleArgs...)
+	return run7z(cmd, append(args, absArchivePath, ArchiveMetadataName), dir, nil)
+}
+
+// ReadArchiveMetadata returns the record embedded in a 7z archive, or nil when the
+// archive has none (it was made before records were written, or by another tool)
+func ReadArchiveMetadata(archivePath string) (*ArchiveMetadata, error) {
+	entries, err := list7zEntries(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, entry := range entries {
+		found = found || entry.Path == ArchiveMetadataName
+	}
+	if !found {
+		return nil, nil
+	}
+
+	data, err := Read7zFile(archivePath, ArchiveMetadataName)
+	if err != nil {
+		return nil, err
+	}
+	var meta ArchiveMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("parsing metadata of %s: %w", archivePath, err)
+	}
+	return &meta, nil
+}
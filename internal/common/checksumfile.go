@@ -0,0 +1,144 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ChecksumEntry is a file listed in a checksum file (.sfv or .md5) with its digest
+type ChecksumEntry struct {
+	Name   string // Relative to the folder of the checksum file, slash-separated
+	Digest string // Lower-case hex
+}
+
+// Lines of checksum files: "name CRC32" in .sfv files (names may contain spaces), and
+// "digest  name", "digest *name" (md5sum) or "MD5 (name) = digest" (BSD md5) in .md5 files
+var (
+	sfvLine    = regexp.MustCompile(`^(.+?)\s+([0-9A-Fa-f]{8})$`)
+	md5Line    = regexp.MustCompile(`^([0-9A-Fa-f]{32}) [ *](.+)$`)
+	md5BSDLine = regexp.MustCompile(`^MD5 \((.+)\) = ([0-9A-Fa-f]{32})$`)
+)
+
+// checksumFileAlgorithms are the checksum file extensions verified on intake
+var checksumFileAlgorithms = map[string]HashAlgorithm{".sfv": HashCRC32, ".md5": HashMD5}
+
+// IsChecksumFile reports whether a file name is an .sfv or .md5 checksum file
+func IsChecksumFile(name string) bool {
+	_, ok := checksumFileAlgorithms[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+// ReadChecksumFile parses an .sfv or .md5 file. Lines starting with ; or # are comments.
+func ReadChecksumFile(path string) ([]ChecksumEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sfv := strings.EqualFold(filepath.Ext(path), ".sfv")
+	var entries []ChecksumEntry
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var name, digest string
+		if match := sfvLine.FindStringSubmatch(line); sfv && match != nil {
+			name, digest = match[1], match[2]
+		} else if match := md5Line.FindStringSubmatch(line); !sfv && match != nil {
+			name, digest = match[2], match[1]
+		} else if match := md5BSDLine.FindStringSubmatch(line); !sfv && match != nil {
+			name, digest = match[1], match[2]
+		} else {
+			return nil, fmt.Errorf("%s line %d: not a file name and checksum", filepath.Base(path), lineNo)
+		}
+		entries = append(entries, ChecksumEntry{
+			Name:   strings.TrimPrefix(strings.ReplaceAll(name, `\`, "/"), "./"),
+			Digest: strings.ToLower(digest),
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// ChecksumMismatch is a file that doesn't match the checksum listed for it
+type ChecksumMismatch struct {
+	Path     string
+	Expected string
+	Actual   string // Empty when the file is missing
+}
+
+func (m ChecksumMismatch) String() string {
+	if m.Actual == "" {
+		return fmt.Sprintf("%s: missing", m.Path)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", m.Path, m.Expected, m.Actual)
+}
+
+// VerifyChecksumFile checks the files listed in an .sfv or .md5 file, which are looked
+// for next to it (ignoring case when the exact name isn't there, as files made on
+// Windows may differ). It returns those that are missing or don't match, and how many
+// files are listed.
+func VerifyChecksumFile(path string) ([]ChecksumMismatch, int, error) {
+	alg, ok := checksumFileAlgorithms[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, 0, fmt.Errorf("%s is not an .sfv or .md5 file", path)
+	}
+	entries, err := ReadChecksumFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	dir := filepath.Dir(path)
+	var mismatches []ChecksumMismatch
+	for _, entry := range entries {
+		file := findFileFold(dir, entry.Name)
+		if file == "" {
+			mismatches = append(mismatches, ChecksumMismatch{Path: filepath.Join(dir, filepath.FromSlash(entry.Name)), Expected: entry.Digest})
+			continue
+		}
+		digest, err := HashFile(file, alg)
+		if err != nil {
+			return nil, 0, err
+		}
+		if digest != entry.Digest {
+			mismatches = append(mismatches, ChecksumMismatch{Path: file, Expected: entry.Digest, Actual: digest})
+		}
+	}
+	return mismatches, len(entries), nil
+}
+
+// findFileFold returns the file at the slash-separated path below dir, matching each
+// element without regard to case if it doesn't exist as written, or "" if there is none
+func findFileFold(dir, name string) string {
+	exact := filepath.Join(dir, filepath.FromSlash(name))
+	if info, err := os.Stat(exact); err == nil && info.Mode().IsRegular() {
+		return exact
+	}
+	path := dir
+	for _, part := range strings.Split(name, "/") {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return ""
+		}
+		found := ""
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), part) {
+				found = entry.Name()
+				break
+			}
+		}
+		if found == "" {
+			return ""
+		}
+		path = filepath.Join(path, found)
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	return path
+}
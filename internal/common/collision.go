@@ -0,0 +1,33 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CollisionPolicy is what happens when a different game already uses the folder name
+// a game would be organized into
+type CollisionPolicy string
+
+const (
+	CollisionFail      CollisionPolicy = "fail"      // Refuse the game before anything is written
+	CollisionSuffix    CollisionPolicy = "suffix"    // Use the first free "Title (2) [ID]", "Title (3) [ID]", ...
+	CollisionSkip      CollisionPolicy = "skip"      // Leave the game out of the batch with a warning
+	CollisionOverwrite CollisionPolicy = "overwrite" // Treat it as the same game (the behavior before collisions were checked)
+)
+
+// CollisionPolicies lists the valid policies, the default first
+var CollisionPolicies = []CollisionPolicy{CollisionFail, CollisionSuffix, CollisionSkip, CollisionOverwrite}
+
+// ParseCollisionPolicy validates a policy name ("" is the default, fail)
+func ParseCollisionPolicy(name string) (CollisionPolicy, error) {
+	if name == "" {
+		return CollisionFail, nil
+	}
+	for _, policy := range CollisionPolicies {
+		if strings.EqualFold(name, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown collision policy %q (use fail, suffix, skip or overwrite)", name)
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Optional folders of a PS3 disc, beside PS3_GAME
+const (
+	ComponentExtra  = "PS3_EXTRA"  // Bonus content: videos, themes, demo packages
+	ComponentUpdate = "PS3_UPDATE" // System software update (PS3UPDAT.PUP) shipped on the disc
+)
+
+// DiscComponents lists the optional disc folders policies apply to
+var DiscComponents = []string{ComponentExtra, ComponentUpdate}
+
+// ComponentPolicy is what happens to an optional disc folder when a game is organized
+type ComponentPolicy string
+
+const (
+	ComponentInclude  ComponentPolicy = "include"  // Kept in game/ or game.7z with the rest of the disc
+	ComponentExclude  ComponentPolicy = "exclude"  // Left out of the organized game
+	ComponentSeparate ComponentPolicy = "separate" // Kept in the disc folder of the layout, as NAME.7z or NAME/
+)
+
+// ComponentPolicies lists the valid policies, the default first
+var ComponentPolicies = []ComponentPolicy{ComponentInclude, ComponentExclude, ComponentSeparate}
+
+// ParseComponentPolicy validates a policy name ("" is the default, include)
+func ParseComponentPolicy(name string) (ComponentPolicy, error) {
+	if name == "" {
+		return ComponentInclude, nil
+	}
+	for _, policy := range ComponentPolicies {
+		if strings.EqualFold(name, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown component policy %q (use include, exclude or separate)", name)
+}
+
+// ParseComponentName returns the disc folder a name refers to: PS3_EXTRA or PS3_UPDATE,
+// in any case, or just "extra" or "update"
+func ParseComponentName(name string) (string, error) {
+	for _, component := range DiscComponents {
+		if strings.EqualFold(name, component) || strings.EqualFold("PS3_"+name, component) {
+			return component, nil
+		}
+	}
+	return "", fmt.Errorf("unknown disc component %q (use %s)", name, strings.Join(DiscComponents, " or "))
+}
+
+// ComponentPolicyMap holds the policy of each disc component that doesn't use the default
+type ComponentPolicyMap map[string]ComponentPolicy
+
+// ParseComponentPolicies parses NAME=POLICY values such as "PS3_UPDATE=exclude" and
+// "extra=separate" on top of base. Later values replace earlier ones.
+func ParseComponentPolicies(base ComponentPolicyMap, specs []string) (ComponentPolicyMap, error) {
+	policies := make(ComponentPolicyMap)
+	for name, policy := range base {
+		policies[name] = policy
+	}
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid component policy %q (use NAME=include, NAME=exclude or NAME=separate)", spec)
+		}
+		component, err := ParseComponentName(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		policy, err := ParseComponentPolicy(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		policies[component] = policy
+	}
+	return policies, nil
+}
+
+// Policy returns the policy for a disc component
+func (m ComponentPolicyMap) Policy(component string) ComponentPolicy {
+	if policy, ok := m[component]; ok {
+		return policy
+	}
+	return ComponentInclude
+}
+
+// DiscMetadataName is the file in an organized game directory recording what happened
+// to the optional folders of its disc, written when any of them isn't kept in the game
+const DiscMetadataName = "metadata.json"
+
+// DiscMetadata records how the disc of an organized game was split up, so it can be
+// put back together
+type DiscMetadata struct {
+	GameID     string          `json:"game_id"`
+	Components []DiscComponent `json:"components"`
+}
+
+// DiscComponent is one optional disc folder and where it went
+type DiscComponent struct {
+	Name   string          `json:"name"` // PS3_EXTRA or PS3_UPDATE
+	Policy ComponentPolicy `json:"policy"`
+	Path   string          `json:"path,omitempty"` // Relative to the organized directory; empty when excluded
+	Files  int             `json:"files"`
+	Size   int64           `json:"size"` // Total size of the files on the disc
+}
+
+// WriteDiscMetadata writes metadata.json into an organized game directory
+func WriteDiscMetadata(targetPath string, meta DiscMetadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", DiscMetadataName, err)
+	}
+	if err := os.WriteFile(filepath.Join(targetPath, DiscMetadataName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", DiscMetadataName, err)
+	}
+	return nil
+}
+
+// ReadDiscMetadata returns the metadata.json of an organized game directory, or nil
+// when it has none (every disc folder is in the game)
+func ReadDiscMetadata(targetPath string) (*DiscMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(targetPath, DiscMetadataName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta DiscMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(targetPath, DiscMetadataName), err)
+	}
+	return &meta, nil
+}
@@ -0,0 +1,169 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CompressionStats holds the original and compressed size of a game
+type CompressionStats struct {
+	OriginalSize   int64 // Size of the uncompressed game files in bytes
+	CompressedSize int64 // Size of game.7z in bytes
+}
+
+// Ratio returns the compressed size as a fraction of the original size
+func (s CompressionStats) Ratio() float64 {
+	if s.OriginalSize == 0 {
+		return 0
+	}
+	return float64(s.CompressedSize) / float64(s.OriginalSize)
+}
+
+// Saved returns the number of bytes saved by compression (negative if the archive is larger)
+func (s CompressionStats) Saved() int64 {
+	return s.OriginalSize - s.CompressedSize
+}
+
+// Add returns the sum of two stats, for batch and library totals
+func (s CompressionStats) Add(other CompressionStats) CompressionStats {
+	return CompressionStats{
+		OriginalSize:   s.OriginalSize + other.OriginalSize,
+		CompressedSize: s.CompressedSize + other.CompressedSize,
+	}
+}
+
+// String formats the stats as "1.20 GB -> 800.00 MB (66.7%, saved 400.00 MB)"
+func (s CompressionStats) String() string {
+	saved := s.Saved()
+	savedStr := "saved " + FormatSize(saved)
+	if saved < 0 {
+		savedStr = "grew by " + FormatSize(-saved)
+	}
+	return fmt.Sprintf("%s -> %s (%.1f%%, %s)",
+		FormatSize(s.OriginalSize), FormatSize(s.CompressedSize), s.Ratio()*100, savedStr)
+}
+
+// DirSize returns the total size of all regular files under a directory
+func DirSize(path string) (int64, error) {
+	var total int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("measuring size of %s: %w", path, err)
+	}
+	return total, nil
+}
+
+// ArchiveEntry describes one file or directory inside a 7z archive
+type ArchiveEntry struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	PackedSize int64  `json:"packed_size"`
+	Modified   string `json:"modified,omitempty"`
+	CRC        string `json:"crc,omitempty"`
+	IsDir      bool   `json:"is_dir"`
+}
+
+// List7zArchive returns the entries of a 7z archive without extracting it, leaving out
+// the embedded ArchiveMetadata record
+func List7zArchive(archivePath string) ([]ArchiveEntry, error) {
+	entries, err := list7zEntries(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.Path != ArchiveMetadataName {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
+
+// list7zEntries returns every entry of a 7z archive
+func list7zEntries(archivePath string) ([]ArchiveEntry, error) {
+	cmd, err := find7zCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	// -slt prints a block of "Key = Value" lines per entry after a "----------" line
+	execCmd := exec.Command(cmd, "l", "-slt", archivePath)
+	var stdout, stderr bytes.Buffer
+	execCmd.Stdout = &stdout
+	execCmd.Stderr = &stderr
+
+	if err := execCmd.Run(); err != nil {
+		return nil, fmt.Errorf("listing %s: %w: %s", archivePath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var entries []ArchiveEntry
+	var current *ArchiveEntry
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "----------" {
+			// Anything before the last separator describes the archive itself
+			entries, current = nil, nil
+			continue
+		}
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			if line == "" {
+				current = nil
+			}
+			continue
+		}
+		if key == "Path" {
+			entries = append(entries, ArchiveEntry{Path: filepath.ToSlash(value)})
+			current = &entries[len(entries)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch key {
+		case "Size":
+			current.Size, _ = strconv.ParseInt(value, 10, 64)
+		case "Packed Size":
+			current.PackedSize, _ = strconv.ParseInt(value, 10, 64)
+		case "Modified":
+			current.Modified = value
+		case "CRC":
+			current.CRC = value
+		case "Folder":
+			current.IsDir = current.IsDir || value == "+"
+		case "Attributes":
+			current.IsDir = current.IsDir || strings.HasPrefix(value, "D")
+		}
+	}
+
+	return entries, nil
+}
+
+// Archive7zContentSize returns the total uncompressed size of the files in a 7z archive
+func Archive7zContentSize(archivePath string) (int64, error) {
+	entries, err := List7zArchive(archivePath)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	return total, nil
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// SameDevice reports whether two paths are on the same file system, so a rename
+// between them is instant. Paths that don't exist yet use their nearest existing parent.
+func SameDevice(a, b string) (bool, error) {
+	devA, err := deviceOf(a)
+	if err != nil {
+		return false, err
+	}
+	devB, err := deviceOf(b)
+	if err != nil {
+		return false, err
+	}
+	return devA == devB, nil
+}
+
+func deviceOf(path string) (uint64, error) {
+	info, err := os.Stat(existingParent(path))
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no device information for %s", path)
+	}
+	return uint64(stat.Dev), nil
+}
@@ -0,0 +1,20 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SameDevice reports whether two paths are on the same volume, so a rename
+// between them is instant. Paths that don't exist yet use their nearest existing parent.
+func SameDevice(a, b string) (bool, error) {
+	absA, err := filepath.Abs(existingParent(a))
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(existingParent(b))
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(filepath.VolumeName(absA), filepath.VolumeName(absB)), nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package common
+
+import "errors"
+
+// FreeSpace returns the bytes available on the file system holding path. It is not
+// supported on this platform.
+func FreeSpace(path string) (int64, error) {
+	return 0, errors.New("free space is not available on this platform")
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package common
+
+import "syscall"
+
+// FreeSpace returns the bytes available to the current user on the file system
+// holding path. Paths that don't exist yet use their nearest existing parent.
+func FreeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &stat); err != nil {
+		return 0, err
+	}
+	return int64(uint64(stat.Bavail) * uint64(stat.Bsize)), nil
+}
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// FreeSpace returns the bytes available to the current user on the volume holding
+// path. Paths that don't exist yet use their nearest existing parent.
+func FreeSpace(path string) (int64, error) {
+	dir, err := syscall.UTF16PtrFromString(existingParent(path))
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	r, _, callErr := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(dir)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if r == 0 {
+		return 0, callErr
+	}
+	return int64(available), nil
+}
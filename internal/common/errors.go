@@ -0,0 +1,25 @@
+package common
+
+import "errors"
+
+// Sentinel errors for failure categories that callers (and the CLI exit code) branch on.
+// They are wrapped with %w, so match them with errors.Is.
+var (
+	// ErrNotDetected means no supported game could be identified at a source path
+	ErrNotDetected = errors.New("unable to determine console type")
+
+	// ErrTargetExists means the output directory already exists and --force was not given
+	ErrTargetExists = errors.New("target directory already exists")
+
+	// ErrToolNotFound means a required external tool (7z, par2) is not installed
+	ErrToolNotFound = errors.New("command not found")
+
+	// ErrWrongPassword means an encrypted archive could not be opened with the password given
+	ErrWrongPassword = errors.New("wrong archive password")
+
+	// ErrReadOnly means an operation would modify a library while read-only mode is on
+	ErrReadOnly = errors.New("read-only mode")
+
+	// ErrInterrupted means an operation was stopped by SIGINT or SIGTERM
+	ErrInterrupted = errors.New("interrupted")
+)
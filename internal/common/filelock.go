@@ -0,0 +1,41 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLockTimeout means another process held a lock file for longer than the caller waited
+var ErrLockTimeout = errors.New("timed out waiting for a lock held by another process")
+
+// LockFile takes an advisory lock on the file at path, shared or exclusive, creating the
+// file if needed. It waits up to timeout for other processes to release a conflicting
+// lock (a zero timeout tries once) and returns the function releasing it.
+func LockFile(path string, exclusive bool, timeout time.Duration) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			return func() {
+				unlock(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w (waited %s for %s)", ErrLockTimeout, timeout, path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || freebsd
+
+package common
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// LocksHeld reports whether LockFile excludes other processes on this platform
+const LocksHeld = true
+
+// tryLock takes an advisory lock on f without waiting; ok is false when another process
+// holds a conflicting one
+func tryLock(f *os.File, exclusive bool) (ok bool, err error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	err = syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// LocksHeld reports whether LockFile excludes other processes on this platform
+const LocksHeld = true
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var (
+	kernel32     = syscall.NewLazyDLL("kernel32.dll")
+	lockFileEx   = kernel32.NewProc("LockFileEx")
+	unlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// tryLock takes a lock on the first byte of f without waiting; ok is false when another
+// process holds a conflicting one
+func tryLock(f *os.File, exclusive bool) (ok bool, err error) {
+	flags := uint32(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	var overlapped syscall.Overlapped
+	r, _, callErr := lockFileEx.Call(f.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return true, nil
+	}
+	if callErr == errorLockViolation {
+		return false, nil
+	}
+	return false, callErr
+}
+
+func unlock(f *os.File) {
+	var overlapped syscall.Overlapped
+	unlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+}
@@ -0,0 +1,123 @@
+package common
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HashAlgorithm names a checksum used for manifests and verification
+type HashAlgorithm string
+
+// Supported hash algorithms
+const (
+	HashSHA256 HashAlgorithm = "sha256"
+	HashSHA1   HashAlgorithm = "sha1"
+	HashMD5    HashAlgorithm = "md5"
+	HashCRC32  HashAlgorithm = "crc32"
+	HashXXH64  HashAlgorithm = "xxh64"
+)
+
+// HashAlgorithms lists the supported algorithms, strongest first
+var HashAlgorithms = []HashAlgorithm{HashSHA256, HashSHA1, HashMD5, HashCRC32, HashXXH64}
+
+// Defaults: a fast non-cryptographic hash for routine verification, and a strong hash
+// for checksum files kept next to archives
+const (
+	DefaultVerifyHash   = HashXXH64
+	DefaultManifestHash = HashSHA256
+)
+
+// ParseHashAlgorithm validates an algorithm name
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	for _, alg := range HashAlgorithms {
+		if strings.EqualFold(name, string(alg)) {
+			return alg, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hash algorithm %q (use sha256, sha1, md5, crc32 or xxh64)", name)
+}
+
+// New returns a new hash for the algorithm
+func (a HashAlgorithm) New() hash.Hash {
+	switch a {
+	case HashSHA1:
+		return sha1.New()
+	case HashMD5:
+		return md5.New()
+	case HashCRC32:
+		return crc32.NewIEEE()
+	case HashXXH64:
+		return NewXXH64()
+	}
+	return sha256.New()
+}
+
+// HashFile returns the hex-encoded digest of a file
+func HashFile(path string, alg HashAlgorithm) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	h := alg.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FileHashes holds the digests of copied files, by slash-separated path relative to the
+// copied directory and then by algorithm
+type FileHashes map[string]map[HashAlgorithm]string
+
+// Sum returns the digest of a file, or "" if it wasn't hashed with alg
+func (h FileHashes) Sum(path string, alg HashAlgorithm) string {
+	return h[path][alg]
+}
+
+// copyHashes collects the digests of the files written by a copy
+type copyHashes struct {
+	root  string // Source directory the recorded paths are relative to
+	algs  []HashAlgorithm
+	files FileHashes
+}
+
+// start returns new hashes for the next file, or nil when the copy isn't hashed
+func (c *copyHashes) start() map[HashAlgorithm]hash.Hash {
+	if c == nil || len(c.algs) == 0 {
+		return nil
+	}
+	sums := make(map[HashAlgorithm]hash.Hash, len(c.algs))
+	for _, alg := range c.algs {
+		if _, ok := sums[alg]; !ok {
+			sums[alg] = alg.New()
+		}
+	}
+	return sums
+}
+
+// record stores the digests of a copied file
+func (c *copyHashes) record(src string, sums map[HashAlgorithm]hash.Hash) {
+	if sums == nil {
+		return
+	}
+	rel, err := filepath.Rel(c.root, src)
+	if err != nil {
+		return
+	}
+	digests := make(map[HashAlgorithm]string, len(sums))
+	for alg, h := range sums {
+		digests[alg] = hex.EncodeToString(h.Sum(nil))
+	}
+	c.files[filepath.ToSlash(rel)] = digests
+}
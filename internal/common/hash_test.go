@@ -0,0 +1,49 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "abc")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reference digests of "abc" (FIPS 180, RFC 1321, ITU-T V.42 and xxhsum)
+	want := map[HashAlgorithm]string{
+		HashSHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		HashSHA1:   "a9993e364706816aba3e25717850c26c9cd0d89d",
+		HashMD5:    "900150983cd24fb0d6963f7d28e17f72",
+		HashCRC32:  "352441c2",
+		HashXXH64:  "44bc2cf5ad770999",
+	}
+	for _, alg := range HashAlgorithms {
+		got, err := HashFile(path, alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want[alg] {
+			t.Errorf("%s = %s, want %s", alg, got, want[alg])
+		}
+	}
+
+	if _, err := HashFile(filepath.Join(t.TempDir(), "missing"), HashSHA256); err == nil {
+		t.Error("hashed a missing file")
+	}
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	for name, want := range map[string]HashAlgorithm{"sha256": HashSHA256, "SHA1": HashSHA1, "Md5": HashMD5, "crc32": HashCRC32, "XXH64": HashXXH64} {
+		if got, err := ParseHashAlgorithm(name); err != nil || got != want {
+			t.Errorf("ParseHashAlgorithm(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", "sha512", "xxh3"} {
+		if _, err := ParseHashAlgorithm(name); err == nil {
+			t.Errorf("ParseHashAlgorithm(%q) accepted", name)
+		}
+	}
+}
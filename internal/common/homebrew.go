@@ -0,0 +1,28 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HomebrewRules keeps homebrew and media apps apart from licensed games in a library
+type HomebrewRules struct {
+	Folder string `yaml:"folder"` // Section of the output directory holding them ("" keeps them among the games)
+	Prefix string `yaml:"prefix"` // Put in front of their titles and folder names, e.g. "[Homebrew] "
+}
+
+// DefaultHomebrewRules returns the rules used when nothing is configured: a Homebrew section
+func DefaultHomebrewRules() HomebrewRules {
+	return HomebrewRules{Folder: "Homebrew"}
+}
+
+// Validate checks that the section is a single folder name and the prefix fits in one
+func (r HomebrewRules) Validate() error {
+	if r.Folder == "." || r.Folder == ".." || strings.ContainsAny(r.Folder, `/\`) {
+		return fmt.Errorf("invalid homebrew folder name %q", r.Folder)
+	}
+	if strings.ContainsAny(r.Prefix, `/\`) {
+		return fmt.Errorf("invalid homebrew prefix %q", r.Prefix)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumSidecarPath returns the checksum file written next to an archive, named after
+// the algorithm (e.g. game.7z.sha256)
+func ChecksumSidecarPath(archivePath string, alg HashAlgorithm) string {
+	return archivePath + "." + string(alg)
+}
+
+// WriteChecksumSidecar writes a checksum file for an archive in the format used by
+// sha256sum, md5sum and xxhsum, so it can be checked with e.g. "sha256sum -c game.7z.sha256"
+func WriteChecksumSidecar(archivePath string, alg HashAlgorithm) (string, error) {
+	digest, err := HashFile(archivePath, alg)
+	if err != nil {
+		return "", err
+	}
+
+	line := fmt.Sprintf("%s  %s\n", digest, filepath.Base(archivePath))
+	if err := os.WriteFile(ChecksumSidecarPath(archivePath, alg), []byte(line), 0644); err != nil {
+		return "", fmt.Errorf("writing checksum file: %w", err)
+	}
+	return digest, nil
+}
+
+// ParseChecksumLine returns the digest from the first line of a checksum file, lower case
+func ParseChecksumLine(data []byte) (string, error) {
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// ReadChecksumSidecar returns the digest stored in an archive's checksum file
+func ReadChecksumSidecar(archivePath string, alg HashAlgorithm) (string, error) {
+	data, err := os.ReadFile(ChecksumSidecarPath(archivePath, alg))
+	if err != nil {
+		return "", err
+	}
+	return ParseChecksumLine(data)
+}
+
+// CreatePAR2 creates PAR2 recovery volumes for an archive with the given redundancy
+// percentage, using the par2 command (par2cmdline)
+func CreatePAR2(archivePath string, redundancy int) error {
+	if redundancy < 1 || redundancy > 100 {
+		return Permanent(fmt.Errorf("PAR2 redundancy must be between 1 and 100 percent, got %d", redundancy))
+	}
+
+	if _, err := exec.LookPath("par2"); err != nil {
+		return Permanent(fmt.Errorf(`par2 %w in PATH. Please install par2cmdline:
+
+  - Ubuntu/Debian: sudo apt-get install par2
+  - macOS: brew install par2
+  - Windows: download par2cmdline from https://github.com/Parchive/par2cmdline/releases`, ErrToolNotFound))
+	}
+
+	archiveName := filepath.Base(archivePath)
+	args := []string{
+		"create",
+		fmt.Sprintf("-r%d", redundancy), // recovery data as a percentage of the archive
+		"-q",                            // quiet
+		archiveName + ".par2",
+		archiveName,
+	}
+
+	execCmd := exec.Command("par2", args...)
+	execCmd.Dir = filepath.Dir(archivePath)
+
+	var output bytes.Buffer
+	execCmd.Stdout = &output
+	execCmd.Stderr = &output
+
+	if err := execCmd.Run(); err != nil {
+		return fmt.Errorf("par2 create failed: %w\n%s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// RemoveArchiveSidecars removes checksum and PAR2 files belonging to an archive,
+// so they never describe an archive that was replaced or removed
+func RemoveArchiveSidecars(archivePath string) error {
+	sidecars, err := filepath.Glob(escapeGlob(archivePath) + "*.par2")
+	if err != nil {
+		return err
+	}
+	for _, alg := range HashAlgorithms {
+		sidecars = append(sidecars, ChecksumSidecarPath(archivePath, alg))
+	}
+
+	for _, path := range sidecars {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// escapeGlob escapes glob metacharacters, which are common in game directory names ("[BLUS12345]")
+func escapeGlob(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
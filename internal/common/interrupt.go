@@ -0,0 +1,95 @@
+package common
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+var (
+	interruptsHandled atomic.Bool
+	stopRequested     atomic.Bool
+	abortRequested    atomic.Bool
+	abortOnce         sync.Once
+	abortCh           = make(chan struct{})
+)
+
+// HandleInterrupts handles SIGINT and SIGTERM until the returned function is called.
+// The first signal asks the batch to stop after the current game (StopRequested); the
+// second stops the current game too, killing its 7z run or copy (AbortRequested); the
+// third exits at once.
+func HandleInterrupts() (stop func()) {
+	signals := make(chan os.Signal, 3)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	interruptsHandled.Store(true)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+			case <-done:
+				return
+			}
+			switch {
+			case !stopRequested.Load():
+				stopRequested.Store(true)
+				ui.Warnf("Interrupted: stopping after the current game (interrupt again to stop it now)\n")
+			case !abortRequested.Load():
+				abortRequested.Store(true)
+				abortOnce.Do(func() { close(abortCh) })
+				ui.Warnf("Interrupted again: stopping the current game and removing its partial output\n")
+			default:
+				ui.Errorf("Interrupted three times: exiting without cleaning up\n")
+				os.Exit(130) // 128 + SIGINT, as a shell reports it
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		interruptsHandled.Store(false)
+		close(done)
+	}
+}
+
+// StopRequested reports whether an interrupt asked the batch to stop after the current game
+func StopRequested() bool {
+	return stopRequested.Load()
+}
+
+// AbortRequested reports whether an interrupt asked to stop the current game as well
+func AbortRequested() bool {
+	return abortRequested.Load()
+}
+
+// startCommand starts an external command. While interrupts are handled it runs in its
+// own process group, so the Ctrl+C the terminal sends to the whole group doesn't stop
+// it before the current game is finished; a second interrupt kills it instead.
+// The returned function stops watching for that and must be called once it exits.
+func startCommand(execCmd *exec.Cmd) (func(), error) {
+	handled := interruptsHandled.Load()
+	if handled {
+		detachProcessGroup(execCmd)
+	}
+	if err := execCmd.Start(); err != nil {
+		return nil, err
+	}
+	if !handled {
+		return func() {}, nil
+	}
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-abortCh:
+			execCmd.Process.Kill()
+		case <-exited:
+		}
+	}()
+	return func() { close(exited) }, nil
+}
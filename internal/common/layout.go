@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layout describes the standard folders created inside an organized game directory
+type Layout struct {
+	UpdatesDir string   `yaml:"updates_dir"` // Folder for game updates (default "_updates")
+	DLCDir     string   `yaml:"dlc_dir"`     // Folder for downloadable content (default "_dlc")
+	ManualsDir string   `yaml:"manuals_dir"` // Folder for manuals and readmes, created when there are any (default "_manuals")
+	DiscDir    string   `yaml:"disc_dir"`    // Folder for disc components kept separately, created when needed (default "_disc")
+	ExtraDirs  []string `yaml:"extra_dirs"`  // Additional folders such as _saves, _artwork
+}
+
+// DefaultLayout returns the standard _updates/_dlc layout
+func DefaultLayout() Layout {
+	return Layout{
+		UpdatesDir: "_updates",
+		DLCDir:     "_dlc",
+		ManualsDir: "_manuals",
+		DiscDir:    "_disc",
+	}
+}
+
+// WithDefaults fills in any empty folder names with the defaults
+func (l Layout) WithDefaults() Layout {
+	defaults := DefaultLayout()
+	if l.UpdatesDir == "" {
+		l.UpdatesDir = defaults.UpdatesDir
+	}
+	if l.DLCDir == "" {
+		l.DLCDir = defaults.DLCDir
+	}
+	if l.ManualsDir == "" {
+		l.ManualsDir = defaults.ManualsDir
+	}
+	if l.DiscDir == "" {
+		l.DiscDir = defaults.DiscDir
+	}
+	return l
+}
+
+// Subfolders returns every standard folder name in creation order
+func (l Layout) Subfolders() []string {
+	l = l.WithDefaults()
+	return append([]string{l.UpdatesDir, l.DLCDir}, l.ExtraDirs...)
+}
+
+// Validate checks that folder names are usable and do not clash with the game files
+func (l Layout) Validate() error {
+	seen := make(map[string]bool)
+	names := l.Subfolders()
+	// The manuals and disc folders may also be listed in extra_dirs to always create them
+	for _, name := range []string{l.WithDefaults().ManualsDir, l.WithDefaults().DiscDir} {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("invalid layout folder name %q", name)
+		}
+		if name == "game" || name == "game.7z" {
+			return fmt.Errorf("layout folder name %q is reserved for game files", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate layout folder name %q", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Library sections holding PS3 content that isn't a game
+const (
+	ThemesSection = "Themes" // XMB themes (.p3t)
+	MediaSection  = "Media"  // Videos, music and pictures to copy to the console's XMB
+)
+
+// ThemeExtension is the extension of PS3 theme files
+const ThemeExtension = ".p3t"
+
+// MediaExtensions are the video, music and picture files the PS3 XMB plays and shows
+var MediaExtensions = []string{
+	".mp4", ".m4v", ".avi", ".mkv", ".mpg", ".mpeg", ".m2ts", ".mts", ".wmv", ".vob",
+	".mp3", ".m4a", ".aac", ".wav", ".wma", ".flac", ".at3",
+	".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tif", ".tiff",
+}
+
+// IsThemeFile reports whether a file name is a PS3 theme
+func IsThemeFile(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ThemeExtension)
+}
+
+// IsMediaFile reports whether a file name is a video, music or picture file
+func IsMediaFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, media := range MediaExtensions {
+		if ext == media {
+			return true
+		}
+	}
+	return false
+}
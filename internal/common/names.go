@@ -0,0 +1,110 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxName is the longest file name, in bytes, that ext4, NTFS, APFS and FAT32 all
+// accept: 255 bytes on ext4, 255 UTF-16 units elsewhere, which a UTF-8 byte count never
+// exceeds
+const DefaultMaxName = 255
+
+// NameLimits caps the length of the folder and file names made from game titles. Lengths
+// are counted in UTF-8 bytes, so a Japanese title uses three per character.
+type NameLimits struct {
+	MaxName int `yaml:"max_name"` // Bytes in one name (default DefaultMaxName)
+	MaxPath int `yaml:"max_path"` // Bytes in the path of a game folder, 0 for no limit
+}
+
+// DefaultNameLimits returns the limits of library folders: names fit every common file
+// system and paths aren't limited
+func DefaultNameLimits() NameLimits {
+	return NameLimits{MaxName: DefaultMaxName}
+}
+
+// DefaultExportNameLimits returns the stricter limits of exports, which often go to FAT32
+// USB sticks and discs read on Windows: paths of game folders are kept within 160 bytes of
+// the export's root, leaving 100 of Windows' 260 characters to the files inside the game
+func DefaultExportNameLimits() NameLimits {
+	return NameLimits{MaxName: DefaultMaxName, MaxPath: 160}
+}
+
+// Validate checks that the limits leave room for a game ID
+func (l NameLimits) Validate() error {
+	if l.MaxName != 0 && (l.MaxName < 32 || l.MaxName > DefaultMaxName) {
+		return fmt.Errorf("max_name must be between 32 and %d", DefaultMaxName)
+	}
+	if l.MaxPath != 0 && l.MaxPath < 64 {
+		return fmt.Errorf("max_path must be 0 (no limit) or at least 64")
+	}
+	return nil
+}
+
+// GameFolderName returns GameFolderName(title, gameID) shortened to fit the limits in
+// parent, the path counted against MaxPath
+func (l NameLimits) GameFolderName(parent, title, gameID string) (string, error) {
+	return l.Name(parent, title, " ["+gameID+"]")
+}
+
+// Name returns the sanitized title followed by keep, cutting the title at a word boundary
+// when the name would be longer than MaxName or make parent/name longer than MaxPath.
+// keep, such as " [BLUS30001]" or " [BLUS30001].desktop", is never shortened; a limit that
+// leaves no room for any of the title is an error.
+func (l NameLimits) Name(parent, title, keep string) (string, error) {
+	title = SanitizeFilename(title)
+	room := l.MaxName
+	if room <= 0 {
+		room = DefaultMaxName
+	}
+	if l.MaxPath > 0 {
+		if r := l.MaxPath - len(filepath.Clean(parent)) - 1; r < room {
+			room = r
+		}
+	}
+	room -= len(keep)
+
+	short := TruncateTitle(title, room)
+	if short == "" && title != "" {
+		return "", fmt.Errorf("no room for %q in %s within the name length limits (max_name %d, max_path %d)", title+keep, parent, l.MaxName, l.MaxPath)
+	}
+	return short + keep, nil
+}
+
+// FitName returns an existing folder name unchanged when it fits the limits in parent,
+// or else shortened like Name, keeping its " [gameID]" ending
+func (l NameLimits) FitName(parent, name, gameID string) (string, error) {
+	keep := " [" + gameID + "]"
+	title, ok := strings.CutSuffix(name, keep)
+	if !ok {
+		title, keep = name, ""
+	}
+	return l.Name(parent, title, keep)
+}
+
+// TruncateTitle shortens a title to at most max bytes. It cuts at the last space or
+// hyphen that keeps at least half of the room, or else at the last whole character, and
+// removes the punctuation and spaces left at the end: at 40 bytes, "Tom Clancy's Splinter
+// Cell Classic Trilogy HD" becomes "Tom Clancy's Splinter Cell Classic" rather than
+// "Tom Clancy's Splinter Cell Classic Tril".
+func TruncateTitle(title string, max int) string {
+	if len(title) <= max {
+		return title
+	}
+	if max <= 0 {
+		return ""
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(title[cut]) {
+		cut--
+	}
+	short := title[:cut]
+	if title[cut] != ' ' {
+		if i := strings.LastIndexAny(short, " -"); i >= max/2 {
+			short = short[:i]
+		}
+	}
+	return strings.TrimRight(short, " .,:;-_–—&+")
+}
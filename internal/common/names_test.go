@@ -0,0 +1,107 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTruncateTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		max   int
+		want  string
+	}{
+		{"Tom Clancy's Splinter Cell Classic Trilogy HD", 40, "Tom Clancy's Splinter Cell Classic"},
+		{"Short", 40, "Short"},
+		{"Short", 5, "Short"},
+		{"Short", 0, ""},
+		{"Gran Turismo 5 Prologue", 12, "Gran Turismo"},             // Cut right before a space
+		{"Spider-Man Web of Shadows", 9, "Spider"},                  // At a hyphen
+		{"Uncharted: Drake's Fortune", 10, "Uncharted"},             // Punctuation left at the end
+		{"A Supercalifragilistic", 12, "A Supercalif"},              // No boundary in the upper half
+		{"ファイナルファンタジー XIII", 10, "ファイ"},                             // Whole characters only
+		{"Ni no Kuni – Wrath of the White Witch", 15, "Ni no Kuni"}, // Multi-byte dash
+	}
+	for _, tt := range tests {
+		got := TruncateTitle(tt.title, tt.max)
+		if got != tt.want {
+			t.Errorf("TruncateTitle(%q, %d) = %q, want %q", tt.title, tt.max, got, tt.want)
+		}
+		if len(got) > max(tt.max, 0) {
+			t.Errorf("TruncateTitle(%q, %d) is %d bytes", tt.title, tt.max, len(got))
+		}
+	}
+}
+
+func TestNameLimits(t *testing.T) {
+	const title = "Tom Clancy's Splinter Cell Classic Trilogy HD"
+	parent := filepath.Join("mnt", "usb", "PS3ISO", "GAMES")
+	tests := []struct {
+		limits NameLimits
+		parent string
+		want   string
+	}{
+		{DefaultNameLimits(), parent, title + " [BLUS30001]"},
+		{NameLimits{}, parent, title + " [BLUS30001]"},
+		{NameLimits{MaxName: 32}, parent, "Tom Clancy's [BLUS30001]"},
+		// 64 bytes of path leave 30 for the title after the parent, separator and ID
+		{NameLimits{MaxName: 255, MaxPath: 64}, parent, "Tom Clancy's Splinter Cell [BLUS30001]"},
+		{NameLimits{MaxName: 32, MaxPath: 64}, parent, "Tom Clancy's [BLUS30001]"},
+	}
+	for _, tt := range tests {
+		got, err := tt.limits.GameFolderName(tt.parent, title, "BLUS30001")
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.limits, err)
+		}
+		if got != tt.want {
+			t.Errorf("%+v: %q, want %q", tt.limits, got, tt.want)
+		}
+	}
+
+	// The title is sanitized before it's measured
+	if got, _ := DefaultNameLimits().GameFolderName(parent, "Uncharted: Drake's Fortune", "BCUS98103"); got != "Uncharted_ Drake's Fortune [BCUS98103]" {
+		t.Errorf("sanitized name %q", got)
+	}
+
+	// A parent leaving no room for the title is an error
+	deep := strings.Repeat("d", 55)
+	if name, err := (NameLimits{MaxPath: 64}).GameFolderName(deep, title, "BLUS30001"); err == nil {
+		t.Errorf("no room, but got %q", name)
+	}
+}
+
+func TestFitName(t *testing.T) {
+	limits := NameLimits{MaxName: 32}
+	tests := []struct {
+		name, want string
+	}{
+		{"God of War III [BCUS98111]", "God of War III [BCUS98111]"},
+		{"Tom Clancy's Splinter Cell Classic Trilogy HD [BCUS98111]", "Tom Clancy's [BCUS98111]"},
+		{"Tom Clancy's Splinter Cell Classic Trilogy HD", "Tom Clancy's Splinter Cell"}, // No ID to keep
+	}
+	for _, tt := range tests {
+		got, err := limits.FitName("lib", tt.name, "BCUS98111")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("FitName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameLimitsValidate(t *testing.T) {
+	valid := []NameLimits{{}, DefaultNameLimits(), DefaultExportNameLimits(), {MaxName: 32, MaxPath: 64}}
+	for _, limits := range valid {
+		if err := limits.Validate(); err != nil {
+			t.Errorf("%+v: %v", limits, err)
+		}
+	}
+	invalid := []NameLimits{{MaxName: 31}, {MaxName: 256}, {MaxName: -1}, {MaxPath: 63}}
+	for _, limits := range invalid {
+		if err := limits.Validate(); err == nil {
+			t.Errorf("%+v accepted", limits)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// accentFold maps accented Latin letters to their base letters. It is a fixed table,
+// not the collation rules of any language: "ö" sorts as "o" whatever the locale.
+var accentFold = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ā", "a", "ă", "a", "ą", "a",
+	"ç", "c", "ć", "c", "č", "c",
+	"ď", "d", "đ", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ē", "e", "ė", "e", "ę", "e", "ě", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ī", "i", "į", "i",
+	"ł", "l", "ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "ō", "o", "ő", "o",
+	"ř", "r", "ś", "s", "š", "s", "ş", "s", "ß", "ss", "ť", "t", "ţ", "t",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ū", "u", "ů", "u", "ű", "u",
+	"ý", "y", "ÿ", "y", "ź", "z", "ż", "z", "ž", "z",
+	"æ", "ae", "œ", "oe",
+)
+
+// FoldKey folds case and the accents in accentFold, so "Écho" sorts and matches
+// with "echo"
+func FoldKey(s string) string {
+	return accentFold.Replace(strings.ToLower(s))
+}
+
+// NaturalLess reports whether a sorts before b, comparing runs of digits by their
+// numeric value ("Game 2" before "Game 10") and text by its FoldKey. Strings
+// that compare equal that way fall back to plain byte order.
+func NaturalLess(a, b string) bool {
+	if c := naturalCompare(FoldKey(a), FoldKey(b)); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if isDigit(ra) && isDigit(rb) {
+			numA, restA := leadingDigits(a)
+			numB, restB := leadingDigits(b)
+			if c := compareDigits(numA, numB); c != 0 {
+				return c
+			}
+			a, b = restA, restB
+			continue
+		}
+		if ra != rb {
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return len(a) - len(b)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func leadingDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareDigits compares two digit strings by value, then by length ("01" after "1")
+func compareDigits(a, b string) int {
+	trimmedA, trimmedB := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(trimmedA) != len(trimmedB) {
+		return len(trimmedA) - len(trimmedB)
+	}
+	if c := strings.Compare(trimmedA, trimmedB); c != 0 {
+		return c
+	}
+	return len(a) - len(b)
+}
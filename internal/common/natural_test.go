@@ -0,0 +1,57 @@
+package common
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Game 2", "Game 10", true},
+		{"Game 10", "Game 2", false},
+		{"Game 9", "Game 10: Part 2", true},
+		{"Disc 1", "Disc 1 Bonus", true},
+		{"game 3", "Game 4", true},
+		{"Écho", "Edge", true}, // Sorted as "echo", not after every ASCII letter
+		{"echo", "Écho", true}, // Equal keys fall back to byte order
+		{"Final Fantasy XIII", "Final Fantasy XIII-2", true},
+		{"1", "01", true}, // Equal values: fewer digits first
+		{"007", "7", false},
+		{"18446744073709551616", "18446744073709551617", true}, // Past uint64
+		{"Track 2b", "Track 2a", false},
+		{"", "a", true},
+		{"a", "a", false},
+	}
+	for _, tt := range tests {
+		if got := NaturalLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("NaturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNaturalSort(t *testing.T) {
+	titles := []string{"Game 10", "game 1", "Ëlite", "Game 2", "Zone", "Game 1", "Elite 3", "Game 2: Remix"}
+	sort.Slice(titles, func(i, j int) bool { return NaturalLess(titles[i], titles[j]) })
+	want := []string{"Ëlite", "Elite 3", "Game 1", "game 1", "Game 2", "Game 2: Remix", "Game 10", "Zone"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("sorted %q, want %q", titles, want)
+	}
+}
+
+func TestFoldKey(t *testing.T) {
+	for s, want := range map[string]string{
+		"Écho":           "echo",
+		"Straße":         "strasse",
+		"Æon Flux":       "aeon flux",
+		"Pokémon Ñandú":  "pokemon nandu",
+		"Plain ASCII 42": "plain ascii 42",
+	} {
+		if got := FoldKey(s); got != want {
+			t.Errorf("FoldKey(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package common
+
+import "syscall"
+
+// networkFSTypes are the file system type names of network file systems
+var networkFSTypes = map[string]bool{"nfs": true, "smbfs": true, "afpfs": true, "webdav": true, "cifs": true}
+
+// IsNetworkMount reports whether path is on a network file system (NFS, SMB, ...).
+// Paths that don't exist yet use their nearest existing parent.
+func IsNetworkMount(path string) (bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &stat); err != nil {
+		return false, err
+	}
+	var name []byte
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return networkFSTypes[string(name)], nil
+}
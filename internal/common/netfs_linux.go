@@ -0,0 +1,26 @@
+package common
+
+import "syscall"
+
+// networkFSTypes are the statfs magic numbers of network file systems
+var networkFSTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517B:     "smb",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x73757245: "coda",
+	0x5346414F: "afs",
+	0x00C36400: "ceph",
+	0x01021997: "9p",
+}
+
+// IsNetworkMount reports whether path is on a network file system (NFS, SMB, ...).
+// Paths that don't exist yet use their nearest existing parent.
+func IsNetworkMount(path string) (bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &stat); err != nil {
+		return false, err
+	}
+	_, ok := networkFSTypes[uint32(stat.Type)]
+	return ok, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package common
+
+// IsNetworkMount reports whether path is on a network file system. Detection is not
+// supported on this platform, so it always reports false.
+func IsNetworkMount(path string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const driveRemote = 4
+
+var getDriveType = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDriveTypeW")
+
+// IsNetworkMount reports whether path is on a network share, either a UNC path or
+// a mapped network drive. Paths that don't exist yet use their nearest existing parent.
+func IsNetworkMount(path string) (bool, error) {
+	abs, err := filepath.Abs(existingParent(path))
+	if err != nil {
+		return false, err
+	}
+	volume := filepath.VolumeName(abs)
+	if len(volume) >= 2 && volume[0] == '\\' && volume[1] == '\\' {
+		return true, nil
+	}
+	root, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return false, err
+	}
+	r, _, _ := getDriveType.Call(uintptr(unsafe.Pointer(root)))
+	return r == driveRemote, nil
+}
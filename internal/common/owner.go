@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Owner is the user and group given to created files and folders, so output written
+// by a service account belongs to the user Samba or Plex expects. -1 keeps the user or
+// group a file was created with.
+type Owner struct {
+	Spec string // As given, e.g. media:media
+	UID  int
+	GID  int
+}
+
+// ParseOwner reads "user:group", "user" or ":group", by name or number ("" is no owner)
+func ParseOwner(spec string) (*Owner, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("changing the owner of files is not supported on Windows")
+	}
+
+	userName, groupName, _ := strings.Cut(spec, ":")
+	if userName == "" && groupName == "" {
+		return nil, fmt.Errorf("invalid owner %q (use user:group, user or :group)", spec)
+	}
+	owner := &Owner{Spec: spec, UID: -1, GID: -1}
+
+	if userName != "" {
+		id, err := lookupID(userName, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("owner %q: %w", spec, err)
+		}
+		owner.UID = id
+	}
+	if groupName != "" {
+		id, err := lookupID(groupName, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("owner %q: %w", spec, err)
+		}
+		owner.GID = id
+	}
+	return owner, nil
+}
+
+// lookupID returns a numeric ID as is, or looks up a user or group name
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	id, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
+func (o *Owner) String() string {
+	return o.Spec
+}
+
+// Apply gives path, everything under it, and the folders between base and path (e.g. an
+// A-Z bucket) to the owner. Symlinks are changed themselves, not their targets. Nil
+// owners change nothing.
+func (o *Owner) Apply(base, path string) error {
+	if o == nil {
+		return nil
+	}
+	if rel, err := filepath.Rel(base, filepath.Dir(path)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		dir := base
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, part)
+			if err := os.Lchown(dir, o.UID, o.GID); err != nil {
+				return fmt.Errorf("setting owner %s: %w", o, err)
+			}
+		}
+	}
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(p, o.UID, o.GID)
+	})
+	if err != nil {
+		return fmt.Errorf("setting owner %s: %w", o, err)
+	}
+	return nil
+}
+
+// OwnerRules pick the owner of output by where it is written: the owner of the longest
+// matching library folder, or else the default
+type OwnerRules struct {
+	Default   *Owner
+	Libraries map[string]*Owner // Library folder (absolute) to owner
+}
+
+// For returns the owner of output written to dir, or nil to leave it alone
+func (r OwnerRules) For(dir string) *Owner {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return r.Default
+	}
+	owner, longest := r.Default, -1
+	for library, libraryOwner := range r.Libraries {
+		rel, err := filepath.Rel(library, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if len(library) > longest {
+			owner, longest = libraryOwner, len(library)
+		}
+	}
+	return owner
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// maxPasswordAttempts limits how often a wrong archive password is retried
+const maxPasswordAttempts = 3
+
+// PasswordFunc returns the password to try for an encrypted archive. attempt starts at 1
+// and grows after each wrong password; an error ends the attempts.
+type PasswordFunc func(archivePath string, attempt int) (string, error)
+
+// ArchivePassword supplies passwords for encrypted input archives. When nil, encrypted
+// archives fail with ErrWrongPassword.
+var ArchivePassword PasswordFunc
+
+// ExtractArchive extracts an input archive (.zip, .7z or anything 7z reads) to destDir,
+// asking ArchivePassword for passwords when it is encrypted
+func ExtractArchive(archivePath, destDir string) error {
+	if strings.EqualFold(filepath.Ext(archivePath), ".zip") {
+		encrypted, err := isZipEncrypted(archivePath)
+		if err != nil {
+			return err
+		}
+		if !encrypted {
+			return ExtractZip(archivePath, destDir)
+		}
+	}
+
+	err := extract7z(archivePath, destDir, "")
+	for attempt := 1; errors.Is(err, ErrWrongPassword); attempt++ {
+		if ArchivePassword == nil {
+			return fmt.Errorf("%s is encrypted and no password is available: %w", archivePath, err)
+		}
+		if attempt > maxPasswordAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", maxPasswordAttempts, err)
+		}
+		if attempt > 1 {
+			ui.Warnf("Wrong password for %s\n", archivePath)
+		}
+		password, perr := ArchivePassword(archivePath, attempt)
+		if perr != nil {
+			return fmt.Errorf("%s is encrypted: %w", archivePath, perr)
+		}
+		err = extract7z(archivePath, destDir, password)
+	}
+	return err
+}
+
+// isZipEncrypted reports whether any file in a zip archive is encrypted
+func isZipEncrypted(path string) (bool, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Flags&0x1 != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxListedFiles limits how many paths permission errors list
+const maxListedFiles = 5
+
+// IsWritableDir reports whether files can be created and removed in dir. It probes with
+// a temporary file, since permission bits do not show read-only media or mounts.
+func IsWritableDir(dir string) bool {
+	f, err := os.CreateTemp(dir, ".rom-organizer-write-test-*")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name) == nil
+}
+
+// CheckReadable returns an error listing the files and folders under root that cannot be
+// read, so a copy fails up front instead of halfway through
+func CheckReadable(root string) error {
+	var unreadable []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsPermission(err) {
+				unreadable = append(unreadable, path)
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				unreadable = append(unreadable, path)
+				return nil
+			}
+			f.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("checking source permissions: %w", err)
+	}
+	if len(unreadable) == 0 {
+		return nil
+	}
+
+	shown := unreadable
+	if len(shown) > maxListedFiles {
+		shown = shown[:maxListedFiles]
+	}
+	return Permanent(fmt.Errorf("%d files in %s cannot be read (check their permissions or whether another program has them locked): %s",
+		len(unreadable), root, strings.Join(shown, ", ")))
+}
+
+// EnsureWritable gives the owner write access to every file and folder under root, so
+// output extracted from archives of read-only media can be changed and deleted later
+func EnsureWritable(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink != 0 {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		want := info.Mode().Perm() | 0600
+		if d.IsDir() {
+			want |= 0700
+		}
+		if want != info.Mode().Perm() {
+			return os.Chmod(path, want)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package common
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcessGroup starts a command in a process group of its own, out of reach of
+// the terminal's SIGINT
+func detachProcessGroup(execCmd *exec.Cmd) {
+	execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,12 @@
+package common
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcessGroup starts a command in a process group of its own, out of reach of
+// the console's Ctrl+C
+func detachProcessGroup(execCmd *exec.Cmd) {
+	execCmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// RetryPolicy controls automatic retries of operations that commonly fail transiently,
+// such as copies to network shares or 7z runs blocked by a file lock
+type RetryPolicy struct {
+	Retries int           // Number of retries after the first attempt (0 disables retrying)
+	Delay   time.Duration // Delay before the first retry, doubled after each further attempt
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error so RetryPolicy.Do returns it immediately without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether an error was marked as not worth retrying
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Do runs op, retrying with exponential backoff while it fails with a non-permanent error.
+// An interrupted op isn't retried. cleanup, if non-nil, runs before every retry to
+// remove partial output.
+func (p RetryPolicy) Do(name string, op func() error, cleanup func()) error {
+	delay := p.Delay
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= p.Retries || IsPermanent(err) || errors.Is(err, ErrInterrupted) {
+			return err
+		}
+
+		ui.Warnf("%s failed (attempt %d/%d): %v\n", name, attempt+1, p.Retries+1, firstLine(err.Error()))
+		ui.Infof("    Retrying in %s...\n", delay)
+
+		time.Sleep(delay)
+		delay *= 2
+
+		if cleanup != nil {
+			cleanup()
+		}
+	}
+}
+
+// firstLine returns the first line of a possibly multi-line message
+func firstLine(msg string) string {
+	for i, r := range msg {
+		if r == '\n' {
+			return msg[:i]
+		}
+	}
+	return msg
+}
+
+// String describes the policy for verbose output
+func (p RetryPolicy) String() string {
+	if p.Retries == 0 {
+		return "disabled"
+	}
+	return fmt.Sprintf("%d retries, %s initial delay", p.Retries, p.Delay)
+}
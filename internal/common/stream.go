@@ -0,0 +1,195 @@
+package common
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// Stream7zFile writes one file of a 7z archive, named by its path inside the
+// archive, to w without extracting anything to disk. It returns the bytes written.
+func Stream7zFile(archivePath, name string, w io.Writer) (int64, error) {
+	entries, err := List7zArchive(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	name = path.Clean(filepath.ToSlash(name))
+	var entry *ArchiveEntry
+	for i := range entries {
+		if strings.EqualFold(entries[i].Path, name) {
+			entry = &entries[i]
+			break
+		}
+	}
+	switch {
+	case entry == nil:
+		return 0, fmt.Errorf("%s not found in %s", name, archivePath)
+	case entry.IsDir:
+		return 0, fmt.Errorf("%s is a directory in %s", name, archivePath)
+	}
+
+	cmd, err := find7zCommand()
+	if err != nil {
+		return 0, err
+	}
+	args := []string{"e", "-so", "-p", archivePath, entry.Path}
+	ui.Debugf("Running: %s %s\n", cmd, strings.Join(args, " "))
+	counter := &CountingWriter{W: w}
+	var stderr strings.Builder
+	execCmd := exec.Command(cmd, args...)
+	execCmd.Stdout = counter
+	execCmd.Stderr = &stderr
+	err = execCmd.Run()
+	n := counter.N.Load()
+	if err != nil {
+		return n, fmt.Errorf("reading %s from %s: %w: %s", name, archivePath, err, strings.TrimSpace(stderr.String()))
+	}
+	if n != entry.Size {
+		return n, fmt.Errorf("reading %s from %s: got %d bytes, expected %d", name, archivePath, n, entry.Size)
+	}
+	return n, nil
+}
+
+// Write7zTar writes the contents of a 7z archive to tw as tar entries below prefix.
+// The archive is decoded once: 7z writes every file to stdout in archive order, and
+// the listed sizes split the stream back into files.
+func Write7zTar(archivePath, prefix string, tw *tar.Writer) error {
+	entries, err := List7zArchive(archivePath)
+	if err != nil {
+		return err
+	}
+
+	cmd, err := find7zCommand()
+	if err != nil {
+		return err
+	}
+	args := []string{"e", "-so", "-p", excludeMetadataArg, archivePath}
+	ui.Debugf("Running: %s %s\n", cmd, strings.Join(args, " "))
+	var stderr strings.Builder
+	execCmd := exec.Command(cmd, args...)
+	execCmd.Stderr = &stderr
+	stdout, err := execCmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("starting 7z: %w", err)
+	}
+	if err := execCmd.Start(); err != nil {
+		return fmt.Errorf("starting 7z: %w", err)
+	}
+
+	streamErr := func() error {
+		for _, entry := range entries {
+			header := &tar.Header{
+				Name:    path.Join(prefix, entry.Path),
+				ModTime: parse7zTime(entry.Modified),
+				Mode:    0o644,
+			}
+			if entry.IsDir {
+				header.Typeflag = tar.TypeDir
+				header.Name += "/"
+				header.Mode = 0o755
+				if err := tw.WriteHeader(header); err != nil {
+					return err
+				}
+				continue
+			}
+			header.Typeflag = tar.TypeReg
+			header.Size = entry.Size
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(tw, stdout, entry.Size); err != nil {
+				return fmt.Errorf("reading %s from %s: %w", entry.Path, archivePath, err)
+			}
+		}
+		if extra, _ := io.Copy(io.Discard, stdout); extra > 0 {
+			return fmt.Errorf("%s produced %d bytes more than its listing", archivePath, extra)
+		}
+		return nil
+	}()
+	if streamErr != nil {
+		// Stop 7z rather than wait for it to write the rest of the archive
+		execCmd.Process.Kill()
+		execCmd.Wait()
+		return streamErr
+	}
+	if err := execCmd.Wait(); err != nil {
+		return fmt.Errorf("extracting %s: %w: %s", archivePath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// WriteDirTar writes the files below dir to tw as tar entries below prefix.
+// skip is called with each path relative to dir and leaves it out (and, for a
+// directory, everything in it) when it returns true.
+func WriteDirTar(dir, prefix string, tw *tar.Writer, skip func(rel string) bool) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if skip != nil && skip(filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil // Symlinks and devices have no place in a game folder
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// parse7zTime parses a modification time from a 7z listing, which may carry
+// fractional seconds; unparsable times give the zero time
+func parse7zTime(value string) time.Time {
+	if len(value) > 19 {
+		value = value[:19]
+	}
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	return t
+}
+
+// CountingWriter counts the bytes written through it to W. N can be read while writes go
+// on, e.g. as the progress of a watched command.
+type CountingWriter struct {
+	W io.Writer
+	N atomic.Int64
+}
+
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.W.Write(p)
+	c.N.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// TitleRules are cleanups applied to game titles before they name a folder
+type TitleRules struct {
+	StripSymbols       bool `yaml:"strip_symbols"`       // Remove ™, ® and ©
+	CollapseWhitespace bool `yaml:"collapse_whitespace"` // Trim and merge runs of spaces, tabs and line breaks
+	FixAllCaps         bool `yaml:"fix_all_caps"`        // Title-case titles of several words written in capitals
+	StripDiscNumbers   bool `yaml:"strip_disc_numbers"`  // Remove "(Disc 1)" and "[CD 2 of 3]" noise
+}
+
+// DefaultTitleRules returns the rules used when nothing is configured: all of them
+func DefaultTitleRules() TitleRules {
+	return TitleRules{StripSymbols: true, CollapseWhitespace: true, FixAllCaps: true, StripDiscNumbers: true}
+}
+
+var (
+	titleSymbols     = strings.NewReplacer("™", "", "®", "", "©", "", "(TM)", "", "(R)", "")
+	discNumberNoise  = regexp.MustCompile(`(?i)\s*[(\[]\s*(disc|disk|cd|dvd)\s*\d+(\s*(of|/)\s*\d+)?\s*[)\]]`)
+	romanNumeral     = regexp.MustCompile(`^[IVX]+$`)
+	lowercaseInTitle = map[string]bool{
+		"a": true, "an": true, "and": true, "at": true, "by": true, "for": true, "in": true,
+		"of": true, "on": true, "or": true, "the": true, "to": true, "vs": true, "vs.": true,
+	}
+)
+
+// Apply returns the title cleaned up by the enabled rules
+func (r TitleRules) Apply(title string) string {
+	if r.StripSymbols {
+		title = titleSymbols.Replace(title)
+	}
+	if r.StripDiscNumbers {
+		title = discNumberNoise.ReplaceAllString(title, "")
+	}
+	if r.CollapseWhitespace {
+		title = strings.Join(strings.Fields(title), " ")
+	}
+	if r.FixAllCaps && isAllCaps(title) {
+		title = titleCase(title)
+	}
+	return title
+}
+
+// isAllCaps reports whether a title of at least two words has letters but no lowercase
+// ones. Single words such as "MAG" or "ICO" are usually the real name.
+func isAllCaps(title string) bool {
+	if len(strings.Fields(title)) < 2 {
+		return false
+	}
+	hasLetter := false
+	for _, r := range title {
+		if unicode.IsLower(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
+
+// titleCase capitalizes the first letter of each word and of each part after a hyphen,
+// keeping Roman numerals and words with digits (e.g. "3D") and lowercasing short joining words
+func titleCase(title string) string {
+	words := strings.Split(title, " ")
+	for i, word := range words {
+		switch {
+		case romanNumeral.MatchString(strings.TrimRight(word, ":,.!?")), strings.IndexFunc(word, unicode.IsDigit) >= 0:
+			continue
+		case i > 0 && lowercaseInTitle[strings.ToLower(word)]:
+			words[i] = strings.ToLower(word)
+			continue
+		}
+
+		var b strings.Builder
+		capital := true
+		for _, r := range word {
+			if capital {
+				b.WriteRune(unicode.ToUpper(r))
+			} else {
+				b.WriteRune(unicode.ToLower(r))
+			}
+			// Capitalize again after a hyphen, slash or other separator, but not an apostrophe
+			capital = !unicode.IsLetter(r) && r != '\''
+		}
+		words[i] = b.String()
+	}
+	return strings.Join(words, " ")
+}
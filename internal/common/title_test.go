@@ -0,0 +1,49 @@
+package common
+
+import "testing"
+
+func TestTitleRules(t *testing.T) {
+	all := DefaultTitleRules()
+	tests := []struct {
+		rules TitleRules
+		title string
+		want  string
+	}{
+		{all, "Uncharted™: Drake's Fortune", "Uncharted: Drake's Fortune"},
+		{all, "LittleBigPlanet® (TM)", "LittleBigPlanet"},
+		{all, "  Gran   Turismo\t5 \n", "Gran Turismo 5"},
+		{all, "Metal Gear Solid 4 (Disc 1)", "Metal Gear Solid 4"},
+		{all, "Final Fantasy XIII [CD 2 of 3]", "Final Fantasy XIII"},
+		{all, "Heavy Rain (disk 1/2) Edition", "Heavy Rain Edition"},
+		{all, "Ratchet (Director's Cut)", "Ratchet (Director's Cut)"},
+
+		// Titles written in capitals
+		{all, "GOD OF WAR III", "God of War III"},
+		{all, "THE LAST OF US", "The Last of Us"},
+		{all, "SPIDER-MAN: WEB OF SHADOWS", "Spider-Man: Web of Shadows"},
+		{all, "MARVEL VS. CAPCOM 3", "Marvel vs. Capcom 3"},
+		{all, "TONY HAWK'S PROJECT 8", "Tony Hawk's Project 8"},
+		{all, "MAG", "MAG"},               // A single word is usually the real name
+		{all, "inFAMOUS 2", "inFAMOUS 2"}, // Mixed case is left alone
+		{all, "1942 2000", "1942 2000"},   // No letters
+
+		// Each rule can be turned off
+		{TitleRules{}, "  GOD  OF WAR™ (Disc 1)", "  GOD  OF WAR™ (Disc 1)"},
+		{TitleRules{StripSymbols: true}, "GOD OF WAR™", "GOD OF WAR"},
+		{TitleRules{CollapseWhitespace: true}, " GOD  OF WAR™ ", "GOD OF WAR™"},
+		{TitleRules{FixAllCaps: true}, "GOD OF WAR", "God of War"},
+		{TitleRules{StripDiscNumbers: true}, "GOD OF WAR (Disc 2)", "GOD OF WAR"},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.Apply(tt.title); got != tt.want {
+			t.Errorf("%+v.Apply(%q) = %q, want %q", tt.rules, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestGameFolderName(t *testing.T) {
+	title := DefaultTitleRules().Apply("RESISTANCE: FALL OF MAN™ (Disc 1)")
+	if got, want := GameFolderName(title, "BCUS98107"), "Resistance_ Fall of Man [BCUS98107]"; got != want {
+		t.Errorf("folder name %q, want %q", got, want)
+	}
+}
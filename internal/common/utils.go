@@ -1,16 +1,19 @@
 package common
 
 import (
-	"archive/zip"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
 )
 
 // GameInfo holds information about a game from any console
@@ -22,6 +25,11 @@ type GameInfo struct {
 	Version  string // Game version if available
 	Category string // Game category if available
 	Source   string // Source path where the game was found
+	Homebrew bool   // Homebrew or a media app rather than a licensed game
+
+	// Unverified is set when the title and ID weren't read from the game's own metadata
+	// (a missing or corrupt PARAM.SFO), but from its folder name or given by the user
+	Unverified bool
 }
 
 // GameMetadata represents metadata that can be extracted from a game
@@ -51,6 +59,10 @@ type ConsoleHandler interface {
 
 	// ValidateGameStructure checks if the source path contains a valid game structure
 	ValidateGameStructure(sourcePath string) error
+
+	// ValidateGameID checks the extracted game ID against the console's ID format,
+	// returning an error with a correction hint for malformed or placeholder IDs
+	ValidateGameID(gameInfo *GameInfo) error
 }
 
 // SanitizeFilename removes or replaces characters that are not safe for filenames
@@ -71,16 +83,37 @@ func SanitizeFilename(filename string) string {
 
 // GenerateTargetPath creates the target directory path for a game
 func GenerateTargetPath(gameInfo *GameInfo, outputDir string) string {
-	sanitizedTitle := SanitizeFilename(gameInfo.Title)
-	targetDirName := fmt.Sprintf("%s [%s]", sanitizedTitle, gameInfo.GameID)
-	return filepath.Join(outputDir, targetDirName)
+	return filepath.Join(outputDir, GameFolderName(gameInfo.Title, gameInfo.GameID))
+}
+
+// GameFolderName returns the organized folder name for a game, "{Title} [{Game ID}]"
+func GameFolderName(title, gameID string) string {
+	return fmt.Sprintf("%s [%s]", SanitizeFilename(title), gameID)
+}
+
+// FirstLetterBucket returns the alphabetical bucket folder for a title:
+// "A" through "Z" for titles starting with a letter, "0-9" for digits, and "#" otherwise
+func FirstLetterBucket(title string) string {
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return string(r - 'a' + 'A')
+		case r >= 'A' && r <= 'Z':
+			return string(r)
+		case r >= '0' && r <= '9':
+			return "0-9"
+		default:
+			return "#"
+		}
+	}
+	return "#"
 }
 
 // CreateTargetStructure creates the base directory structure for a packed game
-func CreateTargetStructure(targetPath string, force bool) error {
+func CreateTargetStructure(targetPath string, layout Layout, force bool) error {
 	// Check if target directory already exists
 	if _, err := os.Stat(targetPath); err == nil && !force {
-		return fmt.Errorf("target directory already exists: %s (use --force to overwrite)", targetPath)
+		return fmt.Errorf("%w: %s (use --force to overwrite)", ErrTargetExists, targetPath)
 	}
 
 	// Create target directory structure
@@ -88,88 +121,39 @@ func CreateTargetStructure(targetPath string, force bool) error {
 		return fmt.Errorf("creating target directory: %w", err)
 	}
 
-	// Create _updates and _dlc subdirectories
-	updatesDir := filepath.Join(targetPath, "_updates")
-	dlcDir := filepath.Join(targetPath, "_dlc")
-
-	if err := os.MkdirAll(updatesDir, 0755); err != nil {
-		return fmt.Errorf("creating _updates directory: %w", err)
-	}
-
-	if err := os.MkdirAll(dlcDir, 0755); err != nil {
-		return fmt.Errorf("creating _dlc directory: %w", err)
+	// Create _updates, _dlc and any extra configured subdirectories
+	for _, name := range layout.Subfolders() {
+		if err := os.MkdirAll(filepath.Join(targetPath, name), 0755); err != nil {
+			return fmt.Errorf("creating %s directory: %w", name, err)
+		}
 	}
 
 	return nil
 }
 
-// ExtractZip extracts a ZIP archive to the specified destination
-func ExtractZip(src, dest string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
-		path := filepath.Join(dest, f.Name)
-
-		// Check for directory traversal
-		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
-			rc.Close()
-			return fmt.Errorf("invalid file path: %s", f.Name)
-		}
-
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.FileInfo().Mode())
-			rc.Close()
-			continue
-		}
-
-		// Create the directories for this file
-		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-			rc.Close()
-			return err
-		}
-
-		outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.FileInfo().Mode())
-		if err != nil {
-			rc.Close()
-			return err
-		}
+// SevenZipCommands are the 7z executables looked up in PATH, in order of preference
+var SevenZipCommands = []string{"7z", "7za", "7zr"}
 
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
+// SevenZipPath is the 7z executable to run instead of searching SevenZipCommands, either
+// a path or a name in PATH (e.g. "7zz"). Set from the config file or --7z-path.
+var SevenZipPath string
 
+// find7zCommand returns SevenZipPath, or the first available 7z executable in PATH
+func find7zCommand() (string, error) {
+	if SevenZipPath != "" {
+		path, err := exec.LookPath(SevenZipPath)
 		if err != nil {
-			return err
+			return "", Permanent(fmt.Errorf("configured 7z executable %s: %w (%v)", SevenZipPath, ErrToolNotFound, err))
 		}
+		return path, nil
 	}
-
-	return nil
-}
-
-// Create7zArchive creates a 7z archive from the source directory
-func Create7zArchive(sourceDir, archivePath string) error {
-	// Check for available 7z commands
-	possibleCommands := []string{"7z", "7za", "7zr"}
-	var cmd string
-
-	for _, cmdName := range possibleCommands {
+	for _, cmdName := range SevenZipCommands {
 		if _, err := exec.LookPath(cmdName); err == nil {
-			cmd = cmdName
-			break
+			return cmdName, nil
 		}
 	}
 
-	if cmd == "" {
-		return fmt.Errorf(`7z command not found in PATH. Please install 7-zip or p7zip:
+	return "", Permanent(fmt.Errorf(`7z %w in PATH. Please install 7-zip or p7zip:
 
 Windows:
   - Download and install 7-Zip from https://www.7-zip.org/
@@ -183,7 +167,24 @@ macOS:
 Linux:
   - Ubuntu/Debian: sudo apt-get install p7zip-full
   - CentOS/RHEL: sudo yum install p7zip
-  - Arch Linux: sudo pacman -S p7zip`)
+  - Arch Linux: sudo pacman -S p7zip`, ErrToolNotFound))
+}
+
+// Create7zArchive creates a 7z archive from the source directory.
+// Files matching opts.StoreExtensions are added in a second pass without compression.
+func Create7zArchive(sourceDir, archivePath string, opts ArchiveOptions) error {
+	if opts.Reproducible {
+		// Name every entry so the order doesn't depend on how the file system lists them
+		entries, err := archiveEntries(sourceDir)
+		if err != nil {
+			return err
+		}
+		return Create7zArchiveFromList(sourceDir, entries, archivePath, opts)
+	}
+
+	cmd, err := find7zCommand()
+	if err != nil {
+		return err
 	}
 
 	// Convert to absolute paths to avoid issues with directory changes
@@ -197,31 +198,247 @@ Linux:
 		return fmt.Errorf("getting absolute path for archive: %w", err)
 	}
 
-	// Build command arguments for maximum compression
+	// Build command arguments for the configured compression level
 	// We use "." to archive everything in the current directory (after cd)
-	args := []string{
-		"a",            // add to archive
-		"-t7z",         // archive type 7z
-		"-mx=9",        // maximum compression level
-		"-mfb=64",      // number of fast bytes for LZMA
-		"-md=32m",      // dictionary size
-		"-ms=on",       // solid archive for better compression
+	args := append([]string{"a", "-t7z"}, compressionArgs(opts)...)
+	args = append(args, opts.ExtraArgs...)
+	args = append(args, excludeArgs(opts.StoreExtensions)...)
+	args = append(args,
 		absArchivePath, // output archive path (absolute)
 		".",            // source files (current directory contents)
+	)
+
+	progress := fileSizes(absArchivePath, absArchivePath+".tmp")
+	if err := run7z(cmd, args, absSourceDir, progress); err != nil {
+		return err
+	}
+
+	storedFiles, err := findFilesWithExtensions(absSourceDir, opts.StoreExtensions)
+	if err != nil {
+		return err
+	}
+	if len(storedFiles) == 0 {
+		return nil
+	}
+
+	// Add already-compressed files with the copy method, listed in a temporary list file
+	listFile, err := write7zListFile(storedFiles)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(listFile)
+
+	args = append([]string{"a", "-t7z"}, storeArgs(opts)...)
+	return run7z(cmd, append(args, absArchivePath, "@"+listFile), absSourceDir, progress)
+}
+
+// Create7zArchiveFromList creates a 7z archive of only the given files, which are
+// relative to sourceDir. Files matching opts.StoreExtensions are stored uncompressed.
+func Create7zArchiveFromList(sourceDir string, files []string, archivePath string, opts ArchiveOptions) error {
+	cmd, err := find7zCommand()
+	if err != nil {
+		return err
+	}
+
+	absSourceDir, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return fmt.Errorf("getting absolute path for source directory: %w", err)
+	}
+	absArchivePath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return fmt.Errorf("getting absolute path for archive: %w", err)
+	}
+
+	if opts.Reproducible {
+		files = append([]string(nil), files...)
+		sort.Slice(files, func(i, j int) bool { return filepath.ToSlash(files[i]) < filepath.ToSlash(files[j]) })
+	}
+
+	var compressed, stored []string
+	for _, file := range files {
+		if hasAnyExtension(file, opts.StoreExtensions) {
+			stored = append(stored, file)
+		} else {
+			compressed = append(compressed, file)
+		}
+	}
+
+	passes := []struct {
+		files []string
+		args  []string
+	}{
+		{compressed, append(compressionArgs(opts), opts.ExtraArgs...)},
+		{stored, storeArgs(opts)},
+	}
+	for _, pass := range passes {
+		if len(pass.files) == 0 {
+			continue
+		}
+		listFile, err := write7zListFile(pass.files)
+		if err != nil {
+			return err
+		}
+		args := append([]string{"a", "-t7z"}, pass.args...)
+		if opts.Reproducible {
+			args = append(args, reproducibleArgs...)
+		}
+		err = run7z(cmd, append(args, absArchivePath, "@"+listFile), absSourceDir, fileSizes(absArchivePath, absArchivePath+".tmp"))
+		os.Remove(listFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write7zListFile writes paths to a temporary 7z list file and returns its name
+func write7zListFile(paths []string) (string, error) {
+	listFile, err := os.CreateTemp("", "rom-organizer-list-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("creating 7z list file: %w", err)
+	}
+
+	_, err = listFile.WriteString(strings.Join(paths, "\n") + "\n")
+	if closeErr := listFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(listFile.Name())
+		return "", fmt.Errorf("writing 7z list file: %w", err)
+	}
+	return listFile.Name(), nil
+}
+
+// hasAnyExtension reports whether name ends in one of the extensions, matched the
+// same way as the 7z exclude switches built by excludeArgs
+func hasAnyExtension(name string, extensions []string) bool {
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, strings.ToLower(ext)) || strings.HasSuffix(name, strings.ToUpper(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeArgs returns the 7z switches for the pass adding files without compression: the
+// extra switches, which may set a password or threads, then -mx=0 so they can't change
+// the method
+func storeArgs(opts ArchiveOptions) []string {
+	return append(append([]string{}, opts.ExtraArgs...), "-mx=0")
+}
+
+// compressionArgs returns the 7z switches for the compression level, solid block
+// and dictionary size
+func compressionArgs(opts ArchiveOptions) []string {
+	if opts.Level <= 0 {
+		return []string{"-mx=0"} // store only
+	}
+
+	args := []string{fmt.Sprintf("-mx=%d", opts.Level)}
+	dictionary := opts.Dictionary
+	if opts.Level >= 9 {
+		args = append(args, "-mfb=64") // number of fast bytes for LZMA
+		if dictionary == "" {
+			dictionary = "32m"
+		}
+	}
+	if dictionary != "" {
+		args = append(args, "-md="+strings.ToLower(dictionary))
+	}
+	solid := opts.SolidBlock
+	if solid == "" {
+		solid = "on" // solid archive for better compression
+	}
+	return append(args, "-ms="+strings.ToLower(solid))
+}
+
+// excludeArgs returns recursive 7z exclude switches for the given extensions
+func excludeArgs(extensions []string) []string {
+	var args []string
+	for _, ext := range extensions {
+		args = append(args, "-xr!*"+strings.ToLower(ext), "-xr!*"+strings.ToUpper(ext))
+	}
+	return args
+}
+
+// reproducibleArgs leave out everything that varies between runs of the same 7z version:
+// file times, and the block splitting of multithreaded LZMA2
+var reproducibleArgs = []string{"-mtm=off", "-mtc=off", "-mta=off", "-mmt=1"}
+
+// archiveEntries returns the paths, relative to root, of the files and empty
+// directories under root
+func archiveEntries(root string) ([]string, error) {
+	var entries []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		if info.IsDir() {
+			children, err := os.ReadDir(path)
+			if err != nil || len(children) > 0 {
+				return err
+			}
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	return entries, nil
+}
+
+// findFilesWithExtensions returns the paths, relative to root, of files matching the
+// 7z exclude switches built by excludeArgs
+func findFilesWithExtensions(root string, extensions []string) ([]string, error) {
+	if len(extensions) == 0 {
+		return nil, nil
 	}
 
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if hasAnyExtension(info.Name(), extensions) {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// run7z runs a 7z command in dir and reports its output on failure. With a progress
+// function (the size of the archive being written) it runs under the Watchdog.
+func run7z(cmd string, args []string, dir string, progress func() int64) error {
+	ui.Debugf("Running: %s %s (in %s)\n", cmd, strings.Join(args, " "), dir)
+
 	execCmd := exec.Command(cmd, args...)
 
 	// Change working directory to source directory
 	// This ensures only the contents are archived, not the directory name
-	execCmd.Dir = absSourceDir
+	execCmd.Dir = dir
 
 	// Capture output for debugging
 	var stdout, stderr bytes.Buffer
 	execCmd.Stdout = &stdout
 	execCmd.Stderr = &stderr
 
-	if err := execCmd.Run(); err != nil {
+	if err := RunWatched(execCmd, "creating "+filepath.Base(args[len(args)-2]), progress); err != nil {
+		if errors.Is(err, ErrStalled) || errors.Is(err, ErrInterrupted) {
+			return err
+		}
 		return fmt.Errorf(`7z command failed: %w
 
 Command: %s %s
@@ -233,14 +450,58 @@ This usually indicates:
 1. The source directory is empty or doesn't exist
 2. Permission issues with the source or destination
 3. Insufficient disk space for the archive`,
-			err, cmd, strings.Join(args, " "), absSourceDir, stdout.String(), stderr.String())
+			err, cmd, strings.Join(args, " "), dir, stdout.String(), stderr.String())
 	}
 
 	return nil
 }
 
-// CopyDir copies the contents of one directory to another
+// RunWatched runs an external command, under the Watchdog when progress is given. A
+// stalled command is killed when the Watchdog stops it, and an interrupted one fails
+// with ErrInterrupted.
+func RunWatched(execCmd *exec.Cmd, name string, progress func() int64) error {
+	exited, err := startCommand(execCmd)
+	if err != nil {
+		return err
+	}
+	defer exited()
+	if progress == nil {
+		err = execCmd.Wait()
+	} else {
+		err = Watchdog.Run(name, progress, execCmd.Wait, func() { execCmd.Process.Kill() })
+	}
+	if err != nil && AbortRequested() {
+		return fmt.Errorf("%s: %w", name, ErrInterrupted)
+	}
+	return err
+}
+
+// copyChunk is how much of a file is copied between two checks of a watched copy
+const copyChunk = 16 << 20
+
+// CopyDir copies the contents of one directory to another, under the Watchdog
 func CopyDir(src, dest string) error {
+	progress := &copyProgress{}
+	return Watchdog.Run("copying "+filepath.Base(src), progress.bytes, func() error {
+		return copyDir(src, dest, progress, nil)
+	}, progress.stop)
+}
+
+// CopyDirHashed copies a directory like CopyDir and returns the digests of the copied
+// files, computed from the data as it is copied rather than by reading the files again
+func CopyDirHashed(src, dest string, algs ...HashAlgorithm) (FileHashes, error) {
+	progress := &copyProgress{}
+	hashes := &copyHashes{root: src, algs: algs, files: FileHashes{}}
+	err := Watchdog.Run("copying "+filepath.Base(src), progress.bytes, func() error {
+		return copyDir(src, dest, progress, hashes)
+	}, progress.stop)
+	if err != nil {
+		return nil, err
+	}
+	return hashes.files, nil
+}
+
+func copyDir(src, dest string, progress *copyProgress, hashes *copyHashes) error {
 	entries, err := os.ReadDir(src)
 	if err != nil {
 		return fmt.Errorf("reading source directory %s: %w", src, err)
@@ -259,11 +520,11 @@ func CopyDir(src, dest string) error {
 			if err := os.MkdirAll(destPath, 0755); err != nil {
 				return fmt.Errorf("creating directory %s: %w", destPath, err)
 			}
-			if err := CopyDir(srcPath, destPath); err != nil {
+			if err := copyDir(srcPath, destPath, progress, hashes); err != nil {
 				return fmt.Errorf("copying directory from %s to %s: %w", srcPath, destPath, err)
 			}
 		} else {
-			if err := CopyFile(srcPath, destPath); err != nil {
+			if err := copyFile(srcPath, destPath, progress, hashes); err != nil {
 				return fmt.Errorf("copying file from %s to %s: %w", srcPath, destPath, err)
 			}
 		}
@@ -272,8 +533,56 @@ func CopyDir(src, dest string) error {
 	return nil
 }
 
-// CopyFile copies a single file from source to destination
+// CopyFile copies a single file from source to destination, under the Watchdog
 func CopyFile(src, dest string) error {
+	progress := &copyProgress{}
+	return Watchdog.Run("copying "+filepath.Base(src), progress.bytes, func() error {
+		return copyFile(src, dest, progress, nil)
+	}, progress.stop)
+}
+
+// ReplaceFile writes data to a new file next to path and renames it over path, so a
+// file hard-linked from a dedup pool gets its own copy instead of changing the data of
+// every game sharing it, and an interrupted write leaves the old file in place
+func ReplaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// CopyFileHashed copies a single file like CopyFile and returns its digests, computed
+// from the data as it is copied
+func CopyFileHashed(src, dest string, algs ...HashAlgorithm) (map[HashAlgorithm]string, error) {
+	progress := &copyProgress{}
+	hashes := &copyHashes{root: filepath.Dir(src), algs: algs, files: FileHashes{}}
+	err := Watchdog.Run("copying "+filepath.Base(src), progress.bytes, func() error {
+		return copyFile(src, dest, progress, hashes)
+	}, progress.stop)
+	if err != nil {
+		return nil, err
+	}
+	return hashes.files[filepath.Base(src)], nil
+}
+
+// copyFile copies a file in chunks, counting them in progress and giving up once the
+// copy is stopped or interrupted. Each chunk still uses the fast copy of the OS where
+// there is one, unless the file is hashed on the way, which needs the data in memory.
+func copyFile(src, dest string, progress *copyProgress, hashes *copyHashes) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("opening source file %s: %w", src, err)
@@ -286,22 +595,51 @@ func CopyFile(src, dest string) error {
 		return fmt.Errorf("creating destination directory %s: %w", destDir, err)
 	}
 
+	// An existing file is unlinked rather than truncated: it may be a hard link into a
+	// dedup pool, whose other links must keep their data
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("replacing destination file %s: %w", dest, err)
+	}
 	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("creating destination file %s: %w", dest, err)
 	}
 	defer destFile.Close()
 
-	_, err = io.Copy(destFile, srcFile)
-	if err != nil {
-		return fmt.Errorf("copying data from %s to %s: %w", src, dest, err)
+	var out io.Writer = destFile
+	sums := hashes.start()
+	if sums != nil {
+		writers := []io.Writer{destFile}
+		for _, h := range sums {
+			writers = append(writers, h)
+		}
+		out = io.MultiWriter(writers...)
 	}
 
-	return nil
+	for {
+		if progress.stopped.Load() {
+			return fmt.Errorf("copying data from %s to %s: %w", src, dest, ErrStalled)
+		}
+		if AbortRequested() {
+			return fmt.Errorf("copying data from %s to %s: %w", src, dest, ErrInterrupted)
+		}
+		n, err := io.CopyN(out, srcFile, copyChunk)
+		progress.n.Add(n)
+		if err == io.EOF {
+			hashes.record(src, sums)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("copying data from %s to %s: %w", src, dest, err)
+		}
+	}
 }
 
-// DetectOrganizedDirectory checks if a directory is already organized and determines its format
-func DetectOrganizedDirectory(sourcePath string, verbose bool) (*OrganizedDirInfo, error) {
+// DetectOrganizedDirectory checks if a directory is already organized and determines its format.
+// The updates and DLC folder names are taken from the given layout.
+func DetectOrganizedDirectory(sourcePath string, layout Layout, verbose bool) (*OrganizedDirInfo, error) {
+	layout = layout.WithDefaults()
+
 	sourceName := filepath.Base(sourcePath)
 
 	// Check if this looks like an organized game directory
@@ -313,8 +651,8 @@ func DetectOrganizedDirectory(sourcePath string, verbose bool) (*OrganizedDirInf
 	// Check if it has the expected subdirectories
 	gameFile := filepath.Join(sourcePath, "game.7z")
 	gameDir := filepath.Join(sourcePath, "game")
-	updatesDir := filepath.Join(sourcePath, "_updates")
-	dlcDir := filepath.Join(sourcePath, "_dlc")
+	updatesDir := filepath.Join(sourcePath, layout.UpdatesDir)
+	dlcDir := filepath.Join(sourcePath, layout.DLCDir)
 
 	hasCompressed := false
 	if _, err := os.Stat(gameFile); err == nil {
@@ -336,7 +674,7 @@ func DetectOrganizedDirectory(sourcePath string, verbose bool) (*OrganizedDirInf
 		hasDLC = true
 	}
 
-	// Must have either game.7z or game/ directory, plus _updates and _dlc to be considered organized
+	// Must have either game.7z or game/ directory, plus the updates and DLC folders to be considered organized
 	isOrganized := (hasCompressed || hasDecompressed) && hasUpdates && hasDLC
 
 	if !isOrganized {
@@ -344,12 +682,12 @@ func DetectOrganizedDirectory(sourcePath string, verbose bool) (*OrganizedDirInf
 	}
 
 	if verbose {
-		fmt.Printf("Detected organized game directory: %s\n", sourcePath)
+		ui.Verbosef("Detected organized game directory: %s\n", sourcePath)
 		if hasCompressed {
-			fmt.Printf("  Format: Compressed (game.7z)\n")
+			ui.Verbosef("  Format: Compressed (game.7z)\n")
 		}
 		if hasDecompressed {
-			fmt.Printf("  Format: Decompressed (game/ folder)\n")
+			ui.Verbosef("  Format: Decompressed (game/ folder)\n")
 		}
 	}
 
@@ -402,7 +740,7 @@ func DetectOrganizedDirectory(sourcePath string, verbose bool) (*OrganizedDirInf
 
 // extractGameInfoFromParamSFO extracts game info from a PARAM.SFO file
 func extractGameInfoFromParamSFO(paramSFOPath string) (*GameInfo, error) {
-	paramSFOData, err := os.ReadFile(paramSFOPath)
+	paramSFOData, err := parsers.ReadParamSFOFile(paramSFOPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading PARAM.SFO: %w", err)
 	}
@@ -432,33 +770,39 @@ func extractGameInfoFromParamSFO(paramSFOPath string) (*GameInfo, error) {
 
 // Extract7zArchive extracts a 7z archive to the specified destination
 func Extract7zArchive(archivePath, destDir string) error {
-	// Check for available 7z commands
-	possibleCommands := []string{"7z", "7za", "7zr"}
-	var cmd string
+	return extract7z(archivePath, destDir, "")
+}
 
-	for _, cmdName := range possibleCommands {
-		if _, err := exec.LookPath(cmdName); err == nil {
-			cmd = cmdName
-			break
-		}
+// Read7zFile returns the contents of one file in a 7z archive, named by its path inside
+// the archive (e.g. "PS3_GAME/PARAM.SFO"), without extracting anything to disk
+func Read7zFile(archivePath, name string) ([]byte, error) {
+	cmd, err := find7zCommand()
+	if err != nil {
+		return nil, err
 	}
 
-	if cmd == "" {
-		return fmt.Errorf(`7z command not found in PATH. Please install 7-zip or p7zip:
-
-Windows:
-  - Download and install 7-Zip from https://www.7-zip.org/
-  - Or install via chocolatey: choco install 7zip
-  - Or install via winget: winget install 7zip.7zip
-
-macOS:
-  - Install via Homebrew: brew install p7zip
-  - Or install via MacPorts: sudo port install p7zip
+	args := []string{"e", "-so", "-p", archivePath, name}
+	ui.Debugf("Running: %s %s\n", cmd, strings.Join(args, " "))
+	var stdout, stderr bytes.Buffer
+	execCmd := exec.Command(cmd, args...)
+	execCmd.Stdout = &stdout
+	execCmd.Stderr = &stderr
+	if err := execCmd.Run(); err != nil {
+		return nil, fmt.Errorf("reading %s from %s: %w: %s", name, archivePath, err, strings.TrimSpace(stderr.String()))
+	}
+	// 7z succeeds without output when the archive has no such file
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s not found in %s", name, archivePath)
+	}
+	return stdout.Bytes(), nil
+}
 
-Linux:
-  - Ubuntu/Debian: sudo apt-get install p7zip-full
-  - CentOS/RHEL: sudo yum install p7zip
-  - Arch Linux: sudo pacman -S p7zip`)
+// extract7z extracts an archive with 7z. The password is always passed, empty for
+// unencrypted archives, so 7z never waits for one on stdin.
+func extract7z(archivePath, destDir, password string) error {
+	cmd, err := find7zCommand()
+	if err != nil {
+		return err
 	}
 
 	// Build command arguments for extraction
@@ -467,16 +811,24 @@ Linux:
 		archivePath,    // source archive
 		"-o" + destDir, // output directory (note: no space between -o and path)
 		"-y",           // assume yes for all prompts
+		excludeMetadataArg,
 	}
 
-	execCmd := exec.Command(cmd, args...)
+	ui.Debugf("Running: %s %s\n", cmd, strings.Join(args, " "))
+	execCmd := exec.Command(cmd, append(args, "-p"+password)...)
 
 	// Capture output for debugging
 	var stdout, stderr bytes.Buffer
 	execCmd.Stdout = &stdout
 	execCmd.Stderr = &stderr
 
-	if err := execCmd.Run(); err != nil {
+	if err := RunWatched(execCmd, "extracting "+filepath.Base(archivePath), dirProgress(destDir)); err != nil {
+		if errors.Is(err, ErrStalled) || errors.Is(err, ErrInterrupted) {
+			return err
+		}
+		if strings.Contains(strings.ToLower(stdout.String()+stderr.String()), "wrong password") {
+			return fmt.Errorf("%w for %s", ErrWrongPassword, archivePath)
+		}
 		return fmt.Errorf(`7z extraction failed: %w
 
 Command: %s %s
@@ -490,9 +842,21 @@ This usually indicates:
 			err, cmd, strings.Join(args, " "), stdout.String(), stderr.String())
 	}
 
+	// Archives made from read-only media keep its permissions; the extracted copy should be writable
+	if err := EnsureWritable(destDir); err != nil {
+		return fmt.Errorf("making extracted files writable: %w", err)
+	}
 	return nil
 }
 
+// ShellCommand returns a command that runs a user-supplied command line through the system shell
+func ShellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
 // MoveDir moves the contents of one directory to another, then removes the source
 func MoveDir(src, dest string) error {
 	// First copy everything
@@ -509,71 +873,107 @@ func MoveDir(src, dest string) error {
 }
 
 // MoveDirWithCleanup moves the contents of one directory to another and handles cleanup
-func MoveDirWithCleanup(src, dest string, force bool, verbose bool) error {
+func MoveDirWithCleanup(src, dest string, force bool) error {
 	// First copy everything
 	if err := CopyDir(src, dest); err != nil {
 		return fmt.Errorf("copying directory: %w", err)
 	}
 
-	// Check if source directory is empty or contains only empty directories
-	isEmpty, err := IsDirEffectivelyEmpty(src)
+	// Check if source directory contains anything besides junk files and empty directories
+	leftovers, err := LeftoverFiles(src, DefaultJunkFiles)
 	if err != nil {
 		return fmt.Errorf("checking if source directory is empty: %w", err)
 	}
 
-	if isEmpty {
-		// Safe to remove - directory is empty or contains only empty subdirectories
-		if verbose {
-			fmt.Printf("Removing empty source directory: %s\n", src)
-		}
+	if len(leftovers) == 0 {
+		// Safe to remove - directory is empty or contains only junk and empty subdirectories
+		ui.Verbosef("Removing empty source directory: %s\n", src)
 		if err := os.RemoveAll(src); err != nil {
 			return fmt.Errorf("removing empty source directory: %w", err)
 		}
 	} else {
 		// Directory contains files - check if force is enabled
 		if force {
-			if verbose {
-				fmt.Printf("⚠️  Forcefully removing source directory with remaining files: %s\n", src)
-			}
+			ui.Verbosef("⚠️  Forcefully removing source directory with remaining files: %s\n", src)
 			if err := os.RemoveAll(src); err != nil {
 				return fmt.Errorf("forcefully removing source directory: %w", err)
 			}
 		} else {
-			fmt.Printf("⚠️  WARNING: Source directory contains remaining files and was not deleted: %s\n", src)
-			fmt.Printf("    Use --force to delete the source directory even with remaining files\n")
+			ReportLeftovers(src, leftovers)
 		}
 	}
 
 	return nil
 }
 
-// IsDirEffectivelyEmpty checks if a directory contains any files (ignores empty directories)
-func IsDirEffectivelyEmpty(dirPath string) (bool, error) {
-	var hasFiles bool
+// DefaultJunkFiles are file name patterns left behind by file managers and OSes,
+// which don't keep a source directory from counting as empty
+var DefaultJunkFiles = []string{"Thumbs.db", "desktop.ini", ".DS_Store", "._*", ".directory"}
+
+// IsJunkFile reports whether a file name matches one of the junk patterns,
+// ignoring case since these files come from case-insensitive file systems
+func IsJunkFile(name string, junk []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range junk {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
 
+// IsDirEffectivelyEmpty checks if a directory contains any files besides junk files
+// (ignores empty directories)
+func IsDirEffectivelyEmpty(dirPath string, junk []string) (bool, error) {
+	leftovers, err := LeftoverFiles(dirPath, junk)
+	return len(leftovers) == 0, err
+}
+
+// LeftoverFiles returns the paths, relative to dirPath, of files that keep it from
+// being effectively empty: everything except junk files and directories
+func LeftoverFiles(dirPath string, junk []string) ([]string, error) {
+	var leftovers []string
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-
-		// Skip the root directory itself
-		if path == dirPath {
+		if info.IsDir() || IsJunkFile(info.Name(), junk) {
 			return nil
 		}
-
-		// If we find any file, the directory is not empty
-		if !info.IsDir() {
-			hasFiles = true
-			return filepath.SkipDir // Stop walking once we find a file
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
 		}
-
+		leftovers = append(leftovers, rel)
 		return nil
 	})
-
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	return leftovers, nil
+}
+
+// ReportLeftovers warns that a source directory was kept and lists, in verbose
+// output, the files that blocked its deletion
+func ReportLeftovers(dirPath string, leftovers []string) {
+	ui.Warnf("Source directory contains %d remaining files and was not deleted: %s\n", len(leftovers), dirPath)
+	for _, file := range leftovers {
+		ui.Verbosef("  Blocking deletion: %s\n", file)
+	}
+	ui.Warnf("Use --force to delete the source directory even with remaining files\n")
+}
 
-	// Directory is effectively empty if no files were found
-	return !hasFiles, nil
+// existingParent returns path, or its nearest ancestor that exists
+func existingParent(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
 }
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// ErrStalled means an operation made no progress for the watchdog's stall timeout and
+// was stopped. It isn't permanent, so a retry policy retries it.
+var ErrStalled = errors.New("stalled: no progress")
+
+// WatchdogOptions controls how long-running 7z runs and copies report that they are
+// still working, and what happens when their I/O stops making progress (e.g. a USB
+// enclosure that hangs)
+type WatchdogOptions struct {
+	Heartbeat    time.Duration `yaml:"heartbeat"`     // Interval of "still working" messages (0 disables)
+	StallTimeout time.Duration `yaml:"stall_timeout"` // No progress for this long is a stall (0 disables)
+	KillStalled  bool          `yaml:"kill_stalled"`  // Stop a stalled operation, failing it with ErrStalled
+}
+
+// DefaultWatchdog reports every minute and warns after 10 minutes without progress
+func DefaultWatchdog() WatchdogOptions {
+	return WatchdogOptions{Heartbeat: time.Minute, StallTimeout: 10 * time.Minute}
+}
+
+// Watchdog is applied to every 7z run, zip extraction and directory or file copy
+var Watchdog = DefaultWatchdog()
+
+// Validate checks the durations
+func (w WatchdogOptions) Validate() error {
+	if w.Heartbeat < 0 || w.StallTimeout < 0 {
+		return fmt.Errorf("watchdog durations must not be negative")
+	}
+	if w.KillStalled && w.StallTimeout == 0 {
+		return fmt.Errorf("watchdog kill_stalled needs a stall_timeout")
+	}
+	return nil
+}
+
+// maxWatchdogTick is the longest interval between two progress checks
+const maxWatchdogTick = 10 * time.Second
+
+// Run runs op, checking progress (bytes processed so far) periodically to print a
+// heartbeat and detect stalls; name reads like "copying Title" in the messages. On a
+// stall with KillStalled, stop is called and Run returns ErrStalled without waiting
+// further for op, which may be stuck in the kernel.
+func (w WatchdogOptions) Run(name string, progress func() int64, op func() error, stop func()) error {
+	if w.Heartbeat <= 0 && w.StallTimeout <= 0 {
+		return op()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- op() }()
+
+	// Check twice per interval so messages come no later than half an interval late
+	tick := maxWatchdogTick
+	for _, interval := range []time.Duration{w.Heartbeat, w.StallTimeout} {
+		if interval > 0 && interval/2 < tick {
+			tick = interval / 2
+		}
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	lastBeat, lastChange := start, start
+	last := progress()
+	stalled := false
+	for {
+		select {
+		case err := <-done:
+			return err
+		case now := <-ticker.C:
+			current := progress()
+			if current != last {
+				if stalled {
+					ui.Infof("    Progress resumed %s\n", name)
+				}
+				last, lastChange, stalled = current, now, false
+			}
+
+			if w.StallTimeout > 0 && !stalled && now.Sub(lastChange) >= w.StallTimeout {
+				stalled = true
+				if w.KillStalled && stop != nil {
+					ui.Warnf("No progress %s for %s at %s; stopping it\n", name, w.StallTimeout, FormatSize(current))
+					stop()
+					return fmt.Errorf("%s: %w for %s", name, ErrStalled, w.StallTimeout)
+				}
+				ui.Warnf("No progress %s for %s at %s (still waiting; --kill-stalled stops it)\n", name, w.StallTimeout, FormatSize(current))
+			}
+
+			if w.Heartbeat > 0 && now.Sub(lastBeat) >= w.Heartbeat {
+				lastBeat = now
+				ui.Infof("    Still %s: %s processed, %s elapsed\n", name, FormatSize(current), now.Sub(start).Round(time.Second))
+			}
+		}
+	}
+}
+
+// fileSizes returns the progress of an operation writing the given files: their
+// combined size, counting those that don't exist yet as empty
+func fileSizes(paths ...string) func() int64 {
+	return func() int64 {
+		var total int64
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil {
+				total += info.Size()
+			}
+		}
+		return total
+	}
+}
+
+// dirProgress returns the progress of an operation writing into a directory: the size
+// of what is in it
+func dirProgress(dir string) func() int64 {
+	return func() int64 {
+		size, _ := DirSize(dir)
+		return size
+	}
+}
+
+// copyProgress counts the bytes of a copy and stops it when the watchdog says so
+type copyProgress struct {
+	n       atomic.Int64
+	stopped atomic.Bool
+}
+
+func (p *copyProgress) bytes() int64 { return p.n.Load() }
+func (p *copyProgress) stop()        { p.stopped.Store(true) }
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// XXH64 primes
+const (
+	xxhPrime1 uint64 = 11400714785074694791
+	xxhPrime2 uint64 = 14029467366897019727
+	xxhPrime3 uint64 = 1609587929392839161
+	xxhPrime4 uint64 = 9650029242287828579
+	xxhPrime5 uint64 = 2870177450012600261
+)
+
+// xxh64 is a streaming XXH64 hash with seed 0, as written by xxhsum
+type xxh64 struct {
+	v1, v2, v3, v4 uint64
+	total          uint64
+	buf            [32]byte
+	n              int // Bytes buffered in buf
+}
+
+// NewXXH64 returns an XXH64 hash. Sum writes the digest big-endian, the canonical
+// form printed by xxhsum.
+func NewXXH64() hash.Hash64 {
+	h := &xxh64{}
+	h.Reset()
+	return h
+}
+
+func (h *xxh64) Reset() {
+	prime1, prime2 := xxhPrime1, xxhPrime2 // Variables, so the seed arithmetic wraps
+	h.v1 = prime1 + prime2
+	h.v2 = prime2
+	h.v3 = 0
+	h.v4 = -prime1
+	h.total = 0
+	h.n = 0
+}
+
+func (h *xxh64) Size() int      { return 8 }
+func (h *xxh64) BlockSize() int { return 32 }
+
+func (h *xxh64) Write(p []byte) (int, error) {
+	written := len(p)
+	h.total += uint64(len(p))
+
+	if h.n > 0 {
+		copied := copy(h.buf[h.n:], p)
+		h.n += copied
+		p = p[copied:]
+		if h.n < 32 {
+			return written, nil
+		}
+		h.stripe(h.buf[:])
+		h.n = 0
+	}
+	for len(p) >= 32 {
+		h.stripe(p)
+		p = p[32:]
+	}
+	h.n = copy(h.buf[:], p)
+	return written, nil
+}
+
+// stripe consumes 32 bytes into the four accumulators
+func (h *xxh64) stripe(p []byte) {
+	h.v1 = xxhRound(h.v1, binary.LittleEndian.Uint64(p[0:]))
+	h.v2 = xxhRound(h.v2, binary.LittleEndian.Uint64(p[8:]))
+	h.v3 = xxhRound(h.v3, binary.LittleEndian.Uint64(p[16:]))
+	h.v4 = xxhRound(h.v4, binary.LittleEndian.Uint64(p[24:]))
+}
+
+func (h *xxh64) Sum64() uint64 {
+	var sum uint64
+	if h.total >= 32 {
+		sum = bits.RotateLeft64(h.v1, 1) + bits.RotateLeft64(h.v2, 7) +
+			bits.RotateLeft64(h.v3, 12) + bits.RotateLeft64(h.v4, 18)
+		sum = xxhMerge(sum, h.v1)
+		sum = xxhMerge(sum, h.v2)
+		sum = xxhMerge(sum, h.v3)
+		sum = xxhMerge(sum, h.v4)
+	} else {
+		sum = xxhPrime5
+	}
+	sum += h.total
+
+	p := h.buf[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		sum ^= xxhRound(0, binary.LittleEndian.Uint64(p))
+		sum = bits.RotateLeft64(sum, 27)*xxhPrime1 + xxhPrime4
+	}
+	if len(p) >= 4 {
+		sum ^= uint64(binary.LittleEndian.Uint32(p)) * xxhPrime1
+		sum = bits.RotateLeft64(sum, 23)*xxhPrime2 + xxhPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		sum ^= uint64(b) * xxhPrime5
+		sum = bits.RotateLeft64(sum, 11) * xxhPrime1
+	}
+
+	sum ^= sum >> 33
+	sum *= xxhPrime2
+	sum ^= sum >> 29
+	sum *= xxhPrime3
+	sum ^= sum >> 32
+	return sum
+}
+
+func (h *xxh64) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, h.Sum64())
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * xxhPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxhPrime1
+}
+
+func xxhMerge(acc, val uint64) uint64 {
+	acc ^= xxhRound(0, val)
+	return acc*xxhPrime1 + xxhPrime4
+}
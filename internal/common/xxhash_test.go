@@ -0,0 +1,56 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestXXH64(t *testing.T) {
+	var long bytes.Buffer
+	for i := 0; i < 4; i++ {
+		for b := 0; b < 256; b++ {
+			long.WriteByte(byte(b))
+		}
+	}
+	long.WriteString("xyz1234")
+
+	// Canonical digests, as xxhsum prints them
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{nil, "ef46db3751d8e999"},
+		{[]byte("a"), "d24ec4f1a98c6e5b"},
+		{[]byte("abc"), "44bc2cf5ad770999"},
+		{[]byte("message digest"), "066ed728fceeb3be"},
+		{[]byte("abcdefghijklmnopqrstuvwxyz"), "cfe1f278fa89835c"},
+		{long.Bytes(), "2adf0fd0c00e492d"}, // 32 stripes, then 4 and 3 bytes
+	}
+	for _, tt := range tests {
+		// One write, and writes that split stripes
+		for _, chunk := range []int{len(tt.data) + 1, 1, 7, 33} {
+			h := NewXXH64()
+			for rest := tt.data; len(rest) > 0; {
+				n := min(chunk, len(rest))
+				h.Write(rest[:n])
+				rest = rest[n:]
+			}
+			if got := fmt.Sprintf("%x", h.Sum(nil)); got != tt.want {
+				t.Errorf("XXH64 of %d bytes in %d-byte writes = %s, want %s", len(tt.data), chunk, got, tt.want)
+			}
+			if got := fmt.Sprintf("%016x", h.Sum64()); got != tt.want {
+				t.Errorf("Sum64 of %d bytes = %s, want %s", len(tt.data), got, tt.want)
+			}
+		}
+	}
+
+	// Reset starts over
+	h := NewXXH64()
+	h.Write([]byte("something else"))
+	h.Reset()
+	h.Write([]byte("abc"))
+	if got := fmt.Sprintf("%x", h.Sum(nil)); got != "44bc2cf5ad770999" {
+		t.Errorf("after Reset: %s", got)
+	}
+}
@@ -0,0 +1,267 @@
+package common
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// maxSymlinkTarget bounds the target read from a symbolic link entry
+const maxSymlinkTarget = 4096
+
+// ZipOptions controls what ExtractZip keeps of an archive besides the file contents
+type ZipOptions struct {
+	Modes    bool // Apply the stored permissions instead of 0644 for files and 0755 for folders
+	Symlinks bool // Recreate symbolic links instead of skipping them
+}
+
+// ZipExtraction is what ExtractArchive keeps of input zips. Set from --zip-modes and
+// --zip-symlinks.
+var ZipExtraction ZipOptions
+
+// ExtractZip extracts a ZIP archive to the specified destination, under the Watchdog.
+// Zip64 archives (files over 4 GB, more than 65535 entries) are read like any other, and
+// each file is streamed to disk in chunks and checked against its stored size and CRC-32,
+// so memory use doesn't grow with the archive. ZipExtraction decides what is kept of
+// modes and symbolic links.
+func ExtractZip(src, dest string) error {
+	progress := &copyProgress{}
+	return Watchdog.Run("extracting "+filepath.Base(src), progress.bytes, func() error {
+		r, err := zip.OpenReader(src)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return extractZip(&r.Reader, filepath.Base(src), dest, ZipExtraction, progress)
+	}, progress.stop)
+}
+
+// zipDir is a folder whose stored mode is applied once everything in it is extracted, so
+// a read-only folder doesn't keep its own files out
+type zipDir struct {
+	path string
+	mode os.FileMode
+}
+
+func extractZip(r *zip.Reader, name, dest string, options ZipOptions, progress *copyProgress) error {
+	var total uint64
+	for _, f := range r.File {
+		total += f.UncompressedSize64
+	}
+	ui.Verbosef("Extracting %d entries (%s) from %s\n", len(r.File), FormatSize(int64(total)), name)
+
+	var dirs []zipDir
+	var links []*zip.File
+	skipped := 0
+	for _, f := range r.File {
+		path, err := zipEntryPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("creating directory %s: %w", path, err)
+			}
+			if options.Modes {
+				dirs = append(dirs, zipDir{path, mode.Perm() | 0700})
+			}
+		case mode&os.ModeSymlink != 0:
+			// Links are made last, so no entry is ever written through one
+			if options.Symlinks {
+				links = append(links, f)
+			} else {
+				skipped++
+			}
+		default:
+			if err := extractZipFile(f, path, options, progress); err != nil {
+				return err
+			}
+		}
+	}
+
+	// A link refused leaves none made, so none can lead out while dest is still around
+	paths := make([]string, 0, len(links))
+	for _, f := range links {
+		path, err := extractZipLink(f, dest)
+		if err == nil {
+			paths = append(paths, path)
+			continue
+		}
+		removeLinks(paths)
+		return err
+	}
+	if err := checkZipLinks(dest, paths); err != nil {
+		removeLinks(paths)
+		return err
+	}
+	if skipped > 0 {
+		ui.Warnf("Skipped %d symbolic links in %s (--zip-symlinks keeps them)\n", skipped, name)
+	}
+
+	// Deepest folders first, so a folder is still writable while its subfolders change
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return fmt.Errorf("setting mode of %s: %w", dirs[i].path, err)
+		}
+	}
+	return nil
+}
+
+// zipEntryPath returns where an entry is extracted, refusing names that would land
+// outside dest (absolute paths, ".." or, on Windows, drive and reserved names)
+func zipEntryPath(dest, name string) (string, error) {
+	local := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("invalid file path: %s", name)
+	}
+	return filepath.Join(dest, local), nil
+}
+
+// extractZipFile streams a file entry to path in chunks, counting them in progress. The
+// zip reader checks the size and CRC-32 once the entry is read to the end.
+func extractZipFile(f *zip.File, path string, options ZipOptions, progress *copyProgress) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", filepath.Dir(path), err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("creating file %s: %w", path, err)
+	}
+	defer out.Close()
+
+	for {
+		if progress.stopped.Load() {
+			return fmt.Errorf("extracting %s: %w", f.Name, ErrStalled)
+		}
+		if AbortRequested() {
+			return fmt.Errorf("extracting %s: %w", f.Name, ErrInterrupted)
+		}
+		n, err := io.CopyN(out, rc, copyChunk)
+		progress.n.Add(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("extracting %s: %w", f.Name, err)
+		}
+	}
+
+	// The stored mode is applied as is, which the umask would trim at creation
+	if options.Modes {
+		if err := out.Chmod(f.Mode().Perm() | 0600); err != nil {
+			return fmt.Errorf("setting mode of %s: %w", path, err)
+		}
+	}
+	return out.Close()
+}
+
+// extractZipLink recreates a symbolic link entry, whose contents are its target, and
+// returns its path. Targets that lead out of the archive as written are refused here;
+// checkZipLinks catches those that get out through other links.
+func extractZipLink(f *zip.File, dest string) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	target, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTarget+1))
+	rc.Close()
+	if err != nil {
+		return "", fmt.Errorf("extracting %s: %w", f.Name, err)
+	}
+	if len(target) == 0 || len(target) > maxSymlinkTarget {
+		return "", fmt.Errorf("symbolic link %s has an invalid target", f.Name)
+	}
+
+	name := filepath.FromSlash(strings.TrimSuffix(f.Name, "/"))
+	linkTarget := filepath.FromSlash(string(target))
+	if filepath.IsAbs(linkTarget) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), linkTarget)) {
+		return "", fmt.Errorf("symbolic link %s points outside the archive: %s", f.Name, target)
+	}
+
+	// Folders were all made before the links, so only a link made earlier can be on the way
+	if err := checkNoZipLink(dest, filepath.Dir(name)); err != nil {
+		return "", fmt.Errorf("symbolic link %s: %w", f.Name, err)
+	}
+	path := filepath.Join(dest, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating directory %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.Symlink(linkTarget, path); err != nil {
+		return "", fmt.Errorf("creating symbolic link %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// checkNoZipLink refuses a folder below dest that goes through a symbolic link, where
+// a link or folder made in it could end up outside dest
+func checkNoZipLink(dest, rel string) error {
+	p := dest
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symbolic link", part)
+		}
+	}
+	return nil
+}
+
+// checkZipLinks checks, once all links of an archive exist, that each resolves to
+// something inside dest. A link can only get out through another, so this waits for
+// every link to be made; one leading nowhere is refused too, as what it leads to can't
+// be checked.
+func checkZipLinks(dest string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			if rel, err := filepath.Rel(root, resolved); err == nil && filepath.IsLocal(rel) {
+				continue
+			}
+		}
+		target, _ := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("symbolic link %s doesn't resolve: %s", path, target)
+		}
+		return fmt.Errorf("symbolic link %s points outside the archive: %s", path, target)
+	}
+	return nil
+}
+
+// removeLinks removes the links made by extractZipLink. None was made in another link,
+// so each is removed where it was made.
+func removeLinks(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,247 @@
+package common
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// zipEntry is a file, folder (name ending in /) or symbolic link (link set) of a test zip
+type zipEntry struct {
+	name string
+	mode os.FileMode
+	data string
+	link string
+}
+
+// testZip returns a zip archive holding the entries
+func testZip(t *testing.T, entries ...zipEntry) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Store}
+		mode, data := e.mode, e.data
+		if e.link != "" {
+			mode, data = os.ModeSymlink|0777, e.link
+		}
+		if mode != 0 {
+			header.SetMode(mode)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// extractTestZip extracts a test zip into a "dest" folder of a new temporary folder and
+// returns both
+func extractTestZip(t *testing.T, r *zip.Reader, options ZipOptions) (root, dest string, err error) {
+	t.Helper()
+	root = t.TempDir()
+	dest = filepath.Join(root, "dest")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root, dest, extractZip(r, "test.zip", dest, options, &copyProgress{})
+}
+
+// onlyDest fails the test if anything but dest was made in root
+func onlyDest(t *testing.T, root, what string) {
+	t.Helper()
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if entry.Name() != "dest" {
+			t.Errorf("%s: made %s outside the destination", what, entry.Name())
+		}
+	}
+}
+
+func TestExtractZipRefusesTraversal(t *testing.T) {
+	for _, name := range []string{"../escape", "/tmp/escape", "game/../../escape", ""} {
+		root, _, err := extractTestZip(t, testZip(t, zipEntry{name: name, data: "x"}), ZipOptions{})
+		if err == nil {
+			t.Errorf("%q: extracted without error", name)
+		}
+		onlyDest(t, root, name)
+	}
+}
+
+func TestExtractZipModes(t *testing.T) {
+	r := testZip(t,
+		zipEntry{name: "game/", mode: os.ModeDir | 0750},
+		zipEntry{name: "game/EBOOT.BIN", mode: 0755, data: "run"},
+		zipEntry{name: "game/PARAM.SFO", mode: 0640, data: "sfo"},
+		zipEntry{name: "game/locked", mode: 0400, data: "ro"},
+	)
+	tests := []struct {
+		options ZipOptions
+		want    map[string]os.FileMode
+	}{
+		{ZipOptions{}, map[string]os.FileMode{"game": 0755, "game/EBOOT.BIN": 0644, "game/PARAM.SFO": 0644, "game/locked": 0644}},
+		{ZipOptions{Modes: true}, map[string]os.FileMode{"game": 0750, "game/EBOOT.BIN": 0755, "game/PARAM.SFO": 0640, "game/locked": 0600}},
+	}
+	for _, tt := range tests {
+		_, dest, err := extractTestZip(t, r, tt.options)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.options, err)
+		}
+		for name, want := range tt.want {
+			info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Without Modes, files are made as 0644 less the umask
+			if got := info.Mode().Perm(); got != want && (tt.options.Modes || got&^want != 0) {
+				t.Errorf("%+v: %s has mode %v, want %v", tt.options, name, got, want)
+			}
+		}
+	}
+}
+
+func TestExtractZipSymlinks(t *testing.T) {
+	file := zipEntry{name: "game/PS3_GAME/PARAM.SFO", data: "sfo"}
+
+	// Without Symlinks, links are left out
+	_, dest, err := extractTestZip(t, testZip(t, file, zipEntry{name: "game/link", link: "PS3_GAME/PARAM.SFO"}), ZipOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "game", "link")); !os.IsNotExist(err) {
+		t.Errorf("link extracted without Symlinks: %v", err)
+	}
+
+	// Links inside the archive are kept, to files and folders, in any order
+	_, dest, err = extractTestZip(t, testZip(t, file,
+		zipEntry{name: "game/sfo", link: "PS3_GAME/PARAM.SFO"},
+		zipEntry{name: "game/x/up", link: "../l/PARAM.SFO"},
+		zipEntry{name: "game/l", link: "PS3_GAME"},
+	), ZipOptions{Symlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"game/sfo", "game/x/up"} {
+		if data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name))); err != nil || string(data) != "sfo" {
+			t.Errorf("%s: %q, %v", name, data, err)
+		}
+	}
+
+	refused := []struct {
+		what  string
+		links []zipEntry
+	}{
+		{"absolute", []zipEntry{{name: "game/link", link: "/etc/passwd"}}},
+		{"parent", []zipEntry{{name: "game/link", link: "../../outside"}}},
+		{"through a link", []zipEntry{{name: "l", link: "."}, {name: "a", link: "l/.."}}},
+		{"link made in a link", []zipEntry{{name: "l", link: "."}, {name: "a", link: "l/.."}, {name: "a/evil", link: "x"}}},
+		{"dangling through a link", []zipEntry{{name: "d/l", link: ".."}, {name: "d/l/l2", link: "../missing"}}},
+		{"dangling", []zipEntry{{name: "game/link", link: "missing"}}},
+		{"loop", []zipEntry{{name: "a", link: "b"}, {name: "b", link: "a"}}},
+	}
+	for _, tt := range refused {
+		root, dest, err := extractTestZip(t, testZip(t, append([]zipEntry{file}, tt.links...)...), ZipOptions{Symlinks: true})
+		if err == nil {
+			t.Errorf("%s: extracted without error", tt.what)
+		}
+		onlyDest(t, root, tt.what)
+		filepath.Walk(dest, func(p string, info os.FileInfo, err error) error {
+			if err == nil && info.Mode()&os.ModeSymlink != 0 {
+				t.Errorf("%s: link %s left behind", tt.what, p)
+			}
+			return nil
+		})
+	}
+}
+
+// TestExtractZipManyEntries checks a Zip64 archive of more entries than a classic zip
+// holds. The entries reuse a few names, so the test doesn't spend its time making files.
+func TestExtractZipManyEntries(t *testing.T) {
+	const count, names = 1<<16 + 10, 100
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < count; i++ {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("game/%d", i%names), Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte{byte(i)})
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != count {
+		t.Fatalf("read %d entries, want %d", len(r.File), count)
+	}
+
+	_, dest, err := extractTestZip(t, r, ZipOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := filepath.Join(dest, "game", fmt.Sprint((count-1)%names))
+	if data, err := os.ReadFile(last); err != nil || !bytes.Equal(data, []byte{byte((count - 1) % 256)}) {
+		t.Fatalf("last entry: %x, %v", data, err)
+	}
+}
+
+// TestExtractZipLargeFile extracts a file over 4 GB, which needs Zip64 sizes. It writes
+// that much to the temporary folder, so it only runs with ROM_ORGANIZER_LARGE_TESTS set.
+func TestExtractZipLargeFile(t *testing.T) {
+	if os.Getenv("ROM_ORGANIZER_LARGE_TESTS") == "" {
+		t.Skip("set ROM_ORGANIZER_LARGE_TESTS to extract a file over 4 GB")
+	}
+	const size = 4<<30 + 1<<20
+	path := filepath.Join(t.TempDir(), "large.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "game/USRDIR/data.psarc", Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.CopyN(w, zeros{}, size); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dest := t.TempDir()
+	if err := ExtractZip(path, dest); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dest, "game", "USRDIR", "data.psarc"))
+	if err != nil || info.Size() != size {
+		t.Fatalf("extracted %v, %v; want %d bytes", info, err, size)
+	}
+}
+
+// zeros reads as endless zero bytes
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,109 @@
+// Package compat looks up emulator compatibility status for games
+package compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultURL is the RPCS3 compatibility API export of the whole database
+const DefaultURL = "https://rpcs3.net/compatibility?api=v1&export"
+
+// RPCS3 compatibility statuses, best first
+const (
+	StatusPlayable = "Playable"
+	StatusIngame   = "Ingame"
+	StatusIntro    = "Intro"
+	StatusLoadable = "Loadable"
+	StatusNothing  = "Nothing"
+)
+
+// Statuses lists the RPCS3 statuses from best to worst
+var Statuses = []string{StatusPlayable, StatusIngame, StatusIntro, StatusLoadable, StatusNothing}
+
+// Entry is the compatibility report for one title ID
+type Entry struct {
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Date   string `json:"date"` // Date the status was last updated (YYYY-MM-DD)
+}
+
+// Database maps title IDs to their compatibility reports
+type Database map[string]Entry
+
+// apiResponse is the RPCS3 compatibility API response envelope
+type apiResponse struct {
+	ReturnCode int      `json:"return_code"`
+	Results    Database `json:"results"`
+}
+
+// ParseDatabase parses an RPCS3 compatibility API response
+func ParseDatabase(data []byte) (Database, error) {
+	var resp apiResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing RPCS3 compatibility data: %w", err)
+	}
+	if resp.ReturnCode < 0 {
+		return nil, fmt.Errorf("RPCS3 compatibility API returned error code %d", resp.ReturnCode)
+	}
+	if resp.Results == nil {
+		return nil, fmt.Errorf("RPCS3 compatibility data has no results")
+	}
+	return resp.Results, nil
+}
+
+// DefaultCachePath returns where the downloaded database is kept
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "rpcs3-compat.json"
+	}
+	return filepath.Join(dir, "rom-organizer", "rpcs3-compat.json")
+}
+
+// Download fetches the compatibility database from url and stores the raw
+// response at cachePath
+func Download(url, cachePath string) (Database, error) {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("downloading RPCS3 compatibility database: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading RPCS3 compatibility database: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading RPCS3 compatibility database: %w", err)
+	}
+
+	db, err := ParseDatabase(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return nil, fmt.Errorf("writing compatibility cache: %w", err)
+	}
+	return db, nil
+}
+
+// LoadFile reads a database saved by Download or exported from the RPCS3 API
+func LoadFile(path string) (Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading compatibility database: %w", err)
+	}
+	return ParseDatabase(data)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+)
+
+// CompressionPolicy overrides archive settings. Unset fields keep the inherited value.
+type CompressionPolicy struct {
+	Level           *int     `yaml:"level"`            // 7z level, 0 (store only) to 9 (maximum)
+	StoreExtensions []string `yaml:"store_extensions"` // Already-compressed files added without compression
+	Reproducible    *bool    `yaml:"reproducible"`     // Byte-identical archives for identical files
+	ExtraArgs       []string `yaml:"extra_args"`       // Additional 7z switches, e.g. -mqs=on
+
+	// Tuning names a solid block and dictionary preset (max, balanced, fast-access);
+	// SolidBlock and Dictionary override the preset's values
+	Tuning     *string `yaml:"tuning"`
+	SolidBlock *string `yaml:"solid_block"` // on, off or a size such as 64m
+	Dictionary *string `yaml:"dictionary"`  // LZMA dictionary size such as 16m
+}
+
+// CompressionConfig holds the default compression policy and per-console overrides
+type CompressionConfig struct {
+	Default  CompressionPolicy            `yaml:"default"`
+	Consoles map[string]CompressionPolicy `yaml:"consoles"` // Keyed by console short name (e.g. "ps3")
+
+	// SevenZip is the 7z executable to run (a path, or a name in PATH such as 7zz)
+	// instead of the first of 7z, 7za and 7zr found in PATH
+	SevenZip string `yaml:"seven_zip"`
+}
+
+// builtinConsolePolicies are applied before the config file. PS3 package and
+// EDAT/SDAT files are encrypted, so compressing them only costs time.
+var builtinConsolePolicies = map[detect.ConsoleType]CompressionPolicy{
+	detect.PS3: {StoreExtensions: []string{".pkg", ".edat", ".sdat"}},
+}
+
+// apply returns opts with the fields set in the policy replaced
+func (p CompressionPolicy) apply(opts common.ArchiveOptions) common.ArchiveOptions {
+	if p.Level != nil {
+		opts.Level = *p.Level
+	}
+	if p.StoreExtensions != nil {
+		opts.StoreExtensions = p.StoreExtensions
+	}
+	if p.ExtraArgs != nil {
+		opts.ExtraArgs = p.ExtraArgs
+	}
+	if p.Reproducible != nil {
+		opts.Reproducible = *p.Reproducible
+	}
+	if p.Tuning != nil {
+		if tuning, ok := common.ArchiveTunings[*p.Tuning]; ok {
+			opts.SolidBlock, opts.Dictionary = tuning.SolidBlock, tuning.Dictionary
+		}
+	}
+	if p.SolidBlock != nil {
+		opts.SolidBlock = *p.SolidBlock
+	}
+	if p.Dictionary != nil {
+		opts.Dictionary = *p.Dictionary
+	}
+	return opts
+}
+
+// validateTuning checks that the policy names a known preset
+func (p CompressionPolicy) validateTuning() error {
+	if p.Tuning == nil {
+		return nil
+	}
+	var opts common.ArchiveOptions
+	return opts.ApplyTuning(*p.Tuning)
+}
+
+// ArchiveOptions resolves the archive settings for a console: built-in defaults,
+// then the console's built-in policy, then the config default, then the config console entry
+func (c CompressionConfig) ArchiveOptions(console detect.ConsoleType) common.ArchiveOptions {
+	opts := common.DefaultArchiveOptions()
+	opts = builtinConsolePolicies[console].apply(opts)
+	opts = c.Default.apply(opts)
+	return c.Consoles[console.ShortName()].apply(opts)
+}
+
+// Validate checks console names and the resolved settings for every console entry
+func (c CompressionConfig) Validate() error {
+	if err := c.Default.validateTuning(); err != nil {
+		return fmt.Errorf("compression default: %w", err)
+	}
+	if err := c.Default.apply(common.DefaultArchiveOptions()).Validate(); err != nil {
+		return fmt.Errorf("compression default: %w", err)
+	}
+	for name, policy := range c.Consoles {
+		if err := policy.validateTuning(); err != nil {
+			return fmt.Errorf("compression for %s: %w", name, err)
+		}
+		console, err := detect.ParseConsoleType(name)
+		if err != nil {
+			return fmt.Errorf("compression consoles: %w", err)
+		}
+		if err := c.ArchiveOptions(console).Validate(); err != nil {
+			return fmt.Errorf("compression for %s: %w", name, err)
+		}
+	}
+	return nil
+}
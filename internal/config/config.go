@@ -0,0 +1,313 @@
+// Package config loads rom-organizer settings from a YAML config file
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+)
+
+// Config holds user settings loaded from the config file
+type Config struct {
+	Layout      common.Layout     `yaml:"layout"`      // Folder names used inside organized game directories
+	Hooks       HooksConfig       `yaml:"hooks"`       // Commands run before/after each game
+	Compression CompressionConfig `yaml:"compression"` // Per-console 7z settings
+	Schedule    ScheduleConfig    `yaml:"schedule"`    // Recurring tasks for the schedule command
+	Catalog     string            `yaml:"catalog"`     // Catalog file with tags and collections
+	Indicators  string            `yaml:"indicators"`  // Indicators file with detection rules for other systems
+	Cleanup     CleanupConfig     `yaml:"cleanup"`     // Source cleanup after --move
+	ReadOnly    ReadOnlyConfig    `yaml:"read_only"`   // Refuse to modify libraries
+	Hashes      HashesConfig      `yaml:"hashes"`      // Hash algorithms for checksums and verification
+	Titles      common.TitleRules `yaml:"titles"`      // Title cleanups applied before folder naming
+
+	// Homebrew places homebrew and media apps (PARAM.SFO CATEGORY HG, HM, AM, ...)
+	Homebrew common.HomebrewRules `yaml:"homebrew"`
+
+	// OnCollision is the default for --on-collision: fail, suffix, skip or overwrite
+	OnCollision string `yaml:"on_collision"`
+
+	// Snapshot takes a file system snapshot before operations that delete or replace content
+	Snapshot SnapshotConfig `yaml:"snapshot"`
+
+	// Components sets what happens to optional disc folders, e.g. PS3_UPDATE: exclude
+	// (include, exclude or separate; the default is include)
+	Components map[string]string `yaml:"components"`
+
+	// Watchdog sets the heartbeat and stall detection of 7z runs and copies
+	Watchdog common.WatchdogOptions `yaml:"watchdog"`
+
+	// Ownership sets the owner of the games compress, decompress and organize create
+	Ownership OwnershipConfig `yaml:"ownership"`
+
+	// Intake sets the defaults for --torrent-safe and --settle
+	Intake IntakeConfig `yaml:"intake"`
+
+	// Library sets where and how compress, decompress and organize place games by default
+	Library LibraryConfig `yaml:"library"`
+
+	// Names limits the length of folder and file names made from titles
+	Names NamesConfig `yaml:"names"`
+}
+
+// NamesConfig holds the name length limits of libraries and, stricter by default, of exports
+type NamesConfig struct {
+	common.NameLimits `yaml:",inline"`
+	Export            common.NameLimits `yaml:"export"` // export split and shortcuts, counted from --to
+}
+
+// LibraryConfig holds the defaults of the commands that organize games, as asked by init
+type LibraryConfig struct {
+	Roots      []string `yaml:"roots,omitempty"`       // Library folders; the first is the default --output
+	Format     string   `yaml:"format,omitempty"`      // What organize makes of new sources: compressed or decompressed (default)
+	OrganizeBy string   `yaml:"organize_by,omitempty"` // Default --organize-by: none or first-letter
+}
+
+// Validate checks the format and grouping names
+func (l LibraryConfig) Validate() error {
+	switch l.Format {
+	case "", "compressed", "decompressed":
+	default:
+		return fmt.Errorf("library.format: %q is not compressed or decompressed", l.Format)
+	}
+	switch l.OrganizeBy {
+	case "", "none", "first-letter":
+	default:
+		return fmt.Errorf("library.organize_by: %q is not none or first-letter", l.OrganizeBy)
+	}
+	return nil
+}
+
+// IntakeConfig protects download folders that a client is still writing or seeding
+type IntakeConfig struct {
+	TorrentSafe bool          `yaml:"torrent_safe"` // Never write inside sources, and refuse --move
+	Settle      time.Duration `yaml:"settle"`       // Skip sources that changed within this long (default 30s with torrent_safe)
+}
+
+// OwnershipConfig gives created games to a user and group (user:group, user or :group),
+// e.g. the media user Samba or Plex expect when a service account runs rom-organizer
+type OwnershipConfig struct {
+	Owner     string            `yaml:"owner"`     // Owner of output in any folder
+	Libraries map[string]string `yaml:"libraries"` // Owner of output under these folders instead
+}
+
+// Rules resolves the user and group names. It is only called by the commands that
+// create games, so a config shared with machines lacking those users still loads.
+func (o OwnershipConfig) Rules() (common.OwnerRules, error) {
+	var rules common.OwnerRules
+	var err error
+	if rules.Default, err = common.ParseOwner(o.Owner); err != nil {
+		return rules, fmt.Errorf("ownership.owner: %w", err)
+	}
+	for dir, spec := range o.Libraries {
+		owner, err := common.ParseOwner(spec)
+		if err != nil {
+			return rules, fmt.Errorf("ownership.libraries: %w", err)
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return rules, fmt.Errorf("ownership.libraries: %w", err)
+		}
+		if rules.Libraries == nil {
+			rules.Libraries = make(map[string]*common.Owner)
+		}
+		rules.Libraries[abs] = owner
+	}
+	return rules, nil
+}
+
+// ComponentPolicies returns the disc component policies from the config
+func (c *Config) ComponentPolicies() (common.ComponentPolicyMap, error) {
+	var specs []string
+	for name, policy := range c.Components {
+		specs = append(specs, name+"="+policy)
+	}
+	return common.ParseComponentPolicies(nil, specs)
+}
+
+// SnapshotConfig holds the command that snapshots the library's file system (ZFS, btrfs,
+// ...) before batches with --move, --force or --on-collision overwrite or that convert
+// organized games in place, before dedup and before check --quarantine. It receives SNAPSHOT_NAME, OPERATION and PATHS (the
+// directories about to be changed, one per line) as environment variables.
+type SnapshotConfig struct {
+	Command string `yaml:"command"` // e.g. zfs snapshot tank/ps3@$SNAPSHOT_NAME
+}
+
+// HashesConfig selects the hash algorithms (sha256, sha1, md5, crc32, xxh64)
+type HashesConfig struct {
+	Verify   string `yaml:"verify"`   // Checking a moved game against its source (default xxh64)
+	Manifest string `yaml:"manifest"` // Checksum files written with --checksum (default sha256)
+}
+
+// VerifyHash returns the verification hash, or the default
+func (h HashesConfig) VerifyHash() common.HashAlgorithm {
+	if alg, err := common.ParseHashAlgorithm(h.Verify); err == nil {
+		return alg
+	}
+	return common.DefaultVerifyHash
+}
+
+// ManifestHash returns the checksum file hash, or the default
+func (h HashesConfig) ManifestHash() common.HashAlgorithm {
+	if alg, err := common.ParseHashAlgorithm(h.Manifest); err == nil {
+		return alg
+	}
+	return common.DefaultManifestHash
+}
+
+// Validate checks the algorithm names
+func (h HashesConfig) Validate() error {
+	for key, name := range map[string]string{"verify": h.Verify, "manifest": h.Manifest} {
+		if name == "" {
+			continue
+		}
+		if _, err := common.ParseHashAlgorithm(name); err != nil {
+			return fmt.Errorf("hashes.%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ReadOnlyConfig sets the default for --read-only
+type ReadOnlyConfig struct {
+	Enabled       bool `yaml:"enabled"`        // Refuse every operation that modifies a library
+	NetworkMounts bool `yaml:"network_mounts"` // Refuse them only for paths on NFS/SMB mounts
+}
+
+// CleanupConfig controls when a source directory counts as empty after --move
+type CleanupConfig struct {
+	// JunkFiles are file name patterns (e.g. Thumbs.db, ._*) ignored when deciding whether
+	// a source directory is empty; setting it replaces the default list
+	JunkFiles []string `yaml:"junk_files"`
+}
+
+// HooksConfig holds shell commands run around each organized game.
+// Commands receive TITLE, GAME_ID, CONSOLE, SOURCE_PATH, TARGET_PATH and STATUS
+// (plus ERROR on failure) as environment variables.
+type HooksConfig struct {
+	Pre  string `yaml:"pre"`  // Run before each game; a non-zero exit skips the game
+	Post string `yaml:"post"` // Run after each game, whether it succeeded or failed
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Layout:  common.DefaultLayout(),
+		Cleanup: CleanupConfig{JunkFiles: common.DefaultJunkFiles},
+		Titles:  common.DefaultTitleRules(),
+
+		Homebrew: common.DefaultHomebrewRules(),
+		Watchdog: common.DefaultWatchdog(),
+		Names: NamesConfig{
+			NameLimits: common.DefaultNameLimits(),
+			Export:     common.DefaultExportNameLimits(),
+		},
+	}
+}
+
+// DefaultPath returns the default config file location
+// (e.g. ~/.config/rom-organizer/config.yaml on Linux)
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rom-organizer", "config.yaml")
+}
+
+// CatalogPath returns the configured catalog file, or the default location
+func (c *Config) CatalogPath() string {
+	if c.Catalog != "" {
+		return c.Catalog
+	}
+	return catalog.DefaultPath()
+}
+
+// IndicatorsPath returns the configured indicators file, or the default location
+func (c *Config) IndicatorsPath() string {
+	if c.Indicators != "" {
+		return c.Indicators
+	}
+	return detect.DefaultIndicatorsPath()
+}
+
+// Load reads the config file at path. An empty path loads the default location,
+// where a missing file is not an error and yields the default configuration.
+func Load(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration for values that would produce a broken layout
+func (c *Config) Validate() error {
+	if err := c.Layout.Validate(); err != nil {
+		return err
+	}
+	if err := c.Compression.Validate(); err != nil {
+		return err
+	}
+	for _, pattern := range c.Cleanup.JunkFiles {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid cleanup.junk_files pattern %q: %w", pattern, err)
+		}
+	}
+	if err := c.Hashes.Validate(); err != nil {
+		return err
+	}
+	if _, err := common.ParseCollisionPolicy(c.OnCollision); err != nil {
+		return fmt.Errorf("on_collision: %w", err)
+	}
+	if _, err := c.ComponentPolicies(); err != nil {
+		return fmt.Errorf("components: %w", err)
+	}
+	if err := c.Homebrew.Validate(); err != nil {
+		return err
+	}
+	if err := c.Watchdog.Validate(); err != nil {
+		return err
+	}
+	if c.Intake.Settle < 0 {
+		return fmt.Errorf("intake.settle must not be negative")
+	}
+	if err := c.Library.Validate(); err != nil {
+		return err
+	}
+	if err := c.Names.Validate(); err != nil {
+		return fmt.Errorf("names: %w", err)
+	}
+	if err := c.Names.Export.Validate(); err != nil {
+		return fmt.Errorf("names.export: %w", err)
+	}
+	return c.Schedule.Validate()
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/NeilGraham/rom-organizer/internal/schedule"
+)
+
+// Notify settings for scheduled tasks
+const (
+	NotifyFailure = "failure" // Notify only when a task fails (default)
+	NotifyAlways  = "always"  // Notify after every run
+	NotifyNever   = "never"   // Never notify
+)
+
+// ScheduleConfig holds recurring tasks run by the schedule command.
+// The notify command receives TASK, STATUS, ERROR and LOG_FILE as environment variables.
+type ScheduleConfig struct {
+	Log    string          `yaml:"log"`    // File that task output and results are appended to
+	Notify string          `yaml:"notify"` // Shell command run after a task according to its notify setting
+	Tasks  []ScheduledTask `yaml:"tasks"`
+}
+
+// ScheduledTask is a rom-organizer command run on a cron schedule
+type ScheduledTask struct {
+	Name    string   `yaml:"name"`
+	Cron    string   `yaml:"cron"`    // Five-field cron expression or @daily/@weekly/...
+	Command []string `yaml:"command"` // rom-organizer arguments, e.g. [stats, /mnt/nas/ps3]
+	Notify  string   `yaml:"notify"`  // failure (default), always or never
+}
+
+// NotifyOn returns the task's notify setting with the default applied
+func (t ScheduledTask) NotifyOn() string {
+	if t.Notify == "" {
+		return NotifyFailure
+	}
+	return t.Notify
+}
+
+// Validate checks that every task has a unique name, a valid cron expression and a command
+func (c ScheduleConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i, task := range c.Tasks {
+		if task.Name == "" {
+			return fmt.Errorf("schedule task %d: name is required", i+1)
+		}
+		if seen[task.Name] {
+			return fmt.Errorf("schedule task %q: duplicate name", task.Name)
+		}
+		seen[task.Name] = true
+
+		if _, err := schedule.ParseCron(task.Cron); err != nil {
+			return fmt.Errorf("schedule task %q: %w", task.Name, err)
+		}
+		if len(task.Command) == 0 {
+			return fmt.Errorf("schedule task %q: command is required", task.Name)
+		}
+		switch task.NotifyOn() {
+		case NotifyFailure, NotifyAlways, NotifyNever:
+		default:
+			return fmt.Errorf("schedule task %q: notify must be failure, always or never", task.Name)
+		}
+	}
+	return nil
+}
+
+// Task returns the scheduled task with the given name
+func (c ScheduleConfig) Task(name string) (ScheduledTask, bool) {
+	for _, task := range c.Tasks {
+		if task.Name == name {
+			return task, true
+		}
+	}
+	return ScheduledTask{}, false
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/NeilGraham/rom-organizer/internal/common"
 	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
 )
 
 // PS3Handler handles PlayStation 3 games
@@ -48,6 +49,9 @@ func (h *PS3Handler) ExtractGameInfo(sourcePath string, verbose bool) (*common.G
 		// Source is a directory - search for PS3_GAME recursively
 		foundGameRoot, foundParamSFO, err := h.findPS3GameRecursively(sourcePath, verbose)
 		if err != nil {
+			if info, statErr := os.Stat(filepath.Join(sourcePath, "PS3_GAME")); statErr == nil && info.IsDir() {
+				return h.guessGameInfo(sourcePath, fmt.Errorf("PS3_GAME/PARAM.SFO not found in %s", sourcePath))
+			}
 			return nil, err
 		}
 		gameRootPath = foundGameRoot
@@ -55,8 +59,8 @@ func (h *PS3Handler) ExtractGameInfo(sourcePath string, verbose bool) (*common.G
 	} else {
 		// Source is likely an archive file
 		ext := strings.ToLower(filepath.Ext(sourcePath))
-		if ext != ".zip" {
-			return nil, fmt.Errorf("archive format %s not supported yet, please use .zip or extract to a folder", ext)
+		if ext != ".zip" && ext != ".7z" && ext != ".rar" {
+			return nil, fmt.Errorf("archive format %s not supported yet, please use .zip, .7z or .rar or extract to a folder", ext)
 		}
 
 		// Extract archive to temporary directory
@@ -66,10 +70,10 @@ func (h *PS3Handler) ExtractGameInfo(sourcePath string, verbose bool) (*common.G
 		}
 
 		if verbose {
-			fmt.Printf("Extracting archive to temporary directory: %s\n", tempDir)
+			ui.Verbosef("Extracting archive to temporary directory: %s\n", tempDir)
 		}
 
-		if err := common.ExtractZip(sourcePath, tempDir); err != nil {
+		if err := common.ExtractArchive(sourcePath, tempDir); err != nil {
 			os.RemoveAll(tempDir)
 			return nil, fmt.Errorf("extracting archive: %w", err)
 		}
@@ -86,27 +90,36 @@ func (h *PS3Handler) ExtractGameInfo(sourcePath string, verbose bool) (*common.G
 
 	// Parse PARAM.SFO to get game information
 	if verbose {
-		fmt.Printf("Reading game information from: %s\n", paramSFOPath)
+		ui.Verbosef("Reading game information from: %s\n", paramSFOPath)
+	}
+
+	// A folder whose PARAM.SFO can't be read (a corrupt dump) is named from its folder
+	// name and PS3_DISC.SFB instead; an extracted archive has no name to go by
+	unreadable := func(err error) (*common.GameInfo, error) {
+		if !sourceInfo.IsDir() {
+			return nil, err
+		}
+		return h.guessGameInfo(gameRootPath, err)
 	}
 
-	paramSFOData, err := os.ReadFile(paramSFOPath)
+	paramSFOData, err := parsers.ReadParamSFOFile(paramSFOPath)
 	if err != nil {
-		return nil, fmt.Errorf("reading PARAM.SFO: %w", err)
+		return unreadable(fmt.Errorf("reading PARAM.SFO: %w", err))
 	}
 
 	paramSFO, err := parsers.ParseParamSFO(paramSFOData)
 	if err != nil {
-		return nil, fmt.Errorf("parsing PARAM.SFO: %w", err)
+		return unreadable(fmt.Errorf("parsing PARAM.SFO: %w", err))
 	}
 
 	title := paramSFO.GetTitle()
 	titleID := paramSFO.GetTitleID()
 
 	if title == "" {
-		return nil, fmt.Errorf("game title not found in PARAM.SFO")
+		return unreadable(fmt.Errorf("game title not found in PARAM.SFO"))
 	}
 	if titleID == "" {
-		return nil, fmt.Errorf("title ID not found in PARAM.SFO")
+		return unreadable(fmt.Errorf("title ID not found in PARAM.SFO"))
 	}
 
 	// Extract additional metadata
@@ -120,6 +133,7 @@ func (h *PS3Handler) ExtractGameInfo(sourcePath string, verbose bool) (*common.G
 		Version:  version,
 		Category: category,
 		Source:   gameRootPath,
+		Homebrew: IsPS3Homebrew(category, titleID),
 	}, nil
 }
 
@@ -129,13 +143,13 @@ func (h *PS3Handler) findPS3GameRecursively(rootPath string, verbose bool) (game
 	paramSFOPath = filepath.Join(rootPath, "PS3_GAME", "PARAM.SFO")
 	if _, err := os.Stat(paramSFOPath); err == nil {
 		if verbose {
-			fmt.Printf("Found PS3_GAME at root level: %s\n", rootPath)
+			ui.Verbosef("Found PS3_GAME at root level: %s\n", rootPath)
 		}
 		return rootPath, paramSFOPath, nil
 	}
 
 	if verbose {
-		fmt.Printf("PS3_GAME not found at root level, searching recursively in: %s\n", rootPath)
+		ui.Verbosef("PS3_GAME not found at root level, searching recursively in: %s\n", rootPath)
 	}
 
 	// Recursively search for PS3_GAME/PARAM.SFO
@@ -153,7 +167,7 @@ func (h *PS3Handler) findPS3GameRecursively(rootPath string, verbose bool) (game
 				// Found PS3_GAME/PARAM.SFO - the game root is the parent of PS3_GAME
 				foundPath = filepath.Dir(parentDir)
 				if verbose {
-					fmt.Printf("Found PS3_GAME in nested directory: %s\n", foundPath)
+					ui.Verbosef("Found PS3_GAME in nested directory: %s\n", foundPath)
 				}
 				return filepath.SkipDir // Stop searching
 			}
@@ -0,0 +1,86 @@
+package consoles
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// PS3DRMFile is an EDAT/SDAT file found in a game folder
+type PS3DRMFile struct {
+	Path    string
+	Header  *parsers.EDATHeader
+	License string // Matching .rap/.rif file, "" if none was found
+}
+
+// LicenseMissing reports whether the file needs a license that was not found
+func (f PS3DRMFile) LicenseMissing() bool {
+	return f.Header.NeedsLicense() && f.License == ""
+}
+
+// FindPS3DRMFiles finds the EDAT/SDAT files under root and matches them with
+// license files (<content ID>.rap or .rif) found under root or licenseDirs.
+// Files with an unreadable header are reported as errors in the second result.
+func FindPS3DRMFiles(root string, licenseDirs []string) ([]PS3DRMFile, []error, error) {
+	var files []PS3DRMFile
+	var problems []error
+	licenses := make(map[string]string)
+
+	collect := func(dir string, wantDRM bool) error {
+		return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			ext := strings.ToLower(filepath.Ext(info.Name()))
+			switch {
+			case ext == ".rap" || ext == ".rif":
+				contentID := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+				if _, ok := licenses[contentID]; !ok {
+					licenses[contentID] = path
+				}
+			case wantDRM && (ext == ".edat" || ext == ".sdat"):
+				header, err := readEDATHeader(path)
+				if err != nil {
+					problems = append(problems, fmt.Errorf("%s: %w", path, err))
+					return nil
+				}
+				files = append(files, PS3DRMFile{Path: path, Header: header})
+			}
+			return nil
+		})
+	}
+
+	if err := collect(root, true); err != nil {
+		return nil, nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	for _, dir := range licenseDirs {
+		if err := collect(dir, false); err != nil {
+			return nil, nil, fmt.Errorf("scanning license directory %s: %w", dir, err)
+		}
+	}
+
+	for i := range files {
+		files[i].License = licenses[files[i].Header.ContentID]
+	}
+	return files, problems, nil
+}
+
+// readEDATHeader reads and parses just the header of an EDAT/SDAT file
+func readEDATHeader(path string) (*parsers.EDATHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, parsers.EDATHeaderSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return parsers.ParseEDAT(buf[:n])
+}
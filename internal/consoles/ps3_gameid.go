@@ -0,0 +1,83 @@
+package consoles
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// ps3TitleIDPattern matches PS3 title IDs: four letters followed by five digits (e.g. BLUS30001)
+var ps3TitleIDPattern = regexp.MustCompile(`^[A-Z]{4}\d{5}$`)
+
+// ps3PlaceholderPrefixes are title ID prefixes left in PARAM.SFO by templates and dumping tools
+var ps3PlaceholderPrefixes = []string{"TEST", "XXXX", "ABCD"}
+
+// ValidateGameID checks that a PS3 title ID is well formed and not a placeholder
+func (h *PS3Handler) ValidateGameID(gameInfo *common.GameInfo) error {
+	id := gameInfo.GameID
+
+	var problem string
+	switch {
+	case !ps3TitleIDPattern.MatchString(id):
+		problem = fmt.Sprintf("malformed title ID %q (expected four letters and five digits, e.g. BLUS30001)", id)
+	case strings.HasSuffix(id, "00000"):
+		problem = fmt.Sprintf("placeholder title ID %q", id)
+	default:
+		for _, prefix := range ps3PlaceholderPrefixes {
+			if strings.HasPrefix(id, prefix) {
+				problem = fmt.Sprintf("placeholder title ID %q", id)
+				break
+			}
+		}
+	}
+	if problem == "" {
+		return nil
+	}
+
+	paramSFOPath := filepath.Join(gameInfo.Source, "PS3_GAME", "PARAM.SFO")
+	suggestion := suggestPS3TitleID(id)
+	if suggestion == "" {
+		suggestion = "<TITLE_ID>"
+	} else {
+		problem += fmt.Sprintf(" - did you mean %s?", suggestion)
+	}
+
+	if gameInfo.Unverified {
+		return fmt.Errorf("%s\nGive the right one with: --set-id %s", problem, suggestion)
+	}
+	return fmt.Errorf("%s\nFix PARAM.SFO with: rom-organizer sfo set %q TITLE_ID %s", problem, paramSFOPath, suggestion)
+}
+
+// suggestPS3TitleID returns a corrected title ID for common formatting mistakes
+// (lowercase letters, "BLUS-30001", surrounding spaces), or "" if none applies
+func suggestPS3TitleID(id string) string {
+	cleaned := strings.ToUpper(strings.TrimSpace(id))
+	cleaned = strings.NewReplacer("-", "", "_", "", " ", "").Replace(cleaned)
+	if cleaned != id && ps3TitleIDPattern.MatchString(cleaned) && !strings.HasSuffix(cleaned, "00000") {
+		return cleaned
+	}
+	return ""
+}
+
+// ps3Regions maps the third letter of a title ID to the region the game was released in
+var ps3Regions = map[byte]string{
+	'U': "USA",
+	'E': "Europe",
+	'J': "Japan",
+	'A': "Asia",
+	'K': "Korea",
+	'H': "Hong Kong",
+	'I': "Internal",
+}
+
+// PS3Region returns the release region a title ID encodes (BLUS30001 is USA, NPEB00001
+// Europe), or "" when the ID doesn't say
+func PS3Region(titleID string) string {
+	if !ps3TitleIDPattern.MatchString(titleID) {
+		return ""
+	}
+	return ps3Regions[titleID[2]]
+}
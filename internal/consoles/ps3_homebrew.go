@@ -0,0 +1,28 @@
+package consoles
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ps3AppCategories are PARAM.SFO CATEGORY values of applications rather than games:
+// media players, system-menu apps and the like, almost always homebrew on a library
+var ps3AppCategories = []string{"AM", "AP", "AT", "AV", "CB", "HM", "AMU", "TI"}
+
+// ps3LicensedIDPattern matches title IDs issued to licensed releases: discs (BLES, BCUS,
+// ...) and PSN titles (NPEB, NPUA, ...). Homebrew picks its own IDs, which rarely look
+// like these.
+var ps3LicensedIDPattern = regexp.MustCompile(`^(B[CL][AEHJKPU][SMDT]|NP[AEHIJKUZ][A-Z])\d{5}$`)
+
+// IsPS3Homebrew reports whether a PARAM.SFO CATEGORY and TITLE_ID belong to homebrew or
+// a media app. HG (HDD game) is shared with licensed PSN games, so it only counts as
+// homebrew when the title ID isn't a licensed one.
+func IsPS3Homebrew(category, titleID string) bool {
+	category = strings.ToUpper(strings.TrimSpace(category))
+	for _, app := range ps3AppCategories {
+		if category == app {
+			return true
+		}
+	}
+	return category == "HG" && !ps3LicensedIDPattern.MatchString(titleID)
+}
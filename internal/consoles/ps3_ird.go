@@ -0,0 +1,219 @@
+package consoles
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// Key and IV used to turn an IRD's data1 into the disc key
+var (
+	irdData1Key = []byte{0x38, 0x0B, 0xCF, 0x0B, 0x53, 0x45, 0x5B, 0x3C, 0x78, 0x17, 0xAB, 0x4F, 0xA3, 0xBA, 0x90, 0xED}
+	irdData1IV  = []byte{0x69, 0x47, 0x47, 0x72, 0xAF, 0x6F, 0xDA, 0xB3, 0x42, 0x74, 0x3A, 0xEF, 0xAA, 0x18, 0x62, 0x87}
+)
+
+// DiscKeyFromIRD derives the disc key from the data1 field of an IRD
+func DiscKeyFromIRD(ird *parsers.IRD) []byte {
+	block, _ := aes.NewCipher(irdData1Key)
+	key := make([]byte, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, irdData1IV).CryptBlocks(key, ird.Data1[:])
+	return key
+}
+
+// IRDMismatch is a file or region that does not match the IRD
+type IRDMismatch struct {
+	Path    string
+	Problem string
+}
+
+// IRDReport is the result of checking a game against an IRD
+type IRDReport struct {
+	Files      int // File hashes in the IRD
+	FilesOK    int
+	Regions    int // Region hashes checked (disc images only)
+	RegionsOK  int
+	Mismatches []IRDMismatch
+}
+
+// OK reports whether everything checked matched the IRD
+func (r *IRDReport) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+func (r *IRDReport) mismatch(path, format string, args ...interface{}) {
+	r.Mismatches = append(r.Mismatches, IRDMismatch{Path: path, Problem: fmt.Sprintf(format, args...)})
+}
+
+// irdFiles maps the IRD's file hashes to paths using the ISO file system stored in its header
+func irdFiles(ird *parsers.IRD) ([]parsers.ISOFile, map[uint64][16]byte, error) {
+	files, err := parsers.ReadISO9660(bytes.NewReader(ird.Header))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading IRD file system: %w", err)
+	}
+	hashes := make(map[uint64][16]byte, len(ird.Files))
+	for _, file := range ird.Files {
+		hashes[file.Sector] = file.MD5
+	}
+	return files, hashes, nil
+}
+
+// VerifyPS3Folder checks the files of a decrypted game folder (JB folder) against an IRD
+func VerifyPS3Folder(ird *parsers.IRD, root string) (*IRDReport, error) {
+	files, hashes, err := irdFiles(ird)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IRDReport{}
+	for _, file := range files {
+		want, ok := hashes[uint64(file.Sector)]
+		if !ok {
+			continue
+		}
+		report.Files++
+
+		path := filepath.Join(root, filepath.FromSlash(file.Path))
+		info, err := os.Stat(path)
+		if err != nil {
+			report.mismatch(file.Path, "missing")
+			continue
+		}
+		if info.Size() != file.Size {
+			report.mismatch(file.Path, "size is %d bytes, expected %d", info.Size(), file.Size)
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			report.mismatch(file.Path, "unreadable: %v", err)
+			continue
+		}
+		got, err := md5Of(f)
+		f.Close()
+		if err != nil {
+			report.mismatch(file.Path, "unreadable: %v", err)
+			continue
+		}
+		if got != want {
+			report.mismatch(file.Path, "MD5 mismatch")
+			continue
+		}
+		report.FilesOK++
+	}
+	return report, nil
+}
+
+// VerifyPS3ISO checks a disc image's files and regions against an IRD. Encrypted
+// and decrypted images are both accepted: data that does not match as stored is
+// checked again decrypted and re-encrypted with key, when one is given.
+func VerifyPS3ISO(ird *parsers.IRD, iso *PS3ISO, key []byte) (*IRDReport, error) {
+	files, hashes, err := irdFiles(ird)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := iso.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer raw.Close()
+
+	readers := []io.ReaderAt{raw}
+	if key != nil {
+		dec, err := iso.NewReader(key)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		enc, err := iso.NewEncryptingReader(key)
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		readers = append(readers, dec, enc)
+	}
+
+	// matches hashes a byte range as stored, then decrypted and re-encrypted until one matches
+	matches := func(off, size int64, want [16]byte) (bool, error) {
+		for _, r := range readers {
+			got, err := md5Of(io.NewSectionReader(r, off, size))
+			if err != nil {
+				return false, err
+			}
+			if got == want {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	report := &IRDReport{}
+	for _, file := range files {
+		want, ok := hashes[uint64(file.Sector)]
+		if !ok {
+			continue
+		}
+		report.Files++
+
+		off := int64(file.Sector) * ps3SectorSize
+		if off+file.Size > raw.Size() {
+			report.mismatch(file.Path, "truncated: image ends before the file")
+			continue
+		}
+		ok, err := matches(off, file.Size, want)
+		if err != nil {
+			report.mismatch(file.Path, "unreadable: %v", err)
+			continue
+		}
+		if !ok {
+			report.mismatch(file.Path, "MD5 mismatch")
+			continue
+		}
+		report.FilesOK++
+	}
+
+	for i, want := range ird.RegionHashes {
+		if i >= len(iso.Regions) {
+			break
+		}
+		region := iso.Regions[i]
+		report.Regions++
+
+		name := fmt.Sprintf("region %d (sectors %d-%d)", i, region.First, region.Last)
+		off := int64(region.First) * ps3SectorSize
+		size := (int64(region.Last) - int64(region.First) + 1) * ps3SectorSize
+		if off+size > raw.Size() {
+			report.mismatch(name, "truncated: image ends before the region")
+			continue
+		}
+		ok, err := matches(off, size, want)
+		if err != nil {
+			report.mismatch(name, "unreadable: %v", err)
+			continue
+		}
+		if !ok {
+			report.mismatch(name, "MD5 mismatch")
+			continue
+		}
+		report.RegionsOK++
+	}
+
+	return report, nil
+}
+
+func md5Of(r io.Reader) ([16]byte, error) {
+	h := md5.New()
+	var sum [16]byte
+	if _, err := io.Copy(h, r); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
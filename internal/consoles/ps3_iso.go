@@ -0,0 +1,321 @@
+package consoles
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// ps3SectorSize is the sector size of PS3 disc images
+const ps3SectorSize = 2048
+
+// PS3ISORegion is a run of sectors in a PS3 disc image. Sector numbers are inclusive.
+type PS3ISORegion struct {
+	First, Last uint32
+	Encrypted   bool
+}
+
+// PS3ISO describes a PS3 disc image from its first two sectors
+type PS3ISO struct {
+	Path    string
+	TitleID string // From the disc header, e.g. BLUS30001
+	Regions []PS3ISORegion
+}
+
+// OpenPS3ISO reads the region table (sector 0) and disc header (sector 1) of a PS3 disc image
+func OpenPS3ISO(path string) (*PS3ISO, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening disc image: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 2*ps3SectorSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("reading disc image header: %w", err)
+	}
+
+	disc := header[ps3SectorSize:]
+	if !bytes.HasPrefix(disc, []byte("PlayStation3")) {
+		return nil, fmt.Errorf("%s is not a PS3 disc image", path)
+	}
+	titleID := strings.ReplaceAll(strings.TrimSpace(string(bytes.TrimRight(disc[0x10:0x20], "\x00"))), "-", "")
+
+	// The table lists plain regions as (first, last) pairs; encrypted regions lie between them
+	plainCount := binary.BigEndian.Uint32(header[0:4])
+	if plainCount == 0 || plainCount > 255 {
+		return nil, fmt.Errorf("%s: invalid region table (%d regions)", path, plainCount)
+	}
+	var regions []PS3ISORegion
+	for i := uint32(0); i < plainCount*2-1; i++ {
+		region := PS3ISORegion{
+			First:     binary.BigEndian.Uint32(header[8+i*4:]),
+			Last:      binary.BigEndian.Uint32(header[12+i*4:]),
+			Encrypted: i%2 == 1,
+		}
+		if region.Encrypted {
+			region.First++
+			region.Last--
+		}
+		regions = append(regions, region)
+	}
+
+	return &PS3ISO{Path: path, TitleID: titleID, Regions: regions}, nil
+}
+
+// IsEncrypted reports whether the image has encrypted regions
+func (iso *PS3ISO) IsEncrypted() bool {
+	for _, region := range iso.Regions {
+		if region.Encrypted && region.Last >= region.First {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptedSector reports whether a sector lies in an encrypted region
+func (iso *PS3ISO) encryptedSector(sector uint32) bool {
+	for _, region := range iso.Regions {
+		if region.Encrypted && sector >= region.First && sector <= region.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDiscKey parses a .dkey file: 32 hex digits, or the 16 raw key bytes
+func ParseDiscKey(data []byte) ([]byte, error) {
+	if len(data) == aes.BlockSize {
+		return data, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != aes.BlockSize {
+		return nil, fmt.Errorf("disc key must be 32 hex digits")
+	}
+	return key, nil
+}
+
+// Decrypt writes a decrypted copy of the image to dest. Encrypted sectors are
+// AES-128-CBC encrypted with the disc key and the sector number as IV.
+func (iso *PS3ISO) Decrypt(dest string, key []byte) error {
+	reader, err := iso.NewReader(key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("creating decrypted image: %w", err)
+	}
+
+	if _, err := io.Copy(out, io.NewSectionReader(reader, 0, reader.Size())); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return fmt.Errorf("decrypting disc image: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return fmt.Errorf("writing decrypted image: %w", err)
+	}
+	return nil
+}
+
+// PS3ISOReader reads a disc image, decrypting (or encrypting) the sectors of
+// encrypted regions when it has a key
+type PS3ISOReader struct {
+	iso     *PS3ISO
+	file    *os.File
+	size    int64
+	block   cipher.Block // nil for raw reads
+	encrypt bool
+}
+
+// NewReader opens the image for reading. With a nil key sectors are returned as stored.
+func (iso *PS3ISO) NewReader(key []byte) (*PS3ISOReader, error) {
+	return iso.newReader(key, false)
+}
+
+// NewEncryptingReader opens a decrypted image and reads it as the encrypted original
+func (iso *PS3ISO) NewEncryptingReader(key []byte) (*PS3ISOReader, error) {
+	return iso.newReader(key, true)
+}
+
+func (iso *PS3ISO) newReader(key []byte, encrypt bool) (*PS3ISOReader, error) {
+	r := &PS3ISOReader{iso: iso, encrypt: encrypt}
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid disc key: %w", err)
+		}
+		r.block = block
+	}
+
+	f, err := os.Open(iso.Path)
+	if err != nil {
+		return nil, fmt.Errorf("opening disc image: %w", err)
+	}
+	// Seek rather than Stat so that block devices (a Blu-ray drive) report their size
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening disc image: %w", err)
+	}
+	r.file, r.size = f, size
+	return r, nil
+}
+
+// Size returns the image size in bytes
+func (r *PS3ISOReader) Size() int64 {
+	return r.size
+}
+
+// Close closes the image file
+func (r *PS3ISOReader) Close() error {
+	return r.file.Close()
+}
+
+// ReadAt implements io.ReaderAt
+func (r *PS3ISOReader) ReadAt(p []byte, off int64) (int, error) {
+	if r.block == nil {
+		return r.file.ReadAt(p, off)
+	}
+
+	buf := make([]byte, ps3SectorSize)
+	iv := make([]byte, aes.BlockSize)
+	read := 0
+	for read < len(p) {
+		pos := off + int64(read)
+		sector := pos / ps3SectorSize
+		n, err := r.file.ReadAt(buf, sector*ps3SectorSize)
+		if n == ps3SectorSize && r.iso.encryptedSector(uint32(sector)) {
+			for i := range iv {
+				iv[i] = 0
+			}
+			binary.BigEndian.PutUint32(iv[12:], uint32(sector))
+			if r.encrypt {
+				cipher.NewCBCEncrypter(r.block, iv).CryptBlocks(buf, buf)
+			} else {
+				cipher.NewCBCDecrypter(r.block, iv).CryptBlocks(buf, buf)
+			}
+		}
+
+		start := int(pos - sector*ps3SectorSize)
+		if start >= n {
+			if err == nil {
+				err = io.EOF
+			}
+			return read, err
+		}
+		read += copy(p[read:], buf[start:n])
+		if err != nil && read < len(p) {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// Files lists the files on the disc. The file system is stored unencrypted.
+func (iso *PS3ISO) Files() ([]parsers.ISOFile, error) {
+	r, err := iso.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return parsers.ReadISO9660(r)
+}
+
+// sceMagic starts every PS3 executable (EBOOT.BIN, .self, .sprx)
+var sceMagic = []byte("SCE\x00")
+
+// executableSample returns an executable stored in an encrypted region, if there is one
+func (iso *PS3ISO) executableSample(files []parsers.ISOFile) (parsers.ISOFile, bool) {
+	for _, file := range files {
+		name := strings.ToUpper(file.Path)
+		isExecutable := strings.HasSuffix(name, "EBOOT.BIN") || strings.HasSuffix(name, ".SELF") || strings.HasSuffix(name, ".SPRX")
+		if isExecutable && file.Size >= 4 && iso.encryptedSector(file.Sector) {
+			return file, true
+		}
+	}
+	return parsers.ISOFile{}, false
+}
+
+// startsWithSCE reports whether the file read through r starts with the executable magic
+func startsWithSCE(r io.ReaderAt, file parsers.ISOFile) bool {
+	magic := make([]byte, len(sceMagic))
+	if _, err := r.ReadAt(magic, int64(file.Sector)*ps3SectorSize); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, sceMagic)
+}
+
+// IsDecrypted reports whether the image's encrypted regions have already been
+// decrypted, by checking an executable stored in one for its plain header.
+// Images without such an executable are assumed to still be encrypted.
+func (iso *PS3ISO) IsDecrypted(files []parsers.ISOFile) (bool, error) {
+	if !iso.IsEncrypted() {
+		return true, nil
+	}
+	sample, ok := iso.executableSample(files)
+	if !ok {
+		return false, nil
+	}
+	r, err := iso.NewReader(nil)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	return startsWithSCE(r, sample), nil
+}
+
+// CheckKey reports whether key decrypts the image, when that can be checked
+func (iso *PS3ISO) CheckKey(files []parsers.ISOFile, key []byte) (bool, error) {
+	sample, ok := iso.executableSample(files)
+	if !ok {
+		return true, nil
+	}
+	r, err := iso.NewReader(key)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	return startsWithSCE(r, sample), nil
+}
+
+// ExtractFiles copies the disc's files into dest, decrypting them with key
+// (nil for an image that is already decrypted)
+func (iso *PS3ISO) ExtractFiles(files []parsers.ISOFile, dest string, key []byte) error {
+	r, err := iso.NewReader(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, file := range files {
+		target := filepath.Join(dest, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", filepath.Dir(target), err)
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", target, err)
+		}
+		_, err = io.Copy(out, io.NewSectionReader(r, int64(file.Sector)*ps3SectorSize, file.Size))
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("extracting %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package consoles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// maxPKGTableSize limits how much of a package is read for its item table and file names
+const maxPKGTableSize = 16 * 1024 * 1024
+
+// PS3PKG is an open PS3 installer package
+type PS3PKG struct {
+	Header *parsers.PKGHeader
+	file   *os.File
+}
+
+// OpenPS3PKG opens a .pkg file and reads its header
+func OpenPS3PKG(path string) (*PS3PKG, error) {
+	header, err := parsers.ReadPKGHeader(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &PS3PKG{Header: header, file: f}, nil
+}
+
+// Close closes the package file
+func (p *PS3PKG) Close() error {
+	return p.file.Close()
+}
+
+// Items decrypts and returns the package's item table
+func (p *PS3PKG) Items() ([]parsers.PKGItem, error) {
+	if p.Header.ItemCount == 0 {
+		return nil, nil
+	}
+
+	// The table is followed by the file names; read the entries first to find their extent
+	if uint64(p.Header.ItemCount)*parsers.PKGItemSize > maxPKGTableSize {
+		return nil, fmt.Errorf("PKG has too many items: %d", p.Header.ItemCount)
+	}
+	entries, err := p.readData(0, uint64(p.Header.ItemCount)*parsers.PKGItemSize)
+	if err != nil {
+		return nil, err
+	}
+	end := uint64(len(entries))
+	for i := uint64(0); i+parsers.PKGItemSize <= uint64(len(entries)); i += parsers.PKGItemSize {
+		nameEnd := uint64(binary.BigEndian.Uint32(entries[i:])) + uint64(binary.BigEndian.Uint32(entries[i+4:]))
+		if nameEnd > end {
+			end = nameEnd
+		}
+	}
+	if end > maxPKGTableSize {
+		return nil, fmt.Errorf("PKG item table too large: %d bytes", end)
+	}
+
+	table, err := p.readData(0, end)
+	if err != nil {
+		return nil, err
+	}
+	return parsers.ParsePKGItems(p.Header, table)
+}
+
+// ReadItem decrypts and returns the contents of a file in the package
+func (p *PS3PKG) ReadItem(item parsers.PKGItem, maxSize uint64) ([]byte, error) {
+	if item.Size > maxSize {
+		return nil, fmt.Errorf("%s is too large to read (%d bytes)", item.Name, item.Size)
+	}
+	return p.readData(item.Offset, item.Size)
+}
+
+// ParamSFO returns the PARAM.SFO at the top of the package, which PSN games and
+// updates carry, or nil when there is none
+func (p *PS3PKG) ParamSFO() (*parsers.ParamSFO, error) {
+	items, err := p.Items()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.IsDir() || !strings.EqualFold(item.Name, "PARAM.SFO") {
+			continue
+		}
+		data, err := p.ReadItem(item, 1024*1024)
+		if err != nil {
+			return nil, err
+		}
+		return parsers.ParseParamSFO(data)
+	}
+	return nil, nil
+}
+
+// readData reads and decrypts size bytes at offset from the data start
+func (p *PS3PKG) readData(offset, size uint64) ([]byte, error) {
+	if offset+size > p.Header.DataSize {
+		return nil, fmt.Errorf("PKG data range %d+%d exceeds the data size %d", offset, size, p.Header.DataSize)
+	}
+	data := make([]byte, size)
+	if _, err := p.file.ReadAt(data, int64(p.Header.DataOffset+offset)); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("PKG file is truncated")
+		}
+		return nil, fmt.Errorf("reading PKG data: %w", err)
+	}
+	if err := p.Header.Decrypt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,42 @@
+package consoles
+
+import "regexp"
+
+// Kinds of pre-release builds returned by PS3Prerelease, which double as catalog tags
+const (
+	PrereleaseDemo = "demo"
+	PrereleaseBeta = "beta"
+)
+
+var (
+	// ps3PrereleaseIDPattern matches disc title IDs whose fourth letter marks a demo (D,
+	// e.g. BCED) or a test build handed out before release (T, e.g. BCET)
+	ps3PrereleaseIDPattern = regexp.MustCompile(`^B[CL][AEHJKPU]([DT])\d{5}$`)
+
+	// Markers in a PARAM.SFO title; whole words only, so "Demon's Souls" isn't a demo
+	ps3DemoTitle = regexp.MustCompile(`(?i)\b(demo|trial( version)?|kiosk|taikenban)\b`)
+	ps3BetaTitle = regexp.MustCompile(`(?i)\b(beta|alpha|prototype|pre-?release)\b`)
+)
+
+// PS3Prerelease returns PrereleaseDemo or PrereleaseBeta when a PARAM.SFO TITLE_ID or
+// TITLE marks a demo or beta build, and "" for everything else. PS3 has no CATEGORY of
+// its own for demos, which ship as DG discs or HG downloads like full games, so the
+// category only rules out homebrew, which names its builds freely.
+func PS3Prerelease(category, titleID, title string) string {
+	if IsPS3Homebrew(category, titleID) {
+		return ""
+	}
+	if match := ps3PrereleaseIDPattern.FindStringSubmatch(titleID); match != nil {
+		if match[1] == "D" {
+			return PrereleaseDemo
+		}
+		return PrereleaseBeta
+	}
+	switch {
+	case ps3DemoTitle.MatchString(title):
+		return PrereleaseDemo
+	case ps3BetaTitle.MatchString(title):
+		return PrereleaseBeta
+	}
+	return ""
+}
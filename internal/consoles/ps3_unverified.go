@@ -0,0 +1,79 @@
+package consoles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// folderNameTitleID finds a title ID in a folder name: "BLUS30001", "BLUS-30001" or
+// "blus_30001", not inside a longer word
+var folderNameTitleID = regexp.MustCompile(`(?i)(?:^|[^A-Za-z])([A-Za-z]{4})[-_ ]?(\d{5})(?:$|\D)`)
+
+// folderNameTags are the bracketed tags dump tools and release groups add to folder names,
+// such as [BLUS30001], (USA) or [!]
+var folderNameTags = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+
+// guessGameInfo names a game whose PARAM.SFO couldn't be read from what else is known: the
+// title ID in PS3_DISC.SFB or the folder name, and the title in the folder name. The
+// result is marked unverified; cause is returned when either can't be found.
+func (h *PS3Handler) guessGameInfo(gameRoot string, cause error) (*common.GameInfo, error) {
+	name := filepath.Base(gameRoot)
+	if strings.EqualFold(name, "game") {
+		// The game/ folder of an organized game is named by the folder around it
+		name = filepath.Base(filepath.Dir(gameRoot))
+	}
+	title, titleID := parseGameFolderName(name)
+
+	fromSFB := false
+	if data, err := os.ReadFile(filepath.Join(gameRoot, "PS3_DISC.SFB")); err == nil {
+		if sfb, err := parsers.ParseDiscSFB(data); err == nil && sfb.TitleID() != "" {
+			titleID, fromSFB = strings.ToUpper(sfb.TitleID()), true
+		}
+	}
+
+	switch {
+	case titleID == "":
+		return nil, fmt.Errorf("%w, and no title ID was found in PS3_DISC.SFB or the folder name %q: name the game with --set-title and --set-id", cause, name)
+	case title == "":
+		return nil, fmt.Errorf("%w, and no title was found in the folder name %q: name the game with --set-title and --set-id", cause, name)
+	}
+	if fromSFB {
+		ui.Warnf("%v; using the title %q from the folder name and the ID %s from PS3_DISC.SFB (metadata unverified)\n", cause, title, titleID)
+	} else {
+		ui.Warnf("%v; using %q [%s] from the folder name (metadata unverified)\n", cause, title, titleID)
+	}
+
+	return &common.GameInfo{
+		Title:      title,
+		GameID:     titleID,
+		Console:    h.GetConsoleDisplayName(),
+		Source:     gameRoot,
+		Unverified: true,
+	}, nil
+}
+
+// parseGameFolderName splits a folder name such as "Demon's Souls [BLUS30443]",
+// "BLUS30443-Demons_Souls" or "Demon's Souls (USA) [BLUS-30443]" into a title and an
+// upper-case title ID, either of which may be ""
+func parseGameFolderName(name string) (title, titleID string) {
+	// Dots separate words only in names without spaces (Demons.Souls.BLUS30443)
+	dotted := !strings.ContainsAny(name, " _")
+	if m := folderNameTitleID.FindStringSubmatchIndex(name); m != nil {
+		titleID = strings.ToUpper(name[m[2]:m[3]] + name[m[4]:m[5]])
+		name = name[:m[2]] + " " + name[m[5]:]
+	}
+	name = folderNameTags.ReplaceAllString(name, " ")
+	name = strings.ReplaceAll(name, "_", " ")
+	if dotted {
+		name = strings.ReplaceAll(name, ".", " ")
+	}
+	title = strings.Trim(strings.Join(strings.Fields(name), " "), " -_.[]()")
+	return title, titleID
+}
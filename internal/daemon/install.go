@@ -0,0 +1,188 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// Service is a long-running rom-organizer command to install as a systemd unit or a
+// Windows scheduled task
+type Service struct {
+	Name       string   // Short name, e.g. "serve"; the unit is rom-organizer-{Name}
+	Executable string   // Absolute path of rom-organizer
+	Args       []string // Arguments after the executable, including --pid-file
+	User       bool     // Run for the current user at logon instead of system-wide at boot
+	Home       string   // HOME of the installing user, so default config, catalog and queue paths resolve
+}
+
+// UnitName is the name of the systemd unit or scheduled task
+func (s Service) UnitName() string {
+	return "rom-organizer-" + s.Name
+}
+
+// SystemdPath is where the unit file is installed: /etc/systemd/system, or the user's
+// systemd folder for a per-user unit
+func (s Service) SystemdPath() (string, error) {
+	if !s.User {
+		return filepath.Join("/etc/systemd/system", s.UnitName()+".service"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user", s.UnitName()+".service"), nil
+}
+
+// SystemdUnit renders the service as a systemd unit. SIGHUP (systemctl reload) re-reads
+// the config; SIGTERM (systemctl stop) lets the current task, job or game finish first.
+func (s Service) SystemdUnit() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=rom-organizer %s\n", s.Name)
+	if !s.User {
+		fmt.Fprintf(&b, "Wants=network-online.target\n")
+		fmt.Fprintf(&b, "After=network-online.target remote-fs.target\n")
+	}
+
+	fmt.Fprintf(&b, "\n[Service]\n")
+	fmt.Fprintf(&b, "Type=simple\n")
+	if s.Home != "" && !s.User {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote("HOME="+s.Home))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", systemdCommand(append([]string{s.Executable}, s.Args...)))
+	fmt.Fprintf(&b, "ExecReload=/bin/kill -HUP $MAINPID\n")
+	fmt.Fprintf(&b, "Restart=on-failure\n")
+	fmt.Fprintf(&b, "RestartSec=30\n")
+	fmt.Fprintf(&b, "# Stopping waits for the current task, job or game to finish\n")
+	fmt.Fprintf(&b, "TimeoutStopSec=1h\n")
+
+	fmt.Fprintf(&b, "\n[Install]\n")
+	if s.User {
+		fmt.Fprintf(&b, "WantedBy=default.target\n")
+	} else {
+		fmt.Fprintf(&b, "WantedBy=multi-user.target\n")
+	}
+	return b.String()
+}
+
+// systemdCommand joins a command line for ExecStart, quoting arguments as systemd reads them
+func systemdCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdQuote escapes the specifier and variable characters of a unit file value and
+// double-quotes it when it contains spaces or quotes
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// TaskXML renders the service as a Windows Task Scheduler definition for
+// "schtasks /Create /XML": started at boot as SYSTEM, or at logon for the current user
+// with User, restarted after failures, and without the default 72 hour time limit.
+func (s Service) TaskXML() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-16"?>` + "\n")
+	b.WriteString(`<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">` + "\n")
+	fmt.Fprintf(&b, "  <RegistrationInfo>\n    <Description>%s</Description>\n  </RegistrationInfo>\n", xmlText("rom-organizer "+s.Name))
+
+	b.WriteString("  <Triggers>\n")
+	if s.User {
+		fmt.Fprintf(&b, "    <LogonTrigger>\n      <UserId>%s</UserId>\n    </LogonTrigger>\n", xmlText(windowsUser()))
+	} else {
+		b.WriteString("    <BootTrigger />\n")
+	}
+	b.WriteString("  </Triggers>\n")
+
+	b.WriteString("  <Principals>\n    <Principal id=\"Author\">\n")
+	if s.User {
+		fmt.Fprintf(&b, "      <UserId>%s</UserId>\n      <LogonType>InteractiveToken</LogonType>\n", xmlText(windowsUser()))
+	} else {
+		b.WriteString("      <UserId>S-1-5-18</UserId>\n      <RunLevel>HighestAvailable</RunLevel>\n")
+	}
+	b.WriteString("    </Principal>\n  </Principals>\n")
+
+	b.WriteString("  <Settings>\n")
+	b.WriteString("    <MultipleInstancesPolicy>IgnoreNew</MultipleInstancesPolicy>\n")
+	b.WriteString("    <DisallowStartIfOnBatteries>false</DisallowStartIfOnBatteries>\n")
+	b.WriteString("    <StopIfGoingOnBatteries>false</StopIfGoingOnBatteries>\n")
+	b.WriteString("    <ExecutionTimeLimit>PT0S</ExecutionTimeLimit>\n")
+	b.WriteString("    <RestartOnFailure>\n      <Interval>PT1M</Interval>\n      <Count>10</Count>\n    </RestartOnFailure>\n")
+	b.WriteString("  </Settings>\n")
+
+	b.WriteString("  <Actions Context=\"Author\">\n    <Exec>\n")
+	fmt.Fprintf(&b, "      <Command>%s</Command>\n", xmlText(s.Executable))
+	fmt.Fprintf(&b, "      <Arguments>%s</Arguments>\n", xmlText(windowsCommand(s.Args)))
+	b.WriteString("    </Exec>\n  </Actions>\n")
+	b.WriteString("</Task>\n")
+	return b.String()
+}
+
+// TaskXMLFile encodes the task definition as UTF-16 with a byte order mark, the
+// encoding schtasks reads reliably
+func (s Service) TaskXMLFile() []byte {
+	units := utf16.Encode([]rune(s.TaskXML()))
+	data := []byte{0xff, 0xfe}
+	for _, u := range units {
+		data = append(data, byte(u), byte(u>>8))
+	}
+	return data
+}
+
+// windowsUser is the current user as DOMAIN\name
+func windowsUser() string {
+	user := os.Getenv("USERNAME")
+	if domain := os.Getenv("USERDOMAIN"); domain != "" {
+		return domain + `\` + user
+	}
+	return user
+}
+
+// windowsCommand joins arguments the way Windows programs split their command line
+func windowsCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+			quoted[i] = arg
+			continue
+		}
+		var b strings.Builder
+		b.WriteByte('"')
+		slashes := 0
+		for _, c := range arg {
+			switch c {
+			case '\\':
+				slashes++
+				continue
+			case '"':
+				b.WriteString(strings.Repeat(`\`, 2*slashes+1))
+			default:
+				b.WriteString(strings.Repeat(`\`, slashes))
+			}
+			slashes = 0
+			b.WriteRune(c)
+		}
+		b.WriteString(strings.Repeat(`\`, 2*slashes))
+		b.WriteByte('"')
+		quoted[i] = b.String()
+	}
+	return strings.Join(quoted, " ")
+}
+
+func xmlText(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,142 @@
+// Package daemon gives the long-running commands (schedule daemon, serve, jobs run
+// --watch) service semantics: PID files, a status check, and unit or task definitions
+// that install them as a systemd service or a Windows scheduled task
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pidSuffix is the extension of PID files
+const pidSuffix = ".pid"
+
+// PIDPath is the PID file of a daemon installed with "daemon install": in the user's
+// runtime folder for a per-user daemon, or the system-wide one
+func PIDPath(name string, user bool) string {
+	userDir, systemDir := pidDirs()
+	if user {
+		return filepath.Join(userDir, name+pidSuffix)
+	}
+	return filepath.Join(systemDir, name+pidSuffix)
+}
+
+// PIDDirs are the folders holding the PID files of installed daemons
+func PIDDirs() []string {
+	user, system := pidDirs()
+	if user == system {
+		return []string{user}
+	}
+	return []string{user, system}
+}
+
+// FindPID returns the PID file of the named daemon, per-user or system-wide, or the
+// system-wide path if there is neither
+func FindPID(name string) string {
+	for _, dir := range PIDDirs() {
+		path := filepath.Join(dir, name+pidSuffix)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return PIDPath(name, false)
+}
+
+// PIDFile is the PID file of a running daemon
+type PIDFile struct {
+	Path string
+}
+
+// WritePID records the current process in path. It fails if another process that is
+// still running already holds the file, so the same daemon isn't started twice.
+func WritePID(path string) (*PIDFile, error) {
+	if status, err := ReadStatus(path); err == nil && status.Running {
+		return nil, fmt.Errorf("already running as pid %d (%s)", status.PID, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating PID file folder: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("writing PID file: %w", err)
+	}
+	return &PIDFile{Path: path}, nil
+}
+
+// Remove deletes the PID file if it still names the current process
+func (p *PIDFile) Remove() {
+	if p == nil {
+		return
+	}
+	if pid, err := readPID(p.Path); err == nil && pid == os.Getpid() {
+		os.Remove(p.Path)
+	}
+}
+
+// Status describes a daemon from its PID file
+type Status struct {
+	Name    string    `json:"name"`
+	PIDFile string    `json:"pid_file"`
+	PID     int       `json:"pid"`
+	Running bool      `json:"running"`
+	Since   time.Time `json:"since"` // When the PID file was written
+}
+
+// ReadStatus reads a PID file and checks whether its process is still running. A PID
+// file left behind by a daemon that was killed reports Running false.
+func ReadStatus(path string) (*Status, error) {
+	pid, err := readPID(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		Name:    strings.TrimSuffix(filepath.Base(path), pidSuffix),
+		PIDFile: path,
+		PID:     pid,
+		Running: processRunning(pid),
+		Since:   info.ModTime(),
+	}, nil
+}
+
+// ListStatus reads every PID file in PIDDirs, sorted by name
+func ListStatus() ([]*Status, error) {
+	var paths []string
+	for _, dir := range PIDDirs() {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+pidSuffix))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	var statuses []*Status
+	for _, path := range paths {
+		status, err := ReadStatus(path)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+func readPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: not a PID file", path)
+	}
+	return pid, nil
+}
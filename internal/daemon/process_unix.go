@@ -0,0 +1,29 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// systemPIDDir is where system-wide daemons keep their PID files
+const systemPIDDir = "/run/rom-organizer"
+
+// pidDirs returns the user's runtime folder and the system-wide /run/rom-organizer
+func pidDirs() (user, system string) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "rom-organizer"), systemPIDDir
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("rom-organizer-%d", os.Getuid())), systemPIDDir
+}
+
+// processRunning reports whether a process with this PID exists. A process owned by
+// another user can't be signalled but still counts.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
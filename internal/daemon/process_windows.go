@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// processQueryLimitedInformation is the least access that allows GetExitCodeProcess
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code GetExitCodeProcess reports for a running process
+const stillActive = 259
+
+// pidDirs returns %ProgramData%\rom-organizer for both user and system tasks, since
+// tasks run as SYSTEM and users share it
+func pidDirs() (user, system string) {
+	dir := os.Getenv("ProgramData")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "rom-organizer")
+	return dir, dir
+}
+
+// processRunning reports whether a process with this PID exists and hasn't exited
+func processRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// Access denied still means there is such a process
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
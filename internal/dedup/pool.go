@@ -0,0 +1,299 @@
+// Package dedup stores identical files of decompressed games once in a
+// content-addressed pool. Game files are replaced by hard links to pool
+// objects, so games stay playable in place, and each game keeps a manifest
+// of the objects it uses.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// DefaultPoolDir is the pool folder created in a library root
+const DefaultPoolDir = "_pool"
+
+// ManifestName is the manifest written next to a pooled game's game/ folder
+const ManifestName = "game.manifest.json"
+
+// ManifestFile is one file of a pooled game
+type ManifestFile struct {
+	Path string `json:"path"` // Relative to game/, with forward slashes
+	Hash string `json:"sha256"`
+	Size int64  `json:"size"`
+}
+
+// Manifest lists the pool objects used by a game
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// Stats summarizes adding games to a pool
+type Stats struct {
+	Files  int   // Files in the games
+	Shared int   // Files whose content was already in the pool
+	Saved  int64 // Bytes no longer stored twice
+}
+
+// HashAlgorithm is the hash naming pool objects
+const HashAlgorithm = common.HashSHA256
+
+// Pool is a content-addressed store of file objects named by their SHA-256
+type Pool struct {
+	Root string
+}
+
+// OpenPool opens the pool at root, creating it if needed
+func OpenPool(root string) (*Pool, error) {
+	if err := os.MkdirAll(filepath.Join(root, "objects"), 0755); err != nil {
+		return nil, fmt.Errorf("creating pool: %w", err)
+	}
+	return &Pool{Root: root}, nil
+}
+
+// objectPath returns where the object with the given hash is stored
+func (p *Pool) objectPath(hash string) string {
+	return filepath.Join(p.Root, "objects", hash[:2], hash)
+}
+
+// ManifestPath returns the manifest path of an organized game directory
+func ManifestPath(gameDir string) string {
+	return filepath.Join(gameDir, ManifestName)
+}
+
+// ReadManifest reads the manifest of an organized game directory
+func ReadManifest(gameDir string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(gameDir))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ManifestPath(gameDir), err)
+	}
+	return &m, nil
+}
+
+// IsPooled reports whether an organized game directory has a manifest
+func IsPooled(gameDir string) bool {
+	_, err := os.Stat(ManifestPath(gameDir))
+	return err == nil
+}
+
+// AddGame moves the files of an organized game's game/ folder into the pool and
+// replaces them with hard links. Files already linked to the pool are not rehashed.
+// The pool must be on the same file system as the game.
+func (p *Pool) AddGame(gameDir string) (*Stats, error) {
+	return p.AddCopiedGame(gameDir, nil)
+}
+
+// AddCopiedGame adds a game like AddGame, taking the SHA-256 of the files just copied
+// into game/ from the digests computed by the copy instead of reading them again
+func (p *Pool) AddCopiedGame(gameDir string, copied common.FileHashes) (*Stats, error) {
+	root := filepath.Join(gameDir, "game")
+	known := make(map[string]string)
+	if m, err := ReadManifest(gameDir); err == nil {
+		for _, f := range m.Files {
+			known[f.Path] = f.Hash
+		}
+	}
+
+	stats := &Stats{}
+	var manifest Manifest
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stats.Files++
+
+		hash, linked := known[rel], false
+		if hash != "" {
+			linked = sameFile(path, p.objectPath(hash))
+		}
+		if !linked {
+			if hash = copied.Sum(rel, HashAlgorithm); hash == "" {
+				if hash, err = hashFile(path); err != nil {
+					return err
+				}
+			}
+			shared, err := p.store(path, hash)
+			if err != nil {
+				return err
+			}
+			if shared {
+				stats.Shared++
+				stats.Saved += info.Size()
+			}
+		}
+
+		manifest.Files = append(manifest.Files, ManifestFile{Path: rel, Hash: hash, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pooling %s: %w", gameDir, err)
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	if err := writeManifest(gameDir, &manifest); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// store links a game file into the pool. When the pool already has the content, the
+// file is replaced by a link to the existing object and shared is true.
+func (p *Pool) store(path, hash string) (shared bool, err error) {
+	object := p.objectPath(hash)
+	if _, err := os.Stat(object); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+			return false, err
+		}
+		if err := os.Link(path, object); err != nil {
+			return false, fmt.Errorf("linking into pool (the pool must be on the same file system as the library): %w", err)
+		}
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if sameFile(path, object) {
+		return false, nil
+	}
+
+	// Link next to the file first so it is replaced atomically
+	temp := path + ".pool-link"
+	os.Remove(temp)
+	if err := os.Link(object, temp); err != nil {
+		return false, fmt.Errorf("linking from pool (the pool must be on the same file system as the library): %w", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveGame replaces a pooled game's links with independent copies and deletes its
+// manifest, so the game's files can be changed without affecting other games
+func RemoveGame(gameDir string) error {
+	m, err := ReadManifest(gameDir)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+
+	for _, f := range m.Files {
+		path := filepath.Join(gameDir, "game", filepath.FromSlash(f.Path))
+		temp := path + ".pool-copy"
+		if err := common.CopyFile(path, temp); err != nil {
+			os.Remove(temp)
+			return fmt.Errorf("copying %s: %w", f.Path, err)
+		}
+		if err := os.Rename(temp, path); err != nil {
+			os.Remove(temp)
+			return fmt.Errorf("replacing %s: %w", f.Path, err)
+		}
+	}
+	return RemoveManifest(gameDir)
+}
+
+// RemoveManifest deletes a game's manifest, if it has one. Used when its game/
+// folder is removed; the objects are freed by the next GC.
+func RemoveManifest(gameDir string) error {
+	if err := os.Remove(ManifestPath(gameDir)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing manifest: %w", err)
+	}
+	return nil
+}
+
+// GC deletes pool objects that no manifest in gameDirs references and returns the
+// number of objects and bytes freed
+func (p *Pool) GC(gameDirs []string) (int, int64, error) {
+	used := make(map[string]bool)
+	for _, dir := range gameDirs {
+		m, err := ReadManifest(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, f := range m.Files {
+			used[f.Hash] = true
+		}
+	}
+
+	var removed int
+	var freed int64
+	err := filepath.WalkDir(filepath.Join(p.Root, "objects"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || used[d.Name()] {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	if err != nil {
+		return removed, freed, fmt.Errorf("collecting pool garbage: %w", err)
+	}
+	return removed, freed, nil
+}
+
+func writeManifest(gameDir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(ManifestPath(gameDir), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,200 @@
+package dedup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// makeGame creates an organized game folder in dir whose game/ folder holds the files
+func makeGame(t *testing.T, dir, name string, files map[string]string) string {
+	t.Helper()
+	gameDir := filepath.Join(dir, name)
+	for rel, data := range files {
+		path := filepath.Join(gameDir, "game", filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return gameDir
+}
+
+// objects returns the paths of the objects in the pool
+func objects(t *testing.T, p *Pool) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(p.Root, "objects", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// newPoolGames returns a pool and two games sharing PS3_GAME/USRDIR/movie.pam
+func newPoolGames(t *testing.T) (p *Pool, a, b string) {
+	t.Helper()
+	library := t.TempDir()
+	p, err := OpenPool(filepath.Join(library, DefaultPoolDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = makeGame(t, library, "Game A [BLUS30001]", map[string]string{
+		"PS3_GAME/USRDIR/movie.pam": "shared movie",
+		"PS3_GAME/PARAM.SFO":        "sfo a",
+	})
+	b = makeGame(t, library, "Game A [BLES00001]", map[string]string{
+		"PS3_GAME/USRDIR/movie.pam": "shared movie",
+		"PS3_GAME/PARAM.SFO":        "sfo b",
+	})
+	return p, a, b
+}
+
+func TestAddGameSharesFiles(t *testing.T) {
+	p, a, b := newPoolGames(t)
+
+	stats, err := p.AddGame(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (Stats{Files: 2}) {
+		t.Errorf("first game: %+v, want 2 files and nothing shared", *stats)
+	}
+	stats, err = p.AddGame(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (Stats{Files: 2, Shared: 1, Saved: int64(len("shared movie"))}) {
+		t.Errorf("second game: %+v, want the movie shared", *stats)
+	}
+	if n := len(objects(t, p)); n != 3 {
+		t.Errorf("pool has %d objects, want 3", n)
+	}
+
+	// Both games and the pool object are links to one file
+	movieA := filepath.Join(a, "game", "PS3_GAME", "USRDIR", "movie.pam")
+	movieB := filepath.Join(b, "game", "PS3_GAME", "USRDIR", "movie.pam")
+	m, err := ReadManifest(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var object string
+	for _, f := range m.Files {
+		if f.Path == "PS3_GAME/USRDIR/movie.pam" {
+			object = p.objectPath(f.Hash)
+		}
+	}
+	if !sameFile(movieA, movieB) || !sameFile(movieA, object) {
+		t.Errorf("movie is not linked to pool object %q in both games", object)
+	}
+	if sameFile(filepath.Join(a, "game", "PS3_GAME", "PARAM.SFO"), filepath.Join(b, "game", "PS3_GAME", "PARAM.SFO")) {
+		t.Error("different files were linked together")
+	}
+
+	// Adding again finds everything linked already
+	stats, err = p.AddGame(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (Stats{Files: 2}) {
+		t.Errorf("adding again: %+v, want nothing new shared", *stats)
+	}
+}
+
+func TestGCFreesUnreferencedObjects(t *testing.T) {
+	p, a, b := newPoolGames(t)
+	for _, dir := range []string{a, b} {
+		if _, err := p.AddGame(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing is freed while both games reference their objects
+	if removed, _, err := p.GC([]string{a, b}); err != nil || removed != 0 {
+		t.Fatalf("GC with both games = %d, %v; want nothing removed", removed, err)
+	}
+
+	if err := RemoveGame(b); err != nil {
+		t.Fatal(err)
+	}
+	if IsPooled(b) {
+		t.Error("game still has a manifest after RemoveGame")
+	}
+	if readFile(t, filepath.Join(b, "game", "PS3_GAME", "PARAM.SFO")) != "sfo b" {
+		t.Error("RemoveGame changed the game's files")
+	}
+
+	removed, freed, err := p.GC([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || freed != int64(len("sfo b")) {
+		t.Errorf("GC = %d objects, %d bytes; want only the PARAM.SFO of the removed game", removed, freed)
+	}
+	if n := len(objects(t, p)); n != 2 {
+		t.Errorf("pool has %d objects after GC, want the 2 of the remaining game", n)
+	}
+	if readFile(t, filepath.Join(a, "game", "PS3_GAME", "USRDIR", "movie.pam")) != "shared movie" {
+		t.Error("GC changed a game that is still pooled")
+	}
+}
+
+// TestWritersLeavePoolAlone checks that replacing or copying over a pooled file gives the
+// game its own file instead of changing the object every game links to
+func TestWritersLeavePoolAlone(t *testing.T) {
+	p, a, b := newPoolGames(t)
+	for _, dir := range []string{a, b} {
+		if _, err := p.AddGame(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	movieA := filepath.Join(a, "game", "PS3_GAME", "USRDIR", "movie.pam")
+	movieB := filepath.Join(b, "game", "PS3_GAME", "USRDIR", "movie.pam")
+
+	if err := common.ReplaceFile(movieA, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, movieA) != "edited" || readFile(t, movieB) != "shared movie" {
+		t.Fatalf("ReplaceFile: game A has %q, game B has %q", readFile(t, movieA), readFile(t, movieB))
+	}
+
+	source := filepath.Join(t.TempDir(), "movie.pam")
+	if err := os.WriteFile(source, []byte("copied"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := common.CopyFile(source, movieB); err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, movieB) != "copied" {
+		t.Fatalf("CopyFile: game B has %q", readFile(t, movieB))
+	}
+	for _, object := range objects(t, p) {
+		if data := readFile(t, object); data == "edited" || data == "copied" {
+			t.Errorf("pool object %s was written through a game", object)
+		}
+	}
+
+	// The next add pools the new content
+	stats, err := p.AddGame(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Shared != 0 || len(objects(t, p)) != 4 {
+		t.Errorf("adding the edited game: %+v, %d objects; want the edit stored as a new object", *stats, len(objects(t, p)))
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(movieA)); len(entries) != 1 {
+		t.Errorf("%d files next to the edited one, want no temporary files left", len(entries)-1)
+	}
+}
@@ -0,0 +1,271 @@
+// Package delta records changes to a compressed game as small archives next to its
+// game.7z, so a revised dump does not require recompressing the whole game
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// Dir is the folder in an organized game directory that holds its deltas
+const Dir = "_deltas"
+
+// nameFormat names deltas by creation time, so they sort in the order they apply
+const nameFormat = "20060102-150405"
+
+// ErrNoChanges is returned by Create when the source matches the current game
+var ErrNoChanges = errors.New("no changes")
+
+// Delta is one set of changes applied on top of game.7z and the deltas before it.
+// Changed and added files are stored in <name>.7z; the rest is in <name>.json.
+type Delta struct {
+	Name    string    `json:"-"`
+	Created time.Time `json:"created"`
+	Changed []string  `json:"changed,omitempty"`
+	Added   []string  `json:"added,omitempty"`
+	Removed []string  `json:"removed,omitempty"`
+}
+
+// fileState identifies the content of a file as 7z lists it
+type fileState struct {
+	Size int64
+	CRC  string
+}
+
+// ArchivePath returns the archive holding the delta's changed and added files
+func (d *Delta) ArchivePath(gameDir string) string {
+	return filepath.Join(gameDir, Dir, d.Name+".7z")
+}
+
+func (d *Delta) manifestPath(gameDir string) string {
+	return filepath.Join(gameDir, Dir, d.Name+".json")
+}
+
+// List returns the deltas of an organized game directory in the order they apply
+func List(gameDir string) ([]*Delta, error) {
+	entries, err := os.ReadDir(filepath.Join(gameDir, Dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading deltas: %w", err)
+	}
+
+	var deltas []*Delta
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(gameDir, Dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading delta %s: %w", name, err)
+		}
+		d := &Delta{Name: name}
+		if err := json.Unmarshal(data, d); err != nil {
+			return nil, fmt.Errorf("parsing delta %s: %w", name, err)
+		}
+		if err := d.checkPaths(); err != nil {
+			return nil, fmt.Errorf("parsing delta %s: %w", name, err)
+		}
+		deltas = append(deltas, d)
+	}
+
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].Name < deltas[j].Name })
+	return deltas, nil
+}
+
+// checkPaths rejects file paths that leave the game root, such as "../x" or "/x", so an
+// edited or corrupt manifest can't make Materialize remove files outside its destination
+func (d *Delta) checkPaths() error {
+	for _, paths := range [][]string{d.Changed, d.Added, d.Removed} {
+		for _, path := range paths {
+			if !filepath.IsLocal(filepath.FromSlash(path)) {
+				return fmt.Errorf("file path %q is outside the game", path)
+			}
+		}
+	}
+	return nil
+}
+
+// currentState returns the files of the game as materialized from game.7z and deltas
+func currentState(gameDir string, deltas []*Delta) (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	addListing := func(archivePath string) error {
+		entries, err := common.List7zArchive(archivePath)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir {
+				state[entry.Path] = fileState{Size: entry.Size, CRC: strings.ToUpper(entry.CRC)}
+			}
+		}
+		return nil
+	}
+
+	if err := addListing(filepath.Join(gameDir, "game.7z")); err != nil {
+		return nil, err
+	}
+	for _, d := range deltas {
+		for _, path := range d.Removed {
+			delete(state, path)
+		}
+		if len(d.Changed)+len(d.Added) > 0 {
+			if err := addListing(d.ArchivePath(gameDir)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return state, nil
+}
+
+// scanSource returns the size and CRC32 of every file under root
+func scanSource(root string) (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := crc32.NewIEEE()
+		if _, err := io.Copy(h, f); err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		state[filepath.ToSlash(rel)] = fileState{Size: info.Size(), CRC: fmt.Sprintf("%08X", h.Sum32())}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	return state, nil
+}
+
+// Create compares sourceDir (the game root, as packed in game.7z) with the current
+// game and stores the differences as a new delta. It returns ErrNoChanges when
+// there are none.
+func Create(gameDir, sourceDir string, opts common.ArchiveOptions) (*Delta, error) {
+	deltas, err := List(gameDir)
+	if err != nil {
+		return nil, err
+	}
+	current, err := currentState(gameDir, deltas)
+	if err != nil {
+		return nil, err
+	}
+	source, err := scanSource(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	d := &Delta{Name: now.Format(nameFormat), Created: now}
+	if len(deltas) > 0 && deltas[len(deltas)-1].Name >= d.Name {
+		return nil, fmt.Errorf("a delta named %s already exists, try again in a second", d.Name)
+	}
+	for path, state := range source {
+		old, ok := current[path]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, path)
+		case old != state:
+			d.Changed = append(d.Changed, path)
+		}
+	}
+	for path := range current {
+		if _, ok := source[path]; !ok {
+			d.Removed = append(d.Removed, path)
+		}
+	}
+	if len(d.Changed)+len(d.Added)+len(d.Removed) == 0 {
+		return nil, ErrNoChanges
+	}
+	sort.Strings(d.Changed)
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+
+	if err := os.MkdirAll(filepath.Join(gameDir, Dir), 0755); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", Dir, err)
+	}
+	if files := append(append([]string{}, d.Changed...), d.Added...); len(files) > 0 {
+		for i, file := range files {
+			files[i] = filepath.FromSlash(file)
+		}
+		archivePath := d.ArchivePath(gameDir)
+		if err := common.Create7zArchiveFromList(sourceDir, files, archivePath, opts); err != nil {
+			os.Remove(archivePath)
+			return nil, fmt.Errorf("creating delta archive: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding delta: %w", err)
+	}
+	if err := os.WriteFile(d.manifestPath(gameDir), append(data, '\n'), 0644); err != nil {
+		os.Remove(d.ArchivePath(gameDir))
+		return nil, fmt.Errorf("writing delta: %w", err)
+	}
+	return d, nil
+}
+
+// Materialize extracts game.7z into dest and applies the deltas in order, stopping
+// after the delta named upTo (all deltas when upTo is empty)
+func Materialize(gameDir, dest, upTo string) error {
+	deltas, err := List(gameDir)
+	if err != nil {
+		return err
+	}
+	if upTo != "" {
+		found := false
+		for i, d := range deltas {
+			if d.Name == upTo {
+				deltas, found = deltas[:i+1], true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("delta %s not found", upTo)
+		}
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", dest, err)
+	}
+	if err := common.Extract7zArchive(filepath.Join(gameDir, "game.7z"), dest); err != nil {
+		return err
+	}
+	for _, d := range deltas {
+		for _, path := range d.Removed {
+			if err := os.Remove(filepath.Join(dest, filepath.FromSlash(path))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("applying delta %s: %w", d.Name, err)
+			}
+		}
+		if len(d.Changed)+len(d.Added) > 0 {
+			if err := common.Extract7zArchive(d.ArchivePath(gameDir), dest); err != nil {
+				return fmt.Errorf("applying delta %s: %w", d.Name, err)
+			}
+		}
+	}
+	return nil
+}
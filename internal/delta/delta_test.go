@@ -0,0 +1,158 @@
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList(t *testing.T) {
+	gameDir := t.TempDir()
+	if deltas, err := List(gameDir); err != nil || deltas != nil {
+		t.Fatalf("List without a %s folder = %v, %v, want no deltas", Dir, deltas, err)
+	}
+
+	// Deltas apply in name order; archives and other files are not deltas of their own
+	writeFile(t, filepath.Join(gameDir, Dir, "20260301-120000.json"), `{"changed": ["PS3_GAME/USRDIR/EBOOT.BIN"]}`)
+	writeFile(t, filepath.Join(gameDir, Dir, "20260301-120000.7z"), "archive")
+	writeFile(t, filepath.Join(gameDir, Dir, "20260105-080000.json"), `{"removed": ["PS3_GAME/ICON0.PNG"]}`)
+	writeFile(t, filepath.Join(gameDir, Dir, "notes.txt"), "not a delta")
+	deltas, err := List(gameDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range deltas {
+		names = append(names, d.Name)
+	}
+	if want := []string{"20260105-080000", "20260301-120000"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("deltas %v, want %v", names, want)
+	}
+	if !reflect.DeepEqual(deltas[0].Removed, []string{"PS3_GAME/ICON0.PNG"}) || !reflect.DeepEqual(deltas[1].Changed, []string{"PS3_GAME/USRDIR/EBOOT.BIN"}) {
+		t.Errorf("deltas read as %+v, %+v", deltas[0], deltas[1])
+	}
+	if path := deltas[1].ArchivePath(gameDir); path != filepath.Join(gameDir, Dir, "20260301-120000.7z") {
+		t.Errorf("archive path %s", path)
+	}
+
+	writeFile(t, filepath.Join(gameDir, Dir, "20260401-000000.json"), "{")
+	if _, err := List(gameDir); err == nil {
+		t.Error("listed a delta with a corrupt manifest")
+	}
+
+	// Paths leaving the game root would have Materialize remove files outside its destination
+	for _, path := range []string{"../outside", "PS3_GAME/../../outside", "/etc/passwd", ""} {
+		manifest, _ := json.Marshal(Delta{Removed: []string{path}})
+		writeFile(t, filepath.Join(gameDir, Dir, "20260401-000000.json"), string(manifest))
+		if _, err := List(gameDir); err == nil {
+			t.Errorf("listed a delta removing %q", path)
+		}
+		if err := Materialize(gameDir, t.TempDir(), ""); err == nil {
+			t.Errorf("materialized a delta removing %q", path)
+		}
+	}
+}
+
+func TestScanSource(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "PS3_GAME", "PARAM.SFO"), "param")
+	writeFile(t, filepath.Join(src, "PS3_DISC.SFB"), "")
+	if err := os.MkdirAll(filepath.Join(src, "PS3_GAME", "USRDIR"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := scanSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]fileState{
+		"PS3_GAME/PARAM.SFO": {Size: 5, CRC: "A4FA7C89"},
+		"PS3_DISC.SFB":       {Size: 0, CRC: "00000000"},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("scanned %v, want %v", state, want)
+	}
+}
+
+func TestCreateAndMaterialize(t *testing.T) {
+	found := false
+	for _, name := range common.SevenZipCommands {
+		if _, err := exec.LookPath(name); err == nil {
+			found = true
+		}
+	}
+	if !found {
+		t.Skip("7z not installed")
+	}
+
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "PS3_GAME", "PARAM.SFO"), "param")
+	writeFile(t, filepath.Join(src, "PS3_GAME", "ICON0.PNG"), "icon")
+	writeFile(t, filepath.Join(src, "PS3_GAME", "USRDIR", "EBOOT.BIN"), "eboot v1")
+	gameDir := t.TempDir()
+	if err := common.Create7zArchive(src, filepath.Join(gameDir, "game.7z"), common.ArchiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A revised dump changes, adds and removes a file
+	writeFile(t, filepath.Join(src, "PS3_GAME", "USRDIR", "EBOOT.BIN"), "eboot v2")
+	writeFile(t, filepath.Join(src, "PS3_GAME", "USRDIR", "patch.dat"), "patch")
+	if err := os.Remove(filepath.Join(src, "PS3_GAME", "ICON0.PNG")); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Create(gameDir, src, common.ArchiveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Changed, []string{"PS3_GAME/USRDIR/EBOOT.BIN"}) ||
+		!reflect.DeepEqual(d.Added, []string{"PS3_GAME/USRDIR/patch.dat"}) ||
+		!reflect.DeepEqual(d.Removed, []string{"PS3_GAME/ICON0.PNG"}) {
+		t.Errorf("delta %+v", d)
+	}
+
+	// Give the delta an older name, so the next one doesn't clash within the same second
+	for _, ext := range []string{".json", ".7z"} {
+		if err := os.Rename(filepath.Join(gameDir, Dir, d.Name+ext), filepath.Join(gameDir, Dir, "20000101-000000"+ext)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Create(gameDir, src, common.ArchiveOptions{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Create with an unchanged source = %v, want ErrNoChanges", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "game")
+	if err := Materialize(gameDir, dest, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := scanSource(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := scanSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("materialized %v, want the revised source %v", got, want)
+	}
+
+	if err := Materialize(gameDir, filepath.Join(t.TempDir(), "game"), "20990101-000000"); err == nil {
+		t.Error("materialized up to a delta that doesn't exist")
+	}
+}
@@ -0,0 +1,55 @@
+package detect
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DetectConsoleFromListing identifies the console of a game packed in an archive from the
+// paths of its entries (slash-separated, relative to the archive root), without extracting
+// it. GamePath is the folder inside the archive that holds the game, "" for its root.
+func DetectConsoleFromListing(paths []string) *DetectionResult {
+	result := &DetectionResult{
+		ConsoleType:    Unknown,
+		AmbiguousFiles: make([]string, 0),
+	}
+
+	// Only names are known without extracting, so there are no magic or size signals
+	var scores scores
+	for _, entry := range paths {
+		parts := strings.Split(strings.Trim(path.Clean(entry), "/"), "/")
+		last := len(parts) - 1
+		for depth, name := range parts {
+			// Hidden folders are skipped, as when searching a directory
+			if strings.HasPrefix(name, ".") || depth > MaxSearchDepth {
+				break
+			}
+			gamePath := strings.Join(parts[:depth], "/")
+			indicators := nameSignals(name, depth < last)
+			for _, m := range indicators {
+				scores.add(m.console, gamePath, depth, m.signal)
+			}
+			if len(indicators) > 0 && depth < last {
+				// The files directly in an indicator folder support it; deeper ones don't count
+				if depth+1 == last {
+					for _, m := range nameSignals(parts[last], false) {
+						m.signal.Path = name + "/" + m.signal.Path
+						scores.add(m.console, gamePath, depth, m.signal)
+					}
+				}
+				break
+			}
+		}
+		if scores.best() == nil && IsAmbiguousFile(parts[last]) {
+			result.AmbiguousFiles = append(result.AmbiguousFiles, entry)
+		}
+	}
+	scores.apply(result)
+
+	if !result.IsValid() && len(result.AmbiguousFiles) > 0 {
+		result.Confidence = 0.3
+		result.IndicatorFound = fmt.Sprintf("Found %d ambiguous files", len(result.AmbiguousFiles))
+	}
+	return result
+}
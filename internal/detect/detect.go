@@ -4,20 +4,55 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/ignore"
 )
 
 const (
-	// MaxSearchDepth limits how deep we'll search to prevent infinite loops
+	// MaxSearchDepth is how deep a search goes by default (--max-depth)
 	MaxSearchDepth = 8
+
+	// MaxSearchEntries is how many files and folders a search looks at by default (--max-entries)
+	MaxSearchEntries = 100000
 )
 
-// DetectConsole analyzes a path and attempts to determine what console type it contains
-// It performs a breadth-first search, stopping early when definitive indicators are found
+// SearchLimits bounds how much of a source tree a search reads, so detection stays
+// cheap on huge or deeply nested intake folders. Zero fields use the defaults.
+type SearchLimits struct {
+	MaxDepth   int // Deepest folder level searched below the source
+	MaxEntries int // Files and folders looked at before the search gives up
+}
+
+// WithDefaults fills in the zero limits with MaxSearchDepth and MaxSearchEntries
+func (l SearchLimits) WithDefaults() SearchLimits {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = MaxSearchDepth
+	}
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = MaxSearchEntries
+	}
+	return l
+}
+
+// DetectConsole analyzes a path and attempts to determine what console type it contains,
+// within the default search limits
 func DetectConsole(rootPath string) (*DetectionResult, error) {
+	return DetectConsoleWithLimits(rootPath, SearchLimits{})
+}
+
+// DetectConsoleWithLimits analyzes a path and attempts to determine what console type it
+// contains. It searches depth-first, stopping early when definitive indicators are found
+// or at the limits, which sets Truncated. Paths ignored by a .romignore in rootPath are
+// skipped.
+func DetectConsoleWithLimits(rootPath string, limits SearchLimits) (*DetectionResult, error) {
 	// Verify the path exists
 	if _, err := os.Stat(rootPath); err != nil {
 		return nil, fmt.Errorf("path does not exist: %w", err)
 	}
+	rules, err := ignore.Load(rootPath)
+	if err != nil {
+		return nil, err
+	}
 
 	result := &DetectionResult{
 		ConsoleType:    Unknown,
@@ -27,12 +62,14 @@ func DetectConsole(rootPath string) (*DetectionResult, error) {
 	}
 
 	// Start recursive search
-	err := searchDirectory(rootPath, rootPath, 0, result)
+	search := &search{limits: limits.WithDefaults(), ignore: rules, result: result}
+	err = search.directory(rootPath, 0)
 	if err != nil {
 		return nil, err
 	}
+	search.scores.apply(result)
 
-	// If we found a definitive indicator, we're done
+	// If an indicator found a game, we're done
 	if result.IsValid() {
 		return result, nil
 	}
@@ -47,15 +84,34 @@ func DetectConsole(rootPath string) (*DetectionResult, error) {
 	return result, nil
 }
 
-// searchDirectory recursively searches a directory for console indicators
-func searchDirectory(currentPath, rootPath string, depth int, result *DetectionResult) error {
-	// Prevent infinite recursion
-	if depth > MaxSearchDepth {
+// search is the state of one DetectConsoleWithLimits call
+type search struct {
+	limits SearchLimits
+	ignore *ignore.Rules
+	result *DetectionResult
+	scores scores
+}
+
+// sure reports whether a candidate found so far is sure enough to stop searching
+func (s *search) sure() bool {
+	best := s.scores.best()
+	return best != nil && best.Confidence >= HighConfidence
+}
+
+// directory recursively searches a directory for signals. The files and folders of a
+// directory are all looked at before its subfolders, so the signals of one game folder
+// combine; the search stops once a candidate is sure.
+func (s *search) directory(currentPath string, depth int) error {
+	result := s.result
+
+	// Prevent infinite recursion and runaway searches
+	if depth > s.limits.MaxDepth {
+		result.Truncated = true
 		return nil
 	}
 
 	// If we already found a high-confidence match, stop searching
-	if result.IsHighConfidence() {
+	if s.sure() {
 		return nil
 	}
 
@@ -65,64 +121,98 @@ func searchDirectory(currentPath, rootPath string, depth int, result *DetectionR
 		return nil
 	}
 
+	var subdirs []string
 	for _, entry := range entries {
 		name := entry.Name()
 		fullPath := filepath.Join(currentPath, name)
 
-		// Skip hidden files and directories
-		if name[0] == '.' {
+		// Skip hidden and ignored files and directories
+		if name[0] == '.' || s.ignore.Ignored(fullPath, entry.IsDir()) {
 			continue
 		}
 
-		// Check for definitive indicators
-		if IsDefinitiveIndicator(name) {
-			console := GetConsoleFromIndicator(name)
+		if result.Entries >= s.limits.MaxEntries {
+			result.Truncated = true
+			return nil
+		}
+		result.Entries++
+
+		// For directory indicators (like PS3_GAME), the parent is the game path
+		// For file indicators (like PARAM.SFO), use the current directory
+		indicators := nameSignals(name, entry.IsDir())
+		for _, m := range indicators {
+			s.scores.add(m.console, currentPath, depth, m.signal)
+		}
 
-			// For directory indicators (like PS3_GAME), the parent is the game path
-			// For file indicators (like PARAM.SFO), use the current directory
-			var gamePath string
-			if entry.IsDir() {
-				gamePath = currentPath // Parent of the indicator directory
+		if entry.IsDir() {
+			if len(indicators) > 0 {
+				// The files of an indicator folder support it; its subfolders aren't searched
+				s.indicatorFolder(fullPath, name, currentPath, depth)
 			} else {
-				gamePath = currentPath // Directory containing the indicator file
+				subdirs = append(subdirs, fullPath)
 			}
-
-			result.ConsoleType = console
-			result.GamePath = gamePath
-			result.Confidence = 0.95 // High confidence for definitive indicators
-			result.IndicatorFound = name
-			result.SearchDepth = depth
-
-			return nil // Stop searching once we find a definitive indicator
+			continue
 		}
 
+		if info, err := entry.Info(); err == nil {
+			for _, m := range fileSignals(fullPath, name, info.Size()) {
+				s.scores.add(m.console, currentPath, depth, m.signal)
+			}
+		}
 		// Check for ambiguous files
-		if !entry.IsDir() && IsAmbiguousFile(name) {
+		if len(indicators) == 0 && IsAmbiguousFile(name) {
 			result.AmbiguousFiles = append(result.AmbiguousFiles, fullPath)
 		}
+	}
 
-		// Recursively search subdirectories
-		if entry.IsDir() {
-			err := searchDirectory(fullPath, rootPath, depth+1, result)
-			if err != nil {
-				continue // Continue searching other directories
-			}
-
-			// If we found a definitive indicator in a subdirectory, stop
-			if result.IsHighConfidence() {
-				return nil
-			}
+	// Recursively search subdirectories, unless this one identified a game
+	for _, subdir := range subdirs {
+		if s.sure() {
+			return nil
+		}
+		if err := s.directory(subdir, depth+1); err != nil {
+			continue // Continue searching other directories
 		}
 	}
 
 	return nil
 }
 
+// indicatorFolder adds the signals of the files directly in an indicator folder, such as
+// PS3_GAME/PARAM.SFO, to the game path the folder is in
+func (s *search) indicatorFolder(dir, name, gamePath string, depth int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || entry.Name()[0] == '.' || s.ignore.Ignored(fullPath, false) {
+			continue
+		}
+		if s.result.Entries >= s.limits.MaxEntries {
+			s.result.Truncated = true
+			return
+		}
+		s.result.Entries++
+
+		signals := nameSignals(entry.Name(), false)
+		if info, err := entry.Info(); err == nil {
+			signals = append(signals, fileSignals(fullPath, entry.Name(), info.Size())...)
+		}
+		for _, m := range signals {
+			m.signal.Path = name + "/" + m.signal.Path
+			s.scores.add(m.console, gamePath, depth, m.signal)
+		}
+	}
+}
+
 // DetectConsoleFromFile analyzes a single file and attempts to determine its console type
 // This is useful for analyzing individual ROM files
 func DetectConsoleFromFile(filePath string) (*DetectionResult, error) {
 	// Check if file exists
-	if _, err := os.Stat(filePath); err != nil {
+	info, err := os.Stat(filePath)
+	if err != nil {
 		return nil, fmt.Errorf("file does not exist: %w", err)
 	}
 
@@ -133,11 +223,14 @@ func DetectConsoleFromFile(filePath string) (*DetectionResult, error) {
 
 	filename := filepath.Base(filePath)
 
-	// Check if it's a definitive indicator file
-	if IsDefinitiveIndicator(filename) {
-		result.ConsoleType = GetConsoleFromIndicator(filename)
-		result.Confidence = 0.9
-		result.IndicatorFound = filename
+	// Score the file's own signals; GamePath stays the file
+	var scores scores
+	signals := append(nameSignals(filename, false), fileSignals(filePath, filename, info.Size())...)
+	for _, m := range signals {
+		scores.add(m.console, filePath, 0, m.signal)
+	}
+	scores.apply(result)
+	if result.IsValid() {
 		return result, nil
 	}
 
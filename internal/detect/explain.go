@@ -0,0 +1,278 @@
+package detect
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/ignore"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// Explanation describes what detection looked at when it found no console, so the
+// user can tell what a source is and what to do with it
+type Explanation struct {
+	Path       string           `json:"path"`
+	Searched   []string         `json:"searched"` // Indicators detection looks for
+	Files      int              `json:"files"`
+	Dirs       int              `json:"dirs"`
+	Depth      int              `json:"depth"`     // Deepest folder level reached
+	Truncated  bool             `json:"truncated"` // The source goes deeper or has more entries than the limits
+	Ignored    int              `json:"ignored"`   // Files and folders skipped by the source's .romignore
+	Limits     SearchLimits     `json:"-"`
+	Closest    []Match          `json:"closest"`    // Names resembling an indicator or another platform
+	Extensions []ExtensionCount `json:"extensions"` // File extensions, most common first
+	Suggestion string           `json:"suggestion,omitempty"`
+}
+
+// Match is a file or folder that comes close to identifying the source
+type Match struct {
+	Path   string `json:"path"`   // Relative to the source
+	Reason string `json:"reason"` // e.g. "PS3_GAME in the wrong case"
+}
+
+// ExtensionCount is how many files have an extension ("" for none)
+type ExtensionCount struct {
+	Extension string `json:"extension"`
+	Count     int    `json:"count"`
+}
+
+// platformSignature is a file or folder name that gives away a platform rom-organizer
+// doesn't organize
+type platformSignature struct {
+	name     string
+	platform string
+}
+
+var platformSignatures = []platformSignature{
+	{"PSP_GAME", "a PSP game"},
+	{"UMD_DATA.BIN", "a PSP game"},
+	{"EBOOT.PBP", "a PSP game or PS1 classic"},
+	{"sce_sys", "a PS4 or PS Vita game"},
+	{"default.xbe", "an Xbox game"},
+	{"default.xex", "an Xbox 360 game"},
+}
+
+// platformExtensions are file types of other platforms' dumps
+var platformExtensions = map[string]string{
+	".cso":  "a PSP disc image",
+	".cue":  "a PS1 or PS2 disc dump",
+	".chd":  "a compressed disc dump (PS1, PS2 or another console)",
+	".nsp":  "a Switch game",
+	".xci":  "a Switch game",
+	".wbfs": "a Wii game",
+	".rvz":  "a GameCube or Wii game",
+	".gcm":  "a GameCube game",
+	".vpk":  "a PS Vita game",
+}
+
+// Explain looks through a source detection found nothing in: it counts what's there,
+// lists names close to the indicators, and guesses what the source is. It reads no more
+// of the source than detection does with the same limits.
+func Explain(rootPath string, limits SearchLimits) *Explanation {
+	limits = limits.WithDefaults()
+	e := &Explanation{Path: rootPath, Limits: limits}
+	for indicator := range ConsoleIndicators {
+		e.Searched = append(e.Searched, indicator)
+	}
+	for ext := range ExtensionIndicators {
+		e.Searched = append(e.Searched, "*"+ext)
+	}
+	sort.Strings(e.Searched)
+
+	rules, _ := ignore.Load(rootPath)
+	extensions := make(map[string]int)
+	var guesses []string
+	entries := 0
+	filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Name() == ignore.FileName && !info.IsDir() {
+			return nil
+		}
+		if rules.Ignored(path, info.IsDir()) {
+			e.Ignored++
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(rootPath, path)
+		depth := strings.Count(filepath.ToSlash(rel), "/") + 1
+		if rel == "." {
+			rel, depth = info.Name(), 0
+		}
+		if info.IsDir() && path != rootPath && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if entries++; entries > limits.MaxEntries {
+			e.Truncated = true
+			return filepath.SkipAll
+		}
+		if depth > e.Depth {
+			e.Depth = depth
+		}
+
+		name := info.Name()
+		if info.IsDir() {
+			if path != rootPath {
+				e.Dirs++
+			}
+		} else {
+			e.Files++
+			extensions[strings.ToLower(filepath.Ext(name))]++
+		}
+		if match, guess := closeMatch(path, name, info); match != "" {
+			e.Closest = append(e.Closest, Match{Path: filepath.ToSlash(rel), Reason: match})
+			if guess != "" {
+				guesses = append(guesses, guess)
+			}
+		}
+		if info.IsDir() && depth > limits.MaxDepth {
+			e.Truncated = true
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	for ext, count := range extensions {
+		e.Extensions = append(e.Extensions, ExtensionCount{Extension: ext, Count: count})
+	}
+	sort.Slice(e.Extensions, func(i, j int) bool {
+		a, b := e.Extensions[i], e.Extensions[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Extension < b.Extension)
+	})
+
+	switch {
+	case len(guesses) > 0:
+		e.Suggestion = guesses[0]
+	case e.Truncated:
+		e.Suggestion = fmt.Sprintf("nothing found within %d folder levels and %d entries; raise --max-depth or --max-entries if the game is further in", limits.MaxDepth, limits.MaxEntries)
+	case e.Files == 0 && e.Ignored > 0:
+		e.Suggestion = fmt.Sprintf("everything in the source is ignored by its %s", ignore.FileName)
+	case e.Files == 0:
+		e.Suggestion = "the source is empty"
+	case len(e.Extensions) > 0:
+		if platform, ok := platformExtensions[e.Extensions[0].Extension]; ok {
+			e.Suggestion = fmt.Sprintf("mostly %s files: looks like %s, which isn't supported yet", e.Extensions[0].Extension, platform)
+		}
+	}
+	return e
+}
+
+// closeMatch reports why a file or folder comes close to identifying the source, and a
+// suggestion when it identifies it
+func closeMatch(path, name string, info os.FileInfo) (match, suggestion string) {
+	for indicator := range ConsoleIndicators {
+		switch {
+		case name == indicator:
+			return "", ""
+		case strings.EqualFold(name, indicator):
+			return indicator + " in the wrong case", fmt.Sprintf("rename %s to %s; indicators are matched case-sensitively", name, indicator)
+		case strings.Contains(strings.ToUpper(name), indicator):
+			return "named like " + indicator, fmt.Sprintf("%s looks like a renamed %s; restore its name", name, indicator)
+		}
+	}
+	switch upper := strings.ToUpper(name); {
+	case upper == "PS3_DISC.SFB" || (upper == "USRDIR" && info.IsDir()):
+		return "PS3 disc file without PS3_GAME", "PS3 files without a PS3_GAME folder: the dump is incomplete, or an installed game missing its PARAM.SFO"
+	case upper == "SYSTEM.CNF" && !info.IsDir():
+		platform := "a PS1 game"
+		if data, err := os.ReadFile(path); err == nil && bytes.Contains(bytes.ToUpper(data), []byte("BOOT2")) {
+			platform = "a PS2 game"
+		}
+		return "SYSTEM.CNF of " + platform, fmt.Sprintf("looks like %s, which isn't supported yet", platform)
+	case strings.EqualFold(filepath.Ext(name), ".iso") && !info.IsDir():
+		switch platform := ISOPlatform(path); platform {
+		case "":
+		case PS3.String():
+			return "disc image of a " + platform + " game", fmt.Sprintf("organize the disc image itself: rom-organizer organize %q", path)
+		default:
+			return "disc image of " + platform, fmt.Sprintf("looks like a disc image of %s, which isn't supported yet", platform)
+		}
+	}
+	for _, signature := range platformSignatures {
+		if strings.EqualFold(name, signature.name) {
+			return "found in " + signature.platform, fmt.Sprintf("looks like %s, which isn't supported yet", signature.platform)
+		}
+	}
+	return "", ""
+}
+
+// ISOPlatform guesses the platform of an ISO 9660 image from the files in it: PS3.String()
+// for a PS3 game, otherwise a description such as "a PS2 game", or "" when unknown
+func ISOPlatform(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	files, err := parsers.ReadISO9660(f)
+	if err != nil {
+		return ""
+	}
+	for _, file := range files {
+		switch strings.ToUpper(file.Path) {
+		case "PS3_GAME/PARAM.SFO":
+			return PS3.String()
+		case "SYSTEM.CNF":
+			data := make([]byte, min(file.Size, 4096))
+			if _, err := f.ReadAt(data, int64(file.Sector)*parsers.ISOSectorSize); err == nil && bytes.Contains(bytes.ToUpper(data), []byte("BOOT2")) {
+				return "a PS2 game"
+			}
+			return "a PS1 game"
+		case "UMD_DATA.BIN", "PSP_GAME/PARAM.SFO":
+			return "a PSP game"
+		case "DEFAULT.XBE":
+			return "an Xbox game"
+		}
+	}
+	return ""
+}
+
+// String formats the explanation as indented lines for the terminal
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  Searched:   %s (%d files, %d folders, depth %d", strings.Join(e.Searched, ", "), e.Files, e.Dirs, e.Depth)
+	if e.Ignored > 0 {
+		fmt.Fprintf(&b, ", %d skipped by %s", e.Ignored, ignore.FileName)
+	}
+	if e.Truncated {
+		fmt.Fprintf(&b, ", stopped at --max-depth %d / --max-entries %d", e.Limits.MaxDepth, e.Limits.MaxEntries)
+	}
+	b.WriteString(")\n")
+	for i, match := range e.Closest {
+		label := "Closest:"
+		if i > 0 {
+			label = ""
+		}
+		if i == 5 {
+			fmt.Fprintf(&b, "  %-11s ... and %d more\n", label, len(e.Closest)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %-11s %s (%s)\n", label, match.Path, match.Reason)
+	}
+	if len(e.Extensions) > 0 {
+		var counts []string
+		for i, ext := range e.Extensions {
+			if i == 8 {
+				counts = append(counts, "...")
+				break
+			}
+			name := ext.Extension
+			if name == "" {
+				name = "(none)"
+			}
+			counts = append(counts, fmt.Sprintf("%s %d", name, ext.Count))
+		}
+		fmt.Fprintf(&b, "  Files:      %s\n", strings.Join(counts, ", "))
+	}
+	if e.Suggestion != "" {
+		fmt.Fprintf(&b, "  Suggestion: %s\n", e.Suggestion)
+	}
+	return b.String()
+}
@@ -1,24 +1,56 @@
 package detect
 
 import (
+	"path/filepath"
 	"strings"
 )
 
-// ConsoleIndicators maps specific files/folders to console types
-// These are definitive indicators that immediately identify a console
-var ConsoleIndicators = map[string]ConsoleType{
+const (
+	// DefaultIndicatorConfidence is the confidence of a file or folder indicator
+	DefaultIndicatorConfidence = 0.95
+
+	// DefaultExtensionConfidence is the confidence of an extension indicator, which other
+	// systems' files may share
+	DefaultExtensionConfidence = 0.6
+)
+
+// builtinIndicators are the ConsoleIndicators rom-organizer knows without an indicators file
+var builtinIndicators = map[string]ConsoleType{
 	"PS3_GAME":  PS3, // PS3 decrypted ISO directory structure
 	"PARAM.SFO": PS3, // PS3 metadata file (when found at appropriate level)
 }
 
-// AmbiguousExtensions are file extensions that could belong to multiple consoles
-// These require secondary analysis to determine the actual console type
-var AmbiguousExtensions = []string{
+// builtinConfidence weighs the built-in indicators: a PARAM.SFO alone could also be a
+// PSP or PS Vita file
+var builtinConfidence = map[string]float64{
+	"PS3_GAME":  0.9,
+	"PARAM.SFO": 0.7,
+}
+
+// builtinAmbiguousExtensions are the AmbiguousExtensions known without an indicators file
+var builtinAmbiguousExtensions = []string{
 	".pkg", // PS3 package files (but could be other consoles in future)
 	".iso", // Could be PS1, PS2, PS3, Xbox, GameCube, etc.
 	".chd", // Compressed Hunks of Data - could be various consoles
 }
 
+// ConsoleIndicators maps specific files/folders to console types
+// These are definitive indicators that immediately identify a console
+// The indicators file adds to them (see LoadIndicators)
+var ConsoleIndicators = copyIndicators(builtinIndicators)
+
+// ExtensionIndicators maps lower-case file extensions (".z64") to console types. None
+// are built in; they come from the indicators file.
+var ExtensionIndicators = map[string]ConsoleType{}
+
+// AmbiguousExtensions are file extensions that could belong to multiple consoles
+// These require secondary analysis to determine the actual console type
+var AmbiguousExtensions = append([]string(nil), builtinAmbiguousExtensions...)
+
+// indicatorConfidence holds the confidence of the built-in indicators and of the
+// indicators and extensions of the indicators file that set one
+var indicatorConfidence = copyConfidence(builtinConfidence)
+
 // IsAmbiguousFile checks if a filename has an ambiguous extension
 func IsAmbiguousFile(filename string) bool {
 	lower := strings.ToLower(filename)
@@ -44,3 +76,45 @@ func IsDefinitiveIndicator(name string) bool {
 	_, exists := ConsoleIndicators[name]
 	return exists
 }
+
+// IndicatorConfidence returns how sure a match of a file or folder indicator alone makes
+// detection: DefaultIndicatorConfidence unless it is built in or the indicators file
+// sets another
+func IndicatorConfidence(indicator string) float64 {
+	if confidence, ok := indicatorConfidence[indicator]; ok {
+		return confidence
+	}
+	return DefaultIndicatorConfidence
+}
+
+// GetConsoleFromExtension returns the console of a file by its extension in
+// ExtensionIndicators and how sure that makes detection, or Unknown
+func GetConsoleFromExtension(filename string) (ConsoleType, float64) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	console, ok := ExtensionIndicators[ext]
+	if !ok || ext == "" {
+		return Unknown, 0
+	}
+	if confidence, ok := indicatorConfidence[ext]; ok {
+		return console, confidence
+	}
+	return console, DefaultExtensionConfidence
+}
+
+// copyIndicators copies an indicator map so the built-in one is never changed
+func copyIndicators(indicators map[string]ConsoleType) map[string]ConsoleType {
+	copied := make(map[string]ConsoleType, len(indicators))
+	for name, console := range indicators {
+		copied[name] = console
+	}
+	return copied
+}
+
+// copyConfidence copies a confidence map so the built-in one is never changed
+func copyConfidence(confidence map[string]float64) map[string]float64 {
+	copied := make(map[string]float64, len(confidence))
+	for name, value := range confidence {
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,184 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IndicatorsFileName is the indicators file looked for next to the config file
+const IndicatorsFileName = "indicators.yaml"
+
+// consoleShortName is what a console declared in an indicators file may be called
+var consoleShortName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// IndicatorsFile adds detection rules for new or obscure systems without recompiling:
+//
+//	consoles:
+//	  n64: Nintendo 64
+//	indicators:
+//	  - name: PSP_GAME
+//	    console: psp
+//	  - extension: .z64
+//	    console: n64
+//	    confidence: 0.7
+//	ambiguous_extensions: [.bin, .img]
+//
+// Consoles it declares can be detected, but have no handler to organize their games.
+type IndicatorsFile struct {
+	// Consoles declares consoles by short name ("n64") and display name ("Nintendo 64")
+	Consoles map[string]string `yaml:"consoles"`
+
+	// Indicators are file or folder names, or file extensions, that identify a console
+	Indicators []IndicatorRule `yaml:"indicators"`
+
+	// AmbiguousExtensions are added to the built-in AmbiguousExtensions
+	AmbiguousExtensions []string `yaml:"ambiguous_extensions"`
+}
+
+// IndicatorRule is one indicator of an indicators file: a Name or an Extension
+type IndicatorRule struct {
+	Name       string  `yaml:"name"`       // Exact file or folder name, e.g. PSP_GAME
+	Extension  string  `yaml:"extension"`  // File extension, e.g. .z64 (case-insensitive)
+	Console    string  `yaml:"console"`    // Short or display name of a built-in or declared console
+	Confidence float64 `yaml:"confidence"` // 0 to 1; default DefaultIndicatorConfidence or DefaultExtensionConfidence
+}
+
+// DefaultIndicatorsPath returns the default indicators file location
+// (e.g. ~/.config/rom-organizer/indicators.yaml on Linux)
+func DefaultIndicatorsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rom-organizer", IndicatorsFileName)
+}
+
+// LoadIndicators reads the indicators file at path and replaces the rules of any file
+// loaded before. A missing file is an error only when required; without one detection
+// uses the built-in rules alone. On an error the rules in use are kept.
+func LoadIndicators(path string, required bool) error {
+	file := &IndicatorsFile{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err) && !required:
+		case err != nil:
+			return fmt.Errorf("reading indicators file: %w", err)
+		default:
+			if err := yaml.Unmarshal(data, file); err != nil {
+				return fmt.Errorf("parsing indicators file %s: %w", path, err)
+			}
+		}
+	}
+	if err := file.Apply(); err != nil {
+		return fmt.Errorf("invalid indicators file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Apply checks the file and makes its rules, with the built-in ones, the rules detection
+// uses, replacing those of any file applied before. Nothing changes if the file is invalid.
+func (f *IndicatorsFile) Apply() error {
+	var consoles []customConsole
+	shortNames := make([]string, 0, len(f.Consoles))
+	for short := range f.Consoles {
+		shortNames = append(shortNames, short)
+	}
+	// Declared consoles are numbered in name order, so reloading the same file gives
+	// them the same ConsoleType
+	sort.Strings(shortNames)
+	for _, short := range shortNames {
+		name := strings.TrimSpace(f.Consoles[short])
+		if !consoleShortName.MatchString(short) {
+			return fmt.Errorf("consoles: %q must be lower-case letters, digits, - and _", short)
+		}
+		if name == "" {
+			return fmt.Errorf("consoles: %s has no display name", short)
+		}
+		for _, builtin := range builtinConsoles {
+			if short == builtin.ShortName() || strings.EqualFold(name, builtin.String()) {
+				return fmt.Errorf("consoles: %s is built in", builtin.ShortName())
+			}
+		}
+		consoles = append(consoles, customConsole{shortName: short, name: name})
+	}
+
+	ambiguous := append([]string(nil), builtinAmbiguousExtensions...)
+	for _, ext := range f.AmbiguousExtensions {
+		if ext = normalizeExtension(ext); ext == "" {
+			return fmt.Errorf("ambiguous_extensions: empty extension")
+		}
+		ambiguous = append(ambiguous, ext)
+	}
+
+	// Console names are resolved against the new declarations, then these are swapped in
+	previous := customConsoles
+	customConsoles = consoles
+	indicators, extensions, confidence, err := f.rules()
+	if err != nil {
+		customConsoles = previous
+		return err
+	}
+
+	ConsoleIndicators, ExtensionIndicators, AmbiguousExtensions = indicators, extensions, ambiguous
+	indicatorConfidence = confidence
+	return nil
+}
+
+// rules checks the indicators and returns the tables detection uses
+func (f *IndicatorsFile) rules() (map[string]ConsoleType, map[string]ConsoleType, map[string]float64, error) {
+	indicators := copyIndicators(builtinIndicators)
+	extensions := map[string]ConsoleType{}
+	confidence := copyConfidence(builtinConfidence)
+	for i, rule := range f.Indicators {
+		where := fmt.Sprintf("indicators[%d]", i)
+		if (rule.Name == "") == (rule.Extension == "") {
+			return nil, nil, nil, fmt.Errorf("%s: give either a name or an extension", where)
+		}
+		console, err := ParseConsoleType(rule.Console)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w (declare new consoles under consoles)", where, err)
+		}
+		if rule.Confidence < 0 || rule.Confidence > 1 {
+			return nil, nil, nil, fmt.Errorf("%s: confidence %v is not between 0 and 1", where, rule.Confidence)
+		}
+
+		key := rule.Name
+		if rule.Name != "" {
+			if strings.ContainsAny(rule.Name, `/\`) {
+				return nil, nil, nil, fmt.Errorf("%s: %q is a path; give a file or folder name", where, rule.Name)
+			}
+			if strings.HasPrefix(rule.Name, ".") {
+				return nil, nil, nil, fmt.Errorf("%s: %s is hidden, and hidden files and folders aren't searched", where, rule.Name)
+			}
+			indicators[rule.Name] = console
+		} else {
+			if key = normalizeExtension(rule.Extension); key == "" {
+				return nil, nil, nil, fmt.Errorf("%s: empty extension", where)
+			}
+			extensions[key] = console
+		}
+		if rule.Confidence > 0 {
+			confidence[key] = rule.Confidence
+		}
+	}
+	return indicators, extensions, confidence, nil
+}
+
+// normalizeExtension returns an extension in lower case with its dot: "Z64" is ".z64"
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext == "" || ext == "." {
+		return ""
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,231 @@
+package detect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// SignalKind is what kind of evidence a Signal is
+type SignalKind string
+
+const (
+	SignalName      SignalKind = "name"      // A file or folder name in ConsoleIndicators
+	SignalExtension SignalKind = "extension" // A file extension in ExtensionIndicators
+	SignalMagic     SignalKind = "magic"     // The first bytes of a file
+	SignalSize      SignalKind = "size"      // A file size only one console's files have
+)
+
+// Signal is one piece of evidence for a console
+type Signal struct {
+	Kind   SignalKind `json:"kind"`
+	Path   string     `json:"path"`   // Slash-separated, relative to the candidate's game path
+	Weight float64    `json:"weight"` // How sure this signal alone makes detection, 0 to 1
+	Detail string     `json:"detail,omitempty"`
+}
+
+// Candidate is a console the signals found in a game folder point to. Confidence combines
+// the weights of its signals as independent evidence: 1 - (1-w1)(1-w2)..., so two
+// signals of 0.7 and 0.5 make 0.85.
+type Candidate struct {
+	Console     ConsoleType `json:"-"`
+	GamePath    string      `json:"game_path"`
+	Confidence  float64     `json:"confidence"`
+	Signals     []Signal    `json:"signals"`
+	SearchDepth int         `json:"search_depth"`
+}
+
+// Indicator returns the name of the file or folder indicator, or extension indicator,
+// that found the candidate's game path, the surest one when there are several, or "" when
+// it only has supporting signals
+func (c Candidate) Indicator() string {
+	best := -1
+	for i, signal := range c.Signals {
+		anchor := signal.Kind == SignalName || signal.Kind == SignalExtension
+		if anchor && !strings.Contains(signal.Path, "/") && (best < 0 || signal.Weight > c.Signals[best].Weight) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return c.Signals[best].Path
+}
+
+// String describes the candidate and its signals, e.g.
+// "PlayStation 3 0.97 in /games/x: PS3_GAME (name 0.90), PS3_GAME/PARAM.SFO (name 0.70)"
+func (c Candidate) String() string {
+	signals := make([]string, 0, len(c.Signals))
+	for _, signal := range c.Signals {
+		what := string(signal.Kind)
+		if signal.Detail != "" {
+			what += ": " + signal.Detail
+		}
+		signals = append(signals, fmt.Sprintf("%s (%s %.2f)", signal.Path, what, signal.Weight))
+	}
+	return fmt.Sprintf("%s %.2f in %s: %s", c.Console, c.Confidence, c.GamePath, strings.Join(signals, ", "))
+}
+
+// fileRule is a magic-bytes or size signal for the files with a name or extension
+type fileRule struct {
+	name      string // Exact file name, or
+	extension string // lower-case file extension
+	magic     []byte // Bytes the file starts with; nil for a size rule
+	minSize   int64  // Size rule: files of at least minSize and at most maxSize bytes;
+	maxSize   int64  // a magic rule only reads files up to maxSize (0 for any)
+	console   ConsoleType
+	weight    float64
+	detail    string
+}
+
+// fileRules are the built-in magic and size signals. Alone they don't identify a game
+// folder, but they make what a name or extension found surer, and rank the candidates
+// of files no indicator names.
+var fileRules = []fileRule{
+	{name: "PARAM.SFO", magic: []byte("\x00PSF"), maxSize: parsers.MaxParamSFOSize, console: PS3, weight: 0.5, detail: "PARAM.SFO header"},
+	{name: "PS3_DISC.SFB", magic: []byte(".SFB"), console: PS3, weight: 0.6, detail: "PS3 disc header"},
+	{name: "EBOOT.BIN", magic: []byte("SCE\x00"), console: PS3, weight: 0.3, detail: "signed executable (SELF)"},
+	{extension: ".pkg", magic: []byte("\x7fPKG"), console: PS3, weight: 0.5, detail: "PKG header"},
+	{extension: ".iso", minSize: 9 << 30, maxSize: 128 << 30, console: PS3, weight: 0.4, detail: "larger than any DVD: a Blu-ray image"},
+}
+
+// fileSignals returns the magic and size signals of a file
+func fileSignals(filePath, name string, size int64) []matchedSignal {
+	var signals []matchedSignal
+	var head []byte
+	ext := strings.ToLower(path.Ext(name))
+	for _, rule := range fileRules {
+		if (rule.name != "" && name != rule.name) || (rule.extension != "" && ext != rule.extension) {
+			continue
+		}
+		signal := Signal{Path: name, Weight: rule.weight, Detail: rule.detail}
+		if rule.magic == nil {
+			if size < rule.minSize || (rule.maxSize > 0 && size > rule.maxSize) {
+				continue
+			}
+			signal.Kind = SignalSize
+		} else {
+			if rule.maxSize > 0 && size > rule.maxSize {
+				continue
+			}
+			if head == nil {
+				head = readHead(filePath, 16)
+			}
+			if !bytes.HasPrefix(head, rule.magic) {
+				continue
+			}
+			signal.Kind = SignalMagic
+		}
+		signals = append(signals, matchedSignal{console: rule.console, signal: signal})
+	}
+	return signals
+}
+
+// readHead returns up to n bytes from the start of a file, or an empty slice
+func readHead(filePath string, n int) []byte {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return []byte{}
+	}
+	defer f.Close()
+	head := make([]byte, n)
+	read, _ := io.ReadFull(f, head)
+	return head[:read]
+}
+
+// matchedSignal is a signal with the console it is evidence for
+type matchedSignal struct {
+	console ConsoleType
+	signal  Signal
+}
+
+// nameSignals returns the name and extension indicator signals of a file or folder
+func nameSignals(name string, isDir bool) []matchedSignal {
+	if IsDefinitiveIndicator(name) {
+		return []matchedSignal{{GetConsoleFromIndicator(name), Signal{Kind: SignalName, Path: name, Weight: IndicatorConfidence(name)}}}
+	}
+	if !isDir {
+		if console, confidence := GetConsoleFromExtension(name); console != Unknown {
+			return []matchedSignal{{console, Signal{Kind: SignalExtension, Path: name, Weight: confidence}}}
+		}
+	}
+	return nil
+}
+
+// scores collects signals into candidates, one per console and game path
+type scores struct {
+	candidates []*Candidate
+}
+
+// add records a signal found in gamePath, once; signals found inside an indicator folder
+// carry its name in their path
+func (s *scores) add(console ConsoleType, gamePath string, depth int, signal Signal) {
+	var candidate *Candidate
+	for _, c := range s.candidates {
+		if c.Console == console && c.GamePath == gamePath {
+			candidate = c
+			break
+		}
+	}
+	if candidate == nil {
+		candidate = &Candidate{Console: console, GamePath: gamePath, SearchDepth: depth}
+		s.candidates = append(s.candidates, candidate)
+	}
+	for _, seen := range candidate.Signals {
+		if seen.Kind == signal.Kind && seen.Path == signal.Path {
+			return
+		}
+	}
+	candidate.Signals = append(candidate.Signals, signal)
+	doubt := 1.0
+	for _, signal := range candidate.Signals {
+		doubt *= 1 - signal.Weight
+	}
+	candidate.Confidence = 1 - doubt
+}
+
+// surer reports whether a candidate ranks before another: by confidence, then the
+// shallower one, then the one found first
+func surer(a, b Candidate) bool {
+	if a.Confidence != b.Confidence {
+		return a.Confidence > b.Confidence
+	}
+	return a.SearchDepth < b.SearchDepth
+}
+
+// best returns the surest candidate with a name or extension indicator, or nil
+func (s *scores) best() *Candidate {
+	var best *Candidate
+	for _, c := range s.candidates {
+		if c.Indicator() != "" && (best == nil || surer(*c, *best)) {
+			best = c
+		}
+	}
+	return best
+}
+
+// apply sets the result from the candidates: all of them ranked, and the console, game
+// path and indicator of the surest one that has an indicator
+func (s *scores) apply(result *DetectionResult) {
+	result.Candidates = make([]Candidate, 0, len(s.candidates))
+	for _, c := range s.candidates {
+		result.Candidates = append(result.Candidates, *c)
+	}
+	sort.SliceStable(result.Candidates, func(i, j int) bool {
+		return surer(result.Candidates[i], result.Candidates[j])
+	})
+
+	if best := s.best(); best != nil {
+		result.ConsoleType = best.Console
+		result.GamePath = best.GamePath
+		result.Confidence = best.Confidence
+		result.IndicatorFound = best.Indicator()
+		result.SearchDepth = best.SearchDepth
+	}
+}
@@ -0,0 +1,159 @@
+package detect
+
+import (
+	"math"
+	"testing"
+)
+
+// otherConsole stands for a console declared in an indicators file
+const otherConsole = lastBuiltinConsole + 1
+
+// found is a signal found for a console in a game path
+type found struct {
+	console  ConsoleType
+	gamePath string
+	depth    int
+	signal   Signal
+}
+
+func nameSignal(path string, weight float64) Signal {
+	return Signal{Kind: SignalName, Path: path, Weight: weight}
+}
+
+func magicSignal(path string, weight float64) Signal {
+	return Signal{Kind: SignalMagic, Path: path, Weight: weight}
+}
+
+func TestScores(t *testing.T) {
+	tests := []struct {
+		name       string
+		found      []found
+		console    ConsoleType
+		gamePath   string
+		confidence float64
+		indicator  string
+		ranked     []string // Game paths of the candidates, surest first
+	}{
+		{
+			name:    "no candidates",
+			console: Unknown,
+			ranked:  []string{},
+		},
+		{
+			name: "supporting signals alone don't pick a game",
+			found: []found{
+				{PS3, "/games/a", 0, magicSignal("PARAM.SFO", 0.5)},
+				{PS3, "/games/a", 0, magicSignal("EBOOT.BIN", 0.3)},
+			},
+			console: Unknown,
+			ranked:  []string{"/games/a"},
+		},
+		{
+			name: "signals combine as independent evidence",
+			found: []found{
+				{PS3, "/games/a", 0, nameSignal("PS3_GAME", 0.7)},
+				{PS3, "/games/a", 0, magicSignal("PS3_GAME/PARAM.SFO", 0.5)},
+			},
+			console: PS3, gamePath: "/games/a", confidence: 0.85, indicator: "PS3_GAME",
+			ranked: []string{"/games/a"},
+		},
+		{
+			name: "a signal found twice counts once",
+			found: []found{
+				{PS3, "/games/a", 0, nameSignal("PS3_GAME", 0.7)},
+				{PS3, "/games/a", 0, nameSignal("PS3_GAME", 0.7)},
+			},
+			console: PS3, gamePath: "/games/a", confidence: 0.7, indicator: "PS3_GAME",
+			ranked: []string{"/games/a"},
+		},
+		{
+			name: "one strong signal beats several weak ones",
+			found: []found{
+				{otherConsole, "/games/weak", 0, nameSignal("a.bin", 0.3)},
+				{otherConsole, "/games/weak", 0, nameSignal("b.bin", 0.3)},
+				{otherConsole, "/games/weak", 0, nameSignal("c.bin", 0.3)},
+				{PS3, "/games/strong", 1, nameSignal("PS3_GAME", 0.9)},
+			},
+			console: PS3, gamePath: "/games/strong", confidence: 0.9, indicator: "PS3_GAME",
+			ranked: []string{"/games/strong", "/games/weak"},
+		},
+		{
+			name: "enough weak signals beat a strong one",
+			found: []found{
+				{PS3, "/games/strong", 0, nameSignal("PS3_GAME", 0.8)},
+				{otherConsole, "/games/weak", 1, nameSignal("a.bin", 0.5)},
+				{otherConsole, "/games/weak", 1, nameSignal("b.bin", 0.5)},
+				{otherConsole, "/games/weak", 1, nameSignal("c.bin", 0.5)},
+			},
+			console: otherConsole, gamePath: "/games/weak", confidence: 0.875, indicator: "a.bin",
+			ranked: []string{"/games/weak", "/games/strong"},
+		},
+		{
+			name: "the surest indicator names the candidate",
+			found: []found{
+				{PS3, "/games/a", 0, nameSignal("PS3_GAME", 0.7)},
+				{PS3, "/games/a", 0, nameSignal("PS3_DISC.SFB", 0.9)},
+				{PS3, "/games/a", 0, nameSignal("PS3_GAME/USRDIR", 0.95)},
+			},
+			console: PS3, gamePath: "/games/a", confidence: 1 - 0.3*0.1*0.05, indicator: "PS3_DISC.SFB",
+			ranked: []string{"/games/a"},
+		},
+		{
+			name: "a candidate without an indicator isn't picked over a less sure one",
+			found: []found{
+				{otherConsole, "/games/b", 0, magicSignal("header.bin", 0.9)},
+				{PS3, "/games/a", 0, nameSignal("PS3_GAME", 0.6)},
+			},
+			console: PS3, gamePath: "/games/a", confidence: 0.6, indicator: "PS3_GAME",
+			ranked: []string{"/games/b", "/games/a"},
+		},
+		{
+			name: "a tie goes to the shallower candidate",
+			found: []found{
+				{PS3, "/games/deep/x", 2, nameSignal("PS3_GAME", 0.9)},
+				{otherConsole, "/games/shallow", 1, nameSignal("game.bin", 0.9)},
+			},
+			console: otherConsole, gamePath: "/games/shallow", confidence: 0.9, indicator: "game.bin",
+			ranked: []string{"/games/shallow", "/games/deep/x"},
+		},
+		{
+			name: "a tie at the same depth goes to the one found first",
+			found: []found{
+				{otherConsole, "/games/first", 1, nameSignal("game.bin", 0.9)},
+				{PS3, "/games/second", 1, nameSignal("PS3_GAME", 0.9)},
+			},
+			console: otherConsole, gamePath: "/games/first", confidence: 0.9, indicator: "game.bin",
+			ranked: []string{"/games/first", "/games/second"},
+		},
+	}
+	for _, tt := range tests {
+		var s scores
+		for _, f := range tt.found {
+			s.add(f.console, f.gamePath, f.depth, f.signal)
+		}
+		var result DetectionResult
+		s.apply(&result)
+
+		if result.ConsoleType != tt.console || result.GamePath != tt.gamePath || result.IndicatorFound != tt.indicator {
+			t.Errorf("%s: picked %v in %q by %q, want %v in %q by %q", tt.name,
+				result.ConsoleType, result.GamePath, result.IndicatorFound, tt.console, tt.gamePath, tt.indicator)
+		}
+		if math.Abs(result.Confidence-tt.confidence) > 1e-9 {
+			t.Errorf("%s: confidence %v, want %v", tt.name, result.Confidence, tt.confidence)
+		}
+		var ranked []string
+		for _, c := range result.Candidates {
+			ranked = append(ranked, c.GamePath)
+		}
+		if result.Candidates == nil || len(ranked) != len(tt.ranked) {
+			t.Errorf("%s: candidates %v, want %v", tt.name, ranked, tt.ranked)
+			continue
+		}
+		for i := range ranked {
+			if ranked[i] != tt.ranked[i] {
+				t.Errorf("%s: candidates %v, want %v", tt.name, ranked, tt.ranked)
+				break
+			}
+		}
+	}
+}
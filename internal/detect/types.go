@@ -1,5 +1,10 @@
 package detect
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ConsoleType represents the different console types we can detect
 type ConsoleType int
 
@@ -11,18 +16,81 @@ const (
 	// Xbox
 	// Xbox360
 	// etc.
+
+	// lastBuiltinConsole is the last console built in; those declared in an indicators
+	// file are numbered after it
+	lastBuiltinConsole = PS3
 )
 
+// builtinConsoles are the consoles rom-organizer knows without an indicators file
+var builtinConsoles = []ConsoleType{PS3}
+
+// customConsole is a console declared in an indicators file
+type customConsole struct {
+	shortName string
+	name      string
+}
+
+// customConsoles are the consoles of the loaded indicators file; the first is
+// lastBuiltinConsole+1
+var customConsoles []customConsole
+
+// custom returns the declaration of a console from an indicators file
+func (c ConsoleType) custom() (customConsole, bool) {
+	i := int(c - lastBuiltinConsole - 1)
+	if i < 0 || i >= len(customConsoles) {
+		return customConsole{}, false
+	}
+	return customConsoles[i], true
+}
+
+// Consoles returns the built-in consoles followed by those of the indicators file
+func Consoles() []ConsoleType {
+	consoles := append([]ConsoleType(nil), builtinConsoles...)
+	for i := range customConsoles {
+		consoles = append(consoles, lastBuiltinConsole+1+ConsoleType(i))
+	}
+	return consoles
+}
+
 // String returns the string representation of the console type
 func (c ConsoleType) String() string {
 	switch c {
 	case PS3:
 		return "PlayStation 3"
 	default:
+		if custom, ok := c.custom(); ok {
+			return custom.name
+		}
 		return "Unknown"
 	}
 }
 
+// ShortName returns the short lowercase name used in flags and config files (e.g. "ps3")
+func (c ConsoleType) ShortName() string {
+	switch c {
+	case PS3:
+		return "ps3"
+	default:
+		if custom, ok := c.custom(); ok {
+			return custom.shortName
+		}
+		return "unknown"
+	}
+}
+
+// ParseConsoleType converts a console name such as "ps3" or "PlayStation 3" into a ConsoleType,
+// including the consoles declared in the indicators file
+func ParseConsoleType(name string) (ConsoleType, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	for _, console := range Consoles() {
+		if normalized == console.ShortName() || normalized == strings.ToLower(console.String()) {
+			return console, nil
+		}
+	}
+	return Unknown, fmt.Errorf("unknown console: %s", name)
+}
+
 // DetectionResult holds the result of console detection
 type DetectionResult struct {
 	ConsoleType    ConsoleType // The detected console type
@@ -31,8 +99,14 @@ type DetectionResult struct {
 	IndicatorFound string      // The specific indicator that was found
 	AmbiguousFiles []string    // Files that need secondary analysis
 	SearchDepth    int         // How deep we searched to find this
+	Entries        int         // Files and folders looked at
+	Truncated      bool        // The search stopped at a depth or entry limit
+	Candidates     []Candidate // Every console the signals point to, surest first
 }
 
+// HighConfidence is the confidence at which detection stops searching
+const HighConfidence = 0.8
+
 // IsValid returns true if the detection result is valid
 func (r DetectionResult) IsValid() bool {
 	return r.ConsoleType != Unknown && r.Confidence > 0.0
@@ -40,5 +114,5 @@ func (r DetectionResult) IsValid() bool {
 
 // IsHighConfidence returns true if we're very confident about the detection
 func (r DetectionResult) IsHighConfidence() bool {
-	return r.Confidence >= 0.8
+	return r.Confidence >= HighConfidence
 }
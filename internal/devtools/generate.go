@@ -0,0 +1,208 @@
+// Package devtools provides helpers for developing and testing rom-organizer.
+// Everything generated here is completely fictional to avoid any copyright issues.
+package devtools
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+)
+
+// GenerateOptions holds options for generating fake test games
+type GenerateOptions struct {
+	OutputDir string               // Directory to write the games into
+	Count     int                  // Number of games to generate per console
+	Seed      int64                // Random seed for reproducible output
+	Clean     bool                 // Remove the output directory before generating
+	Consoles  []detect.ConsoleType // Consoles to generate for (empty means all registered)
+	FileSize  int64                // Size of random filler data added to each game
+	Nested    bool                 // Wrap each game in an extra parent folder
+	Zip       bool                 // Wrap each game in a .zip archive
+}
+
+// FakeGame describes the identity of a generated game
+type FakeGame struct {
+	Title    string
+	GameID   string
+	Category string
+	AppVer   string
+}
+
+// GameGenerator creates fake games for a single console
+type GameGenerator interface {
+	// NextGame picks the identity of the next game to generate
+	NextGame(rng *rand.Rand) FakeGame
+
+	// WriteGame writes the console-specific game files into gameDir
+	WriteGame(gameDir string, game FakeGame, rng *rand.Rand, opts GenerateOptions) error
+}
+
+// generatorFactories creates a fresh generator per run for each supported console
+var generatorFactories = map[detect.ConsoleType]func() GameGenerator{
+	detect.PS3: func() GameGenerator { return newPS3Generator() },
+}
+
+// GenerateGames generates fake games for the requested (or all registered) consoles
+// and returns the paths of the created games
+func GenerateGames(opts GenerateOptions) ([]string, error) {
+	targets := opts.Consoles
+	if len(targets) == 0 {
+		targets = consoles.NewRegistry().GetSupportedConsoles()
+		sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+	}
+
+	if opts.Clean {
+		if err := os.RemoveAll(opts.OutputDir); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cleaning output directory: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	var created []string
+	for _, console := range targets {
+		factory, exists := generatorFactories[console]
+		if !exists {
+			fmt.Printf("Warning: no test game generator for %s, skipping\n", console.String())
+			continue
+		}
+		generator := factory()
+
+		for i := 0; i < opts.Count; i++ {
+			game := generator.NextGame(rng)
+			path, err := writeFakeGame(generator, game, rng, opts)
+			if err != nil {
+				return created, fmt.Errorf("creating %s test game %d: %w", console.String(), i+1, err)
+			}
+			fmt.Printf("Created %s test game: %s [%s]\n", console.String(), game.Title, game.GameID)
+			created = append(created, path)
+		}
+	}
+
+	return created, nil
+}
+
+// writeFakeGame writes a single game, applying the nested and zip wrappers
+func writeFakeGame(generator GameGenerator, game FakeGame, rng *rand.Rand, opts GenerateOptions) (string, error) {
+	name := fmt.Sprintf("%s [%s]", common.SanitizeFilename(game.Title), game.GameID)
+
+	root := opts.OutputDir
+	if opts.Zip {
+		staging, err := os.MkdirTemp("", "devtools-game-*")
+		if err != nil {
+			return "", fmt.Errorf("creating staging directory: %w", err)
+		}
+		defer os.RemoveAll(staging)
+		root = staging
+	}
+
+	gameDir := filepath.Join(root, name)
+	if opts.Nested {
+		gameDir = filepath.Join(root, name, "Release", name)
+	}
+
+	if err := os.MkdirAll(gameDir, 0755); err != nil {
+		return "", fmt.Errorf("creating game directory: %w", err)
+	}
+
+	if err := generator.WriteGame(gameDir, game, rng, opts); err != nil {
+		return "", err
+	}
+
+	if !opts.Zip {
+		return filepath.Join(root, name), nil
+	}
+
+	zipPath := filepath.Join(opts.OutputDir, name+".zip")
+	if err := zipDirectory(root, zipPath); err != nil {
+		return "", fmt.Errorf("creating zip archive: %w", err)
+	}
+	return zipPath, nil
+}
+
+// writeFillerFile writes size bytes of random (incompressible) data to path
+func writeFillerFile(path string, size int64, rng *rand.Rand) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyN(f, rng, size)
+	return err
+}
+
+// zipDirectory creates a zip archive containing the contents of srcDir
+func zipDirectory(srcDir, zipPath string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+
+	err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == srcDir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+
+		writer, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(writer, f)
+		return err
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,113 @@
+package devtools
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// fakePS3Games contains completely fictional titles and IDs
+var fakePS3Games = []FakeGame{
+	{"Galactic Warriors: Return of the Void", "BLUS12345", "DG", "01.00"},
+	{"Crystal Quest: Legends of Mystara", "BLES67890", "DG", "01.20"},
+	{"Neon Racers: Future Streets", "BCUS11111", "DG", "02.01"},
+	{"Adventure Island: Lost Treasures", "BCES22222", "DG", "01.10"},
+	{"Space Marines: Infinite War", "BLUS33333", "DG", "03.00"},
+	{"Magic Kingdom: Dragon's Crown", "BLES44444", "DG", "01.05"},
+	{"Cyber Punk: Digital Revolution", "BCUS55555", "DG", "01.30"},
+	{"Fantasy Quest: Ancient Realms", "BCES66666", "DG", "02.15"},
+	{"Metal Storm: Apocalypse Rising", "BLUS77777", "DG", "01.00"},
+	{"Ocean Adventure: Deep Waters", "BLES88888", "DG", "01.25"},
+	{"Desert Combat: Sand Warriors", "BCUS99999", "DG", "01.40"},
+	{"Forest Guardian: Nature's Call", "BCES10101", "DG", "01.00"},
+	{"City Builder: Metropolis Dreams", "BLUS20202", "DG", "02.30"},
+	{"Puzzle Master: Mind Bender", "BLES30303", "DG", "01.15"},
+	{"Racing Thunder: Speed Demons", "BCUS40404", "DG", "01.50"},
+}
+
+// ps3Generator generates fake PS3 disc games (JB folder layout)
+type ps3Generator struct {
+	usedTitleIDs map[string]int // Tracks used title IDs to avoid duplicates
+}
+
+func newPS3Generator() *ps3Generator {
+	return &ps3Generator{usedTitleIDs: make(map[string]int)}
+}
+
+// NextGame picks a random fake game, making its title ID unique within this run
+func (g *ps3Generator) NextGame(rng *rand.Rand) FakeGame {
+	game := fakePS3Games[rng.Intn(len(fakePS3Games))]
+
+	originalTitleID := game.GameID
+	if count, exists := g.usedTitleIDs[originalTitleID]; exists {
+		// Generate a unique variant that is still a valid title ID: BLUS12345 -> BLUS12346, BLUS12347, etc.
+		prefix, digits := originalTitleID[:4], originalTitleID[4:]
+		number, _ := strconv.Atoi(digits)
+		// Wrap within 1-99999, since an all-zero number is a placeholder ID
+		game.GameID = fmt.Sprintf("%s%05d", prefix, (number+count-1)%99999+1)
+		g.usedTitleIDs[originalTitleID] = count + 1
+	} else {
+		g.usedTitleIDs[originalTitleID] = 1
+	}
+
+	return game
+}
+
+// WriteGame creates the PS3_GAME folder, PARAM.SFO, PS3_DISC.SFB and optional filler data
+func (g *ps3Generator) WriteGame(gameDir string, game FakeGame, rng *rand.Rand, opts GenerateOptions) error {
+	ps3GameDir := filepath.Join(gameDir, "PS3_GAME")
+	usrDir := filepath.Join(ps3GameDir, "USRDIR")
+
+	if err := os.MkdirAll(usrDir, 0755); err != nil {
+		return fmt.Errorf("creating PS3_GAME directory: %w", err)
+	}
+
+	paramSFOData, err := generateParamSFO(game, rng)
+	if err != nil {
+		return fmt.Errorf("generating PARAM.SFO: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(ps3GameDir, "PARAM.SFO"), paramSFOData, 0644); err != nil {
+		return fmt.Errorf("writing PARAM.SFO: %w", err)
+	}
+
+	// Fake SFB and EBOOT files for realism
+	if err := os.WriteFile(filepath.Join(gameDir, "PS3_DISC.SFB"), []byte("FAKE_PS3_DISC_DATA_FOR_TESTING_ONLY"), 0644); err != nil {
+		return fmt.Errorf("writing PS3_DISC.SFB: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(usrDir, "EBOOT.BIN"), []byte("FAKE_EBOOT_FOR_TESTING_ONLY"), 0644); err != nil {
+		return fmt.Errorf("writing EBOOT.BIN: %w", err)
+	}
+
+	if opts.FileSize > 0 {
+		if err := writeFillerFile(filepath.Join(usrDir, "DATA.BIN"), opts.FileSize, rng); err != nil {
+			return fmt.Errorf("writing filler data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// generateParamSFO creates a fake but valid PARAM.SFO file
+func generateParamSFO(game FakeGame, rng *rand.Rand) ([]byte, error) {
+	sfo := parsers.NewParamSFO()
+	sfo.SetString("APP_VER", game.AppVer)
+	sfo.SetInt("ATTRIBUTE", 0)
+	sfo.SetInt("BOOTABLE", 1)
+	sfo.SetString("CATEGORY", game.Category)
+	sfo.SetString("LICENSE", "This is a fake test game for development purposes only.")
+	sfo.SetString("NP_COMMUNICATION_ID", fmt.Sprintf("NPWR%05d_00", rng.Intn(99999)))
+	sfo.SetInt("PARENTAL_LEVEL", 1)
+	sfo.SetString("PS3_SYSTEM_VER", "03.5500")
+	sfo.SetInt("RESOLUTION", 63)
+	sfo.SetInt("SOUND_FORMAT", 279)
+	sfo.SetString("TITLE", game.Title)
+	sfo.SetString("TITLE_ID", game.GameID)
+	sfo.SetString("VERSION", game.AppVer)
+
+	return sfo.Marshal(parsers.MarshalOptions{SortKeys: true})
+}
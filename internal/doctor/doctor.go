@@ -0,0 +1,222 @@
+// Package doctor checks the environment rom-organizer runs in: external tools,
+// the config file, and the directories it writes to
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/config"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	OK   Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Check is the result of one diagnostic
+type Check struct {
+	Name   string
+	Status Status
+	Detail string // What was found
+	Fix    string // How to resolve a warning or failure
+}
+
+// tool is an external program rom-organizer can use
+type tool struct {
+	names       []string // Executables to look for, first found wins
+	versionArgs []string // Arguments that print the version
+	purpose     string
+	required    bool // Missing is a failure rather than a warning
+	optional    bool // Missing is fine: no current command runs it
+	install     string
+}
+
+var tools = []tool{
+	{
+		names:    common.SevenZipCommands,
+		purpose:  "creating and extracting game.7z and input archives",
+		required: true,
+		install:  "install 7-Zip or p7zip (apt install p7zip-full, brew install p7zip, winget install 7zip.7zip)",
+	},
+	{
+		names:       []string{"par2"},
+		versionArgs: []string{"-V"},
+		purpose:     "--par2 recovery data",
+		install:     "install par2cmdline (apt install par2, brew install par2)",
+	},
+	{
+		names:       []string{"chdman"},
+		versionArgs: []string{"-help"},
+		purpose:     "CHD disc images",
+		optional:    true,
+		install:     "install MAME tools (apt install mame-tools, brew install rom-tools)",
+	},
+	{
+		names:       []string{"zstd"},
+		versionArgs: []string{"-V"},
+		purpose:     "zstd archives",
+		optional:    true,
+		install:     "install zstd (apt install zstd, brew install zstd)",
+	},
+}
+
+// versionPattern finds a version number in a tool's banner
+var versionPattern = regexp.MustCompile(`\bv?(\d+\.\d+(?:\.\d+)?)\b`)
+
+// CheckTools looks up each external tool and its version
+func CheckTools() []Check {
+	var checks []Check
+	for _, t := range tools {
+		checks = append(checks, checkTool(t))
+	}
+	checks = append(checks, check7zVariant())
+	return checks
+}
+
+func checkTool(t tool) Check {
+	names := t.names
+	if t.required && common.SevenZipPath != "" {
+		names = []string{common.SevenZipPath}
+	}
+	check := Check{Name: names[0]}
+	for _, name := range names {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		check.Name = name
+		check.Status = OK
+		check.Detail = path
+		if version := toolVersion(path, t.versionArgs); version != "" {
+			check.Detail = fmt.Sprintf("version %s (%s)", version, path)
+		}
+		return check
+	}
+
+	if t.optional {
+		check.Status = OK
+		check.Detail = "not installed (optional, for " + t.purpose + ")"
+		return check
+	}
+	check.Status = Warn
+	check.Detail = "not found, needed for " + t.purpose
+	if t.required {
+		check.Status = Fail
+	}
+	check.Fix = t.install
+	return check
+}
+
+// check7zVariant reports what the 7z executable in use can read: 7zr only handles
+// .7z, and 7za has no RAR support (7z and 7zz read all input formats)
+func check7zVariant() Check {
+	check := Check{Name: "7z formats", Status: OK}
+	names := common.SevenZipCommands
+	if common.SevenZipPath != "" {
+		names = []string{common.SevenZipPath}
+	}
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			continue
+		}
+		switch strings.TrimSuffix(strings.ToLower(filepath.Base(name)), ".exe") {
+		case "7zr":
+			check.Status = Warn
+			check.Detail = "7zr only reads .7z; .zip and .rar inputs will fail"
+			check.Fix = "install the full 7z (p7zip-full) or 7za"
+		case "7za":
+			check.Status = Warn
+			check.Detail = "7za has no RAR support; .rar inputs will fail"
+			check.Fix = "install the full 7z (p7zip-full and p7zip-rar)"
+		default:
+			check.Detail = ".7z, .zip and .rar"
+		}
+		return check
+	}
+	check.Status = Fail
+	check.Detail = "no 7z executable"
+	return check
+}
+
+// toolVersion runs a tool and returns the first version number it prints
+func toolVersion(path string, args []string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, _ := exec.CommandContext(ctx, path, args...).CombinedOutput() // Many tools exit non-zero after printing usage
+	if match := versionPattern.FindSubmatch(out); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// CheckConfig loads and validates the config file (the default location when path is empty)
+func CheckConfig(path string) Check {
+	check := Check{Name: "config"}
+	shown := path
+	if shown == "" {
+		shown = config.DefaultPath()
+	}
+	if _, err := config.Load(path); err != nil {
+		check.Status = Fail
+		check.Detail = err.Error()
+		check.Fix = "fix the config file, or pass --config with another one"
+		return check
+	}
+	check.Status = OK
+	if _, err := os.Stat(shown); err != nil {
+		check.Detail = fmt.Sprintf("no config file at %s, using defaults", shown)
+	} else {
+		check.Detail = shown + " is valid"
+	}
+	return check
+}
+
+// CheckDir tests that dir can be written and has at least minFree bytes available
+func CheckDir(name, dir string, minFree int64) []Check {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return []Check{{
+			Name:   name,
+			Status: Fail,
+			Detail: "not an existing directory",
+			Fix:    fmt.Sprintf("create %s or check the path", dir),
+		}}
+	}
+
+	write := Check{Name: name + " write access", Status: OK, Detail: dir}
+	file, err := os.CreateTemp(dir, ".rom-organizer-doctor-*")
+	if err != nil {
+		write.Status = Fail
+		write.Detail = err.Error()
+		write.Fix = fmt.Sprintf("check the permissions of %s or choose another directory", dir)
+	} else {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	space := Check{Name: name + " free space", Status: OK}
+	free, err := common.FreeSpace(dir)
+	switch {
+	case err != nil:
+		space.Status = Warn
+		space.Detail = fmt.Sprintf("could not measure: %v", err)
+	case free < minFree:
+		space.Status = Warn
+		space.Detail = fmt.Sprintf("%s free in %s, less than %s", common.FormatSize(free), dir, common.FormatSize(minFree))
+		space.Fix = "free up space, or set TMPDIR (or --output) to a larger disk"
+	default:
+		space.Detail = fmt.Sprintf("%s free in %s", common.FormatSize(free), filepath.Clean(dir))
+	}
+	return []Check{write, space}
+}
@@ -0,0 +1,288 @@
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+// Encryption tools for backups; the name is also the file extension of a backup
+const (
+	EncryptAge = "age"
+	EncryptGPG = "gpg"
+)
+
+// Encryption is how backups are encrypted: a tool and the recipients it encrypts to
+type Encryption struct {
+	Tool       string
+	Recipients []string
+}
+
+// ParseEncryption parses --encrypt values such as "age:age1..." and "gpg:me@example.org".
+// All recipients must use the same tool.
+func ParseEncryption(specs []string) (Encryption, error) {
+	var enc Encryption
+	for _, spec := range specs {
+		tool, recipient, ok := strings.Cut(spec, ":")
+		tool = strings.ToLower(tool)
+		if !ok || recipient == "" || (tool != EncryptAge && tool != EncryptGPG) {
+			return Encryption{}, fmt.Errorf("invalid encryption %q (use age:<recipient> or gpg:<recipient>)", spec)
+		}
+		if enc.Tool != "" && enc.Tool != tool {
+			return Encryption{}, fmt.Errorf("can't mix age and gpg recipients")
+		}
+		enc.Tool = tool
+		enc.Recipients = append(enc.Recipients, recipient)
+	}
+	if enc.Tool == "" {
+		return Encryption{}, fmt.Errorf("no recipient to encrypt to")
+	}
+	return enc, nil
+}
+
+// BackupFileName returns the file an organized game is backed up to, named after its
+// folder: "Title [ID].tar.age" or "Title [ID].tar.gpg"
+func BackupFileName(game library.Game, enc Encryption) string {
+	return filepath.Base(game.Path) + ".tar." + enc.Tool
+}
+
+// IsBackupFile reports whether path is named like an encrypted backup
+func IsBackupFile(path string) bool {
+	return backupFolderName(path) != ""
+}
+
+// encryptCommand returns the command encrypting stdin to the recipients into output.
+// An age recipient naming an existing file is read as a recipients file.
+func encryptCommand(enc Encryption, output string) (*exec.Cmd, error) {
+	tool, err := lookupTool(enc.Tool)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	if enc.Tool == EncryptAge {
+		for _, r := range enc.Recipients {
+			if fileExists(r) {
+				args = append(args, "-R", r)
+			} else {
+				args = append(args, "-r", r)
+			}
+		}
+		args = append(args, "-o", output)
+	} else {
+		args = []string{"--batch", "--yes", "--encrypt", "--compress-algo", "none"}
+		for _, r := range enc.Recipients {
+			args = append(args, "--recipient", r)
+		}
+		args = append(args, "--output", output)
+	}
+	return exec.Command(tool, args...), nil
+}
+
+// decryptCommand returns the command decrypting a backup file to stdout, by its extension.
+// age needs identity files; gpg finds the key in its keyring and may ask for the passphrase.
+func decryptCommand(path string, identities []string) (*exec.Cmd, error) {
+	toolName := EncryptGPG
+	if strings.HasSuffix(strings.ToLower(path), "."+EncryptAge) {
+		toolName = EncryptAge
+	}
+	tool, err := lookupTool(toolName)
+	if err != nil {
+		return nil, err
+	}
+	if toolName == EncryptGPG {
+		return exec.Command(tool, "--decrypt", path), nil
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("age backups need an identity file to decrypt (--identity)")
+	}
+	args := []string{"--decrypt"}
+	for _, identity := range identities {
+		args = append(args, "-i", identity)
+	}
+	return exec.Command(tool, append(args, path)...), nil
+}
+
+func lookupTool(name string) (string, error) {
+	tool, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w; install it to encrypt or decrypt backups", name, common.ErrToolNotFound)
+	}
+	return tool, nil
+}
+
+// WriteBackup writes an organized game folder (game.7z or game/, updates, DLC and the
+// other files in it) as a tar stream encrypted for the recipients into dest. Nothing
+// unencrypted is written to disk. It returns the path of the backup file.
+func WriteBackup(game library.Game, dest string, enc Encryption, force bool) (string, error) {
+	output := filepath.Join(dest, BackupFileName(game, enc))
+	if !force && fileExists(output) {
+		return "", fmt.Errorf("%s: %w", output, common.ErrTargetExists)
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", fmt.Errorf("creating %s: %w", dest, err)
+	}
+	tmp := output + ".tmp"
+	cmd, err := encryptCommand(enc, tmp)
+	if err != nil {
+		return "", err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("starting %s: %w", enc.Tool, err)
+	}
+
+	tw := tar.NewWriter(stdin)
+	writeErr := common.WriteDirTar(game.Path, filepath.Base(game.Path), tw, nil)
+	if writeErr == nil {
+		writeErr = tw.Close()
+	}
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("%s failed: %w: %s", enc.Tool, err, strings.TrimSpace(stderr.String()))
+	}
+	if writeErr != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("reading %s: %w", game.Path, writeErr)
+	}
+	if err := os.Rename(tmp, output); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// RestoreBackup decrypts a backup written by WriteBackup into the library root dest,
+// restoring the game folder it holds, and returns the folder's path. The backup is
+// unpacked next to its final place first, so an interrupted restore leaves no
+// half-written game folder behind.
+func RestoreBackup(file, dest string, identities []string, force bool) (string, error) {
+	// Backups are named after their folder; refuse before decrypting what can't be restored
+	if folder := backupFolderName(file); !force && folder != "" && fileExists(filepath.Join(dest, folder)) {
+		return "", fmt.Errorf("%s: %w", filepath.Join(dest, folder), common.ErrTargetExists)
+	}
+	cmd, err := decryptCommand(file, identities)
+	if err != nil {
+		return "", err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(&stderr, os.Stderr) // gpg may talk to the user
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", fmt.Errorf("creating %s: %w", dest, err)
+	}
+	tmp, err := os.MkdirTemp(dest, ".restore-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("starting %s: %w", cmd.Path, err)
+	}
+
+	folder, unpackErr := unpackBackup(tar.NewReader(stdout), tmp)
+	// Drain what's left, so the tool isn't stopped by a closed pipe before reporting
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("decrypting %s: %w: %s", file, err, strings.TrimSpace(stderr.String()))
+	}
+	if unpackErr != nil {
+		return "", fmt.Errorf("unpacking %s: %w", file, unpackErr)
+	}
+
+	target := filepath.Join(dest, folder)
+	if fileExists(target) {
+		if !force {
+			return "", fmt.Errorf("%s: %w", target, common.ErrTargetExists)
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(filepath.Join(tmp, folder), target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// backupFolderName returns the game folder a backup file is named after, "" if it isn't
+func backupFolderName(file string) string {
+	name := filepath.Base(file)
+	for _, tool := range []string{EncryptAge, EncryptGPG} {
+		if strings.HasSuffix(strings.ToLower(name), ".tar."+tool) {
+			return name[:len(name)-len(".tar."+tool)]
+		}
+	}
+	return ""
+}
+
+// unpackBackup extracts the tar stream of a backup into dir and returns the name of the
+// game folder it holds. Entries outside a single top-level folder, and links, are refused.
+func unpackBackup(tr *tar.Reader, dir string) (string, error) {
+	folder := ""
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name := filepath.FromSlash(path.Clean(header.Name))
+		top, _, _ := strings.Cut(name, string(filepath.Separator))
+		if !filepath.IsLocal(name) || top == "." || (folder != "" && top != folder) {
+			return "", fmt.Errorf("unexpected entry %s", header.Name)
+		}
+		folder = top
+
+		target := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeSymlink, tar.TypeLink:
+			// WriteBackup never writes links, so one could only lead somewhere else
+			return "", fmt.Errorf("unexpected link %s -> %s", header.Name, header.Linkname)
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return "", err
+			}
+			os.Chtimes(target, header.ModTime, header.ModTime)
+		default:
+			return "", fmt.Errorf("unexpected entry type for %s", header.Name)
+		}
+	}
+	if folder == "" {
+		return "", errors.New("backup is empty")
+	}
+	return folder, nil
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestUnpackBackupRefusesEscapes checks that backups with entries or links leading out
+// of their one game folder are refused before anything is written outside dir
+func TestUnpackBackupRefusesEscapes(t *testing.T) {
+	reg := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: 1}
+	}
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{"parent", []*tar.Header{reg("../escape")}},
+		{"absolute", []*tar.Header{reg("/tmp/escape")}},
+		{"through the folder", []*tar.Header{reg("Game [BLUS30001]/../../escape")}},
+		{"second folder", []*tar.Header{reg("Game [BLUS30001]/game.7z"), reg("Other/game.7z")}},
+		{"symlink", []*tar.Header{{Name: "Game [BLUS30001]/link", Typeflag: tar.TypeSymlink, Linkname: "../../escape"}}},
+		{"hard link", []*tar.Header{{Name: "Game [BLUS30001]/link", Typeflag: tar.TypeLink, Linkname: "/etc/passwd"}}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, h := range tt.headers {
+			if err := tw.WriteHeader(h); err != nil {
+				t.Fatal(err)
+			}
+			if h.Typeflag == tar.TypeReg {
+				tw.Write([]byte("x"))
+			}
+		}
+		tw.Close()
+
+		root := t.TempDir()
+		dir := filepath.Join(root, "restore")
+		if _, err := unpackBackup(tar.NewReader(&buf), dir); err == nil {
+			t.Errorf("%s: unpacked without error", tt.name)
+		}
+		entries, _ := os.ReadDir(root)
+		for _, entry := range entries {
+			if entry.Name() != "restore" {
+				t.Errorf("%s: wrote %s outside the restore folder", tt.name, entry.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+// ArtworkDir is the folder of an organized game holding cover art and screenshots
+// (listed in the layout's extra_dirs to keep it through conversions)
+const ArtworkDir = "_artwork"
+
+// imageExtensions are the artwork files a gallery shows
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+// GalleryGame is one game of an HTML gallery
+type GalleryGame struct {
+	Title    string
+	GameID   string
+	Console  string
+	Region   string // From the title ID, "" when it doesn't say
+	Version  string // APP_VER from PARAM.SFO
+	Format   string // "game.7z" or "game/"
+	Size     int64
+	Tags     []string
+	Compat   string // RPCS3 status, if looked up
+	Page     string // Detail page, relative to the gallery root
+	Cover    string // Cover image, relative to the gallery root; "" for none
+	Artwork  []string
+	coverSrc string // Image file the cover is copied from
+	icon     []byte // ICON0.PNG read from game.7z, when that is the cover
+	artSrc   []string
+}
+
+// NewGalleryGame collects what the gallery shows of an organized game: its catalog entry,
+// PARAM.SFO version and images (see findArtwork)
+func NewGalleryGame(game library.Game, entry *catalog.Entry, size int64) *GalleryGame {
+	info := game.Info.GameInfo
+	g := &GalleryGame{
+		Title:   info.Title,
+		GameID:  info.GameID,
+		Console: info.Console,
+		Region:  consoles.PS3Region(info.GameID),
+		Format:  "game/",
+		Size:    size,
+	}
+	if g.Console == "" {
+		g.Console = "PlayStation 3"
+	}
+	if game.Info.HasCompressed {
+		g.Format = "game.7z"
+	}
+	// The folder name has characters file systems reject replaced; PARAM.SFO has the real title
+	if sfo, err := library.ReadParamSFO(game); err == nil {
+		if sfo.GetTitle() != "" {
+			g.Title = sfo.GetTitle()
+		}
+		g.Version = sfo.GetString("APP_VER")
+	}
+	if entry != nil {
+		g.Tags = entry.Tags
+		if entry.Compat != nil {
+			g.Compat = entry.Compat.Status
+		}
+	}
+
+	g.coverSrc, g.icon, g.artSrc = findArtwork(game)
+	return g
+}
+
+// HasCover reports whether a cover image was found for the game
+func (g *GalleryGame) HasCover() bool {
+	return g.coverSrc != "" || g.icon != nil
+}
+
+// findArtwork returns a game's cover and its other _artwork images. The cover is the
+// image in _artwork/ named cover or front (else the first one there), falling back to the
+// game's ICON0.PNG; that is read into icon when it's only in game.7z.
+func findArtwork(game library.Game) (cover string, icon []byte, others []string) {
+	images := imageFiles(filepath.Join(game.Path, ArtworkDir))
+	for _, image := range images {
+		name := strings.ToLower(filepath.Base(image))
+		if cover == "" && (strings.Contains(name, "cover") || strings.Contains(name, "front")) {
+			cover = image
+		}
+	}
+	if cover == "" && len(images) > 0 {
+		cover = images[0]
+	}
+	for _, image := range images {
+		if image != cover {
+			others = append(others, image)
+		}
+	}
+	if cover == "" {
+		iconPath := filepath.Join(game.Path, "game", "PS3_GAME", "ICON0.PNG")
+		if fileExists(iconPath) {
+			cover = iconPath
+		} else if game.Info.HasCompressed {
+			icon, _ = common.Read7zFile(filepath.Join(game.Path, "game.7z"), "PS3_GAME/ICON0.PNG")
+		}
+	}
+	return cover, icon, others
+}
+
+// imageFiles returns the image files in a folder, sorted by name
+func imageFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var images []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		for _, imageExt := range imageExtensions {
+			if ext == imageExt {
+				images = append(images, filepath.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// GalleryOptions configures WriteGallery
+type GalleryOptions struct {
+	Title  string // Page title (default "Game Library")
+	Force  bool   // Write into a folder that has files but no gallery
+	DryRun bool
+}
+
+// galleryDirs are the folders of a gallery, replaced on every export
+var galleryDirs = []string{"games", "images"}
+
+// WriteGallery writes a static site to dir: index.html with a cover grid, a search box
+// and console, region and tag filters, and a page per game under games/. Covers and
+// artwork are copied to images/, so the folder can be shared or opened from disk as is;
+// nothing is loaded from elsewhere. Games keep the order they are given in.
+func WriteGallery(dir string, games []*GalleryGame, opts GalleryOptions) error {
+	if opts.Title == "" {
+		opts.Title = "Game Library"
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 && !fileExists(filepath.Join(dir, "index.html")) && !opts.Force {
+		return fmt.Errorf("%s has files but no gallery; use --force to write into it anyway", dir)
+	}
+
+	used := make(map[string]bool)
+	for _, g := range games {
+		slug := common.SanitizeFilename(g.GameID)
+		for n := 2; used[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", common.SanitizeFilename(g.GameID), n)
+		}
+		used[slug] = true
+		g.Page = path.Join("games", slug+".html")
+		if g.coverSrc != "" {
+			g.Cover = path.Join("images", slug, "cover"+strings.ToLower(filepath.Ext(g.coverSrc)))
+		} else if g.icon != nil {
+			g.Cover = path.Join("images", slug, "cover.png")
+		}
+		g.Artwork = nil
+		for _, src := range g.artSrc {
+			g.Artwork = append(g.Artwork, path.Join("images", slug, filepath.Base(src)))
+		}
+	}
+	if opts.DryRun {
+		return nil
+	}
+
+	for _, sub := range galleryDirs {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return fmt.Errorf("removing the previous gallery: %w", err)
+		}
+	}
+	for _, g := range games {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(path.Dir(g.Page))), 0755); err != nil {
+			return err
+		}
+		if err := writeGalleryImages(dir, g); err != nil {
+			return fmt.Errorf("%s [%s]: %w", g.Title, g.GameID, err)
+		}
+		if err := renderGalleryPage(filepath.Join(dir, filepath.FromSlash(g.Page)), gamePageTemplate, galleryPage{Title: opts.Title, Game: g, Root: "../"}); err != nil {
+			return err
+		}
+	}
+
+	page := galleryPage{Title: opts.Title, Games: games}
+	for _, g := range games {
+		page.Consoles = appendUnique(page.Consoles, g.Console)
+		page.Regions = appendUnique(page.Regions, g.Region)
+		for _, tag := range g.Tags {
+			page.Tags = appendUnique(page.Tags, tag)
+		}
+		page.Size += g.Size
+	}
+	sort.Strings(page.Consoles)
+	sort.Strings(page.Regions)
+	sort.Strings(page.Tags)
+	return renderGalleryPage(filepath.Join(dir, "index.html"), indexTemplate, page)
+}
+
+// writeGalleryImages copies a game's cover and artwork into the gallery
+func writeGalleryImages(dir string, g *GalleryGame) error {
+	if g.Cover == "" {
+		return nil
+	}
+	cover := filepath.Join(dir, filepath.FromSlash(g.Cover))
+	if err := os.MkdirAll(filepath.Dir(cover), 0755); err != nil {
+		return err
+	}
+	if g.icon != nil {
+		if err := os.WriteFile(cover, g.icon, 0644); err != nil {
+			return err
+		}
+	} else if err := common.CopyFile(g.coverSrc, cover); err != nil {
+		return err
+	}
+	for i, src := range g.artSrc {
+		if err := common.CopyFile(src, filepath.Join(dir, filepath.FromSlash(g.Artwork[i]))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// galleryPage is what the gallery templates are given
+type galleryPage struct {
+	Title    string
+	Root     string // Path from the page to the gallery root
+	Games    []*GalleryGame
+	Game     *GalleryGame
+	Consoles []string
+	Regions  []string
+	Tags     []string
+	Size     int64
+}
+
+func renderGalleryPage(file string, tmpl *template.Template, page galleryPage) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	err = tmpl.Execute(f, page)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", file, err)
+	}
+	return nil
+}
+
+var galleryFuncs = template.FuncMap{
+	"size":  common.FormatSize,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+const galleryStyle = `<style>
+body { margin: 0; font-family: system-ui, sans-serif; background: #16181d; color: #e6e6e6; }
+header { padding: 1rem 1.5rem; background: #1f2229; display: flex; flex-wrap: wrap; gap: .75rem; align-items: center; }
+header h1 { font-size: 1.3rem; margin: 0 1rem 0 0; }
+header input, header select { background: #2a2e37; color: inherit; border: 1px solid #3a3f4b; border-radius: 4px; padding: .4rem .6rem; }
+header .count { margin-left: auto; color: #9aa0ab; font-size: .9rem; }
+a { color: inherit; text-decoration: none; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(170px, 1fr)); gap: 1rem; padding: 1.5rem; }
+.card { background: #1f2229; border-radius: 6px; overflow: hidden; }
+.card:hover { outline: 2px solid #4c8bf5; }
+.cover { width: 100%; aspect-ratio: 1 / 1; object-fit: cover; background: #2a2e37; display: flex; align-items: center; justify-content: center; color: #6b717d; }
+.card .info { padding: .5rem .6rem .7rem; }
+.card .title { font-size: .9rem; line-height: 1.25; }
+.card .meta, .tags { color: #9aa0ab; font-size: .75rem; margin-top: .25rem; }
+.detail { max-width: 900px; margin: 0 auto; padding: 1.5rem; }
+.detail .top { display: flex; gap: 1.5rem; flex-wrap: wrap; }
+.detail .top .cover { width: 260px; border-radius: 6px; }
+.detail table { border-collapse: collapse; }
+.detail td { padding: .25rem 1rem .25rem 0; vertical-align: top; }
+.detail td:first-child { color: #9aa0ab; }
+.artwork { display: grid; grid-template-columns: repeat(auto-fill, minmax(260px, 1fr)); gap: .75rem; margin-top: 1.5rem; }
+.artwork img { width: 100%; border-radius: 4px; }
+</style>`
+
+var indexTemplate = template.Must(template.New("index").Funcs(galleryFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+` + galleryStyle + `
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="Search titles and IDs" autofocus>
+<select id="console"><option value="">All consoles</option>{{range .Consoles}}<option>{{.}}</option>{{end}}</select>
+<select id="region"><option value="">All regions</option>{{range .Regions}}<option>{{.}}</option>{{end}}</select>
+{{if .Tags}}<select id="tag"><option value="">All tags</option>{{range .Tags}}<option>{{.}}</option>{{end}}</select>{{end}}
+<span class="count"><span id="shown">{{len .Games}}</span> of {{len .Games}} games, {{size .Size}}</span>
+</header>
+<main class="grid">
+{{range .Games}}<a class="card" href="{{.Page}}" data-search="{{lower .Title}} {{lower .GameID}}" data-console="{{.Console}}" data-region="{{.Region}}" data-tags="|{{join .Tags "|"}}|">
+{{if .Cover}}<img class="cover" src="{{.Cover}}" alt="" loading="lazy">{{else}}<div class="cover">{{.GameID}}</div>{{end}}
+<div class="info"><div class="title">{{.Title}}</div><div class="meta">{{.GameID}}{{if .Region}} · {{.Region}}{{end}} · {{size .Size}}</div></div>
+</a>
+{{end}}</main>
+<script>
+(function () {
+  var search = document.getElementById("search");
+  var filters = ["console", "region", "tag"].map(function (id) { return document.getElementById(id); }).filter(Boolean);
+  var cards = Array.prototype.slice.call(document.querySelectorAll(".card"));
+  function update() {
+    var words = search.value.toLowerCase().split(/\s+/).filter(Boolean);
+    var shown = 0;
+    cards.forEach(function (card) {
+      var match = words.every(function (word) { return card.dataset.search.indexOf(word) >= 0; });
+      filters.forEach(function (select) {
+        if (!select.value) return;
+        if (select.id === "tag") match = match && card.dataset.tags.indexOf("|" + select.value + "|") >= 0;
+        else match = match && card.dataset[select.id] === select.value;
+      });
+      card.style.display = match ? "" : "none";
+      if (match) shown++;
+    });
+    document.getElementById("shown").textContent = shown;
+  }
+  search.addEventListener("input", update);
+  filters.forEach(function (select) { select.addEventListener("change", update); });
+})();
+</script>
+</body>
+</html>
+`))
+
+var gamePageTemplate = template.Must(template.New("game").Funcs(galleryFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Game.Title}} [{{.Game.GameID}}] - {{.Title}}</title>
+` + galleryStyle + `
+</head>
+<body>
+<header><h1><a href="{{.Root}}index.html">{{.Title}}</a></h1></header>
+{{with .Game}}<main class="detail">
+<div class="top">
+{{if .Cover}}<img class="cover" src="{{$.Root}}{{.Cover}}" alt="">{{end}}
+<div>
+<h2>{{.Title}}</h2>
+<table>
+<tr><td>Game ID</td><td>{{.GameID}}</td></tr>
+<tr><td>Console</td><td>{{.Console}}</td></tr>
+{{if .Region}}<tr><td>Region</td><td>{{.Region}}</td></tr>{{end}}
+{{if .Version}}<tr><td>Version</td><td>{{.Version}}</td></tr>{{end}}
+<tr><td>Format</td><td>{{.Format}}</td></tr>
+<tr><td>Size</td><td>{{size .Size}}</td></tr>
+{{if .Compat}}<tr><td>RPCS3</td><td>{{.Compat}}</td></tr>{{end}}
+{{if .Tags}}<tr><td>Tags</td><td>{{join .Tags ", "}}</td></tr>{{end}}
+</table>
+</div>
+</div>
+{{if .Artwork}}<div class="artwork">{{range .Artwork}}<img src="{{$.Root}}{{.}}" alt="" loading="lazy">{{end}}</div>{{end}}
+</main>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/ftp"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// installedGamesDir is where a console keeps installed games and game updates
+const installedGamesDir = "/dev_hdd0/game"
+
+// Installed reads the versions of the games installed on a console or in RPCS3, from
+// the PARAM.SFO that installing a game or its updates leaves in dev_hdd0/game/<ID>
+type Installed interface {
+	// Version returns the APP_VER of an installed title ID, or "" when it isn't installed
+	Version(titleID string) (string, error)
+	Close() error
+	String() string
+}
+
+// OpenInstalled opens ftp://host[:port] for a console, or a local dev_hdd0 folder (or the
+// RPCS3 folder containing it)
+func OpenInstalled(location string) (Installed, error) {
+	if strings.HasPrefix(location, "ftp://") {
+		host := strings.TrimSuffix(strings.TrimPrefix(location, "ftp://"), "/")
+		client, err := ftp.Dial(host, "", "", 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := client.List(installedGamesDir)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("listing %s on %s: %w", installedGamesDir, location, err)
+		}
+		titles := make(map[string]bool)
+		for _, entry := range entries {
+			if entry.IsDir {
+				titles[strings.ToUpper(entry.Name)] = true
+			}
+		}
+		return &ftpInstalled{client: client, location: location, titles: titles}, nil
+	}
+
+	root := location
+	if info, err := os.Stat(filepath.Join(location, "dev_hdd0")); err == nil && info.IsDir() {
+		root = filepath.Join(location, "dev_hdd0")
+	}
+	dir := filepath.Join(root, "game")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("no dev_hdd0/game folder in %s", location)
+	}
+	return &localInstalled{dir: dir}, nil
+}
+
+// installedVersion returns the APP_VER of a PARAM.SFO read from an installed game
+func installedVersion(data []byte, where string) (string, error) {
+	sfo, err := parsers.ParseParamSFO(data)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s: %w", where, err)
+	}
+	return sfo.GetString("APP_VER"), nil
+}
+
+// localInstalled is an emulator's dev_hdd0/game folder on a local disk
+type localInstalled struct {
+	dir string
+}
+
+func (l *localInstalled) Version(titleID string) (string, error) {
+	sfoPath := filepath.Join(l.dir, titleID, "PARAM.SFO")
+	data, err := os.ReadFile(sfoPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return installedVersion(data, sfoPath)
+}
+
+func (l *localInstalled) Close() error   { return nil }
+func (l *localInstalled) String() string { return l.dir }
+
+// ftpInstalled is a console's dev_hdd0/game folder reached over FTP
+type ftpInstalled struct {
+	client   *ftp.Client
+	location string
+	titles   map[string]bool // Folders in dev_hdd0/game, upper case
+}
+
+func (f *ftpInstalled) Version(titleID string) (string, error) {
+	if !f.titles[strings.ToUpper(titleID)] {
+		return "", nil
+	}
+	sfoPath := path.Join(installedGamesDir, titleID, "PARAM.SFO")
+	var buf bytes.Buffer
+	if err := f.client.Retrieve(sfoPath, &buf); err != nil {
+		// Some folders, such as game data without an update, have no PARAM.SFO
+		return "", nil
+	}
+	return installedVersion(buf.Bytes(), f.location+sfoPath)
+}
+
+func (f *ftpInstalled) Close() error   { return f.client.Close() }
+func (f *ftpInstalled) String() string { return f.location }
+
+// parseAppVersion parses an APP_VER such as "01.02" as major*100 + minor
+func parseAppVersion(s string) (int, bool) {
+	major, minor, ok := strings.Cut(strings.TrimSpace(s), ".")
+	m, err1 := strconv.Atoi(major)
+	n, err2 := strconv.Atoi(minor)
+	if !ok || err1 != nil || err2 != nil || m < 0 || n < 0 || n > 99 {
+		return 0, false
+	}
+	return m*100 + n, true
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"unicode/utf16"
+)
+
+// Shell link (MS-SHLLINK) flags and sizes used by shellLink
+const (
+	lnkHeaderSize     = 0x4C
+	lnkHasLinkInfo    = 0x02
+	lnkHasName        = 0x04
+	lnkHasWorkingDir  = 0x10
+	lnkHasArguments   = 0x20
+	lnkHasIconLoc     = 0x40
+	lnkIsUnicode      = 0x80
+	lnkShowNormal     = 1
+	lnkInfoHeaderSize = 0x24 // With the Unicode path offsets
+	lnkVolumeLocal    = 0x01 // VolumeIDAndLocalBasePath
+	lnkDriveFixed     = 3
+)
+
+// lnkCLSID is the class identifier every shell link header starts with
+var lnkCLSID = []byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+// shellLink returns a Windows .lnk file starting the emulator with the game's
+// arguments. The target is given by its local path (LinkInfo) rather than an item ID
+// list, which Windows resolves when the link is opened.
+func shellLink(s *Shortcut, emulator string) []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+
+	// ShellLinkHeader
+	binary.Write(&b, le, uint32(lnkHeaderSize))
+	b.Write(lnkCLSID)
+	binary.Write(&b, le, uint32(lnkHasLinkInfo|lnkHasName|lnkHasWorkingDir|lnkHasArguments|lnkHasIconLoc|lnkIsUnicode))
+	binary.Write(&b, le, uint32(0)) // FileAttributes
+	b.Write(make([]byte, 24))       // Creation, access and write times
+	binary.Write(&b, le, uint32(0)) // FileSize
+	binary.Write(&b, le, int32(0))  // IconIndex
+	binary.Write(&b, le, uint32(lnkShowNormal))
+	b.Write(make([]byte, 2+2+4+4)) // HotKey and reserved fields
+
+	// LinkInfo with a local base path, in ANSI and UTF-16
+	volumeID := []byte{0x11, 0, 0, 0, lnkDriveFixed, 0, 0, 0, 0, 0, 0, 0, 0x10, 0, 0, 0, 0}
+	ansiPath := append([]byte(emulator), 0)
+	unicodePath := utf16z(emulator)
+	volumeOffset := uint32(lnkInfoHeaderSize)
+	pathOffset := volumeOffset + uint32(len(volumeID))
+	suffixOffset := pathOffset + uint32(len(ansiPath))
+	unicodePathOffset := suffixOffset + 1
+	unicodeSuffixOffset := unicodePathOffset + uint32(len(unicodePath))
+	size := unicodeSuffixOffset + 2
+	for _, v := range []uint32{size, lnkInfoHeaderSize, lnkVolumeLocal, volumeOffset, pathOffset, 0, suffixOffset, unicodePathOffset, unicodeSuffixOffset} {
+		binary.Write(&b, le, v)
+	}
+	b.Write(volumeID)
+	b.Write(ansiPath)
+	b.WriteByte(0) // Empty common path suffix
+	b.Write(unicodePath)
+	b.Write([]byte{0, 0})
+
+	// StringData: name, working directory, arguments, icon location
+	for _, str := range []string{s.Title, filepath.Dir(emulator), commandLine(s.Args), emulator} {
+		units := utf16.Encode([]rune(str))
+		binary.Write(&b, le, uint16(len(units)))
+		binary.Write(&b, le, units)
+	}
+
+	binary.Write(&b, le, uint32(0)) // TerminalBlock
+	return b.Bytes()
+}
+
+// utf16z encodes s as NUL-terminated UTF-16LE
+func utf16z(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2*len(units)+2)
+	for _, u := range units {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return append(out, 0, 0)
+}
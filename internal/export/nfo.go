@@ -0,0 +1,182 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif" // Decoders for covers converted to folder.jpg
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+const (
+	// NFOFile is the metadata file written next to game.7z or game/: named after the game
+	// file the way ROM scrapers look for <rom>.nfo
+	NFOFile = "game.nfo"
+	// FolderImage is the thumbnail Kodi and media managers show for a folder
+	FolderImage = "folder.jpg"
+)
+
+// nfoGenerator marks NFO files written by rom-organizer, which later exports replace
+const nfoGenerator = "<!-- written by rom-organizer export nfo -->"
+
+// GameNFO is the <game> element of a Kodi game NFO file
+type GameNFO struct {
+	XMLName  xml.Name `xml:"game"`
+	Title    string   `xml:"title"`
+	Platform string   `xml:"platform"`
+	GameID   string   `xml:"gameid"`
+	Region   string   `xml:"region,omitempty"`
+	Version  string   `xml:"version,omitempty"`
+	Genres   []string `xml:"genre"` // Catalog tags
+	Thumb    string   `xml:"thumb,omitempty"`
+	Fanart   []string `xml:"fanart>thumb"`
+}
+
+// NFOOptions configures WriteNFO
+type NFOOptions struct {
+	Force  bool // Replace NFO files and folder images not written by rom-organizer
+	DryRun bool
+}
+
+// NFOResult is what WriteNFO wrote for one game
+type NFOResult struct {
+	NFO       string // Path of the NFO file, "" when it was kept
+	Image     string // Path of folder.jpg, "" when it was kept or there is no cover
+	Unchanged bool   // The NFO file was already up to date
+}
+
+// NewGameNFO builds the NFO of an organized game from its PARAM.SFO, catalog entry and
+// artwork. Kodi platform names are used ("Sony PlayStation 3"), the catalog tags become
+// genres and the _artwork images other than the cover become fanart.
+func NewGameNFO(game library.Game, entry *catalog.Entry) *GameNFO {
+	info := game.Info.GameInfo
+	nfo := &GameNFO{
+		Title:    info.Title,
+		Platform: kodiPlatform(info.Console),
+		GameID:   info.GameID,
+		Region:   consoles.PS3Region(info.GameID),
+	}
+	if sfo, err := library.ReadParamSFO(game); err == nil {
+		if sfo.GetTitle() != "" {
+			nfo.Title = sfo.GetTitle()
+		}
+		nfo.Version = sfo.GetString("APP_VER")
+	}
+	if entry != nil {
+		nfo.Genres = entry.Tags
+	}
+
+	cover, icon, others := findArtwork(game)
+	if cover != "" || icon != nil {
+		nfo.Thumb = FolderImage
+	}
+	for _, image := range others {
+		rel, _ := filepath.Rel(game.Path, image)
+		nfo.Fanart = append(nfo.Fanart, filepath.ToSlash(rel))
+	}
+	return nfo
+}
+
+// kodiPlatform returns the platform name Kodi's game add-ons use for a console
+func kodiPlatform(console string) string {
+	if console == "" {
+		console = "PlayStation 3"
+	}
+	if strings.HasPrefix(console, "PlayStation") {
+		return "Sony " + console
+	}
+	return console
+}
+
+// Marshal returns the NFO file's contents
+func (n *GameNFO) Marshal() ([]byte, error) {
+	data, err := xml.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(nfoGenerator + "\n")
+	buf.Write(data)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// WriteNFO writes game.nfo into an organized game folder, and folder.jpg from its cover
+// (see findArtwork), converted to JPEG when it's another format. An NFO file or folder.jpg
+// that is already there is kept unless rom-organizer wrote it, or with Force. An NFO file
+// that wouldn't change is left as it is.
+func WriteNFO(game library.Game, entry *catalog.Entry, opts NFOOptions) (*NFOResult, error) {
+	nfo := NewGameNFO(game, entry)
+	data, err := nfo.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NFOResult{}
+	nfoPath := filepath.Join(game.Path, NFOFile)
+	existing, err := os.ReadFile(nfoPath)
+	switch {
+	case err == nil && bytes.Equal(existing, data):
+		result.Unchanged = true
+	case err == nil && !bytes.Contains(existing, []byte(nfoGenerator)) && !opts.Force:
+		// Written by hand or by a scraper
+	default:
+		result.NFO = nfoPath
+	}
+
+	imagePath := filepath.Join(game.Path, FolderImage)
+	if nfo.Thumb != "" && (!fileExists(imagePath) || opts.Force) {
+		result.Image = imagePath
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if result.NFO != "" {
+		if err := os.WriteFile(nfoPath, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	if result.Image != "" {
+		cover, icon, _ := findArtwork(game)
+		if err := writeFolderImage(cover, icon, imagePath); err != nil {
+			return result, fmt.Errorf("writing %s: %w", FolderImage, err)
+		}
+	}
+	return result, nil
+}
+
+// writeFolderImage writes a cover as a JPEG file, copying it when it already is one
+func writeFolderImage(cover string, icon []byte, dest string) error {
+	data, name := icon, "ICON0.PNG"
+	if cover != "" {
+		name = filepath.Base(cover)
+		var err error
+		if data, err = os.ReadFile(cover); err != nil {
+			return err
+		}
+	}
+	if ext := strings.ToLower(filepath.Ext(cover)); ext == ".jpg" || ext == ".jpeg" {
+		return os.WriteFile(dest, data, 0644)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("converting %s to JPEG: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, buf.Bytes(), 0644)
+}
@@ -0,0 +1,214 @@
+// Package export copies organized games into the layouts that consoles and other tools expect
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// Folders at the root of a USB stick used by the package manager of PS3 HEN
+const (
+	PackagesDir = "packages" // Installer packages (.pkg)
+	LicensesDir = "exdata"   // Licenses (.rap/.rif) activated with the packages
+)
+
+// PKGLayoutOptions controls an export to the HEN package layout
+type PKGLayoutOptions struct {
+	Layout      common.Layout // Folder names of the organized games
+	LicenseDirs []string      // Extra folders searched for licenses of the exported packages
+	Force       bool          // Replace files of the same name that differ in size
+	DryRun      bool          // Report what would be copied without copying
+
+	// Installed leaves out update packages that aren't newer than the version installed
+	// there (nil only compares them with the game's own APP_VER)
+	Installed Installed
+}
+
+// PKGLayoutResult lists the files an export copied, by name
+type PKGLayoutResult struct {
+	Packages []string
+	Licenses []string
+	Skipped  []string // Already present at the destination with the same size
+
+	// Outdated are update packages no newer than the game's APP_VER or the installed
+	// version, as "<name> (<version>)"
+	Outdated []string
+
+	// Version is the newest of the game's APP_VER and the installed version
+	Version string
+}
+
+// PKGLayout copies the update and DLC packages of an organized game, and their licenses,
+// into dest/packages and dest/exdata. Update packages are only copied when they are
+// newer than the game's APP_VER and the version in opts.Installed; those of homebrew
+// are always copied.
+func PKGLayout(game library.Game, dest string, opts PKGLayoutOptions) (*PKGLayoutResult, error) {
+	gameDir := game.Path
+	layout := opts.Layout.WithDefaults()
+	var packages, licenses []string
+	for _, dir := range []string{layout.UpdatesDir, layout.DLCDir} {
+		err := filepath.Walk(filepath.Join(gameDir, dir), func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			if err != nil || info.IsDir() {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".pkg":
+				packages = append(packages, path)
+			case ".rap", ".rif":
+				licenses = append(licenses, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", gameDir, err)
+		}
+	}
+
+	// Licenses elsewhere are only taken for the content IDs of the exported packages
+	contentIDs := make(map[string]bool)
+	for _, path := range packages {
+		if header, err := parsers.ReadPKGHeader(path); err == nil {
+			contentIDs[header.ContentID] = true
+		}
+	}
+	for _, dir := range opts.LicenseDirs {
+		for id := range contentIDs {
+			for _, ext := range []string{".rap", ".rif"} {
+				path := filepath.Join(dir, id+ext)
+				if _, err := os.Stat(path); err == nil {
+					licenses = append(licenses, path)
+				}
+			}
+		}
+	}
+
+	result := &PKGLayoutResult{}
+	current, err := currentVersion(game, opts.Installed)
+	if err != nil {
+		return nil, err
+	}
+	result.Version = current
+	packages, result.Outdated = newerUpdates(packages, filepath.Join(gameDir, layout.UpdatesDir), current)
+
+	for _, path := range packages {
+		copied, err := exportFile(path, filepath.Join(dest, PackagesDir), opts)
+		if err != nil {
+			return result, err
+		}
+		if copied {
+			result.Packages = append(result.Packages, filepath.Base(path))
+		} else {
+			result.Skipped = append(result.Skipped, filepath.Base(path))
+		}
+	}
+	for _, path := range licenses {
+		copied, err := exportFile(path, filepath.Join(dest, LicensesDir), opts)
+		if err != nil {
+			return result, err
+		}
+		if copied {
+			result.Licenses = append(result.Licenses, filepath.Base(path))
+		} else {
+			result.Skipped = append(result.Skipped, filepath.Base(path))
+		}
+	}
+	return result, nil
+}
+
+// exportFile copies a file into dir, skipping it when a file of the same name and size
+// is already there. It reports whether the file was (or would be) copied.
+func exportFile(path, dir string, opts PKGLayoutOptions) (bool, error) {
+	target := filepath.Join(dir, filepath.Base(path))
+	if existing, err := os.Stat(target); err == nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		if existing.Size() == info.Size() {
+			return false, nil
+		}
+		if !opts.Force {
+			return false, fmt.Errorf("%w: %s differs from %s (use --force to replace it)", common.ErrTargetExists, target, path)
+		}
+	}
+	if opts.DryRun {
+		return true, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("creating %s: %w", dir, err)
+	}
+	if err := common.CopyFile(path, target); err != nil {
+		return false, fmt.Errorf("copying %s: %w", filepath.Base(path), err)
+	}
+	return true, nil
+}
+
+// currentVersion returns the newest of the game's APP_VER and the version installed
+// for its game ID, "" when neither is known. Homebrew isn't checked: its versions
+// don't follow the update packages of licensed games, so "" keeps every package.
+func currentVersion(game library.Game, installed Installed) (string, error) {
+	current := ""
+	if sfo, err := library.ReadParamSFO(game); err == nil {
+		if consoles.IsPS3Homebrew(sfo.GetString("CATEGORY"), sfo.GetTitleID()) {
+			return "", nil
+		}
+		current = sfo.GetString("APP_VER")
+	}
+	if installed == nil {
+		return current, nil
+	}
+	version, err := installed.Version(game.Info.GameInfo.GameID)
+	if err != nil {
+		return "", fmt.Errorf("reading installed version from %s: %w", installed, err)
+	}
+	if v, ok := parseAppVersion(version); ok {
+		if c, ok := parseAppVersion(current); !ok || v > c {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// newerUpdates splits packages into those to copy and the update packages in updatesDir
+// whose APP_VER is not newer than current. Packages whose version can't be read are kept.
+func newerUpdates(packages []string, updatesDir, current string) (keep, outdated []string) {
+	c, ok := parseAppVersion(current)
+	for _, path := range packages {
+		if !ok || !strings.HasPrefix(path, updatesDir+string(filepath.Separator)) {
+			keep = append(keep, path)
+			continue
+		}
+		version := packageVersion(path)
+		if v, known := parseAppVersion(version); known && v <= c {
+			outdated = append(outdated, fmt.Sprintf("%s (%s)", filepath.Base(path), version))
+			continue
+		}
+		keep = append(keep, path)
+	}
+	return keep, outdated
+}
+
+// packageVersion returns the APP_VER of the PARAM.SFO in a package, "" if unreadable
+func packageVersion(path string) string {
+	pkg, err := consoles.OpenPS3PKG(path)
+	if err != nil {
+		return ""
+	}
+	defer pkg.Close()
+	sfo, err := pkg.ParamSFO()
+	if err != nil || sfo == nil {
+		return ""
+	}
+	return sfo.GetString("APP_VER")
+}
@@ -0,0 +1,293 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+// Shortcut formats
+const (
+	ShortcutDesktop = "desktop" // freedesktop.org .desktop entries (Linux desktops)
+	ShortcutLNK     = "lnk"     // Windows shell links
+	ShortcutSteam   = "steam"   // Non-Steam game entries in Steam's shortcuts.vdf
+)
+
+// ShortcutFormats lists the formats WriteShortcuts can write
+var ShortcutFormats = []string{ShortcutDesktop, ShortcutLNK, ShortcutSteam}
+
+// ParseShortcutFormat checks a shortcut format name
+func ParseShortcutFormat(name string) (string, error) {
+	name = strings.ToLower(name)
+	for _, format := range ShortcutFormats {
+		if name == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown shortcut format %q (use %s)", name, strings.Join(ShortcutFormats, ", "))
+}
+
+// DefaultEmulator and DefaultEmulatorArgs start RPCS3 straight into a game, without its game list
+const (
+	DefaultEmulator     = "rpcs3"
+	DefaultEmulatorArgs = "--no-gui {eboot}"
+)
+
+// ErrNotLaunchable means a game is only kept compressed as game.7z, which an emulator can't boot
+var ErrNotLaunchable = errors.New("only kept as game.7z; decompress it to launch it from a shortcut")
+
+// ShortcutOptions configures the shortcuts written for a library
+type ShortcutOptions struct {
+	Format   string            // One of ShortcutFormats
+	Emulator string            // Emulator executable, as a path or a name in PATH
+	Args     string            // Emulator arguments; {eboot}, {game}, {title} and {id} are replaced
+	Layout   common.Layout     // Folder names inside organized game directories
+	Names    common.NameLimits // Length limits of the shortcut files, counted from the folder
+	DryRun   bool              // Report what would be written without writing
+}
+
+// Shortcut launches one organized game in an emulator
+type Shortcut struct {
+	Title  string
+	GameID string
+	Eboot  string   // PS3_GAME/USRDIR/EBOOT.BIN of the game
+	Icon   string   // PS3_GAME/ICON0.PNG, "" if the game has none
+	Manual string   // First file in the game's manuals folder, "" if none
+	Args   []string // Emulator arguments with the placeholders replaced
+}
+
+// NewShortcut builds the shortcut of an organized game. Paths are absolute, as seen from
+// this machine, so shortcuts should be made where they will be used.
+func NewShortcut(game library.Game, opts ShortcutOptions) (*Shortcut, error) {
+	info := game.Info.GameInfo
+	if !game.Info.HasDecompressed {
+		return nil, ErrNotLaunchable
+	}
+	dir, err := filepath.Abs(game.Path)
+	if err != nil {
+		return nil, err
+	}
+	gameDir := filepath.Join(dir, "game", "PS3_GAME")
+	eboot := filepath.Join(gameDir, "USRDIR", "EBOOT.BIN")
+	if _, err := os.Stat(eboot); err != nil {
+		return nil, fmt.Errorf("no EBOOT.BIN to launch: %w", err)
+	}
+
+	s := &Shortcut{Title: info.Title, GameID: info.GameID, Eboot: eboot}
+	// The folder name has characters file systems reject replaced; PARAM.SFO has the real title
+	if sfo, err := library.ReadParamSFO(game); err == nil && sfo.GetTitle() != "" {
+		s.Title = sfo.GetTitle()
+	}
+	if icon := filepath.Join(gameDir, "ICON0.PNG"); fileExists(icon) {
+		s.Icon = icon
+	}
+	s.Manual = firstFile(filepath.Join(dir, opts.Layout.WithDefaults().ManualsDir))
+
+	args := opts.Args
+	if args == "" {
+		args = DefaultEmulatorArgs
+	}
+	replacer := strings.NewReplacer("{eboot}", eboot, "{game}", dir, "{title}", s.Title, "{id}", s.GameID)
+	// Split before replacing, so paths and titles with spaces stay one argument each
+	for _, arg := range strings.Fields(args) {
+		s.Args = append(s.Args, replacer.Replace(arg))
+	}
+	return s, nil
+}
+
+// ResolveEmulator returns the absolute path of the emulator executable. A name that
+// isn't in PATH is kept as is for .desktop entries, which look it up at launch, and is
+// an error for the other formats, which need the full path.
+func ResolveEmulator(opts ShortcutOptions) (string, error) {
+	emulator := opts.Emulator
+	if emulator == "" {
+		emulator = DefaultEmulator
+	}
+	if strings.ContainsAny(emulator, `/\`) {
+		return filepath.Abs(emulator)
+	}
+	path, err := exec.LookPath(emulator)
+	if err == nil {
+		return filepath.Abs(path)
+	}
+	if opts.Format == ShortcutDesktop {
+		return emulator, nil
+	}
+	return "", fmt.Errorf("emulator %s not found in PATH; give its full path", emulator)
+}
+
+// ShortcutFileName returns the file a shortcut is written to in a .desktop or .lnk folder,
+// the game's folder name with the title shortened to fit limits
+func ShortcutFileName(s *Shortcut, format string, limits common.NameLimits) (string, error) {
+	return limits.Name(".", s.Title, " ["+s.GameID+"]."+format)
+}
+
+// WriteShortcuts writes shortcuts for the emulator to dest: a folder of .desktop or .lnk
+// files, or for Steam, the shortcuts.vdf file to update. It returns the files written.
+func WriteShortcuts(shortcuts []*Shortcut, emulator, dest string, opts ShortcutOptions) ([]string, error) {
+	switch opts.Format {
+	case ShortcutSteam:
+		if opts.DryRun {
+			return []string{dest}, nil
+		}
+		if err := writeSteamShortcuts(shortcuts, emulator, dest); err != nil {
+			return nil, err
+		}
+		return []string{dest}, nil
+	case ShortcutDesktop, ShortcutLNK:
+	default:
+		_, err := ParseShortcutFormat(opts.Format)
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return nil, fmt.Errorf("creating shortcut folder: %w", err)
+		}
+	}
+	var written []string
+	for _, s := range shortcuts {
+		name, err := ShortcutFileName(s, opts.Format, opts.Names)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dest, name)
+		written = append(written, path)
+		if opts.DryRun {
+			continue
+		}
+		var data []byte
+		var mode os.FileMode = 0644
+		if opts.Format == ShortcutDesktop {
+			// Desktop environments only launch entries outside the menu folders when executable
+			data, mode = desktopEntry(s, emulator), 0755
+		} else {
+			data = shellLink(s, emulator)
+		}
+		if err := os.WriteFile(path, data, mode); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	return written, nil
+}
+
+// desktopEntry returns a freedesktop.org desktop entry launching the game, with an
+// action opening its manual when it has one
+func desktopEntry(s *Shortcut, emulator string) []byte {
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	fmt.Fprintf(&b, "Name=%s\n", desktopValue(s.Title))
+	fmt.Fprintf(&b, "Comment=%s\n", desktopValue(s.GameID))
+	fmt.Fprintf(&b, "Exec=%s\n", desktopExec(append([]string{emulator}, s.Args...)))
+	if s.Icon != "" {
+		fmt.Fprintf(&b, "Icon=%s\n", desktopValue(s.Icon))
+	}
+	b.WriteString("Terminal=false\n")
+	b.WriteString("Categories=Game;Emulator;\n")
+	if s.Manual != "" {
+		b.WriteString("Actions=manual;\n\n")
+		b.WriteString("[Desktop Action manual]\n")
+		b.WriteString("Name=Open Manual\n")
+		fmt.Fprintf(&b, "Exec=%s\n", desktopExec([]string{"xdg-open", s.Manual}))
+	}
+	return []byte(b.String())
+}
+
+// desktopValue escapes a desktop entry string value
+func desktopValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+}
+
+// desktopExec quotes a command line for the Exec key of a desktop entry, with % doubled
+func desktopExec(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = posixArg(strings.ReplaceAll(arg, "%", "%%"))
+	}
+	return desktopValue(strings.Join(quoted, " "))
+}
+
+// commandLine joins arguments for the command line of the platform the shortcut is
+// written on: Windows quoting on Windows, POSIX shell quoting elsewhere
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if runtime.GOOS == "windows" {
+			quoted[i] = windowsArg(arg)
+		} else {
+			quoted[i] = posixArg(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// posixArg double-quotes an argument with characters a shell or desktop entry treats
+// specially, escaping ", `, $ and \ inside the quotes
+func posixArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`).Replace(arg) + `"`
+}
+
+// windowsArg quotes an argument the way the Microsoft C runtime splits command lines:
+// backslashes are only special before a double quote
+func windowsArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, r := range arg {
+		switch r {
+		case '\\':
+			slashes++
+			continue
+		case '"':
+			// Backslashes before a quote are doubled and the quote escaped
+			b.WriteString(strings.Repeat(`\`, 2*slashes+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, slashes))
+		}
+		slashes = 0
+		b.WriteRune(r)
+	}
+	// Backslashes before the closing quote are doubled too
+	b.WriteString(strings.Repeat(`\`, 2*slashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// firstFile returns the first file in dir by name, "" if there is none
+func firstFile(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return filepath.Join(dir, names[0])
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
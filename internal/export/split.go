@@ -0,0 +1,207 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+// SplitManifestName is the manifest written at the root of every bucket
+const SplitManifestName = "manifest.json"
+
+// SectorSize is the block size of optical media file systems (UDF, ISO 9660); every
+// file occupies a whole number of sectors
+const SectorSize = 2048
+
+// SplitReserve is kept free in every bucket for file system structures and the manifest
+const SplitReserve = 32 * 1024 * 1024
+
+// MediaSizes are the capacities of common backup media, usable as --size values
+var MediaSizes = map[string]int64{
+	"dvd":    4_700_372_992,
+	"dvd-dl": 8_547_991_552,
+	"bd25":   25_025_314_816,
+	"bd50":   50_050_629_632,
+	"bd100":  100_103_356_416,
+	"lto5":   1_500_000_000_000,
+	"lto6":   2_500_000_000_000,
+}
+
+// ParseMediaSize parses a media name from MediaSizes or a size such as "25GB"
+func ParseMediaSize(s string) (int64, error) {
+	if size, ok := MediaSizes[strings.ToLower(s)]; ok {
+		return size, nil
+	}
+	return common.ParseSize(s)
+}
+
+// SplitGame is an organized game assigned to a bucket
+type SplitGame struct {
+	Title  string `json:"title"`
+	GameID string `json:"game_id"`
+	Dir    string `json:"dir"`  // Folder name inside the bucket
+	Size   int64  `json:"size"` // Space taken on the media, in bytes
+	Source string `json:"-"`    // Organized game directory
+}
+
+// Bucket is one numbered volume of a split
+type Bucket struct {
+	Number int         `json:"number"`
+	Name   string      `json:"name"` // Folder name, e.g. backup-01
+	Size   int64       `json:"size"`
+	Games  []SplitGame `json:"games"`
+}
+
+// SplitPlan is the assignment of games to buckets
+type SplitPlan struct {
+	Capacity  int64 // Usable bytes per bucket after SplitReserve
+	Buckets   []*Bucket
+	Oversized []SplitGame // Games larger than a whole bucket, left out
+}
+
+// splitManifest is written as manifest.json in each bucket
+type splitManifest struct {
+	Bucket  int         `json:"bucket"`
+	Buckets int         `json:"buckets"`
+	Size    int64       `json:"size"`
+	Created time.Time   `json:"created"`
+	Games   []SplitGame `json:"games"`
+}
+
+// PlanSplit assigns games to as few buckets of mediaSize bytes as it can (first fit,
+// largest games first). Buckets are named prefix-01, prefix-02, ... Game folders are
+// renamed with their titles shortened when they don't fit limits inside a bucket.
+func PlanSplit(games []library.Game, mediaSize int64, prefix string, limits common.NameLimits) (*SplitPlan, error) {
+	plan := &SplitPlan{Capacity: mediaSize - SplitReserve}
+	if plan.Capacity <= 0 {
+		return nil, fmt.Errorf("bucket size %s is too small", common.FormatSize(mediaSize))
+	}
+
+	var items []SplitGame
+	for _, game := range games {
+		size, err := mediaUsage(game.Path)
+		if err != nil {
+			return nil, err
+		}
+		item := SplitGame{Dir: filepath.Base(game.Path), Size: size, Source: game.Path}
+		if info := game.Info.GameInfo; info != nil {
+			item.Title, item.GameID = info.Title, info.GameID
+			// Leaves room for a hundred buckets or more
+			bucket := fmt.Sprintf("%s-%03d", prefix, 0)
+			if item.Dir, err = limits.FitName(bucket, item.Dir, info.GameID); err != nil {
+				return nil, err
+			}
+		}
+		if size > plan.Capacity {
+			plan.Oversized = append(plan.Oversized, item)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Size > items[j].Size })
+	for _, item := range items {
+		var target *Bucket
+		for _, bucket := range plan.Buckets {
+			if bucket.Size+item.Size <= plan.Capacity {
+				target = bucket
+				break
+			}
+		}
+		if target == nil {
+			number := len(plan.Buckets) + 1
+			target = &Bucket{Number: number, Name: fmt.Sprintf("%s-%02d", prefix, number)}
+			plan.Buckets = append(plan.Buckets, target)
+		}
+		target.Games = append(target.Games, item)
+		target.Size += item.Size
+	}
+
+	for _, bucket := range plan.Buckets {
+		sort.SliceStable(bucket.Games, func(i, j int) bool {
+			return common.NaturalLess(bucket.Games[i].Title, bucket.Games[j].Title)
+		})
+	}
+	return plan, nil
+}
+
+// WriteSplit copies the games of each bucket into dest/<bucket name>/ and writes the
+// bucket's manifest. Existing bucket folders are refused unless force is set.
+func WriteSplit(plan *SplitPlan, dest string, force bool, progress func(bucket *Bucket, game SplitGame)) error {
+	for _, bucket := range plan.Buckets {
+		dir := filepath.Join(dest, bucket.Name)
+		if _, err := os.Stat(dir); err == nil {
+			if !force {
+				return fmt.Errorf("%w: %s (use --force to replace it)", common.ErrTargetExists, dir)
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("removing %s: %w", dir, err)
+			}
+		}
+	}
+
+	for _, bucket := range plan.Buckets {
+		dir := filepath.Join(dest, bucket.Name)
+		for _, game := range bucket.Games {
+			if progress != nil {
+				progress(bucket, game)
+			}
+			if err := common.CopyDir(game.Source, filepath.Join(dir, game.Dir)); err != nil {
+				return fmt.Errorf("copying %s to %s: %w", game.Dir, bucket.Name, err)
+			}
+		}
+		if err := writeSplitManifest(dir, bucket, len(plan.Buckets)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSplitManifest writes the manifest of a bucket into its folder
+func writeSplitManifest(dir string, bucket *Bucket, total int) error {
+	manifest := splitManifest{
+		Bucket:  bucket.Number,
+		Buckets: total,
+		Size:    bucket.Size,
+		Created: time.Now().UTC(),
+		Games:   bucket.Games,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, SplitManifestName)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// mediaUsage returns the space a directory takes on optical media, with every file
+// rounded up to whole sectors
+func mediaUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += (info.Size() + SectorSize - 1) / SectorSize * SectorSize
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("measuring size of %s: %w", dir, err)
+	}
+	return total, nil
+}
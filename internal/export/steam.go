@@ -0,0 +1,213 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// steamShortcutTag marks the shortcuts.vdf entries written by rom-organizer, so a later
+// export replaces them and leaves the user's other non-Steam games alone
+const steamShortcutTag = "rom-organizer"
+
+// Binary VDF value types
+const (
+	vdfMap    = 0x00
+	vdfString = 0x01
+	vdfInt    = 0x02
+	vdfEnd    = 0x08
+)
+
+// vdfNode is a key and value in a binary VDF file; Children is set for maps
+type vdfNode struct {
+	Key      string
+	Type     byte
+	String   string
+	Int      uint32
+	Children []*vdfNode
+}
+
+// child returns the child node with key, or nil
+func (n *vdfNode) child(key string) *vdfNode {
+	for _, c := range n.Children {
+		if c.Key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// writeSteamShortcuts replaces the rom-organizer entries in a Steam shortcuts.vdf with
+// shortcuts, keeping every other entry. The previous file is kept as shortcuts.vdf.bak.
+// Steam rewrites the file when it exits, so it must be closed while the file is updated.
+func writeSteamShortcuts(shortcuts []*Shortcut, emulator, path string) error {
+	root := &vdfNode{Type: vdfMap}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if root, err = parseVDF(data); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+			return fmt.Errorf("backing up %s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	list := root.child("shortcuts")
+	if list == nil {
+		list = &vdfNode{Key: "shortcuts", Type: vdfMap}
+		root.Children = append(root.Children, list)
+	}
+	var kept []*vdfNode
+	for _, entry := range list.Children {
+		if !isOwnSteamShortcut(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	for _, s := range shortcuts {
+		kept = append(kept, steamEntry(s, emulator))
+	}
+	// Entries are keyed by their position
+	for i, entry := range kept {
+		entry.Key = strconv.Itoa(i)
+	}
+	list.Children = kept
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	var b bytes.Buffer
+	writeVDFChildren(&b, root)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// isOwnSteamShortcut reports whether a shortcuts.vdf entry carries steamShortcutTag
+func isOwnSteamShortcut(entry *vdfNode) bool {
+	tags := entry.child("tags")
+	if tags == nil {
+		return false
+	}
+	for _, tag := range tags.Children {
+		if tag.Type == vdfString && tag.String == steamShortcutTag {
+			return true
+		}
+	}
+	return false
+}
+
+// steamEntry returns the shortcuts.vdf entry of a shortcut, with the fields Steam writes
+func steamEntry(s *Shortcut, emulator string) *vdfNode {
+	exe := `"` + emulator + `"`
+	str := func(key, value string) *vdfNode { return &vdfNode{Key: key, Type: vdfString, String: value} }
+	num := func(key string, value uint32) *vdfNode { return &vdfNode{Key: key, Type: vdfInt, Int: value} }
+	return &vdfNode{Type: vdfMap, Children: []*vdfNode{
+		// Steam derives the ID of a non-Steam game, used for its artwork, the same way
+		num("appid", crc32.ChecksumIEEE([]byte(exe+s.Title))|0x80000000),
+		str("AppName", s.Title),
+		str("Exe", exe),
+		str("StartDir", `"`+filepath.Dir(emulator)+`"`),
+		str("icon", s.Icon),
+		str("ShortcutPath", ""),
+		str("LaunchOptions", commandLine(s.Args)),
+		num("IsHidden", 0),
+		num("AllowDesktopConfig", 1),
+		num("AllowOverlay", 1),
+		num("OpenVR", 0),
+		num("Devkit", 0),
+		str("DevkitGameID", ""),
+		num("DevkitOverrideAppID", 0),
+		num("LastPlayTime", 0),
+		str("FlatpakAppID", ""),
+		{Key: "tags", Type: vdfMap, Children: []*vdfNode{str("0", "PlayStation 3"), str("1", steamShortcutTag)}},
+	}}
+}
+
+// parseVDF parses a binary VDF file into a root map
+func parseVDF(data []byte) (*vdfNode, error) {
+	root := &vdfNode{Type: vdfMap}
+	r := bufio.NewReader(bytes.NewReader(data))
+	if err := readVDFChildren(r, root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// readVDFChildren reads the values of a map up to its end marker (or the end of the file)
+func readVDFChildren(r *bufio.Reader, parent *vdfNode) error {
+	for {
+		kind, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if kind == vdfEnd {
+			return nil
+		}
+		key, err := readCString(r)
+		if err != nil {
+			return err
+		}
+		node := &vdfNode{Key: key, Type: kind}
+		switch kind {
+		case vdfMap:
+			if err := readVDFChildren(r, node); err != nil {
+				return err
+			}
+		case vdfString:
+			if node.String, err = readCString(r); err != nil {
+				return err
+			}
+		case vdfInt:
+			if err := binary.Read(r, binary.LittleEndian, &node.Int); err != nil {
+				return fmt.Errorf("reading %s: %w", key, err)
+			}
+		default:
+			return fmt.Errorf("unsupported value type 0x%02X for %s", kind, key)
+		}
+		parent.Children = append(parent.Children, node)
+	}
+}
+
+func readCString(r *bufio.Reader) (string, error) {
+	s, err := r.ReadString(0)
+	if err != nil {
+		return "", fmt.Errorf("truncated string: %w", err)
+	}
+	return s[:len(s)-1], nil
+}
+
+// writeVDFChildren writes the values of a map followed by its end marker
+func writeVDFChildren(b *bytes.Buffer, parent *vdfNode) {
+	for _, node := range parent.Children {
+		b.WriteByte(node.Type)
+		b.WriteString(node.Key)
+		b.WriteByte(0)
+		switch node.Type {
+		case vdfMap:
+			writeVDFChildren(b, node)
+		case vdfString:
+			b.WriteString(node.String)
+			b.WriteByte(0)
+		case vdfInt:
+			binary.Write(b, binary.LittleEndian, node.Int)
+		}
+	}
+	b.WriteByte(vdfEnd)
+}
@@ -0,0 +1,541 @@
+package export
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// Folders of a drive webMAN MOD and multiMAN load games from
+const (
+	GamesDir  = "GAMES"  // Game folders, each with PS3_GAME/ at its top
+	PS3ISODir = "PS3ISO" // Disc images
+)
+
+// Parts of files split for FAT32, which holds at most 4 GB per file: multiMAN writes
+// EBOOT.BIN.66600, EBOOT.BIN.66601, ... and webMAN disc images game.iso.0, game.iso.1, ...
+var (
+	splitPart    = regexp.MustCompile(`^(.+)\.666(\d\d)$`)
+	splitISOPart = regexp.MustCompile(`(?i)^(.+\.iso)\.(\d+)$`)
+)
+
+// DriveStatus is how a game on an exported drive compares with the library
+type DriveStatus string
+
+const (
+	DriveOK        DriveStatus = "ok"
+	DriveMissing   DriveStatus = "missing"   // Expected on the drive but not there
+	DriveExtra     DriveStatus = "extra"     // On the drive but not in the library
+	DriveCorrupted DriveStatus = "corrupted" // Files missing, of another size or with other contents
+)
+
+// DriveCheck is the result for one game or package of an exported drive
+type DriveCheck struct {
+	Status  DriveStatus
+	Kind    string // GAMES, PS3ISO, split or package
+	GameID  string
+	Title   string
+	Path    string // On the drive; for missing games, where it was expected ("" when anywhere)
+	Library string // The organized game or package it was compared with
+	Detail  string
+}
+
+// VerifyDriveOptions controls VerifyDrive
+type VerifyDriveOptions struct {
+	Layout common.Layout
+
+	// Hash also compares file contents: files of game/ folders are hashed on both sides,
+	// and those of game.7z are compared with the CRC32 in the archive. Empty compares
+	// names and sizes only.
+	Hash common.HashAlgorithm
+
+	// Expected are games that must be on the drive; other library games are only
+	// compared when they are found there
+	Expected []library.Game
+
+	// Progress is called before each game or package is compared
+	Progress func(path string)
+}
+
+// referenceFile is a file a copy on the drive must match
+type referenceFile struct {
+	size int64
+	path string // Local file, or "" for a file inside game.7z
+	crc  string // CRC32 from the 7z listing
+}
+
+// driveFile is a file on the drive, in one or more parts
+type driveFile struct {
+	size  int64
+	parts []string
+}
+
+// VerifyDrive compares an exported drive with the library games: game folders in GAMES/,
+// disc images in PS3ISO/, the numbered folders of export split (found by their manifest,
+// at the root or one level below) and the packages of export pkg-layout. Files split into
+// parts for FAT32 are joined. Nothing on the drive is written.
+func VerifyDrive(root string, games []library.Game, opts VerifyDriveOptions) ([]DriveCheck, error) {
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", root)
+	}
+
+	byID := map[string]library.Game{}
+	for _, game := range games {
+		if info := game.Info.GameInfo; info != nil {
+			if _, ok := byID[info.GameID]; !ok {
+				byID[info.GameID] = game
+			}
+		}
+	}
+	v := &driveVerifier{opts: opts, byID: byID, found: map[string]bool{}}
+
+	if err := v.gameFolders(filepath.Join(root, GamesDir)); err != nil {
+		return nil, err
+	}
+	if err := v.discImages(filepath.Join(root, PS3ISODir)); err != nil {
+		return nil, err
+	}
+	buckets, err := splitBuckets(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range buckets {
+		if err := v.splitBucket(bucket); err != nil {
+			return nil, err
+		}
+	}
+	if err := v.packages(filepath.Join(root, PackagesDir), games); err != nil {
+		return nil, err
+	}
+
+	for _, game := range opts.Expected {
+		info := game.Info.GameInfo
+		if info == nil || v.found[info.GameID] {
+			continue
+		}
+		v.found[info.GameID] = true
+		v.add(DriveCheck{Status: DriveMissing, GameID: info.GameID, Title: info.Title, Library: game.Path, Detail: "not on the drive"})
+	}
+	return v.checks, nil
+}
+
+type driveVerifier struct {
+	opts   VerifyDriveOptions
+	byID   map[string]library.Game
+	found  map[string]bool
+	checks []DriveCheck
+}
+
+func (v *driveVerifier) add(check DriveCheck) {
+	v.checks = append(v.checks, check)
+}
+
+func (v *driveVerifier) progress(path string) {
+	if v.opts.Progress != nil {
+		v.opts.Progress(path)
+	}
+}
+
+// gameFolders compares the game folders in GAMES/ with the game/ folder or game.7z of
+// the library game of the same title ID
+func (v *driveVerifier) gameFolders(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		v.progress(path)
+		check := DriveCheck{Kind: GamesDir, Path: path}
+		data, err := parsers.ReadParamSFOFile(filepath.Join(path, "PS3_GAME", "PARAM.SFO"))
+		var sfo *parsers.ParamSFO
+		if err == nil {
+			sfo, err = parsers.ParseParamSFO(data)
+		}
+		if err != nil {
+			check.Status, check.Detail = DriveCorrupted, "no readable PS3_GAME/PARAM.SFO"
+			v.add(check)
+			continue
+		}
+		check.GameID, check.Title = sfo.GetTitleID(), sfo.GetTitle()
+
+		game, ok := v.byID[check.GameID]
+		if !ok {
+			check.Status, check.Detail = DriveExtra, "not in the library"
+			v.add(check)
+			continue
+		}
+		v.found[check.GameID] = true
+		check.Library = game.Path
+		want, err := gameFiles(game)
+		if err != nil {
+			return err
+		}
+		v.compare(check, want, path)
+	}
+	return nil
+}
+
+// discImages checks that the disc images in PS3ISO/ are of games in the library. Their
+// contents can't be compared with an organized game, so only the title ID is checked.
+func (v *driveVerifier) discImages(dir string) error {
+	files, err := driveFiles(dir, false)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedNames(files) {
+		if !strings.EqualFold(filepath.Ext(name), ".iso") {
+			continue
+		}
+		file := files[name]
+		path := filepath.Join(dir, name)
+		v.progress(path)
+		check := DriveCheck{Kind: PS3ISODir, Path: path}
+		iso, err := consoles.OpenPS3ISO(file.parts[0])
+		if err != nil {
+			check.Status, check.Detail = DriveCorrupted, err.Error()
+			v.add(check)
+			continue
+		}
+		check.GameID = iso.TitleID
+		game, ok := v.byID[iso.TitleID]
+		if !ok {
+			check.Status, check.Detail = DriveExtra, "not in the library"
+			v.add(check)
+			continue
+		}
+		v.found[iso.TitleID] = true
+		check.Status, check.Library = DriveOK, game.Path
+		check.Title = game.Info.GameInfo.Title
+		check.Detail = fmt.Sprintf("disc image of %s (contents not compared)", common.FormatSize(file.size))
+		v.add(check)
+	}
+	return nil
+}
+
+// splitBucket compares the game folders listed in the manifest of a split folder with
+// the whole organized folders in the library
+func (v *driveVerifier) splitBucket(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, SplitManifestName))
+	if err != nil {
+		return err
+	}
+	var manifest splitManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("reading %s: %w", filepath.Join(dir, SplitManifestName), err)
+	}
+	for _, item := range manifest.Games {
+		path := filepath.Join(dir, item.Dir)
+		v.progress(path)
+		check := DriveCheck{Kind: "split", GameID: item.GameID, Title: item.Title, Path: path}
+		v.found[item.GameID] = true
+		if _, err := os.Stat(path); err != nil {
+			check.Status, check.Detail = DriveMissing, "listed in "+SplitManifestName+" but not in the folder"
+			v.add(check)
+			continue
+		}
+		game, ok := v.byID[item.GameID]
+		if !ok {
+			check.Status, check.Detail = DriveExtra, "not in the library"
+			v.add(check)
+			continue
+		}
+		check.Library = game.Path
+		want, err := folderFiles(game.Path)
+		if err != nil {
+			return err
+		}
+		v.compare(check, want, path)
+	}
+	return nil
+}
+
+// packages compares the packages in packages/ with those of the library's games by name
+func (v *driveVerifier) packages(dir string, games []library.Game) error {
+	files, err := driveFiles(dir, false)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	layout := v.opts.Layout.WithDefaults()
+	type libraryPackage struct {
+		path string
+		game library.Game
+	}
+	known := map[string]libraryPackage{}
+	for _, game := range games {
+		for _, sub := range []string{layout.UpdatesDir, layout.DLCDir} {
+			matches, _ := filepath.Glob(filepath.Join(game.Path, sub, "*"))
+			for _, match := range matches {
+				if strings.EqualFold(filepath.Ext(match), ".pkg") {
+					known[strings.ToLower(filepath.Base(match))] = libraryPackage{match, game}
+				}
+			}
+		}
+	}
+
+	for _, name := range sortedNames(files) {
+		if !strings.EqualFold(filepath.Ext(name), ".pkg") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		v.progress(path)
+		check := DriveCheck{Kind: "package", Path: path}
+		pkg, ok := known[strings.ToLower(name)]
+		if !ok {
+			check.Status, check.Detail = DriveExtra, "not in the library"
+			v.add(check)
+			continue
+		}
+		if info := pkg.game.Info.GameInfo; info != nil {
+			check.GameID, check.Title = info.GameID, info.Title
+		}
+		check.Library = pkg.path
+		stat, err := os.Stat(pkg.path)
+		if err != nil {
+			return err
+		}
+		v.compare(check, map[string]referenceFile{name: {size: stat.Size(), path: pkg.path}}, dir, name)
+	}
+	return nil
+}
+
+// compare checks the files of a copy on the drive against the reference files and adds
+// the result. only limits the drive files looked at to these names.
+func (v *driveVerifier) compare(check DriveCheck, want map[string]referenceFile, dir string, only ...string) {
+	have, err := driveFiles(dir, true)
+	if err != nil {
+		check.Status, check.Detail = DriveCorrupted, err.Error()
+		v.add(check)
+		return
+	}
+	if len(only) > 0 {
+		kept := map[string]driveFile{}
+		for _, name := range only {
+			if file, ok := have[name]; ok {
+				kept[name] = file
+			}
+		}
+		have = kept
+	}
+
+	var missing, resized, changed []string
+	for _, name := range sortedNames(want) {
+		ref := want[name]
+		file, ok := have[name]
+		switch {
+		case !ok:
+			missing = append(missing, name)
+		case file.size != ref.size:
+			resized = append(resized, name)
+		case v.opts.Hash != "":
+			same, err := sameContents(file, ref, v.opts.Hash)
+			if err != nil {
+				check.Status, check.Detail = DriveCorrupted, err.Error()
+				v.add(check)
+				return
+			}
+			if !same {
+				changed = append(changed, name)
+			}
+		}
+	}
+
+	var problems []string
+	for _, group := range []struct {
+		what  string
+		names []string
+	}{{"missing", missing}, {"of another size", resized}, {"with other contents", changed}} {
+		if len(group.names) > 0 {
+			problems = append(problems, fmt.Sprintf("%d %s (%s)", len(group.names), group.what, listNames(group.names)))
+		}
+	}
+	if len(problems) > 0 {
+		check.Status, check.Detail = DriveCorrupted, strings.Join(problems, ", ")
+	} else {
+		check.Status = DriveOK
+		check.Detail = fmt.Sprintf("%d files match", len(want))
+		if v.opts.Hash != "" {
+			check.Detail += " by " + string(v.opts.Hash)
+		}
+	}
+	v.add(check)
+}
+
+// listNames returns the first few names of a list for a report
+func listNames(names []string) string {
+	const shown = 3
+	if len(names) <= shown {
+		return strings.Join(names, ", ")
+	}
+	return strings.Join(names[:shown], ", ") + fmt.Sprintf(", and %d more", len(names)-shown)
+}
+
+// sameContents compares a file on the drive with its reference: by hash with a local
+// file, or by CRC32 with a file inside game.7z
+func sameContents(file driveFile, ref referenceFile, alg common.HashAlgorithm) (bool, error) {
+	if ref.path == "" {
+		if ref.crc == "" {
+			return true, nil
+		}
+		sum, err := hashParts(file.parts, common.HashCRC32)
+		return strings.EqualFold(sum, ref.crc), err
+	}
+	want, err := common.HashFile(ref.path, alg)
+	if err != nil {
+		return false, err
+	}
+	sum, err := hashParts(file.parts, alg)
+	return sum == want, err
+}
+
+// hashParts hashes the parts of a file as one
+func hashParts(parts []string, alg common.HashAlgorithm) (string, error) {
+	h := alg.New()
+	for _, part := range parts {
+		f, err := os.Open(part)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("hashing %s: %w", part, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// gameFiles lists the files of an organized game's game/ folder, or of its game.7z
+func gameFiles(game library.Game) (map[string]referenceFile, error) {
+	if game.Info.HasDecompressed {
+		return folderFiles(filepath.Join(game.Path, "game"))
+	}
+	entries, err := common.List7zArchive(filepath.Join(game.Path, "game.7z"))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]referenceFile{}
+	for _, entry := range entries {
+		if !entry.IsDir {
+			files[filepath.ToSlash(entry.Path)] = referenceFile{size: entry.Size, crc: entry.CRC}
+		}
+	}
+	return files, nil
+}
+
+// folderFiles lists the files below a folder by slash-separated relative path
+func folderFiles(dir string) (map[string]referenceFile, error) {
+	files := map[string]referenceFile{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = referenceFile{size: info.Size(), path: path}
+		return nil
+	})
+	return files, err
+}
+
+// driveFiles lists the files below a folder on the drive by slash-separated relative
+// path, or only those directly in it, joining the parts of split files
+func driveFiles(dir string, recursive bool) (map[string]driveFile, error) {
+	files := map[string]driveFile{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && !recursive {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		name, _ := splitPartName(filepath.ToSlash(rel))
+		file := files[name]
+		file.size += info.Size()
+		file.parts = append(file.parts, path)
+		files[name] = file
+		return nil
+	})
+	for _, file := range files {
+		// Walk visits game.iso.10 before game.iso.2
+		sort.SliceStable(file.parts, func(i, j int) bool {
+			_, a := splitPartName(file.parts[i])
+			_, b := splitPartName(file.parts[j])
+			return a < b
+		})
+	}
+	return files, err
+}
+
+// splitPartName returns the name of the whole file a part belongs to and the part's
+// number, or the name itself and 0 for a file that isn't split
+func splitPartName(name string) (string, int) {
+	m := splitPart.FindStringSubmatch(name)
+	if m == nil {
+		m = splitISOPart.FindStringSubmatch(name)
+	}
+	if m == nil {
+		return name, 0
+	}
+	n, _ := strconv.Atoi(m[2])
+	return m[1], n
+}
+
+// splitBuckets returns the folders of export split on the drive: the root itself or
+// its subfolders holding a manifest
+func splitBuckets(root string) ([]string, error) {
+	if fileExists(filepath.Join(root, SplitManifestName)) {
+		return []string{root}, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var buckets []string
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		if entry.IsDir() && fileExists(filepath.Join(dir, SplitManifestName)) {
+			buckets = append(buckets, dir)
+		}
+	}
+	return buckets, nil
+}
+
+// sortedNames returns the keys of a file map in order
+func sortedNames[T any](files map[string]T) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,208 @@
+// Package ftp is a minimal passive-mode FTP client, enough to copy files to and
+// from a PS3 running an FTP server such as webMAN MOD's
+package ftp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a directory entry returned by List
+type Entry struct {
+	Name  string
+	IsDir bool
+	Size  int64
+}
+
+// Client is a connection to an FTP server
+type Client struct {
+	conn    *textproto.Conn
+	host    string
+	timeout time.Duration
+}
+
+// Dial connects to an FTP server at addr (host or host:port) and logs in.
+// An empty user logs in anonymously.
+func Dial(addr, user, password string, timeout time.Duration) (*Client, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "21")
+	}
+	host, _, _ := net.SplitHostPort(addr)
+
+	netConn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to FTP server %s: %w", addr, err)
+	}
+
+	c := &Client{conn: textproto.NewConn(netConn), host: host, timeout: timeout}
+	if _, _, err := c.conn.ReadResponse(220); err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("FTP server %s: %w", addr, err)
+	}
+
+	if user == "" {
+		user, password = "anonymous", "anonymous"
+	}
+	code, _, err := c.cmd(0, "USER %s", user)
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	if code == 331 {
+		if _, _, err := c.cmd(230, "PASS %s", password); err != nil {
+			c.conn.Close()
+			return nil, fmt.Errorf("FTP login failed: %w", err)
+		}
+	} else if code != 230 {
+		c.conn.Close()
+		return nil, fmt.Errorf("FTP login failed: unexpected reply %d", code)
+	}
+
+	if _, _, err := c.cmd(200, "TYPE I"); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close logs out and closes the connection
+func (c *Client) Close() error {
+	c.cmd(0, "QUIT")
+	return c.conn.Close()
+}
+
+// cmd sends a command and reads the reply, checking its code unless expect is 0
+func (c *Client) cmd(expect int, format string, args ...interface{}) (int, string, error) {
+	if err := c.conn.PrintfLine(format, args...); err != nil {
+		return 0, "", fmt.Errorf("FTP: %w", err)
+	}
+	code, msg, err := c.conn.ReadResponse(expect)
+	if err != nil {
+		return code, msg, fmt.Errorf("FTP %s: %w", strings.Fields(format)[0], err)
+	}
+	return code, msg, nil
+}
+
+// pasv opens a passive-mode data connection
+func (c *Client) pasv() (net.Conn, error) {
+	_, msg, err := c.cmd(227, "PASV")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := strings.Index(msg, "("), strings.Index(msg, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("FTP PASV: unexpected reply %q", msg)
+	}
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("FTP PASV: unexpected reply %q", msg)
+	}
+	p1, err1 := strconv.Atoi(parts[4])
+	p2, err2 := strconv.Atoi(parts[5])
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("FTP PASV: unexpected reply %q", msg)
+	}
+
+	// Use the control connection's host; PS3 servers may advertise an unroutable address
+	addr := net.JoinHostPort(c.host, strconv.Itoa(p1*256+p2))
+	conn, err := net.DialTimeout("tcp", addr, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("FTP data connection: %w", err)
+	}
+	return conn, nil
+}
+
+// transfer runs a data command, passing the data connection to fn
+func (c *Client) transfer(fn func(net.Conn) error, format string, args ...interface{}) error {
+	data, err := c.pasv()
+	if err != nil {
+		return err
+	}
+
+	if err := c.conn.PrintfLine(format, args...); err != nil {
+		data.Close()
+		return fmt.Errorf("FTP: %w", err)
+	}
+	if _, _, err := c.conn.ReadResponse(1); err != nil {
+		data.Close()
+		return fmt.Errorf("FTP %s: %w", strings.Fields(format)[0], err)
+	}
+
+	fnErr := fn(data)
+	data.Close()
+	if _, _, err := c.conn.ReadResponse(2); err != nil {
+		return fmt.Errorf("FTP %s: %w", strings.Fields(format)[0], err)
+	}
+	return fnErr
+}
+
+// List returns the entries of a directory, parsed from a Unix-style LIST reply
+func (c *Client) List(dir string) ([]Entry, error) {
+	var entries []Entry
+	err := c.transfer(func(conn net.Conn) error {
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if entry, ok := parseListLine(scanner.Text()); ok {
+				entries = append(entries, entry)
+			}
+		}
+		return scanner.Err()
+	}, "LIST %s", dir)
+	return entries, err
+}
+
+// parseListLine parses a line such as
+// "drwxr-xr-x 1 root root 512 Jan 01 2020 BLUS30001-SAVE00"
+func parseListLine(line string) (Entry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 9 {
+		return Entry{}, false
+	}
+	// The name is everything after the eighth field and may contain spaces
+	name := line
+	for i := 0; i < 8; i++ {
+		name = strings.TrimLeft(name, " ")
+		name = name[strings.IndexByte(name, ' '):]
+	}
+	name = strings.TrimLeft(name, " ")
+	if name == "." || name == ".." {
+		return Entry{}, false
+	}
+	size, _ := strconv.ParseInt(fields[4], 10, 64)
+	return Entry{Name: name, IsDir: strings.HasPrefix(fields[0], "d"), Size: size}, true
+}
+
+// Retrieve downloads a file into w
+func (c *Client) Retrieve(file string, w io.Writer) error {
+	return c.transfer(func(conn net.Conn) error {
+		_, err := io.Copy(w, conn)
+		return err
+	}, "RETR %s", file)
+}
+
+// Store uploads r to a file
+func (c *Client) Store(file string, r io.Reader) error {
+	return c.transfer(func(conn net.Conn) error {
+		_, err := io.Copy(conn, r)
+		return err
+	}, "STOR %s", file)
+}
+
+// MakeDir creates a directory, ignoring the error if it already exists
+func (c *Client) MakeDir(dir string) error {
+	code, _, err := c.cmd(0, "MKD %s", dir)
+	if err != nil {
+		return err
+	}
+	if code != 257 && code != 550 {
+		return fmt.Errorf("FTP MKD %s: unexpected reply %d", dir, code)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package ignore reads .romignore files: gitignore-style patterns naming the parts of a
+// library or intake folder that detection, scans and recursive commands leave alone
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the ignore file read from the root of a library or intake folder
+const FileName = ".romignore"
+
+// Rules are the patterns of one .romignore, matched against paths below its folder
+type Rules struct {
+	root     string
+	patterns []pattern
+}
+
+// pattern is one line of a .romignore
+type pattern struct {
+	text    string
+	negate  bool // !pattern: re-includes what an earlier pattern ignored
+	dirOnly bool // pattern/: only matches folders
+	re      *regexp.Regexp
+}
+
+// Load reads the .romignore in root. It returns nil rules, which ignore nothing, when
+// root has none or isn't a folder.
+func Load(root string) (*Rules, error) {
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	path := filepath.Join(root, FileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	rules, err := Parse(root, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Parse reads .romignore patterns for paths below root. The syntax is gitignore's: one
+// pattern per line, # comments, ! to re-include, a trailing / for folders only, and *,
+// ?, [...] and ** wildcards. A pattern without a slash (other than a trailing one)
+// matches a name at any depth; one with a slash matches from root.
+func Parse(root string, data []byte) (*Rules, error) {
+	rules := &Rules{root: root}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if !strings.HasSuffix(text, `\ `) {
+			text = strings.TrimRight(text, " \t")
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p, err := parsePattern(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules, scanner.Err()
+}
+
+func parsePattern(text string) (pattern, error) {
+	p := pattern{text: text}
+	switch {
+	case strings.HasPrefix(text, "!"):
+		p.negate = true
+		text = text[1:]
+	case strings.HasPrefix(text, `\!`), strings.HasPrefix(text, `\#`):
+		text = text[1:]
+	}
+	if strings.HasSuffix(text, "/") {
+		p.dirOnly = true
+		text = strings.TrimRight(text, "/")
+	}
+	if text == "" {
+		return p, fmt.Errorf("empty pattern %q", p.text)
+	}
+
+	// Without a slash the pattern matches a name at any depth
+	prefix := "(?:.*/)?"
+	if strings.Contains(text, "/") {
+		prefix = ""
+		text = strings.TrimPrefix(text, "/")
+	}
+	expr, err := translate(text)
+	if err != nil {
+		return p, fmt.Errorf("pattern %q: %w", p.text, err)
+	}
+	p.re, err = regexp.Compile("^" + prefix + expr + "$")
+	if err != nil {
+		return p, fmt.Errorf("pattern %q: %w", p.text, err)
+	}
+	return p, nil
+}
+
+// translate turns a glob into a regular expression over slash-separated paths
+func translate(glob string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if !strings.HasPrefix(glob[i:], "**") {
+				b.WriteString("[^/]*")
+				continue
+			}
+			atStart := i == 0 || glob[i-1] == '/'
+			switch rest := glob[i+2:]; {
+			case atStart && rest == "":
+				b.WriteString(".*") // trailing /**: everything inside
+			case atStart && strings.HasPrefix(rest, "/"):
+				b.WriteString("(?:.*/)?") // **/: any number of folders
+				i++
+			default:
+				b.WriteString("[^/]*") // ** inside a name is a plain *
+			}
+			i++
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated [")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+// Ignored reports whether a path below the rules' folder is ignored: by the last
+// pattern matching it, or because a folder it is in is ignored. Nil rules ignore nothing.
+func (r *Rules) Ignored(path string, isDir bool) bool {
+	if r == nil || len(r.patterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(r.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i < len(parts); i++ {
+		if r.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.match(strings.Join(parts, "/"), isDir)
+}
+
+// match applies the patterns to one relative path; the last one matching decides
+func (r *Rules) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range r.patterns {
+		if (p.dirOnly && !isDir) || !p.re.MatchString(rel) {
+			continue
+		}
+		ignored = !p.negate
+	}
+	return ignored
+}
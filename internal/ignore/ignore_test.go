@@ -0,0 +1,123 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	root := filepath.Join("library", "root")
+	tests := []struct {
+		patterns string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		// A name matches at any depth, a pattern with a slash from the root
+		{"*.txt", "notes.txt", false, true},
+		{"*.txt", "Game [BLUS30001]/notes.txt", false, true},
+		{"*.txt", "notes.txt.bak", false, false},
+		{"/notes.txt", "notes.txt", false, true},
+		{"/notes.txt", "sub/notes.txt", false, false},
+		{"sub/notes.txt", "sub/notes.txt", false, true},
+		{"sub/notes.txt", "other/sub/notes.txt", false, false},
+
+		// Folder-only patterns
+		{"backup/", "backup", true, true},
+		{"backup/", "backup", false, false},
+		{"backup/", "games/backup/save.dat", false, true},
+
+		// Everything in an ignored folder is ignored
+		{"incoming", "incoming/Game/PS3_GAME/PARAM.SFO", false, true},
+		{"/incoming", "games/incoming/game.iso", false, false},
+
+		// The last matching pattern decides, and ! re-includes
+		{"*.iso\n!keep.iso", "keep.iso", false, false},
+		{"*.iso\n!keep.iso", "other.iso", false, true},
+		{"!keep.iso\n*.iso", "keep.iso", false, true},
+		// A file can't be re-included when its folder is ignored
+		{"dumps/\n!dumps/keep.iso", "dumps/keep.iso", false, true},
+		{"dumps/*\n!dumps/keep.iso", "dumps/keep.iso", false, false},
+
+		// Wildcards
+		{"disc?.iso", "disc1.iso", false, true},
+		{"disc?.iso", "disc10.iso", false, false},
+		{"disc[12].iso", "disc2.iso", false, true},
+		{"disc[!12].iso", "disc2.iso", false, false},
+		{"disc[!12].iso", "disc3.iso", false, true},
+		{"*", "a/b", false, true},
+		{"a/*", "a/b/c", false, true},
+		{"a/*.iso", "a/b/c.iso", false, false},
+		{"**/cache", "cache", true, true},
+		{"**/cache", "x/y/cache", true, true},
+		{"logs/**", "logs/a/b.txt", false, true},
+		{"logs/**", "logs", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+
+		// Comments, blank lines and escapes
+		{"# *.iso\n\n", "game.iso", false, false},
+		{`\#notes`, "#notes", false, true},
+		{`\!important`, "!important", false, true},
+		{`\*.iso`, "game.iso", false, false},
+		{`\*.iso`, "*.iso", false, true},
+		{"game.iso   ", "game.iso", false, true},
+		{"*.iso\r\n", "game.iso", false, true},
+	}
+	for _, tt := range tests {
+		rules, err := Parse(root, []byte(tt.patterns))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.patterns, err)
+		}
+		path := filepath.Join(root, filepath.FromSlash(tt.path))
+		if got := rules.Ignored(path, tt.isDir); got != tt.want {
+			t.Errorf("%q: Ignored(%s, dir=%v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoredOutsideRoot(t *testing.T) {
+	root := filepath.Join("library", "root")
+	rules, err := Parse(root, []byte("*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{root, filepath.Join("library", "other.iso"), filepath.Join("library", "rootless", "game.iso")} {
+		if rules.Ignored(path, false) {
+			t.Errorf("%s is ignored, want only paths below %s", path, root)
+		}
+	}
+
+	var none *Rules
+	if none.Ignored(filepath.Join(root, "game.iso"), false) {
+		t.Error("nil rules ignored a path")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, patterns := range []string{"!", "/", "game[1.iso"} {
+		_, err := Parse("root", []byte("*.txt\n"+patterns))
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%q: error %v, want one on line 2", patterns, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if rules, err := Load(dir); err != nil || rules != nil {
+		t.Fatalf("Load without %s = %v, %v; want no rules", FileName, rules, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("*.nfo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rules.Ignored(filepath.Join(dir, "Game", "release.nfo"), false) {
+		t.Error("pattern from the ignore file not applied")
+	}
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+)
+
+// Plan is a batch of compress, decompress and organize steps declared in a YAML (or JSON)
+// file, so a migration can be reviewed and versioned before it runs. Defaults apply to
+// every step; a step's own settings replace them, and its flags are added to theirs.
+type Plan struct {
+	Defaults PlanStep   `yaml:"defaults"`
+	Steps    []PlanStep `yaml:"steps"`
+	dir      string     // Folder of the plan file, which relative paths are resolved against
+}
+
+// PlanStep is one set of sources organized the same way
+type PlanStep struct {
+	Name       string   `yaml:"name"`
+	Sources    []string `yaml:"sources"`     // Paths and glob patterns
+	Console    string   `yaml:"console"`     // Console the sources must be detected as (e.g. ps3)
+	Format     string   `yaml:"format"`      // compressed, decompressed or keep (the default)
+	Output     string   `yaml:"output"`      // Output directory or sftp:// location
+	Mirrors    []string `yaml:"mirrors"`     // Other output directories receiving a copy
+	OrganizeBy string   `yaml:"organize_by"` // none or first-letter
+	Move       *bool    `yaml:"move"`
+	Flags      []string `yaml:"flags"` // Other flags of the command, e.g. --checksum=sha256
+}
+
+// PlannedJob is a plan step resolved into the command that runs it
+type PlannedJob struct {
+	Step    int    // 1-based position in the plan
+	Name    string // The step's name, or "step N"
+	Console detect.ConsoleType
+	Command string   // compress, decompress or organize
+	Args    []string // Flags, then sources
+	Sources []string
+}
+
+// formatCommands maps a step's format to the command that produces it
+var formatCommands = map[string]string{
+	"compressed":   "compress",
+	"decompressed": "decompress",
+	"keep":         "organize",
+}
+
+// LoadPlan reads a plan file. Unknown keys are errors, so a misspelled setting doesn't
+// silently change what a migration does.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	plan := &Plan{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(plan); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing plan %s: %w", path, err)
+	}
+	if len(plan.Steps) == 0 {
+		return nil, fmt.Errorf("plan %s has no steps", path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	plan.dir = filepath.Dir(abs)
+	return plan, nil
+}
+
+// Jobs resolves every step into the command that runs it: defaults applied, paths made
+// absolute against the plan's folder and glob patterns expanded. A pattern matching
+// nothing, a missing source or an unknown setting fails the whole plan, before any step
+// runs.
+func (p *Plan) Jobs() ([]*PlannedJob, error) {
+	var jobs []*PlannedJob
+	for i, step := range p.Steps {
+		job, err := p.resolve(i+1, p.merge(step))
+		if err != nil {
+			name := step.Name
+			if name == "" {
+				name = fmt.Sprintf("step %d", i+1)
+			}
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// merge fills in the settings a step leaves out from the defaults
+func (p *Plan) merge(step PlanStep) PlanStep {
+	d := p.Defaults
+	if step.Console == "" {
+		step.Console = d.Console
+	}
+	if step.Format == "" {
+		step.Format = d.Format
+	}
+	if step.Output == "" {
+		step.Output = d.Output
+	}
+	if step.Mirrors == nil {
+		step.Mirrors = d.Mirrors
+	}
+	if step.OrganizeBy == "" {
+		step.OrganizeBy = d.OrganizeBy
+	}
+	if step.Move == nil {
+		step.Move = d.Move
+	}
+	step.Flags = append(append([]string{}, d.Flags...), step.Flags...)
+	if len(step.Sources) == 0 {
+		step.Sources = d.Sources
+	}
+	return step
+}
+
+func (p *Plan) resolve(n int, step PlanStep) (*PlannedJob, error) {
+	job := &PlannedJob{Step: n, Name: step.Name}
+	if job.Name == "" {
+		job.Name = fmt.Sprintf("step %d", n)
+	}
+
+	format := step.Format
+	if format == "" {
+		format = "keep"
+	}
+	command, ok := formatCommands[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (expected compressed, decompressed or keep)", step.Format)
+	}
+	job.Command = command
+	if step.Console != "" {
+		console, err := detect.ParseConsoleType(step.Console)
+		if err != nil {
+			return nil, err
+		}
+		job.Console = console
+	}
+	if step.Output == "" {
+		return nil, fmt.Errorf("no output (set output in the step or in defaults)")
+	}
+
+	for _, flag := range step.Flags {
+		if !strings.HasPrefix(flag, "-") {
+			return nil, fmt.Errorf("flag %q doesn't start with -; list sources under sources", flag)
+		}
+	}
+	job.Args = append(job.Args, "--output="+p.path(step.Output))
+	for _, mirror := range step.Mirrors {
+		job.Args = append(job.Args, "--output="+p.path(mirror))
+	}
+	if step.OrganizeBy != "" {
+		job.Args = append(job.Args, "--organize-by="+step.OrganizeBy)
+	}
+	if step.Move != nil && *step.Move {
+		job.Args = append(job.Args, "--move")
+	}
+	job.Args = append(job.Args, step.Flags...)
+
+	if len(step.Sources) == 0 {
+		return nil, fmt.Errorf("no sources")
+	}
+	for _, source := range step.Sources {
+		source = p.path(source)
+		if sftp.IsURL(source) {
+			job.Sources = append(job.Sources, source)
+			continue
+		}
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", source, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("source %s doesn't exist", source)
+		}
+		job.Sources = append(job.Sources, matches...)
+	}
+	// Sources that look like flags can't be taken for them
+	job.Args = append(job.Args, "--")
+	job.Args = append(job.Args, job.Sources...)
+	return job, nil
+}
+
+// path resolves a path of the plan against the plan's folder, expanding a leading ~
+func (p *Plan) path(path string) string {
+	if sftp.IsURL(path) {
+		return path
+	}
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + rest
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.dir, path)
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,293 @@
+// Package jobs implements a persistent queue of organize jobs stored in a JSON file
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// LockTimeout is how long reading or changing the queue waits for another process
+// (a worker, or another jobs command) to finish with it
+var LockTimeout = 30 * time.Second
+
+// State is the lifecycle state of a job
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Job is one queued rom-organizer invocation (e.g. "organize --output /nas /downloads/game")
+type Job struct {
+	ID         int       `json:"id"`
+	Command    string    `json:"command"` // compress, decompress or organize
+	Args       []string  `json:"args"`    // Flags and sources passed to the command
+	State      State     `json:"state"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// Commands that can be queued
+var queueableCommands = map[string]bool{
+	"compress":   true,
+	"decompress": true,
+	"organize":   true,
+}
+
+// Queue is the set of jobs stored in a queue file
+type Queue struct {
+	path   string
+	NextID int    `json:"next_id"`
+	Jobs   []*Job `json:"jobs"`
+}
+
+// DefaultPath returns the default queue file location next to the config file
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "jobs.json"
+	}
+	return filepath.Join(dir, "rom-organizer", "jobs.json")
+}
+
+// Open loads the queue file at path for reading, as an empty queue if it does not exist.
+// Jobs left running by a worker that exited are shown as pending, since they run again.
+func Open(path string) (*Queue, error) {
+	release, err := common.LockFile(path+".lock", false, LockTimeout)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Nothing to lock against before the queue's folder exists
+		release, err = func() {}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("locking job queue: %w", err)
+	}
+	defer release()
+	return load(path)
+}
+
+// Update loads the queue at path, applies change and saves it, holding the queue's lock
+// throughout so changes made by other processes at the same time aren't lost. Nothing is
+// saved if change fails. Jobs left running by a worker that exited return to pending.
+func Update(path string, change func(*Queue) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating job queue directory: %w", err)
+	}
+	release, err := common.LockFile(path+".lock", true, LockTimeout)
+	if err != nil {
+		return fmt.Errorf("locking job queue: %w", err)
+	}
+	defer release()
+
+	q, err := load(path)
+	if err != nil {
+		return err
+	}
+	if err := change(q); err != nil {
+		return err
+	}
+	return q.save()
+}
+
+// load reads the queue file; the caller holds the queue's lock
+func load(path string) (*Queue, error) {
+	q := &Queue{path: path, NextID: 1}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return q, nil
+		}
+		return nil, fmt.Errorf("reading job queue: %w", err)
+	}
+
+	if err := json.Unmarshal(data, q); err != nil {
+		return nil, fmt.Errorf("parsing job queue %s: %w", path, err)
+	}
+
+	for _, job := range q.Jobs {
+		if job.State == StateRunning && q.orphaned(job) {
+			job.State = StatePending
+		}
+	}
+	return q, nil
+}
+
+// runLockPath is the file a worker keeps locked while it runs a job, so other processes
+// can tell a running job from one whose worker exited
+func (q *Queue) runLockPath(id int) string {
+	return q.path + ".job-" + strconv.Itoa(id) + ".lock"
+}
+
+// ProgressPath is the file a running job's progress events are appended to, as lines of
+// --progress-format ndjson output, so other processes can follow the job. It's kept after
+// the job finishes, until the job runs again or is pruned.
+func (q *Queue) ProgressPath(id int) string {
+	return q.path + ".job-" + strconv.Itoa(id) + ".progress"
+}
+
+// orphaned reports whether no worker holds the lock of a running job. A lock that can't
+// be checked counts as held, so a job is never run twice; where the platform has no
+// advisory locks a running job stays running until it's recorded as finished.
+func (q *Queue) orphaned(job *Job) bool {
+	if !common.LocksHeld {
+		return false
+	}
+	path := q.runLockPath(job.ID)
+	release, err := common.LockFile(path, true, 0)
+	if err != nil {
+		return false
+	}
+	release()
+	os.Remove(path)
+	return true
+}
+
+// save writes the queue atomically (write to a temporary file, then rename); the caller
+// holds the queue's lock
+func (q *Queue) save() error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding job queue: %w", err)
+	}
+
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing job queue: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("writing job queue: %w", err)
+	}
+	return nil
+}
+
+// Add queues a new pending job
+func (q *Queue) Add(command string, args []string) (*Job, error) {
+	if !queueableCommands[command] {
+		return nil, fmt.Errorf("cannot queue %q (expected compress, decompress or organize)", command)
+	}
+
+	job := &Job{
+		ID:        q.NextID,
+		Command:   command,
+		Args:      args,
+		State:     StatePending,
+		CreatedAt: time.Now(),
+	}
+	q.NextID++
+	q.Jobs = append(q.Jobs, job)
+	return job, nil
+}
+
+// Get returns the job with the given ID
+func (q *Queue) Get(id int) (*Job, error) {
+	for _, job := range q.Jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("job %d not found", id)
+}
+
+// Next returns the oldest pending job, or nil if none is pending
+func (q *Queue) Next() *Job {
+	for _, job := range q.Jobs {
+		if job.State == StatePending {
+			return job
+		}
+	}
+	return nil
+}
+
+// Start marks a job as running and locks it for this process. The returned function
+// releases the lock, once however often it's called; call it once the job's outcome is
+// recorded, in the same Update.
+func (q *Queue) Start(job *Job) (func(), error) {
+	path := q.runLockPath(job.ID)
+	release, err := common.LockFile(path, true, 0)
+	if err != nil {
+		return nil, fmt.Errorf("locking job %d: %w", job.ID, err)
+	}
+	// Progress is recorded afresh for each attempt
+	os.Remove(q.ProgressPath(job.ID))
+	job.State = StateRunning
+	job.Attempts++
+	job.Error = ""
+	job.StartedAt = time.Now()
+	job.FinishedAt = time.Time{}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			release()
+			os.Remove(path)
+		})
+	}, nil
+}
+
+// Finish records the outcome of a running job
+func (q *Queue) Finish(job *Job, err error) {
+	job.FinishedAt = time.Now()
+	if err != nil {
+		job.State = StateFailed
+		job.Error = err.Error()
+		return
+	}
+	job.State = StateCompleted
+}
+
+// Retry returns a failed or cancelled job to the pending state
+func (q *Queue) Retry(id int) (*Job, error) {
+	job, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != StateFailed && job.State != StateCancelled {
+		return nil, fmt.Errorf("job %d is %s; only failed or cancelled jobs can be retried", id, job.State)
+	}
+	job.State = StatePending
+	return job, nil
+}
+
+// Cancel stops a pending job from running
+func (q *Queue) Cancel(id int) (*Job, error) {
+	job, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != StatePending {
+		return nil, fmt.Errorf("job %d is %s; only pending jobs can be cancelled", id, job.State)
+	}
+	job.State = StateCancelled
+	job.FinishedAt = time.Now()
+	return job, nil
+}
+
+// Prune removes completed and cancelled jobs and returns how many were removed
+func (q *Queue) Prune() int {
+	kept := q.Jobs[:0]
+	for _, job := range q.Jobs {
+		if job.State != StateCompleted && job.State != StateCancelled {
+			kept = append(kept, job)
+			continue
+		}
+		os.Remove(q.ProgressPath(job.ID))
+	}
+	removed := len(q.Jobs) - len(kept)
+	q.Jobs = kept
+	return removed
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// addJob queues a job in the queue at path and returns its ID
+func addJob(t *testing.T, path string) int {
+	t.Helper()
+	var job *Job
+	err := Update(path, func(q *Queue) (err error) {
+		job, err = q.Add("organize", []string{"/downloads/game"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job.ID
+}
+
+func jobState(t *testing.T, path string, id int) State {
+	t.Helper()
+	q, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := q.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job.State
+}
+
+func TestRunningJobKeepsItsWorker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	id := addJob(t, path)
+
+	var release func()
+	err := Update(path, func(q *Queue) (err error) {
+		release, err = q.Start(q.Next())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Other commands see the job running and leave it alone
+	if state := jobState(t, path, id); state != StateRunning {
+		t.Errorf("listed as %s while its worker runs it, want running", state)
+	}
+	err = Update(path, func(q *Queue) error {
+		if next := q.Next(); next != nil {
+			t.Errorf("job %d offered to a second worker", next.ID)
+		}
+		_, err := q.Cancel(id)
+		return err
+	})
+	if err == nil {
+		t.Error("cancelled a running job")
+	}
+	addJob(t, path)
+	if state := jobState(t, path, id); state != StateRunning {
+		t.Errorf("%s after another job was added, want running", state)
+	}
+
+	err = Update(path, func(q *Queue) error {
+		defer release()
+		job, err := q.Get(id)
+		q.Finish(job, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := jobState(t, path, id); state != StateCompleted {
+		t.Errorf("%s after finishing, want completed", state)
+	}
+}
+
+func TestOrphanedJobRunsAgain(t *testing.T) {
+	if !common.LocksHeld {
+		t.Skip("no file locks to tell an orphaned job by")
+	}
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	id := addJob(t, path)
+
+	// The worker exits without recording the outcome
+	err := Update(path, func(q *Queue) error {
+		release, err := q.Start(q.Next())
+		if err == nil {
+			release()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := jobState(t, path, id); state != StatePending {
+		t.Errorf("%s after its worker exited, want pending", state)
+	}
+	err = Update(path, func(q *Queue) error {
+		if next := q.Next(); next == nil || next.ID != id {
+			t.Errorf("next job %v, want job %d", next, id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	const adds = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, adds)
+	for i := 0; i < adds; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(path, func(q *Queue) error {
+				_, err := q.Add("compress", []string{"--output", "/nas", fmt.Sprintf("/downloads/game%d", i)})
+				return err
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Jobs) != adds || q.NextID != adds+1 {
+		t.Errorf("%d jobs, next ID %d after %d concurrent adds", len(q.Jobs), q.NextID, adds)
+	}
+}
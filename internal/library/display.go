@@ -0,0 +1,77 @@
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// DisplaySetup is a TV and audio system to check games against
+type DisplaySetup struct {
+	Resolution string // Video mode the display takes, e.g. "720p" (empty for any)
+	Audio      string // "stereo", "5.1" or "7.1" (empty for any)
+}
+
+// ParseDisplaySetup validates the --resolution and --audio values
+func ParseDisplaySetup(resolution, audio string) (DisplaySetup, error) {
+	setup := DisplaySetup{Resolution: strings.ToLower(resolution), Audio: strings.ToLower(audio)}
+	if _, ok := parsers.DisplayModes[setup.Resolution]; setup.Resolution != "" && !ok {
+		return setup, fmt.Errorf("unknown resolution %q (use %s)", resolution, strings.Join(sortedKeys(parsers.DisplayModes), ", "))
+	}
+	if _, ok := parsers.AudioSetups[setup.Audio]; setup.Audio != "" && !ok {
+		return setup, fmt.Errorf("unknown audio setup %q (use %s)", audio, strings.Join(sortedKeys(parsers.AudioSetups), ", "))
+	}
+	return setup, nil
+}
+
+// DisplayReport is the video and audio support of one game
+type DisplayReport struct {
+	Game
+	Resolutions  []string // Video modes from RESOLUTION
+	SoundFormats []string // Audio formats from SOUND_FORMAT
+	Supported    bool     // The game outputs the setup's resolution and audio
+	Err          error    // PARAM.SFO could not be read; Supported is false
+}
+
+// CheckDisplay reads the RESOLUTION and SOUND_FORMAT of each game and compares them with the setup
+func CheckDisplay(games []Game, setup DisplaySetup) []DisplayReport {
+	reports := make([]DisplayReport, 0, len(games))
+	for _, game := range games {
+		report := DisplayReport{Game: game}
+		sfo, err := ReadParamSFO(game)
+		if err != nil {
+			report.Err = err
+			reports = append(reports, report)
+			continue
+		}
+
+		features := sfo.GetFeatures()
+		report.Resolutions, report.SoundFormats = features.Resolution, features.Sound
+		report.Supported = setup.supports(sfo.GetInt("RESOLUTION"), sfo.GetInt("SOUND_FORMAT"))
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// supports reports whether a game with these RESOLUTION and SOUND_FORMAT values fits the setup
+func (s DisplaySetup) supports(resolution, sound uint32) bool {
+	if s.Resolution != "" && resolution&parsers.DisplayModes[s.Resolution] == 0 {
+		return false
+	}
+	if s.Audio != "" && sound&parsers.AudioSetups[s.Audio] == 0 {
+		return false
+	}
+	return true
+}
+
+func sortedKeys(m map[string]uint32) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return common.NaturalLess(keys[i], keys[j]) })
+	return keys
+}
@@ -0,0 +1,160 @@
+package library
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+)
+
+// Formats accepted by Filter.Format
+const (
+	FormatCompressed   = "compressed"   // Stored as game.7z
+	FormatDecompressed = "decompressed" // Stored as a game/ folder
+)
+
+// Filter selects organized games by console, format, size, game ID and title, and by
+// the catalog tags and collection of the embedded catalog.Filter. Empty fields match
+// every game.
+type Filter struct {
+	catalog.Filter
+
+	Consoles []detect.ConsoleType
+	Format   string   // FormatCompressed or FormatDecompressed
+	MinSize  int64    // Minimum size on disk in bytes
+	MaxSize  int64    // Maximum size on disk in bytes
+	IDs      []string // Game IDs or glob patterns such as BLUS3*, case-insensitive
+	Titles   []string // Case-insensitive substrings of the title
+}
+
+// IsEmpty reports whether the filter matches every game
+func (f Filter) IsEmpty() bool {
+	return f.Filter.IsEmpty() && len(f.Consoles) == 0 && f.Format == "" && f.MinSize == 0 && f.MaxSize == 0 &&
+		len(f.IDs) == 0 && len(f.Titles) == 0
+}
+
+// Validate checks the format, size range and ID patterns, and the catalog filter
+// against c, which may be nil when the filter doesn't use the catalog
+func (f *Filter) Validate(c *catalog.Catalog) error {
+	if !f.Filter.IsEmpty() {
+		if c == nil {
+			return fmt.Errorf("filtering by tag or collection needs the catalog")
+		}
+		if err := f.Filter.Validate(c); err != nil {
+			return err
+		}
+	}
+	switch f.Format {
+	case "", FormatCompressed, FormatDecompressed:
+	default:
+		return fmt.Errorf("invalid format %q (use compressed or decompressed)", f.Format)
+	}
+	if f.MinSize < 0 || f.MaxSize < 0 {
+		return fmt.Errorf("size limits must not be negative")
+	}
+	if f.MaxSize > 0 && f.MinSize > f.MaxSize {
+		return fmt.Errorf("minimum size %s is larger than the maximum size %s", common.FormatSize(f.MinSize), common.FormatSize(f.MaxSize))
+	}
+	for _, pattern := range f.IDs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid game ID pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether a game passes the filter, looking its tags and collections up
+// in c. The game directory is only measured when a size limit is set.
+func (f Filter) Match(game Game, c *catalog.Catalog) (bool, error) {
+	info := gameInfo(game)
+
+	if !f.Filter.IsEmpty() && (c == nil || info.GameID == "" || !f.Filter.Match(c, info.GameID)) {
+		return false, nil
+	}
+
+	if len(f.Consoles) > 0 {
+		console, err := detect.ParseConsoleType(info.Console)
+		if err != nil || !containsConsole(f.Consoles, console) {
+			return false, nil
+		}
+	}
+
+	switch f.Format {
+	case FormatCompressed:
+		if !game.Info.HasCompressed {
+			return false, nil
+		}
+	case FormatDecompressed:
+		if !game.Info.HasDecompressed {
+			return false, nil
+		}
+	}
+
+	if len(f.IDs) > 0 && !matchesAnyID(f.IDs, info.GameID) {
+		return false, nil
+	}
+	if len(f.Titles) > 0 && !containsAnyTitle(f.Titles, info.Title) {
+		return false, nil
+	}
+
+	if f.MinSize > 0 || f.MaxSize > 0 {
+		size, err := common.DirSize(game.Path)
+		if err != nil {
+			return false, err
+		}
+		if size < f.MinSize || (f.MaxSize > 0 && size > f.MaxSize) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FilterGames returns the games that pass the filter, with c as in Match
+func FilterGames(games []Game, f Filter, c *catalog.Catalog) ([]Game, error) {
+	if f.IsEmpty() {
+		return games, nil
+	}
+	var matched []Game
+	for _, game := range games {
+		ok, err := f.Match(game, c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, game)
+		}
+	}
+	return matched, nil
+}
+
+func containsConsole(consoles []detect.ConsoleType, console detect.ConsoleType) bool {
+	for _, c := range consoles {
+		if c == console {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyID(patterns []string, id string) bool {
+	id = strings.ToUpper(id)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyTitle(substrings []string, title string) bool {
+	title = common.FoldKey(title)
+	for _, s := range substrings {
+		if strings.Contains(title, common.FoldKey(s)) {
+			return true
+		}
+	}
+	return false
+}
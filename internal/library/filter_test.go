@@ -0,0 +1,147 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+)
+
+// filterGames returns organized games of known sizes, and a catalog tagging them
+func filterGames(t *testing.T) ([]Game, *catalog.Catalog) {
+	t.Helper()
+	root := t.TempDir()
+	games := []Game{
+		testGame(filepath.Join(root, "a"), "Échoes of Time", "BLUS30001", "PlayStation 3"),
+		testGame(filepath.Join(root, "b"), "Racing Game", "BLES00002", "PlayStation 3"),
+		testGame(filepath.Join(root, "c"), "Racing Game Demo", "NPUB90003", "PlayStation 3"),
+		testGame(filepath.Join(root, "d"), "Old Game", "SLUS20004", "PlayStation 2"),
+		{Path: filepath.Join(root, "e"), Info: &common.OrganizedDirInfo{IsOrganized: true}}, // No readable PARAM.SFO
+	}
+	games[0].Info.HasCompressed = true
+	games[1].Info.HasDecompressed = true
+	games[2].Info.HasCompressed, games[2].Info.HasDecompressed = true, true
+	for i, size := range []int{100, 1000, 10, 0, 0} {
+		if err := os.MkdirAll(games[i].Path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(games[i].Path, "data"), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := catalog.Parse([]byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Tag("BLUS30001", "", "rpg", "favorite")
+	c.Tag("BLES00002", "", "racing", "favorite")
+	c.Tag("NPUB90003", "", "racing", "demo")
+	if err := c.CreateCollection("best", ""); err != nil {
+		t.Fatal(err)
+	}
+	c.AddToCollection("best", "BLUS30001", "")
+	c.AddToCollection("best", "NPUB90003", "")
+	return games, c
+}
+
+func TestFilterGames(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string // Last path elements of the matching games
+	}{
+		{"empty", Filter{}, []string{"a", "b", "c", "d", "e"}},
+		{"console", Filter{Consoles: []detect.ConsoleType{detect.PS3}}, []string{"a", "b", "c"}},
+		{"compressed", Filter{Format: FormatCompressed}, []string{"a", "c"}},
+		{"decompressed", Filter{Format: FormatDecompressed}, []string{"b", "c"}},
+		{"ID glob, any case", Filter{IDs: []string{"blus3*", "NPUB90003"}}, []string{"a", "c"}},
+		{"title substring, folding accents", Filter{Titles: []string{"echoes"}}, []string{"a"}},
+		{"any title", Filter{Titles: []string{"ECHO", "demo"}}, []string{"a", "c"}},
+		{"minimum size", Filter{MinSize: 100}, []string{"a", "b"}},
+		{"size range", Filter{MinSize: 50, MaxSize: 500}, []string{"a"}},
+		{"all fields", Filter{Format: FormatCompressed, Titles: []string{"game"}, MaxSize: 50}, []string{"c"}},
+
+		// Catalog fields never match a game without a game ID
+		{"tags", Filter{Filter: catalog.Filter{Tags: []string{"racing", "favorite"}}}, []string{"b"}},
+		{"excluded tag", Filter{Filter: catalog.Filter{ExcludeTags: []string{"favorite"}}}, []string{"c", "d"}},
+		{"pre-release", Filter{Filter: catalog.Filter{ExcludePrerelease: true}}, []string{"a", "b", "d"}},
+		{"collection", Filter{Filter: catalog.Filter{Collection: "best"}}, []string{"a", "c"}},
+		{"collection and format", Filter{Filter: catalog.Filter{Collection: "best"}, Format: FormatDecompressed}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		games, c := filterGames(t)
+		if err := tt.filter.Validate(c); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		matched, err := FilterGames(games, tt.filter, c)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := []string{}
+		for _, game := range matched {
+			got = append(got, filepath.Base(game.Path))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterWithoutCatalog(t *testing.T) {
+	games, _ := filterGames(t)
+	f := Filter{Filter: catalog.Filter{Tags: []string{"rpg"}}}
+	if err := f.Validate(nil); err == nil {
+		t.Error("validated a tag filter without a catalog")
+	}
+	if matched, err := FilterGames(games, f, nil); err != nil || len(matched) != 0 {
+		t.Errorf("tag filter without a catalog matched %d games, %v", len(matched), err)
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	_, c := filterGames(t)
+	for _, f := range []Filter{
+		{Format: "zip"},
+		{MinSize: -1},
+		{MinSize: 200, MaxSize: 100},
+		{IDs: []string{"BLUS[3"}},
+		{Filter: catalog.Filter{Tags: []string{"not a tag!"}}},
+		{Filter: catalog.Filter{Collection: "missing"}},
+	} {
+		if err := f.Validate(c); err == nil {
+			t.Errorf("validated %+v", f)
+		}
+	}
+
+	// Validating normalizes the catalog tags
+	f := Filter{Filter: catalog.Filter{Tags: []string{"RPG"}}}
+	if err := f.Validate(c); err != nil {
+		t.Fatal(err)
+	}
+	if f.Tags[0] != "rpg" {
+		t.Errorf("tag validated as %q, want rpg", f.Tags[0])
+	}
+}
+
+func TestFilterIsEmpty(t *testing.T) {
+	if !(Filter{}).IsEmpty() {
+		t.Error("zero filter isn't empty")
+	}
+	for _, f := range []Filter{
+		{Format: FormatCompressed},
+		{MaxSize: 1},
+		{Filter: catalog.Filter{Collection: "best"}},
+		{Filter: catalog.Filter{ExcludePrerelease: true}},
+	} {
+		if f.IsEmpty() {
+			t.Errorf("%+v is empty", f)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package library
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// Firmware is a PS3 system software version, stored as major*100 + minor (4.81 is 481)
+type Firmware int
+
+// ParseFirmware parses a version as written by users ("4.81", "4.9") or in
+// PS3_SYSTEM_VER ("04.8100")
+func ParseFirmware(s string) (Firmware, error) {
+	major, minor, ok := strings.Cut(strings.TrimSpace(s), ".")
+	if len(minor) < 2 {
+		minor += "00"[len(minor):]
+	}
+	m, err1 := strconv.Atoi(major)
+	n, err2 := strconv.Atoi(minor[:2])
+	if !ok || err1 != nil || err2 != nil || m < 0 || n < 0 {
+		return 0, fmt.Errorf("invalid firmware version %q (expected e.g. 4.81)", s)
+	}
+	return Firmware(m*100 + n), nil
+}
+
+func (f Firmware) String() string {
+	return fmt.Sprintf("%d.%02d", int(f)/100, int(f)%100)
+}
+
+// FirmwareReport is the firmware an organized game needs
+type FirmwareReport struct {
+	Game
+	Required    Firmware // From PS3_SYSTEM_VER; 0 when the game doesn't say
+	KeyRevision int      // SCE key revision of EBOOT.BIN, -1 when it wasn't read
+	Err         error    // PARAM.SFO could not be read
+}
+
+// AuditFirmware reads the required firmware of each game. The key revision of
+// EBOOT.BIN is only read for decompressed games, so archives aren't unpacked.
+func AuditFirmware(games []Game) []FirmwareReport {
+	reports := make([]FirmwareReport, 0, len(games))
+	for _, game := range games {
+		report := FirmwareReport{Game: game, KeyRevision: -1}
+		sfo, err := ReadParamSFO(game)
+		if err != nil {
+			report.Err = err
+			reports = append(reports, report)
+			continue
+		}
+		if version := sfo.GetString("PS3_SYSTEM_VER"); version != "" {
+			if report.Required, err = ParseFirmware(version); err != nil {
+				report.Err = fmt.Errorf("PS3_SYSTEM_VER: %w", err)
+			}
+		}
+		if game.Info.HasDecompressed {
+			if header, err := readSCEHeader(filepath.Join(game.Path, "game", "PS3_GAME", "USRDIR", "EBOOT.BIN")); err == nil {
+				report.KeyRevision = int(header.KeyRevision)
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// readSCEHeader reads the SCE header at the start of an executable
+func readSCEHeader(path string) (*parsers.SCEHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data := make([]byte, parsers.SCEHeaderSize)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return parsers.ParseSCEHeader(data)
+}
@@ -0,0 +1,86 @@
+// Package library scans output directories that contain organized games
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ignore"
+)
+
+// Game is an organized game directory found in a library
+type Game struct {
+	Path string                   // Organized game directory
+	Info *common.OrganizedDirInfo // Format and game information
+}
+
+// FindGames returns the organized games in a library directory. Games may sit directly
+// in the root or one level down in alphabetical buckets (--organize-by first-letter).
+// A root that is itself an organized game directory is returned as a single game.
+// Folders ignored by a .romignore in the root are skipped.
+func FindGames(root string, layout common.Layout) ([]Game, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("reading library %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("library path is not a directory: %s", root)
+	}
+
+	if game, ok := organizedGame(root, layout); ok {
+		return []Game{game}, nil
+	}
+
+	rules, err := ignore.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	var games []Game
+	if err := scanDir(root, layout, rules, 1, &games); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(games, func(i, j int) bool { return games[i].Path < games[j].Path })
+	return games, nil
+}
+
+// scanDir collects organized games in dir, descending into other folders up to depth levels
+func scanDir(dir string, layout common.Layout, rules *ignore.Rules, depth int, games *[]Game) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || containsName(libraryDirs, entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if rules.Ignored(path, true) {
+			continue
+		}
+		if game, ok := organizedGame(path, layout); ok {
+			*games = append(*games, game)
+			continue
+		}
+		if depth > 0 {
+			if err := scanDir(path, layout, rules, depth-1, games); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// organizedGame reports whether path is an organized game directory
+func organizedGame(path string, layout common.Layout) (Game, bool) {
+	info, err := common.DetectOrganizedDirectory(path, layout, false)
+	if err != nil || !info.IsOrganized {
+		return Game{}, false
+	}
+	return Game{Path: path, Info: info}, true
+}
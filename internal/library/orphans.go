@@ -0,0 +1,220 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ignore"
+)
+
+// QuarantineDir is the library root folder stray files are moved into
+const QuarantineDir = "_quarantine"
+
+// libraryDirs are folders rom-organizer itself keeps in a library root
+var libraryDirs = []string{"_pool", QuarantineDir, common.ThemesSection, common.MediaSection}
+
+// OrphanKind is why a file doesn't belong in a library
+type OrphanKind string
+
+const (
+	OrphanArchive    OrphanKind = "stray archive"   // An input archive or disc image left in the library
+	OrphanTemp       OrphanKind = "temp leftover"   // A temporary folder or file from an interrupted run
+	OrphanIncomplete OrphanKind = "incomplete game" // A game folder missing game.7z/game/ or its standard folders
+	OrphanFolder     OrphanKind = "unknown folder"  // A folder that is neither a game nor a bucket of games
+	OrphanFile       OrphanKind = "stray file"
+	OrphanJunk       OrphanKind = "junk file" // OS and file manager metadata such as Thumbs.db
+)
+
+// Orphan is a file or folder in a library that doesn't belong to any organized game
+type Orphan struct {
+	Path   string
+	Kind   OrphanKind
+	Detail string
+	Size   int64
+}
+
+// strayArchiveExts are input formats that end up in a library by mistake
+var strayArchiveExts = []string{".7z", ".zip", ".rar", ".iso", ".pkg", ".tar", ".part"}
+
+// tempPrefixes are the names of temporary files and folders rom-organizer creates
+var tempPrefixes = []string{".archive-extract-", ".disc-extract-", ".rom-organizer-"}
+
+// gameFolderName matches a folder named like an organized game, e.g. "Title [BLUS30001]"
+var gameFolderName = regexp.MustCompile(`\[[A-Z0-9_-]+\]$`)
+
+// FindOrphans lists what in a library root, and in alphabetical bucket folders one
+// level down, doesn't belong to an organized game. junk holds file name patterns of
+// OS metadata files (common.DefaultJunkFiles when nil). What the root's .romignore
+// ignores, and the .romignore itself, is left alone.
+func FindOrphans(root string, layout common.Layout, junk []string) ([]Orphan, error) {
+	if junk == nil {
+		junk = common.DefaultJunkFiles
+	}
+	rules, err := ignore.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []Orphan
+	if err := findOrphans(root, layout, junk, rules, 1, &orphans); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+func findOrphans(dir string, layout common.Layout, junk []string, rules *ignore.Rules, depth int, orphans *[]Orphan) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() && depth > 0 && containsName(libraryDirs, name) {
+			continue
+		}
+		if rules.Ignored(path, entry.IsDir()) || (name == ignore.FileName && !entry.IsDir()) {
+			continue
+		}
+		if _, ok := organizedGame(path, layout); ok {
+			continue
+		}
+
+		orphan := Orphan{Path: path}
+		switch {
+		case hasAnyPrefix(name, tempPrefixes):
+			orphan.Kind = OrphanTemp
+		case !entry.IsDir() && matchesAny(name, junk):
+			orphan.Kind = OrphanJunk
+		case !entry.IsDir() && hasAnyExt(name, strayArchiveExts):
+			orphan.Kind = OrphanArchive
+		case !entry.IsDir():
+			orphan.Kind = OrphanFile
+		case gameFolderName.MatchString(name):
+			orphan.Kind = OrphanIncomplete
+			orphan.Detail = incompleteReason(path, layout)
+		default:
+			// A bucket such as A/ or 0-9/ holds games; look inside instead
+			hasGames, err := containsGames(path, layout)
+			if err != nil {
+				return err
+			}
+			if (hasGames || isBucketName(name)) && depth > 0 {
+				if err := findOrphans(path, layout, junk, rules, depth-1, orphans); err != nil {
+					return err
+				}
+				continue
+			}
+			orphan.Kind = OrphanFolder
+		}
+
+		if entry.IsDir() {
+			orphan.Size, _ = common.DirSize(path)
+		} else if info, err := entry.Info(); err == nil {
+			orphan.Size = info.Size()
+		}
+		*orphans = append(*orphans, orphan)
+	}
+	return nil
+}
+
+// incompleteReason describes what a game-named folder is missing
+func incompleteReason(path string, layout common.Layout) string {
+	layout = layout.WithDefaults()
+	var missing []string
+	if !exists(filepath.Join(path, "game.7z")) && !exists(filepath.Join(path, "game")) {
+		missing = append(missing, "game.7z or game/")
+	}
+	for _, dir := range []string{layout.UpdatesDir, layout.DLCDir} {
+		if !exists(filepath.Join(path, dir)) {
+			missing = append(missing, dir+"/")
+		}
+	}
+	if len(missing) == 0 {
+		return "unreadable game information"
+	}
+	return "missing " + strings.Join(missing, ", ")
+}
+
+// containsGames reports whether dir directly holds an organized game
+func containsGames(dir string, layout common.Layout) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("reading directory %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, ok := organizedGame(filepath.Join(dir, entry.Name()), layout); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isBucketName reports whether name is a --organize-by first-letter folder
+func isBucketName(name string) bool {
+	return name == "0-9" || name == "#" || (len(name) == 1 && name[0] >= 'A' && name[0] <= 'Z')
+}
+
+// Quarantine moves an orphan into the quarantine folder of root, keeping its path
+// relative to root, and returns the new location
+func Quarantine(root string, orphan Orphan) (string, error) {
+	rel, err := filepath.Rel(root, orphan.Path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is not inside %s", orphan.Path, root)
+	}
+	target := filepath.Join(root, QuarantineDir, rel)
+	for i := 2; exists(target); i++ {
+		target = filepath.Join(root, QuarantineDir, fmt.Sprintf("%s (%d)", rel, i))
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("creating quarantine folder: %w", err)
+	}
+	if err := os.Rename(orphan.Path, target); err != nil {
+		return "", fmt.Errorf("moving %s to quarantine: %w", orphan.Path, err)
+	}
+	return target, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyExt(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return containsName(exts, ext)
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
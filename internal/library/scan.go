@@ -0,0 +1,224 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/ignore"
+)
+
+// ScanChange is how a game differs from the previous scan
+type ScanChange string
+
+const (
+	ScanNew       ScanChange = "new"
+	ScanChanged   ScanChange = "changed"
+	ScanUnchanged ScanChange = "unchanged"
+	ScanRemoved   ScanChange = "removed"
+)
+
+// ScanOptions controls what a scan reads
+type ScanOptions struct {
+	Hash common.HashAlgorithm // Hash of the game content ("" skips hashing)
+	Full bool                 // Re-hash and re-parse games that look unchanged
+}
+
+// ScanResult is the outcome of scanning one game directory
+type ScanResult struct {
+	Path          string
+	Change        ScanChange
+	Record        *catalog.ScanRecord // The new record, or the old one for removed games
+	ContentChange bool                // The hash differs from the previous scan
+	Err           error
+}
+
+// Scan compares games with the records of the previous scan. Only games whose size or
+// latest modification time changed (or every game, with Full) are hashed and parsed
+// again; the others keep their record. previous is not modified.
+func Scan(games []Game, previous map[string]*catalog.ScanRecord, opts ScanOptions) []ScanResult {
+	results := make([]ScanResult, 0, len(games))
+	for _, game := range games {
+		results = append(results, scanGame(game, previous[game.Path], opts))
+	}
+	return results
+}
+
+// Removed returns the results for games recorded under one of the roots that are no
+// longer organized game directories there. Games in folders a root's .romignore ignores
+// are left as they were recorded.
+func Removed(roots []string, games []Game, previous map[string]*catalog.ScanRecord) []ScanResult {
+	found := make(map[string]bool, len(games))
+	for _, game := range games {
+		found[game.Path] = true
+	}
+	var rules []*ignore.Rules
+	for _, root := range roots {
+		if r, err := ignore.Load(root); err == nil && r != nil {
+			rules = append(rules, r)
+		}
+	}
+
+	var removed []ScanResult
+	for path, record := range previous {
+		if found[path] || !underAny(path, roots) || ignoredByAny(path, rules) {
+			continue
+		}
+		removed = append(removed, ScanResult{Path: path, Change: ScanRemoved, Record: record})
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Path < removed[j].Path })
+	return removed
+}
+
+func scanGame(game Game, old *catalog.ScanRecord, opts ScanOptions) ScanResult {
+	result := ScanResult{Path: game.Path}
+	size, modTime, err := dirState(game.Path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	switch {
+	case old == nil:
+		result.Change = ScanNew
+	case old.Size != size || !old.ModTime.Equal(modTime):
+		result.Change = ScanChanged
+	default:
+		result.Change = ScanUnchanged
+		if !opts.Full && (opts.Hash == "" || strings.HasPrefix(old.Hash, string(opts.Hash)+":")) {
+			result.Record = old
+			return result
+		}
+	}
+
+	record := &catalog.ScanRecord{
+		GameID:  game.Info.GameInfo.GameID,
+		Title:   game.Info.GameInfo.Title,
+		Size:    size,
+		ModTime: modTime,
+		Scanned: time.Now(),
+	}
+	if sfo, err := ReadParamSFO(game); err != nil {
+		result.Err = err
+	} else {
+		record.Version = sfo.GetString("APP_VER")
+		record.Firmware = sfo.GetString("PS3_SYSTEM_VER")
+		record.Build = consoles.PS3Prerelease(sfo.GetString("CATEGORY"), sfo.GetTitleID(), sfo.GetTitle())
+	}
+	if opts.Hash != "" {
+		if record.Hash, err = contentHash(game, opts.Hash); err != nil {
+			result.Err = err
+		}
+		if old != nil && old.Hash != "" && old.Hash != record.Hash && strings.HasPrefix(old.Hash, string(opts.Hash)+":") {
+			result.ContentChange = true
+			result.Change = ScanChanged
+		}
+	}
+	result.Record = record
+	return result
+}
+
+// dirState returns the total size of the files in dir and the latest modification time
+// of any of them, by reading file metadata only
+func dirState(dir string) (int64, time.Time, error) {
+	var size int64
+	var latest time.Time
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("reading %s: %w", dir, err)
+	}
+	return size, latest.UTC(), nil
+}
+
+// contentHash hashes game.7z, or for decompressed games the sorted list of the game/
+// files with their hashes, as "<algorithm>:<hex>"
+func contentHash(game Game, alg common.HashAlgorithm) (string, error) {
+	if !game.Info.HasDecompressed {
+		sum, err := common.HashFile(filepath.Join(game.Path, "game.7z"), alg)
+		if err != nil {
+			return "", err
+		}
+		return string(alg) + ":" + sum, nil
+	}
+
+	gameDir := filepath.Join(game.Path, "game")
+	var files []string
+	err := filepath.Walk(gameDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", gameDir, err)
+	}
+	sort.Strings(files)
+
+	h := alg.New()
+	for _, path := range files {
+		sum, err := common.HashFile(path, alg)
+		if err != nil {
+			return "", err
+		}
+		rel, _ := filepath.Rel(gameDir, path)
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), sum)
+	}
+	return fmt.Sprintf("%s:%x", alg, h.Sum(nil)), nil
+}
+
+// ignoredByAny reports whether any of the rules ignores a game directory
+func ignoredByAny(path string, rules []*ignore.Rules) bool {
+	for _, r := range rules {
+		if r.Ignored(path, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// underAny reports whether path is one of the roots or inside one
+func underAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// StoredArchiveHash returns the game.7z digest a scan recorded for a compressed game,
+// when the game directory hasn't changed since
+func StoredArchiveHash(game Game, record *catalog.ScanRecord) (common.HashAlgorithm, string, bool) {
+	if record == nil || record.Hash == "" || game.Info.HasDecompressed {
+		return "", "", false
+	}
+	size, modTime, err := dirState(game.Path)
+	if err != nil || record.Size != size || !record.ModTime.Equal(modTime) {
+		return "", "", false
+	}
+	alg, digest, ok := strings.Cut(record.Hash, ":")
+	if !ok {
+		return "", "", false
+	}
+	return common.HashAlgorithm(alg), digest, true
+}
@@ -0,0 +1,33 @@
+package library
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// paramSFOPath is the location of a PS3 game's PARAM.SFO inside game/ or game.7z
+const paramSFOPath = "PS3_GAME/PARAM.SFO"
+
+// ReadParamSFO reads the PARAM.SFO of an organized game, from its game/ folder or, for
+// compressed games, from game.7z without extracting the archive
+func ReadParamSFO(game Game) (*parsers.ParamSFO, error) {
+	var data []byte
+	var err error
+	if game.Info.HasDecompressed {
+		data, err = parsers.ReadParamSFOFile(filepath.Join(game.Path, "game", filepath.FromSlash(paramSFOPath)))
+	} else {
+		data, err = common.Read7zFile(filepath.Join(game.Path, "game.7z"), paramSFOPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sfo, err := parsers.ParseParamSFO(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing PARAM.SFO of %s: %w", game.Path, err)
+	}
+	return sfo, nil
+}
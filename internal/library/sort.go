@@ -0,0 +1,93 @@
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// SortBy selects the order games are listed in
+type SortBy string
+
+// Sort orders
+const (
+	SortTitle   SortBy = "title"   // Natural order of titles ("Game 2" before "Game 10")
+	SortID      SortBy = "id"      // Game ID
+	SortConsole SortBy = "console" // Console, then title
+	SortSize    SortBy = "size"    // Size on disk, largest first
+)
+
+// SortKeys lists the accepted --sort values
+var SortKeys = []SortBy{SortTitle, SortID, SortConsole, SortSize}
+
+// ParseSortBy validates a --sort value
+func ParseSortBy(s string) (SortBy, error) {
+	for _, key := range SortKeys {
+		if strings.EqualFold(s, string(key)) {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("invalid sort order %q (use title, id, console or size)", s)
+}
+
+// SortGames orders games in place. size returns the size of a game and is only
+// called for SortSize.
+func SortGames(games []Game, by SortBy, size func(Game) int64) {
+	var sizes map[string]int64
+	if by == SortSize {
+		sizes = make(map[string]int64, len(games))
+		for _, game := range games {
+			sizes[game.Path] = size(game)
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return gameLess(games[i], games[j], by, sizes)
+	})
+}
+
+// SortStats orders measured games in place, using their disk size for SortSize
+func SortStats(games []GameStats, by SortBy) {
+	sizes := make(map[string]int64, len(games))
+	for _, game := range games {
+		sizes[game.Path] = game.DiskSize
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return gameLess(games[i].Game, games[j].Game, by, sizes)
+	})
+}
+
+// gameLess compares two games by the sort key, falling back to title, ID and path
+func gameLess(a, b Game, by SortBy, sizes map[string]int64) bool {
+	infoA, infoB := gameInfo(a), gameInfo(b)
+	switch by {
+	case SortID:
+		if infoA.GameID != infoB.GameID {
+			return infoA.GameID < infoB.GameID
+		}
+	case SortConsole:
+		if infoA.Console != infoB.Console {
+			return common.NaturalLess(infoA.Console, infoB.Console)
+		}
+	case SortSize:
+		if sizes[a.Path] != sizes[b.Path] {
+			return sizes[a.Path] > sizes[b.Path]
+		}
+	}
+	if infoA.Title != infoB.Title {
+		return common.NaturalLess(infoA.Title, infoB.Title)
+	}
+	if infoA.GameID != infoB.GameID {
+		return infoA.GameID < infoB.GameID
+	}
+	return common.NaturalLess(a.Path, b.Path)
+}
+
+// gameInfo returns the game information of a game, or an empty one when it has none
+func gameInfo(game Game) common.GameInfo {
+	if game.Info == nil || game.Info.GameInfo == nil {
+		return common.GameInfo{}
+	}
+	return *game.Info.GameInfo
+}
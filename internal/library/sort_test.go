@@ -0,0 +1,67 @@
+package library
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+func testGame(path, title, id, console string) Game {
+	info := &common.GameInfo{Title: title, GameID: id, Console: console}
+	return Game{Path: path, Info: &common.OrganizedDirInfo{IsOrganized: true, GameInfo: info}}
+}
+
+func TestSortGames(t *testing.T) {
+	sizes := map[string]int64{"/lib/a": 30, "/lib/b": 10, "/lib/c": 20, "/lib/d": 30, "/lib/e": 0}
+	games := func() []Game {
+		return []Game{
+			testGame("/lib/a", "Game 10", "BLUS30010", "PlayStation 3"),
+			testGame("/lib/b", "Game 2", "SLUS20002", "PlayStation 2"),
+			testGame("/lib/c", "Another Game", "BLES00003", "PlayStation 3"),
+			testGame("/lib/d", "Game 2", "BLUS30002", "PlayStation 3"),
+			{Path: "/lib/e", Info: &common.OrganizedDirInfo{IsOrganized: true}},
+		}
+	}
+	tests := []struct {
+		by   SortBy
+		want []string
+	}{
+		// Games without information sort as an empty title, first
+		{SortTitle, []string{"/lib/e", "/lib/c", "/lib/d", "/lib/b", "/lib/a"}},
+		{SortID, []string{"/lib/e", "/lib/c", "/lib/d", "/lib/a", "/lib/b"}},
+		{SortConsole, []string{"/lib/e", "/lib/b", "/lib/c", "/lib/d", "/lib/a"}},
+		// Largest first; equal sizes by title
+		{SortSize, []string{"/lib/d", "/lib/a", "/lib/c", "/lib/b", "/lib/e"}},
+	}
+	for _, tt := range tests {
+		list := games()
+		SortGames(list, tt.by, func(game Game) int64 {
+			if tt.by != SortSize {
+				t.Errorf("%s: measured %s", tt.by, game.Path)
+			}
+			return sizes[game.Path]
+		})
+		var paths []string
+		for _, game := range list {
+			paths = append(paths, game.Path)
+		}
+		if !reflect.DeepEqual(paths, tt.want) {
+			t.Errorf("sorted by %s: %v, want %v", tt.by, paths, tt.want)
+		}
+	}
+}
+
+func TestParseSortBy(t *testing.T) {
+	for _, key := range SortKeys {
+		if got, err := ParseSortBy(string(key)); err != nil || got != key {
+			t.Errorf("ParseSortBy(%q) = %q, %v", key, got, err)
+		}
+	}
+	if got, err := ParseSortBy("Title"); err != nil || got != SortTitle {
+		t.Errorf("ParseSortBy is case-sensitive: %q, %v", got, err)
+	}
+	if _, err := ParseSortBy("date"); err == nil {
+		t.Error("parsed an unknown sort order")
+	}
+}
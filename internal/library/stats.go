@@ -0,0 +1,63 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// GameStats holds the size information for one organized game
+type GameStats struct {
+	Game
+	DiskSize    int64                    // Size of the whole organized directory
+	Compression *common.CompressionStats // Archive stats for compressed games
+}
+
+// Stats summarizes the size of a set of organized games
+type Stats struct {
+	Games        []GameStats
+	Compressed   int                     // Games stored as game.7z
+	Decompressed int                     // Games stored as a game/ folder
+	DiskSize     int64                   // Total size on disk
+	Compression  common.CompressionStats // Totals across compressed games
+}
+
+// CollectStats measures the given games. Compressed games are listed with 7z to find
+// the size of their original files.
+func CollectStats(games []Game) (*Stats, error) {
+	stats := &Stats{}
+
+	for _, game := range games {
+		diskSize, err := common.DirSize(game.Path)
+		if err != nil {
+			return nil, err
+		}
+		gameStats := GameStats{Game: game, DiskSize: diskSize}
+
+		if game.Info.HasCompressed {
+			archivePath := filepath.Join(game.Path, "game.7z")
+			archiveInfo, err := os.Stat(archivePath)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", archivePath, err)
+			}
+			originalSize, err := common.Archive7zContentSize(archivePath)
+			if err != nil {
+				return nil, err
+			}
+
+			compression := common.CompressionStats{OriginalSize: originalSize, CompressedSize: archiveInfo.Size()}
+			gameStats.Compression = &compression
+			stats.Compression = stats.Compression.Add(compression)
+			stats.Compressed++
+		} else {
+			stats.Decompressed++
+		}
+
+		stats.DiskSize += diskSize
+		stats.Games = append(stats.Games, gameStats)
+	}
+
+	return stats, nil
+}
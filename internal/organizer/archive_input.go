@@ -0,0 +1,85 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// inputArchiveExtensions are the archive types organized by extracting them first
+var inputArchiveExtensions = []string{".zip", ".7z", ".rar"}
+
+// IsInputArchive reports whether path is an archive file holding a game to organize
+func IsInputArchive(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, archiveExt := range inputArchiveExtensions {
+		if ext == archiveExt {
+			return true
+		}
+	}
+	return false
+}
+
+// organizeArchive extracts an archive into a temporary folder next to the output and
+// organizes the extracted files. Encrypted archives ask common.ArchivePassword. A 7z
+// archive of a bare game folder is kept as game.7z instead, unless decompressing.
+func organizeArchive(archivePath string, opts OrganizeOptions) (*GameResult, error) {
+	if _, ok := rarSetName(filepath.Base(archivePath)); ok && !isFirstRARVolume(filepath.Base(archivePath)) {
+		return nil, common.Permanent(fmt.Errorf("%s is not the first volume of its RAR set (give the .part1.rar or .rar file)", archivePath))
+	}
+	if opts.Format != Decompressed {
+		if result, ok, err := organizeStrayArchive(archivePath, opts); ok {
+			return result, err
+		}
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(opts.OutputDir, ".archive-extract-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ui.Infof("Extracting %s...\n", filepath.Base(archivePath))
+	opts.reportStage(StageExtracting)
+	if err := common.ExtractArchive(archivePath, tempDir); err != nil {
+		return nil, fmt.Errorf("extracting archive: %w", err)
+	}
+	// Checksum files packed with the game are checked before they go with the extras
+	if !opts.NoChecksumFiles {
+		if err := verifyChecksumFiles(sourceChecksumFiles(tempDir), opts); err != nil {
+			return nil, err
+		}
+	}
+	stripSceneJunk(tempDir, opts)
+
+	// The extracted copy is temporary, so --move applies to the archive instead
+	archiveOpts := opts
+	archiveOpts.MoveSource = false
+	result, err := OrganizeGame(tempDir, archiveOpts)
+	if err != nil {
+		return nil, err
+	}
+	result.SourcePath = archivePath
+
+	if opts.MoveSource {
+		// All volumes of a split RAR archive go with it
+		for _, volume := range rarVolumes(archivePath) {
+			ui.Verbosef("Removing archive: %s\n", volume)
+			if err := opts.discardSource(volume); err != nil {
+				return result, fmt.Errorf("removing archive: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
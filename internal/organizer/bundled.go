@@ -0,0 +1,135 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// bundledKind is the folder of the organized game a bundled file belongs in
+type bundledKind int
+
+const (
+	bundledDLC bundledKind = iota
+	bundledUpdate
+	bundledManual
+)
+
+// bundledFile is a file that came with a game but is kept out of game/ and game.7z,
+// such as an update package in a download bundle or a PDF manual
+type bundledFile struct {
+	path   string // Source file
+	inGame bool   // Whether the file is inside the game folder being organized
+	kind   bundledKind
+}
+
+// dir returns the folder of the organized game the file belongs in
+func (f bundledFile) dir(layout common.Layout) string {
+	layout = layout.WithDefaults()
+	switch f.kind {
+	case bundledUpdate:
+		return layout.UpdatesDir
+	case bundledManual:
+		return layout.ManualsDir
+	}
+	return layout.DLCDir
+}
+
+// placeBundledFile copies or moves a bundled file into its folder of the organized game
+func placeBundledFile(file bundledFile, targetPath string, opts OrganizeOptions) error {
+	dest := filepath.Join(targetPath, file.dir(opts.Layout), filepath.Base(file.path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(dest), err)
+	}
+	ui.Verbosef("Placing %s in %s/\n", filepath.Base(file.path), file.dir(opts.Layout))
+
+	if opts.MoveSource && canRename(file.path, dest) {
+		if err := os.Rename(file.path, dest); err == nil {
+			opts.sourceMoved(file.path, dest)
+			return nil
+		}
+	}
+	// A copy that replaces a move is hashed on the way, so the source is read once
+	var algs []common.HashAlgorithm
+	if opts.MoveSource {
+		algs = append(algs, opts.snapshotHash())
+	}
+	var sums map[common.HashAlgorithm]string
+	err := opts.Retry.Do("Copying "+filepath.Base(file.path), func() (err error) {
+		sums, err = common.CopyFileHashed(file.path, dest, algs...)
+		return err
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", filepath.Base(file.path), err)
+	}
+	if !opts.MoveSource {
+		return nil
+	}
+
+	want := sums[opts.snapshotHash()]
+	got, err := common.HashFile(dest, opts.snapshotHash())
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("refusing to delete %s: the copy in %s differs", file.path, dest)
+	}
+	return opts.discardSource(file.path)
+}
+
+// relocateBundledFile moves a file that was copied into game/ with the rest of the game
+// folder into its own folder
+func relocateBundledFile(file bundledFile, targetPath string, opts OrganizeOptions) error {
+	copied := filepath.Join(targetPath, "game", filepath.Base(file.path))
+	dest := filepath.Join(targetPath, file.dir(opts.Layout), filepath.Base(file.path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(dest), err)
+	}
+	ui.Verbosef("Placing %s in %s/\n", filepath.Base(file.path), file.dir(opts.Layout))
+	if err := os.Rename(copied, dest); err != nil {
+		return fmt.Errorf("moving %s out of game/: %w", filepath.Base(file.path), err)
+	}
+	// A game/ folder moved from the source gets its file back on undo
+	opts.sourceMoved(copied, dest)
+	return nil
+}
+
+// archiveFiles lists the files under root to put in game.7z, leaving out bundled
+// files and disc components that are placed separately. It returns nil when nothing
+// inside root is left out.
+func archiveFiles(root string, bundled []bundledFile, components []discComponent) ([]string, error) {
+	skip := make(map[string]bool)
+	for _, file := range bundled {
+		if file.inGame {
+			skip[filepath.Clean(file.path)] = true
+		}
+	}
+	for _, component := range components {
+		skip[filepath.Clean(component.path)] = true
+	}
+	if len(skip) == 0 {
+		return nil, nil
+	}
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() && skip[filepath.Clean(path)] {
+			return filepath.SkipDir
+		}
+		if err != nil || info.IsDir() || skip[filepath.Clean(path)] {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing game files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,61 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// sourceChecksumFiles returns the .sfv and .md5 files at the top of a source folder, and
+// at the top of the folder wrapping everything else in it if there is one
+func sourceChecksumFiles(dir string) []string {
+	var files []string
+	for level := 0; level < 2; level++ {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return files
+		}
+		var folders []string
+		others := 0
+		for _, entry := range entries {
+			switch {
+			case entry.Type().IsRegular() && common.IsChecksumFile(entry.Name()):
+				files = append(files, filepath.Join(dir, entry.Name()))
+			case entry.IsDir():
+				folders = append(folders, entry.Name())
+			default:
+				others++
+			}
+		}
+		if len(folders) != 1 || others > 0 {
+			return files
+		}
+		dir = filepath.Join(dir, folders[0])
+	}
+	return files
+}
+
+// verifyChecksumFiles checks the files listed in .sfv and .md5 files before anything is
+// copied, printing every missing or corrupt one. A source that doesn't match is not
+// organized, as it would only preserve a broken download.
+func verifyChecksumFiles(paths []string, opts OrganizeOptions) error {
+	for _, path := range paths {
+		ui.Infof("Verifying %s...\n", filepath.Base(path))
+		opts.reportStage(StageVerifying)
+		mismatches, listed, err := common.VerifyChecksumFile(path)
+		if err != nil {
+			return common.Permanent(fmt.Errorf("reading %s: %w", path, err))
+		}
+		if len(mismatches) > 0 {
+			for _, mismatch := range mismatches {
+				ui.Errorf("  %s\n", mismatch)
+			}
+			return common.Permanent(fmt.Errorf("%d of %d files don't match %s; download them again or use --no-checksum-files", len(mismatches), listed, filepath.Base(path)))
+		}
+		ui.Verbosef("%d files match %s\n", listed, filepath.Base(path))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package organizer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// ErrCollisionSkipped means a game was left out because a different game already uses
+// its folder name and the collision policy is skip
+var ErrCollisionSkipped = errors.New("folder name taken by a different game")
+
+// isCollisionSkip reports whether err is a game skipped by the collision policy
+func isCollisionSkip(err error) bool {
+	return errors.Is(err, ErrCollisionSkipped)
+}
+
+// resolveCollision checks whether targetPath already holds a different game than the
+// one being organized and applies the collision policy. It returns the folder to use.
+// rawTitle is the PARAM.SFO title before the title rules were applied, which tells apart
+// games whose cleaned-up or sanitized titles end up the same.
+func resolveCollision(targetPath string, gameInfo *common.GameInfo, rawTitle string, opts OrganizeOptions) (string, error) {
+	if opts.OnCollision == common.CollisionOverwrite {
+		return targetPath, nil
+	}
+	occupant, different := occupiedBy(targetPath, gameInfo.GameID, rawTitle, opts.Layout)
+	if !different {
+		return targetPath, nil
+	}
+	taken := fmt.Sprintf("%s already holds %q", targetPath, occupant)
+
+	switch opts.OnCollision {
+	case common.CollisionSuffix:
+		parent := filepath.Dir(targetPath)
+		for i := 2; ; i++ {
+			name, err := opts.NameLimits.Name(absPath(parent), gameInfo.Title, fmt.Sprintf(" (%d) [%s]", i, gameInfo.GameID))
+			if err != nil {
+				return "", err
+			}
+			candidate := filepath.Join(parent, name)
+			// A suffixed folder of the same game is reused, so running the batch
+			// again behaves like it does for any other existing game
+			_, statErr := os.Stat(candidate)
+			if title, different := occupiedBy(candidate, gameInfo.GameID, rawTitle, opts.Layout); os.IsNotExist(statErr) || (title != "" && !different) {
+				ui.Warnf("%s; organizing %q into %s\n", taken, rawTitle, filepath.Base(candidate))
+				return candidate, nil
+			}
+		}
+	case common.CollisionSkip:
+		return "", fmt.Errorf("%w: %s", ErrCollisionSkipped, taken)
+	default:
+		return "", fmt.Errorf("%w: %s, a different game than %q (use --on-collision suffix, skip or overwrite)",
+			common.ErrTargetExists, taken, rawTitle)
+	}
+}
+
+// occupiedBy reports the PARAM.SFO title of the organized game in dir when it is a
+// different game than gameID and rawTitle. Folders that are missing, not organized or
+// unreadable don't count as a different game; creating the target reports them as usual.
+func occupiedBy(dir, gameID, rawTitle string, layout common.Layout) (string, bool) {
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return "", false
+	}
+	info, err := common.DetectOrganizedDirectory(dir, layout, false)
+	if err != nil || !info.IsOrganized {
+		return "", false
+	}
+	sfo, err := library.ReadParamSFO(library.Game{Path: dir, Info: info})
+	if err != nil {
+		ui.Verbosef("Could not read the game in %s to check for a collision: %v\n", dir, err)
+		return "", false
+	}
+	title := sfo.GetTitle()
+	return title, title != rawTitle || sfo.GetTitleID() != gameID
+}
+
+// absPath returns path made absolute, which is how name length limits count it
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
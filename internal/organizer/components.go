@@ -0,0 +1,158 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// discComponent is an optional disc folder (PS3_EXTRA, PS3_UPDATE) found beside
+// PS3_GAME whose policy keeps it out of game/ or game.7z
+type discComponent struct {
+	name   string // Folder name, e.g. PS3_UPDATE
+	path   string // Source folder
+	policy common.ComponentPolicy
+	files  int
+	size   int64
+}
+
+// findComponents returns the optional disc folders in root that aren't included in the
+// game. Folder names are matched in any case, as some dumps lower-case them.
+func findComponents(root string, opts OrganizeOptions) ([]discComponent, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil
+	}
+	var components []discComponent
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := componentFolder(entry.Name())
+		if name == "" {
+			continue
+		}
+		policy := opts.Components.Policy(name)
+		if policy == common.ComponentInclude {
+			continue
+		}
+		if policy == common.ComponentExclude && opts.MoveSource {
+			return nil, fmt.Errorf("%s=exclude can't be combined with --move: %s would be deleted with the source (use separate to keep it)", name, entry.Name())
+		}
+
+		component := discComponent{name: name, path: filepath.Join(root, entry.Name()), policy: policy}
+		err := filepath.Walk(component.path, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.Mode().IsRegular() {
+				component.files++
+				component.size += info.Size()
+			}
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", component.path, err)
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// componentFolder returns the disc component a folder name is, or ""
+func componentFolder(name string) string {
+	for _, component := range common.DiscComponents {
+		if strings.EqualFold(name, component) {
+			return component
+		}
+	}
+	return ""
+}
+
+// componentDest returns where a separated component goes in the organized game,
+// relative to it: a NAME.7z archive for compressed games, otherwise a NAME/ folder
+func componentDest(component discComponent, opts OrganizeOptions) string {
+	dest := filepath.Join(opts.Layout.WithDefaults().DiscDir, component.name)
+	if opts.Format == Compressed {
+		dest += ".7z"
+	}
+	return dest
+}
+
+// separateComponents takes the components out of a game/ folder copied or moved with
+// them: excluded ones are deleted and separated ones moved to the disc folder
+func separateComponents(components []discComponent, targetPath string, opts OrganizeOptions) error {
+	for _, component := range components {
+		copied := filepath.Join(targetPath, "game", filepath.Base(component.path))
+		if component.policy == common.ComponentExclude {
+			ui.Verbosef("Leaving out %s\n", component.name)
+			if err := os.RemoveAll(copied); err != nil {
+				return fmt.Errorf("removing %s from game/: %w", component.name, err)
+			}
+			continue
+		}
+		dest := filepath.Join(targetPath, componentDest(component, opts))
+		ui.Verbosef("Placing %s in %s\n", component.name, filepath.Dir(componentDest(component, opts)))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+		if err := os.Rename(copied, dest); err != nil {
+			return fmt.Errorf("moving %s out of game/: %w", component.name, err)
+		}
+		opts.sourceMoved(copied, dest)
+	}
+	return nil
+}
+
+// archiveComponents writes the separated components of a compressed game to their own
+// archives in the disc folder
+func archiveComponents(components []discComponent, targetPath string, archive common.ArchiveOptions, opts OrganizeOptions) error {
+	for _, component := range components {
+		if component.policy != common.ComponentSeparate {
+			continue
+		}
+		dest := filepath.Join(targetPath, componentDest(component, opts))
+		ui.Verbosef("Creating %s\n", componentDest(component, opts))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		os.Remove(dest)
+		err := opts.Retry.Do("Creating "+filepath.Base(dest), func() error {
+			return common.Create7zArchive(component.path, dest, archive)
+		}, func() { os.Remove(dest) })
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", filepath.Base(dest), err)
+		}
+	}
+	return nil
+}
+
+// writeComponentRecord records in metadata.json where the components went, so the
+// disc can be put back together. Without components any earlier record is removed.
+func writeComponentRecord(components []discComponent, targetPath string, gameInfo *common.GameInfo, opts OrganizeOptions) error {
+	if len(components) == 0 {
+		if err := os.Remove(filepath.Join(targetPath, common.DiscMetadataName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	meta := common.DiscMetadata{GameID: gameInfo.GameID}
+	for _, component := range components {
+		record := common.DiscComponent{
+			Name:   component.name,
+			Policy: component.policy,
+			Files:  component.files,
+			Size:   component.size,
+		}
+		if component.policy == common.ComponentSeparate {
+			record.Path = filepath.ToSlash(componentDest(component, opts))
+		}
+		meta.Components = append(meta.Components, record)
+		ui.Infof("  %s: %s (%d files, %s)\n", component.name, component.policy, component.files, common.FormatSize(component.size))
+	}
+	return common.WriteDiscMetadata(targetPath, meta)
+}
@@ -0,0 +1,120 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// DiscKeyFunc returns the disc key for an encrypted PS3 disc image, or nil when
+// none is known. imagePath is the image file or drive device being organized.
+type DiscKeyFunc func(imagePath, titleID string) ([]byte, error)
+
+// IsDiscImage reports whether path is a PS3 disc image (.iso) or a raw drive device
+func IsDiscImage(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return info.Mode()&os.ModeDevice != 0 || strings.EqualFold(filepath.Ext(path), ".iso")
+}
+
+// organizeDiscImage extracts a disc image or drive into a temporary folder next to
+// the output, decrypting it when needed, and organizes the extracted files
+func organizeDiscImage(imagePath string, opts OrganizeOptions) (*GameResult, error) {
+	iso, err := consoles.OpenPS3ISO(imagePath)
+	if err != nil {
+		if platform := detect.ISOPlatform(imagePath); platform != "" && platform != detect.PS3.String() {
+			return nil, fmt.Errorf("%w for: %s (looks like a disc image of %s, which isn't supported yet)", common.ErrNotDetected, imagePath, platform)
+		}
+		return nil, err
+	}
+	files, err := iso.Files()
+	if err != nil {
+		return nil, fmt.Errorf("reading disc file system: %w", err)
+	}
+
+	key, err := discKeyFor(iso, files, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MoveSource && !common.IsWritableDir(filepath.Dir(imagePath)) {
+		ui.Warnf("%s is on read-only media or its folder is not writable; --move disabled, the image will be kept\n", imagePath)
+		opts.MoveSource = false
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(opts.OutputDir, ".disc-extract-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ui.Infof("Reading disc %s (%d files)...\n", iso.TitleID, len(files))
+	opts.reportStage(StageReading)
+	if err := iso.ExtractFiles(files, tempDir, key); err != nil {
+		return nil, err
+	}
+
+	// The extracted copy is temporary, so --move applies to the image file instead
+	discOpts := opts
+	discOpts.MoveSource = false
+	result, err := OrganizeGame(tempDir, discOpts)
+	if err != nil {
+		return nil, err
+	}
+	result.SourcePath = imagePath
+
+	if opts.MoveSource {
+		if info, err := os.Stat(imagePath); err == nil && info.Mode().IsRegular() {
+			ui.Verbosef("Removing disc image: %s\n", imagePath)
+			if err := opts.discardSource(imagePath); err != nil {
+				return result, fmt.Errorf("removing disc image: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// discKeyFor returns the key needed to read the image, or nil when its
+// encrypted regions are already decrypted
+func discKeyFor(iso *consoles.PS3ISO, files []parsers.ISOFile, opts OrganizeOptions) ([]byte, error) {
+	decrypted, err := iso.IsDecrypted(files)
+	if err != nil {
+		return nil, err
+	}
+	if decrypted {
+		ui.Verbosef("Disc image is not encrypted\n")
+		return nil, nil
+	}
+
+	var key []byte
+	if opts.DiscKey != nil {
+		if key, err = opts.DiscKey(iso.Path, iso.TitleID); err != nil {
+			return nil, fmt.Errorf("looking up disc key: %w", err)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("disc image %s is encrypted and no disc key is known for %s: import one with 'dkey import' or place a .dkey/.ird file next to the image", iso.Path, iso.TitleID)
+	}
+
+	ok, err := iso.CheckKey(files, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("disc key for %s does not decrypt %s", iso.TitleID, iso.Path)
+	}
+	ui.Verbosef("Decrypting disc with the key for %s\n", iso.TitleID)
+	return key, nil
+}
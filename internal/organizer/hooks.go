@@ -0,0 +1,66 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// Hook statuses passed to hook commands in the STATUS environment variable
+const (
+	HookStatusPending = "pending" // Pre-hook: the game is about to be organized
+	HookStatusSuccess = "success" // Post-hook: the game was organized successfully
+	HookStatusFailed  = "failed"  // Post-hook: organizing the game failed
+	HookStatusSkipped = "skipped" // Progress only: the game was skipped by the collision policy, no hooks run
+)
+
+// hookContext holds the values exposed to a hook command
+type hookContext struct {
+	SourcePath string
+	TargetPath string
+	GameInfo   *common.GameInfo
+	Status     string
+	Err        error
+}
+
+// environ returns the hook environment variables appended to the current environment
+func (c hookContext) environ() []string {
+	env := append(os.Environ(),
+		"SOURCE_PATH="+c.SourcePath,
+		"TARGET_PATH="+c.TargetPath,
+		"STATUS="+c.Status,
+	)
+	if c.GameInfo != nil {
+		env = append(env,
+			"TITLE="+c.GameInfo.Title,
+			"GAME_ID="+c.GameInfo.GameID,
+			"CONSOLE="+c.GameInfo.Console,
+		)
+	}
+	if c.Err != nil {
+		env = append(env, "ERROR="+c.Err.Error())
+	}
+	return env
+}
+
+// runHook runs a user hook command through the system shell with the game's
+// details in its environment. An empty command is a no-op.
+func runHook(command, name string, ctx hookContext) error {
+	if command == "" {
+		return nil
+	}
+
+	ui.Verbosef("Running %s-hook: %s\n", name, command)
+
+	cmd := common.ShellCommand(command)
+	cmd.Env = ctx.environ()
+	cmd.Stdout = ui.Output()
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s-hook %q failed: %w", name, command, err)
+	}
+	return nil
+}
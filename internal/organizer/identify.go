@@ -0,0 +1,325 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// Guess is one identity a source might have
+type Guess struct {
+	Console    string   `json:"console"`
+	Title      string   `json:"title,omitempty"`
+	GameID     string   `json:"game_id,omitempty"`
+	Confidence float64  `json:"confidence"` // 0.0 to 1.0
+	Evidence   []string `json:"evidence"`
+	Path       string   `json:"path,omitempty"` // What to organize to take this identity, "" when it can't be as is
+}
+
+// IdentifyOptions tune Identify
+type IdentifyOptions struct {
+	Limits detect.SearchLimits
+	// TitleFor names a game ID found only in a file name, e.g. from the compatibility
+	// database. Optional.
+	TitleFor func(gameID string) string
+}
+
+// nameGameID finds a PS3 title ID in a file name, also written as BLUS-30001 or blus_30001
+var nameGameID = regexp.MustCompile(`(?i)(?:^|[^A-Za-z])([A-Za-z]{4})[-_ ]?(\d{5})(?:$|\D)`)
+
+// Identify tries every registered console handler on an unnamed folder, archive or disc
+// image and returns what it might be, most likely first. Games found inside it, a title
+// ID in its name and what its contents look like all count; nothing is extracted.
+func Identify(sourcePath string, opts IdentifyOptions) ([]Guess, error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var guesses []Guess
+	switch {
+	case info.IsDir():
+		guesses = identifyFolder(sourcePath, opts.Limits)
+	case IsDiscImage(sourcePath):
+		guesses = identifyDiscImage(sourcePath)
+	case IsInputArchive(sourcePath):
+		guesses = identifyArchive(sourcePath)
+	}
+	guesses = addNameGuess(guesses, sourcePath, opts)
+
+	if len(guesses) == 0 && info.IsDir() {
+		if explanation := detect.Explain(sourcePath, opts.Limits); explanation.Suggestion != "" {
+			guesses = append(guesses, Guess{
+				Console:    detect.Unknown.String(),
+				Confidence: 0.1,
+				Evidence:   []string{explanation.Suggestion},
+			})
+		}
+	}
+
+	sort.SliceStable(guesses, func(i, j int) bool {
+		return guesses[i].Confidence > guesses[j].Confidence
+	})
+	return guesses, nil
+}
+
+// identifyFolder reads every game folder below dir through the handler of the console
+// its indicator belongs to. An indicator in the wrong case is reported, but can't be
+// organized until it is renamed.
+func identifyFolder(dir string, limits detect.SearchLimits) []Guess {
+	limits = limits.WithDefaults()
+	registry := consoles.NewRegistry()
+	var guesses []Guess
+	entries := 0
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		entries++
+		rel, _ := filepath.Rel(dir, p)
+		depth := 0
+		if rel != "." {
+			depth = len(strings.Split(rel, string(filepath.Separator)))
+		}
+		if depth > limits.MaxDepth || entries > limits.MaxEntries {
+			return filepath.SkipDir
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		for indicator, console := range detect.ConsoleIndicators {
+			if !strings.EqualFold(d.Name(), indicator) {
+				continue
+			}
+			handler, err := registry.GetHandler(console)
+			if err != nil {
+				continue
+			}
+			gameRoot := filepath.Dir(p)
+			if d.Name() != indicator {
+				guesses = append(guesses, wrongCaseGuess(gameRoot, d.Name(), indicator, handler))
+				return filepath.SkipDir
+			}
+			gameInfo, err := handler.ExtractGameInfo(gameRoot, false)
+			if err != nil {
+				guesses = append(guesses, Guess{
+					Console:    handler.GetConsoleDisplayName(),
+					Confidence: 0.3,
+					Evidence:   []string{fmt.Sprintf("%s in %s, but: %v", indicator, gameRoot, err)},
+				})
+				return filepath.SkipDir
+			}
+			evidence := fmt.Sprintf("%s read by the %s handler", paramSFOName(gameRoot, dir), handler.GetConsoleDisplayName())
+			if gameInfo.Unverified {
+				evidence = fmt.Sprintf("%s named from its folder by the %s handler", gameRoot, handler.GetConsoleDisplayName())
+			}
+			guesses = append(guesses, gameGuess(gameInfo, handler, evidence, gameRoot))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return guesses
+}
+
+// paramSFOName is where a game folder's PARAM.SFO is, relative to the source
+func paramSFOName(gameRoot, source string) string {
+	rel, err := filepath.Rel(source, filepath.Join(gameRoot, "PS3_GAME", "PARAM.SFO"))
+	if err != nil {
+		return "PS3_GAME/PARAM.SFO"
+	}
+	return filepath.ToSlash(rel)
+}
+
+// gameGuess turns a game read from its metadata into a guess, trusted less when its ID
+// doesn't pass the handler's check or was only guessed from the folder name
+func gameGuess(gameInfo *common.GameInfo, handler common.ConsoleHandler, evidence, organizePath string) Guess {
+	guess := Guess{
+		Console:    handler.GetConsoleDisplayName(),
+		Title:      gameInfo.Title,
+		GameID:     gameInfo.GameID,
+		Confidence: 0.95,
+		Evidence:   []string{evidence},
+		Path:       organizePath,
+	}
+	if err := handler.ValidateGameID(gameInfo); err != nil {
+		problem, _, _ := strings.Cut(err.Error(), "\n")
+		guess.Confidence = 0.6
+		guess.Evidence = append(guess.Evidence, problem)
+	}
+	if gameInfo.Unverified {
+		guess.Confidence = min(guess.Confidence, 0.5)
+		guess.Evidence = append(guess.Evidence, "metadata unverified: PARAM.SFO is missing or unreadable")
+	}
+	return guess
+}
+
+// wrongCaseGuess reads the game behind an indicator whose case is off (ps3_game/), which
+// detection doesn't accept
+func wrongCaseGuess(gameRoot, name, indicator string, handler common.ConsoleHandler) Guess {
+	guess := Guess{
+		Console:    handler.GetConsoleDisplayName(),
+		Confidence: 0.5,
+		Evidence:   []string{fmt.Sprintf("%s in the wrong case in %s; rename it to %s to organize it", name, gameRoot, indicator)},
+	}
+	sfoEntries, _ := os.ReadDir(filepath.Join(gameRoot, name))
+	for _, entry := range sfoEntries {
+		if !strings.EqualFold(entry.Name(), "PARAM.SFO") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(gameRoot, name, entry.Name()))
+		if err != nil {
+			break
+		}
+		if sfo, err := parsers.ParseParamSFO(data); err == nil {
+			guess.Title, guess.GameID = sfo.GetTitle(), sfo.GetTitleID()
+		}
+		break
+	}
+	return guess
+}
+
+// identifyArchive reads the games in an archive from its listing and their PARAM.SFO
+// files, without extracting it
+func identifyArchive(archivePath string) []Guess {
+	entries, err := common.List7zArchive(archivePath)
+	if err != nil {
+		return []Guess{{
+			Console:    detect.Unknown.String(),
+			Confidence: 0.1,
+			Evidence:   []string{fmt.Sprintf("could not list the archive: %v", err)},
+		}}
+	}
+	paths := make([]string, len(entries))
+	for i, entry := range entries {
+		paths[i] = entry.Path
+	}
+	detection := detect.DetectConsoleFromListing(paths)
+	if !detection.IsValid() {
+		return nil
+	}
+	handler, err := consoles.NewRegistry().GetHandler(detection.ConsoleType)
+	if err != nil {
+		return nil
+	}
+
+	var guesses []Guess
+	seen := make(map[string]bool)
+	for _, entry := range paths {
+		entry = path.Clean(entry)
+		if !strings.HasSuffix(entry, "PS3_GAME/PARAM.SFO") || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		data, err := common.Read7zFile(archivePath, entry)
+		if err != nil {
+			continue
+		}
+		sfo, err := parsers.ParseParamSFO(data)
+		if err != nil {
+			continue
+		}
+		gameInfo := &common.GameInfo{Title: sfo.GetTitle(), GameID: sfo.GetTitleID(), Source: archivePath}
+		guesses = append(guesses, gameGuess(gameInfo, handler, entry+" in the archive", archivePath))
+	}
+	if len(guesses) > 1 {
+		// Organizing the archive takes its first game only
+		for i := 1; i < len(guesses); i++ {
+			guesses[i].Path = ""
+			guesses[i].Confidence -= 0.1
+			guesses[i].Evidence = append(guesses[i].Evidence, "not the archive's first game; extract it to organize this one")
+		}
+	}
+	if len(guesses) == 0 {
+		guesses = append(guesses, Guess{
+			Console:    handler.GetConsoleDisplayName(),
+			Confidence: detection.Confidence / 2,
+			Evidence:   []string{fmt.Sprintf("%s in the archive, but no readable PARAM.SFO", detection.IndicatorFound)},
+			Path:       archivePath,
+		})
+	}
+	return guesses
+}
+
+// identifyDiscImage reads a disc image's header; encrypted images don't give their title
+func identifyDiscImage(imagePath string) []Guess {
+	iso, err := consoles.OpenPS3ISO(imagePath)
+	if err != nil {
+		if platform := detect.ISOPlatform(imagePath); platform != "" {
+			return []Guess{{
+				Console:    detect.Unknown.String(),
+				Confidence: 0.4,
+				Evidence:   []string{fmt.Sprintf("looks like %s, which isn't supported yet", platform)},
+			}}
+		}
+		return nil
+	}
+	return []Guess{{
+		Console:    detect.PS3.String(),
+		GameID:     iso.TitleID,
+		Confidence: 0.85,
+		Evidence:   []string{"PS3 disc image header"},
+		Path:       imagePath,
+	}}
+}
+
+// addNameGuess looks for a title ID in the source's name. One matching a game found in
+// the source raises that guess; any other is a guess of its own, titled by TitleFor or
+// from the rest of the name.
+func addNameGuess(guesses []Guess, sourcePath string, opts IdentifyOptions) []Guess {
+	name := filepath.Base(sourcePath)
+	if info, err := os.Stat(sourcePath); err == nil && !info.IsDir() {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	match := nameGameID.FindStringSubmatch(name)
+	if match == nil {
+		return guesses
+	}
+	gameID := strings.ToUpper(match[1] + match[2])
+
+	for i := range guesses {
+		if guesses[i].GameID == gameID {
+			guesses[i].Confidence = min(guesses[i].Confidence+0.04, 0.99)
+			guesses[i].Evidence = append(guesses[i].Evidence, "the name has the same title ID")
+			if guesses[i].Title == "" && opts.TitleFor != nil {
+				guesses[i].Title = opts.TitleFor(gameID)
+			}
+			return guesses
+		}
+	}
+
+	guess := Guess{
+		Console:    detect.PS3.String(),
+		GameID:     gameID,
+		Confidence: 0.35,
+		Evidence:   []string{fmt.Sprintf("title ID %s in the name", gameID)},
+	}
+	if opts.TitleFor != nil {
+		guess.Title = opts.TitleFor(gameID)
+	}
+	if guess.Title == "" {
+		guess.Title = titleFromName(name, match[0])
+	} else {
+		guess.Confidence += 0.1
+		guess.Evidence = append(guess.Evidence, "title from the compatibility database")
+	}
+	return append(guesses, guess)
+}
+
+// titleFromName cleans a name down to a title: no ID, bracketed tags or dots and
+// underscores between words
+func titleFromName(name, idMatch string) string {
+	name = strings.Replace(name, strings.Trim(idMatch, "-_ .[]()"), "", 1)
+	name = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`).ReplaceAllString(name, "")
+	name = strings.NewReplacer(".", " ", "_", " ").Replace(name)
+	return strings.Trim(strings.Join(strings.Fields(name), " "), " -")
+}
@@ -0,0 +1,158 @@
+package organizer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// DefaultSettle is how long --torrent-safe waits for sources to stop changing when no
+// --settle is given
+const DefaultSettle = 30 * time.Second
+
+// ErrStillWriting means a source was left out of the batch because its files were still
+// changing, e.g. a download that isn't complete yet
+var ErrStillWriting = errors.New("source is still being written")
+
+// isStillWriting reports whether err is a source skipped because it is still being written
+func isStillWriting(err error) bool {
+	return errors.Is(err, ErrStillWriting)
+}
+
+// partialSuffixes are the extensions download clients give files that are not complete
+// (qBittorrent, uTorrent, BitTorrent, Firefox, Chrome, aria2 and others)
+var partialSuffixes = []string{".part", ".partial", ".!qb", ".!ut", ".!bt", ".crdownload", ".aria2", ".incomplete"}
+
+// sourceState is what settling compares between two looks at a source
+type sourceState struct {
+	Files   int
+	Size    int64
+	ModTime time.Time
+	Partial string // First file with a download client's incomplete extension
+}
+
+// readSourceState counts the files under a source (or the source file itself), their
+// total size and the latest modification time
+func readSourceState(source string) (sourceState, error) {
+	var state sourceState
+	err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(state.ModTime) {
+			state.ModTime = info.ModTime()
+		}
+		if d.IsDir() {
+			return nil
+		}
+		state.Files++
+		state.Size += info.Size()
+		if state.Partial == "" && hasPartialSuffix(d.Name()) {
+			state.Partial = path
+		}
+		return nil
+	})
+	return state, err
+}
+
+func hasPartialSuffix(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// intake guards a batch's sources: with TorrentSafe nothing is written inside them, and
+// sources holding incomplete downloads or, with Settle, whose files changed since the
+// start of the batch are skipped
+type intake struct {
+	opts   OrganizeOptions
+	before map[string]sourceState
+}
+
+// newIntake looks at every local source once and waits opts.Settle, so a source still
+// being written shows a difference by the time it is organized. It returns nil when
+// neither --torrent-safe nor --settle is in effect.
+func newIntake(sources []string, opts OrganizeOptions) *intake {
+	if !opts.TorrentSafe && opts.Settle <= 0 {
+		return nil
+	}
+	in := &intake{opts: opts, before: make(map[string]sourceState)}
+	if opts.Settle <= 0 {
+		return in
+	}
+
+	for _, source := range sources {
+		if sftp.IsURL(source) {
+			continue
+		}
+		// Unreadable sources are left for OrganizeGame to report
+		if state, err := readSourceState(source); err == nil {
+			in.before[source] = state
+		}
+	}
+	if len(in.before) == 0 {
+		return in
+	}
+
+	ui.Infof("Waiting %s for sources to stop changing...\n", opts.Settle)
+	deadline := time.Now().Add(opts.Settle)
+	for time.Now().Before(deadline) && !common.StopRequested() {
+		time.Sleep(min(time.Second, time.Until(deadline)))
+	}
+	return in
+}
+
+// check refuses a source that is still being written, or whose organizing would write
+// inside it. Nil intakes allow everything.
+func (in *intake) check(source string) error {
+	if in == nil || sftp.IsURL(source) {
+		return nil
+	}
+
+	if in.opts.TorrentSafe {
+		outputs := append([]string{in.opts.OutputDir, in.opts.Staging}, in.opts.MirrorDirs...)
+		for _, output := range outputs {
+			if output != "" && !sftp.IsURL(output) && isWithin(output, source) {
+				return common.Permanent(fmt.Errorf("output %s is inside the source; --torrent-safe never writes inside sources", output))
+			}
+		}
+	}
+
+	now, err := readSourceState(source)
+	if err != nil {
+		return nil
+	}
+	if now.Partial != "" {
+		return fmt.Errorf("%w: %s is incomplete", ErrStillWriting, now.Partial)
+	}
+	if before, ok := in.before[source]; ok && now != before {
+		return fmt.Errorf("%w: its files changed after the batch started", ErrStillWriting)
+	}
+	return nil
+}
+
+// isWithin reports whether path is dir or below it
+func isWithin(path, dir string) bool {
+	absPath, err1 := filepath.Abs(path)
+	absDir, err2 := filepath.Abs(dir)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
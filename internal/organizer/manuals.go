@@ -0,0 +1,52 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// manualExtensions are document formats that are always treated as manuals
+var manualExtensions = map[string]bool{
+	".pdf": true, ".epub": true, ".djvu": true, ".chm": true, ".cbz": true,
+}
+
+// readmePattern matches text documents named like manuals or readmes
+var readmePattern = regexp.MustCompile(`(?i)^(readme|read me|read_me|manual|instructions|guide)[^/]*(\.(txt|md|html?|rtf|docx?|odt))?$`)
+
+// isManualFile reports whether a file name looks like a manual or readme
+func isManualFile(name string) bool {
+	return manualExtensions[strings.ToLower(filepath.Ext(name))] || readmePattern.MatchString(name)
+}
+
+// findManuals returns the manuals and readmes at the top of the game folder root, and
+// those beside it when the game sits in a wrapping folder (sourcePath)
+func findManuals(sourcePath, root string) []bundledFile {
+	var manuals []bundledFile
+	for _, path := range topLevelManuals(root) {
+		manuals = append(manuals, bundledFile{path: path, inGame: true, kind: bundledManual})
+	}
+
+	if filepath.Clean(sourcePath) == filepath.Clean(root) {
+		return manuals
+	}
+	for _, path := range topLevelManuals(sourcePath) {
+		manuals = append(manuals, bundledFile{path: path, kind: bundledManual})
+	}
+	return manuals
+}
+
+func topLevelManuals(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && isManualFile(entry.Name()) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths
+}
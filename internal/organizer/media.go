@@ -0,0 +1,159 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// Categories of the GameInfo reported for themes and media bundles
+const (
+	CategoryTheme = "theme"
+	CategoryMedia = "media"
+)
+
+// isTheme reports whether path is a PS3 theme file
+func isTheme(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && common.IsThemeFile(path)
+}
+
+// mediaBundle reports whether path is a media file, or a folder holding nothing but
+// media files and themes (junk files aside). themes is true when every file in the
+// folder is a theme, making it a theme pack.
+func mediaBundle(path string, junk []string) (ok, themes bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, false
+	}
+	if info.Mode().IsRegular() {
+		return common.IsMediaFile(path), false
+	}
+	if !info.IsDir() {
+		return false, false
+	}
+
+	files, media, other := 0, 0, false
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || common.IsJunkFile(info.Name(), junk) {
+			return err
+		}
+		switch {
+		case common.IsThemeFile(file):
+			files++
+		case common.IsMediaFile(file):
+			files++
+			media++
+		default:
+			other = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil || other || files == 0 {
+		return false, false
+	}
+	return true, media == 0
+}
+
+// themeName returns the name a theme carries, or one made from its file name
+func themeName(path string) (string, error) {
+	info, err := parsers.ReadP3TInfo(path)
+	if err != nil {
+		return "", fmt.Errorf("reading theme %s: %w", filepath.Base(path), err)
+	}
+	if info.Name != "" {
+		return info.Name, nil
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.TrimSpace(strings.ReplaceAll(name, "_", " ")), nil
+}
+
+// organizeTheme places a theme file in the Themes section as "<name>.p3t"
+func organizeTheme(sourcePath string, opts OrganizeOptions) (*GameResult, error) {
+	name, err := themeName(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	target := filepath.Join(opts.OutputDir, common.ThemesSection, common.SanitizeFilename(name)+common.ThemeExtension)
+	gameInfo := &common.GameInfo{Title: name, Console: "PlayStation 3", Category: CategoryTheme, Source: sourcePath}
+	if err := placeContent(sourcePath, target, gameInfo, opts); err != nil {
+		return nil, err
+	}
+	ui.Successf("Successfully organized theme:\n")
+	ui.Infof("  Name: %s\n", name)
+	ui.Infof("  Output: %s\n", target)
+	return &GameResult{SourcePath: sourcePath, TargetPath: target, GameInfo: gameInfo}, nil
+}
+
+// organizeMedia places a media file or bundle in the Media section under its own name,
+// or a folder of themes in the Themes section
+func organizeMedia(sourcePath string, themes bool, opts OrganizeOptions) (*GameResult, error) {
+	section, category := common.MediaSection, CategoryMedia
+	if themes {
+		section, category = common.ThemesSection, CategoryTheme
+	}
+	name := filepath.Base(filepath.Clean(sourcePath))
+	target := filepath.Join(opts.OutputDir, section, common.SanitizeFilename(name))
+	gameInfo := &common.GameInfo{Title: name, Console: "PlayStation 3", Category: category, Source: sourcePath}
+	if err := placeContent(sourcePath, target, gameInfo, opts); err != nil {
+		return nil, err
+	}
+	ui.Successf("Successfully organized %s:\n", category)
+	ui.Infof("  Name: %s\n", name)
+	ui.Infof("  Output: %s\n", target)
+	return &GameResult{SourcePath: sourcePath, TargetPath: target, GameInfo: gameInfo}, nil
+}
+
+// placeContent copies a file or folder to target as is, or moves it with --move, after
+// running the pre-hook. An existing target is only replaced with --force.
+func placeContent(sourcePath, target string, gameInfo *common.GameInfo, opts OrganizeOptions) error {
+	if _, err := os.Stat(target); err == nil {
+		if !opts.Force {
+			return fmt.Errorf("%w: %s (use --force to replace it)", common.ErrTargetExists, target)
+		}
+	}
+	preHook := hookContext{SourcePath: sourcePath, TargetPath: target, GameInfo: gameInfo, Status: HookStatusPending}
+	if err := runHook(opts.PreHook, "pre", preHook); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return fmt.Errorf("removing existing %s: %w", target, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(target), err)
+	}
+
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+	if opts.MoveSource && canRename(sourcePath, target) {
+		opts.reportStage(StageMoving)
+		if err := os.Rename(sourcePath, target); err == nil {
+			opts.sourceMoved(sourcePath, target)
+			return nil
+		}
+	}
+	opts.reportStage(StageCopying)
+	err = opts.Retry.Do("Copying "+filepath.Base(sourcePath), func() error {
+		if info.IsDir() {
+			return common.CopyDir(sourcePath, target)
+		}
+		return common.CopyFile(sourcePath, target)
+	}, func() { os.RemoveAll(target) })
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", filepath.Base(sourcePath), err)
+	}
+	if opts.MoveSource {
+		if err := opts.discardSource(sourcePath); err != nil {
+			return fmt.Errorf("removing source: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// MirrorResult describes the outcome of copying an organized game to one mirror destination
+type MirrorResult struct {
+	Destination string // Mirror output directory
+	Path        string // Organized game directory inside the mirror
+	Err         error  // Non-nil if the copy failed
+}
+
+// mirrorPaths returns the mirror copies made of a game, leaving out the failed ones and
+// the sftp:// primary output, which is uploaded to like a mirror
+func (r *GameResult) mirrorPaths() []string {
+	var paths []string
+	for _, mirror := range r.Mirrors {
+		if mirror.Err == nil && mirror.Path != r.TargetPath {
+			paths = append(paths, mirror.Path)
+		}
+	}
+	return paths
+}
+
+// mirrorGame copies an organized game from the primary output to every mirror destination,
+// keeping the same relative path (including any alphabetical bucket)
+func mirrorGame(result *GameResult, opts OrganizeOptions) {
+	if result.InPlace {
+		ui.Warnf("%s was handled in place and is not mirrored\n", result.SourcePath)
+		return
+	}
+
+	relPath, err := filepath.Rel(opts.OutputDir, result.TargetPath)
+	if err != nil {
+		relPath = filepath.Base(result.TargetPath)
+	}
+
+	for _, dest := range opts.MirrorDirs {
+		if sftp.IsURL(dest) {
+			result.Mirrors = append(result.Mirrors, uploadToMirror(result, dest, relPath, opts))
+			continue
+		}
+
+		mirrorPath := filepath.Join(dest, relPath)
+		ui.Verbosef("Mirroring to: %s\n", mirrorPath)
+		opts.reportStage(StageMirroring)
+
+		// A retry only removes what a failed attempt copied, never a mirror that was there
+		copying := false
+		err := opts.Retry.Do("Mirroring to "+dest, func() error {
+			if err := prepareMirror(mirrorPath, opts.Force); err != nil {
+				return err
+			}
+			copying = true
+			return copyToMirror(result.TargetPath, mirrorPath)
+		}, func() {
+			if copying {
+				os.RemoveAll(mirrorPath)
+			}
+		})
+		if err != nil {
+			ui.Errorf("Error mirroring %s to %s: %v\n", result.GameInfo.Title, dest, err)
+		} else if owner := opts.Owners.For(dest); owner != nil {
+			if err := owner.Apply(dest, mirrorPath); err != nil {
+				ui.Warnf("%s: %v\n", mirrorPath, err)
+			}
+		}
+
+		result.Mirrors = append(result.Mirrors, MirrorResult{
+			Destination: dest,
+			Path:        mirrorPath,
+			Err:         err,
+		})
+	}
+}
+
+// prepareMirror makes way for a mirror copy: an existing one is an error that retrying
+// can't fix, unless force removes it
+func prepareMirror(dest string, force bool) error {
+	if _, err := os.Stat(dest); err == nil {
+		if !force {
+			return common.Permanent(fmt.Errorf("%w: %s (use --force to overwrite)", common.ErrTargetExists, dest))
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return fmt.Errorf("removing existing mirror directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyToMirror copies an organized game directory to a mirror path
+func copyToMirror(src, dest string) error {
+	// Themes are organized as single files
+	if info, err := os.Stat(src); err == nil && info.Mode().IsRegular() {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		return common.CopyFile(src, dest)
+	}
+	return common.CopyDir(src, dest)
+}
+
+// printDestinationSummary prints per-destination status when mirrors are configured
+// and returns the number of failed mirror copies
+func printDestinationSummary(results []*GameResult, opts OrganizeOptions) int {
+	if len(opts.MirrorDirs) == 0 {
+		return 0
+	}
+
+	primary := 0
+	for _, result := range results {
+		if !result.InPlace {
+			primary++
+		}
+	}
+
+	ui.Infof("Destinations:\n")
+	if opts.remoteOutput == "" {
+		ui.Infof("  %s (primary): %d games\n", opts.OutputDir, primary)
+	}
+
+	totalFailures := 0
+	for _, dest := range opts.MirrorDirs {
+		succeeded, failed := 0, 0
+		for _, result := range results {
+			for _, mirror := range result.Mirrors {
+				if mirror.Destination != dest {
+					continue
+				}
+				if mirror.Err != nil {
+					failed++
+				} else {
+					succeeded++
+				}
+			}
+		}
+		totalFailures += failed
+
+		kind := "mirror"
+		if dest == opts.remoteOutput {
+			kind = "primary"
+		}
+		if failed > 0 {
+			ui.Infof("  %s (%s): %d games, %d failed\n", dest, kind, succeeded, failed)
+		} else {
+			ui.Infof("  %s (%s): %d games\n", dest, kind, succeeded)
+		}
+	}
+
+	return totalFailures
+}
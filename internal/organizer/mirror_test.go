@@ -0,0 +1,58 @@
+package organizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// TestMirrorKeepsExistingCopy checks that an existing mirror copy fails the mirror
+// without being retried, removed or overwritten, and that --force replaces it
+func TestMirrorKeepsExistingCopy(t *testing.T) {
+	output, mirror := t.TempDir(), t.TempDir()
+	game := filepath.Join(output, "Game [BLUS30001]")
+	if err := os.MkdirAll(filepath.Join(game, "game"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(game, "game", "EBOOT.BIN"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(mirror, "Game [BLUS30001]", "extra.txt")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := OrganizeOptions{
+		OutputDir:  output,
+		MirrorDirs: []string{mirror},
+		Retry:      common.RetryPolicy{Retries: 3, Delay: time.Millisecond},
+	}
+	result := &GameResult{TargetPath: game, GameInfo: &common.GameInfo{Title: "Game"}}
+	mirrorGame(result, opts)
+	if len(result.Mirrors) != 1 || !errors.Is(result.Mirrors[0].Err, common.ErrTargetExists) {
+		t.Fatalf("mirror results = %+v, want ErrTargetExists", result.Mirrors)
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "keep me" {
+		t.Fatalf("existing mirror copy changed: %q, %v", data, err)
+	}
+
+	opts.Force = true
+	result = &GameResult{TargetPath: game, GameInfo: &common.GameInfo{Title: "Game"}}
+	mirrorGame(result, opts)
+	if err := result.Mirrors[0].Err; err != nil {
+		t.Fatalf("forced mirror: %v", err)
+	}
+	if _, err := os.Stat(existing); !os.IsNotExist(err) {
+		t.Fatalf("forced mirror kept the old copy's files: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(mirror, "Game [BLUS30001]", "game", "EBOOT.BIN")); err != nil || string(data) != "new" {
+		t.Fatalf("forced mirror copy: %q, %v", data, err)
+	}
+}
@@ -1,13 +1,20 @@
 package organizer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/NeilGraham/rom-organizer/internal/common"
 	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/dedup"
+	"github.com/NeilGraham/rom-organizer/internal/delta"
 	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
 )
 
 // GameFormat represents the desired format for the organized game
@@ -22,77 +29,304 @@ const (
 	Decompressed
 )
 
+// OrganizeBy controls how organized games are grouped inside the output directory
+type OrganizeBy int
+
+const (
+	// FlatLayout places every game directly in the output directory
+	FlatLayout OrganizeBy = iota
+	// FirstLetter places games under A/, B/, ..., 0-9/ subfolders based on their title
+	FirstLetter
+)
+
+// ParseOrganizeBy converts an --organize-by flag value into an OrganizeBy mode
+func ParseOrganizeBy(value string) (OrganizeBy, error) {
+	switch value {
+	case "", "none":
+		return FlatLayout, nil
+	case "first-letter":
+		return FirstLetter, nil
+	default:
+		return FlatLayout, fmt.Errorf("invalid --organize-by value %q (expected none or first-letter)", value)
+	}
+}
+
 // OrganizeOptions holds options for organizing operations
 type OrganizeOptions struct {
 	OutputDir  string
 	Force      bool
-	Verbose    bool
 	MoveSource bool
 	Format     GameFormat
+	OrganizeBy OrganizeBy
+	Layout     common.Layout      // Folder names for updates, DLC and extras (defaults when empty)
+	MirrorDirs []string           // Additional destinations that receive a copy of each organized game
+	PreHook    string             // Shell command run before each game (non-zero exit skips the game)
+	PostHook   string             // Shell command run after each game with its STATUS
+	Retry      common.RetryPolicy // Retries for copy and 7z operations that fail transiently
+	MaxErrors  int                // Abort the batch after this many failed games (0 means never)
+	PAR2       int                // Redundancy percent of PAR2 recovery data for new archives (0 disables)
+	Progress   ProgressFunc       // Receives machine-readable progress events (nil disables)
+
+	// AllowInvalidID organizes games with malformed or placeholder game IDs after a warning
+	AllowInvalidID bool
+
+	// Compression holds archive settings per console short name (e.g. "ps3");
+	// consoles without an entry use common.DefaultArchiveOptions
+	Compression map[string]common.ArchiveOptions
+
+	// Components holds the policies for optional disc folders (PS3_EXTRA, PS3_UPDATE);
+	// those not listed are included in the game
+	Components common.ComponentPolicyMap
+
+	// Dedup links the files of newly decompressed games into the content-addressed
+	// pool in the output directory, storing files shared between games once
+	Dedup bool
+
+	// QuickVerify only compares file names, sizes and PARAM.SFO when verifying the
+	// organized copy before --move deletes the source, instead of every file's hash
+	QuickVerify bool
+
+	// Checksum writes a game.7z.<algorithm> checksum file next to each new archive ("" disables)
+	Checksum common.HashAlgorithm
+
+	// VerifyHash is the hash comparing an organized game/ folder with its source before
+	// --move deletes it (common.DefaultVerifyHash when empty). New archives are always
+	// checked by CRC32, the hash 7z records for each file.
+	VerifyHash common.HashAlgorithm
+
+	// Timings reports how long each stage took per game and the batch throughput
+	Timings bool
+
+	// DiscKey looks up keys for encrypted PS3 disc images (nil refuses encrypted images)
+	DiscKey DiscKeyFunc
+
+	// JunkFiles are file name patterns that don't keep a source directory from being
+	// deleted after --move (common.DefaultJunkFiles when nil)
+	JunkFiles []string
+
+	// TitleRules clean up game titles before they name the organized folder
+	TitleRules common.TitleRules
+
+	// NameLimits shortens titles so folder names and paths stay within them (only the
+	// 255-byte common.DefaultMaxName applies when zero)
+	NameLimits common.NameLimits
+
+	// Homebrew places homebrew and media apps in their own section of the output
+	// directory or marks their folder names (the zero value treats them like games)
+	Homebrew common.HomebrewRules
+
+	// Search bounds how deep and how much of each source detection reads
+	// (detect.MaxSearchDepth and detect.MaxSearchEntries when zero)
+	Search detect.SearchLimits
+
+	// CompressionOverride applies settings pinned for a single game on top of the
+	// console's (nil uses the console settings for every game)
+	CompressionOverride CompressionOverrideFunc
+
+	// OnCollision is what happens when a different game already has the folder name a
+	// game would be organized into (common.CollisionFail when empty)
+	OnCollision common.CollisionPolicy
+
+	// Owners gives each organized game and its mirror copies to a user and group,
+	// chosen by the output folder (the zero value leaves ownership alone)
+	Owners common.OwnerRules
+
+	// TorrentSafe treats sources as read-only intake, e.g. payloads a torrent client is
+	// still seeding: nothing is written inside them and already organized sources are not
+	// converted in place (--move is refused before a batch starts)
+	TorrentSafe bool
+
+	// Settle skips sources whose files changed within this long before they are
+	// organized (0 disables). With Settle or TorrentSafe, sources holding a download
+	// client's incomplete files are skipped too.
+	Settle time.Duration
+
+	// NoChecksumFiles skips checking sources against the .sfv and .md5 files found in
+	// them (and in scene releases) before they are organized
+	NoChecksumFiles bool
+
+	// Staging is the local folder games are organized in before they are uploaded when
+	// OutputDir is an sftp:// location (a temporary folder when empty)
+	Staging string
+
+	// NewGameFormat is the format sources that aren't organized yet are given when Format
+	// is KeepOriginal; the zero value, KeepOriginal, makes them decompressed
+	NewGameFormat GameFormat
+
+	// TitleOverride and GameIDOverride replace the title and ID read from a game's
+	// metadata, for a single source whose PARAM.SFO is missing or wrong. Together they
+	// name a game whose metadata can't be read at all; either marks it unverified.
+	TitleOverride  string
+	GameIDOverride string
+
+	// NoTrash deletes the sources --move is done with instead of keeping them in the
+	// TrashDir next to them for undo
+	NoTrash bool
+
+	// remoteOutput is the sftp:// OutputDir of the batch, which stageRemoteOutput
+	// replaces with the staging folder and uploads to like a mirror
+	remoteOutput string
+
+	// moves records where --move put the current game's source paths
+	moves *sourceMoves
+}
+
+// CompressionOverrideFunc returns the archive settings for a game, given the
+// settings of its console
+type CompressionOverrideFunc func(gameID string, archive common.ArchiveOptions) (common.ArchiveOptions, error)
+
+// snapshotHash returns the hash a source snapshot records: CRC32 when it is checked
+// against a new archive, otherwise the verification hash
+func (o OrganizeOptions) snapshotHash() common.HashAlgorithm {
+	if o.Format == Compressed {
+		return common.HashCRC32
+	}
+	if o.VerifyHash == "" {
+		return common.DefaultVerifyHash
+	}
+	return o.VerifyHash
+}
+
+// junkFiles returns the junk file patterns, falling back to the defaults
+func (o OrganizeOptions) junkFiles() []string {
+	if o.JunkFiles == nil {
+		return common.DefaultJunkFiles
+	}
+	return o.JunkFiles
+}
+
+// GameResult describes the outcome of organizing a single game
+type GameResult struct {
+	SourcePath string           // Source path given by the user
+	TargetPath string           // Organized game directory in the primary output
+	GameInfo   *common.GameInfo // Game information used for naming
+	InPlace    bool             // True when an already organized directory was handled in place
+	Mirrors    []MirrorResult   // Per-destination results for mirror outputs
+
+	// Compression holds the archive size stats when the game was compressed in this run
+	Compression *common.CompressionStats
+
+	// Timings holds the time spent per stage when OrganizeOptions.Timings is set
+	Timings *StageTimings
 }
 
 // OrganizeGame organizes a ROM game according to the specified format
-func OrganizeGame(sourcePath string, opts OrganizeOptions) error {
+func OrganizeGame(sourcePath string, opts OrganizeOptions) (*GameResult, error) {
 	formatName := map[GameFormat]string{
 		KeepOriginal: "keep original",
 		Compressed:   "compress",
 		Decompressed: "decompress",
 	}[opts.Format]
 
-	if opts.Verbose {
-		fmt.Printf("Organizing ROM game from: %s\n", sourcePath)
-		fmt.Printf("Output directory: %s\n", opts.OutputDir)
-		fmt.Printf("Target format: %s\n", formatName)
+	ui.Verbosef("Organizing ROM game from: %s\n", sourcePath)
+	ui.Verbosef("Output directory: %s\n", opts.OutputDir)
+	ui.Verbosef("Target format: %s\n", formatName)
+
+	opts.reportStage(StageDetecting)
+
+	// Themes and media aren't games; they go to their own library sections as they are
+	if isTheme(sourcePath) {
+		return organizeTheme(sourcePath, opts)
+	}
+	if ok, themes := mediaBundle(sourcePath, opts.junkFiles()); ok {
+		return organizeMedia(sourcePath, themes, opts)
+	}
+
+	// Remote sources, disc images and drives are read into a folder first
+	if sftp.IsURL(sourcePath) {
+		return organizeRemoteSource(sourcePath, opts)
+	}
+	if IsDiscImage(sourcePath) {
+		return organizeDiscImage(sourcePath, opts)
+	}
+	if IsInputArchive(sourcePath) {
+		return organizeArchive(sourcePath, opts)
+	}
+	if release := findSceneRelease(sourcePath, opts); release != nil {
+		return organizeSceneRelease(release, opts)
 	}
 
 	// First check if this is an organized directory
-	organizedInfo, err := common.DetectOrganizedDirectory(sourcePath, opts.Verbose)
+	organizedInfo, err := common.DetectOrganizedDirectory(sourcePath, opts.Layout, ui.IsVerbose())
 	if err != nil {
-		return fmt.Errorf("checking if directory is organized: %w", err)
+		return nil, fmt.Errorf("checking if directory is organized: %w", err)
 	}
 
 	if organizedInfo.IsOrganized {
 		return handleOrganizedDirectory(sourcePath, organizedInfo, opts)
 	}
 
+	// Downloads that came with .sfv or .md5 files are checked before anything is copied
+	if !opts.NoChecksumFiles {
+		if err := verifyChecksumFiles(sourceChecksumFiles(sourcePath), opts); err != nil {
+			return nil, err
+		}
+	}
+
 	// Use detection system to identify console type and extract game info
-	detection, err := detect.DetectConsole(sourcePath)
+	detection, err := detect.DetectConsoleWithLimits(sourcePath, opts.Search)
 	if err != nil {
-		return fmt.Errorf("detecting console type: %w", err)
+		return nil, fmt.Errorf("detecting console type: %w", err)
 	}
 
 	if detection.ConsoleType == detect.Unknown {
-		if len(detection.AmbiguousFiles) > 0 {
-			return fmt.Errorf("found ambiguous files but console-specific organization not yet implemented - detected %d ambiguous files", len(detection.AmbiguousFiles))
-		}
-		return fmt.Errorf("unable to determine console type for: %s", sourcePath)
+		return nil, notDetected(sourcePath, detection, opts.Search)
 	}
 
 	// Get console handler
 	registry := consoles.NewRegistry()
 	if !registry.IsSupported(detection.ConsoleType) {
-		return fmt.Errorf("organization for %s is not yet implemented", detection.ConsoleType.String())
+		return nil, fmt.Errorf("organization for %s is not yet implemented", detection.ConsoleType.String())
 	}
 
 	handler, err := registry.GetHandler(detection.ConsoleType)
 	if err != nil {
-		return fmt.Errorf("getting console handler: %w", err)
+		return nil, fmt.Errorf("getting console handler: %w", err)
 	}
 
-	if opts.Verbose {
-		fmt.Printf("Console Detection Results:\n")
-		fmt.Printf("Console Type: %s (confidence: %.2f)\n", detection.ConsoleType.String(), detection.Confidence)
-		fmt.Printf("Game Path: %s\n", detection.GamePath)
-		fmt.Printf("Indicator: %s\n", detection.IndicatorFound)
+	ui.Verbosef("Console Detection Results:\n")
+	ui.Verbosef("Console Type: %s (confidence: %.2f)\n", detection.ConsoleType.String(), detection.Confidence)
+	ui.Verbosef("Game Path: %s\n", detection.GamePath)
+	ui.Verbosef("Indicator: %s\n", detection.IndicatorFound)
+	for _, candidate := range detection.Candidates {
+		ui.Verbosef("Candidate: %s\n", candidate)
 	}
 
 	return organizeGame(sourcePath, detection, handler, opts)
 }
 
+// notDetected prints what detection looked at in a source it found no game in, and
+// returns the error for it, ending in the suggestion when there is one
+func notDetected(sourcePath string, detection *detect.DetectionResult, limits detect.SearchLimits) error {
+	explanation := detect.Explain(sourcePath, limits)
+	ui.Warnf("No game found in %s:\n%s", sourcePath, explanation)
+
+	err := fmt.Errorf("%w for: %s", common.ErrNotDetected, sourcePath)
+	if len(detection.AmbiguousFiles) > 0 {
+		err = fmt.Errorf("%w: found %d ambiguous files but console-specific organization not yet implemented", err, len(detection.AmbiguousFiles))
+	}
+	if explanation.Suggestion != "" {
+		err = fmt.Errorf("%w (%s)", err, explanation.Suggestion)
+	}
+	return err
+}
+
 // handleOrganizedDirectory handles organization of already organized directories
-func handleOrganizedDirectory(sourcePath string, organizedInfo *common.OrganizedDirInfo, opts OrganizeOptions) error {
+func handleOrganizedDirectory(sourcePath string, organizedInfo *common.OrganizedDirInfo, opts OrganizeOptions) (*GameResult, error) {
+	result := &GameResult{
+		SourcePath: sourcePath,
+		TargetPath: sourcePath,
+		GameInfo:   organizedInfo.GameInfo,
+		InPlace:    true,
+	}
+
+	preHook := hookContext{SourcePath: sourcePath, TargetPath: sourcePath, GameInfo: organizedInfo.GameInfo, Status: HookStatusPending}
+	if err := runHook(opts.PreHook, "pre", preHook); err != nil {
+		return nil, err
+	}
+
 	if opts.MoveSource {
-		fmt.Printf("⚠️  WARNING: --move flag ignored for already organized directories (safety measure)\n")
+		ui.Warnf("--move flag ignored for already organized directories (safety measure)\n")
 	}
 
 	// Determine current format
@@ -124,358 +358,944 @@ func handleOrganizedDirectory(sourcePath string, organizedInfo *common.Organized
 			format = "Decompressed (game/ folder)"
 		}
 
-		if opts.Verbose {
-			fmt.Printf("Source is already in the desired format\n")
-		}
+		ui.Verbosef("Source is already in the desired format\n")
 
-		fmt.Printf("Directory is already organized:\n")
-		fmt.Printf("  Title: %s\n", organizedInfo.GameInfo.Title)
-		fmt.Printf("  Game ID: %s\n", organizedInfo.GameInfo.GameID)
-		fmt.Printf("  Console: %s\n", organizedInfo.GameInfo.Console)
-		fmt.Printf("  Format: %s\n", format)
-		fmt.Printf("  Location: %s\n", sourcePath)
-		return nil
+		ui.Infof("Directory is already organized:\n")
+		ui.Infof("  Title: %s\n", organizedInfo.GameInfo.Title)
+		ui.Infof("  Game ID: %s\n", organizedInfo.GameInfo.GameID)
+		ui.Infof("  Console: %s\n", organizedInfo.GameInfo.Console)
+		ui.Infof("  Format: %s\n", format)
+		ui.Infof("  Location: %s\n", sourcePath)
+		return result, nil
+	}
+
+	// Archives and images unpacked into the output are fair game
+	if opts.TorrentSafe && !isWithin(sourcePath, opts.OutputDir) {
+		return nil, common.Permanent(fmt.Errorf("converting %s in place would change the source, which --torrent-safe doesn't allow", sourcePath))
+	}
+
+	if opts.Dedup && opts.Format == Decompressed {
+		ui.Warnf("--dedup only applies to newly organized games; run 'dedup add' on the library to pool this one\n")
 	}
 
 	// Conversion needed
-	return convertOrganizedDirectory(sourcePath, organizedInfo, opts)
+	compression, err := convertOrganizedDirectory(sourcePath, organizedInfo, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Compression = compression
+	return result, nil
+}
+
+// ConvertsInPlace reports whether organizing sourcePath with opts converts an already
+// organized game where it is, deleting its game/ folder or game.7z
+func ConvertsInPlace(sourcePath string, opts OrganizeOptions) bool {
+	if opts.Format == KeepOriginal {
+		return false
+	}
+	if opts.TorrentSafe && !isWithin(sourcePath, opts.OutputDir) {
+		return false
+	}
+	organizedInfo, err := common.DetectOrganizedDirectory(sourcePath, opts.Layout, false)
+	if err != nil || !organizedInfo.IsOrganized {
+		return false
+	}
+	if opts.Format == Compressed {
+		return organizedInfo.HasDecompressed
+	}
+	return organizedInfo.HasCompressed
 }
 
 // convertOrganizedDirectory converts an organized directory between formats
-func convertOrganizedDirectory(sourcePath string, organizedInfo *common.OrganizedDirInfo, opts OrganizeOptions) error {
+func convertOrganizedDirectory(sourcePath string, organizedInfo *common.OrganizedDirInfo, opts OrganizeOptions) (*common.CompressionStats, error) {
 	game7zPath := filepath.Join(sourcePath, "game.7z")
 	gameDir := filepath.Join(sourcePath, "game")
+	var compression *common.CompressionStats
 
 	switch opts.Format {
 	case Compressed:
 		// Convert to compressed format
 		if organizedInfo.HasDecompressed {
-			if opts.Verbose {
-				fmt.Printf("Converting decompressed game/ folder to compressed game.7z...\n")
-				fmt.Printf("Compressing contents of: %s\n", gameDir)
-			}
+			ui.Verbosef("Converting decompressed game/ folder to compressed game.7z...\n")
+			ui.Verbosef("Compressing contents of: %s\n", gameDir)
+
+			originalSize, _ := common.DirSize(gameDir)
 
 			// Create the 7z archive from the game folder contents
-			if err := common.Create7zArchive(gameDir, game7zPath); err != nil {
-				return fmt.Errorf("creating game.7z archive: %w", err)
+			archive, err := archiveOptionsFor(organizedInfo.GameInfo, opts)
+			if err != nil {
+				return nil, err
+			}
+			opts.reportStage(StageCompressing)
+			err = opts.Retry.Do("Creating game.7z", func() error {
+				return common.Create7zArchive(gameDir, game7zPath, archive)
+			}, func() { os.Remove(game7zPath) })
+			if err != nil {
+				return nil, fmt.Errorf("creating game.7z archive: %w", err)
+			}
+			if err := embedArchiveMetadata(game7zPath, organizedInfo.GameInfo); err != nil {
+				return nil, err
+			}
+			compression = measureCompression(originalSize, game7zPath)
+			if err := writeArchiveSidecars(game7zPath, opts); err != nil {
+				return nil, err
 			}
 
 			// Remove the game/ folder if compression was successful
-			if opts.Verbose {
-				fmt.Printf("Removing original game/ folder...\n")
-			}
+			ui.Verbosef("Removing original game/ folder...\n")
 			if err := os.RemoveAll(gameDir); err != nil {
-				fmt.Printf("Warning: could not remove original game/ folder: %v\n", err)
+				ui.Warnf("could not remove original game/ folder: %v\n", err)
+			} else if err := dedup.RemoveManifest(sourcePath); err != nil {
+				ui.Warnf("%v\n", err)
 			}
 
-			fmt.Printf("Successfully converted to compressed format:\n")
-			fmt.Printf("  Title: %s\n", organizedInfo.GameInfo.Title)
-			fmt.Printf("  Game ID: %s\n", organizedInfo.GameInfo.GameID)
-			fmt.Printf("  Console: %s\n", organizedInfo.GameInfo.Console)
-			fmt.Printf("  Format: Compressed (game.7z)\n")
-			fmt.Printf("  Location: %s\n", sourcePath)
+			ui.Successf("Successfully converted to compressed format:\n")
+			ui.Infof("  Title: %s\n", organizedInfo.GameInfo.Title)
+			ui.Infof("  Game ID: %s\n", organizedInfo.GameInfo.GameID)
+			ui.Infof("  Console: %s\n", organizedInfo.GameInfo.Console)
+			ui.Infof("  Format: Compressed (game.7z)\n")
+			if compression != nil {
+				ui.Infof("  Compression: %s\n", compression)
+			}
+			ui.Infof("  Location: %s\n", sourcePath)
 		}
 
 	case Decompressed:
 		// Convert to decompressed format
 		if organizedInfo.HasCompressed {
-			if opts.Verbose {
-				fmt.Printf("Extracting compressed game.7z to game/ folder...\n")
-				fmt.Printf("Extracting to: %s\n", gameDir)
-			}
+			ui.Verbosef("Extracting compressed game.7z to game/ folder...\n")
+			ui.Verbosef("Extracting to: %s\n", gameDir)
 
 			// Ensure the game directory doesn't exist to prevent conflicts
 			if _, err := os.Stat(gameDir); err == nil {
-				if opts.Verbose {
-					fmt.Printf("Removing existing game/ folder before extraction...\n")
-				}
+				ui.Verbosef("Removing existing game/ folder before extraction...\n")
 				if err := os.RemoveAll(gameDir); err != nil {
-					return fmt.Errorf("removing existing game/ folder: %w", err)
+					return nil, fmt.Errorf("removing existing game/ folder: %w", err)
 				}
 			}
 
 			// Create the game directory
 			if err := os.MkdirAll(gameDir, 0755); err != nil {
-				return fmt.Errorf("creating game/ directory: %w", err)
+				return nil, fmt.Errorf("creating game/ directory: %w", err)
 			}
 
-			// Extract the 7z archive to the game folder
-			if err := common.Extract7zArchive(game7zPath, gameDir); err != nil {
-				return fmt.Errorf("extracting game.7z archive: %w", err)
+			// Extract the 7z archive to the game folder, applying any deltas on top
+			opts.reportStage(StageExtracting)
+			err := opts.Retry.Do("Extracting game.7z", func() error {
+				return delta.Materialize(sourcePath, gameDir, "")
+			}, nil)
+			if err != nil {
+				return nil, fmt.Errorf("extracting game.7z archive: %w", err)
 			}
 
 			// Remove the game.7z file if extraction was successful
-			if opts.Verbose {
-				fmt.Printf("Removing original game.7z file...\n")
-			}
+			ui.Verbosef("Removing original game.7z file...\n")
 			if err := os.Remove(game7zPath); err != nil {
-				fmt.Printf("Warning: could not remove original game.7z file: %v\n", err)
+				ui.Warnf("could not remove original game.7z file: %v\n", err)
+			}
+			if err := common.RemoveArchiveSidecars(game7zPath); err != nil {
+				ui.Warnf("could not remove checksum/PAR2 files: %v\n", err)
+			}
+			if err := os.RemoveAll(filepath.Join(sourcePath, delta.Dir)); err != nil {
+				ui.Warnf("could not remove %s folder: %v\n", delta.Dir, err)
 			}
 
-			fmt.Printf("Successfully converted to decompressed format:\n")
-			fmt.Printf("  Title: %s\n", organizedInfo.GameInfo.Title)
-			fmt.Printf("  Game ID: %s\n", organizedInfo.GameInfo.GameID)
-			fmt.Printf("  Console: %s\n", organizedInfo.GameInfo.Console)
-			fmt.Printf("  Format: Decompressed (game/ folder)\n")
-			fmt.Printf("  Location: %s\n", sourcePath)
+			ui.Successf("Successfully converted to decompressed format:\n")
+			ui.Infof("  Title: %s\n", organizedInfo.GameInfo.Title)
+			ui.Infof("  Game ID: %s\n", organizedInfo.GameInfo.GameID)
+			ui.Infof("  Console: %s\n", organizedInfo.GameInfo.Console)
+			ui.Infof("  Format: Decompressed (game/ folder)\n")
+			ui.Infof("  Location: %s\n", sourcePath)
 		}
 	}
 
-	return nil
+	return compression, nil
 }
 
 // organizeGame handles organization of games for any console using the appropriate handler
-func organizeGame(sourcePath string, detection *detect.DetectionResult, handler common.ConsoleHandler, opts OrganizeOptions) error {
+func organizeGame(sourcePath string, detection *detect.DetectionResult, handler common.ConsoleHandler, opts OrganizeOptions) (_ *GameResult, err error) {
+	if opts.Format == KeepOriginal {
+		opts.Format = opts.NewGameFormat
+	}
+
 	// Extract game information using the console handler
-	gameInfo, err := handler.ExtractGameInfo(detection.GamePath, opts.Verbose)
+	gameInfo, err := handler.ExtractGameInfo(detection.GamePath, ui.IsVerbose())
+	if err != nil && opts.TitleOverride != "" && opts.GameIDOverride != "" {
+		ui.Verbosef("%v\n", err)
+		ui.Warnf("The game info of %s can't be read; using the given title and ID (metadata unverified)\n", sourcePath)
+		gameInfo, err = &common.GameInfo{Console: handler.GetConsoleDisplayName(), Source: detection.GamePath}, nil
+	}
 	if err != nil {
-		return fmt.Errorf("extracting game info: %w", err)
+		return nil, fmt.Errorf("extracting game info: %w", err)
+	}
+	if opts.TitleOverride != "" {
+		gameInfo.Title, gameInfo.Unverified = opts.TitleOverride, true
+	}
+	if opts.GameIDOverride != "" {
+		gameInfo.GameID, gameInfo.Unverified = opts.GameIDOverride, true
 	}
 
-	// Generate target path
-	targetPath := common.GenerateTargetPath(gameInfo, opts.OutputDir)
+	targetPath, err := prepareTarget(sourcePath, gameInfo, handler, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the source before anything is written, so read-only media or locked files
+	// are reported up front rather than halfway through a copy
+	root := detection.GamePath
+	if opts.Format == Compressed {
+		root = gameInfo.Source
+	}
+	if err := common.CheckReadable(root); err != nil {
+		return nil, err
+	}
+	if opts.MoveSource && !(common.IsWritableDir(root) && common.IsWritableDir(filepath.Dir(root))) {
+		ui.Warnf("%s is on read-only media or not writable; --move disabled, copying instead\n", root)
+		opts.MoveSource = false
+	}
 
-	if opts.Verbose {
-		fmt.Printf("Game Title: %s\n", gameInfo.Title)
-		fmt.Printf("Game ID: %s\n", gameInfo.GameID)
-		fmt.Printf("Console: %s\n", gameInfo.Console)
-		fmt.Printf("Target directory: %s\n", targetPath)
+	// Record the source before anything is written, so a move can be verified before deleting it.
+	// A folder moved within one file system is renamed instead and needs no snapshot.
+	var snapshot *sourceSnapshot
+	if opts.MoveSource && !(opts.Format != Compressed && canRename(root, opts.OutputDir)) {
+		if snapshot, err = takeSnapshot(root, opts.QuickVerify, opts.snapshotHash(), opts.Format != Compressed); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update and DLC packages and manuals bundled with the game go to their own folders
+	bundled := append(findBundledPackages(sourcePath, root, gameInfo.GameID), findManuals(sourcePath, root)...)
+	// Optional disc folders may be left out or kept beside the game
+	components, err := findComponents(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil && opts.Format == Compressed {
+		for _, file := range bundled {
+			snapshot.forget(file.path)
+		}
+		for _, component := range components {
+			snapshot.forgetDir(component.path)
+		}
+	}
+
+	// A target created for this game is removed again if the game is interrupted, as
+	// long as the source is still there
+	if _, statErr := os.Stat(targetPath); os.IsNotExist(statErr) {
+		defer func() {
+			if _, statErr := os.Stat(root); errors.Is(err, common.ErrInterrupted) && statErr == nil {
+				ui.Warnf("Removing the partial output %s\n", targetPath)
+				if removeErr := os.RemoveAll(targetPath); removeErr != nil {
+					ui.Warnf("Could not remove %s: %v\n", targetPath, removeErr)
+				}
+			}
+		}()
 	}
 
 	// Create target directory structure
-	if err := common.CreateTargetStructure(targetPath, opts.Force); err != nil {
-		return err
+	if err := common.CreateTargetStructure(targetPath, opts.Layout, opts.Force); err != nil {
+		return nil, err
 	}
 
 	// Clean up existing game files if force is enabled
 	if opts.Force {
-		game7zPath := filepath.Join(targetPath, "game.7z")
-		gameDir := filepath.Join(targetPath, "game")
-
-		if _, err := os.Stat(game7zPath); err == nil {
-			if opts.Verbose {
-				fmt.Printf("Removing existing game.7z file...\n")
-			}
-			if err := os.Remove(game7zPath); err != nil {
-				return fmt.Errorf("removing existing game.7z: %w", err)
-			}
+		if err := clearExistingGame(targetPath); err != nil {
+			return nil, err
+		}
+		if err := os.RemoveAll(filepath.Join(targetPath, opts.Layout.WithDefaults().DiscDir)); err != nil {
+			return nil, fmt.Errorf("removing existing disc components: %w", err)
 		}
+	}
 
-		if _, err := os.Stat(gameDir); err == nil {
-			if opts.Verbose {
-				fmt.Printf("Removing existing game/ directory...\n")
-			}
-			if err := os.RemoveAll(gameDir); err != nil {
-				return fmt.Errorf("removing existing game/ directory: %w", err)
+	// Files outside the game folder, or left out of game.7z, are placed first so a
+	// --move can clean up the source once the game itself is organized
+	for _, file := range bundled {
+		if !file.inGame || opts.Format == Compressed {
+			if err := placeBundledFile(file, targetPath, opts); err != nil {
+				return nil, err
 			}
 		}
 	}
 
 	// Organize the game files based on the desired format
+	var compression *common.CompressionStats
 	switch opts.Format {
 	case KeepOriginal, Decompressed:
-		return organizeGameDecompressed(sourcePath, detection, targetPath, gameInfo, opts)
+		var copied common.FileHashes
+		copied, err = organizeGameDecompressed(sourcePath, detection, targetPath, gameInfo, snapshot, opts)
+		if err == nil {
+			err = separateComponents(components, targetPath, opts)
+		}
+		for _, file := range bundled {
+			if err == nil && file.inGame {
+				err = relocateBundledFile(file, targetPath, opts)
+			}
+		}
+		if err == nil && opts.Dedup {
+			err = poolGame(targetPath, opts.OutputDir, copied)
+		}
 	case Compressed:
-		return organizeGameCompressed(sourcePath, detection, targetPath, gameInfo, opts)
+		compression, err = organizeGameCompressed(sourcePath, detection, targetPath, gameInfo, snapshot, bundled, components, opts)
 	default:
-		return fmt.Errorf("unsupported format: %v", opts.Format)
+		err = fmt.Errorf("unsupported format: %v", opts.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := writeComponentRecord(components, targetPath, gameInfo, opts); err != nil {
+		return nil, err
+	}
+
+	return &GameResult{
+		SourcePath:  sourcePath,
+		TargetPath:  targetPath,
+		GameInfo:    gameInfo,
+		Compression: compression,
+	}, nil
+}
+
+// prepareTarget validates the game ID, cleans up the title and returns the organized
+// folder for a game, after checking it for collisions and running the pre-hook
+func prepareTarget(sourcePath string, gameInfo *common.GameInfo, handler common.ConsoleHandler, opts OrganizeOptions) (string, error) {
+	if err := handler.ValidateGameID(gameInfo); err != nil {
+		if !opts.AllowInvalidID {
+			return "", fmt.Errorf("%w\n(use --allow-invalid-id to organize it anyway)", err)
+		}
+		ui.Warnf("%v\n", err)
+	}
+	rawTitle := gameInfo.Title
+	if title := opts.TitleRules.Apply(gameInfo.Title); title != gameInfo.Title {
+		ui.Verbosef("Title cleaned up: %q -> %q\n", gameInfo.Title, title)
+		gameInfo.Title = title
+	}
+	if gameInfo.Homebrew {
+		ui.Verbosef("Homebrew (category %s)\n", gameInfo.Category)
+		gameInfo.Title = opts.Homebrew.Prefix + gameInfo.Title
+	}
+
+	// Generate target path, inside the homebrew section or an alphabetical bucket if requested
+	outputDir := opts.OutputDir
+	switch {
+	case gameInfo.Homebrew && opts.Homebrew.Folder != "":
+		outputDir = filepath.Join(outputDir, opts.Homebrew.Folder)
+	case opts.OrganizeBy == FirstLetter:
+		outputDir = filepath.Join(outputDir, common.FirstLetterBucket(gameInfo.Title))
+	}
+	name, err := opts.NameLimits.GameFolderName(absPath(outputDir), gameInfo.Title, gameInfo.GameID)
+	if err != nil {
+		return "", err
+	}
+	if name != common.GameFolderName(gameInfo.Title, gameInfo.GameID) {
+		ui.Verbosef("Title shortened to fit the name length limits: %s\n", name)
+	}
+	targetPath, err := resolveCollision(filepath.Join(outputDir, name), gameInfo, rawTitle, opts)
+	if err != nil {
+		return "", err
+	}
+
+	ui.Verbosef("Game Title: %s\n", gameInfo.Title)
+	ui.Verbosef("Game ID: %s\n", gameInfo.GameID)
+	ui.Verbosef("Console: %s\n", gameInfo.Console)
+	ui.Verbosef("Target directory: %s\n", targetPath)
+
+	preHook := hookContext{SourcePath: sourcePath, TargetPath: targetPath, GameInfo: gameInfo, Status: HookStatusPending}
+	if err := runHook(opts.PreHook, "pre", preHook); err != nil {
+		return "", err
+	}
+	return targetPath, nil
+}
+
+// clearExistingGame removes the game.7z (with its checksum, PAR2 and delta files) or game/
+// folder of an organized directory before --force replaces it
+func clearExistingGame(targetPath string) error {
+	game7zPath := filepath.Join(targetPath, "game.7z")
+	gameDir := filepath.Join(targetPath, "game")
+
+	if _, err := os.Stat(game7zPath); err == nil {
+		ui.Verbosef("Removing existing game.7z file...\n")
+		if err := os.Remove(game7zPath); err != nil {
+			return fmt.Errorf("removing existing game.7z: %w", err)
+		}
+		if err := common.RemoveArchiveSidecars(game7zPath); err != nil {
+			return fmt.Errorf("removing existing checksum/PAR2 files: %w", err)
+		}
+		if err := os.RemoveAll(filepath.Join(targetPath, delta.Dir)); err != nil {
+			return fmt.Errorf("removing existing deltas: %w", err)
+		}
+	}
+
+	if _, err := os.Stat(gameDir); err == nil {
+		ui.Verbosef("Removing existing game/ directory...\n")
+		if err := os.RemoveAll(gameDir); err != nil {
+			return fmt.Errorf("removing existing game/ directory: %w", err)
+		}
+		if err := dedup.RemoveManifest(targetPath); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // organizeGameDecompressed organizes a game in decompressed format (game/ folder)
-func organizeGameDecompressed(sourcePath string, detection *detect.DetectionResult, targetPath string, gameInfo *common.GameInfo, opts OrganizeOptions) error {
+func organizeGameDecompressed(sourcePath string, detection *detect.DetectionResult, targetPath string, gameInfo *common.GameInfo, snapshot *sourceSnapshot, opts OrganizeOptions) (copied common.FileHashes, err error) {
 	gameDir := filepath.Join(targetPath, "game")
 
 	if opts.MoveSource {
-		if opts.Verbose {
-			fmt.Printf("Moving game files to game/ folder (decompressed format)...\n")
-		}
+		ui.Verbosef("Moving game files to game/ folder (decompressed format)...\n")
 
 		// Move the detected game directory to the target
-		if err := moveGameDirectory(detection.GamePath, gameDir, opts.Verbose); err != nil {
-			return fmt.Errorf("moving game directory: %w", err)
+		opts.reportStage(StageMoving)
+		if copied, err = moveGameDirectory(detection.GamePath, gameDir, snapshot, opts); err != nil {
+			return nil, fmt.Errorf("moving game directory: %w", err)
 		}
 
 		// Handle cleanup of the original source directory
 		if err := cleanupSourceAfterMove(sourcePath, detection.GamePath, opts); err != nil {
-			return fmt.Errorf("cleaning up source directory: %w", err)
+			return nil, fmt.Errorf("cleaning up source directory: %w", err)
 		}
 	} else {
-		if opts.Verbose {
-			fmt.Printf("Copying game files to game/ folder (decompressed format)...\n")
-		}
-
-		// Copy the detected game directory to the target
-		if err := common.CopyDir(detection.GamePath, gameDir); err != nil {
-			return fmt.Errorf("copying game directory: %w", err)
+		ui.Verbosef("Copying game files to game/ folder (decompressed format)...\n")
+
+		// Copy the detected game directory to the target, hashing it for the pool on the way
+		opts.reportStage(StageCopying)
+		err := opts.Retry.Do("Copying game files", func() (err error) {
+			copied, err = common.CopyDirHashed(detection.GamePath, gameDir, copyHashAlgs(nil, opts)...)
+			return err
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("copying game directory: %w", err)
 		}
 	}
 
-	fmt.Printf("Successfully organized %s game:\n", gameInfo.Console)
-	fmt.Printf("  Title: %s\n", gameInfo.Title)
-	fmt.Printf("  Game ID: %s\n", gameInfo.GameID)
-	fmt.Printf("  Console: %s\n", gameInfo.Console)
-	fmt.Printf("  Format: Decompressed (game/ folder)\n")
-	fmt.Printf("  Output: %s\n", targetPath)
+	ui.Successf("Successfully organized %s game:\n", gameInfo.Console)
+	ui.Infof("  Title: %s\n", gameInfo.Title)
+	ui.Infof("  Game ID: %s\n", gameInfo.GameID)
+	ui.Infof("  Console: %s\n", gameInfo.Console)
+	ui.Infof("  Format: Decompressed (game/ folder)\n")
+	ui.Infof("  Output: %s\n", targetPath)
 
-	return nil
+	return copied, nil
 }
 
 // organizeGameCompressed organizes a game in compressed format (game.7z)
-func organizeGameCompressed(sourcePath string, detection *detect.DetectionResult, targetPath string, gameInfo *common.GameInfo, opts OrganizeOptions) error {
+func organizeGameCompressed(sourcePath string, detection *detect.DetectionResult, targetPath string, gameInfo *common.GameInfo, snapshot *sourceSnapshot, bundled []bundledFile, components []discComponent, opts OrganizeOptions) (*common.CompressionStats, error) {
 	game7zPath := filepath.Join(targetPath, "game.7z")
 
-	if opts.Verbose {
-		fmt.Printf("Creating game.7z archive...\n")
+	ui.Verbosef("Creating game.7z archive...\n")
+
+	originalSize, _ := common.DirSize(gameInfo.Source)
+
+	// Bundled files still in the game folder and disc components kept out of the game
+	// are placed separately, not archived
+	files, err := archiveFiles(gameInfo.Source, bundled, components)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := common.Create7zArchive(gameInfo.Source, game7zPath); err != nil {
-		return fmt.Errorf("creating game.7z archive: %w", err)
+	archive, err := archiveOptionsFor(gameInfo, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.reportStage(StageCompressing)
+	err = opts.Retry.Do("Creating game.7z", func() error {
+		if files != nil {
+			return common.Create7zArchiveFromList(gameInfo.Source, files, game7zPath, archive)
+		}
+		return common.Create7zArchive(gameInfo.Source, game7zPath, archive)
+	}, func() { os.Remove(game7zPath) })
+	if err != nil {
+		return nil, fmt.Errorf("creating game.7z archive: %w", err)
+	}
+	if err := embedArchiveMetadata(game7zPath, gameInfo); err != nil {
+		return nil, err
+	}
+	if err := archiveComponents(components, targetPath, archive, opts); err != nil {
+		return nil, err
+	}
+	compression := measureCompression(originalSize, game7zPath)
+	if err := writeArchiveSidecars(game7zPath, opts); err != nil {
+		return nil, err
 	}
 
-	// Handle source cleanup if move was requested
+	// Handle source cleanup if move was requested, once the archive matches the source
 	if opts.MoveSource {
+		if snapshot != nil {
+			if err := snapshot.verifyArchive(game7zPath); err != nil {
+				return nil, err
+			}
+		}
 		if err := cleanupSourceAfterMove(sourcePath, detection.GamePath, opts); err != nil {
-			return fmt.Errorf("cleaning up source directory: %w", err)
+			return nil, fmt.Errorf("cleaning up source directory: %w", err)
 		}
 	}
 
-	fmt.Printf("Successfully organized %s game:\n", gameInfo.Console)
-	fmt.Printf("  Title: %s\n", gameInfo.Title)
-	fmt.Printf("  Game ID: %s\n", gameInfo.GameID)
-	fmt.Printf("  Console: %s\n", gameInfo.Console)
-	fmt.Printf("  Format: Compressed (game.7z)\n")
-	fmt.Printf("  Output: %s\n", targetPath)
+	ui.Successf("Successfully organized %s game:\n", gameInfo.Console)
+	ui.Infof("  Title: %s\n", gameInfo.Title)
+	ui.Infof("  Game ID: %s\n", gameInfo.GameID)
+	ui.Infof("  Console: %s\n", gameInfo.Console)
+	ui.Infof("  Format: Compressed (game.7z)\n")
+	if compression != nil {
+		ui.Infof("  Compression: %s\n", compression)
+	}
+	ui.Infof("  Output: %s\n", targetPath)
+
+	return compression, nil
+}
 
+// archiveOptionsFor returns the compression settings for a game: those of its
+// console, with any override pinned for the game applied
+func archiveOptionsFor(gameInfo *common.GameInfo, opts OrganizeOptions) (common.ArchiveOptions, error) {
+	archive := common.DefaultArchiveOptions()
+	if consoleType, err := detect.ParseConsoleType(gameInfo.Console); err == nil {
+		if configured, ok := opts.Compression[consoleType.ShortName()]; ok {
+			archive = configured
+		}
+	}
+	if opts.CompressionOverride != nil {
+		var err error
+		if archive, err = opts.CompressionOverride(gameInfo.GameID, archive); err != nil {
+			return archive, fmt.Errorf("looking up compression override: %w", err)
+		}
+		if err := archive.Validate(); err != nil {
+			return archive, fmt.Errorf("compression override for %s: %w", gameInfo.GameID, err)
+		}
+	}
+
+	details := ""
+	if len(archive.StoreExtensions) > 0 {
+		details += fmt.Sprintf(" (storing %s uncompressed)", strings.Join(archive.StoreExtensions, ", "))
+	}
+	if archive.SolidBlock != "" {
+		details += fmt.Sprintf(" (solid block %s)", archive.SolidBlock)
+	}
+	if archive.Dictionary != "" {
+		details += fmt.Sprintf(" (dictionary %s)", archive.Dictionary)
+	}
+	if archive.Reproducible {
+		details += " (reproducible)"
+	}
+	ui.Verbosef("Compression level: %d%s\n", archive.Level, details)
+	return archive, nil
+}
+
+// embedArchiveMetadata records the game's identity inside a new archive, so a game.7z
+// separated from its folder can still be identified
+func embedArchiveMetadata(archivePath string, gameInfo *common.GameInfo) error {
+	entries, err := common.List7zArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	meta := common.ArchiveMetadata{
+		Title:      gameInfo.Title,
+		GameID:     gameInfo.GameID,
+		Console:    gameInfo.Console,
+		AppVersion: gameInfo.Version,
+		Organizer:  common.Version,
+		SourceHash: common.ListingHash(entries),
+	}
+	if err := common.WriteArchiveMetadata(archivePath, meta); err != nil {
+		return fmt.Errorf("embedding metadata in game.7z: %w", err)
+	}
 	return nil
 }
 
-// moveGameDirectory moves a game directory from source to destination
-func moveGameDirectory(src, dest string, verbose bool) error {
-	if verbose {
-		fmt.Printf("Moving directory: %s -> %s\n", src, dest)
+// writeArchiveSidecars writes the PAR2 recovery data and checksum file requested for a new archive
+func writeArchiveSidecars(archivePath string, opts OrganizeOptions) error {
+	if opts.PAR2 > 0 {
+		ui.Verbosef("Creating PAR2 recovery data (%d%% redundancy)...\n", opts.PAR2)
+		err := opts.Retry.Do("Creating PAR2 recovery data", func() error {
+			return common.CreatePAR2(archivePath, opts.PAR2)
+		}, func() { common.RemoveArchiveSidecars(archivePath) })
+		if err != nil {
+			return fmt.Errorf("creating PAR2 recovery data: %w", err)
+		}
 	}
 
-	// First copy the directory
-	if err := common.CopyDir(src, dest); err != nil {
-		return fmt.Errorf("copying directory during move: %w", err)
+	if opts.Checksum != "" {
+		digest, err := common.WriteChecksumSidecar(archivePath, opts.Checksum)
+		if err != nil {
+			return err
+		}
+		ui.Verbosef("%s: %s\n", strings.ToUpper(string(opts.Checksum)), digest)
+	}
+
+	return nil
+}
+
+// measureCompression returns the stats for an archive created from originalSize bytes of files,
+// or nil if the archive cannot be measured
+func measureCompression(originalSize int64, archivePath string) *common.CompressionStats {
+	info, err := os.Stat(archivePath)
+	if err != nil || originalSize == 0 {
+		return nil
+	}
+	return &common.CompressionStats{OriginalSize: originalSize, CompressedSize: info.Size()}
+}
+
+// poolGame links a decompressed game's files into the pool of its library
+func poolGame(targetPath, libraryRoot string, copied common.FileHashes) error {
+	pool, err := dedup.OpenPool(filepath.Join(libraryRoot, dedup.DefaultPoolDir))
+	if err != nil {
+		return err
+	}
+	stats, err := pool.AddCopiedGame(targetPath, copied)
+	if err != nil {
+		return err
+	}
+	ui.Infof("  Dedup: %d of %d files already pooled, saved %s\n", stats.Shared, stats.Files, common.FormatSize(stats.Saved))
+	return nil
+}
+
+// moveGameDirectory moves a game directory from source to destination, renaming it when
+// both are on the same file system. Otherwise the source is copied and only removed once
+// the copy matches the snapshot, which is taken first when none is given. The digests
+// taken while copying are returned (nil after a rename).
+func moveGameDirectory(src, dest string, snapshot *sourceSnapshot, opts OrganizeOptions) (common.FileHashes, error) {
+	ui.Verbosef("Moving directory: %s -> %s\n", src, dest)
+
+	if canRename(src, dest) {
+		if err := os.Rename(src, dest); err == nil {
+			opts.sourceMoved(src, dest)
+			ui.Verbosef("Successfully moved directory\n")
+			return nil, nil
+		}
+		ui.Verbosef("Rename failed, copying instead\n")
+	}
+	if snapshot == nil {
+		var err error
+		if snapshot, err = takeSnapshot(src, opts.QuickVerify, opts.snapshotHash(), true); err != nil {
+			return nil, err
+		}
+	}
+
+	// First copy the directory, hashing it for the snapshot on the way
+	var copied common.FileHashes
+	err := opts.Retry.Do("Copying game files", func() (err error) {
+		copied, err = common.CopyDirHashed(src, dest, copyHashAlgs(snapshot, opts)...)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("copying directory during move: %w", err)
+	}
+	snapshot.recordCopy(copied)
+	if err := snapshot.verifyDir(dest); err != nil {
+		return nil, err
 	}
 
 	// Then remove the source
-	if err := os.RemoveAll(src); err != nil {
-		return fmt.Errorf("removing source directory after move: %w", err)
+	if err := opts.discardSource(src); err != nil {
+		return nil, fmt.Errorf("removing source directory after move: %w", err)
 	}
 
-	if verbose {
-		fmt.Printf("Successfully moved directory\n")
+	ui.Verbosef("Successfully moved directory\n")
+
+	return copied, nil
+}
+
+// copyHashAlgs returns the hashes a copy of a game takes as it reads the files: those
+// the snapshot left to it, and the pool's with --dedup, so neither reads them again
+func copyHashAlgs(snapshot *sourceSnapshot, opts OrganizeOptions) []common.HashAlgorithm {
+	algs := snapshot.hashAlgs()
+	if opts.Dedup {
+		algs = append(algs, dedup.HashAlgorithm)
 	}
+	return algs
+}
 
-	return nil
+// canRename reports whether src can be moved to dest by renaming, which is instant
+func canRename(src, dest string) bool {
+	same, err := common.SameDevice(src, dest)
+	return err == nil && same
 }
 
 // cleanupSourceAfterMove handles cleanup of the source directory after moving game files
 func cleanupSourceAfterMove(originalSourcePath, gameSourcePath string, opts OrganizeOptions) error {
 	// Add warning about move flag for organized directories
-	organizedInfo, err := common.DetectOrganizedDirectory(originalSourcePath, false)
+	organizedInfo, err := common.DetectOrganizedDirectory(originalSourcePath, opts.Layout, false)
 	if err == nil && organizedInfo.IsOrganized {
-		fmt.Printf("⚠️  WARNING: --move flag ignored for already organized directories (safety measure)\n")
+		ui.Warnf("--move flag ignored for already organized directories (safety measure)\n")
 		return nil
 	}
 
-	if opts.Verbose {
-		fmt.Printf("Move flag enabled - cleaning up source directory\n")
-	}
+	ui.Verbosef("Move flag enabled - cleaning up source directory\n")
 
 	// If the user specified the exact game directory, remove it
 	if originalSourcePath == gameSourcePath {
-		if opts.Verbose {
-			fmt.Printf("Removing source game directory: %s\n", originalSourcePath)
-		}
-		if err := os.RemoveAll(originalSourcePath); err != nil {
+		ui.Verbosef("Removing source game directory: %s\n", originalSourcePath)
+		if err := opts.discardSource(originalSourcePath); err != nil {
 			return fmt.Errorf("removing source directory: %w", err)
 		}
-		if opts.Verbose {
-			fmt.Printf("Successfully removed source directory\n")
-		}
+		ui.Verbosef("Successfully removed source directory\n")
 		return nil
 	}
 
 	// User specified a parent directory, check if it's now empty or should be cleaned up
-	if opts.Verbose {
-		fmt.Printf("Checking if source directory should be cleaned up: %s\n", originalSourcePath)
-	}
+	ui.Verbosef("Checking if source directory should be cleaned up: %s\n", originalSourcePath)
 
 	// Check if the directory is effectively empty
-	isEmpty, err := common.IsDirEffectivelyEmpty(originalSourcePath)
+	leftovers, err := common.LeftoverFiles(originalSourcePath, opts.junkFiles())
 	if err != nil {
 		return fmt.Errorf("checking if source directory is empty: %w", err)
 	}
 
-	if isEmpty {
+	if len(leftovers) == 0 {
 		// Safe to remove - directory contains no significant files
-		if opts.Verbose {
-			fmt.Printf("Removing empty source directory: %s\n", originalSourcePath)
-		}
-		if err := os.RemoveAll(originalSourcePath); err != nil {
+		ui.Verbosef("Removing empty source directory: %s\n", originalSourcePath)
+		if err := opts.discardSource(originalSourcePath); err != nil {
 			return fmt.Errorf("removing empty source directory: %w", err)
 		}
-		if opts.Verbose {
-			fmt.Printf("Successfully removed empty source directory\n")
-		}
+		ui.Verbosef("Successfully removed empty source directory\n")
 	} else {
 		// Directory contains files - check if force is enabled
 		if opts.Force {
-			if opts.Verbose {
-				fmt.Printf("⚠️  Forcefully removing source directory with remaining files: %s\n", originalSourcePath)
-			}
-			if err := os.RemoveAll(originalSourcePath); err != nil {
+			ui.Verbosef("⚠️  Forcefully removing source directory with remaining files: %s\n", originalSourcePath)
+			if err := opts.discardSource(originalSourcePath); err != nil {
 				return fmt.Errorf("forcefully removing source directory: %w", err)
 			}
-			if opts.Verbose {
-				fmt.Printf("Successfully removed source directory with force\n")
-			}
+			ui.Verbosef("Successfully removed source directory with force\n")
 		} else {
-			fmt.Printf("⚠️  WARNING: Source directory contains remaining files and was not deleted: %s\n", originalSourcePath)
-			fmt.Printf("    Use --force to delete the source directory even with remaining files\n")
+			common.ReportLeftovers(originalSourcePath, leftovers)
 		}
 	}
 
 	return nil
 }
 
+// BatchError reports a batch in which some games failed or could not be mirrored.
+// It unwraps to the per-game errors, so errors.Is finds their categories.
+type BatchError struct {
+	Total          int     // Games in the batch
+	Succeeded      int     // Games organized successfully
+	Skipped        int     // Games not attempted because the batch was aborted
+	MirrorFailures int     // Failed copies to mirror destinations
+	Errs           []error // Per-game errors, prefixed with the source path
+	Interrupted    bool    // The batch was stopped by SIGINT or SIGTERM
+}
+
+func (e *BatchError) Error() string {
+	if e.Interrupted {
+		return fmt.Sprintf("interrupted after %d of %d games", e.Total-e.Skipped, e.Total)
+	}
+	if len(e.Errs) == 0 {
+		return fmt.Sprintf("failed to mirror %d game copies", e.MirrorFailures)
+	}
+	return fmt.Sprintf("failed to process %d out of %d games", len(e.Errs), e.Total)
+}
+
+func (e *BatchError) Unwrap() []error { return e.Errs }
+
 // OrganizeGames organizes multiple ROM games according to the specified format
 func OrganizeGames(sourcePaths []string, opts OrganizeOptions) error {
-	var errors []error
+	if sftp.IsURL(opts.OutputDir) {
+		staged, cleanup, err := stageRemoteOutput(opts)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		opts = staged
+	}
+
+	var errs []error
+	var results []*GameResult
+	var collisions, unsettled []string
 	successCount := 0
 	totalCount := len(sourcePaths)
+	processedCount := 0
+
+	emit := func(event ProgressEvent) {
+		if opts.Progress != nil {
+			event.Total = totalCount
+			opts.Progress(event)
+		}
+	}
+	emit(ProgressEvent{Event: EventBatchStarted})
+	intake := newIntake(sourcePaths, opts)
+	batch := time.Now().Format("20060102-150405")
 
 	for i, sourcePath := range sourcePaths {
-		if opts.Verbose {
-			fmt.Printf("\n=== Processing %d/%d: %s ===\n", i+1, totalCount, sourcePath)
+		if opts.MaxErrors > 0 && len(errs) >= opts.MaxErrors {
+			ui.Errorf("\nAborting batch after %d failed games (limit %d)\n", len(errs), opts.MaxErrors)
+			break
 		}
+		if common.StopRequested() {
+			ui.Warnf("Stopping the batch: interrupted\n")
+			break
+		}
+		processedCount++
 
-		if err := OrganizeGame(sourcePath, opts); err != nil {
-			fmt.Printf("Error processing %s: %v\n", sourcePath, err)
-			errors = append(errors, fmt.Errorf("%s: %w", sourcePath, err))
-		} else {
-			successCount++
+		ui.Verbosef("\n=== Processing %d/%d: %s ===\n", i+1, totalCount, sourcePath)
+
+		// Stage events from inside the organizer carry this game's position in the batch
+		gameOpts := opts
+		gameOpts.moves = newSourceMoves(sourcePath, batch, opts)
+		index, startPercent := i+1, batchPercent(i, totalCount)
+		var timings *StageTimings
+		if opts.Timings {
+			timings = newStageTimings()
+		}
+		if opts.Progress != nil || timings != nil {
+			gameOpts.Progress = func(event ProgressEvent) {
+				if timings != nil && event.Event == EventGameProgress {
+					timings.enter(event.Stage)
+				}
+				if opts.Progress != nil {
+					event.Index, event.Source, event.Percent = index, sourcePath, startPercent
+					emit(event)
+				}
+			}
+		}
+		emit(ProgressEvent{Event: EventGameStarted, Index: index, Source: sourcePath, Percent: startPercent})
+
+		err := intake.check(sourcePath)
+		if isStillWriting(err) {
+			ui.Warnf("Skipping %s: %v\n", sourcePath, err)
+			unsettled = append(unsettled, sourcePath)
+			emit(ProgressEvent{Event: EventGameDone, Index: index, Source: sourcePath, Percent: batchPercent(i+1, totalCount), Status: HookStatusSkipped, Error: err.Error()})
+			continue
+		}
+		var result *GameResult
+		if err == nil {
+			result, err = OrganizeGame(sourcePath, gameOpts)
+		}
+		if isCollisionSkip(err) {
+			ui.Warnf("Skipping %s: %v\n", sourcePath, err)
+			collisions = append(collisions, sourcePath)
+			emit(ProgressEvent{Event: EventGameDone, Index: index, Source: sourcePath, Percent: batchPercent(i+1, totalCount), Status: HookStatusSkipped, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			ui.Errorf("Error processing %s: %v\n", sourcePath, err)
+			errs = append(errs, fmt.Errorf("%s: %w", sourcePath, err))
+			emit(ProgressEvent{Event: EventGameDone, Index: index, Source: sourcePath, Percent: batchPercent(i+1, totalCount), Status: HookStatusFailed, Error: err.Error(), Moved: gameOpts.moves.paths()})
+
+			postHook := hookContext{SourcePath: sourcePath, Status: HookStatusFailed, Err: err}
+			if hookErr := runHook(opts.PostHook, "post", postHook); hookErr != nil {
+				ui.Warnf("%v\n", hookErr)
+			}
+			continue
+		}
+
+		successCount++
+		if owner := opts.Owners.For(opts.OutputDir); owner != nil {
+			if err := owner.Apply(opts.OutputDir, result.TargetPath); err != nil {
+				ui.Warnf("%s: %v\n", result.TargetPath, err)
+			}
+		}
+		if len(opts.MirrorDirs) > 0 {
+			mirrorGame(result, gameOpts)
+		}
+		if opts.remoteOutput != "" {
+			finishRemoteOutput(result, opts)
+		}
+		if timings != nil {
+			timings.finish()
+			timings.Bytes = gameDataSize(result)
+			result.Timings = timings
+			ui.Infof("  Timings: %s\n", timings)
+		}
+		results = append(results, result)
+		emit(ProgressEvent{
+			Event:   EventGameDone,
+			Index:   index,
+			Source:  sourcePath,
+			Target:  result.TargetPath,
+			Title:   result.GameInfo.Title,
+			GameID:  result.GameInfo.GameID,
+			Percent: batchPercent(i+1, totalCount),
+			Status:  HookStatusSuccess,
+
+			Unverified: result.GameInfo.Unverified,
+			InPlace:    result.InPlace,
+			Mirrors:    result.mirrorPaths(),
+			Moved:      gameOpts.moves.paths(),
+		})
+
+		postHook := hookContext{SourcePath: sourcePath, TargetPath: result.TargetPath, GameInfo: result.GameInfo, Status: HookStatusSuccess}
+		if hookErr := runHook(opts.PostHook, "post", postHook); hookErr != nil {
+			ui.Warnf("%v\n", hookErr)
 		}
 	}
 
 	// Print summary
-	fmt.Printf("\n=== Summary ===\n")
-	fmt.Printf("Successfully processed: %d/%d games\n", successCount, totalCount)
-	if len(errors) > 0 {
-		fmt.Printf("Failed: %d games\n", len(errors))
-		for _, err := range errors {
-			fmt.Printf("  - %v\n", err)
+	ui.Infof("\n=== Summary ===\n")
+	if successCount == totalCount {
+		ui.Successf("Successfully processed: %d/%d games\n", successCount, totalCount)
+	} else {
+		ui.Infof("Successfully processed: %d/%d games\n", successCount, totalCount)
+	}
+	printCompressionSummary(results)
+	printTimingsSummary(results)
+	mirrorFailures := printDestinationSummary(results, opts)
+	interrupted := common.StopRequested()
+	if skipped := totalCount - processedCount; skipped > 0 && interrupted {
+		ui.Warnf("Skipped: %d games (interrupted)\n", skipped)
+	} else if skipped > 0 {
+		ui.Errorf("Skipped: %d games (batch aborted)\n", skipped)
+	}
+	if len(collisions) > 0 {
+		ui.Warnf("Skipped: %d games (folder name taken by a different game)\n", len(collisions))
+		for _, source := range collisions {
+			ui.Warnf("  - %s\n", source)
+		}
+	}
+	if len(unsettled) > 0 {
+		ui.Warnf("Skipped: %d games (still being written; run again later)\n", len(unsettled))
+		for _, source := range unsettled {
+			ui.Warnf("  - %s\n", source)
+		}
+	}
+	if len(errs) > 0 {
+		ui.Errorf("Failed: %d games\n", len(errs))
+		for _, err := range errs {
+			ui.Errorf("  - %v\n", err)
+		}
+	}
+	emit(ProgressEvent{
+		Event:     EventBatchSummary,
+		Percent:   100,
+		Succeeded: successCount,
+		Failed:    len(errs),
+		Skipped:   totalCount - processedCount,
+	})
+
+	if len(errs) > 0 || mirrorFailures > 0 || interrupted {
+		return &BatchError{
+			Total:          totalCount,
+			Succeeded:      successCount,
+			Skipped:        totalCount - processedCount,
+			MirrorFailures: mirrorFailures,
+			Errs:           errs,
+			Interrupted:    interrupted,
 		}
-		return fmt.Errorf("failed to process %d out of %d games", len(errors), totalCount)
 	}
 
 	return nil
 }
 
+// batchPercent returns the completion percentage after done of total games
+func batchPercent(done, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(done) * 100 / float64(total)
+}
+
+// printCompressionSummary prints the cumulative space saved by games compressed in this batch
+func printCompressionSummary(results []*GameResult) {
+	var total common.CompressionStats
+	compressed := 0
+	for _, result := range results {
+		if result.Compression != nil {
+			total = total.Add(*result.Compression)
+			compressed++
+		}
+	}
+
+	if compressed > 0 {
+		ui.Infof("Compressed: %d games, %s\n", compressed, total)
+	}
+}
+
 // PackageGames packages multiple games into compressed format (legacy compatibility)
 func PackageGames(sourcePaths []string, opts OrganizeOptions) error {
 	opts.Format = Compressed
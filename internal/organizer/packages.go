@@ -0,0 +1,63 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+)
+
+// updateNamePattern matches the app/patch version suffix of update package names
+// (e.g. UP0001-BLUS30490_00-GTAIVPATCH000001-A0106-V0100-PE.pkg)
+var updateNamePattern = regexp.MustCompile(`(?i)-A\d{4}-V\d{4}|patch|update`)
+
+// findBundledPackages returns the .pkg files at the top of the game folder root, and
+// those beside it in sourcePath whose name or content ID carries the game's ID
+func findBundledPackages(sourcePath, root, gameID string) []bundledFile {
+	var packages []bundledFile
+	for _, path := range topLevelPackages(root) {
+		packages = append(packages, newBundledPackage(path, true))
+	}
+
+	if filepath.Clean(sourcePath) == filepath.Clean(root) {
+		return packages
+	}
+	for _, path := range topLevelPackages(sourcePath) {
+		if strings.Contains(strings.ToUpper(filepath.Base(path)), gameID) {
+			packages = append(packages, newBundledPackage(path, false))
+			continue
+		}
+		if header, err := parsers.ReadPKGHeader(path); err == nil && header.TitleID() == gameID {
+			packages = append(packages, newBundledPackage(path, false))
+		}
+	}
+	return packages
+}
+
+func topLevelPackages(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.EqualFold(filepath.Ext(entry.Name()), ".pkg") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths
+}
+
+func newBundledPackage(path string, inGame bool) bundledFile {
+	name := filepath.Base(path)
+	if header, err := parsers.ReadPKGHeader(path); err == nil {
+		name += " " + header.ContentID
+	}
+	kind := bundledDLC
+	if updateNamePattern.MatchString(name) {
+		kind = bundledUpdate
+	}
+	return bundledFile{path: path, inGame: inGame, kind: kind}
+}
@@ -0,0 +1,67 @@
+package organizer
+
+// Progress event names
+const (
+	EventBatchStarted = "batch_started"
+	EventGameStarted  = "game_started"
+	EventGameProgress = "game_progress"
+	EventGameDone     = "game_done"
+	EventBatchSummary = "batch_summary"
+)
+
+// Stages reported in game_progress events
+const (
+	StageDetecting   = "detecting"
+	StageReading     = "reading"
+	StageCopying     = "copying"
+	StageMoving      = "moving"
+	StageCompressing = "compressing"
+	StageExtracting  = "extracting"
+	StageMirroring   = "mirroring"
+	StageDownloading = "downloading"
+	StageUploading   = "uploading"
+	StageVerifying   = "verifying"
+)
+
+// ProgressEvent describes one step of a batch for machine-readable progress output
+type ProgressEvent struct {
+	Event   string  `json:"event"`
+	Index   int     `json:"index,omitempty"` // 1-based position of the game in the batch
+	Total   int     `json:"total"`           // Games in the batch
+	Percent float64 `json:"percent"`         // Batch completion, 0 to 100
+	Source  string  `json:"source,omitempty"`
+	Target  string  `json:"target,omitempty"`
+	Title   string  `json:"title,omitempty"`
+	GameID  string  `json:"gameId,omitempty"`
+	Stage   string  `json:"stage,omitempty"`
+	Status  string  `json:"status,omitempty"` // success, failed or skipped for game_done
+	Error   string  `json:"error,omitempty"`
+
+	// Unverified is set on game_done when the game's title and ID weren't read from its
+	// metadata (see common.GameInfo.Unverified)
+	Unverified bool `json:"unverified,omitempty"`
+
+	// InPlace is set on game_done when an already organized game was handled where it is,
+	// so the batch created no folder for it
+	InPlace bool `json:"inPlace,omitempty"`
+
+	// Mirrors are the mirror copies made of the game, and Moved the source paths --move
+	// renamed, in order, for game_done
+	Mirrors []string    `json:"mirrors,omitempty"`
+	Moved   []MovedPath `json:"moved,omitempty"`
+
+	// Batch summary counts
+	Succeeded int `json:"succeeded,omitempty"`
+	Failed    int `json:"failed,omitempty"`
+	Skipped   int `json:"skipped,omitempty"`
+}
+
+// ProgressFunc receives progress events as a batch runs
+type ProgressFunc func(ProgressEvent)
+
+// reportStage sends a game_progress event for the game being organized, if progress is enabled
+func (o OrganizeOptions) reportStage(stage string) {
+	if o.Progress != nil {
+		o.Progress(ProgressEvent{Event: EventGameProgress, Stage: stage})
+	}
+}
@@ -0,0 +1,148 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/sftp"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// organizeRemoteSource streams an sftp:// source (a game folder or an archive) into a
+// temporary folder next to the output and organizes it from there. The remote copy is
+// never changed, so --move is refused.
+func organizeRemoteSource(sourceURL string, opts OrganizeOptions) (*GameResult, error) {
+	if opts.MoveSource {
+		return nil, common.Permanent(fmt.Errorf("--move is not supported for sftp:// sources; the remote copy is left as it is"))
+	}
+	loc, err := sftp.Parse(sourceURL)
+	if err != nil {
+		return nil, common.Permanent(err)
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(opts.OutputDir, ".sftp-download-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ui.Infof("Downloading %s...\n", loc)
+	opts.reportStage(StageDownloading)
+	var localPath string
+	err = opts.Retry.Do("Downloading "+loc.String(), func() error {
+		localPath, err = sftp.Download(loc, tempDir)
+		return err
+	}, func() { clearDir(tempDir) })
+	if err != nil {
+		return nil, fmt.Errorf("downloading source: %w", err)
+	}
+
+	result, err := OrganizeGame(localPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.SourcePath = sourceURL
+	return result, nil
+}
+
+// clearDir removes everything inside dir, keeping dir itself
+func clearDir(dir string) {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(dir, entry.Name()))
+	}
+}
+
+// uploadToMirror streams an organized game to an sftp:// destination, keeping the same
+// relative path. Without --force an existing game there is checked for before anything
+// is sent.
+func uploadToMirror(result *GameResult, dest, relPath string, opts OrganizeOptions) MirrorResult {
+	mirror := MirrorResult{Destination: dest}
+	loc, err := sftp.Parse(dest)
+	if err != nil {
+		mirror.Err = err
+		return mirror
+	}
+	target := loc.Join(filepath.ToSlash(relPath))
+	mirror.Path = target.String()
+	ui.Verbosef("Uploading to: %s\n", target)
+	opts.reportStage(StageUploading)
+
+	mirror.Err = opts.Retry.Do("Uploading to "+dest, func() error {
+		if !opts.Force {
+			exists, _, err := sftp.Stat(target)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return common.Permanent(fmt.Errorf("%w: %s (use --force to overwrite)", common.ErrTargetExists, target))
+			}
+		}
+		return sftp.Upload(result.TargetPath, loc.Join(path.Dir(filepath.ToSlash(relPath))), opts.Force)
+	}, nil)
+	if mirror.Err != nil {
+		ui.Errorf("Error uploading %s to %s: %v\n", result.GameInfo.Title, dest, mirror.Err)
+	}
+	return mirror
+}
+
+// stageRemoteOutput prepares a batch whose output is an sftp:// location: games are
+// organized in a local staging folder and uploaded like a mirror, then removed from
+// staging. It returns the options to organize with and a function removing the
+// staging folder if this created it and nothing was left behind.
+func stageRemoteOutput(opts OrganizeOptions) (OrganizeOptions, func(), error) {
+	if _, err := sftp.Parse(opts.OutputDir); err != nil {
+		return opts, nil, err
+	}
+	for _, dest := range opts.MirrorDirs {
+		if dest == opts.OutputDir {
+			return opts, nil, fmt.Errorf("%s is given as an output twice", dest)
+		}
+	}
+
+	staging, cleanup := opts.Staging, func() {}
+	if staging == "" {
+		dir, err := os.MkdirTemp("", "rom-organizer-staging-*")
+		if err != nil {
+			return opts, nil, fmt.Errorf("creating staging directory: %w", err)
+		}
+		staging, cleanup = dir, func() { os.Remove(dir) }
+	}
+	ui.Verbosef("Staging games for %s in %s\n", opts.OutputDir, staging)
+
+	opts.remoteOutput = opts.OutputDir
+	opts.MirrorDirs = append([]string{opts.OutputDir}, opts.MirrorDirs...)
+	opts.OutputDir = staging
+	return opts, cleanup, nil
+}
+
+// finishRemoteOutput removes a game from staging once it reached the sftp:// output and
+// points its result there. A game whose upload failed stays in staging.
+func finishRemoteOutput(result *GameResult, opts OrganizeOptions) {
+	for _, mirror := range result.Mirrors {
+		if mirror.Destination != opts.remoteOutput {
+			continue
+		}
+		if mirror.Err != nil {
+			ui.Warnf("Kept %s in staging: %s\n", result.GameInfo.Title, result.TargetPath)
+			return
+		}
+		if err := os.RemoveAll(result.TargetPath); err != nil {
+			ui.Warnf("Could not remove the staged copy %s: %v\n", result.TargetPath, err)
+		}
+		// Remove bucket folders (A/, homebrew sections) left empty in staging
+		for dir := filepath.Dir(result.TargetPath); dir != opts.OutputDir && len(dir) > len(opts.OutputDir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+		result.TargetPath = mirror.Path
+		return
+	}
+}
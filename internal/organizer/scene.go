@@ -0,0 +1,191 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// sceneJunkDirs are the extra folders of scene releases: video samples, photos of the
+// disc as proof, and the covers and subtitles some groups add
+var sceneJunkDirs = []string{"sample", "samples", "proof", "proofs", "covers", "subs"}
+
+// sceneJunkExtensions are release files that aren't part of the game
+var sceneJunkExtensions = []string{".nfo", ".sfv", ".md5", ".diz", ".url"}
+
+// New-style RAR volumes are name.part01.rar, name.part02.rar, ...; old-style ones are
+// name.rar followed by name.r00 to name.r99, then name.s00 and on
+var (
+	rarPartVolume = regexp.MustCompile(`(?i)^(.+)\.part(\d+)\.rar$`)
+	rarOldVolume  = regexp.MustCompile(`(?i)^(.+)\.(rar|[r-z]\d\d)$`)
+)
+
+// sceneRelease is a download folder holding a game as a split RAR set, the way scene
+// releases come
+type sceneRelease struct {
+	dir     string
+	first   string   // First volume, the one 7z is given
+	volumes []string // Every volume of the set
+	sums    []string // .sfv and .md5 files verified before extracting
+	junk    []string // .nfo files, Sample/ and Proof/ folders and the like
+}
+
+// isSceneJunk reports whether a file or folder at the top of a release (or of what it
+// extracts to) is one of the scene extras
+func isSceneJunk(name string, isDir bool, junkFiles []string) bool {
+	lower := strings.ToLower(name)
+	if isDir {
+		for _, dir := range sceneJunkDirs {
+			if lower == dir {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ext := range sceneJunkExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return common.IsJunkFile(name, junkFiles)
+}
+
+// rarSetName returns the name shared by the volumes of a RAR set, and whether the file
+// is a RAR volume at all
+func rarSetName(name string) (string, bool) {
+	if match := rarPartVolume.FindStringSubmatch(name); match != nil {
+		return strings.ToLower(match[1]) + ".part", true
+	}
+	if match := rarOldVolume.FindStringSubmatch(name); match != nil {
+		return strings.ToLower(match[1]), true
+	}
+	return "", false
+}
+
+// isFirstRARVolume reports whether a RAR volume is the one to extract a set from
+func isFirstRARVolume(name string) bool {
+	if match := rarPartVolume.FindStringSubmatch(name); match != nil {
+		return strings.TrimLeft(match[2], "0") == "1"
+	}
+	return strings.EqualFold(filepath.Ext(name), ".rar")
+}
+
+// rarVolumes returns the volumes of the RAR set a first volume starts, itself included
+func rarVolumes(first string) []string {
+	set, ok := rarSetName(filepath.Base(first))
+	if !ok {
+		return []string{first}
+	}
+	entries, err := os.ReadDir(filepath.Dir(first))
+	if err != nil {
+		return []string{first}
+	}
+	var volumes []string
+	for _, entry := range entries {
+		if name, ok := rarSetName(entry.Name()); ok && name == set && entry.Type().IsRegular() {
+			volumes = append(volumes, filepath.Join(filepath.Dir(first), entry.Name()))
+		}
+	}
+	return volumes
+}
+
+// findSceneRelease recognizes a folder holding one RAR set at its top, with nothing
+// else but the usual .sfv, .md5 and .nfo files and Sample/ or Proof/ folders. Anything more,
+// such as a game folder beside the set, leaves the folder to normal detection.
+func findSceneRelease(dir string, opts OrganizeOptions) *sceneRelease {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	release := &sceneRelease{dir: dir}
+	set := ""
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if name, ok := rarSetName(entry.Name()); ok && entry.Type().IsRegular() {
+			if set != "" && name != set {
+				return nil // Several sets: not one release
+			}
+			set = name
+			release.volumes = append(release.volumes, path)
+			if isFirstRARVolume(entry.Name()) {
+				release.first = path
+			}
+			continue
+		}
+		if !isSceneJunk(entry.Name(), entry.IsDir(), opts.junkFiles()) {
+			return nil
+		}
+		if common.IsChecksumFile(entry.Name()) {
+			release.sums = append(release.sums, path)
+		}
+		release.junk = append(release.junk, path)
+	}
+	if release.first == "" {
+		return nil
+	}
+	sort.Strings(release.volumes)
+	return release
+}
+
+// organizeSceneRelease checks a release's RAR set against its checksum files, then
+// extracts and organizes it. With --move the whole release folder goes once the game is
+// organized.
+func organizeSceneRelease(release *sceneRelease, opts OrganizeOptions) (*GameResult, error) {
+	ui.Verbosef("Scene release: %d RAR volumes starting with %s\n", len(release.volumes), filepath.Base(release.first))
+	if !opts.NoChecksumFiles {
+		if err := verifyChecksumFiles(release.sums, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	archiveOpts := opts
+	archiveOpts.MoveSource = false
+	result, err := organizeArchive(release.first, archiveOpts)
+	if err != nil {
+		return nil, err
+	}
+	result.SourcePath = release.dir
+
+	if opts.MoveSource {
+		ui.Verbosef("Removing release: %s\n", release.dir)
+		for _, path := range append(release.volumes, release.junk...) {
+			if err := opts.discardSource(path); err != nil {
+				return result, fmt.Errorf("removing release files: %w", err)
+			}
+		}
+		if err := os.Remove(release.dir); err != nil {
+			ui.Warnf("Could not remove %s: %v\n", release.dir, err)
+		}
+	}
+	return result, nil
+}
+
+// stripSceneJunk deletes scene extras from the top of an extracted archive, and from the
+// top of the folder wrapping everything in it if there is one
+func stripSceneJunk(dir string, opts OrganizeOptions) {
+	for level := 0; level < 2; level++ {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		var kept []os.DirEntry
+		for _, entry := range entries {
+			if isSceneJunk(entry.Name(), entry.IsDir(), opts.junkFiles()) {
+				ui.Verbosef("Leaving out %s\n", entry.Name())
+				os.RemoveAll(filepath.Join(dir, entry.Name()))
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		if len(kept) != 1 || !kept[0].IsDir() {
+			return
+		}
+		dir = filepath.Join(dir, kept[0].Name())
+	}
+}
@@ -0,0 +1,221 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// maxReportedMismatches limits how many differences a failed verification lists
+const maxReportedMismatches = 5
+
+// sourceSnapshot records the files of a source before --move, so the organized copy
+// can be verified before anything is deleted
+type sourceSnapshot struct {
+	root  string
+	alg   common.HashAlgorithm
+	files map[string]snapshotFile // Keyed by slash-separated path relative to root
+
+	// hashLater leaves the hashes to be taken by the copy, which reads every file anyway
+	hashLater bool
+}
+
+type snapshotFile struct {
+	size    int64
+	modTime time.Time
+	sum     string // Hex digest, when hashed
+	hashed  bool
+	pending bool // To be hashed by the copy
+}
+
+// takeSnapshot records the size and modification time of every file under root and the
+// hash of each file,
+// or only of PARAM.SFO files when quick is set. With hashLater, only the sizes are read
+// now; the hashes are filled in by recordCopy from a copy hashed on the way, so the
+// source is read once rather than twice.
+func takeSnapshot(root string, quick bool, alg common.HashAlgorithm, hashLater bool) (*sourceSnapshot, error) {
+	if hashLater {
+		ui.Verbosef("Recording source snapshot of %s (%s while copying)...\n", root, alg)
+	} else {
+		ui.Verbosef("Recording source snapshot of %s (%s)...\n", root, alg)
+	}
+	s := &sourceSnapshot{root: root, alg: alg, files: make(map[string]snapshotFile), hashLater: hashLater}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		file := snapshotFile{size: info.Size(), modTime: info.ModTime()}
+		if hashLater {
+			file.pending = !quick || strings.EqualFold(info.Name(), "PARAM.SFO")
+		} else if !quick || strings.EqualFold(info.Name(), "PARAM.SFO") {
+			if file.sum, err = common.HashFile(path, alg); err != nil {
+				return err
+			}
+			file.hashed = true
+		}
+		s.files[filepath.ToSlash(rel)] = file
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("recording source snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// hashAlgs returns the hashes a copy of the source has to take for the snapshot
+func (s *sourceSnapshot) hashAlgs() []common.HashAlgorithm {
+	if s == nil || !s.hashLater {
+		return nil
+	}
+	return []common.HashAlgorithm{s.alg}
+}
+
+// recordCopy fills in the hashes left to the copy from the digests it took as it read
+// the source. A file the copy didn't hash is missing from it, which verifyDir reports.
+func (s *sourceSnapshot) recordCopy(hashes common.FileHashes) {
+	for rel, file := range s.files {
+		if !file.pending {
+			continue
+		}
+		if sum := hashes.Sum(rel, s.alg); sum != "" {
+			file.sum, file.hashed, file.pending = sum, true, false
+			s.files[rel] = file
+		}
+	}
+}
+
+// forget drops a file that is not expected in the organized copy
+func (s *sourceSnapshot) forget(path string) {
+	if rel, err := filepath.Rel(s.root, path); err == nil {
+		delete(s.files, filepath.ToSlash(rel))
+	}
+}
+
+// forgetDir leaves every file under a directory out of the snapshot
+func (s *sourceSnapshot) forgetDir(dir string) {
+	rel, err := filepath.Rel(s.root, dir)
+	if err != nil {
+		return
+	}
+	prefix := filepath.ToSlash(rel) + "/"
+	for path := range s.files {
+		if strings.HasPrefix(path, prefix) {
+			delete(s.files, path)
+		}
+	}
+}
+
+// sourceChanges reports the source files changed or gone since the snapshot. The copy
+// may hold an older version of those, or one caught halfway through a write, so the
+// source must not be deleted.
+func (s *sourceSnapshot) sourceChanges() []string {
+	var problems []string
+	for _, rel := range s.sortedPaths() {
+		want := s.files[rel]
+		info, err := os.Stat(filepath.Join(s.root, filepath.FromSlash(rel)))
+		switch {
+		case err != nil:
+			problems = append(problems, rel+": gone from the source")
+		case info.Size() != want.size || !info.ModTime().Equal(want.modTime):
+			problems = append(problems, rel+": changed in the source since the snapshot")
+		}
+	}
+	return problems
+}
+
+// verifyDir checks a copied directory against the snapshot, and that the source didn't
+// change since
+func (s *sourceSnapshot) verifyDir(dir string) error {
+	problems := s.sourceChanges()
+	for _, rel := range s.sortedPaths() {
+		want := s.files[rel]
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			problems = append(problems, rel+": missing")
+			continue
+		}
+		if info.Size() != want.size {
+			problems = append(problems, fmt.Sprintf("%s: size %d, expected %d", rel, info.Size(), want.size))
+			continue
+		}
+		if want.pending {
+			problems = append(problems, rel+": not read by the copy")
+			continue
+		}
+		if want.hashed {
+			sum, err := common.HashFile(path, s.alg)
+			if err != nil {
+				return err
+			}
+			if sum != want.sum {
+				problems = append(problems, rel+": content differs")
+			}
+		}
+	}
+	return s.result(dir, problems)
+}
+
+// verifyArchive checks a new archive's listing against the snapshot, which must hold
+// CRC32s, and that the source didn't change since
+func (s *sourceSnapshot) verifyArchive(archivePath string) error {
+	if s.alg != common.HashCRC32 {
+		return fmt.Errorf("cannot verify %s against a %s snapshot", archivePath, s.alg)
+	}
+	entries, err := common.List7zArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	archived := make(map[string]common.ArchiveEntry)
+	for _, entry := range entries {
+		archived[entry.Path] = entry
+	}
+
+	problems := s.sourceChanges()
+	for _, rel := range s.sortedPaths() {
+		want := s.files[rel]
+		entry, ok := archived[rel]
+		switch {
+		case !ok || entry.IsDir:
+			problems = append(problems, rel+": missing from archive")
+		case entry.Size != want.size:
+			problems = append(problems, fmt.Sprintf("%s: size %d, expected %d", rel, entry.Size, want.size))
+		case want.hashed && !strings.EqualFold(entry.CRC, want.sum):
+			problems = append(problems, rel+": CRC differs")
+		}
+	}
+	return s.result(archivePath, problems)
+}
+
+// result turns verification problems into an error that stops the source being deleted
+func (s *sourceSnapshot) result(target string, problems []string) error {
+	if len(problems) == 0 {
+		ui.Verbosef("Verified %d files in %s against the source snapshot\n", len(s.files), target)
+		return nil
+	}
+	shown := problems
+	if len(shown) > maxReportedMismatches {
+		shown = shown[:maxReportedMismatches]
+	}
+	return fmt.Errorf("refusing to delete source %s: %s does not match it (%d differences: %s)",
+		s.root, target, len(problems), strings.Join(shown, "; "))
+}
+
+func (s *sourceSnapshot) sortedPaths() []string {
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
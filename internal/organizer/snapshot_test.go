@@ -0,0 +1,228 @@
+package organizer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// writeSource creates a game source folder and returns its path
+func writeSource(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "Game")
+	for rel, data := range map[string]string{
+		"PS3_DISC.SFB":              "disc",
+		"PS3_GAME/PARAM.SFO":        "param",
+		"PS3_GAME/USRDIR/EBOOT.BIN": "eboot",
+		"PS3_GAME/USRDIR/data.pak":  "game data",
+	} {
+		writeSnapshotFile(t, filepath.Join(src, filepath.FromSlash(rel)), data)
+	}
+	return src
+}
+
+// writeSnapshotFile writes a file with a modification time an hour ago, so a rewrite
+// shows as a change however coarse the file system's timestamps are
+func writeSnapshotFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rewrite changes a file's content, keeping its size
+func rewrite(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.ToUpper(string(data))), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyDir(t *testing.T) {
+	data := filepath.Join("PS3_GAME", "USRDIR", "data.pak")
+	tests := []struct {
+		name         string
+		beforeCopy   func(t *testing.T, src string) // Between the snapshot and the copy
+		afterCopy    func(t *testing.T, src, dest string)
+		skipHashCopy bool   // The copy doesn't hash data.pak for a snapshot hashed later
+		want         string // Problem reported, "" when the copy verifies
+	}{
+		{name: "unchanged"},
+		{
+			name:       "source rewritten before the copy",
+			beforeCopy: func(t *testing.T, src string) { rewrite(t, filepath.Join(src, data)) },
+			want:       "data.pak: changed in the source",
+		},
+		{
+			name:       "source grown before the copy",
+			beforeCopy: func(t *testing.T, src string) { writeSnapshotFile(t, filepath.Join(src, data), "more game data") },
+			want:       "data.pak: size 14, expected 9",
+		},
+		{
+			name:      "source rewritten after the copy",
+			afterCopy: func(t *testing.T, src, dest string) { rewrite(t, filepath.Join(src, data)) },
+			want:      "data.pak: changed in the source",
+		},
+		{
+			name: "source file deleted",
+			afterCopy: func(t *testing.T, src, dest string) {
+				os.Remove(filepath.Join(src, data))
+				os.Remove(filepath.Join(dest, data))
+			},
+			want: "data.pak: gone from the source",
+		},
+		{
+			name:      "copy missing a file",
+			afterCopy: func(t *testing.T, src, dest string) { os.Remove(filepath.Join(dest, data)) },
+			want:      "data.pak: missing",
+		},
+		{
+			name: "copy truncated",
+			afterCopy: func(t *testing.T, src, dest string) {
+				if err := os.Truncate(filepath.Join(dest, data), 4); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "data.pak: size 4, expected 9",
+		},
+		{
+			name:      "copy corrupted",
+			afterCopy: func(t *testing.T, src, dest string) { rewrite(t, filepath.Join(dest, data)) },
+			want:      "data.pak: content differs",
+		},
+		{
+			name:         "file the copy didn't hash",
+			skipHashCopy: true,
+			want:         "data.pak: not read by the copy",
+		},
+	}
+	for _, tt := range tests {
+		for _, hashLater := range []bool{false, true} {
+			if tt.skipHashCopy && !hashLater {
+				continue
+			}
+			src := writeSource(t)
+			snapshot, err := takeSnapshot(src, false, common.HashXXH64, hashLater)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.beforeCopy != nil {
+				tt.beforeCopy(t, src)
+			}
+			dest := filepath.Join(t.TempDir(), "game")
+			copied, err := common.CopyDirHashed(src, dest, snapshot.hashAlgs()...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.skipHashCopy {
+				delete(copied, filepath.ToSlash(data))
+			}
+			snapshot.recordCopy(copied)
+			if tt.afterCopy != nil {
+				tt.afterCopy(t, src, dest)
+			}
+
+			err = snapshot.verifyDir(dest)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("%s (hash later: %v): %v", tt.name, hashLater, err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("%s (hash later: %v): error %v, want %q", tt.name, hashLater, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestVerifyDirQuick(t *testing.T) {
+	src := writeSource(t)
+	snapshot, err := takeSnapshot(src, true, common.HashXXH64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "game")
+	if err := common.CopyDir(src, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	// A quick snapshot only hashes PARAM.SFO: other files are checked by size
+	rewrite(t, filepath.Join(dest, "PS3_GAME", "USRDIR", "data.pak"))
+	if err := snapshot.verifyDir(dest); err != nil {
+		t.Errorf("quick verify compared the content of data.pak: %v", err)
+	}
+	rewrite(t, filepath.Join(dest, "PS3_GAME", "PARAM.SFO"))
+	if err := snapshot.verifyDir(dest); err == nil || !strings.Contains(err.Error(), "PARAM.SFO: content differs") {
+		t.Errorf("quick verify of a changed PARAM.SFO: %v", err)
+	}
+}
+
+func TestVerifyArchive(t *testing.T) {
+	src := writeSource(t)
+	snapshot, err := takeSnapshot(src, false, common.HashXXH64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.verifyArchive(filepath.Join(t.TempDir(), "game.7z")); err == nil {
+		t.Error("verified an archive against a snapshot without CRC32s")
+	}
+
+	found := false
+	for _, name := range common.SevenZipCommands {
+		if _, err := exec.LookPath(name); err == nil {
+			found = true
+		}
+	}
+	if !found {
+		t.Skip("7z not installed")
+	}
+
+	snapshot, err = takeSnapshot(src, false, common.HashCRC32, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "game.7z")
+	if err := common.Create7zArchive(src, archive, common.ArchiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.verifyArchive(archive); err != nil {
+		t.Fatalf("verifying a complete archive: %v", err)
+	}
+
+	// A truncated archive can't be listed, or is missing files
+	info, err := os.Stat(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(t.TempDir(), "game.7z")
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(truncated, data[:info.Size()/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.verifyArchive(truncated); err == nil {
+		t.Error("verified a truncated archive")
+	}
+
+	// The archive no longer holds what deleting the source would lose
+	rewrite(t, filepath.Join(src, "PS3_GAME", "USRDIR", "data.pak"))
+	if err := snapshot.verifyArchive(archive); err == nil || !strings.Contains(err.Error(), "data.pak: changed in the source") {
+		t.Errorf("verifying against a source changed since the snapshot: %v", err)
+	}
+}
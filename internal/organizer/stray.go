@@ -0,0 +1,173 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/consoles"
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/parsers"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// organizeStrayArchive organizes a 7z archive whose root is a PS3 game folder, such as a
+// game.7z separated from its organized folder, by placing the archive itself as game.7z
+// in a folder named from the PARAM.SFO inside it. Nothing is extracted or recompressed.
+// ok is false when the archive can't be organized this way and should be extracted.
+func organizeStrayArchive(archivePath string, opts OrganizeOptions) (result *GameResult, ok bool, err error) {
+	gameInfo, ok := peekArchive(archivePath)
+	if !ok {
+		return nil, false, nil
+	}
+	handler, err := consoles.NewRegistry().GetHandler(detect.PS3)
+	if err != nil {
+		return nil, true, fmt.Errorf("getting console handler: %w", err)
+	}
+	gameInfo.Console = handler.GetConsoleDisplayName()
+	ui.Infof("%s holds %s [%s]; keeping the archive as its game.7z\n", filepath.Base(archivePath), gameInfo.Title, gameInfo.GameID)
+
+	targetPath, err := prepareTarget(archivePath, gameInfo, handler, opts)
+	if err != nil {
+		return nil, true, err
+	}
+	if err := common.CheckReadable(archivePath); err != nil {
+		return nil, true, err
+	}
+	if opts.MoveSource && !common.IsWritableDir(filepath.Dir(archivePath)) {
+		ui.Warnf("%s is on read-only media or not writable; --move disabled, copying instead\n", archivePath)
+		opts.MoveSource = false
+	}
+
+	if err := common.CreateTargetStructure(targetPath, opts.Layout, opts.Force); err != nil {
+		return nil, true, err
+	}
+	if opts.Force {
+		if err := clearExistingGame(targetPath); err != nil {
+			return nil, true, err
+		}
+	}
+
+	game7zPath := filepath.Join(targetPath, "game.7z")
+	if err := placeArchive(archivePath, game7zPath, opts); err != nil {
+		return nil, true, err
+	}
+	if meta, err := common.ReadArchiveMetadata(game7zPath); err != nil || meta == nil || meta.GameID != gameInfo.GameID || meta.Title != gameInfo.Title {
+		if err := embedArchiveMetadata(game7zPath, gameInfo); err != nil {
+			return nil, true, err
+		}
+	}
+	if err := writeArchiveSidecars(game7zPath, opts); err != nil {
+		return nil, true, err
+	}
+
+	ui.Successf("Successfully organized %s game:\n", gameInfo.Console)
+	ui.Infof("  Title: %s\n", gameInfo.Title)
+	ui.Infof("  Game ID: %s\n", gameInfo.GameID)
+	ui.Infof("  Console: %s\n", gameInfo.Console)
+	ui.Infof("  Format: Compressed (game.7z, kept as is)\n")
+	ui.Infof("  Output: %s\n", targetPath)
+
+	return &GameResult{SourcePath: archivePath, TargetPath: targetPath, GameInfo: gameInfo}, true, nil
+}
+
+// peekArchive reads the game information of a 7z archive holding a PS3 game folder at
+// its root from the archive listing and the PARAM.SFO inside it, without extracting it
+func peekArchive(archivePath string) (*common.GameInfo, bool) {
+	if !strings.EqualFold(filepath.Ext(archivePath), ".7z") {
+		return nil, false
+	}
+	entries, err := common.List7zArchive(archivePath)
+	if err != nil {
+		ui.Verbosef("Could not list %s, extracting it instead: %v\n", archivePath, err)
+		return nil, false
+	}
+	paths := make([]string, len(entries))
+	for i, entry := range entries {
+		paths[i] = entry.Path
+	}
+	detection := detect.DetectConsoleFromListing(paths)
+	if detection.ConsoleType != detect.PS3 || detection.GamePath != "" || !onlyGameFolder(paths) {
+		return nil, false
+	}
+
+	data, err := common.Read7zFile(archivePath, "PS3_GAME/PARAM.SFO")
+	if err != nil {
+		ui.Verbosef("Could not read PARAM.SFO from %s, extracting it instead: %v\n", archivePath, err)
+		return nil, false
+	}
+	sfo, err := parsers.ParseParamSFO(data)
+	if err != nil || sfo.GetTitle() == "" || sfo.GetTitleID() == "" {
+		return nil, false
+	}
+	return &common.GameInfo{
+		Title:    sfo.GetTitle(),
+		GameID:   sfo.GetTitleID(),
+		Version:  sfo.GetString("APP_VER"),
+		Category: sfo.GetString("CATEGORY"),
+		Source:   archivePath,
+		Homebrew: consoles.IsPS3Homebrew(sfo.GetString("CATEGORY"), sfo.GetTitleID()),
+	}, true
+}
+
+// onlyGameFolder reports whether everything at the root of an archive listing belongs to
+// a PS3 game folder (PS3_GAME/, PS3_DISC.SFB, PS3_UPDATE/, ...), so the archive can be
+// used as game.7z unchanged. Update packages or manuals packed next to the game need the
+// archive extracted to be placed in their own folders.
+func onlyGameFolder(paths []string) bool {
+	for _, path := range paths {
+		root, _, _ := strings.Cut(path, "/")
+		if !strings.HasPrefix(root, "PS3_") {
+			return false
+		}
+	}
+	return true
+}
+
+// placeArchive copies an archive to dest, or moves it with --move. A move between file
+// systems is only finished once the copy's hash matches the original.
+func placeArchive(src, dest string, opts OrganizeOptions) error {
+	if opts.MoveSource && canRename(src, filepath.Dir(dest)) {
+		opts.reportStage(StageMoving)
+		if err := os.Rename(src, dest); err == nil {
+			opts.sourceMoved(src, dest)
+			return nil
+		}
+		ui.Verbosef("Rename failed, copying instead\n")
+	}
+
+	// The original is hashed as it is copied, so only the copy is read again
+	hash := opts.snapshotHash()
+	var algs []common.HashAlgorithm
+	if opts.MoveSource {
+		algs = append(algs, hash)
+	}
+	var sums map[common.HashAlgorithm]string
+	opts.reportStage(StageCopying)
+	err := opts.Retry.Do("Copying game.7z", func() (err error) {
+		sums, err = common.CopyFileHashed(src, dest, algs...)
+		return err
+	}, func() { os.Remove(dest) })
+	if err != nil {
+		return fmt.Errorf("copying archive: %w", err)
+	}
+	if !opts.MoveSource {
+		return nil
+	}
+
+	want := sums[hash]
+	got, err := common.HashFile(dest, hash)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("copy of %s doesn't match the original (%s); the original was kept", src, hash)
+	}
+	ui.Verbosef("Removing archive: %s\n", src)
+	if err := opts.discardSource(src); err != nil {
+		return fmt.Errorf("removing archive after move: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// StageTimings records how long each stage of organizing one or more games took
+type StageTimings struct {
+	Stages map[string]time.Duration // Time spent per stage (StageDetecting, StageCopying, ...)
+	Total  time.Duration
+	Bytes  int64 // Game data processed, for throughput
+
+	order   []string
+	current string
+	started time.Time
+	began   time.Time
+}
+
+// newStageTimings starts timing a game
+func newStageTimings() *StageTimings {
+	now := time.Now()
+	return &StageTimings{Stages: make(map[string]time.Duration), started: now, began: now}
+}
+
+// enter ends the current stage and starts the next one
+func (t *StageTimings) enter(stage string) {
+	now := time.Now()
+	t.record(now)
+	t.current, t.started = stage, now
+}
+
+// finish ends the current stage and the total
+func (t *StageTimings) finish() {
+	now := time.Now()
+	t.record(now)
+	t.current = ""
+	t.Total = now.Sub(t.began)
+}
+
+func (t *StageTimings) record(now time.Time) {
+	if t.current == "" {
+		return
+	}
+	if _, ok := t.Stages[t.current]; !ok {
+		t.order = append(t.order, t.current)
+	}
+	t.Stages[t.current] += now.Sub(t.started)
+}
+
+// add sums another game's timings into t
+func (t *StageTimings) add(other *StageTimings) {
+	for _, stage := range other.order {
+		if _, ok := t.Stages[stage]; !ok {
+			t.order = append(t.order, stage)
+		}
+		t.Stages[stage] += other.Stages[stage]
+	}
+	t.Total += other.Total
+	t.Bytes += other.Bytes
+}
+
+// String formats the stages in the order they ran, the total and the throughput,
+// e.g. "detecting 0.1s, compressing 42.0s; total 42.1s, 1.20 GB at 29.2 MB/s"
+func (t *StageTimings) String() string {
+	parts := make([]string, len(t.order))
+	for i, stage := range t.order {
+		parts[i] = fmt.Sprintf("%s %s", stage, formatDuration(t.Stages[stage]))
+	}
+	s := strings.Join(parts, ", ")
+	if s != "" {
+		s += "; "
+	}
+	s += "total " + formatDuration(t.Total)
+	if t.Bytes > 0 && t.Total > 0 {
+		s += fmt.Sprintf(", %s at %.1f MB/s", common.FormatSize(t.Bytes), float64(t.Bytes)/(1024*1024)/t.Total.Seconds())
+	}
+	return s
+}
+
+// formatDuration rounds durations for display: 0.4s, 12.3s, 2m5s
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+// gameDataSize returns the uncompressed size of an organized game, for throughput
+func gameDataSize(result *GameResult) int64 {
+	if result.Compression != nil {
+		return result.Compression.OriginalSize
+	}
+	if size, err := common.DirSize(filepath.Join(result.TargetPath, "game")); err == nil {
+		return size
+	}
+	if size, err := common.Archive7zContentSize(filepath.Join(result.TargetPath, "game.7z")); err == nil {
+		return size
+	}
+	return 0
+}
+
+// printTimingsSummary prints the stage times and throughput of all timed games
+func printTimingsSummary(results []*GameResult) {
+	total := &StageTimings{Stages: make(map[string]time.Duration)}
+	timed := 0
+	for _, result := range results {
+		if result.Timings != nil {
+			total.add(result.Timings)
+			timed++
+		}
+	}
+	if timed > 0 {
+		ui.Infof("Timings (%d games): %s\n", timed, total)
+	}
+}
@@ -0,0 +1,81 @@
+package organizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// TrashDir is the folder, next to each source of a batch, where --move keeps the source
+// files it is done with instead of deleting them, so undo can put them back until the
+// trash is purged
+const TrashDir = ".rom-organizer-trash"
+
+// MovedPath is a source path that --move renamed: into the organized game, within it
+// (a bundled file or disc component taken out of game/), or into the trash
+type MovedPath struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// sourceMoves records where --move put the source paths of one game, in order
+type sourceMoves struct {
+	trash string // Folder the game's discarded sources go to ("" deletes them)
+	moved []MovedPath
+}
+
+// newSourceMoves returns the record of a game's moves; its trash is a folder named after
+// the batch's start in the TrashDir next to the source
+func newSourceMoves(sourcePath, batch string, opts OrganizeOptions) *sourceMoves {
+	moves := &sourceMoves{}
+	if opts.MoveSource && !opts.NoTrash {
+		moves.trash = filepath.Join(filepath.Dir(absPath(sourcePath)), TrashDir, batch)
+	}
+	return moves
+}
+
+// paths returns the recorded moves (nil without a record)
+func (m *sourceMoves) paths() []MovedPath {
+	if m == nil {
+		return nil
+	}
+	return m.moved
+}
+
+// sourceMoved records that --move renamed a source path to dest
+func (o OrganizeOptions) sourceMoved(from, to string) {
+	if o.moves != nil && o.MoveSource {
+		o.moves.moved = append(o.moves.moved, MovedPath{From: absPath(from), To: absPath(to)})
+	}
+}
+
+// discardSource deletes a source path --move is done with. When the batch keeps a trash,
+// the path is renamed into it instead, which is instant: the trash is next to the source.
+func (o OrganizeOptions) discardSource(path string) error {
+	if o.moves == nil || o.moves.trash == "" {
+		return os.RemoveAll(path)
+	}
+	// Already gone, as RemoveAll allows: a game folder moved away is cleaned up again
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.MkdirAll(o.moves.trash, 0755); err != nil {
+		return fmt.Errorf("creating trash folder: %w", err)
+	}
+	// Each path gets its own folder, so sources of the same name don't collide
+	dir, err := os.MkdirTemp(o.moves.trash, "")
+	if err != nil {
+		return fmt.Errorf("creating trash folder: %w", err)
+	}
+	dest := filepath.Join(dir, filepath.Base(path))
+	if err := os.Rename(path, dest); err != nil {
+		os.Remove(dir)
+		ui.Warnf("Could not keep %s in the trash (%v); deleting it, undo won't restore it\n", path, err)
+		return os.RemoveAll(path)
+	}
+	ui.Verbosef("Moved %s to the trash: %s\n", path, dest)
+	o.sourceMoved(path, dest)
+	return nil
+}
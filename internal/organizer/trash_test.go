@@ -0,0 +1,120 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/detect"
+	"github.com/NeilGraham/rom-organizer/internal/devtools"
+)
+
+// moveNestedGame generates a game wrapped in a folder, organizes the folder with --move
+// and returns the folder and the game_done event
+func moveNestedGame(t *testing.T, noTrash bool) (string, ProgressEvent) {
+	t.Helper()
+	sources, output := t.TempDir(), t.TempDir()
+	paths, err := devtools.GenerateGames(devtools.GenerateOptions{
+		OutputDir: sources, Count: 1, Seed: 1, Nested: true, Consoles: []detect.ConsoleType{detect.PS3},
+	})
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("generating a game: %v, %v", paths, err)
+	}
+	wrapper := paths[0]
+
+	var done ProgressEvent
+	opts := OrganizeOptions{
+		OutputDir:  output,
+		MoveSource: true,
+		Format:     Decompressed,
+		NoTrash:    noTrash,
+		Progress: func(event ProgressEvent) {
+			if event.Event == EventGameDone {
+				done = event
+			}
+		},
+	}
+	if err := OrganizeGames([]string{wrapper}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != HookStatusSuccess {
+		t.Fatalf("game_done = %+v, want success", done)
+	}
+	return wrapper, done
+}
+
+func TestMoveKeepsSourcesInTrash(t *testing.T) {
+	wrapper, done := moveNestedGame(t, false)
+	if _, err := os.Stat(wrapper); !os.IsNotExist(err) {
+		t.Fatalf("source folder still there after --move: %v", err)
+	}
+
+	// The game folder is renamed into the game, then the emptied wrapper goes to the trash
+	if len(done.Moved) != 2 {
+		t.Fatalf("moved = %+v, want the game folder and its wrapper", done.Moved)
+	}
+	game, trashed := done.Moved[0], done.Moved[1]
+	if !isWithin(game.From, wrapper) || game.To != filepath.Join(done.Target, "game") {
+		t.Errorf("game folder moved %s -> %s, want from %s into %s", game.From, game.To, wrapper, done.Target)
+	}
+	trash := filepath.Join(filepath.Dir(wrapper), TrashDir)
+	if trashed.From != wrapper || filepath.Base(trashed.To) != filepath.Base(wrapper) || !isWithin(trashed.To, trash) {
+		t.Errorf("wrapper moved %s -> %s, want into %s", trashed.From, trashed.To, trash)
+	}
+	if _, err := os.Stat(trashed.To); err != nil {
+		t.Errorf("trashed wrapper: %v", err)
+	}
+}
+
+func TestMoveWithoutTrashDeletes(t *testing.T) {
+	wrapper, done := moveNestedGame(t, true)
+	if len(done.Moved) != 1 || done.Moved[0].To != filepath.Join(done.Target, "game") {
+		t.Errorf("moved = %+v, want only the game folder", done.Moved)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(wrapper), TrashDir)); !os.IsNotExist(err) {
+		t.Errorf("trash folder made with NoTrash: %v", err)
+	}
+	if _, err := os.Stat(wrapper); !os.IsNotExist(err) {
+		t.Errorf("source folder still there after --move: %v", err)
+	}
+}
+
+func TestDiscardSourceNamesCollide(t *testing.T) {
+	dir := t.TempDir()
+	opts := OrganizeOptions{MoveSource: true, moves: &sourceMoves{trash: filepath.Join(dir, TrashDir, "batch")}}
+	for _, sub := range []string{"a", "b"} {
+		path := filepath.Join(dir, sub, "game.iso")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(sub), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := opts.discardSource(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved := opts.moves.paths()
+	if len(moved) != 2 || moved[0].To == moved[1].To {
+		t.Fatalf("moved = %+v, want two trash entries", moved)
+	}
+	for i, sub := range []string{"a", "b"} {
+		if data, err := os.ReadFile(moved[i].To); err != nil || string(data) != sub {
+			t.Errorf("trashed %s: %q, %v", moved[i].From, data, err)
+		}
+	}
+}
+
+func TestDiscardSourceGone(t *testing.T) {
+	dir := t.TempDir()
+	opts := OrganizeOptions{MoveSource: true, moves: &sourceMoves{trash: filepath.Join(dir, TrashDir, "batch")}}
+	if err := opts.discardSource(filepath.Join(dir, "gone")); err != nil {
+		t.Fatal(err)
+	}
+	if moved := opts.moves.paths(); len(moved) != 0 {
+		t.Errorf("moved = %+v, want nothing recorded", moved)
+	}
+	if _, err := os.Stat(filepath.Join(dir, TrashDir)); !os.IsNotExist(err) {
+		t.Errorf("trash folder made for a path that is gone: %v", err)
+	}
+}
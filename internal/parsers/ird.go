@@ -0,0 +1,158 @@
+package parsers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// irdMaxSize caps the decompressed IRD and the ISO header and footer inside it. Real ones
+// are a few MB at most, so anything larger is corrupt or a decompression bomb.
+const irdMaxSize = 64 << 20
+
+// IRDFile is a file entry in an IRD: the sector the file starts at and the MD5 of its contents
+type IRDFile struct {
+	Sector uint64
+	MD5    [16]byte
+}
+
+// IRD holds the contents of a PS3 IRD (ISO Rebuild Data) file, which describes
+// an original pressed disc: its ISO header and footer, region and file hashes,
+// and the data needed to derive the disc key.
+type IRD struct {
+	Version       uint8
+	ProductCode   string // Title ID, e.g. BLUS30001
+	Title         string
+	SystemVersion string // Firmware version on the disc
+	GameVersion   string
+	AppVersion    string
+	Header        []byte // Decompressed ISO sectors up to the first file
+	Footer        []byte // Decompressed ISO sectors after the last file
+	RegionHashes  [][16]byte
+	Files         []IRDFile
+	Data1         [16]byte // Encrypted disc key
+	Data2         [16]byte
+	PIC           []byte
+	UID           uint32
+}
+
+// ParseIRD parses an IRD file (versions 6 to 9), gzip-compressed or not
+func ParseIRD(data []byte) (*IRD, error) {
+	if !bytes.HasPrefix(data, []byte("3IRD")) {
+		decompressed, err := gunzip(data)
+		if err != nil {
+			return nil, fmt.Errorf("not an IRD file: %w", err)
+		}
+		data = decompressed
+	}
+	if !bytes.HasPrefix(data, []byte("3IRD")) {
+		return nil, fmt.Errorf("not an IRD file: invalid magic")
+	}
+
+	r := &irdReader{data: data, pos: 4}
+	ird := &IRD{Version: r.byte()}
+	if ird.Version < 6 || ird.Version > 9 {
+		return nil, fmt.Errorf("unsupported IRD version %d", ird.Version)
+	}
+
+	ird.ProductCode = r.text(9)
+	ird.Title = r.text(int(r.byte()))
+	ird.SystemVersion = r.text(4)
+	ird.GameVersion = r.text(5)
+	ird.AppVersion = r.text(5)
+	if ird.Version == 7 {
+		r.uint32() // IRD ID
+	}
+
+	header := r.bytes(int(r.uint32()))
+	footer := r.bytes(int(r.uint32()))
+
+	regionCount := int(r.byte())
+	for i := 0; i < regionCount; i++ {
+		var hash [16]byte
+		copy(hash[:], r.bytes(16))
+		ird.RegionHashes = append(ird.RegionHashes, hash)
+	}
+
+	fileCount := int(r.uint32())
+	for i := 0; i < fileCount && r.err == nil; i++ {
+		file := IRDFile{Sector: r.uint64()}
+		copy(file.MD5[:], r.bytes(16))
+		ird.Files = append(ird.Files, file)
+	}
+
+	r.uint32() // Reserved
+	if ird.Version >= 9 {
+		ird.PIC = r.bytes(115)
+	}
+	copy(ird.Data1[:], r.bytes(16))
+	copy(ird.Data2[:], r.bytes(16))
+	if ird.Version < 9 {
+		ird.PIC = r.bytes(115)
+	}
+	if ird.Version > 7 {
+		ird.UID = r.uint32()
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("parsing IRD: %w", r.err)
+	}
+
+	var err error
+	if ird.Header, err = gunzip(header); err != nil {
+		return nil, fmt.Errorf("decompressing IRD header: %w", err)
+	}
+	if ird.Footer, err = gunzip(footer); err != nil {
+		return nil, fmt.Errorf("decompressing IRD footer: %w", err)
+	}
+	return ird, nil
+}
+
+// gunzip decompresses gzip data of up to irdMaxSize bytes
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, irdMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > irdMaxSize {
+		return nil, fmt.Errorf("decompresses to more than %d bytes", irdMaxSize)
+	}
+	return out, nil
+}
+
+// irdReader reads little-endian IRD fields, remembering the first out-of-bounds read
+type irdReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// irdZeros is what reads past the end return, whatever length the file claimed; it's long
+// enough for the fixed-size fields read from it
+var irdZeros [8]byte
+
+func (r *irdReader) bytes(n int) []byte {
+	if r.err == nil && (n < 0 || n > len(r.data)-r.pos) {
+		r.err = fmt.Errorf("unexpected end of data at offset %d", r.pos)
+	}
+	if r.err != nil {
+		return irdZeros[:min(max(n, 0), len(irdZeros))]
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *irdReader) byte() uint8    { return r.bytes(1)[0] }
+func (r *irdReader) uint32() uint32 { return binary.LittleEndian.Uint32(r.bytes(4)) }
+func (r *irdReader) uint64() uint64 { return binary.LittleEndian.Uint64(r.bytes(8)) }
+func (r *irdReader) text(n int) string {
+	return string(bytes.TrimRight(r.bytes(n), "\x00"))
+}
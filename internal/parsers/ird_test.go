@@ -0,0 +1,156 @@
+package parsers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func gzipped(t testing.TB, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildIRD writes an IRD of the given version with one region and two files
+func buildIRD(t testing.TB, version uint8, header, footer []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	b.WriteString("3IRD")
+	b.WriteByte(version)
+	b.WriteString("BLUS30001")
+	b.WriteByte(byte(len("Test Game")))
+	b.WriteString("Test Game")
+	b.WriteString("4.21")
+	b.WriteString("01.00")
+	b.WriteString("01.02")
+	if version == 7 {
+		le(uint32(0xCAFE))
+	}
+	le(uint32(len(header)))
+	b.Write(header)
+	le(uint32(len(footer)))
+	b.Write(footer)
+	b.WriteByte(1)
+	b.Write(bytes.Repeat([]byte{0xAA}, 16))
+	le(uint32(2))
+	for i := 1; i <= 2; i++ {
+		le(uint64(i * 0x100))
+		b.Write(bytes.Repeat([]byte{byte(i)}, 16))
+	}
+	le(uint32(0)) // Reserved
+	pic := bytes.Repeat([]byte{0x50}, 115)
+	if version >= 9 {
+		b.Write(pic)
+	}
+	b.Write(bytes.Repeat([]byte{0xD1}, 16))
+	b.Write(bytes.Repeat([]byte{0xD2}, 16))
+	if version < 9 {
+		b.Write(pic)
+	}
+	if version > 7 {
+		le(uint32(0x12345678))
+	}
+	return b.Bytes()
+}
+
+func TestParseIRD(t *testing.T) {
+	header, footer := []byte("ISO header sectors"), []byte("ISO footer sectors")
+	for _, version := range []uint8{6, 7, 8, 9} {
+		data := buildIRD(t, version, gzipped(t, header), gzipped(t, footer))
+		for _, compressed := range []bool{false, true} {
+			if compressed {
+				data = gzipped(t, data)
+			}
+			ird, err := ParseIRD(data)
+			if err != nil {
+				t.Fatalf("version %d, gzipped %v: %v", version, compressed, err)
+			}
+			if ird.Version != version || ird.ProductCode != "BLUS30001" || ird.Title != "Test Game" ||
+				ird.SystemVersion != "4.21" || ird.GameVersion != "01.00" || ird.AppVersion != "01.02" {
+				t.Errorf("version %d: fields %+v", version, ird)
+			}
+			if !bytes.Equal(ird.Header, header) || !bytes.Equal(ird.Footer, footer) {
+				t.Errorf("version %d: header %q, footer %q", version, ird.Header, ird.Footer)
+			}
+			if len(ird.RegionHashes) != 1 || ird.RegionHashes[0][0] != 0xAA {
+				t.Errorf("version %d: region hashes %x", version, ird.RegionHashes)
+			}
+			if len(ird.Files) != 2 || ird.Files[1].Sector != 0x200 || ird.Files[1].MD5[0] != 2 {
+				t.Errorf("version %d: files %+v", version, ird.Files)
+			}
+			if ird.Data1[0] != 0xD1 || ird.Data2[0] != 0xD2 || len(ird.PIC) != 115 || ird.PIC[0] != 0x50 {
+				t.Errorf("version %d: data1 %x, data2 %x, PIC %x", version, ird.Data1, ird.Data2, ird.PIC)
+			}
+			if want := map[bool]uint32{true: 0x12345678}[version > 7]; ird.UID != want {
+				t.Errorf("version %d: UID %#x, want %#x", version, ird.UID, want)
+			}
+		}
+	}
+}
+
+func TestParseIRDRejectsHostileInput(t *testing.T) {
+	valid := buildIRD(t, 9, gzipped(t, []byte("header")), gzipped(t, []byte("footer")))
+
+	// The header length field sits right after the fixed fields
+	lengthAt := 4 + 1 + 9 + 1 + len("Test Game") + 4 + 5 + 5
+	hugeLength := func(length uint32) []byte {
+		data := append([]byte{}, valid[:lengthAt+4]...)
+		binary.LittleEndian.PutUint32(data[lengthAt:], length)
+		return data
+	}
+	bomb := buildIRD(t, 9, gzipped(t, make([]byte, irdMaxSize+1)), gzipped(t, nil))
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "not an IRD"},
+		{"bad magic", []byte("4IRD\x09"), "not an IRD"},
+		{"old version", append([]byte("3IRD\x05"), valid[5:]...), "unsupported IRD version 5"},
+		{"magic only", []byte("3IRD"), "unsupported IRD version 0"},
+		{"truncated", valid[:len(valid)-1], "unexpected end of data"},
+		{"header length 0x7FFFFFFF", hugeLength(0x7FFFFFFF), "unexpected end of data"},
+		{"header length 0xFFFFFFFF", hugeLength(0xFFFFFFFF), "unexpected end of data"},
+		{"header decompression bomb", bomb, "decompressing IRD header"},
+	}
+	for _, tt := range tests {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := ParseIRD(tt.data)
+		runtime.ReadMemStats(&after)
+
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+		// Lengths read from the file must not be allocated before they're checked
+		if allocated := after.TotalAlloc - before.TotalAlloc; tt.data != nil && len(tt.data) < 1<<10 && allocated > 1<<20 {
+			t.Errorf("%s: allocated %d bytes for %d bytes of input", tt.name, allocated, len(tt.data))
+		}
+	}
+}
+
+// FuzzParseIRD checks that no file makes the IRD parser panic
+func FuzzParseIRD(f *testing.F) {
+	valid := buildIRD(f, 9, gzipped(f, []byte("header")), gzipped(f, []byte("footer")))
+	f.Add(valid)
+	f.Add(valid[:len(valid)/2])
+	f.Add(buildIRD(f, 7, nil, nil))
+	f.Add([]byte("3IRD"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseIRD(data)
+	})
+}
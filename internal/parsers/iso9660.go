@@ -0,0 +1,96 @@
+package parsers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ISOSectorSize is the logical sector size of ISO 9660 images
+const ISOSectorSize = 2048
+
+// ISOFile is a file in an ISO 9660 file system
+type ISOFile struct {
+	Path   string // Slash-separated path from the root, e.g. PS3_GAME/USRDIR/EBOOT.BIN
+	Sector uint32 // First sector of the file's data
+	Size   int64  // Total size, including any further extents
+}
+
+// ReadISO9660 lists the files in an ISO 9660 image using its primary volume descriptor
+func ReadISO9660(r io.ReaderAt) ([]ISOFile, error) {
+	pvd := make([]byte, ISOSectorSize)
+	if _, err := r.ReadAt(pvd, 16*ISOSectorSize); err != nil {
+		return nil, fmt.Errorf("reading volume descriptor: %w", err)
+	}
+	if pvd[0] != 1 || string(pvd[1:6]) != "CD001" {
+		return nil, fmt.Errorf("no ISO 9660 primary volume descriptor")
+	}
+
+	root := pvd[156 : 156+34]
+	var files []ISOFile
+	visited := make(map[uint32]bool)
+	err := readISODir(r, binary.LittleEndian.Uint32(root[2:]), binary.LittleEndian.Uint32(root[10:]), "", &files, visited)
+	return files, err
+}
+
+// readISODir appends the files of the directory stored at sector to files, recursing into subdirectories
+func readISODir(r io.ReaderAt, sector, size uint32, dir string, files *[]ISOFile, visited map[uint32]bool) error {
+	if visited[sector] {
+		return nil
+	}
+	visited[sector] = true
+
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, int64(sector)*ISOSectorSize); err != nil {
+		return fmt.Errorf("reading directory %q: %w", dir, err)
+	}
+
+	for pos := 0; pos < len(data); {
+		length := int(data[pos])
+		if length == 0 {
+			// Records do not cross sector boundaries; skip the padding
+			pos = (pos/ISOSectorSize + 1) * ISOSectorSize
+			continue
+		}
+		if length < 34 || pos+length > len(data) {
+			return fmt.Errorf("corrupt directory record in %q", dir)
+		}
+		record := data[pos : pos+length]
+		pos += length
+
+		nameLen := int(record[32])
+		if 33+nameLen > len(record) {
+			return fmt.Errorf("corrupt directory record in %q", dir)
+		}
+		name := string(record[33 : 33+nameLen])
+		if name == "\x00" || name == "\x01" {
+			continue
+		}
+		if i := strings.IndexByte(name, ';'); i >= 0 {
+			name = name[:i]
+		}
+		name = strings.TrimSuffix(name, ".")
+
+		extent := binary.LittleEndian.Uint32(record[2:])
+		extentSize := binary.LittleEndian.Uint32(record[10:])
+		flags := record[25]
+		filePath := path.Join(dir, name)
+
+		if flags&0x02 != 0 {
+			if err := readISODir(r, extent, extentSize, filePath, files, visited); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Files over 4 GB are split into several records with the same name
+		if n := len(*files); n > 0 && (*files)[n-1].Path == filePath {
+			(*files)[n-1].Size += int64(extentSize)
+			continue
+		}
+		*files = append(*files, ISOFile{Path: filePath, Sector: extent, Size: int64(extentSize)})
+	}
+	return nil
+}
@@ -2,13 +2,6 @@
 // This file contains parsers for PlayStation 3 (PS3) specific file formats
 package parsers
 
-import (
-	"bytes"
-	"encoding/binary"
-	"fmt"
-	"strings"
-)
-
 // Data format constants for PARAM.SFO entries
 const (
 	FMT_UTF8_SPECIAL = 0x0004 // UTF-8 string (special case)
@@ -38,6 +31,8 @@ type ParamSFOEntry struct {
 type ParamSFO struct {
 	Header  ParamSFOHeader
 	Entries []ParamSFOEntry
+
+	raw []byte // The file it was parsed from, whose layout Write keeps
 }
 
 // GetTitle returns the game title from the PARAM.SFO data
@@ -103,113 +98,15 @@ type rawEntry struct {
 	DataOff   uint32 // Offset in data table
 }
 
-// ParseParamSFO parses a PARAM.SFO file from raw bytes
+// ParseParamSFO parses a PARAM.SFO file from raw bytes. Offsets and lengths are checked
+// against the file size, so a corrupt or hostile file fails with an *SFOFormatError
+// naming the malformed table and entry; ValidateParamSFO lists every problem instead.
 func ParseParamSFO(data []byte) (*ParamSFO, error) {
-	// Check magic header
-	if len(data) < 4 || string(data[:4]) != "\x00PSF" {
-		return nil, fmt.Errorf("not a valid PARAM.SFO file: invalid magic header")
-	}
-
-	if len(data) < 20 {
-		return nil, fmt.Errorf("file too small to contain valid header")
-	}
-
-	// Parse header
-	version := binary.LittleEndian.Uint32(data[4:8])
-	keyTableOffset := binary.LittleEndian.Uint32(data[8:12])
-	dataTableOffset := binary.LittleEndian.Uint32(data[12:16])
-	entryCount := binary.LittleEndian.Uint32(data[16:20])
-
-	// Validate offsets
-	if keyTableOffset >= uint32(len(data)) || dataTableOffset >= uint32(len(data)) {
-		return nil, fmt.Errorf("invalid table offsets")
-	}
-
-	header := ParamSFOHeader{
-		Version:         version,
-		KeyTableOffset:  keyTableOffset,
-		DataTableOffset: dataTableOffset,
-		EntryCount:      entryCount,
-	}
-
-	// Parse raw entries
-	rawEntries := make([]rawEntry, entryCount)
-	for i := uint32(0); i < entryCount; i++ {
-		entryOffset := 20 + i*16
-		if entryOffset+16 > uint32(len(data)) {
-			return nil, fmt.Errorf("entry %d extends beyond file", i)
-		}
-
-		buf := bytes.NewReader(data[entryOffset : entryOffset+16])
-		if err := binary.Read(buf, binary.LittleEndian, &rawEntries[i]); err != nil {
-			return nil, fmt.Errorf("error parsing entry %d: %v", i, err)
-		}
-	}
-
-	// Convert raw entries to structured entries
-	entries := make([]ParamSFOEntry, 0, entryCount)
-	for i, raw := range rawEntries {
-		// Parse key
-		keyStart := int(keyTableOffset) + int(raw.KeyOffset)
-		if keyStart >= len(data) {
-			return nil, fmt.Errorf("entry %d: invalid key offset", i)
+	sfo, problems := scanParamSFO(data)
+	for _, problem := range problems {
+		if problem.Fatal {
+			return nil, &SFOFormatError{Problem: problem}
 		}
-
-		keyEnd := bytes.IndexByte(data[keyStart:], 0)
-		if keyEnd == -1 {
-			return nil, fmt.Errorf("entry %d: key not null-terminated", i)
-		}
-
-		key := string(data[keyStart : keyStart+keyEnd])
-
-		// Parse value
-		valStart := int(dataTableOffset) + int(raw.DataOff)
-		valEnd := valStart + int(raw.DataLen)
-
-		if valStart < 0 || valEnd > len(data) {
-			return nil, fmt.Errorf("entry %d (%s): value out of bounds", i, key)
-		}
-
-		val := data[valStart:valEnd]
-
-		// Format value based on data format
-		var formattedValue interface{}
-		switch raw.DataFmt {
-		case FMT_UTF8_SPECIAL, FMT_UTF8:
-			// UTF-8 string, remove null terminator if present
-			str := string(val)
-			if nullIdx := strings.IndexByte(str, 0); nullIdx != -1 {
-				str = str[:nullIdx]
-			}
-			formattedValue = str
-
-		case FMT_INT32:
-			if len(val) >= 4 {
-				num := binary.LittleEndian.Uint32(val)
-				formattedValue = num
-			} else {
-				return nil, fmt.Errorf("entry %d (%s): invalid integer data", i, key)
-			}
-
-		default:
-			// Store as raw bytes for unsupported formats
-			formattedValue = val
-		}
-
-		entry := ParamSFOEntry{
-			Key:     key,
-			Value:   formattedValue,
-			DataFmt: raw.DataFmt,
-			DataLen: raw.DataLen,
-			DataMax: raw.DataMax,
-			DataOff: raw.DataOff,
-		}
-
-		entries = append(entries, entry)
 	}
-
-	return &ParamSFO{
-		Header:  header,
-		Entries: entries,
-	}, nil
+	return sfo, nil
 }
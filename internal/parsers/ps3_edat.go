@@ -0,0 +1,95 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// NPD license types stored in EDAT/SDAT headers
+const (
+	NPDLicenseNetwork = 1 // Needs an account-bound .rif (PSN purchase)
+	NPDLicenseLocal   = 2 // Needs a .rap/.rif license file
+	NPDLicenseFree    = 3 // Free content, no license needed
+)
+
+// EDAT header flags
+const (
+	EDATFlagCompressed = 0x00000001
+	EDATFlagSDAT       = 0x01000000 // File is an SDAT (key derived from the file, no license)
+)
+
+// EDATHeaderSize is the size of the NPD header (0x80 bytes) plus the EDAT header (0x10 bytes)
+const EDATHeaderSize = 0x90
+
+// EDATHeader holds the identifying fields of a PS3 EDAT or SDAT file
+type EDATHeader struct {
+	Version   uint32 // NPD version (1-4)
+	License   uint32 // NPDLicenseNetwork, NPDLicenseLocal or NPDLicenseFree
+	Type      uint32
+	ContentID string // e.g. UP0001-BLUS30001_00-DLCPACK000000001
+	Flags     uint32
+	BlockSize uint32
+	FileSize  uint64 // Size of the decrypted data
+}
+
+// ParseEDAT parses the header of an EDAT or SDAT file
+func ParseEDAT(data []byte) (*EDATHeader, error) {
+	if len(data) < EDATHeaderSize {
+		return nil, fmt.Errorf("file too small to be an EDAT/SDAT: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[0:4], []byte("NPD\x00")) {
+		return nil, fmt.Errorf("invalid EDAT/SDAT magic: %q", data[0:4])
+	}
+
+	h := &EDATHeader{
+		Version:   binary.BigEndian.Uint32(data[0x04:]),
+		License:   binary.BigEndian.Uint32(data[0x08:]),
+		Type:      binary.BigEndian.Uint32(data[0x0C:]),
+		ContentID: string(bytes.TrimRight(data[0x10:0x40], "\x00")),
+		Flags:     binary.BigEndian.Uint32(data[0x80:]),
+		BlockSize: binary.BigEndian.Uint32(data[0x84:]),
+		FileSize:  binary.BigEndian.Uint64(data[0x88:]),
+	}
+
+	if h.Version < 1 || h.Version > 4 {
+		return nil, fmt.Errorf("unsupported NPD version %d", h.Version)
+	}
+	return h, nil
+}
+
+// IsSDAT reports whether the file is an SDAT rather than an EDAT
+func (h *EDATHeader) IsSDAT() bool {
+	return h.Flags&EDATFlagSDAT != 0
+}
+
+// NeedsLicense reports whether a .rap/.rif license is required to use the file
+func (h *EDATHeader) NeedsLicense() bool {
+	return !h.IsSDAT() && (h.License == NPDLicenseNetwork || h.License == NPDLicenseLocal)
+}
+
+// LicenseName returns a readable name for the license type
+func (h *EDATHeader) LicenseName() string {
+	if h.IsSDAT() {
+		return "none (SDAT)"
+	}
+	switch h.License {
+	case NPDLicenseNetwork:
+		return "network"
+	case NPDLicenseLocal:
+		return "local"
+	case NPDLicenseFree:
+		return "free"
+	default:
+		return fmt.Sprintf("unknown (%d)", h.License)
+	}
+}
+
+// TitleID returns the title ID embedded in the content ID, or "" if it is malformed
+func (h *EDATHeader) TitleID() string {
+	// Content IDs look like XXYYYY-TITLEID00_00-LABEL
+	if len(h.ContentID) < 16 || h.ContentID[6] != '-' {
+		return ""
+	}
+	return h.ContentID[7:16]
+}
@@ -0,0 +1,93 @@
+package parsers
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// buildEDAT writes an NPD and EDAT header
+func buildEDAT(version, license, flags uint32) []byte {
+	data := make([]byte, EDATHeaderSize)
+	be := binary.BigEndian
+	copy(data, "NPD\x00")
+	be.PutUint32(data[0x04:], version)
+	be.PutUint32(data[0x08:], license)
+	be.PutUint32(data[0x0C:], 1)
+	copy(data[0x10:], "UP0001-BLUS30001_00-DLCPACK000000001")
+	be.PutUint32(data[0x80:], flags)
+	be.PutUint32(data[0x84:], 0x4000)
+	be.PutUint64(data[0x88:], 0x123456789)
+	return data
+}
+
+func TestParseEDAT(t *testing.T) {
+	tests := []struct {
+		name         string
+		license      uint32
+		flags        uint32
+		sdat         bool
+		needsLicense bool
+		licenseName  string
+	}{
+		{"EDAT network", NPDLicenseNetwork, 0, false, true, "network"},
+		{"EDAT local", NPDLicenseLocal, EDATFlagCompressed, false, true, "local"},
+		{"EDAT free", NPDLicenseFree, 0, false, false, "free"},
+		{"EDAT unknown license", 7, 0, false, false, "unknown (7)"},
+		{"SDAT", NPDLicenseLocal, EDATFlagSDAT, true, false, "none (SDAT)"},
+		{"SDAT compressed", NPDLicenseFree, EDATFlagSDAT | EDATFlagCompressed, true, false, "none (SDAT)"},
+	}
+	for _, tt := range tests {
+		for _, version := range []uint32{1, 4} {
+			h, err := ParseEDAT(buildEDAT(version, tt.license, tt.flags))
+			if err != nil {
+				t.Fatalf("%s, version %d: %v", tt.name, version, err)
+			}
+			want := EDATHeader{
+				Version:   version,
+				License:   tt.license,
+				Type:      1,
+				ContentID: "UP0001-BLUS30001_00-DLCPACK000000001",
+				Flags:     tt.flags,
+				BlockSize: 0x4000,
+				FileSize:  0x123456789,
+			}
+			if *h != want {
+				t.Errorf("%s: header %+v, want %+v", tt.name, *h, want)
+			}
+			if h.IsSDAT() != tt.sdat || h.NeedsLicense() != tt.needsLicense || h.LicenseName() != tt.licenseName {
+				t.Errorf("%s: SDAT %v, needs license %v, license %q; want %v, %v, %q",
+					tt.name, h.IsSDAT(), h.NeedsLicense(), h.LicenseName(), tt.sdat, tt.needsLicense, tt.licenseName)
+			}
+			if h.TitleID() != "BLUS30001" {
+				t.Errorf("%s: title ID %q", tt.name, h.TitleID())
+			}
+		}
+	}
+}
+
+func TestParseEDATRejectsBadHeaders(t *testing.T) {
+	valid := buildEDAT(3, NPDLicenseLocal, 0)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "too small"},
+		{"short", valid[:EDATHeaderSize-1], "too small"},
+		{"bad magic", append([]byte("NPD\x01"), valid[4:]...), "invalid EDAT/SDAT magic"},
+		{"version 0", buildEDAT(0, NPDLicenseLocal, 0), "unsupported NPD version 0"},
+		{"version 5", buildEDAT(5, NPDLicenseLocal, 0), "unsupported NPD version 5"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseEDAT(tt.data); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// A malformed content ID has no title ID
+	h := &EDATHeader{ContentID: "UP0001BLUS30001_00"}
+	if id := h.TitleID(); id != "" {
+		t.Errorf("title ID %q from a malformed content ID", id)
+	}
+}
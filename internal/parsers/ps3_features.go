@@ -0,0 +1,146 @@
+package parsers
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PS3Languages are the PS3 system languages, indexed by the number in TITLE_00 to TITLE_19
+var PS3Languages = []string{
+	"Japanese", "English (US)", "French", "Spanish", "German", "Italian", "Dutch",
+	"Portuguese (Portugal)", "Russian", "Korean", "Chinese (Traditional)", "Chinese (Simplified)",
+	"Finnish", "Swedish", "Danish", "Norwegian", "Polish", "Portuguese (Brazil)", "English (UK)",
+	"Turkish",
+}
+
+// AttributeFlag is a bit of the PS3 PARAM.SFO ATTRIBUTE value
+type AttributeFlag struct {
+	Bit        uint32
+	Name       string
+	RemotePlay bool // The bit enables Remote Play rather than describing a feature
+}
+
+// PS3AttributeFlags are the known ATTRIBUTE bits, as documented on the PS3 Developer Wiki
+var PS3AttributeFlags = []AttributeFlag{
+	{Bit: 0x00000002, Name: "PSP (v1, MPEG4 SP)", RemotePlay: true},
+	{Bit: 0x00000004, Name: "PSP (v2, MPEG4 AVC)", RemotePlay: true},
+	{Bit: 0x00000008, Name: "XMB in-game notifications"},
+	{Bit: 0x00000010, Name: "XMB in-game disabled"},
+	{Bit: 0x00000020, Name: "Background music"},
+	{Bit: 0x00000040, Name: "Voice chat"},
+	{Bit: 0x00000080, Name: "PS Vita", RemotePlay: true},
+	{Bit: 0x00000100, Name: "Move controller warning"},
+	{Bit: 0x00000200, Name: "Navigation controller warning"},
+	{Bit: 0x00000400, Name: "PlayStation Eye warning"},
+	{Bit: 0x00000800, Name: "Move calibration notice"},
+	{Bit: 0x00001000, Name: "Stereoscopic 3D"},
+	{Bit: 0x00010000, Name: "Install disc"},
+	{Bit: 0x00020000, Name: "Install packages"},
+	{Bit: 0x00080000, Name: "Game purchase enabled"},
+	{Bit: 0x00400000, Name: "PC Engine"},
+	{Bit: 0x00800000, Name: "License logo disabled"},
+	{Bit: 0x01000000, Name: "Move controller"},
+	{Bit: 0x10000000, Name: "Neo Geo"},
+}
+
+// SFOFlag is a named bit of a PARAM.SFO integer
+type SFOFlag struct {
+	Bit  uint32
+	Name string
+}
+
+// PS3Resolutions are the RESOLUTION bits: the video modes a game can output
+var PS3Resolutions = []SFOFlag{
+	{Bit: 0x01, Name: "480p"},
+	{Bit: 0x02, Name: "576p"},
+	{Bit: 0x04, Name: "720p"},
+	{Bit: 0x08, Name: "1080p"},
+	{Bit: 0x10, Name: "480p 16:9"},
+	{Bit: 0x20, Name: "576p 16:9"},
+}
+
+// PS3SoundFormats are the SOUND_FORMAT bits: the audio formats a game can output
+var PS3SoundFormats = []SFOFlag{
+	{Bit: 0x001, Name: "LPCM 2.0"},
+	{Bit: 0x004, Name: "LPCM 5.1"},
+	{Bit: 0x010, Name: "LPCM 7.1"},
+	{Bit: 0x100, Name: "Dolby Digital 5.1"},
+	{Bit: 0x200, Name: "DTS 5.1"},
+}
+
+// DisplayModes maps --resolution values to the RESOLUTION bits that satisfy them
+var DisplayModes = map[string]uint32{
+	"480p":  0x01 | 0x10,
+	"576p":  0x02 | 0x20,
+	"720p":  0x04,
+	"1080p": 0x08,
+}
+
+// AudioSetups maps --audio values to the SOUND_FORMAT bits that satisfy them
+var AudioSetups = map[string]uint32{
+	"stereo": 0x001,
+	"5.1":    0x004 | 0x100 | 0x200,
+	"7.1":    0x010,
+}
+
+// flagNames returns the names of the flags set in value
+func flagNames(flags []SFOFlag, value uint32) []string {
+	var names []string
+	for _, flag := range flags {
+		if value&flag.Bit != 0 {
+			names = append(names, flag.Name)
+		}
+	}
+	return names
+}
+
+// Features describes the decoded PARAM.SFO fields that are stored as codes
+type Features struct {
+	Languages  []string `json:"languages"`                   // Languages with a localized title
+	Features   []string `json:"features"`                    // ATTRIBUTE flags other than Remote Play
+	RemotePlay []string `json:"remotePlay"`                  // Remote Play clients the game allows
+	Resolution []string `json:"resolutions"`                 // Video modes from RESOLUTION
+	Sound      []string `json:"soundFormats"`                // Audio formats from SOUND_FORMAT
+	Unknown    uint32   `json:"unknownAttributes,omitempty"` // ATTRIBUTE bits with no known meaning
+}
+
+// GetFeatures decodes the languages from the TITLE_xx entries, the ATTRIBUTE flags and
+// the RESOLUTION and SOUND_FORMAT bits
+func (p *ParamSFO) GetFeatures() Features {
+	var f Features
+	for i, language := range PS3Languages {
+		if p.GetString(fmt.Sprintf("TITLE_%02d", i)) != "" {
+			f.Languages = append(f.Languages, language)
+		}
+	}
+
+	attribute := p.GetInt("ATTRIBUTE")
+	known := uint32(0)
+	for _, flag := range PS3AttributeFlags {
+		known |= flag.Bit
+		if attribute&flag.Bit == 0 {
+			continue
+		}
+		if flag.RemotePlay {
+			f.RemotePlay = append(f.RemotePlay, flag.Name)
+		} else {
+			f.Features = append(f.Features, flag.Name)
+		}
+	}
+	f.Unknown = attribute &^ known
+
+	f.Resolution = flagNames(PS3Resolutions, p.GetInt("RESOLUTION"))
+	f.Sound = flagNames(PS3SoundFormats, p.GetInt("SOUND_FORMAT"))
+	return f
+}
+
+// FormatAttribute returns an ATTRIBUTE value in hex with the names of its known bits
+func FormatAttribute(attribute uint32) string {
+	s := "0x" + strconv.FormatUint(uint64(attribute), 16)
+	for _, flag := range PS3AttributeFlags {
+		if attribute&flag.Bit != 0 {
+			s += ", " + flag.Name
+		}
+	}
+	return s
+}
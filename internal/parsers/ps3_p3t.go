@@ -0,0 +1,126 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// P3THeaderSize is the size of the header of a PS3 theme (.p3t): the magic, a version
+// and the offset and size of each table of its CXML document
+const P3THeaderSize = 0x38
+
+// p3tMaxTable bounds the tree and string tables read from a theme; the images and
+// sounds are in the file table, which is never read
+const p3tMaxTable = 4 << 20
+
+// CXML attribute type holding a string (offset and length in the string table)
+const cxmlAttrString = 3
+
+// P3TInfo is what a PS3 theme says about itself
+type P3TInfo struct {
+	Version uint32
+	Name    string // Theme name, "" when the theme doesn't carry one
+}
+
+// ReadP3TInfo reads the header and name of a PS3 theme file. The name is a string
+// attribute of its <name> (or <title>) element, or of an element directly inside it
+// such as <localizedString>, other than a locale or ID; the first one found wins.
+func ReadP3TInfo(path string) (*P3TInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, P3THeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("file too small to be a theme: %w", err)
+	}
+	if !bytes.Equal(header[0:4], []byte("P3TF")) {
+		return nil, fmt.Errorf("invalid theme magic: %q", header[0:4])
+	}
+	info := &P3TInfo{Version: binary.BigEndian.Uint32(header[0x04:])}
+
+	tree, err := readP3TTable(f, header[0x08:])
+	if err != nil {
+		return nil, fmt.Errorf("reading theme tree: %w", err)
+	}
+	strs, err := readP3TTable(f, header[0x18:])
+	if err != nil {
+		return nil, fmt.Errorf("reading theme strings: %w", err)
+	}
+	info.Name = p3tName(tree, strs)
+	return info, nil
+}
+
+// readP3TTable reads the table whose offset and size are at the start of entry
+func readP3TTable(f *os.File, entry []byte) ([]byte, error) {
+	offset := binary.BigEndian.Uint32(entry[0:])
+	size := binary.BigEndian.Uint32(entry[4:])
+	if size > p3tMaxTable {
+		return nil, fmt.Errorf("table of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, int64(offset)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// p3tName walks the elements of a CXML tree in order. Each element is seven int32
+// (name, attribute count, parent, previous, next, first and last child, the links
+// being tree offsets) followed by 16-byte attributes (name, type, two values).
+func p3tName(tree, strs []byte) string {
+	nameOf := make(map[int32]string) // Element name by tree offset
+	for pos := 0; pos+28 <= len(tree); {
+		element := cxmlString(strs, int32(binary.BigEndian.Uint32(tree[pos:])), -1)
+		attrs := int(int32(binary.BigEndian.Uint32(tree[pos+4:])))
+		parent := nameOf[int32(binary.BigEndian.Uint32(tree[pos+8:]))]
+		nameOf[int32(pos)] = element
+		if attrs < 0 || pos+28+attrs*16 > len(tree) {
+			return ""
+		}
+
+		named := isP3TNameElement(element) || isP3TNameElement(parent)
+		for i := 0; i < attrs; i++ {
+			attr := tree[pos+28+i*16:]
+			if !named || binary.BigEndian.Uint32(attr[4:]) != cxmlAttrString {
+				continue
+			}
+			switch strings.ToLower(cxmlString(strs, int32(binary.BigEndian.Uint32(attr[0:])), -1)) {
+			case "locale", "lang", "id":
+				continue
+			}
+			value := cxmlString(strs, int32(binary.BigEndian.Uint32(attr[8:])), int32(binary.BigEndian.Uint32(attr[12:])))
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+		pos += 28 + attrs*16
+	}
+	return ""
+}
+
+func isP3TNameElement(name string) bool {
+	return strings.EqualFold(name, "name") || strings.EqualFold(name, "title")
+}
+
+// cxmlString returns the string at offset in a CXML string table: length bytes, or up
+// to the terminating NUL when length is negative. Out-of-range strings are "".
+func cxmlString(strs []byte, offset, length int32) string {
+	if offset < 0 || int(offset) >= len(strs) {
+		return ""
+	}
+	data := strs[offset:]
+	if length >= 0 && int(length) <= len(data) {
+		data = data[:length]
+	}
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+	return string(data)
+}
@@ -0,0 +1,231 @@
+package parsers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PKGHeaderSize is the size of the PKG header fields read by ParsePKG
+const PKGHeaderSize = 0x80
+
+// PKGItemSize is the size of one entry in the encrypted item table of a PKG
+const PKGItemSize = 0x20
+
+// PKG revisions
+const (
+	PKGRevisionDebug  = 0x0000
+	PKGRevisionRetail = 0x8000
+)
+
+// PKG content types from the metadata of a package
+const (
+	PKGContentGameData = 0x04 // Game updates and add-on data
+	PKGContentGameExec = 0x05 // Complete PSN games
+	PKGContentTheme    = 0x09
+	PKGContentWidget   = 0x0A
+	PKGContentLicense  = 0x0B
+	PKGContentAvatar   = 0x0D
+	PKGContentMinis    = 0x0F
+)
+
+// ps3PKGKey is the public AES key retail PS3 packages are encrypted with
+var ps3PKGKey = []byte{0x2E, 0x7B, 0x71, 0xD7, 0xC9, 0xC9, 0xA1, 0x4E, 0xA3, 0x22, 0x1F, 0x18, 0x88, 0x28, 0xB8, 0xF8}
+
+// PKGHeader holds the identifying fields of a PS3 installer package
+type PKGHeader struct {
+	Revision    uint16 // PKGRevisionRetail or PKGRevisionDebug
+	Type        uint16 // 1 for PS3
+	ItemCount   uint32
+	TotalSize   uint64
+	DataOffset  uint64 // Start of the encrypted item table and file data
+	DataSize    uint64
+	ContentID   string // e.g. UP0001-BLUS30490_00-GTAIVDLCPACK0001
+	ContentType uint32 // PKGContentGameData, ... (0 when the metadata has none)
+	Digest      [16]byte
+	DataRIV     [16]byte // Initial counter of the data encryption
+}
+
+// PKGItem is a file or directory stored in a package
+type PKGItem struct {
+	Name   string // Path relative to the install folder, e.g. USRDIR/EBOOT.BIN
+	Offset uint64 // Relative to the data start
+	Size   uint64
+	Flags  uint32
+}
+
+// IsDir reports whether the item is a directory
+func (i PKGItem) IsDir() bool {
+	return i.Flags&0xFF == 0x04
+}
+
+// ParsePKG parses the header of a PS3 .pkg file from its first bytes. The content type
+// is read from the metadata that follows the header when data includes it.
+func ParsePKG(data []byte) (*PKGHeader, error) {
+	if len(data) < PKGHeaderSize {
+		return nil, fmt.Errorf("file too small to be a PKG: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[0:4], []byte("\x7FPKG")) {
+		return nil, fmt.Errorf("invalid PKG magic: %q", data[0:4])
+	}
+
+	h := &PKGHeader{
+		Revision:   binary.BigEndian.Uint16(data[0x04:]),
+		Type:       binary.BigEndian.Uint16(data[0x06:]),
+		ItemCount:  binary.BigEndian.Uint32(data[0x14:]),
+		TotalSize:  binary.BigEndian.Uint64(data[0x18:]),
+		DataOffset: binary.BigEndian.Uint64(data[0x20:]),
+		DataSize:   binary.BigEndian.Uint64(data[0x28:]),
+		ContentID:  string(bytes.TrimRight(data[0x30:0x60], "\x00")),
+	}
+	copy(h.Digest[:], data[0x60:0x70])
+	copy(h.DataRIV[:], data[0x70:0x80])
+
+	// Metadata is a list of (id, size, value) entries; id 2 holds the content type
+	offset := uint64(binary.BigEndian.Uint32(data[0x08:]))
+	count := binary.BigEndian.Uint32(data[0x0C:])
+	for i := uint32(0); i < count && offset+8 <= uint64(len(data)); i++ {
+		id := binary.BigEndian.Uint32(data[offset:])
+		size := uint64(binary.BigEndian.Uint32(data[offset+4:]))
+		if id == 2 && size >= 4 && offset+12 <= uint64(len(data)) {
+			h.ContentType = binary.BigEndian.Uint32(data[offset+8:])
+			break
+		}
+		offset += 8 + size
+	}
+	return h, nil
+}
+
+// ReadPKGHeader reads the header of a .pkg file
+func ReadPKGHeader(path string) (*PKGHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, 0x400)
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	h, err := ParsePKG(data[:n])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return h, nil
+}
+
+// TitleID returns the title ID embedded in the content ID (e.g. BLUS30490), or "" if malformed
+func (h *PKGHeader) TitleID() string {
+	if len(h.ContentID) < 16 || h.ContentID[6] != '-' {
+		return ""
+	}
+	return h.ContentID[7:16]
+}
+
+// ContentTypeName returns a readable name for the content type
+func (h *PKGHeader) ContentTypeName() string {
+	switch h.ContentType {
+	case PKGContentGameData:
+		return "game data (update or add-on)"
+	case PKGContentGameExec:
+		return "game"
+	case PKGContentTheme:
+		return "theme"
+	case PKGContentWidget:
+		return "widget"
+	case PKGContentLicense:
+		return "license"
+	case PKGContentAvatar:
+		return "avatar"
+	case PKGContentMinis:
+		return "minis game"
+	case 0:
+		return "unknown"
+	}
+	return fmt.Sprintf("0x%02X", h.ContentType)
+}
+
+// Decrypt decrypts (or encrypts) data found at offset from the data start in place.
+// Retail packages use AES-CTR with the public PS3 key; debug packages a SHA-1 keystream.
+func (h *PKGHeader) Decrypt(data []byte, offset uint64) error {
+	var block func(counter uint64, out []byte)
+	switch h.Revision {
+	case PKGRevisionRetail:
+		cipher, err := aes.NewCipher(ps3PKGKey)
+		if err != nil {
+			return err
+		}
+		block = func(counter uint64, out []byte) {
+			var iv [16]byte
+			copy(iv[:], h.DataRIV[:])
+			addCounter(&iv, counter)
+			cipher.Encrypt(out, iv[:])
+		}
+	case PKGRevisionDebug:
+		var seed [0x40]byte
+		copy(seed[0x00:], h.Digest[0:8])
+		copy(seed[0x08:], h.Digest[0:8])
+		copy(seed[0x10:], h.Digest[8:16])
+		copy(seed[0x18:], h.Digest[8:16])
+		block = func(counter uint64, out []byte) {
+			binary.BigEndian.PutUint64(seed[0x38:], counter)
+			sum := sha1.Sum(seed[:])
+			copy(out, sum[:16])
+		}
+	default:
+		return fmt.Errorf("unsupported PKG revision 0x%04X", h.Revision)
+	}
+
+	var key [16]byte
+	for i := range data {
+		pos := offset + uint64(i)
+		if i == 0 || pos%16 == 0 {
+			block(pos/16, key[:])
+		}
+		data[i] ^= key[pos%16]
+	}
+	return nil
+}
+
+// addCounter adds n to a 128-bit big-endian counter
+func addCounter(counter *[16]byte, n uint64) {
+	low := binary.BigEndian.Uint64(counter[8:])
+	sum := low + n
+	binary.BigEndian.PutUint64(counter[8:], sum)
+	if sum < low {
+		high := binary.BigEndian.Uint64(counter[:8])
+		binary.BigEndian.PutUint64(counter[:8], high+1)
+	}
+}
+
+// ParsePKGItems parses the decrypted item table and the file names it points to.
+// table holds the decrypted data from the data start, at least up to the last name.
+func ParsePKGItems(h *PKGHeader, table []byte) ([]PKGItem, error) {
+	// The count comes from the file; the table it claims may not be there
+	items := make([]PKGItem, 0, min(uint64(h.ItemCount), uint64(len(table))/PKGItemSize))
+	for i := uint64(0); i < uint64(h.ItemCount); i++ {
+		entry := i * PKGItemSize
+		if entry+PKGItemSize > uint64(len(table)) {
+			return nil, fmt.Errorf("PKG item table truncated at item %d", i)
+		}
+		nameOffset := uint64(binary.BigEndian.Uint32(table[entry:]))
+		nameSize := uint64(binary.BigEndian.Uint32(table[entry+4:]))
+		if nameOffset+nameSize > uint64(len(table)) {
+			return nil, fmt.Errorf("PKG item %d name out of range", i)
+		}
+		items = append(items, PKGItem{
+			Name:   strings.TrimRight(string(table[nameOffset:nameOffset+nameSize]), "\x00"),
+			Offset: binary.BigEndian.Uint64(table[entry+8:]),
+			Size:   binary.BigEndian.Uint64(table[entry+16:]),
+			Flags:  binary.BigEndian.Uint32(table[entry+24:]),
+		})
+	}
+	return items, nil
+}
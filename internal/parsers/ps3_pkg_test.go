@@ -0,0 +1,207 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// buildPKGHeader writes a PKG header followed by metadata entries of (id, value) with
+// 4-byte values
+func buildPKGHeader(revision uint16, metadata ...[2]uint32) []byte {
+	data := make([]byte, PKGHeaderSize)
+	be := binary.BigEndian
+	copy(data, "\x7FPKG")
+	be.PutUint16(data[0x04:], revision)
+	be.PutUint16(data[0x06:], 1)
+	be.PutUint32(data[0x08:], PKGHeaderSize)
+	be.PutUint32(data[0x0C:], uint32(len(metadata)))
+	be.PutUint32(data[0x14:], 2)
+	be.PutUint64(data[0x18:], 0x12345)
+	be.PutUint64(data[0x20:], 0x140)
+	be.PutUint64(data[0x28:], 0x10000)
+	copy(data[0x30:], "UP0001-BLUS30490_00-GTAIVDLCPACK0001")
+	for i := range data[0x60:0x80] {
+		data[0x60+i] = byte(i)
+	}
+	for _, entry := range metadata {
+		data = be.AppendUint32(data, entry[0])
+		data = be.AppendUint32(data, 4)
+		data = be.AppendUint32(data, entry[1])
+	}
+	return data
+}
+
+func TestParsePKG(t *testing.T) {
+	data := buildPKGHeader(PKGRevisionRetail, [2]uint32{1, 0x2}, [2]uint32{2, PKGContentGameData}, [2]uint32{3, 0x9})
+	h, err := ParsePKG(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PKGHeader{
+		Revision:    PKGRevisionRetail,
+		Type:        1,
+		ItemCount:   2,
+		TotalSize:   0x12345,
+		DataOffset:  0x140,
+		DataSize:    0x10000,
+		ContentID:   "UP0001-BLUS30490_00-GTAIVDLCPACK0001",
+		ContentType: PKGContentGameData,
+		Digest:      [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		DataRIV:     [16]byte{16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31},
+	}
+	if *h != want {
+		t.Errorf("header %+v, want %+v", *h, want)
+	}
+	if h.TitleID() != "BLUS30490" || h.ContentTypeName() != "game data (update or add-on)" {
+		t.Errorf("title ID %q, content type %q", h.TitleID(), h.ContentTypeName())
+	}
+
+	// Metadata cut off by the end of the data leaves the content type unknown
+	h, err = ParsePKG(data[:PKGHeaderSize+12+10])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ContentType != 0 || h.ContentTypeName() != "unknown" {
+		t.Errorf("content type %#x from truncated metadata", h.ContentType)
+	}
+
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"short", data[:PKGHeaderSize-1], "too small"},
+		{"bad magic", append([]byte("\x7FPKH"), data[4:]...), "invalid PKG magic"},
+	} {
+		if _, err := ParsePKG(tt.data); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAddCounter(t *testing.T) {
+	tests := []struct {
+		counter string
+		n       uint64
+		want    string
+	}{
+		{"00000000000000000000000000000000", 1, "00000000000000000000000000000001"},
+		{"000000000000000000000000000000ff", 1, "00000000000000000000000000000100"},
+		{"0000000000000000ffffffffffffffff", 1, "00000000000000010000000000000000"},
+		{"00000000000000ffffffffffffffffff", 2, "00000000000001000000000000000001"},
+		{"ffffffffffffffffffffffffffffffff", 1, "00000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		var counter [16]byte
+		hex.Decode(counter[:], []byte(tt.counter))
+		addCounter(&counter, tt.n)
+		if got := hex.EncodeToString(counter[:]); got != tt.want {
+			t.Errorf("%s + %d = %s, want %s", tt.counter, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPKGDecrypt(t *testing.T) {
+	tests := []struct {
+		name   string
+		header PKGHeader
+		// Keystream of the first three 16-byte blocks, from openssl enc -aes-128-ctr
+		// for retail and Python's hashlib for debug packages
+		keystream string
+	}{
+		{
+			name: "retail, counter carrying into the high half",
+			header: PKGHeader{
+				Revision: PKGRevisionRetail,
+				DataRIV:  [16]byte{8: 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+			},
+			keystream: "b12b173b2bcd2d6ae5bf35d1ab370459" + "f55819bed897394e5065afed29f4b219" + "345bb58317a6d2b1cc6c161dcde98327",
+		},
+		{
+			name: "debug",
+			header: PKGHeader{
+				Revision: PKGRevisionDebug,
+				Digest:   [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			},
+			keystream: "b886318ba10b52de6c7b57179a1e4831" + "e991cb3af258e53ca90f0cf42d0a3323" + "c0ea7618dc1ffe9e7911352beb75d7db",
+		},
+	}
+	for _, tt := range tests {
+		want, _ := hex.DecodeString(tt.keystream)
+
+		// Whole blocks, and a range starting and ending inside blocks
+		for _, span := range [][2]int{{0, 48}, {5, 37}} {
+			data := make([]byte, span[1]-span[0])
+			if err := tt.header.Decrypt(data, uint64(span[0])); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want[span[0]:span[1]]) {
+				t.Errorf("%s, bytes %d-%d: keystream %x, want %x", tt.name, span[0], span[1], data, want[span[0]:span[1]])
+			}
+		}
+	}
+
+	h := PKGHeader{Revision: 0x1234}
+	if err := h.Decrypt(make([]byte, 16), 0); err == nil || !strings.Contains(err.Error(), "unsupported PKG revision 0x1234") {
+		t.Errorf("unknown revision: error %v", err)
+	}
+}
+
+// buildPKGItems writes an item table followed by the item names
+func buildPKGItems(names ...string) []byte {
+	be := binary.BigEndian
+	table := make([]byte, len(names)*PKGItemSize)
+	for i, name := range names {
+		entry := table[i*PKGItemSize:]
+		be.PutUint32(entry[0:], uint32(len(table)))
+		be.PutUint32(entry[4:], uint32(len(name)))
+		be.PutUint64(entry[8:], uint64(0x1000*(i+1)))
+		be.PutUint64(entry[16:], uint64(0x10*(i+1)))
+		be.PutUint32(entry[24:], uint32(3+i)) // A file, then a directory
+		table = append(table, name...)
+		for len(table)%16 != 0 {
+			table = append(table, 0)
+		}
+	}
+	return table
+}
+
+func TestParsePKGItems(t *testing.T) {
+	table := buildPKGItems("USRDIR/EBOOT.BIN", "USRDIR")
+	items, err := ParsePKGItems(&PKGHeader{ItemCount: 2}, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PKGItem{
+		{Name: "USRDIR/EBOOT.BIN", Offset: 0x1000, Size: 0x10, Flags: 3},
+		{Name: "USRDIR", Offset: 0x2000, Size: 0x20, Flags: 4},
+	}
+	if len(items) != len(want) || items[0] != want[0] || items[1] != want[1] {
+		t.Errorf("items %+v, want %+v", items, want)
+	}
+	if items[0].IsDir() || !items[1].IsDir() {
+		t.Errorf("IsDir %v, %v", items[0].IsDir(), items[1].IsDir())
+	}
+
+	outOfRange := append([]byte{}, table...)
+	binary.BigEndian.PutUint32(outOfRange[PKGItemSize+4:], uint32(len(table)))
+
+	for _, tt := range []struct {
+		name  string
+		count uint32
+		table []byte
+		want  string
+	}{
+		{"name out of range", 2, outOfRange, "PKG item 1 name out of range"},
+		{"truncated table", 3, table[:3*PKGItemSize-1], "truncated at item 2"},
+		{"huge count", 0xFFFFFFFF, table, "PKG item 2"}, // The names are read as item 2
+	} {
+		_, err := ParsePKGItems(&PKGHeader{ItemCount: tt.count}, tt.table)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
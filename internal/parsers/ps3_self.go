@@ -0,0 +1,46 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// SCEHeaderSize is the size of the header that starts every PS3 executable
+const SCEHeaderSize = 0x20
+
+// SCE header types
+const (
+	SCETypeSELF = 1
+	SCETypeRVK  = 2
+	SCETypePKG  = 3
+	SCETypeSPP  = 4
+)
+
+// SCEHeader is the plain header of a SELF (EBOOT.BIN, .self, .sprx)
+type SCEHeader struct {
+	Version        uint32
+	KeyRevision    uint16 // Revision of the keys the executable is signed with; newer firmware brings newer keys
+	HeaderType     uint16 // SCETypeSELF for executables
+	MetadataOffset uint32
+	HeaderLength   uint64
+	DataLength     uint64
+}
+
+// ParseSCEHeader parses the SCE header at the start of a SELF
+func ParseSCEHeader(data []byte) (*SCEHeader, error) {
+	if len(data) < SCEHeaderSize {
+		return nil, fmt.Errorf("file too small to be a SELF: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[0:4], []byte("SCE\x00")) {
+		return nil, fmt.Errorf("invalid SELF magic: %q", data[0:4])
+	}
+	return &SCEHeader{
+		Version:        binary.BigEndian.Uint32(data[0x04:]),
+		KeyRevision:    binary.BigEndian.Uint16(data[0x08:]),
+		HeaderType:     binary.BigEndian.Uint16(data[0x0A:]),
+		MetadataOffset: binary.BigEndian.Uint32(data[0x0C:]),
+		HeaderLength:   binary.BigEndian.Uint64(data[0x10:]),
+		DataLength:     binary.BigEndian.Uint64(data[0x18:]),
+	}, nil
+}
@@ -0,0 +1,48 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// DiscSFB represents a parsed PS3_DISC.SFB file, the disc's own record of its title ID
+// and content flags
+type DiscSFB struct {
+	Version uint32
+	Entries map[string]string // e.g. TITLE_ID: "BLUS-30001", HYBRID_FLAG: "g"
+}
+
+// TitleID returns the disc's title ID without its dash (BLUS30001), or "" if it has none
+func (s *DiscSFB) TitleID() string {
+	return strings.ReplaceAll(s.Entries["TITLE_ID"], "-", "")
+}
+
+// ParseDiscSFB parses a PS3_DISC.SFB file from raw bytes. The file starts with ".SFB"
+// and a big-endian version, followed at 0x20 by 32-byte entries (a 16-byte key, then the
+// offset and length of its value) until an empty key.
+func ParseDiscSFB(data []byte) (*DiscSFB, error) {
+	if len(data) < 0x20 || string(data[:4]) != ".SFB" {
+		return nil, fmt.Errorf("not a valid PS3_DISC.SFB file: invalid magic header")
+	}
+
+	sfb := &DiscSFB{
+		Version: binary.BigEndian.Uint32(data[4:8]),
+		Entries: map[string]string{},
+	}
+	for off := 0x20; off+0x20 <= len(data); off += 0x20 {
+		key := string(bytes.TrimRight(data[off:off+0x10], "\x00"))
+		if key == "" {
+			break
+		}
+		valueOff := binary.BigEndian.Uint32(data[off+0x10 : off+0x14])
+		valueLen := binary.BigEndian.Uint32(data[off+0x14 : off+0x18])
+		if uint64(valueOff)+uint64(valueLen) > uint64(len(data)) {
+			return nil, fmt.Errorf("value of %s extends beyond file", key)
+		}
+		value := data[valueOff : valueOff+valueLen]
+		sfb.Entries[key] = string(bytes.TrimRight(value, "\x00 "))
+	}
+	return sfb, nil
+}
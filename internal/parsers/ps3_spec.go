@@ -0,0 +1,187 @@
+package parsers
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SFOSpec is a human-editable description of a PARAM.SFO file.
+// It can be written as YAML or JSON (JSON is accepted as a subset of YAML).
+//
+// Example:
+//
+//	version: "1.1"
+//	sort_keys: true
+//	entries:
+//	  - key: TITLE
+//	    value: My Homebrew
+//	    max: 128
+//	  - key: TITLE_ID
+//	    value: NPUB90001
+//	  - key: ATTRIBUTE
+//	    format: int32
+//	    value: 0
+type SFOSpec struct {
+	Version       string         `yaml:"version"`        // Header version as "major.minor" (default 1.1)
+	KeyAlignment  uint32         `yaml:"key_alignment"`  // Key table alignment in bytes (default 4)
+	DataAlignment uint32         `yaml:"data_alignment"` // Data slot alignment in bytes (default 4)
+	SortKeys      bool           `yaml:"sort_keys"`      // Sort entries by key before writing
+	Entries       []SFOSpecEntry `yaml:"entries"`
+}
+
+// SFOSpecEntry describes a single PARAM.SFO entry in a spec file
+type SFOSpecEntry struct {
+	Key    string      `yaml:"key"`
+	Format string      `yaml:"format"` // utf8, utf8-special, int32 or a numeric format code such as 0x0204
+	Value  interface{} `yaml:"value"`  // String, integer, or hex string for raw formats
+	Max    uint32      `yaml:"max"`    // Reserved data length (default: value length rounded to alignment)
+}
+
+// ParseSFOSpec parses a YAML or JSON PARAM.SFO spec
+func ParseSFOSpec(data []byte) (*SFOSpec, error) {
+	var spec SFOSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+	if len(spec.Entries) == 0 {
+		return nil, fmt.Errorf("spec contains no entries")
+	}
+	return &spec, nil
+}
+
+// Build converts the spec into a ParamSFO and the options needed to marshal it
+func (s *SFOSpec) Build() (*ParamSFO, MarshalOptions, error) {
+	opts := MarshalOptions{
+		KeyAlignment:  s.KeyAlignment,
+		DataAlignment: s.DataAlignment,
+		SortKeys:      s.SortKeys,
+	}
+
+	sfo := NewParamSFO()
+	if s.Version != "" {
+		version, err := parseSFOVersion(s.Version)
+		if err != nil {
+			return nil, opts, err
+		}
+		sfo.Header.Version = version
+	}
+
+	for i, e := range s.Entries {
+		if e.Key == "" {
+			return nil, opts, fmt.Errorf("entry %d: missing key", i)
+		}
+
+		dataFmt, err := parseSFOFormat(e.Format, e.Value)
+		if err != nil {
+			return nil, opts, fmt.Errorf("entry %d (%s): %w", i, e.Key, err)
+		}
+
+		value, err := convertSpecValue(dataFmt, e.Value)
+		if err != nil {
+			return nil, opts, fmt.Errorf("entry %d (%s): %w", i, e.Key, err)
+		}
+
+		sfo.Entries = append(sfo.Entries, ParamSFOEntry{
+			Key:     e.Key,
+			Value:   value,
+			DataFmt: dataFmt,
+			DataMax: e.Max,
+		})
+	}
+
+	return sfo, opts, nil
+}
+
+// parseSFOVersion converts "major.minor" into the header version field
+func parseSFOVersion(version string) (uint32, error) {
+	parts := strings.SplitN(version, ".", 2)
+	major, err := strconv.ParseUint(parts[0], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q", version)
+	}
+	var minor uint64
+	if len(parts) == 2 {
+		minor, err = strconv.ParseUint(parts[1], 10, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", version)
+		}
+	}
+	return uint32(major) | uint32(minor)<<8, nil
+}
+
+// parseSFOFormat resolves a format name or code, inferring it from the value when empty
+func parseSFOFormat(format string, value interface{}) (uint16, error) {
+	switch strings.ToLower(format) {
+	case "":
+		switch value.(type) {
+		case int, uint64:
+			return FMT_INT32, nil
+		default:
+			return FMT_UTF8, nil
+		}
+	case "utf8", "string":
+		return FMT_UTF8, nil
+	case "utf8-special", "utf8s":
+		return FMT_UTF8_SPECIAL, nil
+	case "int32", "int":
+		return FMT_INT32, nil
+	}
+
+	code, err := strconv.ParseUint(format, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("unknown format %q", format)
+	}
+	return uint16(code), nil
+}
+
+// convertSpecValue converts a decoded YAML value into the Go type expected for the format
+func convertSpecValue(dataFmt uint16, value interface{}) (interface{}, error) {
+	switch dataFmt {
+	case FMT_UTF8, FMT_UTF8_SPECIAL:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case nil:
+			return "", nil
+		default:
+			return fmt.Sprintf("%v", v), nil
+		}
+
+	case FMT_INT32:
+		switch v := value.(type) {
+		case int:
+			if v < 0 || int64(v) > 0xFFFFFFFF {
+				return nil, fmt.Errorf("integer %d out of range", v)
+			}
+			return uint32(v), nil
+		case uint64:
+			if v > 0xFFFFFFFF {
+				return nil, fmt.Errorf("integer %d out of range", v)
+			}
+			return uint32(v), nil
+		case string:
+			num, err := strconv.ParseUint(v, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer %q", v)
+			}
+			return uint32(num), nil
+		default:
+			return nil, fmt.Errorf("int32 entries require an integer value")
+		}
+
+	default:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("format 0x%04X requires a hex string value", dataFmt)
+		}
+		raw, err := hex.DecodeString(strings.ReplaceAll(str, " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex data: %w", err)
+		}
+		return raw, nil
+	}
+}
@@ -0,0 +1,231 @@
+package parsers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxParamSFOSize caps the PARAM.SFO files that are read. Retail files are a few KB and
+// save data ones under 16 KB, so anything near this is corrupt or hostile.
+const MaxParamSFOSize = 1 << 20
+
+// PARAM.SFO layout sizes
+const (
+	sfoHeaderSize     = 20
+	sfoIndexEntrySize = 16
+)
+
+// PARAM.SFO tables named in validation problems
+const (
+	SFOHeader    = "header"
+	SFOIndex     = "index"
+	SFOKeyTable  = "key table"
+	SFODataTable = "data table"
+)
+
+// SFOProblem is a malformed or suspicious part of a PARAM.SFO file
+type SFOProblem struct {
+	Fatal   bool   `json:"fatal"`         // The file can't be read; otherwise it only breaks the format's conventions
+	Table   string `json:"table"`         // SFOHeader, SFOIndex, SFOKeyTable or SFODataTable
+	Entry   int    `json:"entry"`         // Index entry, or -1 for the file as a whole
+	Key     string `json:"key,omitempty"` // The entry's key, when it could be read
+	Offset  int64  `json:"offset"`        // Where in the file the problem is
+	Message string `json:"message"`
+}
+
+// String describes the problem with where it is, e.g. "index entry 3 (TITLE) at 0x2c: ..."
+func (p SFOProblem) String() string {
+	where := p.Table
+	if p.Entry >= 0 {
+		where += fmt.Sprintf(" entry %d", p.Entry)
+		if p.Key != "" {
+			where += " (" + p.Key + ")"
+		}
+	}
+	return fmt.Sprintf("%s at 0x%x: %s", where, p.Offset, p.Message)
+}
+
+// SFOFormatError is returned by ParseParamSFO for a file it can't read
+type SFOFormatError struct {
+	Problem SFOProblem
+}
+
+func (e *SFOFormatError) Error() string {
+	return "not a valid PARAM.SFO file: " + e.Problem.String()
+}
+
+// ValidateParamSFO checks a PARAM.SFO file and returns every problem found, in file order.
+// The file can be parsed when none of them is Fatal.
+func ValidateParamSFO(data []byte) []SFOProblem {
+	_, problems := scanParamSFO(data)
+	return problems
+}
+
+// ReadParamSFOFile reads a PARAM.SFO file for ParseParamSFO or ValidateParamSFO,
+// refusing files larger than MaxParamSFOSize before reading them
+func ReadParamSFOFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxParamSFOSize {
+		return nil, &SFOFormatError{Problem: sizeProblem(info.Size())}
+	}
+	return os.ReadFile(path)
+}
+
+func sizeProblem(size int64) SFOProblem {
+	return SFOProblem{Fatal: true, Table: SFOHeader, Entry: -1, Message: fmt.Sprintf("file is %d bytes, more than the %d a PARAM.SFO can be", size, MaxParamSFOSize)}
+}
+
+// scanParamSFO parses a PARAM.SFO file, carrying on past malformed entries so every
+// problem is reported. Every offset and length is checked against the file size before
+// it is used, so no table or value is read, or allocated, beyond the file.
+func scanParamSFO(data []byte) (*ParamSFO, []SFOProblem) {
+	var problems []SFOProblem
+	report := func(fatal bool, table string, entry int, key string, offset uint64, format string, args ...interface{}) {
+		problems = append(problems, SFOProblem{Fatal: fatal, Table: table, Entry: entry, Key: key, Offset: int64(offset), Message: fmt.Sprintf(format, args...)})
+	}
+
+	size := uint64(len(data))
+	if size > MaxParamSFOSize {
+		return nil, []SFOProblem{sizeProblem(int64(size))}
+	}
+	if size < 4 || string(data[:4]) != "\x00PSF" {
+		report(true, SFOHeader, -1, "", 0, "invalid magic header")
+		return nil, problems
+	}
+	if size < sfoHeaderSize {
+		report(true, SFOHeader, -1, "", 0, "file too small to contain valid header")
+		return nil, problems
+	}
+
+	header := ParamSFOHeader{
+		Version:         binary.LittleEndian.Uint32(data[4:8]),
+		KeyTableOffset:  binary.LittleEndian.Uint32(data[8:12]),
+		DataTableOffset: binary.LittleEndian.Uint32(data[12:16]),
+		EntryCount:      binary.LittleEndian.Uint32(data[16:20]),
+	}
+	keyTable, dataTable := uint64(header.KeyTableOffset), uint64(header.DataTableOffset)
+	indexEnd := sfoHeaderSize + uint64(header.EntryCount)*sfoIndexEntrySize
+
+	if header.Version != DefaultParamSFOVersion {
+		report(false, SFOHeader, -1, "", 4, "unusual version 0x%08x (retail files are 1.1, 0x%08x)", header.Version, DefaultParamSFOVersion)
+	}
+	if keyTable >= size {
+		report(true, SFOHeader, -1, "", 8, "key table offset 0x%x is beyond the end of the file (%d bytes)", keyTable, size)
+	}
+	if dataTable >= size {
+		report(true, SFOHeader, -1, "", 12, "data table offset 0x%x is beyond the end of the file (%d bytes)", dataTable, size)
+	}
+	if indexEnd > size {
+		report(true, SFOIndex, -1, "", 16, "%d entries need %d bytes of index, more than the file's %d", header.EntryCount, indexEnd-sfoHeaderSize, size)
+	}
+	if len(problems) > 0 && problems[len(problems)-1].Fatal {
+		return nil, problems
+	}
+	if indexEnd > keyTable {
+		report(false, SFOIndex, -1, "", sfoHeaderSize, "index of %d entries runs into the key table at 0x%x", header.EntryCount, keyTable)
+	}
+	if dataTable < keyTable {
+		report(false, SFOHeader, -1, "", 12, "data table at 0x%x starts before the key table at 0x%x", dataTable, keyTable)
+	}
+
+	// The count is bounded by the file size above, so this allocates at most size/16 entries
+	entries := make([]ParamSFOEntry, 0, header.EntryCount)
+	seen := make(map[string]bool, header.EntryCount)
+	sorted := true
+	prevKey := ""
+	for i := 0; i < int(header.EntryCount); i++ {
+		at := sfoHeaderSize + uint64(i)*sfoIndexEntrySize
+		raw := rawEntry{
+			KeyOffset: binary.LittleEndian.Uint16(data[at : at+2]),
+			DataFmt:   binary.LittleEndian.Uint16(data[at+2 : at+4]),
+			DataLen:   binary.LittleEndian.Uint32(data[at+4 : at+8]),
+			DataMax:   binary.LittleEndian.Uint32(data[at+8 : at+12]),
+			DataOff:   binary.LittleEndian.Uint32(data[at+12 : at+16]),
+		}
+
+		// Key
+		keyStart := keyTable + uint64(raw.KeyOffset)
+		if keyStart >= size {
+			report(true, SFOIndex, i, "", at, "invalid key offset: 0x%x is beyond the end of the file", keyStart)
+			continue
+		}
+		keyLen := strings.IndexByte(string(data[keyStart:]), 0)
+		if keyLen == -1 {
+			report(true, SFOKeyTable, i, "", keyStart, "key not null-terminated")
+			continue
+		}
+		key := string(data[keyStart : keyStart+uint64(keyLen)])
+		switch {
+		case key == "":
+			report(false, SFOKeyTable, i, key, keyStart, "empty key")
+		case seen[key]:
+			report(false, SFOKeyTable, i, key, keyStart, "duplicate key; lookups find only the first")
+		case keyStart < dataTable && keyStart+uint64(keyLen) >= dataTable:
+			report(false, SFOKeyTable, i, key, keyStart, "key runs into the data table at 0x%x", dataTable)
+		}
+		if sorted && key < prevKey {
+			report(false, SFOKeyTable, i, key, keyStart, "keys aren't in alphabetical order, as retail files' are")
+			sorted = false
+		}
+		seen[key], prevKey = true, key
+
+		// Value
+		valStart := dataTable + uint64(raw.DataOff)
+		valEnd := valStart + uint64(raw.DataLen)
+		if valEnd > size {
+			report(true, SFODataTable, i, key, valStart, "value out of bounds: %d bytes run past the end of the file (%d bytes)", raw.DataLen, size)
+			continue
+		}
+		if raw.DataLen > raw.DataMax {
+			report(false, SFOIndex, i, key, at+4, "value of %d bytes is longer than its %d-byte slot", raw.DataLen, raw.DataMax)
+		} else if valStart+uint64(raw.DataMax) > size {
+			report(false, SFODataTable, i, key, valStart, "%d-byte slot runs past the end of the file", raw.DataMax)
+		}
+		val := data[valStart:valEnd]
+
+		var value interface{}
+		switch raw.DataFmt {
+		case FMT_UTF8_SPECIAL, FMT_UTF8:
+			str := string(val)
+			if nullIdx := strings.IndexByte(str, 0); nullIdx != -1 {
+				str = str[:nullIdx]
+			} else if raw.DataFmt == FMT_UTF8 {
+				report(false, SFODataTable, i, key, valStart, "string is not null-terminated")
+			}
+			if !utf8.ValidString(str) {
+				report(false, SFODataTable, i, key, valStart, "string is not valid UTF-8")
+			}
+			value = str
+		case FMT_INT32:
+			if len(val) < 4 {
+				report(true, SFODataTable, i, key, valStart, "invalid integer data: %d bytes instead of 4", len(val))
+				continue
+			}
+			if len(val) > 4 {
+				report(false, SFOIndex, i, key, at+4, "integer entry is %d bytes long instead of 4", len(val))
+			}
+			value = binary.LittleEndian.Uint32(val)
+		default:
+			// Store as raw bytes for unsupported formats
+			report(false, SFOIndex, i, key, at+2, "unknown data format 0x%04x, kept as raw bytes", raw.DataFmt)
+			value = val
+		}
+
+		entries = append(entries, ParamSFOEntry{
+			Key:     key,
+			Value:   value,
+			DataFmt: raw.DataFmt,
+			DataLen: raw.DataLen,
+			DataMax: raw.DataMax,
+			DataOff: raw.DataOff,
+		})
+	}
+
+	return &ParamSFO{Header: header, Entries: entries, raw: data}, problems
+}
@@ -0,0 +1,59 @@
+package parsers
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzParseParamSFO checks that no file makes the PARAM.SFO parser panic or read out of
+// bounds, that it fails exactly when validation finds a fatal problem, and that every file
+// it parses is written back byte for byte
+func FuzzParseParamSFO(f *testing.F) {
+	sfo := NewParamSFO()
+	sfo.SetString("CATEGORY", "DG")
+	sfo.SetString("TITLE", "Fuzz Game")
+	sfo.SetString("TITLE_ID", "BLUS30001")
+	sfo.SetInt("ATTRIBUTE", 0x20)
+	valid, err := sfo.Marshal(MarshalOptions{SortKeys: true})
+	if err != nil {
+		f.Fatal(err)
+	}
+	if problems := ValidateParamSFO(valid); len(problems) > 0 {
+		f.Fatalf("written PARAM.SFO has problems: %v", problems)
+	}
+
+	f.Add(valid)
+	f.Add(valid[:len(valid)/2])
+	f.Add(valid[:sfoHeaderSize])
+	f.Add([]byte("\x00PSF"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		problems := ValidateParamSFO(data)
+		fatal := false
+		for _, problem := range problems {
+			if problem.Table == "" || problem.Offset < 0 || problem.Message == "" {
+				t.Errorf("incomplete problem: %+v", problem)
+			}
+			fatal = fatal || problem.Fatal
+		}
+
+		parsed, err := ParseParamSFO(data)
+		if fatal != (err != nil) {
+			t.Fatalf("ParseParamSFO error %v, but validation found fatal problems: %v", err, fatal)
+		}
+		if err != nil {
+			return
+		}
+		if len(parsed.Entries) > int(parsed.Header.EntryCount) {
+			t.Fatalf("%d entries parsed from an index of %d", len(parsed.Entries), parsed.Header.EntryCount)
+		}
+		out, err := parsed.Write()
+		if err != nil {
+			t.Fatalf("writing a parsed file: %v", err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatalf("parsed file written back differently:\n%x\n%x", data, out)
+		}
+	})
+}
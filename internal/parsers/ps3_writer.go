@@ -0,0 +1,232 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// DefaultParamSFOVersion is the header version written by most PS3 titles (1.1)
+const DefaultParamSFOVersion = 0x00000101
+
+// MarshalOptions controls the binary layout of a serialized PARAM.SFO file
+type MarshalOptions struct {
+	KeyAlignment  uint32 // Alignment of the key table (0 means 4 bytes)
+	DataAlignment uint32 // Alignment of each data slot (0 means 4 bytes)
+	SortKeys      bool   // Write entries sorted by key, as retail PARAM.SFO files are
+}
+
+// NewParamSFO creates an empty PARAM.SFO with the default header version
+func NewParamSFO() *ParamSFO {
+	return &ParamSFO{
+		Header: ParamSFOHeader{Version: DefaultParamSFOVersion},
+	}
+}
+
+// SetString sets a UTF-8 string entry, adding it if it does not exist
+func (p *ParamSFO) SetString(key, value string) {
+	p.setEntry(ParamSFOEntry{Key: key, Value: value, DataFmt: FMT_UTF8})
+}
+
+// SetInt sets a 32-bit integer entry, adding it if it does not exist
+func (p *ParamSFO) SetInt(key string, value uint32) {
+	p.setEntry(ParamSFOEntry{Key: key, Value: value, DataFmt: FMT_INT32})
+}
+
+// setEntry replaces the entry with the same key or appends a new one
+func (p *ParamSFO) setEntry(entry ParamSFOEntry) {
+	for i := range p.Entries {
+		if p.Entries[i].Key == entry.Key {
+			entry.DataMax = p.Entries[i].DataMax
+			p.Entries[i] = entry
+			return
+		}
+	}
+	p.Entries = append(p.Entries, entry)
+}
+
+// Marshal serializes the PARAM.SFO into its binary representation.
+// Offsets, lengths and the entry count are recomputed from the entries;
+// an entry's DataMax is honored when it is large enough to hold the value.
+func (p *ParamSFO) Marshal(opts MarshalOptions) ([]byte, error) {
+	keyAlign := opts.KeyAlignment
+	if keyAlign == 0 {
+		keyAlign = 4
+	}
+	dataAlign := opts.DataAlignment
+	if dataAlign == 0 {
+		dataAlign = 4
+	}
+
+	entries := make([]ParamSFOEntry, len(p.Entries))
+	copy(entries, p.Entries)
+	if opts.SortKeys {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	}
+
+	seen := make(map[string]bool, len(entries))
+	var keyTable, dataTable bytes.Buffer
+	rawEntries := make([]rawEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("entry with empty key")
+		}
+		if seen[entry.Key] {
+			return nil, fmt.Errorf("duplicate key: %s", entry.Key)
+		}
+		seen[entry.Key] = true
+
+		value, err := encodeEntryValue(entry)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %w", entry.Key, err)
+		}
+
+		dataMax := alignUp(uint32(len(value)), dataAlign)
+		if entry.DataMax > dataMax {
+			dataMax = entry.DataMax
+		}
+
+		if keyTable.Len() > 0xFFFF {
+			return nil, fmt.Errorf("key table too large")
+		}
+
+		rawEntries = append(rawEntries, rawEntry{
+			KeyOffset: uint16(keyTable.Len()),
+			DataFmt:   entry.DataFmt,
+			DataLen:   uint32(len(value)),
+			DataMax:   dataMax,
+			DataOff:   uint32(dataTable.Len()),
+		})
+
+		keyTable.WriteString(entry.Key)
+		keyTable.WriteByte(0)
+
+		dataTable.Write(value)
+		dataTable.Write(make([]byte, dataMax-uint32(len(value))))
+	}
+
+	// Pad the key table so the data table starts aligned
+	keyTable.Write(make([]byte, alignUp(uint32(keyTable.Len()), keyAlign)-uint32(keyTable.Len())))
+
+	keyTableOffset := uint32(20 + 16*len(rawEntries))
+	dataTableOffset := keyTableOffset + uint32(keyTable.Len())
+
+	version := p.Header.Version
+	if version == 0 {
+		version = DefaultParamSFOVersion
+	}
+
+	var out bytes.Buffer
+	out.WriteString("\x00PSF")
+	binary.Write(&out, binary.LittleEndian, version)
+	binary.Write(&out, binary.LittleEndian, keyTableOffset)
+	binary.Write(&out, binary.LittleEndian, dataTableOffset)
+	binary.Write(&out, binary.LittleEndian, uint32(len(rawEntries)))
+	for _, raw := range rawEntries {
+		binary.Write(&out, binary.LittleEndian, raw)
+	}
+	out.Write(keyTable.Bytes())
+	out.Write(dataTable.Bytes())
+
+	return out.Bytes(), nil
+}
+
+// Write serializes a parsed PARAM.SFO keeping the layout of the file it was parsed from:
+// an unchanged file is written back byte for byte, including unknown data formats, the
+// padding of the key table and slots, and anything after the last value. A changed value
+// that still fits its slot is written into it, leaving every other byte alone. Adding,
+// removing or reordering entries, changing a format or slot size, or growing a value past
+// its slot writes a new layout with Marshal instead, sorted if the file's keys were.
+// A PARAM.SFO that wasn't parsed is written with Marshal's defaults.
+func (p *ParamSFO) Write() ([]byte, error) {
+	if p.raw == nil {
+		return p.Marshal(MarshalOptions{})
+	}
+	// The file parsed once, so it parses again to the same entries
+	parsed, _ := scanParamSFO(p.raw)
+	relayout := func() ([]byte, error) {
+		return p.Marshal(MarshalOptions{SortKeys: sort.SliceIsSorted(parsed.Entries, func(i, j int) bool {
+			return parsed.Entries[i].Key < parsed.Entries[j].Key
+		})})
+	}
+	if len(parsed.Entries) != len(p.Entries) {
+		return relayout()
+	}
+
+	out := append([]byte(nil), p.raw...)
+	binary.LittleEndian.PutUint32(out[4:8], p.Header.Version)
+	for i, entry := range p.Entries {
+		orig := parsed.Entries[i]
+		if entry.Key != orig.Key || entry.DataFmt != orig.DataFmt || entry.DataMax != orig.DataMax {
+			return relayout()
+		}
+		if reflect.DeepEqual(entry.Value, orig.Value) {
+			continue
+		}
+
+		value, err := encodeEntryValue(entry)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %w", entry.Key, err)
+		}
+		start := uint64(parsed.Header.DataTableOffset) + uint64(orig.DataOff)
+		end := start + uint64(len(value))
+		if uint64(len(value)) > uint64(orig.DataMax) || end > uint64(len(out)) {
+			return relayout()
+		}
+		// Clear what is left of the old value, but not the rest of the slot, which retail
+		// files zero anyway
+		copy(out[start:], value)
+		for pos := end; pos < start+uint64(orig.DataLen); pos++ {
+			out[pos] = 0
+		}
+		at := sfoHeaderSize + i*sfoIndexEntrySize
+		binary.LittleEndian.PutUint32(out[at+4:at+8], uint32(len(value)))
+	}
+	return out, nil
+}
+
+// encodeEntryValue converts an entry value into the bytes stored in the data table
+func encodeEntryValue(entry ParamSFOEntry) ([]byte, error) {
+	switch entry.DataFmt {
+	case FMT_UTF8:
+		str, ok := entry.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("format 0x%04X requires a string value", entry.DataFmt)
+		}
+		return append([]byte(str), 0), nil
+
+	case FMT_UTF8_SPECIAL:
+		str, ok := entry.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("format 0x%04X requires a string value", entry.DataFmt)
+		}
+		return []byte(str), nil
+
+	case FMT_INT32:
+		num, ok := entry.Value.(uint32)
+		if !ok {
+			return nil, fmt.Errorf("format 0x%04X requires an integer value", entry.DataFmt)
+		}
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, num)
+		return buf, nil
+
+	default:
+		raw, ok := entry.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("format 0x%04X requires raw byte data", entry.DataFmt)
+		}
+		return raw, nil
+	}
+}
+
+// alignUp rounds n up to the next multiple of align
+func alignUp(n, align uint32) uint32 {
+	if align <= 1 {
+		return n
+	}
+	return (n + align - 1) / align * align
+}
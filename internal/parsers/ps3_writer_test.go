@@ -0,0 +1,65 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// TestWriteChangesOnlyTheValue checks that a value edited in a parsed PARAM.SFO rewrites
+// only its own bytes and length, keeping unknown formats and padding elsewhere
+func TestWriteChangesOnlyTheValue(t *testing.T) {
+	sfo := NewParamSFO()
+	sfo.SetString("CATEGORY", "DG")
+	sfo.setEntry(ParamSFOEntry{Key: "CUSTOM", DataFmt: 0x0101, Value: []byte{0xde, 0xad, 0xbe, 0xef}})
+	sfo.setEntry(ParamSFOEntry{Key: "TITLE", DataFmt: FMT_UTF8, Value: "A Long Game Title", DataMax: 128})
+	sfo.SetString("TITLE_ID", "BLUS30001")
+	data, err := sfo.Marshal(MarshalOptions{SortKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Junk in the unused end of the TITLE slot, which a relayout would zero
+	parsed, err := ParseParamSFO(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, _ := parsed.GetEntry("TITLE")
+	slot := int(parsed.Header.DataTableOffset + title.DataOff)
+	data[slot+100] = 0xff
+
+	parsed, err = ParseParamSFO(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.SetString("TITLE", "Short")
+	out, err := parsed.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]byte(nil), data...)
+	copy(want[slot:], make([]byte, title.DataLen))
+	copy(want[slot:], "Short\x00")
+	index := sfoHeaderSize + 2*sfoIndexEntrySize // TITLE is the third key
+	binary.LittleEndian.PutUint32(want[index+4:], 6)
+	if !bytes.Equal(out, want) {
+		t.Fatalf("Write changed more than the value:\n%x\n%x", want, out)
+	}
+
+	// A value longer than its slot needs a new layout, which still reads back
+	parsed.SetString("TITLE", strings.Repeat("x", 200))
+	if out, err = parsed.Write(); err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := ParseParamSFO(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reparsed.GetTitle(); got != strings.Repeat("x", 200) {
+		t.Fatalf("title after relayout = %q", got)
+	}
+	if custom, _ := reparsed.GetEntry("CUSTOM"); !bytes.Equal(custom.Value.([]byte), []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("unknown format entry lost in relayout: %x", custom.Value)
+	}
+}
@@ -0,0 +1,179 @@
+// Package remote checks a copy of an organized library kept on an HTTP server or an
+// object store (S3, B2, R2, GCS and the like, through their HTTP endpoints) against the
+// local library, without downloading its archives
+package remote
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNotFound means the remote object doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// ErrNoRanges means the server ignored a Range header and would send the whole object
+var ErrNoRanges = errors.New("server doesn't support ranged reads")
+
+// Object is what a HEAD request tells about a remote file
+type Object struct {
+	Size int64
+	ETag string
+	MD5  string // Hex MD5 from a single-part ETag, Content-MD5 or x-goog-hash, "" if none
+	// Hex SHA-256 from x-amz-checksum-sha256, "" if none
+	SHA256 string
+}
+
+// Client reads objects below a base URL. User info in the URL is sent as basic
+// authentication; presigned query strings are kept on every request.
+type Client struct {
+	base *url.URL
+	http *http.Client
+}
+
+// NewClient returns a client for a library copy at an http:// or https:// URL
+func NewClient(base string, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", base, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL %s (use http:// or https://)", base)
+	}
+	return &Client{base: u, http: &http.Client{Timeout: timeout}}, nil
+}
+
+// URL returns the URL of a slash-separated path below the base URL
+func (c *Client) URL(rel string) string {
+	u := *c.base
+	u.User = nil // Sent as a header, kept out of messages
+	u.Path = path.Join("/", u.Path, rel)
+	u.RawPath = ""
+	return u.String()
+}
+
+func (c *Client) request(method, rel string) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.URL(rel), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.base.User != nil {
+		password, _ := c.base.User.Password()
+		req.SetBasicAuth(c.base.User.Username(), password)
+	}
+	return req, nil
+}
+
+// Head returns the size and checksums the server reports for an object
+func (c *Client) Head(rel string) (*Object, error) {
+	req, err := c.request(http.MethodHead, rel)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HEAD %s: %s", rel, resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return nil, fmt.Errorf("HEAD %s: no Content-Length", rel)
+	}
+
+	obj := &Object{Size: resp.ContentLength, ETag: resp.Header.Get("ETag")}
+	obj.MD5 = etagMD5(obj.ETag)
+	if obj.MD5 == "" {
+		obj.MD5 = base64Hex(resp.Header.Get("Content-MD5"), 16)
+	}
+	for _, value := range resp.Header.Values("X-Goog-Hash") {
+		for _, part := range strings.Split(value, ",") {
+			if digest, ok := strings.CutPrefix(strings.TrimSpace(part), "md5="); ok && obj.MD5 == "" {
+				obj.MD5 = base64Hex(digest, 16)
+			}
+		}
+	}
+	obj.SHA256 = base64Hex(resp.Header.Get("X-Amz-Checksum-Sha256"), 32)
+	return obj, nil
+}
+
+// md5ETag matches an ETag holding the MD5 of the whole object, as S3-compatible stores
+// return for objects uploaded in one part. Multipart ETags end in -<parts> and aren't.
+var md5ETag = regexp.MustCompile(`^(?:W/)?"?([0-9a-fA-F]{32})"?$`)
+
+func etagMD5(etag string) string {
+	if m := md5ETag.FindStringSubmatch(etag); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
+
+// base64Hex converts a base64 digest header to hex, "" unless it decodes to size bytes
+func base64Hex(value string, size int) string {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(raw) != size {
+		return ""
+	}
+	return hex.EncodeToString(raw)
+}
+
+// ReadRange reads length bytes of an object starting at offset
+func (c *Client) ReadRange(rel string, offset, length int64) ([]byte, error) {
+	req, err := c.request(http.MethodGet, rel)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	case http.StatusOK:
+		// Reading on would download the whole archive
+		return nil, ErrNoRanges
+	default:
+		return nil, fmt.Errorf("GET %s: %s", rel, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, length))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rel, err)
+	}
+	return data, nil
+}
+
+// ReadSmall downloads a small object such as a checksum file, up to max bytes
+func (c *Client) ReadSmall(rel string, max int64) ([]byte, error) {
+	req, err := c.request(http.MethodGet, rel)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rel, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, max))
+}
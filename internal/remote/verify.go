@@ -0,0 +1,170 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// Verification results, worst last
+const (
+	StatusOK         = "ok"         // Contents verified by a server checksum or spot checks
+	StatusUnverified = "unverified" // Only the size could be compared
+	StatusMissing    = "missing"
+	StatusMismatch   = "mismatch"
+	StatusError      = "error"
+)
+
+// Manifest is what the local library knows about a file: its size and the digests
+// stored in checksum sidecars or the catalog
+type Manifest struct {
+	Size    int64
+	Digests map[common.HashAlgorithm]string // Hex, lower case
+}
+
+// VerifyOptions configures Verify
+type VerifyOptions struct {
+	SpotChecks int   // Ranges read when no server checksum can be compared; 0 disables them
+	SpotSize   int64 // Bytes per range
+}
+
+// Result is the outcome of verifying one remote file
+type Result struct {
+	Path   string   // Relative path, slash-separated
+	Status string   // One of the Status constants
+	Checks []string // What was compared, e.g. "size", "server md5"
+	Detail string   // Why the file didn't pass
+	Read   int64    // Bytes downloaded for the checks
+}
+
+// Verify compares a remote file with its manifest without downloading it: the size and
+// any checksum the server reports (an MD5 ETag, Content-MD5, x-goog-hash or
+// x-amz-checksum-sha256), then checksum sidecars stored next to it. When the server
+// reports no comparable checksum, ranges of the remote file are read and compared with
+// the local file at local.
+func (c *Client) Verify(rel, local string, manifest Manifest, opts VerifyOptions) Result {
+	result := Result{Path: rel}
+	fail := func(status, format string, args ...any) Result {
+		result.Status, result.Detail = status, fmt.Sprintf(format, args...)
+		return result
+	}
+
+	obj, err := c.Head(rel)
+	if errors.Is(err, ErrNotFound) {
+		return fail(StatusMissing, "not on the server")
+	}
+	if err != nil {
+		return fail(StatusError, "%v", err)
+	}
+	if obj.Size != manifest.Size {
+		return fail(StatusMismatch, "size is %d bytes, expected %d", obj.Size, manifest.Size)
+	}
+	result.Checks = append(result.Checks, "size")
+
+	contentChecked := false
+	for _, server := range []struct {
+		alg    common.HashAlgorithm
+		digest string
+		name   string
+	}{{common.HashMD5, obj.MD5, "md5"}, {common.HashSHA256, obj.SHA256, "sha256"}} {
+		expected := manifest.Digests[server.alg]
+		if server.digest == "" || expected == "" {
+			continue
+		}
+		if server.digest != expected {
+			return fail(StatusMismatch, "server %s is %s, expected %s", server.name, server.digest, expected)
+		}
+		result.Checks = append(result.Checks, "server "+server.name)
+		contentChecked = true
+	}
+
+	// Checksum files uploaded with the archive must agree with the local ones
+	for _, alg := range common.HashAlgorithms {
+		expected := manifest.Digests[alg]
+		if expected == "" {
+			continue
+		}
+		sidecar := rel + "." + string(alg)
+		data, err := c.ReadSmall(sidecar, 4096)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fail(StatusError, "%v", err)
+		}
+		result.Read += int64(len(data))
+		digest, err := common.ParseChecksumLine(data)
+		if err != nil || digest != expected {
+			return fail(StatusMismatch, "%s doesn't match the local checksum", sidecar)
+		}
+		result.Checks = append(result.Checks, "remote ."+string(alg))
+	}
+
+	if !contentChecked && opts.SpotChecks > 0 && local != "" {
+		read, err := c.spotCheck(rel, local, manifest.Size, opts)
+		result.Read += read
+		switch {
+		case errors.Is(err, ErrNoRanges):
+			result.Detail = err.Error()
+		case err != nil:
+			return fail(StatusMismatch, "%v", err)
+		default:
+			result.Checks = append(result.Checks, "spot checks")
+			contentChecked = true
+		}
+	}
+
+	result.Status = StatusOK
+	if !contentChecked {
+		result.Status = StatusUnverified
+	}
+	return result
+}
+
+// spotCheck compares ranges of the remote file with the local file: the start and the
+// end, where truncated and half-uploaded files differ, and random offsets in between
+func (c *Client) spotCheck(rel, local string, size int64, opts VerifyOptions) (int64, error) {
+	file, err := os.Open(local)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	length := opts.SpotSize
+	if length <= 0 || length > size {
+		length = size
+	}
+	if length == 0 {
+		return 0, nil
+	}
+	offsets := []int64{0, size - length}
+	for len(offsets) < opts.SpotChecks {
+		offsets = append(offsets, rand.Int63n(size-length+1))
+	}
+	if length == size {
+		// One range already covers the whole file
+		offsets = offsets[:1]
+	}
+
+	var read int64
+	want := make([]byte, length)
+	for _, offset := range offsets[:min(len(offsets), opts.SpotChecks)] {
+		got, err := c.ReadRange(rel, offset, length)
+		read += int64(len(got))
+		if err != nil {
+			return read, err
+		}
+		if _, err := file.ReadAt(want, offset); err != nil && err != io.EOF {
+			return read, fmt.Errorf("reading %s: %w", local, err)
+		}
+		if !bytes.Equal(got, want) {
+			return read, fmt.Errorf("bytes %d-%d differ from the local file", offset, offset+length-1)
+		}
+	}
+	return read, nil
+}
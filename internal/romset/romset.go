@@ -0,0 +1,314 @@
+// Package romset rebuilds the arcade and cartridge ROM sets of a DAT file into one zip
+// archive per set, storing parent and clone sets split, merged or non-merged the way
+// ROM managers such as ClrMamePro and RomVault do
+package romset
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+)
+
+// Policy decides which archive holds the ROMs a clone shares with its parent
+type Policy string
+
+const (
+	Split     Policy = "split"      // Each set holds only its own ROMs; a clone needs its parent's archive
+	Merged    Policy = "merged"     // A parent's archive holds its clones' ROMs too; clones have none
+	NonMerged Policy = "non-merged" // Each set holds every ROM it needs except its BIOS's
+)
+
+// Entry is one file of a rebuilt archive
+type Entry struct {
+	Path string // Within the archive
+	ROM  catalog.ROM
+}
+
+// Archives returns the archives the sets of dat are stored in under policy, keyed by
+// archive name (without .zip). ROMs that were never dumped are left out, and so are sets
+// left with no ROMs of their own.
+func Archives(dat *catalog.DAT, policy Policy) map[string][]Entry {
+	names := make([]string, 0, len(dat.Sets))
+	for name := range dat.Sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archives := make(map[string][]Entry)
+	for _, name := range names {
+		set := dat.Sets[name]
+		archive := name
+		if policy == Merged && set.Parent != "" {
+			archive = set.Parent
+		}
+		for _, rom := range set.ROMs {
+			if rom.CRC == "" {
+				continue
+			}
+			from := owner(dat, name, rom)
+			switch policy {
+			case Split, Merged:
+				if from != name {
+					continue
+				}
+			case NonMerged:
+				if from != name && dat.Sets[from].BIOS {
+					continue
+				}
+			}
+			archives[archive] = addEntry(archives[archive], name, archive, rom)
+		}
+	}
+	return archives
+}
+
+// owner returns the set a ROM of the named set is stored in when sets are split: the set
+// itself, or the parent or BIOS it shares the ROM with
+func owner(dat *catalog.DAT, name string, rom catalog.ROM) string {
+	// Bounded, so a DAT whose sets share ROMs in a loop can't hang
+	for i := 0; rom.Merge != "" && i < len(dat.Sets); i++ {
+		from := dat.Sets[name].ROMOf
+		set, ok := dat.Sets[from]
+		if !ok {
+			break
+		}
+		shared, ok := findROM(set.ROMs, rom.Merge)
+		if !ok {
+			break
+		}
+		name, rom = from, shared
+	}
+	return name
+}
+
+func findROM(roms []catalog.ROM, name string) (catalog.ROM, bool) {
+	for _, rom := range roms {
+		if rom.Name == name {
+			return rom, true
+		}
+	}
+	return catalog.ROM{}, false
+}
+
+// addEntry adds a ROM of set to the entries of archive. A ROM already there is not added
+// twice; a clone's ROM whose name the parent uses for different data goes in a folder
+// named after the clone.
+func addEntry(entries []Entry, set, archive string, rom catalog.ROM) []Entry {
+	path := rom.Name
+	for _, entry := range entries {
+		if entry.Path != path {
+			continue
+		}
+		if entry.ROM.CRC == rom.CRC || set == archive {
+			return entries
+		}
+		path = set + "/" + rom.Name
+	}
+	return append(entries, Entry{Path: path, ROM: rom})
+}
+
+// Result is the outcome of rebuilding one archive
+type Result struct {
+	Name    string
+	Found   int     // ROMs found in the sources
+	Missing []Entry // ROMs found in no source; the archive isn't written
+	Exists  bool    // The archive was already there and not replaced
+	Err     error
+}
+
+// romKey identifies a ROM's content
+type romKey struct {
+	size int64
+	crc  string
+}
+
+// source is where a ROM was found: a file in a zip archive, or a loose file
+type source struct {
+	zipFile *zip.File
+	path    string
+}
+
+// Index finds ROMs in folders, zip archives and loose files by size and CRC32
+type Index struct {
+	files map[romKey]source
+	zips  []*zip.ReadCloser
+}
+
+// IndexSources reads the size and CRC32 of every file under paths. Zip archives are
+// looked into, using the CRC32 they store. Close the index when done.
+func IndexSources(paths []string) (*Index, error) {
+	ix := &Index{files: make(map[romKey]source)}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if strings.EqualFold(filepath.Ext(path), ".zip") {
+				return ix.addZip(path)
+			}
+			return ix.addFile(path)
+		})
+		if err != nil {
+			ix.Close()
+			return nil, err
+		}
+	}
+	return ix, nil
+}
+
+func (ix *Index) addZip(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	ix.zips = append(ix.zips, zr)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		key := romKey{size: int64(f.UncompressedSize64), crc: fmt.Sprintf("%08x", f.CRC32)}
+		if _, ok := ix.files[key]; !ok {
+			ix.files[key] = source{zipFile: f}
+		}
+	}
+	return nil
+}
+
+func (ix *Index) addFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := crc32.NewIEEE()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	key := romKey{size: size, crc: fmt.Sprintf("%08x", h.Sum32())}
+	if _, ok := ix.files[key]; !ok {
+		ix.files[key] = source{path: path}
+	}
+	return nil
+}
+
+// Close closes the zip archives the index read from
+func (ix *Index) Close() {
+	for _, zr := range ix.zips {
+		zr.Close()
+	}
+}
+
+// Rebuild writes each archive whose ROMs are all in the index to destDir as <name>.zip.
+// An archive that already exists is kept unless overwrite is set.
+func (ix *Index) Rebuild(archives map[string][]Entry, destDir string, overwrite bool) []Result {
+	names := make([]string, 0, len(archives))
+	for name := range archives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		result := Result{Name: name}
+		entries := archives[name]
+		for _, entry := range entries {
+			if _, ok := ix.files[romKey{size: entry.ROM.Size, crc: entry.ROM.CRC}]; ok {
+				result.Found++
+			} else {
+				result.Missing = append(result.Missing, entry)
+			}
+		}
+
+		dest := filepath.Join(destDir, name+".zip")
+		switch {
+		case !filepath.IsLocal(name) || strings.ContainsAny(name, `/\`):
+			result.Err = fmt.Errorf("set name %q is not a file name", name)
+		case len(result.Missing) > 0:
+		case !overwrite && exists(dest):
+			result.Exists = true
+		default:
+			result.Err = ix.writeArchive(dest, entries)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeArchive writes the entries to a zip archive at dest, atomically (write to a
+// temporary file, then rename). ROMs taken from zip archives are copied compressed.
+func (ix *Index) writeArchive(dest string, entries []Entry) error {
+	sorted := append([]Entry{}, entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(dest), err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", dest, err)
+	}
+	zw := zip.NewWriter(tmp)
+	for _, entry := range sorted {
+		if err = ix.writeEntry(zw, entry); err != nil {
+			break
+		}
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dest)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing %s: %w", dest, err)
+	}
+	return nil
+}
+
+func (ix *Index) writeEntry(zw *zip.Writer, entry Entry) error {
+	src := ix.files[romKey{size: entry.ROM.Size, crc: entry.ROM.CRC}]
+	if src.zipFile != nil {
+		header := src.zipFile.FileHeader
+		header.Name = entry.Path
+		w, err := zw.CreateRaw(&header)
+		if err != nil {
+			return err
+		}
+		r, err := src.zipFile.OpenRaw()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		return err
+	}
+
+	f, err := os.Open(src.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: entry.Path, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
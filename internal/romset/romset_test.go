@@ -0,0 +1,179 @@
+package romset
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+)
+
+// testROM returns the ROM of a DAT holding data
+func testROM(name, data string) catalog.ROM {
+	return catalog.ROM{Name: name, Size: int64(len(data)), CRC: fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(data)))}
+}
+
+func merged(rom catalog.ROM) catalog.ROM {
+	rom.Merge = rom.Name
+	return rom
+}
+
+// testDAT has a BIOS, a parent using it and a clone of the parent. The clone has a
+// ROM of its own named like one of the parent's.
+func testDAT() *catalog.DAT {
+	bios := testROM("bios.bin", "bios")
+	p1, s1, c1 := testROM("game.p1", "game p1"), testROM("game.s1", "game s1"), testROM("game.c1", "game c1")
+	return &catalog.DAT{Sets: map[string]*catalog.ROMSet{
+		"neogeo": {BIOS: true, ROMs: []catalog.ROM{bios}},
+		"game": {ROMOf: "neogeo", ROMs: []catalog.ROM{
+			p1, s1, c1, merged(bios),
+			{Name: "game.x1", Size: 16}, // Never dumped
+		}},
+		"gamej": {Parent: "game", ROMOf: "game", ROMs: []catalog.ROM{
+			testROM("gamej.p1", "gamej p1"), testROM("game.s1", "gamej s1"), merged(c1), merged(bios),
+		}},
+	}}
+}
+
+// listing returns "path=data CRC" for the entries of each archive
+func listing(archives map[string][]Entry) map[string][]string {
+	out := make(map[string][]string)
+	for name, entries := range archives {
+		for _, entry := range entries {
+			out[name] = append(out[name], entry.Path+"="+entry.ROM.CRC)
+		}
+	}
+	return out
+}
+
+func TestArchives(t *testing.T) {
+	dat := testDAT()
+	crc := func(data string) string { return testROM("", data).CRC }
+	bios := []string{"bios.bin=" + crc("bios")}
+	parent := []string{"game.p1=" + crc("game p1"), "game.s1=" + crc("game s1"), "game.c1=" + crc("game c1")}
+
+	tests := []struct {
+		policy Policy
+		want   map[string][]string
+	}{
+		{Split, map[string][]string{
+			"neogeo": bios,
+			"game":   parent,
+			"gamej":  {"gamej.p1=" + crc("gamej p1"), "game.s1=" + crc("gamej s1")},
+		}},
+		{Merged, map[string][]string{
+			"neogeo": bios,
+			"game":   append(append([]string{}, parent...), "gamej.p1="+crc("gamej p1"), "gamej/game.s1="+crc("gamej s1")),
+		}},
+		{NonMerged, map[string][]string{
+			"neogeo": bios,
+			"game":   parent,
+			"gamej":  {"gamej.p1=" + crc("gamej p1"), "game.s1=" + crc("gamej s1"), "game.c1=" + crc("game c1")},
+		}},
+	}
+	for _, tt := range tests {
+		if got := listing(Archives(dat, tt.policy)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: archives %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestRebuild(t *testing.T) {
+	src, dest := t.TempDir(), t.TempDir()
+	// ROMs are found by content whatever their names, in zips and loose files
+	writeZip(t, filepath.Join(src, "downloaded.zip"), map[string]string{
+		"a": "game p1", "b": "game s1", "c/d": "game c1", "e": "gamej p1", "unrelated": "readme",
+	})
+	if err := os.WriteFile(filepath.Join(src, "gamej s1.bin"), []byte("gamej s1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := IndexSources([]string{src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	results := index.Rebuild(Archives(testDAT(), Merged), dest, false)
+	if len(results) != 2 {
+		t.Fatalf("results %+v", results)
+	}
+	if bios := results[1]; bios.Name != "neogeo" || bios.Found != 0 || len(bios.Missing) != 1 {
+		t.Errorf("BIOS result %+v, want its ROM missing", bios)
+	}
+	if exists(filepath.Join(dest, "neogeo.zip")) {
+		t.Error("wrote an incomplete set")
+	}
+	if game := results[0]; game.Name != "game" || game.Err != nil || game.Found != 5 || len(game.Missing) != 0 || game.Exists {
+		t.Errorf("parent result %+v", game)
+	}
+	want := map[string]string{
+		"game.c1": "game c1", "game.p1": "game p1", "game.s1": "game s1",
+		"gamej.p1": "gamej p1", "gamej/game.s1": "gamej s1",
+	}
+	if got := readZip(t, filepath.Join(dest, "game.zip")); !reflect.DeepEqual(got, want) {
+		t.Errorf("game.zip holds %v, want %v", got, want)
+	}
+
+	// An archive already there is kept unless overwriting
+	if results := index.Rebuild(Archives(testDAT(), Merged), dest, false); !results[0].Exists {
+		t.Errorf("rebuilt over an existing archive: %+v", results[0])
+	}
+	if results := index.Rebuild(Archives(testDAT(), Merged), dest, true); results[0].Exists || results[0].Err != nil {
+		t.Errorf("overwriting: %+v", results[0])
+	}
+
+	// Set names become file names, so they can't leave the output directory
+	archives := map[string][]Entry{"../escape": {{Path: "game.p1", ROM: testROM("game.p1", "game p1")}}}
+	if results := index.Rebuild(archives, dest, false); results[0].Err == nil {
+		t.Error("wrote a set named ../escape")
+	}
+}
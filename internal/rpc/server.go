@@ -0,0 +1,303 @@
+// Package rpc implements the gRPC control API (see api/rpcpb) on top of the job queue
+// and the organized libraries
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/NeilGraham/rom-organizer/api/rpcpb"
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/jobs"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+	"github.com/NeilGraham/rom-organizer/internal/organizer"
+)
+
+// PollInterval is how often WatchJob checks the queue and the job's progress file
+var PollInterval = 500 * time.Millisecond
+
+// FindGamesFunc returns the organized games of the served libraries that pass filter,
+// and the catalog they were filtered with
+type FindGamesFunc func(filter catalog.Filter) ([]library.Game, *catalog.Catalog, error)
+
+// Server is the RomOrganizer gRPC service
+type Server struct {
+	rpcpb.UnimplementedRomOrganizerServer
+	queuePath string
+	findGames FindGamesFunc
+}
+
+// NewServer returns the service for the job queue at queuePath, listing games with findGames
+func NewServer(queuePath string, findGames FindGamesFunc) *Server {
+	return &Server{queuePath: queuePath, findGames: findGames}
+}
+
+var states = map[jobs.State]rpcpb.JobState{
+	jobs.StatePending:   rpcpb.JobState_JOB_STATE_PENDING,
+	jobs.StateRunning:   rpcpb.JobState_JOB_STATE_RUNNING,
+	jobs.StateCompleted: rpcpb.JobState_JOB_STATE_COMPLETED,
+	jobs.StateFailed:    rpcpb.JobState_JOB_STATE_FAILED,
+	jobs.StateCancelled: rpcpb.JobState_JOB_STATE_CANCELLED,
+}
+
+// finished reports whether a job in state won't change again unless it's retried
+func finished(state jobs.State) bool {
+	return state == jobs.StateCompleted || state == jobs.StateFailed || state == jobs.StateCancelled
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func jobMessage(job *jobs.Job) *rpcpb.Job {
+	return &rpcpb.Job{
+		Id:         int64(job.ID),
+		Command:    job.Command,
+		Args:       job.Args,
+		State:      states[job.State],
+		Attempts:   int32(job.Attempts),
+		Error:      job.Error,
+		CreatedAt:  timestamp(job.CreatedAt),
+		StartedAt:  timestamp(job.StartedAt),
+		FinishedAt: timestamp(job.FinishedAt),
+	}
+}
+
+// SubmitJob queues a compress, decompress or organize job
+func (s *Server) SubmitJob(ctx context.Context, req *rpcpb.SubmitJobRequest) (*rpcpb.Job, error) {
+	var job *jobs.Job
+	var addErr error
+	err := jobs.Update(s.queuePath, func(queue *jobs.Queue) error {
+		job, addErr = queue.Add(req.Command, req.Args)
+		return addErr
+	})
+	if addErr != nil {
+		return nil, status.Error(codes.InvalidArgument, addErr.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jobMessage(job), nil
+}
+
+// GetJob returns a queued job
+func (s *Server) GetJob(ctx context.Context, req *rpcpb.GetJobRequest) (*rpcpb.Job, error) {
+	queue, err := jobs.Open(s.queuePath)
+	if err != nil {
+		return nil, err
+	}
+	job, err := queue.Get(int(req.Id))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return jobMessage(job), nil
+}
+
+// ListJobs returns the queued jobs, oldest first
+func (s *Server) ListJobs(ctx context.Context, req *rpcpb.ListJobsRequest) (*rpcpb.ListJobsResponse, error) {
+	queue, err := jobs.Open(s.queuePath)
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpcpb.ListJobsResponse{}
+	for _, job := range queue.Jobs {
+		if req.State == rpcpb.JobState_JOB_STATE_UNSPECIFIED || states[job.State] == req.State {
+			resp.Jobs = append(resp.Jobs, jobMessage(job))
+		}
+	}
+	return resp, nil
+}
+
+// CancelJob stops a pending job from running
+func (s *Server) CancelJob(ctx context.Context, req *rpcpb.CancelJobRequest) (*rpcpb.Job, error) {
+	var job *jobs.Job
+	var rpcErr error
+	err := jobs.Update(s.queuePath, func(queue *jobs.Queue) error {
+		if _, err := queue.Get(int(req.Id)); err != nil {
+			rpcErr = status.Error(codes.NotFound, err.Error())
+			return err
+		}
+		var err error
+		if job, err = queue.Cancel(int(req.Id)); err != nil {
+			rpcErr = status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return err
+	})
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jobMessage(job), nil
+}
+
+// WatchJob streams a job's progress events and state changes until it's finished. The
+// queue is only read, so watching never holds up the worker running the job.
+func (s *Server) WatchJob(req *rpcpb.WatchJobRequest, stream rpcpb.RomOrganizer_WatchJobServer) error {
+	var sent jobs.State
+	var offset int64
+	for {
+		queue, err := jobs.Open(s.queuePath)
+		if err != nil {
+			return err
+		}
+		job, err := queue.Get(int(req.Id))
+		if err != nil {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		// Read after the state, so a finished job's last events are in the file
+		events, next, err := readProgress(queue.ProgressPath(job.ID), offset)
+		if err != nil {
+			return err
+		}
+		offset = next
+
+		// A new state goes before the events of a running job and after those of a
+		// finished one
+		message := jobMessage(job)
+		changed := job.State != sent
+		sent = job.State
+		if changed && !finished(job.State) {
+			if err := stream.Send(&rpcpb.JobUpdate{Job: message}); err != nil {
+				return err
+			}
+		}
+		for _, event := range events {
+			if err := stream.Send(&rpcpb.JobUpdate{Job: message, Progress: event}); err != nil {
+				return err
+			}
+		}
+		if finished(job.State) {
+			if changed {
+				return stream.Send(&rpcpb.JobUpdate{Job: message})
+			}
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// readProgress returns the events added to a job's progress file since offset, and the
+// offset to read from next. A line still being written is left for the next read, and a
+// file started afresh (the job ran again) is read from the beginning.
+func readProgress(path string, offset int64) ([]*rpcpb.Progress, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return nil, offset, err
+	}
+	if end := bytes.LastIndexByte(data, '\n'); end >= 0 {
+		data = data[:end+1]
+	} else {
+		data = nil
+	}
+
+	var events []*rpcpb.Progress
+	for _, raw := range bytes.Split(data, []byte("\n")) {
+		var line struct {
+			Time time.Time `json:"time"`
+			organizer.ProgressEvent
+		}
+		if json.Unmarshal(raw, &line) != nil {
+			continue
+		}
+		event := line.ProgressEvent
+		events = append(events, &rpcpb.Progress{
+			Event:     event.Event,
+			Index:     int32(event.Index),
+			Total:     int32(event.Total),
+			Percent:   event.Percent,
+			Source:    event.Source,
+			Target:    event.Target,
+			Title:     event.Title,
+			GameId:    event.GameID,
+			Stage:     event.Stage,
+			Status:    event.Status,
+			Error:     event.Error,
+			Succeeded: int32(event.Succeeded),
+			Failed:    int32(event.Failed),
+			Skipped:   int32(event.Skipped),
+			Time:      timestamp(line.Time),
+		})
+	}
+	return events, offset + int64(len(data)), nil
+}
+
+// ListGames returns the organized games of the served libraries that pass the request's
+// catalog filter
+func (s *Server) ListGames(ctx context.Context, req *rpcpb.ListGamesRequest) (*rpcpb.ListGamesResponse, error) {
+	by := library.SortTitle
+	if req.Sort != "" {
+		var err error
+		if by, err = library.ParseSortBy(req.Sort); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	games, c, err := s.findGames(catalog.Filter{
+		Tags:              req.Tags,
+		ExcludeTags:       req.ExcludeTags,
+		Collection:        req.Collection,
+		ExcludePrerelease: req.NoDemos,
+	})
+	if err != nil {
+		return nil, err
+	}
+	library.SortGames(games, by, func(game library.Game) int64 {
+		size, _ := common.DirSize(game.Path)
+		return size
+	})
+
+	resp := &rpcpb.ListGamesResponse{}
+	for _, game := range games {
+		info := game.Info.GameInfo
+		message := &rpcpb.Game{
+			Path:       game.Path,
+			Title:      info.Title,
+			GameId:     info.GameID,
+			Console:    info.Console,
+			Compressed: game.Info.HasCompressed,
+			Tags:       c.Tags(info.GameID),
+			Unverified: c.Unverified(info.GameID),
+		}
+		if compat := c.Compat(info.GameID); compat != nil {
+			message.Compat = compat.Status
+		}
+		resp.Games = append(resp.Games, message)
+	}
+	return resp, nil
+}
@@ -0,0 +1,243 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/NeilGraham/rom-organizer/api/rpcpb"
+	"github.com/NeilGraham/rom-organizer/internal/catalog"
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/jobs"
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+// dial serves s over an in-memory connection and returns a client for it
+func dial(t *testing.T, s *Server) rpcpb.RomOrganizerClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	rpcpb.RegisterRomOrganizerServer(server, s)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rpcpb.NewRomOrganizerClient(conn)
+}
+
+func TestJobs(t *testing.T) {
+	queuePath := filepath.Join(t.TempDir(), "jobs.json")
+	client := dial(t, NewServer(queuePath, nil))
+	ctx := context.Background()
+
+	job, err := client.SubmitJob(ctx, &rpcpb.SubmitJobRequest{Command: "organize", Args: []string{"--output", "/nas", "/downloads/game"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Id != 1 || job.State != rpcpb.JobState_JOB_STATE_PENDING || len(job.Args) != 3 || job.CreatedAt == nil || job.StartedAt != nil {
+		t.Errorf("submitted job %v", job)
+	}
+	if _, err := client.SubmitJob(ctx, &rpcpb.SubmitJobRequest{Command: "rename"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("submitting a command that can't be queued: %v", err)
+	}
+
+	// Jobs submitted over gRPC are in the queue "jobs run" reads
+	queue, err := jobs.Open(queuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.Jobs) != 1 || queue.Jobs[0].Command != "organize" {
+		t.Fatalf("queue holds %+v", queue.Jobs)
+	}
+
+	if got, err := client.GetJob(ctx, &rpcpb.GetJobRequest{Id: 1}); err != nil || got.Command != "organize" {
+		t.Errorf("GetJob = %v, %v", got, err)
+	}
+	if _, err := client.GetJob(ctx, &rpcpb.GetJobRequest{Id: 2}); status.Code(err) != codes.NotFound {
+		t.Errorf("getting a job that doesn't exist: %v", err)
+	}
+
+	if got, err := client.CancelJob(ctx, &rpcpb.CancelJobRequest{Id: 1}); err != nil || got.State != rpcpb.JobState_JOB_STATE_CANCELLED {
+		t.Errorf("CancelJob = %v, %v", got, err)
+	}
+	if _, err := client.CancelJob(ctx, &rpcpb.CancelJobRequest{Id: 1}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("cancelling a cancelled job: %v", err)
+	}
+
+	client.SubmitJob(ctx, &rpcpb.SubmitJobRequest{Command: "compress", Args: []string{"/downloads/other"}})
+	list, err := client.ListJobs(ctx, &rpcpb.ListJobsRequest{State: rpcpb.JobState_JOB_STATE_PENDING})
+	if err != nil || len(list.Jobs) != 1 || list.Jobs[0].Command != "compress" {
+		t.Errorf("pending jobs = %v, %v", list, err)
+	}
+	if list, err := client.ListJobs(ctx, &rpcpb.ListJobsRequest{}); err != nil || len(list.Jobs) != 2 {
+		t.Errorf("all jobs = %v, %v", list, err)
+	}
+}
+
+func TestWatchJob(t *testing.T) {
+	PollInterval = 10 * time.Millisecond
+	queuePath := filepath.Join(t.TempDir(), "jobs.json")
+	client := dial(t, NewServer(queuePath, nil))
+
+	var release func()
+	var progressPath string
+	err := jobs.Update(queuePath, func(queue *jobs.Queue) error {
+		job, err := queue.Add("organize", []string{"/downloads/game"})
+		if err != nil {
+			return err
+		}
+		progressPath = queue.ProgressPath(job.ID)
+		release, err = queue.Start(job)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// What the job's child process appends, the last line still being written
+	lines := `{"time":"2026-01-02T03:04:05Z","event":"game_started","index":1,"total":1,"percent":0,"title":"Game","gameId":"BLUS30001"}
+not json
+{"time":"2026-01-02T03:04:06Z","event":"game_progress","total":1,"percent":40,"stage":"copying"}
+{"time":"2026-01-02T03:04:07Z","event":"game_done","tot`
+	if err := os.WriteFile(progressPath, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := client.WatchJob(ctx, &rpcpb.WatchJobRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() *rpcpb.JobUpdate {
+		t.Helper()
+		update, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return update
+	}
+
+	if update := next(); update.Job.State != rpcpb.JobState_JOB_STATE_RUNNING || update.Progress != nil {
+		t.Errorf("first update %v, want the running state", update)
+	}
+	if update := next(); update.Progress.GetEvent() != "game_started" || update.Progress.GameId != "BLUS30001" ||
+		!update.Progress.Time.AsTime().Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("second update %v", update)
+	}
+	if update := next(); update.Progress.GetStage() != "copying" || update.Progress.Percent != 40 {
+		t.Errorf("third update %v", update)
+	}
+
+	// The job finishes once its last event is complete
+	f, err := os.OpenFile(progressPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`al":1,"percent":100,"status":"success"}` + "\n")
+	f.Close()
+	err = jobs.Update(queuePath, func(queue *jobs.Queue) error {
+		defer release()
+		job, err := queue.Get(1)
+		queue.Finish(job, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if update := next(); update.Progress.GetEvent() != "game_done" || update.Progress.Percent != 100 {
+		t.Errorf("fourth update %v", update)
+	}
+	if update := next(); update.Job.State != rpcpb.JobState_JOB_STATE_COMPLETED || update.Progress != nil {
+		t.Errorf("fifth update %v, want the completed state", update)
+	}
+	if update, err := stream.Recv(); err != io.EOF {
+		t.Errorf("stream went on after the job finished: %v, %v", update, err)
+	}
+
+	// A finished job's recorded progress is streamed to a later watcher too
+	stream, err = client.WatchJob(ctx, &rpcpb.WatchJobRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Errorf("watching a finished job streamed %d updates, want 3 events and its state", count)
+	}
+
+	stream, _ = client.WatchJob(ctx, &rpcpb.WatchJobRequest{Id: 7})
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("watching a job that doesn't exist: %v", err)
+	}
+}
+
+func TestListGames(t *testing.T) {
+	c, _ := catalog.Parse([]byte(`{}`))
+	if err := c.Tag("BLUS30001", "Alpha", "favorites"); err != nil {
+		t.Fatal(err)
+	}
+	var filtered catalog.Filter
+	client := dial(t, NewServer("", func(filter catalog.Filter) ([]library.Game, *catalog.Catalog, error) {
+		filtered = filter
+		return []library.Game{
+			{Path: "/nas/Zeta [BLES00002]", Info: &common.OrganizedDirInfo{
+				IsOrganized: true, HasCompressed: true,
+				GameInfo: &common.GameInfo{Title: "Zeta", GameID: "BLES00002", Console: "PlayStation 3"},
+			}},
+			{Path: "/nas/Alpha [BLUS30001]", Info: &common.OrganizedDirInfo{
+				IsOrganized: true, HasDecompressed: true,
+				GameInfo: &common.GameInfo{Title: "Alpha", GameID: "BLUS30001", Console: "PlayStation 3"},
+			}},
+		}, c, nil
+	}))
+
+	resp, err := client.ListGames(context.Background(), &rpcpb.ListGamesRequest{ExcludeTags: []string{"kids"}, NoDemos: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.ExcludeTags) != 1 || !filtered.ExcludePrerelease {
+		t.Errorf("filtered with %+v", filtered)
+	}
+	if len(resp.Games) != 2 {
+		t.Fatalf("games %v", resp.Games)
+	}
+	alpha, zeta := resp.Games[0], resp.Games[1]
+	if alpha.Title != "Alpha" || alpha.Compressed || len(alpha.Tags) != 1 || alpha.Tags[0] != "favorites" {
+		t.Errorf("first game %v", alpha)
+	}
+	if zeta.GameId != "BLES00002" || !zeta.Compressed || len(zeta.Tags) != 0 {
+		t.Errorf("second game %v", zeta)
+	}
+
+	if _, err := client.ListGames(context.Background(), &rpcpb.ListGamesRequest{Sort: "rating"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("sorting by an unknown key: %v", err)
+	}
+}
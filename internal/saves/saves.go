@@ -0,0 +1,144 @@
+package saves
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+)
+
+// Dir is the folder inside an organized game that holds save snapshots
+const Dir = "_saves"
+
+// snapshotFormat names snapshots by the time they were taken
+const snapshotFormat = "20060102-150405"
+
+// Import copies the title's save folders from the store into a new timestamped
+// snapshot in the game's _saves folder, either as a folder or, with compress, a .7z
+// archive. It returns the snapshot name and the save folders copied.
+func Import(store Store, gameDir, titleID string, compress bool, archive common.ArchiveOptions) (string, []string, error) {
+	names, err := store.SaveDirs(titleID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("no save data for %s in %s", titleID, store)
+	}
+
+	snapshot := time.Now().Format(snapshotFormat)
+	savesDir := filepath.Join(gameDir, Dir)
+	target := filepath.Join(savesDir, snapshot)
+	if compress {
+		if target, err = os.MkdirTemp("", "saves-*"); err != nil {
+			return "", nil, fmt.Errorf("creating temporary directory: %w", err)
+		}
+		defer os.RemoveAll(target)
+	}
+
+	for _, name := range names {
+		if err := store.Download(name, filepath.Join(target, name)); err != nil {
+			if !compress {
+				os.RemoveAll(target)
+			}
+			return "", nil, fmt.Errorf("copying %s: %w", name, err)
+		}
+	}
+
+	if compress {
+		if err := os.MkdirAll(savesDir, 0755); err != nil {
+			return "", nil, fmt.Errorf("creating %s: %w", savesDir, err)
+		}
+		if err := common.Create7zArchive(target, filepath.Join(savesDir, snapshot+".7z"), archive); err != nil {
+			return "", nil, err
+		}
+	}
+	return snapshot, names, nil
+}
+
+// Snapshots returns the names of a game's save snapshots, oldest first
+func Snapshots(gameDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(gameDir, Dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".7z")
+		if _, err := time.Parse(snapshotFormat, name); err == nil {
+			snapshots = append(snapshots, name)
+		}
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+// Export copies the save folders of a snapshot (the latest if snapshot is empty)
+// to the store. Existing save folders are only replaced with force.
+func Export(store Store, gameDir, snapshot string, force bool) (string, []string, error) {
+	if snapshot == "" {
+		snapshots, err := Snapshots(gameDir)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(snapshots) == 0 {
+			return "", nil, fmt.Errorf("no save snapshots in %s", filepath.Join(gameDir, Dir))
+		}
+		snapshot = snapshots[len(snapshots)-1]
+	}
+
+	source := filepath.Join(gameDir, Dir, snapshot)
+	if _, err := os.Stat(source); err != nil {
+		archive := source + ".7z"
+		if _, err := os.Stat(archive); err != nil {
+			return "", nil, fmt.Errorf("no save snapshot %s in %s", snapshot, filepath.Join(gameDir, Dir))
+		}
+		if source, err = os.MkdirTemp("", "saves-*"); err != nil {
+			return "", nil, fmt.Errorf("creating temporary directory: %w", err)
+		}
+		defer os.RemoveAll(source)
+		if err := common.Extract7zArchive(archive, source); err != nil {
+			return "", nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	if !force {
+		for _, name := range names {
+			existing, err := store.SaveDirs(name)
+			if err != nil {
+				return "", nil, err
+			}
+			for _, e := range existing {
+				if e == name {
+					return "", nil, fmt.Errorf("%w: save %s already exists in %s (use --force to replace it)", common.ErrTargetExists, name, store)
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		if err := store.Upload(filepath.Join(source, name), name); err != nil {
+			return "", nil, fmt.Errorf("copying %s: %w", name, err)
+		}
+	}
+	return snapshot, names, nil
+}
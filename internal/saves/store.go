@@ -0,0 +1,213 @@
+// Package saves copies PS3 save data between a console or emulator and the
+// _saves folder of organized games
+package saves
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ftp"
+)
+
+// DefaultUser is the first PS3 user profile
+const DefaultUser = "00000001"
+
+// Store is a PS3 file system holding save data: an emulator's dev_hdd0 folder,
+// or a console reached over FTP
+type Store interface {
+	// SaveDirs returns the names of the save folders belonging to a title ID
+	SaveDirs(titleID string) ([]string, error)
+	// Download copies a save folder into the local directory dest
+	Download(name, dest string) error
+	// Upload copies the local save folder src into the store as name
+	Upload(src, name string) error
+	Close() error
+	String() string
+}
+
+// Open returns the store at location: ftp://host[:port] for a console, or a local
+// dev_hdd0 folder (or the RPCS3 folder containing it). user is the PS3 user profile.
+func Open(location, user string) (Store, error) {
+	if user == "" {
+		user = DefaultUser
+	}
+
+	if strings.HasPrefix(location, "ftp://") {
+		host := strings.TrimSuffix(strings.TrimPrefix(location, "ftp://"), "/")
+		client, err := ftp.Dial(host, "", "", 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return &ftpStore{client: client, location: location, dir: path.Join("/dev_hdd0/home", user, "savedata")}, nil
+	}
+
+	root := location
+	if info, err := os.Stat(filepath.Join(location, "dev_hdd0")); err == nil && info.IsDir() {
+		root = filepath.Join(location, "dev_hdd0")
+	}
+	dir := filepath.Join(root, "home", user, "savedata")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("no savedata folder for user %s in %s", user, location)
+	}
+	return &localStore{dir: dir}, nil
+}
+
+// matchSaveDirs returns the names that are save folders of the title ID, sorted
+func matchSaveDirs(names []string, titleID string) []string {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(strings.ToUpper(name), strings.ToUpper(titleID)) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// localStore is a savedata folder on a local disk, such as RPCS3's
+type localStore struct {
+	dir string
+}
+
+func (s *localStore) SaveDirs(titleID string) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.dir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return matchSaveDirs(names, titleID), nil
+}
+
+func (s *localStore) Download(name, dest string) error {
+	return common.CopyDir(filepath.Join(s.dir, name), dest)
+}
+
+func (s *localStore) Upload(src, name string) error {
+	return common.CopyDir(src, filepath.Join(s.dir, name))
+}
+
+func (s *localStore) Close() error   { return nil }
+func (s *localStore) String() string { return s.dir }
+
+// ftpStore is a console's savedata folder reached over FTP
+type ftpStore struct {
+	client   *ftp.Client
+	location string
+	dir      string
+}
+
+func (s *ftpStore) SaveDirs(titleID string) ([]string, error) {
+	entries, err := s.client.List(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir {
+			if err := checkRemoteName(entry.Name); err != nil {
+				return nil, err
+			}
+			names = append(names, entry.Name)
+		}
+	}
+	return matchSaveDirs(names, titleID), nil
+}
+
+// checkRemoteName refuses a name from the server's listing that isn't a single file or
+// folder name, so a malicious server can't make a download write outside its folder
+func checkRemoteName(name string) error {
+	if name == "." || strings.ContainsAny(name, `/\`) || !filepath.IsLocal(name) {
+		return fmt.Errorf("server listed an invalid file name %q", name)
+	}
+	return nil
+}
+
+func (s *ftpStore) Download(name, dest string) error {
+	return s.download(path.Join(s.dir, name), dest)
+}
+
+func (s *ftpStore) download(remote, dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	entries, err := s.client.List(remote)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := checkRemoteName(entry.Name); err != nil {
+			return err
+		}
+		remotePath := path.Join(remote, entry.Name)
+		localPath := filepath.Join(dest, entry.Name)
+		if entry.IsDir {
+			if err := s.download(remotePath, localPath); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Create(localPath)
+		if err != nil {
+			return err
+		}
+		err = s.client.Retrieve(remotePath, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("downloading %s: %w", remotePath, err)
+		}
+	}
+	return nil
+}
+
+func (s *ftpStore) Upload(src, name string) error {
+	return s.upload(src, path.Join(s.dir, name))
+}
+
+func (s *ftpStore) upload(src, remote string) error {
+	if err := s.client.MakeDir(remote); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		localPath := filepath.Join(src, entry.Name())
+		remotePath := path.Join(remote, entry.Name())
+		if entry.IsDir() {
+			if err := s.upload(localPath, remotePath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.uploadFile(localPath, remotePath); err != nil {
+			return fmt.Errorf("uploading %s: %w", remotePath, err)
+		}
+	}
+	return nil
+}
+
+func (s *ftpStore) uploadFile(localPath, remotePath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.client.Store(remotePath, f)
+}
+
+func (s *ftpStore) Close() error   { return s.client.Close() }
+func (s *ftpStore) String() string { return s.location }
@@ -0,0 +1,28 @@
+package saves
+
+import "testing"
+
+// TestCheckRemoteName checks which names from an FTP listing a download accepts
+func TestCheckRemoteName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"BLUS30001-SAVE00", true},
+		{"PARAM.SFO", true},
+		{"save data", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../escape", false},
+		{"dir/file", false},
+		{`..\escape`, false},
+		{`dir\file`, false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if err := checkRemoteName(tt.name); (err == nil) != tt.ok {
+			t.Errorf("checkRemoteName(%q) = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package schedule parses cron expressions used for recurring library tasks
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression (minute hour day-of-month month day-of-week)
+type Cron struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDOM bool
+	anyDOW bool
+}
+
+// cronField describes the allowed range of one cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronShortcuts maps the common @-shortcuts to their expressions
+var cronShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseCron parses a standard five-field cron expression such as "0 3 * * *"
+// or one of the @hourly/@daily/@weekly/@monthly/@yearly shortcuts.
+// Fields accept *, numbers, ranges (1-5), lists (1,15) and steps (*/15, 0-30/10);
+// day of week is 0-7 with both 0 and 7 meaning Sunday.
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[strings.ToLower(spec)]; ok {
+		spec = shortcut
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+
+	// Sunday may be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Cron{
+		expr:   expr,
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDOM: strings.HasPrefix(parts[2], "*"),
+		anyDOW: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField parses one comma-separated cron field into a bit set
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step in %q", spec.name, item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := spec.min, spec.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = parseCronNumber(bounds[0], spec); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronNumber(bounds[1], spec); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: invalid range %q", spec.name, rangePart)
+			}
+		default:
+			n, err := parseCronNumber(rangePart, spec)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronNumber(s string, spec cronField) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("%s: %q is not a number from %d to %d", spec.name, s, spec.min, spec.max)
+	}
+	return n, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next returns the first matching minute strictly after t, in t's location.
+// It returns the zero time if nothing matches within five years (e.g. "0 0 31 2 *").
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = after(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()))
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = after(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()))
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = after(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()))
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// after returns next, the start of a later month, day or hour, moved on by whole hours
+// when a daylight saving change skips that time and time.Date resolved it to one
+// before t, which would make Next loop
+func after(t, next time.Time) time.Time {
+	for !next.After(t) {
+		next = next.Add(time.Hour)
+	}
+	return next
+}
+
+// dayMatches applies cron's day rule: when both day of month and day of week
+// are restricted, a day matching either one is enough. A field starting with *
+// (such as */2) doesn't count as restricted, but its step still applies.
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDOM || c.anyDOW {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+// nextTimes returns the first n times the schedule runs after from
+func nextTimes(c *Cron, from time.Time, n int) []time.Time {
+	var times []time.Time
+	for t := from; len(times) < n; {
+		t = c.Next(t)
+		times = append(times, t)
+		if t.IsZero() {
+			break
+		}
+	}
+	return times
+}
+
+func checkTimes(t *testing.T, expr string, got, want []time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%q: runs at %v, want %v", expr, got, want)
+		return
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("%q: runs at %v, want %v", expr, got, want)
+			return
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// January 1st 2026 is a Thursday
+	jan1 := date(2026, 1, 1, 0, 0)
+	tests := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		// Steps, ranges and lists
+		{"*/15 * * * *", jan1, []time.Time{date(2026, 1, 1, 0, 15), date(2026, 1, 1, 0, 30), date(2026, 1, 1, 0, 45), date(2026, 1, 1, 1, 0)}},
+		{"0-30/10 9 * * *", jan1, []time.Time{date(2026, 1, 1, 9, 0), date(2026, 1, 1, 9, 10), date(2026, 1, 1, 9, 20), date(2026, 1, 1, 9, 30), date(2026, 1, 2, 9, 0)}},
+		{"5/20 * * * *", jan1, []time.Time{date(2026, 1, 1, 0, 5), date(2026, 1, 1, 0, 25), date(2026, 1, 1, 0, 45), date(2026, 1, 1, 1, 5)}},
+		{"5,10 1,2 * * *", jan1, []time.Time{date(2026, 1, 1, 1, 5), date(2026, 1, 1, 1, 10), date(2026, 1, 1, 2, 5), date(2026, 1, 1, 2, 10), date(2026, 1, 2, 1, 5)}},
+		{"0 22-23,2 * * *", jan1, []time.Time{date(2026, 1, 1, 2, 0), date(2026, 1, 1, 22, 0), date(2026, 1, 1, 23, 0), date(2026, 1, 2, 2, 0)}},
+		{"0 12 * * 1-5", jan1, []time.Time{date(2026, 1, 1, 12, 0), date(2026, 1, 2, 12, 0), date(2026, 1, 5, 12, 0)}},
+		{"0 3 * 2,4 *", jan1, []time.Time{date(2026, 2, 1, 3, 0), date(2026, 2, 2, 3, 0)}},
+
+		// The next run is strictly after from, at a whole minute
+		{"0 0 * * *", jan1, []time.Time{date(2026, 1, 2, 0, 0)}},
+		{"* * * * *", jan1.Add(30 * time.Second), []time.Time{date(2026, 1, 1, 0, 1)}},
+
+		// Sunday is 0 or 7
+		{"0 0 * * 0", jan1, []time.Time{date(2026, 1, 4, 0, 0), date(2026, 1, 11, 0, 0)}},
+		{"0 0 * * 7", jan1, []time.Time{date(2026, 1, 4, 0, 0), date(2026, 1, 11, 0, 0)}},
+		{"0 0 * * 5-7", jan1, []time.Time{date(2026, 1, 2, 0, 0), date(2026, 1, 3, 0, 0), date(2026, 1, 4, 0, 0), date(2026, 1, 9, 0, 0)}},
+		{"@weekly", jan1, []time.Time{date(2026, 1, 4, 0, 0)}},
+
+		// Day of month and day of week both restricted: either one is enough
+		{"0 0 13 * 5", jan1, []time.Time{date(2026, 1, 2, 0, 0), date(2026, 1, 9, 0, 0), date(2026, 1, 13, 0, 0), date(2026, 1, 16, 0, 0)}},
+		{"0 0 13 * *", jan1, []time.Time{date(2026, 1, 13, 0, 0), date(2026, 2, 13, 0, 0)}},
+		{"0 0 * * 5", jan1, []time.Time{date(2026, 1, 2, 0, 0), date(2026, 1, 9, 0, 0)}},
+		// A field starting with * still restricts the days with its step
+		{"0 0 */10 * *", jan1, []time.Time{date(2026, 1, 11, 0, 0), date(2026, 1, 21, 0, 0), date(2026, 1, 31, 0, 0), date(2026, 2, 1, 0, 0)}},
+		{"0 0 * * */3", jan1, []time.Time{date(2026, 1, 3, 0, 0), date(2026, 1, 4, 0, 0), date(2026, 1, 7, 0, 0), date(2026, 1, 10, 0, 0)}},
+		// and then both fields must match, as in Vixie cron: odd days that are Mondays
+		{"0 0 */2 * 1", jan1, []time.Time{date(2026, 1, 5, 0, 0), date(2026, 1, 19, 0, 0), date(2026, 2, 9, 0, 0)}},
+
+		// Month and year ends
+		{"0 0 31 * *", date(2026, 1, 31, 0, 0), []time.Time{date(2026, 3, 31, 0, 0), date(2026, 5, 31, 0, 0), date(2026, 7, 31, 0, 0), date(2026, 8, 31, 0, 0)}},
+		{"59 23 31 12 *", jan1, []time.Time{date(2026, 12, 31, 23, 59), date(2027, 12, 31, 23, 59)}},
+		{"@yearly", date(2026, 12, 31, 23, 59), []time.Time{date(2027, 1, 1, 0, 0)}},
+		{"* * * * *", date(2026, 12, 31, 23, 59), []time.Time{date(2027, 1, 1, 0, 0)}},
+		{"0 0 29 2 *", jan1, []time.Time{date(2028, 2, 29, 0, 0), date(2032, 2, 29, 0, 0)}},
+		{"0 0 30 2 *", jan1, []time.Time{{}}},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		checkTimes(t, tt.expr, nextTimes(c, tt.from, len(tt.want)), tt.want)
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	local := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, ny)
+	}
+
+	// March 8th 2026: clocks go from 2:00 to 3:00, so 2:30 doesn't happen that day
+	checkTimes(t, "30 2 * * *", nextTimes(mustParse(t, "30 2 * * *"), local(3, 7, 3, 0), 2),
+		[]time.Time{local(3, 9, 2, 30), local(3, 10, 2, 30)})
+	spring := local(3, 8, 1, 0)
+	checkTimes(t, "0 * * * *", nextTimes(mustParse(t, "0 * * * *"), spring, 2),
+		[]time.Time{spring.Add(time.Hour), spring.Add(2 * time.Hour)})
+	checkTimes(t, "0 4 * * *", nextTimes(mustParse(t, "0 4 * * *"), spring, 2),
+		[]time.Time{spring.Add(2 * time.Hour), spring.Add(2*time.Hour + 24*time.Hour)})
+
+	// November 1st 2026: clocks go from 2:00 back to 1:00; runs stay 30 minutes apart
+	fall := local(11, 1, 0, 30)
+	var want []time.Time
+	for i := 1; i <= 5; i++ {
+		want = append(want, fall.Add(time.Duration(i)*30*time.Minute))
+	}
+	checkTimes(t, "*/30 * * * *", nextTimes(mustParse(t, "*/30 * * * *"), fall, 5), want)
+	checkTimes(t, "0 3 * * *", nextTimes(mustParse(t, "0 3 * * *"), fall, 1), []time.Time{fall.Add(3*time.Hour + 30*time.Minute)})
+}
+
+func mustParse(t *testing.T, expr string) *Cron {
+	t.Helper()
+	c, err := ParseCron(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"@fortnightly",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("%q: parsed, want an error", expr)
+		}
+	}
+}
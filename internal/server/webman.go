@@ -0,0 +1,136 @@
+// Package server shares an organized library over the network
+package server
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NeilGraham/rom-organizer/internal/library"
+)
+
+// GamesDir is the share folder webMAN MOD scans for folder-format PS3 games
+const GamesDir = "GAMES"
+
+// GamesFS is a read-only http.FileSystem presenting decompressed organized games
+// in the GAMES/<Title [ID]>/PS3_GAME layout webMAN MOD expects from a network share.
+// Compressed games cannot be streamed and are left out.
+type GamesFS struct {
+	games   map[string]string // Share folder name -> organized game/ directory
+	started time.Time
+}
+
+// NewGamesFS builds the share from organized games, returning it with the
+// paths of the compressed games that were skipped
+func NewGamesFS(games []library.Game) (*GamesFS, []string) {
+	share := &GamesFS{games: make(map[string]string), started: time.Now()}
+	var skipped []string
+	for _, game := range games {
+		if !game.Info.HasDecompressed {
+			skipped = append(skipped, game.Path)
+			continue
+		}
+		share.games[filepath.Base(game.Path)] = filepath.Join(game.Path, "game")
+	}
+	return share, skipped
+}
+
+// Len returns the number of shared games
+func (s *GamesFS) Len() int {
+	return len(s.games)
+}
+
+// Open implements http.FileSystem
+func (s *GamesFS) Open(name string) (http.File, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	parts := strings.SplitN(name, "/", 3)
+
+	switch {
+	case name == "":
+		return s.virtualDir("/", []string{GamesDir}), nil
+	case parts[0] != GamesDir:
+		return nil, os.ErrNotExist
+	case len(parts) == 1:
+		folders := make([]string, 0, len(s.games))
+		for folder := range s.games {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+		return s.virtualDir(GamesDir, folders), nil
+	}
+
+	gameDir, ok := s.games[parts[1]]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	rest := "/"
+	if len(parts) == 3 {
+		rest += parts[2]
+	}
+	return http.Dir(gameDir).Open(rest)
+}
+
+func (s *GamesFS) virtualDir(name string, entries []string) *virtualDir {
+	return &virtualDir{info: dirInfo{name: path.Base(name), modTime: s.started}, entries: entries}
+}
+
+// virtualDir is a directory listing that does not exist on disk
+type virtualDir struct {
+	info    dirInfo
+	entries []string
+	offset  int
+}
+
+func (d *virtualDir) Close() error { return nil }
+func (d *virtualDir) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("%s is a directory", d.info.name)
+}
+func (d *virtualDir) Seek(int64, int) (int64, error) { return 0, nil }
+func (d *virtualDir) Stat() (fs.FileInfo, error)     { return d.info, nil }
+func (d *virtualDir) Readdir(count int) ([]fs.FileInfo, error) {
+	if d.offset >= len(d.entries) && count > 0 {
+		return nil, io.EOF
+	}
+	end := len(d.entries)
+	if count > 0 && d.offset+count < end {
+		end = d.offset + count
+	}
+	infos := make([]fs.FileInfo, 0, end-d.offset)
+	for _, entry := range d.entries[d.offset:end] {
+		infos = append(infos, dirInfo{name: entry, modTime: d.info.modTime})
+	}
+	d.offset = end
+	return infos, nil
+}
+
+// dirInfo describes a virtual directory
+type dirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (i dirInfo) Name() string       { return i.name }
+func (i dirInfo) Size() int64        { return 0 }
+func (i dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i dirInfo) ModTime() time.Time { return i.modTime }
+func (i dirInfo) IsDir() bool        { return true }
+func (i dirInfo) Sys() any           { return nil }
+
+// ReadOnly wraps a handler so that only GET and HEAD requests reach it
+func ReadOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "read-only share", http.StatusMethodNotAllowed)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
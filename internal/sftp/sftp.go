@@ -0,0 +1,373 @@
+// Package sftp reads and writes sftp://[user@]host[:port]/path locations through the
+// system's ssh client, so games on a seedbox or another machine can be organized
+// without downloading or mounting them first. Files and folders are streamed as tar
+// over the connection, which needs a POSIX shell and tar on the remote side. ssh runs
+// with BatchMode, so logins must work without a prompt (keys or an agent).
+package sftp
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/NeilGraham/rom-organizer/internal/common"
+	"github.com/NeilGraham/rom-organizer/internal/ui"
+)
+
+// Scheme prefixes the locations this package handles
+const Scheme = "sftp://"
+
+// SSHPath is the ssh client to run
+var SSHPath = "ssh"
+
+// IsURL reports whether a source or output path is an sftp:// location
+func IsURL(p string) bool {
+	return strings.HasPrefix(strings.ToLower(p), Scheme)
+}
+
+// Location is a file or folder on an SSH host. A path starting with /~/ is relative to
+// the home folder of the login.
+type Location struct {
+	User string
+	Host string
+	Port string
+	Path string // Absolute, or ~/... below the home folder
+}
+
+// Parse reads an sftp:// URL
+func Parse(raw string) (*Location, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", raw, err)
+	}
+	if !strings.EqualFold(u.Scheme, "sftp") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid URL %s (use sftp://[user@]host[:port]/path)", raw)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return nil, fmt.Errorf("%s: passwords in sftp:// URLs are not supported; use an SSH key or agent", raw)
+	}
+
+	p := path.Clean("/" + u.Path)
+	if p == "/~" || strings.HasPrefix(p, "/~/") {
+		p = p[1:]
+	}
+	if p == "/" || p == "~" {
+		return nil, fmt.Errorf("%s: give a folder or file below the root or home folder", raw)
+	}
+	return &Location{User: u.User.Username(), Host: u.Hostname(), Port: u.Port(), Path: p}, nil
+}
+
+// String formats the location as an sftp:// URL
+func (l *Location) String() string {
+	host := l.Host
+	if l.Port != "" {
+		host += ":" + l.Port
+	}
+	if l.User != "" {
+		host = l.User + "@" + host
+	}
+	p := l.Path
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return Scheme + host + p
+}
+
+// Join returns the location of a slash-separated path below l
+func (l *Location) Join(rel string) *Location {
+	joined := *l
+	joined.Path = path.Join(l.Path, rel)
+	return &joined
+}
+
+// Base returns the last element of the path
+func (l *Location) Base() string {
+	return path.Base(l.Path)
+}
+
+// quoted returns the path quoted for the remote shell. A leading ~/ stays unquoted so
+// the shell expands it.
+func (l *Location) quoted() string {
+	if rest, ok := strings.CutPrefix(l.Path, "~/"); ok {
+		return "~/" + shellQuote(rest)
+	}
+	return shellQuote(l.Path)
+}
+
+// parent returns the quoted folder containing the path
+func (l *Location) parent() string {
+	dir := *l
+	dir.Path = path.Dir(l.Path)
+	if dir.Path == "~" {
+		return "~"
+	}
+	return dir.quoted()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// command returns the ssh command running a shell script on the host
+func (l *Location) command(script string) *exec.Cmd {
+	args := []string{"-o", "BatchMode=yes"}
+	if l.Port != "" {
+		args = append(args, "-p", l.Port)
+	}
+	dest := l.Host
+	if l.User != "" {
+		dest = l.User + "@" + l.Host
+	}
+	args = append(args, "--", dest, script)
+	ui.Debugf("Running: %s %s\n", SSHPath, strings.Join(args, " "))
+	return exec.Command(SSHPath, args...)
+}
+
+// run runs a script on the host and returns its output
+func (l *Location) run(script string) (string, error) {
+	var stderr bytes.Buffer
+	execCmd := l.command(script)
+	execCmd.Stderr = &stderr
+	out, err := execCmd.Output()
+	if err != nil {
+		return "", sshError(l, err, stderr.String())
+	}
+	return string(out), nil
+}
+
+// sshError describes a failed ssh run with what the remote side printed
+func sshError(l *Location, err error, stderr string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return common.Permanent(fmt.Errorf("%s: ssh client not found (install OpenSSH)", l.Host))
+	}
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("%s: %w: %s", l, err, msg)
+	}
+	return fmt.Errorf("%s: %w", l, err)
+}
+
+// Stat reports whether the location exists and is a folder
+func Stat(l *Location) (exists, isDir bool, err error) {
+	p := l.quoted()
+	out, err := l.run(fmt.Sprintf("if [ -d %s ]; then echo dir; elif [ -e %s ]; then echo file; else echo none; fi", p, p))
+	if err != nil {
+		return false, false, err
+	}
+	switch strings.TrimSpace(out) {
+	case "dir":
+		return true, true, nil
+	case "file":
+		return true, false, nil
+	default:
+		return false, false, nil
+	}
+}
+
+// Download streams a remote file or folder into dir, under the Watchdog, and returns
+// its local path
+func Download(l *Location, dir string) (string, error) {
+	var stderr bytes.Buffer
+	execCmd := l.command(fmt.Sprintf("cd %s && tar -cf - -- %s", l.parent(), shellQuote(l.Base())))
+	execCmd.Stderr = &stderr
+
+	var received atomic.Int64
+	pr, pw := io.Pipe()
+	execCmd.Stdout = pw
+	extracted := make(chan error, 1)
+	go func() {
+		reader := &countingReader{r: pr, n: &received}
+		err := extractTar(reader, dir)
+		if err == nil {
+			// tar pads its output past the end marker; read it so ssh can exit
+			_, err = io.Copy(io.Discard, reader)
+		}
+		if err != nil {
+			// Stop ssh rather than leave it blocked writing to a pipe nobody reads
+			pr.CloseWithError(err)
+			if execCmd.Process != nil {
+				execCmd.Process.Kill()
+			}
+		}
+		extracted <- err
+	}()
+
+	err := common.RunWatched(execCmd, "downloading "+l.Base(), received.Load)
+	pw.CloseWithError(err)
+	if extractErr := <-extracted; extractErr != nil && err == nil {
+		err = fmt.Errorf("unpacking %s: %w", l, extractErr)
+	}
+	if err != nil {
+		if errors.Is(err, common.ErrStalled) || errors.Is(err, common.ErrInterrupted) {
+			return "", err
+		}
+		return "", sshError(l, err, stderr.String())
+	}
+
+	local := filepath.Join(dir, filepath.FromSlash(l.Base()))
+	if _, err := os.Lstat(local); err != nil {
+		return "", fmt.Errorf("%s: nothing was downloaded", l)
+	}
+	return local, nil
+}
+
+// targetExistsCode is the exit status of an upload script refusing to replace a target
+const targetExistsCode = 17
+
+// Upload streams a local file or folder into the remote folder dir, keeping its name,
+// under the Watchdog. It is unpacked next to the target first and moved into place, so
+// a failed upload leaves no partial game. An existing target is replaced when replace
+// is set and is an error otherwise.
+func Upload(localPath string, dir *Location, replace bool) error {
+	name := filepath.Base(localPath)
+	target := dir.Join(name)
+	staging := dir.Join(".rom-organizer-upload-" + name)
+
+	script := fmt.Sprintf("mkdir -p -- %s && cd %s && rm -rf -- %s && mkdir -- %s && tar -xf - -C %s && ",
+		dir.quoted(), dir.quoted(), shellQuote(staging.Base()), shellQuote(staging.Base()), shellQuote(staging.Base()))
+	if replace {
+		script += fmt.Sprintf("rm -rf -- %s && ", shellQuote(name))
+	} else {
+		script += fmt.Sprintf("if [ -e %s ]; then echo 'already exists' >&2; exit %d; fi && ", shellQuote(name), targetExistsCode)
+	}
+	script += fmt.Sprintf("mv -- %s/%s %s && rmdir -- %s", shellQuote(staging.Base()), shellQuote(name), shellQuote(name), shellQuote(staging.Base()))
+
+	var stderr bytes.Buffer
+	execCmd := dir.command(script)
+	execCmd.Stderr = &stderr
+
+	pr, pw := io.Pipe()
+	execCmd.Stdin = pr
+	sent := &common.CountingWriter{W: pw}
+	written := make(chan error, 1)
+	go func() {
+		err := writeTar(sent, localPath)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	err := common.RunWatched(execCmd, "uploading "+name, sent.N.Load)
+	// Unblock the writer when ssh exited without reading everything
+	pr.CloseWithError(io.ErrClosedPipe)
+	writeErr := <-written
+	if err == nil && writeErr != nil {
+		err = fmt.Errorf("packing %s: %w", localPath, writeErr)
+	}
+	if err == nil {
+		return nil
+	}
+
+	dir.run("rm -rf -- " + staging.quoted())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == targetExistsCode {
+		return common.Permanent(fmt.Errorf("%w: %s (use --force to overwrite)", common.ErrTargetExists, target))
+	}
+	if errors.Is(err, common.ErrStalled) || errors.Is(err, common.ErrInterrupted) {
+		return err
+	}
+	return sshError(target, err, stderr.String())
+}
+
+// writeTar writes a file or a folder and everything in it as a tar stream
+func writeTar(w io.Writer, localPath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	name := filepath.Base(localPath)
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := common.WriteDirTar(localPath, name, tw, nil); err != nil {
+			return err
+		}
+	} else {
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// extractTar unpacks the folders and regular files of a tar stream into dir. Entries
+// leaving dir are refused, and links are skipped: game folders have no use for them.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Backslashes and volume names only lead elsewhere on Windows, which IsLocal knows
+		name := filepath.FromSlash(path.Clean(header.Name))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("unsafe path in stream: %s", header.Name)
+		}
+		target := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode&0777)|0600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			os.Chtimes(target, header.ModTime, header.ModTime)
+		default:
+			ui.Verbosef("Skipping %s (not a file or folder)\n", header.Name)
+		}
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
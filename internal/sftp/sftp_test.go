@@ -0,0 +1,62 @@
+package sftp
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// tarOf returns a tar stream holding a regular file for each name
+func tarOf(t *testing.T, names ...string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// TestExtractTarRefusesEscapes checks that entries leading out of the folder fail the
+// download before anything is written outside it
+func TestExtractTarRefusesEscapes(t *testing.T) {
+	names := []string{"../escape", "/tmp/escape", "game/../../escape", "game/./../../escape"}
+	if runtime.GOOS == "windows" {
+		names = append(names, `..\escape`, `game\..\..\escape`, `C:\escape`, `C:escape`, `\\host\share\escape`)
+	}
+	for _, name := range names {
+		root := t.TempDir()
+		dir := filepath.Join(root, "dir")
+		err := extractTar(tarOf(t, name), dir)
+		if err == nil {
+			t.Errorf("%s: extracted without error", name)
+		}
+		entries, _ := os.ReadDir(root)
+		for _, entry := range entries {
+			if entry.Name() != "dir" {
+				t.Errorf("%s: wrote %s outside the folder", name, entry.Name())
+			}
+		}
+	}
+
+	dir := t.TempDir()
+	if err := extractTar(tarOf(t, "./game/PS3_GAME/PARAM.SFO", "game/a/../EBOOT.BIN"), dir); err != nil {
+		t.Fatalf("safe entries: %v", err)
+	}
+	for _, name := range []string{"game/PS3_GAME/PARAM.SFO", "game/EBOOT.BIN"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s not extracted: %v", name, err)
+		}
+	}
+}
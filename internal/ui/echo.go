@@ -0,0 +1,37 @@
+//go:build !windows
+
+package ui
+
+import (
+	"os"
+	"os/exec"
+)
+
+// disableEcho turns off terminal echo with stty and returns a function restoring it
+func disableEcho(tty *os.File) (func(), error) {
+	off := exec.Command("stty", "-echo")
+	off.Stdin = tty
+	if err := off.Run(); err != nil {
+		return nil, err
+	}
+	return func() {
+		on := exec.Command("stty", "echo")
+		on.Stdin = tty
+		on.Run()
+	}, nil
+}
+
+// singleKeys makes the terminal hand over each key as it is pressed, without echo, and
+// returns a function restoring line input
+func singleKeys(tty *os.File) (func(), error) {
+	raw := exec.Command("stty", "-icanon", "-echo", "min", "1")
+	raw.Stdin = tty
+	if err := raw.Run(); err != nil {
+		return nil, err
+	}
+	return func() {
+		cooked := exec.Command("stty", "icanon", "echo")
+		cooked.Stdin = tty
+		cooked.Run()
+	}, nil
+}
@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	enableLineInput = 0x0002
+	enableEchoInput = 0x0004
+)
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// disableEcho turns off console echo and returns a function restoring the previous mode
+func disableEcho(tty *os.File) (func(), error) {
+	handle := syscall.Handle(tty.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	if r, _, err := setConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput)); r == 0 {
+		return nil, err
+	}
+	return func() {
+		setConsoleMode.Call(uintptr(handle), uintptr(mode))
+	}, nil
+}
+
+// singleKeys makes the console hand over each key as it is pressed, without echo, and
+// returns a function restoring the previous mode
+func singleKeys(tty *os.File) (func(), error) {
+	handle := syscall.Handle(tty.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	if r, _, err := setConsoleMode.Call(uintptr(handle), uintptr(mode&^(enableLineInput|enableEchoInput))); r == 0 {
+		return nil, err
+	}
+	return func() {
+		setConsoleMode.Call(uintptr(handle), uintptr(mode))
+	}, nil
+}
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+)
+
+// ReadKey prints prompt to stderr and returns the next key pressed, without waiting for
+// Enter
+func ReadKey(prompt string) (byte, error) {
+	if !IsTerminal(os.Stdin) {
+		return 0, ErrNoTerminal
+	}
+	fmt.Fprint(os.Stderr, prompt)
+
+	restore, err := singleKeys(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		return 0, ErrNoTerminal
+	}
+	key := make([]byte, 1)
+	_, err = os.Stdin.Read(key)
+	restore()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return 0, fmt.Errorf("reading key: %w", err)
+	}
+	return key[0], nil
+}
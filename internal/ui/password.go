@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNoTerminal means input was needed but stdin is not an interactive terminal
+var ErrNoTerminal = errors.New("stdin is not a terminal")
+
+// IsTerminal reports whether f is an interactive terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ReadPassword prints prompt to stderr and reads a line from the terminal without echoing it
+func ReadPassword(prompt string) (string, error) {
+	if !IsTerminal(os.Stdin) {
+		return "", ErrNoTerminal
+	}
+	fmt.Fprint(os.Stderr, prompt)
+
+	// Character devices such as /dev/null pass the check above but have no echo to turn off
+	restore, err := disableEcho(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		return "", ErrNoTerminal
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	restore()
+	fmt.Fprintln(os.Stderr)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,129 @@
+// Package ui prints leveled, optionally colorized messages for the command line
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Level controls which messages are printed
+type Level int
+
+const (
+	// LevelQuiet prints only warnings and errors (-q)
+	LevelQuiet Level = iota
+	// LevelNormal prints progress and results (default)
+	LevelNormal
+	// LevelVerbose adds detailed progress (-v)
+	LevelVerbose
+	// LevelDebug adds external commands and other diagnostics (-vv)
+	LevelDebug
+)
+
+// ANSI color codes
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorGray   = "\033[90m"
+)
+
+var (
+	level    = LevelNormal
+	useColor = DefaultColor()
+
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// SetLevel sets the verbosity level
+func SetLevel(l Level) {
+	level = l
+}
+
+// CurrentLevel returns the verbosity level
+func CurrentLevel() Level {
+	return level
+}
+
+// IsVerbose reports whether verbose (-v) output is enabled
+func IsVerbose() bool {
+	return level >= LevelVerbose
+}
+
+// SetOutput redirects messages normally printed to stdout, for example to stderr when
+// stdout carries machine-readable output
+func SetOutput(w io.Writer) {
+	stdout = w
+}
+
+// Output returns the writer used for messages, for passing to child processes
+func Output() io.Writer {
+	return stdout
+}
+
+// SetColor enables or disables colored output
+func SetColor(enabled bool) {
+	useColor = enabled
+}
+
+// DefaultColor reports whether color should be used: stdout is a terminal,
+// NO_COLOR is not set and TERM is not "dumb"
+func DefaultColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Infof prints a normal progress message
+func Infof(format string, args ...interface{}) {
+	print(stdout, LevelNormal, "", format, args...)
+}
+
+// Successf prints a success message in green
+func Successf(format string, args ...interface{}) {
+	print(stdout, LevelNormal, colorGreen, format, args...)
+}
+
+// Verbosef prints a detail shown with -v
+func Verbosef(format string, args ...interface{}) {
+	print(stdout, LevelVerbose, "", format, args...)
+}
+
+// Debugf prints a diagnostic shown with -vv
+func Debugf(format string, args ...interface{}) {
+	print(stdout, LevelDebug, colorGray, format, args...)
+}
+
+// Warnf prints a warning in yellow, prefixed with "⚠️  WARNING: ". Warnings are shown even with -q.
+func Warnf(format string, args ...interface{}) {
+	print(stdout, LevelQuiet, colorYellow, "⚠️  WARNING: "+format, args...)
+}
+
+// Errorf prints an error in red to stderr. Errors are always shown.
+func Errorf(format string, args ...interface{}) {
+	print(stderr, LevelQuiet, colorRed, format, args...)
+}
+
+// print writes a message if the current level allows it, wrapping it in color when enabled
+func print(w io.Writer, min Level, color, format string, args ...interface{}) {
+	if level < min {
+		return
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	if useColor && color != "" {
+		// Keep a trailing newline outside the color codes
+		trimmed := msg
+		newline := ""
+		if len(trimmed) > 0 && trimmed[len(trimmed)-1] == '\n' {
+			trimmed, newline = trimmed[:len(trimmed)-1], "\n"
+		}
+		msg = color + trimmed + colorReset + newline
+	}
+	fmt.Fprint(w, msg)
+}
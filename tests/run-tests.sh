@@ -72,12 +72,11 @@ echo
 # Run integration tests with verbose output (from cmd/rom-organizer directory)
 echo "🚀 Running integration tests..."
 echo "   This will test the complete workflow:"
-echo "   • Test game generation using tests/generate-test-games.go → tests/test-games/"
+echo "   • Test game generation using 'rom-organizer devtools generate-games'"
 echo "   • Binary building in cmd/rom-organizer/"
 echo "   • Metadata extraction (single & multiple paths)"
-echo "   • Organize command → tests/test-organized/"
-echo "   • Compress command → tests/test-compressed/"
-echo "   • Decompress command → tests/test-decompressed/"
+echo "   • Organize, compress and decompress commands"
+echo "   Artifacts go to temporary directories, or to tests/ with --keep"
 echo
 
 # Set environment variable for Go tests to know about --keep flag
@@ -92,8 +91,8 @@ cd cmd/rom-organizer
 echo "📋 Running TestBuildBinary..."
 go test -v -run TestBuildBinary
 
-echo "📋 Running TestGenerateTestGamesScript..."
-go test -v -run TestGenerateTestGamesScript
+echo "📋 Running TestDevtoolsGenerateGames..."
+go test -v -run TestDevtoolsGenerateGames
 
 echo "📋 Running TestIntegration..."
 go test -v -run TestIntegration -timeout 10m
@@ -139,6 +138,6 @@ else
     echo "   • Compressed games: tests/test-compressed/"
     echo "   • Decompressed games: tests/test-decompressed/"
     echo "   • Test binaries: cmd/rom-organizer/rom-organizer-dev*"
-    echo "   • Temp test games: tests/temp-test-games/"
-    echo "   💡 To clean up manually: rm -rf tests/test-* tests/temp-test-games tests/bench-games-* cmd/rom-organizer/rom-organizer-*"
+    echo "   • Temp test games: tests/temp-test-games/ and tests/temp-test-games-variants/"
+    echo "   💡 To clean up manually: rm -rf tests/test-* tests/temp-test-games* cmd/rom-organizer/rom-organizer-*"
 fi 
\ No newline at end of file